@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func BackupCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var format string
+	var compress int
+	var jobs int
+
+	backupCmd := &cobra.Command{
+		Use:   "backup [file]",
+		Short: "Back up a database with pg_dump",
+		Long: `Back up a database from a running PostgreSQL container to a local file.
+
+pg_dump runs inside the container, so no local PostgreSQL client is needed.
+Without a file argument the backup is named <database>-<timestamp>.dump
+(.sql for plain format, no extension for directory format).`,
+		Example: `  # Back up the default container's database (custom format)
+  pgbox backup
+
+  # Back up to a specific file as plain SQL
+  pgbox backup --format plain mydb.sql
+
+  # Parallel directory-format backup of a specific database
+  pgbox backup -d mydb --format directory --jobs 4 ./mydb-backup`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := ""
+			if len(args) == 1 {
+				output = args[0]
+			}
+
+			orch := orchestrator.NewBackupOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.BackupConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Output:        output,
+				Format:        format,
+				Compress:      compress,
+				Jobs:          jobs,
+			})
+		},
+	}
+
+	backupCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	backupCmd.Flags().StringVarP(&database, "database", "d", "", "Database to back up (default: container's POSTGRES_DB)")
+	backupCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	backupCmd.Flags().StringVarP(&format, "format", "F", "custom", "Backup format (custom, plain, or directory)")
+	backupCmd.Flags().IntVarP(&compress, "compress", "Z", -1, "Compression level 0-9 (default: pg_dump default)")
+	backupCmd.Flags().IntVarP(&jobs, "jobs", "j", 1, "Number of parallel jobs (directory format only)")
+
+	return backupCmd
+}
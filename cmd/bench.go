@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func BenchCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var initTables bool
+	var scale int
+	var clients int
+	var threads int
+	var duration time.Duration
+	var builtin string
+	var scripts []string
+	var jsonOutput bool
+
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark the container with pgbench",
+		Long: `Run a pgbench workload against a running container and summarize its
+throughput (TPS) and latency, to compare extensions, settings or PostgreSQL
+versions on the same machine.
+
+The built-in workloads (` + strings.Join(orchestrator.BenchBuiltins, ", ") + `) run on
+the pgbench tables, which are created at --scale the first time; --init
+recreates them, e.g. at a different scale. --script runs SQL files from the
+host instead, each with an optional @weight; pass several to mix them. Scripts
+use pgbench's syntax, such as \set aid random(1, 100000 * :scale).
+
+pgbench reports progress every 5 seconds while it runs.`,
+		Example: `  # 10 seconds of the TPC-B-like workload with one client
+  pgbox bench
+
+  # Recreate the tables at scale 50 and run 8 clients on 4 threads for a minute
+  pgbox bench --init --scale 50 --clients 8 --threads 4 --time 1m
+
+  # Read-only workload
+  pgbox bench --builtin select-only -c 16 -j 4
+
+  # Mix two custom scripts, 9 reads for every write
+  pgbox bench --script read.sql@9 --script write.sql@1
+
+  # Machine-readable summary (latencies in milliseconds)
+  pgbox bench --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewBenchOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.BenchConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Init:          initTables,
+				Scale:         scale,
+				Clients:       clients,
+				Threads:       threads,
+				Duration:      duration,
+				Builtin:       builtin,
+				Scripts:       scripts,
+				JSON:          jsonOutput,
+			})
+		},
+	}
+
+	benchCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	benchCmd.Flags().StringVarP(&database, "database", "d", "", "Database to benchmark (default: container's POSTGRES_DB)")
+	benchCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	benchCmd.Flags().BoolVarP(&initTables, "init", "i", false, "Recreate the pgbench tables before the run")
+	benchCmd.Flags().IntVarP(&scale, "scale", "s", 1, "Scale factor of the pgbench tables (100,000 accounts each)")
+	benchCmd.Flags().IntVarP(&clients, "clients", "c", 1, "Number of concurrent clients")
+	benchCmd.Flags().IntVarP(&threads, "threads", "j", 1, "Number of pgbench worker threads")
+	benchCmd.Flags().DurationVarP(&duration, "time", "T", 10*time.Second, "How long to run the workload")
+	benchCmd.Flags().StringVarP(&builtin, "builtin", "b", "", "Built-in workload: "+strings.Join(orchestrator.BenchBuiltins, ", ")+" (default: tpcb-like)")
+	benchCmd.Flags().StringArrayVarP(&scripts, "script", "f", nil, "SQL script on the host to run, optionally file@weight (repeatable)")
+	benchCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the summary as JSON")
+	benchCmd.MarkFlagsMutuallyExclusive("builtin", "script")
+
+	return benchCmd
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func CheckCmd() *cobra.Command {
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check a database for common production problems",
+		Long: `Run checks that catch problems in development before they reach production.
+Each check exits with an error when it finds something, so it can gate CI.`,
+	}
+
+	checkCmd.AddCommand(checkSequencesCmd())
+
+	return checkCmd
+}
+
+func checkSequencesCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var threshold float64
+
+	sequencesCmd := &cobra.Command{
+		Use:   "sequences",
+		Short: "Find sequences and identity/serial columns close to overflowing",
+		Long: `Find sequences that have used --threshold percent or more of their range,
+taking the owning column's type into account, and serial columns whose
+sequence can produce values larger than the column can store (for example
+an integer column fed by a bigint sequence).`,
+		Example: `  # Report sequences that have used half their range
+  pgbox check sequences
+
+  # Stricter threshold for a specific database
+  pgbox check sequences -d mydb --threshold 25`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewCheckOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Sequences(cmd.Context(), orchestrator.CheckConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Threshold:     threshold,
+			})
+		},
+	}
+
+	sequencesCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	sequencesCmd.Flags().StringVarP(&database, "database", "d", "", "Database to check (default: container's POSTGRES_DB)")
+	sequencesCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	sequencesCmd.Flags().Float64Var(&threshold, "threshold", 50, "Report sequences that have used at least this percent of their range")
+
+	return sequencesCmd
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/orchestrator"
@@ -11,6 +12,14 @@ import (
 func CleanCmd() *cobra.Command {
 	var force bool
 	var all bool
+	var instance string
+	var match []string
+	var containers bool
+	var volumes bool
+	var images bool
+	var temp bool
+	var jsonOutput bool
+	var dryRun bool
 
 	cleanCmd := &cobra.Command{
 		Use:   "clean",
@@ -18,10 +27,23 @@ func CleanCmd() *cobra.Command {
 		Long: `Remove pgbox-related Docker containers and images to free up space and clear cache.
 
 By default, this command will:
-- Stop and remove all running pgbox containers
+- Stop and remove all running pgbox containers, and the files pgbox generated for them
 - Remove all pgbox Docker images
 
-Use --all to also remove PostgreSQL base images.`,
+Use --all to also remove PostgreSQL base images. Use --instance to remove only
+one named instance's container and data volume, leaving shared images alone.
+
+--containers, --volumes, --images and --temp limit the clean to those kinds of
+resource, and can be combined: --images alone frees disk space without
+touching data volumes. --temp covers the files pgbox left in /tmp and the
+generated files of containers that no longer exist. The confirmation prompt
+names the kinds that will be removed.
+
+For scripts, --match limits the clean to containers, data volumes (by their
+container's name) and images (by repository) matching a glob pattern. --json prints a summary of what was removed, what failed and the
+approximate disk space reclaimed, and needs --force. The exit status is
+non-zero when anything could not be removed. --dry-run lists the resources and
+the runtime commands that would remove them, and removes nothing.`,
 		Example: `  # Clean pgbox containers and images
   pgbox clean
 
@@ -29,18 +51,66 @@ Use --all to also remove PostgreSQL base images.`,
   pgbox clean --force
 
   # Clean everything including PostgreSQL base images
-  pgbox clean --all`,
+  pgbox clean --all
+
+  # List what would be removed, without removing anything
+  pgbox clean --dry-run
+
+  # Remove only images and temporary files, keeping containers and data
+  pgbox clean --images --temp
+
+  # Remove only the container and volume of a named instance
+  pgbox clean --instance shop
+
+  # In CI: remove this job's containers only and report what was removed
+  pgbox clean --match 'pgbox-ci-1234-*' --containers --force --json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := instanceContainerName(instance, "")
+			if err != nil {
+				return err
+			}
+			var scopes []string
+			for scope, selected := range map[string]bool{
+				orchestrator.CleanScopeContainers: containers,
+				orchestrator.CleanScopeVolumes:    volumes,
+				orchestrator.CleanScopeImages:     images,
+				orchestrator.CleanScopeTemp:       temp,
+			} {
+				if selected {
+					scopes = append(scopes, scope)
+				}
+			}
 			orch := orchestrator.NewCleanOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
-			return orch.Run(orchestrator.CleanConfig{
-				Force: force,
-				All:   all,
+			return orch.Run(cmd.Context(), orchestrator.CleanConfig{
+				Force:         force,
+				All:           all,
+				ContainerName: name,
+				Match:         match,
+				Scopes:        scopes,
+				JSON:          jsonOutput,
+				DryRun:        dryRun,
 			})
 		},
 	}
 
 	cleanCmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
 	cleanCmd.Flags().BoolVarP(&all, "all", "a", false, "Also remove PostgreSQL base images")
+	cleanCmd.Flags().StringVar(&instance, "instance", "", "Only remove this named instance's container and volume")
+	cleanCmd.Flags().StringArrayVar(&match, "match", nil, "Only remove resources whose name matches this glob pattern (repeatable)")
+	cleanCmd.Flags().BoolVar(&containers, "containers", false, "Remove containers (combinable with --volumes, --images and --temp)")
+	cleanCmd.Flags().BoolVar(&volumes, "volumes", false, "Remove data volumes")
+	cleanCmd.Flags().BoolVar(&images, "images", false, "Remove images")
+	cleanCmd.Flags().BoolVar(&temp, "temp", false, "Remove temporary and stale generated files")
+	// The single-kind flags clean had before the kinds could be combined
+	for flag, target := range map[string]*bool{"containers-only": &containers, "volumes-only": &volumes, "images-only": &images} {
+		cleanCmd.Flags().BoolVar(target, flag, false, "")
+		_ = cleanCmd.Flags().MarkDeprecated(flag, "use --"+strings.TrimSuffix(flag, "-only")+" instead")
+	}
+	cleanCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print a JSON summary of removed resources and reclaimed bytes (needs --force)")
+	cleanCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be removed and the commands that would remove it, without removing anything")
+	cleanCmd.MarkFlagsMutuallyExclusive("all", "instance")
+	cleanCmd.MarkFlagsMutuallyExclusive("json", "dry-run")
+	cleanCmd.MarkFlagsMutuallyExclusive("instance", "match")
 
 	return cleanCmd
 }
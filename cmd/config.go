@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func ConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with pgbox configuration files",
+	}
+
+	configCmd.AddCommand(configLintCmd())
+
+	return configCmd
+}
+
+func configLintCmd() *cobra.Command {
+	lintCmd := &cobra.Command{
+		Use:   "lint [pgbox.toml]",
+		Short: "Check pgbox.toml, the user config and extension specs for mistakes",
+		Long: `Check the configuration files pgbox reads without starting anything:
+pgbox.toml (found from the current directory, or the given file), the user
+config.toml and the custom extension specs from --ext-dir or $PGBOX_EXT_DIR.
+
+It reports TOML syntax errors and unknown keys, unsupported PostgreSQL
+versions, invalid ports, unknown extensions, extensions that are not
+available for the configured version, and settings with invalid names or,
+for the parameters pgbox documents (see pgbox guc), invalid values. Each
+problem is printed as file:line:column: message, and the command exits with
+an error when it finds any, so it can gate CI.`,
+		Example: `  # Lint the project's pgbox.toml and the user config
+  pgbox config lint
+
+  # Lint a specific file along with a directory of extension specs
+  pgbox config lint ./db/pgbox.toml --ext-dir ./pgbox-ext`,
+		Annotations: map[string]string{noDaemonAnnotation: "true", ownExtDirAnnotation: "true"},
+		Args:        cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			extDir, _ := cmd.Flags().GetString("ext-dir")
+			cfg := orchestrator.LintConfig{ExtDir: extensionDir(extDir)}
+			if len(args) == 1 {
+				cfg.ProjectFile = args[0]
+			}
+			return orchestrator.NewLintOrchestrator(cmd.OutOrStdout()).Run(cfg)
+		},
+	}
+
+	return lintCmd
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func CpCmd() *cobra.Command {
+	var containerName string
+	var instance string
+
+	cpCmd := &cobra.Command{
+		Use:   "cp <src> <dest>",
+		Short: "Copy files between the host and the container",
+		Long: `Copy a file or directory between the host and a pgbox container with
+docker cp, without looking up the container's name. Write the container side
+with a leading ":", e.g. :/tmp/data.csv; the container is the one given with
+-n/--instance, or the auto-detected one.
+
+Files copied into the container can be read by the server, e.g. with
+COPY ... FROM '/tmp/data.csv'.`,
+		Example: `  # Copy a CSV into the container and load it
+  pgbox cp ./data.csv :/tmp/data.csv
+  pgbox psql -- -c "COPY items FROM '/tmp/data.csv' WITH (FORMAT csv, HEADER)"
+
+  # Pull the settings ALTER SYSTEM wrote out of a named instance
+  pgbox cp --instance shop :/var/lib/postgresql/data/postgresql.auto.conf .`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := instanceContainerName(instance, containerName)
+			if err != nil {
+				return err
+			}
+			orch := orchestrator.NewCpOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.CpConfig{
+				ContainerName: name,
+				Source:        args[0],
+				Destination:   args[1],
+			})
+		},
+	}
+
+	cpCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	cpCmd.Flags().StringVar(&instance, "instance", "", "Named instance (container pgbox-<instance>)")
+	cpCmd.MarkFlagsMutuallyExclusive("name", "instance")
+
+	return cpCmd
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/orchestrator"
 	"github.com/spf13/cobra"
@@ -8,27 +10,82 @@ import (
 
 func DownCmd() *cobra.Command {
 	var containerName string
+	var all bool
+	var instance string
+	var remove bool
+	var volumes bool
+	var force bool
+	var dryRun bool
 
 	downCmd := &cobra.Command{
 		Use:   "down",
 		Short: "Stop a running PostgreSQL container",
 		Long: `Stop a running PostgreSQL container started with pgbox up.
 
-This command stops and removes the container but preserves any volumes.`,
+By default this only stops the container; its data volume is preserved. Use
+--rm to also remove the container, or --volumes to remove the container, its
+data volume and the custom image built for it. Removals ask for confirmation
+unless --force is given. --dry-run prints the runtime commands down would run
+without running them.`,
 		Example: `  # Stop the default pgbox container
   pgbox down
 
   # Stop a container with a custom name
-  pgbox down -n my-postgres`,
+  pgbox down -n my-postgres
+
+  # Stop a named instance started with pgbox up --instance
+  pgbox down --instance shop
+
+  # Stop and remove the container, keeping its data volume
+  pgbox down --rm
+
+  # Full teardown: container, data volume and custom image, without prompting
+  pgbox down --volumes --force
+
+  # See what a full teardown would remove
+  pgbox down --volumes --dry-run
+
+  # Stop every instance declared under [instances] in pgbox.toml
+  pgbox down --all`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			orch := orchestrator.NewDownOrchestrator(docker.NewClient(), cmd.OutOrStdout())
-			return orch.Run(orchestrator.DownConfig{
-				ContainerName: containerName,
+			if all {
+				project, err := loadProject(cmd)
+				if err != nil {
+					return err
+				}
+				instances, err := projectInstances(project)
+				if err != nil {
+					return err
+				}
+				return orchestrator.NewInstancesOrchestrator(docker.NewClient(), cmd.OutOrStdout()).Down(cmd.Context(), instances)
+			}
+
+			name, err := instanceContainerName(instance, containerName)
+			if err != nil {
+				return err
+			}
+			orch := orchestrator.NewDownOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.Run(cmd.Context(), orchestrator.DownConfig{
+				ContainerName: name,
+				Remove:        remove,
+				Volumes:       volumes,
+				Force:         force,
+				DryRun:        dryRun,
 			})
 		},
 	}
 
 	downCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name to stop (default: pgbox-pg<version>)")
+	downCmd.Flags().StringVar(&instance, "instance", "", "Named instance to stop (container pgbox-<instance>)")
+	downCmd.Flags().BoolVar(&all, "all", false, "Stop all [instances] from pgbox.toml")
+	downCmd.Flags().BoolVar(&remove, "rm", false, "Also remove the container")
+	downCmd.Flags().BoolVar(&volumes, "volumes", false, "Also remove the container, its data volume and custom image")
+	downCmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt for --rm and --volumes")
+	downCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the commands that would stop and remove resources without running them")
+	downCmd.MarkFlagsMutuallyExclusive("name", "instance")
+	downCmd.MarkFlagsMutuallyExclusive("all", "rm")
+	downCmd.MarkFlagsMutuallyExclusive("all", "volumes")
+	downCmd.MarkFlagsMutuallyExclusive("all", "dry-run")
 
 	return downCmd
 }
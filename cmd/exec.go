@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func ExecCmd() *cobra.Command {
+	var containerName string
+	var user string
+
+	execCmd := &cobra.Command{
+		Use:   "exec [flags] [-- command args...]",
+		Short: "Run a command inside the PostgreSQL container",
+		Long: `Run an arbitrary command inside a running PostgreSQL container, such as
+pg_dump, pg_basebackup or a shell. Without a command, starts bash.
+
+A TTY is allocated when stdin is a terminal, so interactive programs work and
+piped input is passed through.`,
+		Example: `  # Open a shell in the default container
+  pgbox exec
+
+  # Run pg_basebackup as the postgres OS user
+  pgbox exec -u postgres -- pg_basebackup -D /tmp/base -Ft
+
+  # Pipe a script into a command
+  pgbox exec -n my-postgres -- sh -c 'cat > /tmp/script.sql' < script.sql`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewExecOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.ExecConfig{
+				ContainerName: containerName,
+				User:          user,
+				Command:       args,
+			})
+		},
+	}
+
+	execCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	execCmd.Flags().StringVarP(&user, "user", "u", "", "OS user to run the command as (default: image default)")
+	// Stop parsing pgbox flags at the command so its own flags pass through
+	execCmd.Flags().SetInterspersed(false)
+
+	return execCmd
+}
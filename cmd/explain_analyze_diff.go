@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/ahacop/pgbox/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func ExplainAnalyzeDiffCmd() *cobra.Command {
+	var database string
+	var user string
+	var width int
+
+	explainCmd := &cobra.Command{
+		Use:   "explain-analyze-diff <container-a> <container-b> [query]",
+		Short: "Compare the plan and timing of a query on two containers",
+		Long: `Run the same query with EXPLAIN (ANALYZE, BUFFERS) on two running containers
+and print the plans side by side, followed by their planning and execution
+times.
+
+Use it to compare PostgreSQL versions (start them with pgbox up -v 16 and
+pgbox up -v 17), or the same schema with and without an index or extension.
+Lines marked * differ in more than costs, row counts and timings.
+
+The query runs in a transaction that is rolled back, so INSERT, UPDATE and
+DELETE statements leave no changes behind. It is read from stdin when not
+given as an argument.`,
+		Example: `  # Compare a query on PostgreSQL 16 and 17
+  pgbox explain-analyze-diff pgbox-pg16 pgbox-pg17 "SELECT count(*) FROM orders WHERE total > 100"
+
+  # Read the query from a file
+  pgbox explain-analyze-diff app-noindex app-index < query.sql`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var query string
+			if len(args) == 3 {
+				query = args[2]
+			} else if !util.StdinIsTerminal() {
+				input, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read query from stdin: %w", err)
+				}
+				query = string(input)
+			}
+
+			orch := orchestrator.NewExplainDiffOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.ExplainDiffConfig{
+				Containers: args[:2],
+				Query:      query,
+				Database:   database,
+				User:       user,
+				Width:      width,
+			})
+		},
+	}
+
+	explainCmd.Flags().StringVarP(&database, "database", "d", "", "Database name (default: each container's POSTGRES_DB)")
+	explainCmd.Flags().StringVarP(&user, "user", "u", "", "Username (default: each container's POSTGRES_USER)")
+	explainCmd.Flags().IntVar(&width, "width", 160, "Total width of the side-by-side output")
+
+	return explainCmd
+}
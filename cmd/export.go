@@ -2,62 +2,299 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/ahacop/pgbox/internal/util"
 	"github.com/spf13/cobra"
 )
 
 func ExportCmd() *cobra.Command {
 	var pgVersion string
 	var port string
-	var extList string
+	var extFlags []string
 	var baseImage string
+	var splitInit bool
+	var prefer []string
+	var setFlags []string
+	var envFlags []string
+	var profile string
+	var hardened bool
+	var format string
+	var ui []string
+	var composeProfiles bool
+	var replica bool
+	var pgbouncer bool
+	var interactive bool
+	var envFile bool
+	var adopt bool
+	var check bool
+	var arch string
+	var locale string
+	var encoding string
+	var timezone string
+	var initdbArgs []string
+	var ssl bool
 
 	exportCmd := &cobra.Command{
-		Use:   "export [directory]",
+		Use:   "export <directory> [extension...]",
 		Short: "Export Docker configuration to directory",
 		Long: `Export a Docker Compose configuration for PostgreSQL with optional extensions.
 
 This command generates a docker-compose.yml, Dockerfile, and init.sql that can be
-used independently of pgbox to run PostgreSQL with your chosen configuration.`,
+used independently of pgbox to run PostgreSQL with your chosen configuration.
+
+With --format devcontainer-feature it instead writes .devcontainer/devcontainer.json
+using the pgbox devcontainer feature, plus a pgbox.toml (unless one exists), so
+GitHub Codespaces and other devcontainer hosts install pgbox and start the
+database when the container boots.
+
+With --format devcontainer it adds PostgreSQL to an existing compose-based
+devcontainer instead: the db service goes to .devcontainer/docker-compose.pgbox.yml
+with its build files in .devcontainer/pgbox/, and .devcontainer/devcontainer.pgbox.json
+holds the dockerComposeFile, forwardPorts, postCreateCommand (waits for the
+database) and DATABASE_URL settings to merge into devcontainer.json.
+
+--env KEY=VALUE adds an environment variable to the db service. With
+--env-file, the environment values of db and the other services, including
+the credentials, go to a .env file next to the compose file instead, and the
+compose file refers to them as ${POSTGRES_USER} and so on. Variables already
+in an existing .env are kept.
+
+--locale, --encoding and --initdb-arg go to POSTGRES_INITDB_ARGS of the db
+service, which initdb reads when the data volume is created, and --timezone
+to its TZ. The postgres image only has the C and en_US.UTF-8 libc locales;
+for others, use ICU, as in --initdb-arg=--locale-provider=icu
+--initdb-arg=--icu-locale=tr-TR.
+
+--ssl writes a self-signed certificate and its key, server.crt and
+server.key, next to the compose file and turns on TLS in the db service with
+them. An existing pair is kept while it is valid. The key is a development
+key, but keep it out of version control all the same.
+
+Exporting again into the same directory prints what changed: files added or
+changed, with the packages, settings and init SQL fragments added or removed
+in pgbox's blocks. With --check nothing is written, and export fails if it
+would change any file, so CI can catch an export that drifted from
+pgbox.toml or the extension catalog.
+
+--adopt takes over the PostgreSQL service of a docker-compose.yml already in
+the directory: the service running a PostgreSQL image, or named db or
+postgres. It moves into a pgbox block at the top of services, and the other
+services and top-level keys stay as they are. The credentials, other
+environment values, ports, data volume, container name and -c settings of
+its command carry over unless given on the command line, as do keys pgbox
+does not generate, such as restart. The data volume only works with the
+PostgreSQL major version it was created with, so -v must match the service's
+image. Run export with --adopt again to update the service later.
+
+Extensions installed from .deb or .zip downloads are resolved for this
+machine's architecture. --arch amd64 or --arch arm64 picks another, for an
+image built elsewhere, and --arch all writes a Dockerfile that picks each
+download by the TARGETARCH of the build, so one export builds on both, as
+with docker buildx build --platform linux/amd64,linux/arm64.
+
+--interactive (-i) chooses the extensions in the same picker as pgbox up -i.
+
+--with-replica adds a replica service: a read-only streaming replica of db,
+published on the port after it. The db service mounts replica-setup.sh as an
+init script, which allows replication connections and creates the slot the
+replica streams from.
+
+--with-pgbouncer adds a pgbouncer service in transaction pooling mode in front
+of db, published on port 6432, with its generated pgbouncer.ini and
+userlist.txt written next to the compose file.
+
+Values not given on the command line come from PGBOX_* environment variables
+(PGBOX_VERSION, PGBOX_PORT, PGBOX_USER, PGBOX_PASSWORD, PGBOX_DATABASE,
+PGBOX_BASE_IMAGE), then from a pgbox.toml in the current directory or a parent.`,
 		Example: `  # Export basic PostgreSQL 17 configuration
   pgbox export ./my-postgres
 
   # Export with specific version and extensions
-  pgbox export ./my-postgres -v 16 --ext hypopg,pgvector
+  pgbox export ./my-postgres hypopg pgvector -v 16
 
   # Export with custom port
   pgbox export ./my-postgres -p 5433
 
+  # Export with the analytics tuning profile
+  pgbox export ./my-postgres --profile analytics
+
+  # Locked-down service: no-new-privileges, minimal capabilities, read-only
+  # root, SCRAM auth, generated password, port on 127.0.0.1
+  pgbox export ./my-postgres --hardened
+
+  # Bake PostgreSQL settings into the compose command and postgresql.conf.pgbox
+  pgbox export ./my-postgres --set shared_buffers=1GB --set max_connections=200
+
   # Export with custom base image
-  pgbox export ./my-postgres --base-image postgres:17-alpine`,
-		Args: cobra.ExactArgs(1),
+  pgbox export ./my-postgres --base-image postgres:17-alpine
+
+  # Keep credentials out of docker-compose.yml, in .env
+  pgbox export ./my-postgres --env-file --env TZ=UTC
+
+  # Create the database with the C locale and a Berlin time zone
+  pgbox export ./my-postgres --locale C --timezone Europe/Berlin
+
+  # TLS with a generated self-signed certificate
+  pgbox export ./my-postgres --ssl
+
+  # Fail in CI when the committed export is out of date
+  pgbox export ./my-postgres --check
+
+  # Take over the postgres service of an existing docker-compose.yml
+  pgbox export . --adopt --ext pgvector -v 16
+
+  # Export a Dockerfile that builds on both amd64 and arm64
+  pgbox export ./my-postgres --ext pg_search --arch all
+
+  # Pick the extensions interactively
+  pgbox export ./my-postgres -i
+
+  # Export one reviewable init file per extension
+  pgbox export ./my-postgres --ext pgvector,pg_cron --split-init
+
+  # Add pgAdmin to the compose file, and pgweb and adminer behind compose profiles
+  pgbox export ./my-postgres --with-ui pgadmin --compose-profiles
+
+  # Add a read-only replica service for testing read/write splitting
+  pgbox export ./my-postgres --with-replica
+
+  # Add a pgbouncer service to test transaction pooling
+  pgbox export ./my-postgres --with-pgbouncer
+
+  # Add the database to an existing VS Code devcontainer
+  pgbox export . --format devcontainer --ext pgvector
+
+  # Export a devcontainer that installs pgbox and starts the database in Codespaces
+  pgbox export . --format devcontainer-feature --ext pgvector`,
+		Annotations: noDaemon,
+		Args:        cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := ValidatePostgresVersion(pgVersion); err != nil {
+			project, err := loadProject(cmd)
+			if err != nil {
+				return err
+			}
+			extensions, err := ParseExtensionArgs(extFlags, args[1:])
+			if err != nil {
+				return err
+			}
+			userSettings, err := ParseSettings(setFlags)
+			if err != nil {
+				return err
+			}
+			env, err := ParseEnv(envFlags)
+			if err != nil {
 				return err
 			}
+			var settings map[string]string
+			if project != nil {
+				if len(args) == 1 {
+					fromProjectList(cmd, "ext", &extensions, project.Extensions)
+				}
+				fromProjectList(cmd, "prefer", &prefer, project.Prefer)
+				settings = project.Settings
+			}
+			settings = mergeSettings(settings, userSettings)
+
+			r, err := newResolver(cmd, project)
+			if err != nil {
+				return err
+			}
+			if pgVersion, err = resolveVersion(cmd, r); err != nil {
+				return err
+			}
+			if interactive {
+				if extensions, err = pickExtensions(cmd, pgVersion, "export", extensions); err != nil {
+					return err
+				}
+			}
+			port = resolve(cmd, r, config.KeyPort)
+			baseImage = resolve(cmd, r, config.KeyBaseImage)
+			user := resolve(cmd, r, config.KeyUser)
+			password := resolve(cmd, r, config.KeyPassword)
+			database := resolve(cmd, r, config.KeyDatabase)
+			if adopt {
+				// Let the adopted service's own values fill what was not given
+				for key, value := range map[string]*string{config.KeyPort: &port, config.KeyUser: &user, config.KeyPassword: &password, config.KeyDatabase: &database} {
+					if _, source := r.Get(key); source == config.SourceDefault {
+						*value = ""
+					}
+				}
+			}
+			if hardened {
+				// Let --hardened pick a non-default superuser and password
+				if _, source := r.Get(config.KeyUser); source == config.SourceDefault {
+					user = ""
+				}
+				if _, source := r.Get(config.KeyPassword); source == config.SourceDefault {
+					password = ""
+				}
+			}
 
-			extensions := ParseExtensionList(extList)
 			orch := orchestrator.NewExportOrchestrator(cmd.OutOrStdout())
 
-			return orch.Run(orchestrator.ExportConfig{
-				TargetDir:  args[0],
-				Version:    pgVersion,
-				Port:       port,
-				Extensions: extensions,
-				BaseImage:  baseImage,
-				User:       os.Getenv("PGBOX_USER"),
-				Password:   os.Getenv("PGBOX_PASSWORD"),
-				Database:   os.Getenv("PGBOX_DATABASE"),
-			})
+			cfg := orchestrator.ExportConfig{
+				TargetDir:       args[0],
+				Format:          format,
+				Version:         pgVersion,
+				Port:            port,
+				Extensions:      extensions,
+				BaseImage:       baseImage,
+				SplitInit:       splitInit,
+				Prefer:          prefer,
+				Profile:         profile,
+				Hardened:        hardened,
+				Settings:        settings,
+				UI:              ui,
+				User:            user,
+				Password:        password,
+				Database:        database,
+				ComposeProfiles: composeProfiles,
+				Replica:         replica,
+				Pgbouncer:       pgbouncer,
+				Env:             env,
+				EnvFile:         envFile,
+				Adopt:           adopt,
+				Check:           check,
+				Arch:            arch,
+				Locale:          locale,
+				Encoding:        encoding,
+				Timezone:        timezone,
+				InitdbArgs:      initdbArgs,
+				SSL:             ssl,
+			}
+			cfg.GUCChoices = promptGUCChoices(os.Stdin, cmd.OutOrStdout(), util.StdinIsTerminal(), orch.GUCConflicts(cfg))
+			return orch.Run(cfg)
 		},
 	}
 
-	exportCmd.Flags().StringVarP(&pgVersion, "version", "v", config.DefaultVersion, "PostgreSQL version (16, 17, or 18)")
+	exportCmd.Flags().StringVarP(&pgVersion, "version", "v", "", versionFlagUsage)
 	exportCmd.Flags().StringVarP(&port, "port", "p", "5432", "Port to expose PostgreSQL on")
-	exportCmd.Flags().StringVar(&extList, "ext", "", "Comma-separated list of extensions")
+	exportCmd.Flags().StringArrayVar(&extFlags, "ext", nil, "Extensions (repeatable or comma-separated; or give them after the directory)")
+	exportCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Choose extensions in an interactive picker with fuzzy search")
 	exportCmd.Flags().StringVar(&baseImage, "base-image", "", "Base Docker image (default: postgres:<version>)")
+	exportCmd.Flags().StringVar(&profile, "profile", "", profileFlagUsage())
+	exportCmd.Flags().BoolVar(&ssl, "ssl", false, "Turn on TLS in the db service with a generated self-signed certificate")
+	exportCmd.Flags().BoolVar(&hardened, "hardened", false, "Harden the service as pgbox up --hardened does")
+	exportCmd.Flags().StringArrayVar(&setFlags, "set", nil, "PostgreSQL setting as key=value, overriding extensions and pgbox.toml (repeatable)")
+	exportCmd.Flags().StringArrayVar(&envFlags, "env", nil, "Environment variable for the db service as KEY=VALUE (repeatable)")
+	exportCmd.Flags().BoolVar(&envFile, "env-file", false, "Write environment values, including credentials, to a .env file next to the compose file")
+	exportCmd.Flags().StringVar(&locale, "locale", "", "Locale of the data volume, passed to initdb (for example C or en_US.UTF-8)")
+	exportCmd.Flags().StringVar(&encoding, "encoding", "", "Encoding of the data volume, passed to initdb (for example UTF8 or LATIN1)")
+	exportCmd.Flags().StringVar(&timezone, "timezone", "", "Time zone (TZ) of the db service, such as Europe/Berlin")
+	exportCmd.Flags().StringArrayVar(&initdbArgs, "initdb-arg", nil, "Further initdb argument for the data volume, such as --initdb-arg=--data-checksums (repeatable)")
+	exportCmd.Flags().StringSliceVar(&prefer, "prefer", nil, "Resolve GUC conflicts in favor of an extension (<extension> or <guc>=<extension>)")
+	exportCmd.Flags().StringVar(&format, "format", orchestrator.FormatCompose, "Output format: "+strings.Join(orchestrator.ExportFormats, ", "))
+	exportCmd.Flags().StringVar(&arch, "arch", "", "Architecture to resolve .deb and .zip downloads for: "+strings.Join(orchestrator.ExportArches, ", ")+" (default: this machine's)")
+	exportCmd.Flags().StringSliceVar(&ui, "with-ui", nil, "Add database UI services that start with the database: "+strings.Join(orchestrator.UIToolNames(), ", "))
+	exportCmd.Flags().BoolVar(&replica, "with-replica", false, "Add a read-only streaming replica service on the port after the database's")
+	exportCmd.Flags().BoolVar(&pgbouncer, "with-pgbouncer", false, "Add a pgbouncer service in transaction pooling mode on port 6432")
+	exportCmd.Flags().BoolVar(&composeProfiles, "compose-profiles", false, "Add every other UI behind a compose profile named after it (docker-compose --profile pgweb up)")
+	exportCmd.Flags().BoolVar(&check, "check", false, "Change nothing, and fail if the export would change files in the directory")
+	exportCmd.Flags().BoolVar(&adopt, "adopt", false, "Take over the PostgreSQL service of an existing docker-compose.yml in the directory")
+	exportCmd.Flags().BoolVar(&splitInit, "split-init", false, "Write one numbered init file per extension to docker-entrypoint-initdb.d/")
 
 	return exportCmd
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/ahacop/pgbox/internal/util"
+	"github.com/spf13/cobra"
+)
+
+func ExtCmd() *cobra.Command {
+	extCmd := &cobra.Command{
+		Use:   "ext",
+		Short: "Enable or drop extensions in a running container",
+		Long: `Enable or drop catalog extensions in a running container without starting over.
+
+When the command targets the default container (no -n) and a pgbox.toml is
+found, its extensions list is updated to match, so the next pgbox up or
+export includes the change.`,
+	}
+
+	extCmd.AddCommand(extAddCmd())
+	extCmd.AddCommand(extRemoveCmd())
+
+	return extCmd
+}
+
+func extAddCmd() *cobra.Command {
+	var containerName string
+	var yes bool
+
+	addCmd := &cobra.Command{
+		Use:   "add <extension>...",
+		Short: "Enable extensions, rebuilding the image if they need it",
+		Long: `Enable extensions in a running container.
+
+Extensions the image already ships (such as the contrib extensions) are created
+in place with CREATE EXTENSION. Extensions that need packages or
+shared_preload_libraries require a rebuilt image: after confirmation the
+container is recreated from it with the same port, credentials and settings,
+keeping its data volume.`,
+		Example: `  # Enable a contrib extension in place
+  pgbox ext add pg_trgm
+
+  # Add pgvector, rebuilding the image without asking
+  pgbox ext add pgvector --yes`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := projectToSync(cmd)
+			if err != nil {
+				return err
+			}
+			var input io.Reader
+			if util.StdinIsTerminal() {
+				input = os.Stdin
+			}
+			orch := orchestrator.NewExtOrchestrator(docker.NewClient(), cmd.OutOrStdout(), input)
+			return orch.Add(cmd.Context(), orchestrator.ExtConfig{
+				ContainerName: containerName,
+				Extensions:    ParseExtensionList(strings.Join(args, ",")),
+				Project:       project,
+				Yes:           yes,
+			})
+		},
+	}
+
+	addCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	addCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Rebuild the image and recreate the container without asking")
+
+	return addCmd
+}
+
+func extRemoveCmd() *cobra.Command {
+	var containerName string
+	var cascade bool
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <extension>...",
+		Short: "Drop extensions",
+		Long: `Drop extensions from the container's database with DROP EXTENSION.
+
+Installed packages and shared_preload_libraries entries stay until the
+container is recreated.`,
+		Example: `  # Drop pgvector
+  pgbox ext remove pgvector
+
+  # Also drop the columns and indexes that use it
+  pgbox ext remove pgvector --cascade`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := projectToSync(cmd)
+			if err != nil {
+				return err
+			}
+			orch := orchestrator.NewExtOrchestrator(docker.NewClient(), cmd.OutOrStdout(), nil)
+			return orch.Remove(cmd.Context(), orchestrator.ExtConfig{
+				ContainerName: containerName,
+				Extensions:    ParseExtensionList(strings.Join(args, ",")),
+				Project:       project,
+				Cascade:       cascade,
+			})
+		},
+	}
+
+	removeCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	removeCmd.Flags().BoolVar(&cascade, "cascade", false, "Also drop objects that depend on the extensions")
+
+	return removeCmd
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func GrantsCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var role string
+	var diffFile string
+
+	grantsCmd := &cobra.Command{
+		Use:   "grants",
+		Short: "Show role memberships and privileges, or diff them against a file",
+		Long: `Show role memberships, privileges on the database, schemas, tables and
+sequences, and default privileges, as GRANT and ALTER DEFAULT PRIVILEGES
+statements. Owners' implicit privileges and built-in pg_ roles are left out.
+
+The output can be saved as a desired grants file. With --diff, pgbox compares
+the database against that file, printing missing grants with + and extra
+grants with -, and exits with an error when they differ.`,
+		Example: `  # Show all grants in the default database
+  pgbox grants
+
+  # Only what the app role can do
+  pgbox grants --role app
+
+  # Record the current grants, then check them in CI
+  pgbox grants > grants.sql
+  pgbox grants --diff grants.sql`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewGrantsOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.GrantsConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Role:          role,
+				DesiredFile:   diffFile,
+			})
+		},
+	}
+
+	grantsCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	grantsCmd.Flags().StringVarP(&database, "database", "d", "", "Database to inspect (default: container's POSTGRES_DB)")
+	grantsCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	grantsCmd.Flags().StringVar(&role, "role", "", "Only show grants to this role")
+	grantsCmd.Flags().StringVar(&diffFile, "diff", "", "Compare against a desired grants file")
+
+	return grantsCmd
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/ahacop/pgbox/internal/profiles"
+	"github.com/spf13/cobra"
+)
+
+func GucCmd() *cobra.Command {
+	var pgVersion string
+
+	gucCmd := &cobra.Command{
+		Use:   "guc <name>",
+		Short: "Explain a PostgreSQL configuration parameter",
+		Long: `Explain a PostgreSQL configuration parameter (GUC): what it does, when a
+change takes effect, its type, default, unit and range, and which pgbox
+extensions or flags set it on your behalf.
+
+The information comes from a catalog built into pgbox, so no container needs
+to be running. Parameters not in the catalog are matched by substring.`,
+		Example: `  # Why does pgbox set wal_level when I add wal2json?
+  pgbox guc wal_level
+
+  # Defaults differ between major versions
+  pgbox guc vacuum_buffer_usage_limit --version 16
+
+  # Find parameters by part of their name
+  pgbox guc cron`,
+		Annotations: noDaemon,
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := newResolver(cmd, nil)
+			if err != nil {
+				return err
+			}
+			pgVersion, err := resolveVersion(cmd, r)
+			if err != nil {
+				return err
+			}
+			return explainGUC(cmd.OutOrStdout(), args[0], pgVersion)
+		},
+	}
+
+	gucCmd.Flags().StringVarP(&pgVersion, "version", "v", "", versionFlagUsage)
+
+	return gucCmd
+}
+
+func explainGUC(w io.Writer, name, version string) error {
+	name = strings.ToLower(name)
+	param, ok := extensions.GetParameter(name, version)
+	setters := extensions.GetGUCSetters(name)
+	profileValue, inProfile := orchestrator.FastUnsafeSettings[name]
+	hardenedValue, inHardened := orchestrator.HardenedSettings[name]
+	var inProfiles []string
+	for _, profile := range profiles.Names() {
+		if _, ok := profiles.Catalog[profile].Settings[name]; ok {
+			inProfiles = append(inProfiles, profile)
+		}
+	}
+
+	if !ok && len(setters) == 0 && !inProfile && !inHardened && len(inProfiles) == 0 {
+		if _, exists := extensions.Parameters[name]; exists {
+			return fmt.Errorf("parameter %s does not exist in PostgreSQL %s", name, version)
+		}
+		matches := extensions.SearchParameters(name)
+		if len(matches) == 0 {
+			return fmt.Errorf("unknown parameter: %s", name)
+		}
+		_, _ = fmt.Fprintf(w, "No parameter named %s. Matching parameters:\n", name)
+		for _, match := range matches {
+			_, _ = fmt.Fprintf(w, "  %s\n", match)
+		}
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(w, "%s (PostgreSQL %s)\n", name, version)
+	if ok {
+		_, _ = fmt.Fprintf(w, "  %s\n\n", param.Description)
+		if param.Extension != "" {
+			_, _ = fmt.Fprintf(w, "  Extension: %s\n", param.Extension)
+		}
+		_, _ = fmt.Fprintf(w, "  Type:      %s\n", param.Type)
+		_, _ = fmt.Fprintf(w, "  Context:   %s (%s)\n", param.Context, gucContextEffect(param.Context))
+		def := param.Default
+		if def == "" {
+			def = "(empty)"
+		} else if param.Unit != "" {
+			def += " " + param.Unit
+		}
+		_, _ = fmt.Fprintf(w, "  Default:   %s\n", def)
+		if param.Min != "" || param.Max != "" {
+			_, _ = fmt.Fprintf(w, "  Range:     %s to %s\n", param.Min, param.Max)
+		}
+		if len(param.Values) > 0 {
+			_, _ = fmt.Fprintf(w, "  Values:    %s\n", strings.Join(param.Values, ", "))
+		}
+	} else {
+		_, _ = fmt.Fprintln(w, "  Not documented in the pgbox parameter catalog.")
+	}
+
+	if len(setters) > 0 || inProfile || inHardened || len(inProfiles) > 0 {
+		_, _ = fmt.Fprintln(w, "\nSet by pgbox:")
+		names := make([]string, 0, len(setters))
+		for ext := range setters {
+			names = append(names, ext)
+		}
+		sort.Strings(names)
+		for _, ext := range names {
+			_, _ = fmt.Fprintf(w, "  %s = %s with --ext %s\n", name, setters[ext], ext)
+		}
+		for _, profile := range inProfiles {
+			_, _ = fmt.Fprintf(w, "  %s = %s with --profile %s\n", name, profiles.Catalog[profile].Settings[name], profile)
+		}
+		if inProfile {
+			_, _ = fmt.Fprintf(w, "  %s = %s with --fast-unsafe\n", name, profileValue)
+		}
+		if inHardened {
+			_, _ = fmt.Fprintf(w, "  %s = %s with --hardened\n", name, hardenedValue)
+		}
+	}
+	return nil
+}
+
+// gucContextEffect describes when a change to a parameter with the given
+// pg_settings context takes effect.
+func gucContextEffect(context string) string {
+	switch context {
+	case "postmaster":
+		return "needs a restart: pgbox restart"
+	case "sighup":
+		return "applies on reload: pgbox reload"
+	case "superuser":
+		return "superusers can change it per session; pgbox reload sets the default"
+	case "user":
+		return "any user can change it per session; pgbox reload sets the default"
+	default:
+		return "see the PostgreSQL documentation"
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuc_ExplainsParameterAndSetters(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := GucCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"wal_level"})
+
+	require.NoError(t, cmd.Execute())
+
+	output := buf.String()
+	assert.Contains(t, output, "wal_level (PostgreSQL 18)")
+	assert.Contains(t, output, "Context:   postmaster (needs a restart: pgbox restart)")
+	assert.Contains(t, output, "Default:   replica")
+	assert.Contains(t, output, "Values:    minimal, replica, logical")
+	assert.Contains(t, output, "wal_level = logical with --ext wal2json")
+}
+
+func TestGuc_VersionSpecificDefault(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := GucCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"vacuum_buffer_usage_limit", "--version", "16"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, buf.String(), "Default:   256 kB")
+}
+
+func TestGuc_FastUnsafe(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, explainGUC(&buf, "fsync", "17"))
+
+	assert.Contains(t, buf.String(), "fsync = off with --fast-unsafe")
+}
+
+func TestGuc_NotInVersion(t *testing.T) {
+	err := explainGUC(&bytes.Buffer{}, "old_snapshot_threshold", "17")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist in PostgreSQL 17")
+}
+
+func TestGuc_SearchesBySubstring(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, explainGUC(&buf, "cron", "18"))
+
+	assert.Contains(t, buf.String(), "cron.database_name")
+	assert.Contains(t, buf.String(), "cron.max_running_jobs")
+}
+
+func TestGuc_Unknown(t *testing.T) {
+	err := explainGUC(&bytes.Buffer{}, "no_such_setting", "18")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown parameter")
+}
+
+func TestGuc_Profiles(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, explainGUC(&buf, "fsync", "17"))
+
+	assert.Contains(t, buf.String(), "fsync = off with --profile ci")
+	assert.Contains(t, buf.String(), "fsync = off with --profile test")
+}
@@ -1,12 +1,29 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/container"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/picker"
+	"github.com/ahacop/pgbox/internal/profiles"
+	"github.com/ahacop/pgbox/internal/util"
+	"github.com/spf13/cobra"
 )
 
 // ValidPostgresVersions contains the supported PostgreSQL versions.
-var ValidPostgresVersions = []string{"16", "17", "18"}
+var ValidPostgresVersions = config.SupportedVersions
+
+// versionFlagUsage describes the --version flag of commands that resolve the
+// version with resolveVersion.
+const versionFlagUsage = "PostgreSQL version: 16, 17, 18 or latest (default: pgbox.toml, then default_version in ~/.config/pgbox/config.toml, then " + config.DefaultVersion + ")"
 
 // ValidatePostgresVersion checks if the given version is a supported PostgreSQL version.
 func ValidatePostgresVersion(version string) error {
@@ -31,3 +48,204 @@ func ParseExtensionList(extList string) []string {
 	}
 	return result
 }
+
+// ParseExtensionArgs combines the values of a repeatable --ext flag, each of
+// which may be a comma-separated list, with extensions given as positional
+// arguments. Giving both, an empty name, a name more than once or a version
+// number as an argument is an error.
+func ParseExtensionArgs(flags, args []string) ([]string, error) {
+	if len(flags) > 0 && len(args) > 0 {
+		return nil, fmt.Errorf("extensions given both with --ext (%s) and as arguments (%s); use one or the other",
+			strings.Join(flags, ","), strings.Join(args, " "))
+	}
+
+	var result []string
+	add := func(value, origin string) error {
+		for _, name := range ParseExtensionList(value) {
+			if name == "" {
+				return fmt.Errorf("empty extension name in %s %q", origin, value)
+			}
+			if slices.Contains(result, name) {
+				return fmt.Errorf("extension %s given more than once", name)
+			}
+			result = append(result, name)
+		}
+		return nil
+	}
+	for _, value := range flags {
+		// --ext "" explicitly asks for no extensions
+		if value == "" {
+			continue
+		}
+		if err := add(value, "--ext"); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range args {
+		if _, err := strconv.Atoi(arg); err == nil {
+			return nil, fmt.Errorf("%s is not an extension; use -v %s to choose the PostgreSQL version", arg, arg)
+		}
+		if err := add(arg, "argument"); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// instanceContainerName returns the container for an --instance label, or
+// name unchanged when no instance is given.
+func instanceContainerName(instance, name string) (string, error) {
+	if instance == "" {
+		return name, nil
+	}
+	return container.InstanceName(instance)
+}
+
+// ParseSettings parses repeated key=value flags into a settings map.
+// Returns an error for malformed entries or keys given more than once.
+func ParseSettings(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	settings := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q (expected key=value)", v)
+		}
+		if prev, dup := settings[key]; dup {
+			return nil, fmt.Errorf("--set %s given more than once ('%s' and '%s')", key, prev, value)
+		}
+		settings[key] = strings.TrimSpace(value)
+	}
+	return settings, nil
+}
+
+// ParseEnv parses repeated --env KEY=VALUE flags into a map. Values are
+// kept as given, including surrounding spaces.
+func ParseEnv(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env %q (expected KEY=VALUE)", v)
+		}
+		if prev, dup := env[key]; dup {
+			return nil, fmt.Errorf("--env %s given more than once ('%s' and '%s')", key, prev, value)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// mergeSettings returns the pgbox.toml [settings] with the --set values on
+// top, so a flag wins over the project file for the same key.
+func mergeSettings(project, flags map[string]string) map[string]string {
+	if len(flags) == 0 {
+		return project
+	}
+	merged := make(map[string]string, len(project)+len(flags))
+	for key, value := range project {
+		merged[key] = value
+	}
+	for key, value := range flags {
+		merged[key] = value
+	}
+	return merged
+}
+
+// profileFlagUsage describes the --profile flag.
+func profileFlagUsage() string {
+	return "Tuning profile for common scenarios: " + strings.Join(profiles.Names(), ", ") + " (overrides extension settings; --set overrides it)"
+}
+
+// pickExtensions lets the user choose extensions for version in the
+// interactive picker, starting from the ones already requested. action names
+// what enter does, such as "start".
+func pickExtensions(cmd *cobra.Command, version, action string, requested []string) ([]string, error) {
+	if !util.StdinIsTerminal() {
+		return nil, fmt.Errorf("--interactive needs a terminal")
+	}
+	title := fmt.Sprintf("Extensions for PostgreSQL %s: type to filter, space to toggle, enter to %s, esc to cancel", version, action)
+	chosen, err := picker.Run(os.Stdin, cmd.OutOrStdout(), picker.New(title, extensionItems(version), requested))
+	if err != nil {
+		return nil, fmt.Errorf("extension picker: %w", err)
+	}
+	return chosen, nil
+}
+
+// extensionItems lists the catalog extensions available for version, tagged
+// preload when they need shared_preload_libraries and restart when they only
+// need other server settings; either takes a server restart.
+func extensionItems(version string) []picker.Item {
+	var items []picker.Item
+	for _, name := range extensions.ListExtensions() {
+		if extensions.ValidateVersion([]string{name}, version) != nil {
+			continue
+		}
+		ext, _ := extensions.Get(name)
+		var tags []string
+		if len(ext.Preload) > 0 {
+			tags = append(tags, "preload")
+		} else if len(ext.GUCs) > 0 {
+			tags = append(tags, "restart")
+		}
+		items = append(items, picker.Item{Name: name, Description: extensions.Describe(name), Tags: tags})
+	}
+	return items
+}
+
+// promptGUCChoices asks, when stdin is a terminal, which value to use for
+// each GUC in conflictErr: one of the extensions' values or a custom one.
+// Returns the values picked by GUC, or nil when there is nothing to ask or
+// the answers cannot be read, leaving the run to report the conflict.
+func promptGUCChoices(in io.Reader, out io.Writer, interactive bool, conflictErr *extensions.ConflictError) map[string]string {
+	if !interactive || conflictErr == nil {
+		return nil
+	}
+	reader := bufio.NewReader(in)
+	choices := make(map[string]string)
+
+	for _, c := range conflictErr.Conflicts {
+		_, _ = fmt.Fprintf(out, "\nGUC conflict for %s:\n", c.Key)
+		for i, ext := range c.Extensions {
+			_, _ = fmt.Fprintf(out, "  %d) %s (from %s)\n", i+1, c.Values[ext], ext)
+		}
+		custom := len(c.Extensions) + 1
+		_, _ = fmt.Fprintf(out, "  %d) custom value\n", custom)
+
+		for choices[c.Key] == "" {
+			_, _ = fmt.Fprintf(out, "Choose [1-%d]: ", custom)
+			response, err := reader.ReadString('\n')
+			if err != nil {
+				return nil
+			}
+			n, convErr := strconv.Atoi(strings.TrimSpace(response))
+			switch {
+			case convErr != nil || n < 1 || n > custom:
+				continue
+			case n < custom:
+				choices[c.Key] = c.Values[c.Extensions[n-1]]
+				continue
+			}
+			_, _ = fmt.Fprintf(out, "Value for %s: ", c.Key)
+			if response, err = reader.ReadString('\n'); err != nil {
+				return nil
+			}
+			choices[c.Key] = strings.TrimSpace(response)
+		}
+	}
+
+	return choices
+}
+
+// runningInCI reports whether pgbox runs in a CI job. GitHub Actions, GitLab
+// CI, CircleCI, Travis and most other CI systems set CI.
+func runningInCI() bool {
+	ci := strings.ToLower(os.Getenv("CI"))
+	return ci != "" && ci != "false" && ci != "0"
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/picker"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConflictError() *extensions.ConflictError {
+	return &extensions.ConflictError{Conflicts: []extensions.GUCConflict{{
+		Key:        "wal_level",
+		Extensions: []string{"wal2json", "other"},
+		Values:     map[string]string{"wal2json": "logical", "other": "replica"},
+	}}}
+}
+
+func TestPromptGUCChoices(t *testing.T) {
+	var out bytes.Buffer
+
+	// An invalid answer is asked again
+	choices := promptGUCChoices(strings.NewReader("7\n2\n"), &out, true, testConflictError())
+
+	assert.Equal(t, map[string]string{"wal_level": "replica"}, choices)
+	assert.Contains(t, out.String(), "1) logical (from wal2json)")
+	assert.Contains(t, out.String(), "2) replica (from other)")
+	assert.Contains(t, out.String(), "3) custom value")
+}
+
+func TestPromptGUCChoices_CustomValue(t *testing.T) {
+	var out bytes.Buffer
+
+	choices := promptGUCChoices(strings.NewReader("3\n\n3\nminimal\n"), &out, true, testConflictError())
+
+	assert.Equal(t, map[string]string{"wal_level": "minimal"}, choices, "an empty custom value is asked again")
+	assert.Contains(t, out.String(), "Value for wal_level: ")
+}
+
+func TestPromptGUCChoices_NothingToAsk(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.Nil(t, promptGUCChoices(strings.NewReader("1\n"), &out, false, testConflictError()), "not a terminal")
+	assert.Nil(t, promptGUCChoices(strings.NewReader("1\n"), &out, true, nil), "no conflict")
+	assert.Empty(t, out.String())
+	assert.Nil(t, promptGUCChoices(strings.NewReader(""), &out, true, testConflictError()), "no answer")
+}
+
+func TestParseSettings(t *testing.T) {
+	settings, err := ParseSettings([]string{"work_mem=64MB", "search_path=a,b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"work_mem": "64MB", "search_path": "a,b"}, settings)
+
+	_, err = ParseSettings([]string{"work_mem"})
+	assert.ErrorContains(t, err, "expected key=value")
+
+	_, err = ParseSettings([]string{"work_mem=1MB", "work_mem=2MB"})
+	assert.ErrorContains(t, err, "given more than once")
+}
+
+func TestMergeSettings(t *testing.T) {
+	project := map[string]string{"work_mem": "64MB", "max_connections": "50"}
+
+	assert.Equal(t, project, mergeSettings(project, nil))
+	assert.Equal(t, map[string]string{"work_mem": "128MB", "max_connections": "50", "shared_buffers": "1GB"},
+		mergeSettings(project, map[string]string{"work_mem": "128MB", "shared_buffers": "1GB"}))
+	assert.Equal(t, "64MB", project["work_mem"], "the project settings are not modified")
+}
+
+func TestParseExtensionArgs(t *testing.T) {
+	exts, err := ParseExtensionArgs([]string{"pgvector", "pg_cron, hypopg"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgvector", "pg_cron", "hypopg"}, exts)
+
+	exts, err = ParseExtensionArgs(nil, []string{"pgvector", "pg_cron"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgvector", "pg_cron"}, exts)
+
+	exts, err = ParseExtensionArgs([]string{""}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, exts)
+
+	_, err = ParseExtensionArgs([]string{"pgvector"}, []string{"pg_cron"})
+	assert.ErrorContains(t, err, "both with --ext (pgvector) and as arguments (pg_cron)")
+
+	_, err = ParseExtensionArgs([]string{"pgvector,,pg_cron"}, nil)
+	assert.ErrorContains(t, err, `empty extension name in --ext "pgvector,,pg_cron"`)
+
+	_, err = ParseExtensionArgs([]string{"pgvector", "pgvector"}, nil)
+	assert.ErrorContains(t, err, "extension pgvector given more than once")
+
+	_, err = ParseExtensionArgs(nil, []string{"17"})
+	assert.ErrorContains(t, err, "use -v 17 to choose the PostgreSQL version")
+}
+
+func TestValidatePostgresVersion(t *testing.T) {
+	for _, v := range []string{"16", "17", "18"} {
+		assert.NoError(t, ValidatePostgresVersion(v))
+	}
+	assert.ErrorContains(t, ValidatePostgresVersion("15"), "must be 16, 17, or 18")
+	assert.Error(t, ValidatePostgresVersion("19"))
+}
+
+func TestNeedsDaemon(t *testing.T) {
+	root := RootCmd()
+	find := func(args ...string) *cobra.Command {
+		cmd, _, err := root.Find(args)
+		require.NoError(t, err)
+		return cmd
+	}
+
+	assert.True(t, needsDaemon(find("up")))
+	assert.True(t, needsDaemon(find("check", "sequences")))
+	assert.False(t, needsDaemon(root))
+	assert.False(t, needsDaemon(find("guc")))
+	assert.False(t, needsDaemon(find("export")))
+	assert.False(t, needsDaemon(find("list-extensions")))
+}
+
+func TestRunningInCI(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "true": true, "1": true, "false": false, "0": false, "FALSE": false} {
+		t.Setenv("CI", value)
+		assert.Equal(t, want, runningInCI(), "CI=%q", value)
+	}
+}
+
+func TestExtensionItems(t *testing.T) {
+	items := map[string]picker.Item{}
+	for _, item := range extensionItems("17") {
+		items[item.Name] = item
+	}
+
+	assert.NotContains(t, items, "adminpack")
+	assert.Contains(t, extensionItems("16"), items["hstore"])
+	assert.Equal(t, []string{"preload"}, items["pg_cron"].Tags)
+	assert.Empty(t, items["hstore"].Tags)
+	assert.Equal(t, extensions.Describe("pg_cron"), items["pg_cron"].Description)
+}
+
+func TestParseEnv(t *testing.T) {
+	env, err := ParseEnv([]string{"TZ=UTC", "GREETING= hi there", "EMPTY="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"TZ": "UTC", "GREETING": " hi there", "EMPTY": ""}, env)
+
+	_, err = ParseEnv([]string{"TZ"})
+	assert.EqualError(t, err, `invalid --env "TZ" (expected KEY=VALUE)`)
+
+	_, err = ParseEnv([]string{"TZ=UTC", "TZ=CET"})
+	assert.EqualError(t, err, "--env TZ given more than once ('UTC' and 'CET')")
+}
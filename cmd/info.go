@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/spf13/cobra"
+)
+
+func InfoCmd() *cobra.Command {
+	var tipsOnly bool
+	var jsonOutput bool
+
+	infoCmd := &cobra.Command{
+		Use:     "info <extension>",
+		Aliases: []string{"inspect"},
+		Short:   "Show what an extension needs, its documentation and tips",
+		Long: `Show how pgbox installs a catalog extension: where its package comes from
+and the package or download for each PostgreSQL version, its CREATE EXTENSION
+name and init SQL, the base image it needs and whether pgbox up builds a custom
+image for it, the shared_preload_libraries entries and settings it adds, the
+PostgreSQL versions it supports, and a link to its documentation. Extensions
+from --ext-dir specs are shown the same way.
+
+Use --tips to print only the getting-started tips that pgbox up shows after
+starting a container with the extension, or --json for everything in a form
+scripts can read.`,
+		Example: `  # What does pg_cron change?
+  pgbox info pg_cron
+
+  # How do I start using pgvector?
+  pgbox info pgvector --tips
+
+  # Which package does pgvector install on each version?
+  pgbox inspect pgvector --json | jq '.packages'`,
+		Annotations: noDaemon,
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if jsonOutput {
+				return showExtensionInfoJSON(cmd.OutOrStdout(), args[0])
+			}
+			return showExtensionInfo(cmd.OutOrStdout(), args[0], tipsOnly)
+		},
+	}
+
+	infoCmd.Flags().BoolVar(&tipsOnly, "tips", false, "Only show the getting-started tips and documentation link")
+	infoCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print everything pgbox knows about the extension as JSON")
+	infoCmd.MarkFlagsMutuallyExclusive("tips", "json")
+
+	return infoCmd
+}
+
+// extensionInfo is what info --json prints.
+type extensionInfo struct {
+	Name        string             `json:"name"`
+	Source      string             `json:"source"`
+	Spec        string             `json:"spec,omitempty"`
+	SQLName     string             `json:"sql_name"`
+	Category    string             `json:"category,omitempty"`
+	Packages    []extensionPackage `json:"packages"`
+	ApkPackage  string             `json:"apk_package,omitempty"` // Installed instead on Alpine base images
+	BaseImage   string             `json:"base_image"`
+	CustomImage bool               `json:"custom_image"` // pgbox up builds an image for it
+	Preload     []string           `json:"preload"`
+	GUCs        map[string]string  `json:"gucs"`
+	InitSQL     string             `json:"init_sql"`
+	Scripts     []initdbScript     `json:"initdb_scripts,omitempty"`
+	Versions    []string           `json:"versions"`
+	DocURL      string             `json:"doc_url,omitempty"`
+	Tips        []string           `json:"tips"`
+}
+
+// extensionPackage is the apt package or download an extension installs for
+// one PostgreSQL version and, for downloads, one architecture.
+type extensionPackage struct {
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+	Package string `json:"package"`
+}
+
+// initdbScript is a shell fragment an extension runs when a new data volume
+// is initialized.
+type initdbScript struct {
+	Name string `json:"name"`
+	Run  string `json:"run"`
+}
+
+// inspectExtension collects what the catalog says about an extension.
+func inspectExtension(name string) (extensionInfo, error) {
+	ext, ok := extensions.Get(name)
+	if !ok {
+		return extensionInfo{}, fmt.Errorf("unknown extension: %s. See pgbox list-extensions", name)
+	}
+	versions := []string{}
+	for _, version := range config.SupportedVersions {
+		if ext.SupportsVersion(version) {
+			versions = append(versions, version)
+		}
+	}
+
+	info := extensionInfo{
+		Name:        name,
+		Source:      extensionSource(ext),
+		Spec:        ext.File,
+		SQLName:     extensions.GetSQLName(name),
+		Category:    extensions.Category(name),
+		Packages:    []extensionPackage{},
+		BaseImage:   "postgres:<version>",
+		ApkPackage:  strings.ReplaceAll(ext.ApkPackage, "{v}", "<version>"),
+		CustomImage: ext.Package != "" || ext.ApkPackage != "" || extensions.HasDebURL(name) || extensions.HasZipURL(name),
+		Preload:     append([]string{}, ext.Preload...),
+		GUCs:        map[string]string{},
+		InitSQL:     extensions.GetInitSQL(name),
+		Versions:    versions,
+		DocURL:      ext.DocURL,
+		Tips:        append([]string{}, ext.Tips...),
+	}
+	if ext.BaseImage != "" {
+		info.BaseImage = strings.ReplaceAll(ext.BaseImage, "{v}", "<version>")
+	}
+	for _, script := range extensions.GetInitdbScripts(name) {
+		info.Scripts = append(info.Scripts, initdbScript{Name: script.Name, Run: script.Run})
+	}
+	for key, value := range ext.GUCs {
+		info.GUCs[key] = value
+	}
+	for _, version := range versions {
+		if pkg := extensions.GetPackage(name, version); pkg != "" {
+			info.Packages = append(info.Packages, extensionPackage{Version: version, Package: pkg})
+		}
+		for _, arch := range []string{"amd64", "arm64"} {
+			url := extensions.GetDebURL(name, version, arch)
+			if url == "" {
+				url = extensions.GetZipURL(name, version, arch)
+			}
+			if url != "" {
+				info.Packages = append(info.Packages, extensionPackage{Version: version, Arch: arch, Package: url})
+			}
+		}
+	}
+	return info, nil
+}
+
+func showExtensionInfoJSON(w io.Writer, name string) error {
+	info, err := inspectExtension(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(info)
+}
+
+func showExtensionInfo(w io.Writer, name string, tipsOnly bool) error {
+	info, err := inspectExtension(name)
+	if err != nil {
+		return err
+	}
+	ext, _ := extensions.Get(name)
+
+	if tipsOnly {
+		if len(ext.Tips) == 0 {
+			_, _ = fmt.Fprintf(w, "No tips for %s.\n", name)
+		}
+		for _, tip := range ext.Tips {
+			_, _ = fmt.Fprintf(w, "- %s\n", tip)
+		}
+		if ext.DocURL != "" {
+			_, _ = fmt.Fprintf(w, "Docs: %s\n", ext.DocURL)
+		}
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(w, "%s\n", name)
+	_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Source:", extensionSource(ext))
+	if ext.File != "" {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Spec:", ext.File)
+	}
+	_, _ = fmt.Fprintf(w, "  %-10s %s\n", "SQL name:", info.SQLName)
+	if info.Category != "" {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Category:", info.Category)
+	}
+	if len(info.Packages) > 0 {
+		_, _ = fmt.Fprintln(w, "  Packages:")
+		for _, pkg := range info.Packages {
+			key := pkg.Version
+			if pkg.Arch != "" {
+				key += "/" + pkg.Arch
+			}
+			_, _ = fmt.Fprintf(w, "    %s: %s\n", key, pkg.Package)
+		}
+	}
+	if info.ApkPackage != "" {
+		_, _ = fmt.Fprintf(w, "  %-10s apk (%s)\n", "Alpine:", info.ApkPackage)
+	}
+	image := info.BaseImage
+	if info.CustomImage {
+		image = "custom, built by pgbox up from " + image
+	}
+	_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Image:", image)
+	if len(ext.Preload) > 0 {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Preload:", strings.Join(ext.Preload, ", "))
+	}
+	if len(ext.GUCs) > 0 {
+		keys := make([]string, 0, len(ext.GUCs))
+		for key := range ext.GUCs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		_, _ = fmt.Fprintln(w, "  Settings:")
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "    %s = %s\n", key, ext.GUCs[key])
+		}
+	}
+	_, _ = fmt.Fprintln(w, "  Init SQL:")
+	for _, line := range strings.Split(strings.TrimSpace(info.InitSQL), "\n") {
+		_, _ = fmt.Fprintf(w, "    %s\n", line)
+	}
+	if len(info.Scripts) > 0 {
+		_, _ = fmt.Fprintln(w, "  Initdb scripts:")
+		for _, script := range info.Scripts {
+			_, _ = fmt.Fprintf(w, "    %s:\n", script.Name)
+			for _, line := range strings.Split(strings.TrimSpace(script.Run), "\n") {
+				_, _ = fmt.Fprintf(w, "      %s\n", line)
+			}
+		}
+	}
+	versions := "all supported"
+	if len(ext.Versions) > 0 {
+		versions = strings.Join(ext.Versions, ", ")
+	}
+	_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Versions:", versions)
+	if ext.DocURL != "" {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", "Docs:", ext.DocURL)
+	}
+	if len(ext.Tips) > 0 {
+		_, _ = fmt.Fprintln(w, "  Tips:")
+		for _, tip := range ext.Tips {
+			_, _ = fmt.Fprintf(w, "    - %s\n", tip)
+		}
+	}
+	return nil
+}
+
+// extensionSource describes where an extension's files come from.
+func extensionSource(ext extensions.Extension) string {
+	switch {
+	case ext.DebURL != "" || len(ext.Debs) > 0:
+		return "downloaded .deb"
+	case ext.ZipURL != "" || len(ext.Zips) > 0:
+		return "downloaded .zip"
+	case ext.Package != "":
+		return fmt.Sprintf("apt (%s)", strings.ReplaceAll(ext.Package, "{v}", "<version>"))
+	}
+	return "builtin"
+}
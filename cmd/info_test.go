@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoCmd(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := InfoCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"pg_cron"})
+
+	require.NoError(t, cmd.Execute())
+
+	out := buf.String()
+	assert.Contains(t, out, "Source:    apt (postgresql-<version>-cron)")
+	assert.Contains(t, out, "  Packages:\n    16: postgresql-16-cron\n    17: postgresql-17-cron\n")
+	assert.Contains(t, out, "Image:     custom, built by pgbox up from postgres:<version>")
+	assert.Contains(t, out, "  Init SQL:\n    CREATE EXTENSION IF NOT EXISTS pg_cron;\n    GRANT USAGE ON SCHEMA cron TO postgres;\n")
+	assert.Contains(t, out, "Preload:   pg_cron")
+	assert.Contains(t, out, "    cron.database_name = postgres")
+	assert.Contains(t, out, "Docs:      https://github.com/citusdata/pg_cron")
+	assert.Contains(t, out, "    - Schedule a job: SELECT cron.schedule(")
+}
+
+func TestInfoCmd_Tips(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := InfoCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"wal2json", "--tips"})
+
+	require.NoError(t, cmd.Execute())
+
+	out := buf.String()
+	assert.Contains(t, out, "- Create a slot: SELECT pg_create_logical_replication_slot('slot_name', 'wal2json');\n")
+	assert.Contains(t, out, "Docs: https://github.com/eulerto/wal2json\n")
+	assert.NotContains(t, out, "Source:")
+}
+
+func TestInfoCmd_UnknownExtension(t *testing.T) {
+	cmd := InfoCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"nope"})
+
+	err := cmd.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown extension: nope")
+}
+
+func TestInfoCmd_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := InfoCmd()
+	cmd.SetOut(&buf)
+	cmd.SetArgs([]string{"pg_textsearch", "--json"})
+
+	require.NoError(t, cmd.Execute())
+
+	var info extensionInfo
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &info))
+	assert.Equal(t, "pg_textsearch", info.Name)
+	assert.Equal(t, "postgres:<version>-bookworm", info.BaseImage)
+	assert.True(t, info.CustomImage)
+	assert.Equal(t, []string{"17", "18"}, info.Versions)
+	require.Len(t, info.Packages, 4)
+	assert.Equal(t, extensionPackage{Version: "17", Arch: "amd64",
+		Package: "https://github.com/timescale/pg_textsearch/releases/download/v0.1.0/pg-textsearch-v0.1.0-pg17-amd64.zip"}, info.Packages[0])
+	assert.Equal(t, "CREATE EXTENSION IF NOT EXISTS pg_textsearch;", info.InitSQL)
+	assert.Contains(t, buf.String(), `"base_image": "postgres:<version>-bookworm"`)
+}
+
+func TestInfoCmd_InspectAlias(t *testing.T) {
+	root := RootCmd()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetArgs([]string{"inspect", "hstore"})
+
+	require.NoError(t, root.Execute())
+
+	assert.Contains(t, buf.String(), "Image:     postgres:<version>\n")
+	assert.NotContains(t, buf.String(), "Packages:")
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func InitCmd() *cobra.Command {
+	var pgVersion string
+	var port string
+	var extFlags []string
+	var force bool
+
+	initCmd := &cobra.Command{
+		Use:   "init [directory]",
+		Short: "Create a pgbox.toml project configuration",
+		Long: `Create a pgbox.toml file that declares the PostgreSQL version, port,
+credentials, extensions and settings for a project.
+
+pgbox up and pgbox export read this file from the current directory or any
+parent, so everyone working in the repository gets the same database.
+Command-line flags still override values from the file.`,
+		Example: `  # Create pgbox.toml in the current directory
+  pgbox init
+
+  # Pre-fill version and extensions
+  pgbox init -v 17 --ext pgvector,pg_cron`,
+		Annotations: noDaemon,
+		Args:        cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := newResolver(cmd, nil)
+			if err != nil {
+				return err
+			}
+			pgVersion, err := resolveVersion(cmd, r)
+			if err != nil {
+				return err
+			}
+
+			extensions, err := ParseExtensionArgs(extFlags, nil)
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+
+			orch := orchestrator.NewInitOrchestrator(cmd.OutOrStdout())
+			return orch.Run(orchestrator.InitConfig{
+				Dir:        dir,
+				Version:    pgVersion,
+				Port:       port,
+				Extensions: extensions,
+				Force:      force,
+			})
+		},
+	}
+
+	initCmd.Flags().StringVarP(&pgVersion, "version", "v", "", versionFlagUsage)
+	initCmd.Flags().StringVarP(&port, "port", "p", "5432", "Port to expose PostgreSQL on")
+	initCmd.Flags().StringArrayVar(&extFlags, "ext", nil, "Extensions (repeatable or comma-separated)")
+	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing pgbox.toml")
+
+	return initCmd
+}
@@ -1,25 +1,41 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
+	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/orchestrator"
 	"github.com/spf13/cobra"
 )
 
 func ListExtensionsCmd() *cobra.Command {
 	var showSource bool
-	var filterKind string
+	var filter extensionFilter
+	var installed bool
+	var containerName string
+	var instance string
 
 	listExtCmd := &cobra.Command{
 		Use:   "list-extensions",
 		Short: "List available PostgreSQL extensions",
-		Long: `List all available PostgreSQL extensions from the catalog.
+		Long: `List all available PostgreSQL extensions from the catalog, with a short
+description of each.
 
 Extensions include both built-in PostgreSQL contrib modules and third-party
-extensions installable from apt.postgresql.org.`,
+extensions installable from apt.postgresql.org, plus custom specs from
+--ext-dir.
+
+Narrow the list with --kind, --category (one of: ` + strings.Join(extensions.Categories, ", ") + `)
+and --search, which matches a case-insensitive substring of the name or
+description. Custom specs set their category with category = "...".
+
+With --installed, list the extensions created in the database of the running
+container instead, with their versions and catalog names.`,
 		Example: `  # List all extensions
   pgbox list-extensions
 
@@ -28,35 +44,53 @@ extensions installable from apt.postgresql.org.`,
 
   # Filter by kind (builtin or package)
   pgbox list-extensions --kind builtin
-  pgbox list-extensions --kind package`,
+  pgbox list-extensions --kind package
+
+  # Find extensions by category or by what they do
+  pgbox list-extensions --category fdw
+  pgbox list-extensions --search vector
+
+  # Show what the running container has
+  pgbox list-extensions --installed`,
+		Annotations: noDaemon,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return listExtensions(cmd.OutOrStdout(), showSource, filterKind)
+			if filter.category != "" && !slices.Contains(extensions.Categories, filter.category) {
+				return fmt.Errorf("invalid category %q (must be one of: %s)", filter.category, strings.Join(extensions.Categories, ", "))
+			}
+			if !installed {
+				return listExtensions(cmd.OutOrStdout(), showSource, filter)
+			}
+			name, err := instanceContainerName(instance, containerName)
+			if err != nil {
+				return err
+			}
+			client := docker.NewClient()
+			if err := client.CheckDaemon(cmd.Context()); err != nil {
+				return err
+			}
+			return listInstalledExtensions(cmd.Context(), cmd.OutOrStdout(), orchestrator.NewExtOrchestrator(client, cmd.OutOrStdout(), cmd.InOrStdin()), name, filter)
 		},
 	}
 
 	listExtCmd.Flags().BoolVarP(&showSource, "source", "s", false, "Show source information for each extension")
-	listExtCmd.Flags().StringVarP(&filterKind, "kind", "k", "", "Filter by kind (builtin or package)")
+	listExtCmd.Flags().StringVarP(&filter.kind, "kind", "k", "", "Filter by kind (builtin or package)")
+	listExtCmd.Flags().StringVar(&filter.category, "category", "", "Filter by category, such as fdw, gis or search")
+	listExtCmd.Flags().StringVar(&filter.search, "search", "", "Filter by a substring of the name or description")
+	listExtCmd.Flags().BoolVar(&installed, "installed", false, "List the extensions created in the running container")
+	listExtCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name for --installed (default: auto-detect)")
+	listExtCmd.Flags().StringVar(&instance, "instance", "", "Named instance for --installed (container pgbox-<instance>)")
+	listExtCmd.MarkFlagsMutuallyExclusive("name", "instance")
+	listExtCmd.MarkFlagsMutuallyExclusive("installed", "source")
 
 	return listExtCmd
 }
 
-func listExtensions(w io.Writer, showSource bool, filterKind string) error {
-	allExtensions := extensions.ListExtensions()
-
+func listExtensions(w io.Writer, showSource bool, filter extensionFilter) error {
 	var displayed []string
-	for _, name := range allExtensions {
-		ext, _ := extensions.Get(name)
-
-		if filterKind != "" {
-			isBuiltin := ext.Package == ""
-			if filterKind == "builtin" && !isBuiltin {
-				continue
-			}
-			if filterKind == "package" && isBuiltin {
-				continue
-			}
+	for _, name := range extensions.ListExtensions() {
+		if filter.matches(name) {
+			displayed = append(displayed, name)
 		}
-		displayed = append(displayed, name)
 	}
 
 	_, _ = fmt.Fprintf(w, "PostgreSQL Extensions (%d available):\n\n", len(displayed))
@@ -70,9 +104,64 @@ func listExtensions(w io.Writer, showSource bool, filterKind string) error {
 			}
 			_, _ = fmt.Fprintf(w, "%-30s %s\n", name, source)
 		} else {
-			_, _ = fmt.Fprintf(w, "%s\n", name)
+			_, _ = fmt.Fprintf(w, "%-30s %-12s %s\n", name, extensions.Category(name), extensions.Describe(name))
 		}
 	}
 
 	return nil
 }
+
+// listInstalledExtensions prints the extensions created in the database of
+// the running container. Extensions outside the catalog are only listed
+// without a filter.
+func listInstalledExtensions(ctx context.Context, w io.Writer, orch *orchestrator.ExtOrchestrator, containerName string, filter extensionFilter) error {
+	name, database, installed, err := orch.Installed(ctx, containerName)
+	if err != nil {
+		return err
+	}
+
+	var displayed []orchestrator.InstalledExtension
+	for _, ext := range installed {
+		if filter == (extensionFilter{}) || (ext.Catalog != "" && filter.matches(ext.Catalog)) {
+			displayed = append(displayed, ext)
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "Extensions in %s on %s (%d installed):\n\n", database, name, len(displayed))
+
+	for _, ext := range displayed {
+		label := ext.SQLName
+		description := "(not in catalog)"
+		if ext.Catalog != "" {
+			description = extensions.Describe(ext.Catalog)
+			if ext.Catalog != ext.SQLName {
+				label = fmt.Sprintf("%s (%s)", ext.Catalog, ext.SQLName)
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%-30s %-10s %s\n", label, ext.Version, description)
+	}
+
+	return nil
+}
+
+// extensionFilter narrows the extensions list-extensions prints. Empty fields
+// match everything.
+type extensionFilter struct {
+	kind     string // builtin or package
+	category string // One of extensions.Categories
+	search   string // Case-insensitive substring of the name or description
+}
+
+// matches reports whether a catalog extension passes the filter.
+func (f extensionFilter) matches(name string) bool {
+	ext, _ := extensions.Get(name)
+	isBuiltin := ext.Package == ""
+	switch {
+	case f.kind == "builtin" && !isBuiltin, f.kind == "package" && isBuiltin:
+		return false
+	case f.category != "" && extensions.Category(name) != f.category:
+		return false
+	}
+	search := strings.ToLower(f.search)
+	return strings.Contains(strings.ToLower(name), search) || strings.Contains(strings.ToLower(extensions.Describe(name)), search)
+}
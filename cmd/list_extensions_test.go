@@ -5,6 +5,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/orchestrator"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -38,6 +41,9 @@ func TestListExtensions_ReturnsExtensions(t *testing.T) {
 	assert.Contains(t, output, "pgvector")
 	assert.Contains(t, output, "hstore")
 	assert.Contains(t, output, "pg_cron")
+
+	// Each with a description
+	assert.Contains(t, output, "Vector similarity search for embeddings")
 }
 
 func TestListExtensions_SourceFlag(t *testing.T) {
@@ -77,7 +83,7 @@ func TestListExtensions_KindFilterBuiltin(t *testing.T) {
 	lines := strings.Split(output, "\n")
 	for _, line := range lines {
 		// pgvector should not appear when filtering for builtin
-		if strings.TrimSpace(line) == "pgvector" {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "pgvector" {
 			t.Error("pgvector should not appear in builtin filter")
 		}
 	}
@@ -102,10 +108,112 @@ func TestListExtensions_KindFilterPackage(t *testing.T) {
 	// Should NOT include builtin extensions
 	lines := strings.Split(output, "\n")
 	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
 		// hstore is builtin, should not appear
-		if trimmed == "hstore" {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "hstore" {
 			t.Error("hstore should not appear in package filter")
 		}
 	}
 }
+
+func TestListInstalledExtensions(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "acme_audit\t1.0\nhstore\t1.8\nvector\t0.8.0\n", nil
+	}
+	var buf bytes.Buffer
+	orch := orchestrator.NewExtOrchestrator(mock, &buf, strings.NewReader(""))
+
+	require.NoError(t, listInstalledExtensions(t.Context(), &buf, orch, "pgbox-pg17", extensionFilter{}))
+
+	output := buf.String()
+	assert.Contains(t, output, "Extensions in postgres on pgbox-pg17 (3 installed)")
+	assert.Contains(t, output, "pgvector (vector)")
+	assert.Contains(t, output, "0.8.0")
+	assert.Contains(t, output, "Key/value pairs")
+	assert.Contains(t, output, "(not in catalog)")
+}
+
+func TestListInstalledExtensions_KindFilter(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "acme_audit\t1.0\nhstore\t1.8\nvector\t0.8.0\n", nil
+	}
+	var buf bytes.Buffer
+	orch := orchestrator.NewExtOrchestrator(mock, &buf, strings.NewReader(""))
+
+	require.NoError(t, listInstalledExtensions(t.Context(), &buf, orch, "pgbox-pg17", extensionFilter{kind: "package"}))
+
+	output := buf.String()
+	assert.Contains(t, output, "(1 installed)")
+	assert.Contains(t, output, "pgvector")
+	assert.NotContains(t, output, "hstore")
+	assert.NotContains(t, output, "acme_audit")
+}
+
+func TestListExtensions_CategoryFilter(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := ListExtensionsCmd()
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"--category", "fdw"})
+
+	require.NoError(t, cmd.Execute())
+
+	output := buf.String()
+	assert.Contains(t, output, "postgres_fdw")
+	assert.Contains(t, output, "file_fdw")
+	assert.NotContains(t, output, "hstore")
+	assert.NotContains(t, output, "pgvector")
+}
+
+func TestListExtensions_SearchFilter(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := ListExtensionsCmd()
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"--search", "VECTOR"})
+
+	require.NoError(t, cmd.Execute())
+
+	output := buf.String()
+	assert.Contains(t, output, "pgvector", "matches the name")
+	assert.NotContains(t, output, "hstore")
+
+	buf.Reset()
+	cmd = ListExtensionsCmd()
+	cmd.SetOut(&buf)
+	cmd.SetErr(&buf)
+	cmd.SetArgs([]string{"--search", "key/value"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, buf.String(), "hstore", "matches the description")
+}
+
+func TestListExtensions_InvalidCategory(t *testing.T) {
+	cmd := ListExtensionsCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--category", "nosuch"})
+
+	err := cmd.Execute()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid category "nosuch"`)
+}
+
+func TestListInstalledExtensions_CategoryFilter(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "acme_audit\t1.0\nhstore\t1.8\nvector\t0.8.0\n", nil
+	}
+	var buf bytes.Buffer
+	orch := orchestrator.NewExtOrchestrator(mock, &buf, strings.NewReader(""))
+
+	require.NoError(t, listInstalledExtensions(t.Context(), &buf, orch, "pgbox-pg17", extensionFilter{category: extensions.Category("hstore")}))
+
+	output := buf.String()
+	assert.Contains(t, output, "hstore")
+	assert.NotContains(t, output, "acme_audit")
+}
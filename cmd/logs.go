@@ -9,13 +9,18 @@ import (
 func LogsCmd() *cobra.Command {
 	var containerName string
 	var follow bool
+	var since string
+	var tail string
+	var grep string
 
 	logsCmd := &cobra.Command{
 		Use:   "logs",
 		Short: "Show container logs",
 		Long: `Display logs from a running PostgreSQL container.
 
-By default shows recent logs and exits. Use -f/--follow to stream logs continuously.`,
+By default shows recent logs and exits. Use -f/--follow to stream logs continuously.
+--since and --tail limit how far back to look; --grep shows only lines matching
+a regular expression.`,
 		Example: `  # Show logs from the default container
   pgbox logs
 
@@ -23,18 +28,30 @@ By default shows recent logs and exits. Use -f/--follow to stream logs continuou
   pgbox logs -n my-postgres -f
 
   # Show logs from a specific container
-  pgbox logs -n my-postgres`,
+  pgbox logs -n my-postgres
+
+  # Show the last 100 lines from the past hour
+  pgbox logs --since 1h --tail 100
+
+  # Follow only errors
+  pgbox logs -f --grep 'ERROR|FATAL'`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			orch := orchestrator.NewLogsOrchestrator(docker.NewClient(), cmd.OutOrStdout())
-			return orch.Run(orchestrator.LogsConfig{
+			return orch.Run(cmd.Context(), orchestrator.LogsConfig{
 				ContainerName: containerName,
 				Follow:        follow,
+				Since:         since,
+				Tail:          tail,
+				Grep:          grep,
 			})
 		},
 	}
 
 	logsCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
 	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	logsCmd.Flags().StringVar(&since, "since", "", "Show logs since a timestamp or relative duration (e.g. 10m)")
+	logsCmd.Flags().StringVar(&tail, "tail", "", "Number of lines to show from the end of the logs")
+	logsCmd.Flags().StringVar(&grep, "grep", "", "Only show lines matching a regular expression")
 
 	return logsCmd
 }
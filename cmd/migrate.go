@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func MigrateCmd() *cobra.Command {
+	var containerName string
+	var instance string
+	var database string
+	var user string
+	var target string
+	var dryRun bool
+
+	migrateCmd := &cobra.Command{
+		Use:     "migrate [dir]",
+		Aliases: []string{"run-sql"},
+		Short:   "Apply a directory of SQL migrations",
+		Long: `Apply the .sql files in a directory (default: migrations) to a running
+PostgreSQL container, in file name order, e.g. 0001_create_users.sql before
+0002_add_email.sql.
+
+Applied files are recorded in the pgbox_migrations table, so each file runs
+once; rerunning migrate applies only new files. Each file runs in its own
+transaction together with its record, so a failing file leaves no trace and
+the run stops there. Files therefore cannot contain statements that refuse to
+run in a transaction, such as CREATE INDEX CONCURRENTLY, or their own
+BEGIN/COMMIT. Editing an applied file prints a warning but does not rerun it.
+
+--target stops after the given file, named in full or by the version before
+its first "_". --dry-run lists the files that would be applied.`,
+		Example: `  # Apply ./migrations to the auto-detected container
+  pgbox migrate
+
+  # See what would run against a named instance
+  pgbox migrate db/migrations --instance shop --dry-run
+
+  # Apply up to and including 0003_*.sql
+  pgbox migrate --target 0003`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := instanceContainerName(instance, containerName)
+			if err != nil {
+				return err
+			}
+			dir := "migrations"
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			orch := orchestrator.NewMigrateOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.MigrateConfig{
+				ContainerName: name,
+				Database:      database,
+				User:          user,
+				Dir:           dir,
+				Target:        target,
+				DryRun:        dryRun,
+			})
+		},
+	}
+
+	migrateCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	migrateCmd.Flags().StringVar(&instance, "instance", "", "Named instance (container pgbox-<instance>)")
+	migrateCmd.Flags().StringVarP(&database, "database", "d", "", "Database name (default: container's POSTGRES_DB)")
+	migrateCmd.Flags().StringVarP(&user, "user", "u", "", "Username (default: container's POSTGRES_USER)")
+	migrateCmd.Flags().StringVar(&target, "target", "", "Stop after this migration (file name or version prefix)")
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the migrations that would be applied without applying them")
+	migrateCmd.MarkFlagsMutuallyExclusive("name", "instance")
+
+	return migrateCmd
+}
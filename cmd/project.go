@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+// loadProject loads pgbox.toml from the working directory or its parents.
+// Returns nil when no project file exists.
+func loadProject(cmd *cobra.Command) (*config.ProjectConfig, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine working directory: %w", err)
+	}
+	project, err := config.LoadProjectFrom(wd)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		// On stderr, so it never mixes into output meant for scripts
+		logging.Infof(cmd.ErrOrStderr(), "Using configuration from %s", project.Path)
+	}
+	return project, nil
+}
+
+// projectToSync returns the project file that a command changing a container
+// keeps in sync. A container picked with -n may not be the project's, so it
+// has none.
+func projectToSync(cmd *cobra.Command) (*config.ProjectConfig, error) {
+	if cmd.Flags().Changed("name") {
+		return nil, nil
+	}
+	return loadProject(cmd)
+}
+
+// fromProjectList sets target to the project file's list unless the flag was
+// given on the command line or the file leaves it unset.
+func fromProjectList(cmd *cobra.Command, flag string, target *[]string, value []string) {
+	if len(value) > 0 && !cmd.Flags().Changed(flag) {
+		*target = value
+	}
+}
+
+// newResolver returns the resolver for cmd's configuration values: flags
+// given on its command line, then PGBOX_* environment variables, pgbox.toml,
+// the user config and built-in defaults.
+func newResolver(cmd *cobra.Command, project *config.ProjectConfig) (*config.Resolver, error) {
+	user, err := config.LoadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	flags := make(map[string]string)
+	for key := range config.EnvVars {
+		if f := cmd.Flags().Lookup(config.FlagName(key)); f != nil && f.Changed {
+			flags[key] = f.Value.String()
+		}
+	}
+	return config.NewResolver(flags, project, user), nil
+}
+
+// resolve returns the value for key. With --verbose it reports where the
+// value came from; passwords are not echoed.
+func resolve(cmd *cobra.Command, r *config.Resolver, key string) string {
+	value, source := r.Get(key)
+	if value != "" {
+		shown := value
+		if key == config.KeyPassword {
+			shown = "***"
+		}
+		verbosef(cmd, "Using %s %s (from %s)", key, shown, source)
+	}
+	return value
+}
+
+// resolveVersion returns the PostgreSQL version to use, resolving "latest"
+// and checking that it is supported. With --verbose it reports where the
+// version came from.
+func resolveVersion(cmd *cobra.Command, r *config.Resolver) (string, error) {
+	version, source := r.Version()
+	if err := ValidatePostgresVersion(version); err != nil {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+	verbosef(cmd, "Using PostgreSQL %s (from %s)", version, source)
+	return version, nil
+}
+
+// projectInstances checks that the project declares [instances] with supported
+// versions and returns the configuration for the instances orchestrator.
+func projectInstances(project *config.ProjectConfig) (orchestrator.InstancesConfig, error) {
+	if project == nil {
+		return orchestrator.InstancesConfig{}, fmt.Errorf("--all requires a %s with [instances]. Create one with: pgbox init", config.ProjectFileName)
+	}
+	user, err := config.LoadUserConfig()
+	if err != nil {
+		return orchestrator.InstancesConfig{}, err
+	}
+	// Instances without a version inherit the resolved project default
+	project.Version, _ = config.NewResolver(nil, project, user).Version()
+	for name, inst := range project.Instances {
+		if inst.Version == config.LatestKeyword {
+			inst.Version, _ = config.NewResolver(map[string]string{config.KeyVersion: inst.Version}, nil, nil).Version()
+		}
+		merged, _ := project.Instance(name)
+		if err := ValidatePostgresVersion(merged.Version); err != nil {
+			return orchestrator.InstancesConfig{}, fmt.Errorf("instance %s: %w", name, err)
+		}
+	}
+	return orchestrator.InstancesConfig{Project: project}, nil
+}
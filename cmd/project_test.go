@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromProjectList_FlagsOverrideFile(t *testing.T) {
+	var extensions, prefer []string
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	cmd.Flags().StringSliceVar(&extensions, "ext", nil, "")
+	cmd.Flags().StringSliceVar(&prefer, "prefer", nil, "")
+	require.NoError(t, cmd.ParseFlags([]string{"--prefer", "pg_cron"}))
+
+	fromProjectList(cmd, "ext", &extensions, []string{"pgvector"})
+	fromProjectList(cmd, "prefer", &prefer, []string{"wal2json"})
+
+	assert.Equal(t, []string{"pgvector"}, extensions)
+	assert.Equal(t, []string{"pg_cron"}, prefer)
+}
+
+func TestNewResolver_Precedence(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("PGBOX_USER", "envuser")
+	t.Setenv("PGBOX_PORT", "6000")
+	t.Setenv("PGBOX_DATABASE", "")
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	cmd.Flags().StringP("version", "v", "", "")
+	cmd.Flags().String("port", "5432", "")
+	cmd.Flags().String("user", "postgres", "")
+	cmd.Flags().String("database", "postgres", "")
+	cmd.Flags().Bool("verbose", false, "")
+	require.NoError(t, cmd.ParseFlags([]string{"--port", "7000", "--verbose"}))
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+	project := &config.ProjectConfig{
+		InstanceConfig: config.InstanceConfig{Version: "16", Port: "5433", User: "app", Database: "shop"},
+		Path:           "/repo/pgbox.toml",
+	}
+
+	r, err := newResolver(cmd, project)
+	require.NoError(t, err)
+	version, err := resolveVersion(cmd, r)
+	require.NoError(t, err)
+
+	assert.Equal(t, "16", version)
+	assert.Equal(t, "7000", resolve(cmd, r, config.KeyPort))
+	assert.Equal(t, "envuser", resolve(cmd, r, config.KeyUser))
+	assert.Equal(t, "shop", resolve(cmd, r, config.KeyDatabase))
+	assert.Equal(t, "postgres", resolve(cmd, r, config.KeyPassword))
+	assert.Contains(t, stderr.String(), "Using PostgreSQL 16 (from version in /repo/pgbox.toml)")
+	assert.Contains(t, stderr.String(), "Using port 7000 (from --port flag)")
+	assert.Contains(t, stderr.String(), "Using user envuser (from $PGBOX_USER)")
+	assert.Contains(t, stderr.String(), "Using password *** (from built-in default)")
+}
+
+func TestResolveVersion_Invalid(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("PGBOX_VERSION", "15")
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+
+	r, err := newResolver(cmd, nil)
+	require.NoError(t, err)
+	_, err = resolveVersion(cmd, r)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "$PGBOX_VERSION: invalid PostgreSQL version: 15")
+}
+
+func TestLoadProject_ReportsFileOnStderr(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pgbox.toml"), []byte("version = \"16\"\n"), 0644))
+	t.Chdir(dir)
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	project, err := loadProject(cmd)
+
+	require.NoError(t, err)
+	require.NotNil(t, project)
+	assert.Empty(t, stdout.String(), "stdout is left to the command's own output")
+	assert.Contains(t, stderr.String(), "Using configuration from ")
+}
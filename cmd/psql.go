@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/orchestrator"
 	"github.com/spf13/cobra"
@@ -10,6 +12,9 @@ func PsqlCmd() *cobra.Command {
 	var psqlDatabase string
 	var psqlUser string
 	var psqlName string
+	var instance string
+	var start bool
+	var replica bool
 
 	psqlCmd := &cobra.Command{
 		Use:   "psql [flags] [-- psql-args...]",
@@ -18,6 +23,13 @@ func PsqlCmd() *cobra.Command {
 
 This command executes psql inside the container, so no local PostgreSQL client is needed.
 
+If the container given with -n or --instance exists but is stopped, psql asks
+whether to start it (in a terminal) or starts it right away with --start, and
+connects once PostgreSQL accepts connections.
+
+With --replica, psql connects to the read-only replica pgbox up --with-replica
+runs next to the container, to check what reads see there.
+
 You can pass additional arguments to psql after a '--' separator.`,
 		Example: `  # Connect to default container with default database and user
   pgbox psql
@@ -31,6 +43,15 @@ You can pass additional arguments to psql after a '--' separator.`,
   # Connect to a container with custom name
   pgbox psql -n my-postgres
 
+  # Start the container first if it is stopped
+  pgbox psql -n my-postgres --start
+
+  # Connect to the read-only replica started with pgbox up --with-replica
+  pgbox psql --replica
+
+  # Connect to a named instance started with pgbox up --instance
+  pgbox psql --instance shop
+
   # Pass additional arguments to psql (e.g., execute a command)
   pgbox psql -- -c "SELECT version();"
 
@@ -56,12 +77,19 @@ You can pass additional arguments to psql after a '--' separator.`,
 				database = psqlDatabase
 			}
 
-			orch := orchestrator.NewPsqlOrchestrator(docker.NewClient(), cmd.OutOrStdout())
-			return orch.Run(orchestrator.PsqlConfig{
-				ContainerName: psqlName,
+			name, err := instanceContainerName(instance, psqlName)
+			if err != nil {
+				return err
+			}
+
+			orch := orchestrator.NewPsqlOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.Run(cmd.Context(), orchestrator.PsqlConfig{
+				ContainerName: name,
 				Database:      database,
 				User:          user,
 				ExtraArgs:     extraArgs,
+				Start:         start,
+				Replica:       replica,
 			})
 		},
 		DisableFlagParsing: false,
@@ -71,6 +99,11 @@ You can pass additional arguments to psql after a '--' separator.`,
 	psqlCmd.Flags().StringVarP(&psqlDatabase, "database", "d", "postgres", "Database name to connect to")
 	psqlCmd.Flags().StringVarP(&psqlUser, "user", "u", "postgres", "Username for connection")
 	psqlCmd.Flags().StringVarP(&psqlName, "name", "n", "", "Container name (default: pgbox-pg<version>)")
+	psqlCmd.Flags().StringVar(&instance, "instance", "", "Named instance to connect to (container pgbox-<instance>)")
+	psqlCmd.Flags().BoolVar(&start, "start", false, "Start the container if it is stopped, without asking")
+	psqlCmd.Flags().BoolVar(&replica, "replica", false, "Connect to the container's read-only replica (see pgbox up --with-replica)")
+	psqlCmd.MarkFlagsMutuallyExclusive("name", "instance")
+	psqlCmd.MarkFlagsMutuallyExclusive("replica", "start")
 
 	return psqlCmd
 }
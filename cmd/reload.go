@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func ReloadCmd() *cobra.Command {
+	var containerName string
+	var confFile string
+	var hbaFile string
+	var settings []string
+
+	reloadCmd := &cobra.Command{
+		Use:   "reload",
+		Short: "Apply configuration changes without recreating the container",
+		Long: `Apply settings and pg_hba.conf changes to a running PostgreSQL container
+and reload its configuration with pg_reload_conf().
+
+Settings are written with ALTER SYSTEM, so they persist in the data volume.
+The command prints which settings changed and which only take effect after
+a restart.`,
+		Example: `  # Change a setting on the default container
+  pgbox reload --set work_mem=64MB
+
+  # Apply settings from an exported postgresql.conf.pgbox
+  pgbox reload --conf ./postgresql.conf.pgbox
+
+  # Replace pg_hba.conf on a specific container
+  pgbox reload -n my-postgres --hba ./pg_hba.conf`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := ParseSettings(settings)
+			if err != nil {
+				return err
+			}
+
+			orch := orchestrator.NewReloadOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.ReloadConfig{
+				ContainerName: containerName,
+				ConfFile:      confFile,
+				HBAFile:       hbaFile,
+				Settings:      parsed,
+			})
+		},
+	}
+
+	reloadCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	reloadCmd.Flags().StringVar(&confFile, "conf", "", "postgresql.conf snippet to apply")
+	reloadCmd.Flags().StringVar(&hbaFile, "hba", "", "File to install as pg_hba.conf")
+	reloadCmd.Flags().StringArrayVar(&settings, "set", nil, "Setting to apply as key=value (repeatable)")
+
+	return reloadCmd
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func RemapPortCmd() *cobra.Command {
+	var containerName string
+
+	remapCmd := &cobra.Command{
+		Use:   "remap-port <port>",
+		Short: "Publish a container on a different host port, keeping its data",
+		Long: `Recreate a running container so it publishes PostgreSQL on a new host port.
+
+The new container uses the same image, credentials, extensions and settings,
+and the same data volume. The new connection string is printed. When the
+command targets the default container (no -n) and a pgbox.toml is found, its
+port is updated to match.`,
+		Example: `  # Move the auto-detected container to port 5433
+  pgbox remap-port 5433
+
+  # Move a specific container
+  pgbox remap-port 6543 -n my-postgres`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := projectToSync(cmd)
+			if err != nil {
+				return err
+			}
+			orch := orchestrator.NewRemapPortOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.RemapPortConfig{
+				ContainerName: containerName,
+				Port:          args[0],
+				Project:       project,
+			})
+		},
+	}
+
+	remapCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+
+	return remapCmd
+}
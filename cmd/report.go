@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func ReportCmd() *cobra.Command {
+	var containerName string
+	var outputFile string
+	var logLines int
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Bundle sanitized diagnostics for a bug report",
+		Long: `Gather diagnostics into a zip file to attach to a GitHub issue.
+
+The report contains the pgbox version, OS and architecture, the container
+runtime version, the state of the pgbox container, its recent logs and, when a
+pgbox.toml is found, the project file and the Dockerfile and docker-compose.yml
+pgbox would render from it. Passwords are replaced with *** everywhere.
+
+It works even when the container daemon is down, recording why it is
+unreachable instead.`,
+		Example: `  # Write pgbox-report-<timestamp>.zip in the current directory
+  pgbox report
+
+  # Report on a specific container, with more log lines
+  pgbox report -n my-postgres --tail 1000 -o report.zip`,
+		Annotations: noDaemon,
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, err := loadProject(cmd)
+			if err != nil {
+				return err
+			}
+			if outputFile == "" {
+				outputFile = fmt.Sprintf("pgbox-report-%s.zip", time.Now().Format("20060102-150405"))
+			}
+			version := cmd.Root().Version
+			if version == "" {
+				version = "dev"
+			}
+
+			cfg := orchestrator.ReportConfig{
+				ContainerName: containerName,
+				OutputFile:    outputFile,
+				PgboxVersion:  version,
+				LogLines:      logLines,
+			}
+			if project != nil {
+				r, err := newResolver(cmd, project)
+				if err != nil {
+					return err
+				}
+				cfg.ProjectFile = project.Path
+				cfg.Scaffold = &orchestrator.ExportConfig{
+					Port:       resolve(cmd, r, config.KeyPort),
+					Extensions: project.Extensions,
+					BaseImage:  resolve(cmd, r, config.KeyBaseImage),
+					Prefer:     project.Prefer,
+					Settings:   project.Settings,
+					User:       resolve(cmd, r, config.KeyUser),
+					Password:   resolve(cmd, r, config.KeyPassword),
+					Database:   resolve(cmd, r, config.KeyDatabase),
+				}
+				// An invalid version is reported in the scaffold rather than failing the report
+				if cfg.Scaffold.Version, err = resolveVersion(cmd, r); err != nil {
+					cfg.Scaffold.Version, _ = r.Get(config.KeyVersion)
+				}
+			}
+
+			orch := orchestrator.NewReportOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), cfg)
+		},
+	}
+
+	reportCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	reportCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Zip file to write (default: pgbox-report-<timestamp>.zip)")
+	reportCmd.Flags().IntVar(&logLines, "tail", 200, "Number of recent container log lines to include")
+
+	return reportCmd
+}
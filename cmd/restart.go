@@ -22,7 +22,7 @@ This command stops and then starts the container, preserving all data and config
   pgbox restart -n my-postgres`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			orch := orchestrator.NewRestartOrchestrator(docker.NewClient(), cmd.OutOrStdout())
-			return orch.Run(orchestrator.RestartConfig{
+			return orch.Run(cmd.Context(), orchestrator.RestartConfig{
 				ContainerName: containerName,
 			})
 		},
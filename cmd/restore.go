@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func RestoreCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var format string
+	var jobs int
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restore a database from a backup",
+		Long: `Restore a backup made with pgbox backup (or pg_dump) into a running
+PostgreSQL container.
+
+The backup format is detected automatically: directories are directory-format
+dumps, files starting with the pg_dump signature are custom-format, and
+anything else is loaded as plain SQL (gzip-compressed files are supported).`,
+		Example: `  # Restore into the default container
+  pgbox restore mydb.dump
+
+  # Restore a plain SQL dump into a specific database
+  pgbox restore -d mydb mydb.sql
+
+  # Parallel restore
+  pgbox restore --jobs 4 ./mydb-backup`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewRestoreOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.RestoreConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Input:         args[0],
+				Format:        format,
+				Jobs:          jobs,
+			})
+		},
+	}
+
+	restoreCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	restoreCmd.Flags().StringVarP(&database, "database", "d", "", "Database to restore into (default: container's POSTGRES_DB)")
+	restoreCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	restoreCmd.Flags().StringVarP(&format, "format", "F", "", "Backup format (custom, plain, or directory; default: detect)")
+	restoreCmd.Flags().IntVarP(&jobs, "jobs", "j", 1, "Number of parallel jobs (custom and directory formats)")
+
+	return restoreCmd
+}
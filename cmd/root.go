@@ -1,10 +1,39 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/charmbracelet/fang"
 	"github.com/spf13/cobra"
 )
 
 func RootCmd() *cobra.Command {
+	rootCmd, _ := newRootCmd()
+	return rootCmd
+}
+
+// newRootCmd returns the root command and a function that releases the
+// context --timeout gives its commands and closes the --trace-docker file,
+// for the caller to run however the command ends.
+func newRootCmd() (*cobra.Command, func()) {
+	var runtimeName string
+	var traceDest string
+	var extDir string
+	var quiet bool
+	var logFormat string
+	var timeout time.Duration
+	cancelTimeout := func() {}
+	closeTrace := func() {}
+
 	rootCmd := &cobra.Command{
 		Use:   "pgbox",
 		Short: "PostgreSQL-in-Docker with selectable extensions",
@@ -12,25 +41,211 @@ func RootCmd() *cobra.Command {
 with your choice of extensions.
 
 It provides an easy way to spin up PostgreSQL instances with
-specific extensions for development and testing purposes.`,
+specific extensions for development and testing purposes.
+
+pgbox drives the docker CLI by default. Use --runtime or the PGBOX_RUNTIME
+environment variable to use podman or nerdctl instead.
+
+Use --trace-docker to log every runtime command pgbox runs, with its
+duration, exit code and output, for debugging or bug reports. --verbose logs
+just the command lines, along with where configuration values come from;
+--quiet hides progress messages and keeps warnings and results.
+--log-format json writes those messages as JSON lines.
+
+Use --ext-dir or the PGBOX_EXT_DIR environment variable to add extensions from
+a directory of TOML specs, one <name>.toml per extension, merged over the
+built-in catalog. See pgbox info <name> for what a spec defines.
+
+Use --timeout to give up on a command, and stop the runtime commands it is
+running, once it has taken longer than the given duration, so a hung daemon
+does not hang scripts and CI jobs. Interactive psql and exec sessions are not
+limited by it.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := configureLogging(cmd, quiet, logFormat); err != nil {
+				return err
+			}
+			if timeout < 0 {
+				return fmt.Errorf("--timeout must not be negative")
+			}
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeoutCause(cmd.Context(), timeout, fmt.Errorf("--timeout %s expired", timeout))
+				cmd.SetContext(ctx)
+				cancelTimeout = cancel
+			}
+			if err := docker.SelectRuntime(runtimeName); err != nil {
+				return err
+			}
+			if traceDest != "" {
+				w, closeFile, err := openTrace(traceDest)
+				if err != nil {
+					return err
+				}
+				docker.SetTrace(w)
+				closeTrace = func() {
+					docker.SetTrace(nil)
+					closeFile()
+				}
+			}
+			if cmd.Annotations[ownExtDirAnnotation] == "" {
+				if err := loadExtensionDir(extDir); err != nil {
+					return err
+				}
+			}
+			if !needsDaemon(cmd) {
+				return nil
+			}
+			return docker.NewClient().CheckDaemon(cmd.Context())
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			_ = cmd.Help()
 		},
 	}
 
+	rootCmd.PersistentFlags().StringVar(&runtimeName, "runtime", "", "Container runtime: docker, podman, or nerdctl (default: $PGBOX_RUNTIME or docker)")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Explain where configuration values come from and log the runtime commands run")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print warnings, errors and results, not progress messages")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Format of progress, warning and --verbose messages: "+strings.Join(logging.Formats, ", "))
+	rootCmd.PersistentFlags().StringVar(&traceDest, "trace-docker", "", "Log every container runtime command to stderr, or to the given file (--trace-docker=FILE)")
+	rootCmd.PersistentFlags().Lookup("trace-docker").NoOptDefVal = "-"
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Stop a command that takes longer than this, such as 30s or 2m (default: no limit)")
+	rootCmd.PersistentFlags().StringVar(&extDir, "ext-dir", "", "Directory of custom extension specs (<name>.toml) merged over the built-in catalog (default: $"+extensions.ExtDirEnvVar+")")
+
+	rootCmd.AddCommand(InitCmd())
+	rootCmd.AddCommand(ConfigCmd())
 	rootCmd.AddCommand(UpCmd())
 	rootCmd.AddCommand(DownCmd())
 	rootCmd.AddCommand(RestartCmd())
+	rootCmd.AddCommand(RemapPortCmd())
+	rootCmd.AddCommand(UpgradeCmd())
+	rootCmd.AddCommand(ReloadCmd())
 	rootCmd.AddCommand(StatusCmd())
 	rootCmd.AddCommand(LogsCmd())
+	rootCmd.AddCommand(TimingsCmd())
 	rootCmd.AddCommand(PsqlCmd())
+	rootCmd.AddCommand(SQLCmd())
+	rootCmd.AddCommand(MigrateCmd())
+	rootCmd.AddCommand(ExplainAnalyzeDiffCmd())
+	rootCmd.AddCommand(ExecCmd())
+	rootCmd.AddCommand(CpCmd())
+	rootCmd.AddCommand(BackupCmd())
+	rootCmd.AddCommand(RestoreCmd())
+	rootCmd.AddCommand(TestDBCmd())
+	rootCmd.AddCommand(TmpCmd())
+	rootCmd.AddCommand(VolumeCmd())
+	rootCmd.AddCommand(SnapshotCmd())
+	rootCmd.AddCommand(SizeCmd())
+	rootCmd.AddCommand(VacuumStatusCmd())
+	rootCmd.AddCommand(StatsCmd())
+	rootCmd.AddCommand(BenchCmd())
+	rootCmd.AddCommand(CheckCmd())
+	rootCmd.AddCommand(GrantsCmd())
 	rootCmd.AddCommand(ExportCmd())
 	rootCmd.AddCommand(ListExtensionsCmd())
+	rootCmd.AddCommand(InfoCmd())
+	rootCmd.AddCommand(ExtCmd())
+	rootCmd.AddCommand(GucCmd())
+	rootCmd.AddCommand(WhyCmd())
+	rootCmd.AddCommand(ReportCmd())
 	rootCmd.AddCommand(CleanCmd())
 
-	return rootCmd
+	return rootCmd, func() {
+		cancelTimeout()
+		closeTrace()
+	}
+}
+
+// Execute runs pgbox under ctx with fang's error and help styling. The
+// --timeout context and the trace file are released once the command
+// returns, including when it fails.
+func Execute(ctx context.Context, opts ...fang.Option) error {
+	rootCmd, release := newRootCmd()
+	defer release()
+	return fang.Execute(ctx, rootCmd, opts...)
+}
+
+// openTrace returns the writer for --trace-docker and a function that closes
+// it: stderr for "-", otherwise the named file, appended to so several runs
+// can share one log. The file is only readable by its owner, since traced
+// commands can include connection details.
+func openTrace(dest string) (io.Writer, func(), error) {
+	if dest == "-" {
+		return os.Stderr, func() {}, nil
+	}
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	return f, func() { _ = f.Close() }, nil
 }
 
-func Execute() error {
-	return RootCmd().Execute()
+// loadExtensionDir merges the custom extension specs from --ext-dir, or from
+// $PGBOX_EXT_DIR when the flag is not given, over the catalog.
+func loadExtensionDir(dir string) error {
+	dir = extensionDir(dir)
+	if dir == "" {
+		return nil
+	}
+	_, err := extensions.LoadDir(dir)
+	return err
+}
+
+// extensionDir returns the --ext-dir value, or $PGBOX_EXT_DIR when the flag
+// is not given.
+func extensionDir(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(extensions.ExtDirEnvVar)
+}
+
+// ownExtDirAnnotation marks commands that read --ext-dir themselves, so the
+// root command does not stop at the first invalid spec before they run.
+const ownExtDirAnnotation = "pgbox/own-ext-dir"
+
+// configureLogging sets the logging level from --verbose and --quiet and the
+// format from --log-format.
+func configureLogging(cmd *cobra.Command, quiet bool, format string) error {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	level := logging.LevelInfo
+	switch {
+	case verbose && quiet:
+		return errors.New("--verbose and --quiet cannot be used together")
+	case verbose:
+		level = logging.LevelDebug
+	case quiet:
+		level = logging.LevelWarn
+	}
+	return logging.Configure(level, format)
+}
+
+// verbosef writes a diagnostic line to stderr when --verbose is set.
+func verbosef(cmd *cobra.Command, format string, args ...any) {
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		logging.Log(cmd.ErrOrStderr(), logging.LevelDebug, format, args...)
+	}
+}
+
+// noDaemonAnnotation marks commands that never talk to the container runtime,
+// so the root command skips its daemon check for them.
+const noDaemonAnnotation = "pgbox/no-daemon"
+
+// noDaemon is the Annotations value for commands that work without Docker.
+var noDaemon = map[string]string{noDaemonAnnotation: "true"}
+
+// needsDaemon reports whether cmd needs a reachable container runtime.
+// Help, shell completion and commands annotated with noDaemon do not.
+func needsDaemon(cmd *cobra.Command) bool {
+	if !cmd.HasParent() {
+		return false
+	}
+	switch cmd.Name() {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return false
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[noDaemonAnnotation] != "" || c.Name() == "completion" {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCmd_VerboseAndQuiet(t *testing.T) {
+	root := RootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"--verbose", "--quiet", "list-extensions"})
+
+	assert.EqualError(t, root.Execute(), "--verbose and --quiet cannot be used together")
+}
+
+func TestRootCmd_UnknownLogFormat(t *testing.T) {
+	root := RootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"--log-format", "xml", "list-extensions"})
+
+	assert.EqualError(t, root.Execute(), `unknown log format "xml" (available: text, json)`)
+}
+
+func TestRootCmd_Timeout(t *testing.T) {
+	root := RootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	var deadline time.Time
+	var limited bool
+	root.AddCommand(&cobra.Command{
+		Use:         "probe",
+		Annotations: map[string]string{noDaemonAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			deadline, limited = cmd.Context().Deadline()
+			return nil
+		},
+	})
+
+	root.SetArgs([]string{"--timeout", "30s", "probe"})
+	require.NoError(t, root.Execute())
+	assert.True(t, limited)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), deadline, 5*time.Second)
+
+	root.SetArgs([]string{"--timeout", "-1s", "probe"})
+	assert.EqualError(t, root.Execute(), "--timeout must not be negative")
+}
+
+func TestRootCmd_TimeoutReleasedWhenCommandFails(t *testing.T) {
+	root, release := newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	var ctx context.Context
+	root.AddCommand(&cobra.Command{
+		Use:         "probe",
+		Annotations: map[string]string{noDaemonAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx = cmd.Context()
+			return errors.New("probe failed")
+		},
+	})
+
+	root.SetArgs([]string{"--timeout", "30s", "probe"})
+	require.EqualError(t, root.Execute(), "probe failed")
+	require.NoError(t, ctx.Err(), "the command runs until it returns")
+	release()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestRootCmd_TraceFileIsPrivate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	root, release := newRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.AddCommand(&cobra.Command{
+		Use:         "probe",
+		Annotations: map[string]string{noDaemonAnnotation: "true"},
+		RunE:        func(cmd *cobra.Command, args []string) error { return nil },
+	})
+
+	root.SetArgs([]string{"--trace-docker=" + path, "probe"})
+	require.NoError(t, root.Execute())
+	release()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func SizeCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var top int
+	var jsonOutput bool
+
+	sizeCmd := &cobra.Command{
+		Use:   "size",
+		Short: "Show database sizes and the largest relations",
+		Long: `Show the on-disk size of every database in the container and the largest
+tables, materialized views and indexes in one database, with the heap, index
+and TOAST parts of each table broken out.`,
+		Example: `  # Database sizes and the 10 largest relations in the default database
+  pgbox size
+
+  # The 25 largest relations in a specific database
+  pgbox size -d mydb --top 25
+
+  # Machine-readable output (sizes in bytes)
+  pgbox size --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewSizeOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.SizeConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Top:           top,
+				JSON:          jsonOutput,
+			})
+		},
+	}
+
+	sizeCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	sizeCmd.Flags().StringVarP(&database, "database", "d", "", "Database to inspect (default: container's POSTGRES_DB)")
+	sizeCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	sizeCmd.Flags().IntVar(&top, "top", 10, "Number of relations to list")
+	sizeCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+
+	return sizeCmd
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func SnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save and restore named copies of a container's data",
+		Long: `Save named snapshots of a container's data volume and roll back to them later,
+for example before trying a destructive migration.
+
+Snapshots are stored under ~/.local/share/pgbox/snapshots/<container>/
+($XDG_DATA_HOME/pgbox/snapshots when set). A running container is stopped
+while its volume is copied and started again afterwards.`,
+	}
+
+	snapshotCmd.AddCommand(snapshotCreateCmd())
+	snapshotCmd.AddCommand(snapshotRestoreCmd())
+	snapshotCmd.AddCommand(snapshotListCmd())
+
+	return snapshotCmd
+}
+
+func snapshotCreateCmd() *cobra.Command {
+	var containerName string
+	var force bool
+
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Snapshot the container's data volume",
+		Example: `  # Save a point to return to before a migration
+  pgbox snapshot create before-migration
+
+  # Overwrite an existing snapshot of a specific container
+  pgbox snapshot create seeded -n my-postgres --force`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewSnapshotOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Create(cmd.Context(), orchestrator.SnapshotConfig{
+				ContainerName: containerName,
+				Name:          args[0],
+				Force:         force,
+			})
+		},
+	}
+
+	createCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	createCmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing snapshot with the same name")
+
+	return createCmd
+}
+
+func snapshotRestoreCmd() *cobra.Command {
+	var containerName string
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <name>",
+		Short: "Replace the container's data with a snapshot",
+		Example: `  # Roll back to a snapshot
+  pgbox snapshot restore before-migration`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewSnapshotOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Restore(cmd.Context(), orchestrator.SnapshotConfig{
+				ContainerName: containerName,
+				Name:          args[0],
+			})
+		},
+	}
+
+	restoreCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+
+	return restoreCmd
+}
+
+func snapshotListCmd() *cobra.Command {
+	var containerName string
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots with their size and creation time",
+		Example: `  # List all snapshots
+  pgbox snapshot list
+
+  # List snapshots of one container
+  pgbox snapshot list -n my-postgres`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewSnapshotOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			_, err := orch.List(orchestrator.SnapshotConfig{ContainerName: containerName})
+			return err
+		},
+	}
+
+	listCmd.Flags().StringVarP(&containerName, "name", "n", "", "Only list snapshots of this container")
+
+	return listCmd
+}
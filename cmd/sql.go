@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func SQLCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var atomic bool
+
+	sqlCmd := &cobra.Command{
+		Use:   "sql",
+		Short: "Run a SQL script from stdin",
+		Long: `Run a SQL script read from stdin against a running PostgreSQL container.
+
+Every statement is echoed with its execution time, and the script stops at the
+first error. By default the whole script runs in a single transaction, so a
+failing statement rolls back everything before it instead of leaving the
+database half-migrated. Use --atomic=false to apply statements one by one.`,
+		Example: `  # Apply a script atomically to the auto-detected container
+  pgbox sql < schema.sql
+
+  # Pipe SQL from another command
+  echo "CREATE TABLE t (id int);" | pgbox sql
+
+  # Apply statements without a wrapping transaction
+  pgbox sql --atomic=false < data.sql`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewSQLOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.SQLConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Atomic:        atomic,
+				Input:         os.Stdin,
+			})
+		},
+	}
+
+	sqlCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	sqlCmd.Flags().StringVarP(&database, "database", "d", "", "Database name (default: container's POSTGRES_DB)")
+	sqlCmd.Flags().StringVarP(&user, "user", "u", "", "Username (default: container's POSTGRES_USER)")
+	sqlCmd.Flags().BoolVar(&atomic, "atomic", true, "Run the whole script in a single transaction")
+
+	return sqlCmd
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func StatsCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var limit int
+	var sort string
+	var reset bool
+	var jsonOutput bool
+
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show the top queries from pg_stat_statements",
+		Long: `Show the statements that took the most time, from pg_stat_statements: their
+calls, total and mean execution time, rows and query text, for every database
+in the container. --sort orders them by total time (the default), mean time,
+calls or rows.
+
+pg_stat_statements is created in the database first if it is not there yet.
+Its library must be in shared_preload_libraries, which takes a restart; if it
+is not, add it with: pgbox ext add pg_stat_statements
+
+--reset clears the statistics, to measure a workload from a clean slate.`,
+		Example: `  # The 10 statements that took the most time
+  pgbox stats
+
+  # The 25 slowest statements on average
+  pgbox stats --sort mean --limit 25
+
+  # Start over, run the workload, then look
+  pgbox stats --reset
+
+  # Machine-readable output (times in milliseconds)
+  pgbox stats --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewStatsOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.StatsConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Limit:         limit,
+				Sort:          sort,
+				Reset:         reset,
+				JSON:          jsonOutput,
+			})
+		},
+	}
+
+	statsCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	statsCmd.Flags().StringVarP(&database, "database", "d", "", "Database to connect to (default: container's POSTGRES_DB)")
+	statsCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	statsCmd.Flags().IntVar(&limit, "limit", 10, "Number of statements to list")
+	statsCmd.Flags().StringVar(&sort, "sort", "total", "Order statements by: "+strings.Join(orchestrator.StatsSorts, ", "))
+	statsCmd.Flags().BoolVar(&reset, "reset", false, "Clear the statistics instead of printing them")
+	statsCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the statements as JSON")
+	statsCmd.MarkFlagsMutuallyExclusive("reset", "json")
+
+	return statsCmd
+}
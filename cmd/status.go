@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/orchestrator"
 	"github.com/spf13/cobra"
@@ -8,6 +10,9 @@ import (
 
 func StatusCmd() *cobra.Command {
 	var containerName string
+	var instance string
+	var format string
+	var full bool
 
 	statusCmd := &cobra.Command{
 		Use:   "status",
@@ -18,21 +23,52 @@ Shows information about running containers including:
 - Container name
 - PostgreSQL version
 - Port mapping
-- Running time`,
+- Running time
+
+For a single container it also prints ready-to-copy connection info, with
+the password read from the container, as a libpq URI, a libpq key/value DSN,
+a JDBC URL and a .env line. --format prints just one of them, for scripts.
+
+--full also reports, for each container, its CPU and memory use, the size of
+its data volume, the server's uptime and client connections, and the size of
+each database.`,
 		Example: `  # Show status of all pgbox containers
   pgbox status
 
   # Show status of a specific container
-  pgbox status -n my-postgres`,
+  pgbox status -n my-postgres
+
+  # Show status of a named instance
+  pgbox status --instance shop
+
+  # Add resource use, uptime, connections and database sizes
+  pgbox status --full
+
+  # Print only the JDBC URL of the auto-detected container
+  pgbox status --format jdbc
+
+  # Append DATABASE_URL to a .env file
+  pgbox status --format env >> .env`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := instanceContainerName(instance, containerName)
+			if err != nil {
+				return err
+			}
 			orch := orchestrator.NewStatusOrchestrator(docker.NewClient(), cmd.OutOrStdout())
-			return orch.Run(orchestrator.StatusConfig{
-				ContainerName: containerName,
+			return orch.Run(cmd.Context(), orchestrator.StatusConfig{
+				ContainerName: name,
+				Format:        format,
+				Full:          full,
 			})
 		},
 	}
 
 	statusCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name to check status for")
+	statusCmd.Flags().StringVar(&instance, "instance", "", "Named instance to check status for (container pgbox-<instance>)")
+	statusCmd.Flags().StringVar(&format, "format", "", "Print only the connection info: "+strings.Join(orchestrator.ConnectionFormats, ", "))
+	statusCmd.Flags().BoolVar(&full, "full", false, "Also show resource use, uptime, connections and database sizes")
+	statusCmd.MarkFlagsMutuallyExclusive("name", "instance")
+	statusCmd.MarkFlagsMutuallyExclusive("full", "format")
 
 	return statusCmd
 }
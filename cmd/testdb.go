@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func TestDBCmd() *cobra.Command {
+	testdbCmd := &cobra.Command{
+		Use:   "testdb",
+		Short: "Provision databases for parallel test workers",
+		Long: `Create and drop numbered copies of a template database inside one running
+container, so parallel test workers each get their own database without
+starting a container per worker.`,
+	}
+
+	testdbCmd.AddCommand(testDBCreateCmd())
+	testdbCmd.AddCommand(testDBDropCmd())
+
+	return testdbCmd
+}
+
+func testDBCreateCmd() *cobra.Command {
+	var containerName string
+	var template string
+	var prefix string
+	var count int
+
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create test databases from a template and print their DSNs",
+		Long: `Create --count databases named <prefix>_1 ... <prefix>_N by cloning the
+template database, replacing any existing databases with the same names.
+One connection string is printed per line.`,
+		Example: `  # Create 8 copies of the default database
+  pgbox testdb create --count 8
+
+  # Clone a migrated schema database with a custom prefix
+  pgbox testdb create --template app_schema --prefix app_worker --count 4`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewTestDBOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			_, err := orch.Create(cmd.Context(), orchestrator.TestDBConfig{
+				ContainerName: containerName,
+				Template:      template,
+				Prefix:        prefix,
+				Count:         count,
+			})
+			return err
+		},
+	}
+
+	createCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	createCmd.Flags().StringVar(&template, "template", "", "Database to clone (default: container's POSTGRES_DB)")
+	createCmd.Flags().StringVar(&prefix, "prefix", "", "Database name prefix (default: <template>_test)")
+	createCmd.Flags().IntVar(&count, "count", 1, "Number of databases to create")
+
+	return createCmd
+}
+
+func testDBDropCmd() *cobra.Command {
+	var containerName string
+	var template string
+	var prefix string
+
+	dropCmd := &cobra.Command{
+		Use:   "drop",
+		Short: "Drop test databases created by testdb create",
+		Example: `  # Drop the default test databases
+  pgbox testdb drop
+
+  # Drop databases created with a custom prefix
+  pgbox testdb drop --prefix app_worker`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewTestDBOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Drop(cmd.Context(), orchestrator.TestDBConfig{
+				ContainerName: containerName,
+				Template:      template,
+				Prefix:        prefix,
+			})
+		},
+	}
+
+	dropCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	dropCmd.Flags().StringVar(&template, "template", "", "Template the databases were cloned from (used for the default prefix)")
+	dropCmd.Flags().StringVar(&prefix, "prefix", "", "Database name prefix (default: <template>_test)")
+
+	return dropCmd
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func TimingsCmd() *cobra.Command {
+	var containerName string
+	var instance string
+
+	timingsCmd := &cobra.Command{
+		Use:   "timings",
+		Short: "Show where the time to create a container went",
+		Long: `Show how long each step of the pgbox up that created a container took:
+
+  build   building the custom image for its extensions
+  pull    pulling the images that were not local
+  create  docker run
+  init    initdb and the init scripts, on a new data volume
+  start   until PostgreSQL accepted connections
+
+The slowest step is marked, with a tip when caching can help. Timings are
+recorded when pgbox up creates a container in the background, and are also
+shown by pgbox status -n <name>.`,
+		Example: `  # Show timings of the auto-detected container
+  pgbox timings
+
+  # Show timings of a named instance
+  pgbox timings --instance shop`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := instanceContainerName(instance, containerName)
+			if err != nil {
+				return err
+			}
+			orch := orchestrator.NewTimingsOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.TimingsConfig{ContainerName: name})
+		},
+	}
+
+	timingsCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	timingsCmd.Flags().StringVar(&instance, "instance", "", "Named instance (container pgbox-<instance>)")
+	timingsCmd.MarkFlagsMutuallyExclusive("name", "instance")
+
+	return timingsCmd
+}
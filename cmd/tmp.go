@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func TmpCmd() *cobra.Command {
+	var pgVersion string
+	var extFlags []string
+	var prefer []string
+	var setFlags []string
+	var profile string
+	var fastUnsafe bool
+	var waitTimeout time.Duration
+
+	tmpCmd := &cobra.Command{
+		Use:   "tmp [flags] -- <command> [args...]",
+		Short: "Run a command against a throwaway PostgreSQL instance",
+		Long: `Start a temporary PostgreSQL instance, run a command against it and remove it.
+
+The instance gets a random container name, password and host port. The command
+runs with DATABASE_URL and the libpq variables PGHOST, PGPORT, PGUSER,
+PGPASSWORD and PGDATABASE pointing at it, and with the terminal's stdin and
+stdout. pgbox's own messages go to stderr, so the command's output can be
+piped or redirected.
+
+When the command exits, or startup fails, the container and its data volume
+are removed. pgbox exits with the command's exit status. A custom image built
+for the extensions is kept, so the next run starts faster.
+
+Extensions, --prefer and [settings] come from pgbox.toml when no flags are
+given. Everything after the first argument belongs to the command, so its own
+flags need no quoting; use -- to make that explicit.`,
+		Example: `  # Run the test suite against a fresh database
+  pgbox tmp -- make test
+
+  # With extensions and a specific version
+  pgbox tmp -v 16 --ext pgvector -- go test ./...
+
+  # Durability trade-offs suit a database that is thrown away
+  pgbox tmp --profile test -- npm test
+
+  # One-off query
+  pgbox tmp -- psql -c 'select version()'`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Keep stdout for the command
+			cmd.SetOut(cmd.ErrOrStderr())
+
+			project, err := loadProject(cmd)
+			if err != nil {
+				return err
+			}
+			extensions, err := ParseExtensionArgs(extFlags, nil)
+			if err != nil {
+				return err
+			}
+			userSettings, err := ParseSettings(setFlags)
+			if err != nil {
+				return err
+			}
+			var settings map[string]string
+			if project != nil {
+				fromProjectList(cmd, "ext", &extensions, project.Extensions)
+				fromProjectList(cmd, "prefer", &prefer, project.Prefer)
+				settings = project.Settings
+			}
+			settings = mergeSettings(settings, userSettings)
+
+			r, err := newResolver(cmd, project)
+			if err != nil {
+				return err
+			}
+			if pgVersion, err = resolveVersion(cmd, r); err != nil {
+				return err
+			}
+
+			orch := orchestrator.NewTmpOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.TmpConfig{
+				Version:     pgVersion,
+				Database:    resolve(cmd, r, config.KeyDatabase),
+				User:        resolve(cmd, r, config.KeyUser),
+				Extensions:  extensions,
+				Prefer:      prefer,
+				Profile:     profile,
+				Settings:    settings,
+				FastUnsafe:  fastUnsafe,
+				WaitTimeout: waitTimeout,
+				Command:     args,
+			})
+		},
+	}
+
+	// Flags after the command belong to it
+	tmpCmd.Flags().SetInterspersed(false)
+	tmpCmd.Flags().StringVarP(&pgVersion, "version", "v", "", versionFlagUsage)
+	tmpCmd.Flags().StringArrayVar(&extFlags, "ext", nil, "Extensions to install (repeatable or comma-separated)")
+	tmpCmd.Flags().StringSliceVar(&prefer, "prefer", nil, "Resolve GUC conflicts in favor of an extension (<extension> or <guc>=<extension>)")
+	tmpCmd.Flags().StringVar(&profile, "profile", "", profileFlagUsage())
+	tmpCmd.Flags().StringArrayVar(&setFlags, "set", nil, "PostgreSQL setting as key=value (repeatable)")
+	tmpCmd.Flags().BoolVar(&fastUnsafe, "fast-unsafe", false, "Disable fsync, synchronous_commit and full_page_writes for speed")
+	tmpCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 60*time.Second, "How long to wait for PostgreSQL to accept connections")
+
+	return tmpCmd
+}
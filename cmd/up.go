@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/container"
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/ahacop/pgbox/internal/util"
 	"github.com/spf13/cobra"
 )
 
@@ -15,41 +22,304 @@ func UpCmd() *cobra.Command {
 	var database string
 	var user string
 	var detach bool
-	var extensionList string
+	var extFlags []string
+	var prefer []string
+	var setFlags []string
+	var envFlags []string
+	var profile string
+	var fastUnsafe bool
+	var hardened bool
+	var all bool
+	var dryRun bool
+	var waitTimeout time.Duration
+	var instance string
+	var ui []string
+	var autoPort bool
+	var genPassword bool
+	var strict bool
+	var removeOnExit bool
+	var progress string
+	var replica bool
+	var pgbouncer bool
+	var interactive bool
+	var restart string
+	var recreate bool
+	var locale string
+	var encoding string
+	var timezone string
+	var initdbArgs []string
+	var ssl bool
+	var pull string
 
 	upCmd := &cobra.Command{
-		Use:   "up",
+		Use:   "up [extension...]",
 		Short: "Start PostgreSQL in Docker",
 		Long: `Start a PostgreSQL instance in Docker with the specified version.
 
 This command starts a PostgreSQL container with sensible defaults for development.
-The container runs in the background by default (detached mode).`,
+The container runs in the background by default (detached mode), and up
+returns once PostgreSQL accepts connections, or fails after --wait-timeout.
+
+Before creating a container, up checks that the host port is free and, if not,
+names the container or process holding it. With --auto-port it moves to the
+next free port instead and prints the new connection string.
+
+An existing container with the same name is restarted. If it was created
+without requested extensions that need their own image or server settings,
+up fails and explains how to add them instead of starting it without them.
+Other differences from the request are noted. --recreate replaces the
+container with one built for the request instead: the data volume and the
+credentials it was created with are kept, and requested extensions it lacked
+are created once it is up.
+
+Two up runs for the same container do not race: the second waits on a lock
+file in ~/.config/pgbox/containers/ and then finds the container the first
+started.
+
+With --gen-password, up generates a random password for a new container and
+stores it in ~/.config/pgbox/containers/<name>.toml (mode 0600). Later
+commands such as psql, status and backup read it from there, and it is
+removed with the container's volume by down -v or clean.
+
+With --detach=false, up follows the container's logs in the terminal. Ctrl+C
+(or SIGTERM) stops the container, and with --rm also removes it; the data
+volume is kept either way.
+
+--profile applies a named set of server settings: dev (more memory, slow
+query and lock wait logging), test (durability off, more connections), ci
+(like test, also autovacuum and JIT off with less memory) or analytics
+(large memory settings and parallel query). Profile values win over the ones
+extensions set, and --set wins over both.
+
+--hardened starts a new container with a locked-down posture for development:
+no-new-privileges, all capabilities dropped except the few the entrypoint
+needs, a read-only root filesystem with tmpfs for /tmp and the socket
+directory, SCRAM authentication for TCP connections, the port published on
+127.0.0.1 only, and a superuser named pgbox_admin unless --user is given.
+Without --password it generates one, as --gen-password does.
+
+With --strict, which is the default when the CI environment variable is set,
+warnings fail the command, and so do missing extensions or errors in the
+server log after startup.
+
+--env KEY=VALUE passes an environment variable to a new container, after the
+ones pgbox sets. POSTGRES_USER, POSTGRES_PASSWORD and POSTGRES_DB come from
+--user, --password and --database instead.
+
+--locale, --encoding and --initdb-arg are passed to initdb, through
+POSTGRES_INITDB_ARGS, when a new data volume is created; --timezone sets TZ,
+from which initdb also takes the timezone setting. They help reproduce
+collation bugs locally. The postgres image only has the C and en_US.UTF-8
+libc locales; for others, use ICU, as in
+--initdb-arg=--locale-provider=icu --initdb-arg=--icu-locale=tr-TR.
+
+--ssl turns on TLS in a new container with a self-signed certificate for
+localhost and the container name. The certificate and key are kept with the
+container's other generated files, and reused while they are valid, so a
+client that trusts the certificate keeps working after --recreate. The
+connection strings of up and pgbox status then ask for sslmode=require.
+
+New containers get the same health check as the db service of pgbox export
+(pg_isready every 10s), so docker ps and tools that wait for a healthy
+container see the same status either way. --restart sets their restart
+policy: no (the default), on-failure or unless-stopped.
+
+Before creating containers, up pulls the images they need that are not
+present locally, all at once, prefixing each line of progress with its
+image; the output of a custom image build is prefixed with "build |". --pull
+always pulls every image again, and builds custom images with docker build
+--pull, to pick up new patch releases; --pull never fails instead of pulling,
+for offline work.
+
+--with-replica also runs a read-only streaming replica in <name>-replica,
+with its own volume and the first free port after the primary's. The primary
+gets a replication slot and a pg_hba.conf entry for replication connections,
+and the replica starts from a pg_basebackup of it. Connect to it with pgbox
+psql --replica; pgbox down stops it and down --rm removes it as well.
+
+--with-pgbouncer also runs pgbouncer in <name>-pgbouncer, in transaction
+pooling mode on the first free port from 6432, so applications can be tested
+against a pooler locally. Its pgbouncer.ini and userlist.txt are generated
+into the container's data directory and rewritten on every up.
+
+--interactive (-i) opens a picker listing the catalog extensions available
+for the version, with their descriptions and whether they need
+shared_preload_libraries (preload) or other settings that take a restart.
+Type to filter with fuzzy search, press space to toggle an extension and
+enter to start with the selection. Extensions given as arguments, with --ext
+or in pgbox.toml start out selected.
+
+--dry-run goes through the same steps but prints, instead of running them,
+the runtime commands that would build the image and create or start the
+container, along with the rendered Dockerfile and init.sql. It still reads
+what exists, such as the containers and images already there.
+
+Values not given on the command line come from PGBOX_* environment variables
+(PGBOX_VERSION, PGBOX_PORT, PGBOX_NAME, PGBOX_USER, PGBOX_PASSWORD,
+PGBOX_DATABASE), then from a pgbox.toml file in the current directory or a
+parent (create one with pgbox init), then from built-in defaults.`,
 		Example: `  # Start PostgreSQL 18 (creates container named pgbox-pg18)
   pgbox up
 
   # Start PostgreSQL 17 (creates container named pgbox-pg17)
   pgbox up -v 17
 
+  # Fall back to the next free port if 5432 is taken
+  pgbox up --auto-port
+
   # Start PostgreSQL with custom name
   pgbox up -n my-postgres
 
-  # Start with extensions
-  pgbox up --ext hypopg,pgvector
+  # Start with extensions (also: --ext pgvector --ext pg_cron, or --ext pgvector,pg_cron)
+  pgbox up pgvector pg_cron
+
+  # Pick extensions from the catalog interactively
+  pgbox up -i
+
+  # Tune the server for a test suite
+  pgbox up --profile test
+
+  # Override PostgreSQL settings
+  pgbox up --set shared_buffers=1GB --set max_connections=200
+
+  # Trade durability for speed on a throwaway test database
+  pgbox up --fast-unsafe
+
+  # Locked-down container, reachable from this machine only
+  pgbox up --hardened
+
+  # Rebuild the image and recreate the container with a new extension,
+  # keeping its data
+  pgbox up --ext pgvector --recreate
+
+  # Come back after a Docker daemon restart or reboot
+  pgbox up --restart unless-stopped
 
   # Start in foreground (attached mode)
   pgbox up --detach=false
 
+  # Start in foreground and remove the container on Ctrl+C (keeps the volume)
+  pgbox up --detach=false --rm
+
+  # Use a generated password instead of "postgres"
+  pgbox up --gen-password
+
+  # Pass environment variables through to the container
+  pgbox up --env LANG=en_US.utf8 --env PGOPTIONS=-cjit=off
+
+  # A Turkish ICU collation and a time zone, to reproduce collation bugs
+  pgbox up --timezone Europe/Istanbul --initdb-arg=--locale-provider=icu --initdb-arg=--icu-locale=tr-TR
+
+  # A single-byte encoding with the C locale
+  pgbox up --locale C --encoding LATIN1
+
+  # TLS with a generated self-signed certificate
+  pgbox up --ssl
+
   # Start with custom database and user
-  pgbox up --database=mydb --user=myuser --password=secret`,
+  pgbox up --database=mydb --user=myuser --password=secret
+
+  # Run a second, independent environment next to the default one
+  # (container pgbox-shop, volume pgbox-shop-data, first free port from 5432)
+  pgbox up --instance shop
+
+  # Also run pgAdmin, connected to the database
+  pgbox up --with-ui pgadmin
+
+  # Also run a read-only replica to test read/write splitting
+  pgbox up --with-replica
+
+  # Also run pgbouncer in transaction pooling mode on port 6432
+  pgbox up --with-pgbouncer
+
+  # Pick up the latest patch release of the image
+  pgbox up --pull always
+
+  # Allow a slow first start (large init scripts) up to 5 minutes
+  pgbox up --wait-timeout 5m
+
+  # Show the docker commands, Dockerfile and init.sql without running anything
+  pgbox up pgvector --dry-run
+
+  # Start every instance declared under [instances] in pgbox.toml
+  pgbox up --all`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := ValidatePostgresVersion(pgVersion); err != nil {
+			project, err := loadProject(cmd)
+			if err != nil {
+				return err
+			}
+			if all {
+				instances, err := projectInstances(project)
+				if err != nil {
+					return err
+				}
+				return orchestrator.NewInstancesOrchestrator(docker.NewClient(), cmd.OutOrStdout()).Up(cmd.Context(), instances)
+			}
+
+			extensions, err := ParseExtensionArgs(extFlags, args)
+			if err != nil {
+				return err
+			}
+			userSettings, err := ParseSettings(setFlags)
+			if err != nil {
+				return err
+			}
+			env, err := ParseEnv(envFlags)
+			if err != nil {
 				return err
 			}
+			var settings map[string]string
+			if project != nil {
+				if len(args) == 0 {
+					fromProjectList(cmd, "ext", &extensions, project.Extensions)
+				}
+				fromProjectList(cmd, "prefer", &prefer, project.Prefer)
+				settings = project.Settings
+			}
+			settings = mergeSettings(settings, userSettings)
+
+			r, err := newResolver(cmd, project)
+			if err != nil {
+				return err
+			}
+			if pgVersion, err = resolveVersion(cmd, r); err != nil {
+				return err
+			}
+			if interactive {
+				if extensions, err = pickExtensions(cmd, pgVersion, "start", extensions); err != nil {
+					return err
+				}
+			}
+			port = resolve(cmd, r, config.KeyPort)
+			name = resolve(cmd, r, config.KeyName)
+			user = resolve(cmd, r, config.KeyUser)
+			password = resolve(cmd, r, config.KeyPassword)
+			database = resolve(cmd, r, config.KeyDatabase)
+			if _, source := r.Get(config.KeyPassword); source == config.SourceDefault {
+				// Leave it to the orchestrator, which prefers a stored password
+				password = ""
+			}
+			if _, source := r.Get(config.KeyUser); source == config.SourceDefault && hardened {
+				// Let --hardened pick a non-default superuser
+				user = ""
+			}
+
+			if instance != "" {
+				if name, err = container.InstanceName(instance); err != nil {
+					return err
+				}
+				if _, source := r.Get(config.KeyPort); source == config.SourceDefault {
+					free, err := util.FreePort(5432, 100)
+					if err != nil {
+						return err
+					}
+					port = strconv.Itoa(free)
+				}
+			}
 
-			extensions := ParseExtensionList(extensionList)
 			orch := orchestrator.NewUpOrchestrator(docker.NewClient(), cmd.OutOrStdout())
 
-			return orch.Run(orchestrator.UpConfig{
+			cfg := orchestrator.UpConfig{
 				Version:       pgVersion,
 				Port:          port,
 				ContainerName: name,
@@ -58,18 +328,77 @@ The container runs in the background by default (detached mode).`,
 				User:          user,
 				Detach:        detach,
 				Extensions:    extensions,
-			})
+				Prefer:        prefer,
+				FastUnsafe:    fastUnsafe,
+				Hardened:      hardened,
+				Profile:       profile,
+				Settings:      settings,
+				WaitTimeout:   waitTimeout,
+				UI:            ui,
+				AutoPort:      autoPort,
+				GenPassword:   genPassword,
+				Strict:        strict,
+				RemoveOnExit:  removeOnExit,
+				BuildProgress: progress,
+				DryRun:        dryRun,
+				Replica:       replica,
+				Pgbouncer:     pgbouncer,
+				Restart:       restart,
+				Env:           env,
+				Recreate:      recreate,
+				Locale:        locale,
+				Encoding:      encoding,
+				Timezone:      timezone,
+				InitdbArgs:    initdbArgs,
+				SSL:           ssl,
+				Pull:          pull,
+			}
+			cfg.GUCChoices = promptGUCChoices(os.Stdin, cmd.OutOrStdout(), util.StdinIsTerminal(), orch.GUCConflicts(cmd.Context(), cfg))
+			return orch.Run(cmd.Context(), cfg)
 		},
 	}
 
-	upCmd.Flags().StringVarP(&pgVersion, "version", "v", config.DefaultVersion, "PostgreSQL version (16, 17, or 18)")
+	upCmd.Flags().StringVarP(&pgVersion, "version", "v", "", versionFlagUsage)
 	upCmd.Flags().StringVarP(&port, "port", "p", "5432", "Port to expose PostgreSQL on")
 	upCmd.Flags().StringVarP(&name, "name", "n", "", "Container name (default: pgbox-pg<version>)")
+	upCmd.Flags().BoolVar(&autoPort, "auto-port", false, "Use the next free port when --port is already taken")
 	upCmd.Flags().StringVar(&password, "password", "postgres", "PostgreSQL password")
+	upCmd.Flags().BoolVar(&genPassword, "gen-password", false, "Generate a random password and store it under the user config directory")
 	upCmd.Flags().StringVar(&database, "database", "postgres", "Default database name")
 	upCmd.Flags().StringVar(&user, "user", "postgres", "PostgreSQL user")
 	upCmd.Flags().BoolVarP(&detach, "detach", "d", true, "Run container in background")
-	upCmd.Flags().StringVar(&extensionList, "ext", "", "Comma-separated list of extensions to install")
+	upCmd.Flags().StringVar(&restart, "restart", "", "Restart policy of a new container: "+strings.Join(orchestrator.RestartPolicies, ", "))
+	upCmd.Flags().BoolVar(&recreate, "recreate", false, "Replace an existing container with one built for the requested extensions and settings, keeping its data volume")
+	upCmd.Flags().BoolVar(&removeOnExit, "rm", false, "With --detach=false, remove the container when it stops (keeps the data volume)")
+	upCmd.Flags().StringArrayVar(&extFlags, "ext", nil, "Extensions to install (repeatable or comma-separated; or give them as arguments)")
+	upCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Choose extensions in an interactive picker with fuzzy search")
+	upCmd.Flags().StringVar(&instance, "instance", "", "Named instance (container pgbox-<instance>, port defaults to the first free one from 5432)")
+	upCmd.Flags().BoolVar(&all, "all", false, "Start all [instances] from pgbox.toml in dependency order")
+	upCmd.Flags().StringVar(&profile, "profile", "", profileFlagUsage())
+	upCmd.Flags().StringArrayVar(&setFlags, "set", nil, "PostgreSQL setting as key=value, overriding extensions, --fast-unsafe and pgbox.toml (repeatable)")
+	upCmd.Flags().StringArrayVar(&envFlags, "env", nil, "Environment variable for a new container as KEY=VALUE (repeatable)")
+	upCmd.Flags().StringVar(&locale, "locale", "", "Locale of a new data volume, passed to initdb (for example C or en_US.UTF-8)")
+	upCmd.Flags().StringVar(&encoding, "encoding", "", "Encoding of a new data volume, passed to initdb (for example UTF8 or LATIN1)")
+	upCmd.Flags().StringVar(&timezone, "timezone", "", "Time zone (TZ) of a new container, such as Europe/Berlin")
+	upCmd.Flags().StringArrayVar(&initdbArgs, "initdb-arg", nil, "Further initdb argument for a new data volume, such as --initdb-arg=--data-checksums (repeatable)")
+	upCmd.Flags().BoolVar(&fastUnsafe, "fast-unsafe", false, "Disable fsync, synchronous_commit and full_page_writes (data may be lost on crash)")
+	upCmd.Flags().BoolVar(&ssl, "ssl", false, "Turn on TLS in a new container with a generated self-signed certificate")
+	upCmd.Flags().BoolVar(&hardened, "hardened", false, "Restricted container: no-new-privileges, minimal capabilities, read-only root, SCRAM auth, non-default superuser, port on 127.0.0.1")
+	upCmd.Flags().BoolVar(&strict, "strict", runningInCI(), "Fail on warnings and on issues found after startup (default: on when $CI is set)")
+	upCmd.Flags().StringVar(&pull, "pull", orchestrator.PullMissing, "When to pull images: "+strings.Join(orchestrator.PullPolicies, ", "))
+	upCmd.Flags().StringVar(&progress, "progress", "", "Build output for custom images, passed to docker build --progress (auto, plain, tty, quiet)")
+	upCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 60*time.Second, "How long to wait for PostgreSQL to accept connections")
+	upCmd.Flags().StringSliceVar(&ui, "with-ui", nil, "Also run database UIs in their own containers: "+strings.Join(orchestrator.UIToolNames(), ", "))
+	upCmd.Flags().BoolVar(&replica, "with-replica", false, "Also run a read-only streaming replica on the next free port (connect with pgbox psql --replica)")
+	upCmd.Flags().BoolVar(&pgbouncer, "with-pgbouncer", false, "Also run pgbouncer in transaction pooling mode on the first free port from 6432")
+	upCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the runtime commands and rendered Dockerfile and init.sql without creating, building or starting anything")
+	upCmd.Flags().StringSliceVar(&prefer, "prefer", nil, "Resolve GUC conflicts in favor of an extension (<extension> or <guc>=<extension>)")
+	upCmd.MarkFlagsMutuallyExclusive("name", "instance")
+	upCmd.MarkFlagsMutuallyExclusive("all", "instance")
+	upCmd.MarkFlagsMutuallyExclusive("all", "dry-run")
+	upCmd.MarkFlagsMutuallyExclusive("all", "recreate")
+	upCmd.MarkFlagsMutuallyExclusive("all", "interactive")
+	upCmd.MarkFlagsMutuallyExclusive("password", "gen-password")
 
 	return upCmd
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func UpgradeCmd() *cobra.Command {
+	var containerName string
+	var from string
+	var to string
+
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade --to <version>",
+		Short: "Move a container's data to a newer PostgreSQL major version",
+		Long: `Upgrade a container to a newer PostgreSQL major version.
+
+The old container is started if needed and all of its databases and roles are
+dumped with pg_dumpall. A container on the new version is then started with
+the same extensions, settings, credentials and port, and the dump is restored
+into its fresh data volume. The dump is kept under the pgbox data directory.
+
+A container with a default name such as pgbox-pg16 is upgraded to a new
+pgbox-pg17, and the old container is left stopped with its data. Any other
+container keeps its name: its old data volume is copied to
+<name>-pg<from>-data first, so the previous version can be started again.`,
+		Example: `  # Upgrade pgbox-pg16 to PostgreSQL 17 (creates pgbox-pg17)
+  pgbox upgrade --from 16 --to 17
+
+  # Upgrade a named container in place
+  pgbox upgrade -n myapp --to 18`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from != "" {
+				if err := ValidatePostgresVersion(from); err != nil {
+					return err
+				}
+			}
+			if err := ValidatePostgresVersion(to); err != nil {
+				return err
+			}
+			orch := orchestrator.NewUpgradeOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.UpgradeConfig{
+				ContainerName: containerName,
+				From:          from,
+				To:            to,
+			})
+		},
+	}
+
+	upgradeCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: pgbox-pg<from>, then auto-detect)")
+	upgradeCmd.Flags().StringVar(&from, "from", "", "PostgreSQL version the container runs now (default: read from the container)")
+	upgradeCmd.Flags().StringVar(&to, "to", "", "PostgreSQL version to upgrade to")
+	_ = upgradeCmd.MarkFlagRequired("to")
+
+	return upgradeCmd
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func VacuumStatusCmd() *cobra.Command {
+	var containerName string
+	var database string
+	var user string
+	var top int
+
+	vacuumStatusCmd := &cobra.Command{
+		Use:   "vacuum-status",
+		Short: "Show autovacuum state per table",
+		Long: `Show dead tuples, the autovacuum trigger point, and the last vacuum and
+analyze times for the tables with the most dead tuples, along with any
+autovacuum workers currently running.
+
+Tables that autovacuum will never process under the current settings (for
+example autovacuum=off or autovacuum_enabled=false on the table) are flagged.`,
+		Example: `  # Vacuum state of the default database
+  pgbox vacuum-status
+
+  # The 50 tables with the most dead tuples in a specific database
+  pgbox vacuum-status -d mydb --top 50`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewVacuumStatusOrchestrator(docker.NewClient(), cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.VacuumStatusConfig{
+				ContainerName: containerName,
+				Database:      database,
+				User:          user,
+				Top:           top,
+			})
+		},
+	}
+
+	vacuumStatusCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	vacuumStatusCmd.Flags().StringVarP(&database, "database", "d", "", "Database to inspect (default: container's POSTGRES_DB)")
+	vacuumStatusCmd.Flags().StringVarP(&user, "user", "u", "", "Username for connection (default: container's POSTGRES_USER)")
+	vacuumStatusCmd.Flags().IntVar(&top, "top", 20, "Number of tables to list")
+
+	return vacuumStatusCmd
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func VolumeCmd() *cobra.Command {
+	volumeCmd := &cobra.Command{
+		Use:   "volume",
+		Short: "Manage data volumes and copy them to and from the host",
+		Long: `List, inspect and remove pgbox data volumes, and export and import them as
+tar.gz archives.
+
+Data is streamed through a short-lived helper container rather than read from
+the volume's path on the host, so this works when the container daemon runs in
+a VM (Colima, Docker Desktop, podman machine).`,
+	}
+
+	volumeCmd.AddCommand(volumeListCmd())
+	volumeCmd.AddCommand(volumeInspectCmd())
+	volumeCmd.AddCommand(volumeRemoveCmd())
+	volumeCmd.AddCommand(volumeExportCmd())
+	volumeCmd.AddCommand(volumeImportCmd())
+
+	return volumeCmd
+}
+
+func volumeListCmd() *cobra.Command {
+	var jsonOutput bool
+
+	listCmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List pgbox data volumes",
+		Long: `List pgbox data volumes with their size and the container, state, PostgreSQL
+version and image each belongs to.
+
+Sizes come from the runtime's system df report, or from a helper container for
+volumes it leaves out. A volume whose container was removed shows as missing,
+with the version and image its name records.`,
+		Example: `  # List data volumes
+  pgbox volume ls
+
+  # List them as JSON
+  pgbox volume ls --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewVolumeOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.List(cmd.Context(), orchestrator.VolumeListConfig{JSON: jsonOutput})
+		},
+	}
+
+	listCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the volumes as JSON")
+
+	return listCmd
+}
+
+func volumeInspectCmd() *cobra.Command {
+	var jsonOutput bool
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <container|volume>",
+		Short: "Show a data volume and the container it belongs to",
+		Long: `Show a data volume's size, creation time and mount point, and the container,
+PostgreSQL version, image and extensions it belongs to.`,
+		Example: `  # Show the data volume of container pgbox-pg17
+  pgbox volume inspect pgbox-pg17
+
+  # The same, as JSON
+  pgbox volume inspect pgbox-pg17-data --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewVolumeOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.Inspect(cmd.Context(), orchestrator.VolumeInspectConfig{Target: args[0], JSON: jsonOutput})
+		},
+	}
+
+	inspectCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the volume as JSON")
+
+	return inspectCmd
+}
+
+func volumeRemoveCmd() *cobra.Command {
+	var force bool
+
+	removeCmd := &cobra.Command{
+		Use:     "rm <container|volume>...",
+		Aliases: []string{"remove"},
+		Short:   "Remove data volumes",
+		Long: `Remove specific data volumes, deleting their databases.
+
+A volume in use by a running container is refused; stop it first with pgbox
+down. A stopped container that owns a volume is removed with it, as it cannot
+start without its data. Asks for confirmation unless --force is given.`,
+		Example: `  # Remove the data volume of a stopped container, and the container
+  pgbox volume rm pgbox-pg16
+
+  # Remove two volumes without confirmation
+  pgbox volume rm pgbox-pg15-data pgbox-shop-data --force`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewVolumeOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.Remove(cmd.Context(), orchestrator.VolumeRemoveConfig{Targets: args, Force: force})
+		},
+	}
+
+	removeCmd.Flags().BoolVarP(&force, "force", "f", false, "Skip the confirmation prompt")
+
+	return removeCmd
+}
+
+func volumeExportCmd() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export <container|volume> [file]",
+		Short: "Export a data volume to a tar.gz archive",
+		Long: `Export a stopped container's data volume to a tar.gz archive on the host.
+
+A <file>.sha256 checksum is written next to the archive, and the archive is
+read back once to verify it before the command succeeds. Without a file
+argument the archive is named <volume>-<timestamp>.tar.gz.`,
+		Example: `  # Export the data volume of a stopped container
+  pgbox down -n pgbox-pg17
+  pgbox volume export pgbox-pg17
+
+  # Export a volume to a specific file
+  pgbox volume export pgbox-pg17-data ./pg17.tar.gz`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := ""
+			if len(args) == 2 {
+				output = args[1]
+			}
+
+			orch := orchestrator.NewVolumeOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.Export(cmd.Context(), orchestrator.VolumeExportConfig{
+				Target: args[0],
+				Output: output,
+			})
+		},
+	}
+
+	return exportCmd
+}
+
+func volumeImportCmd() *cobra.Command {
+	var force bool
+
+	importCmd := &cobra.Command{
+		Use:   "import <file> <container|volume>",
+		Short: "Import a tar.gz archive into a data volume",
+		Long: `Import an archive created by pgbox volume export into a data volume.
+
+The archive is checked against its .sha256 file when one exists. The volume is
+created if needed; a volume that already has data is only replaced with --force.`,
+		Example: `  # Restore an export into the volume of container pgbox-pg17
+  pgbox volume import ./pg17.tar.gz pgbox-pg17
+
+  # Replace the contents of an existing volume
+  pgbox volume import ./pg17.tar.gz pgbox-pg17 --force`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orch := orchestrator.NewVolumeOrchestrator(docker.NewClient(), cmd.OutOrStdout(), os.Stdin)
+			return orch.Import(cmd.Context(), orchestrator.VolumeImportConfig{
+				Input:  args[0],
+				Target: args[1],
+				Force:  force,
+			})
+		},
+	}
+
+	importCmd.Flags().BoolVar(&force, "force", false, "Replace the contents of a non-empty volume")
+
+	return importCmd
+}
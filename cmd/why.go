@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+	"github.com/spf13/cobra"
+)
+
+func WhyCmd() *cobra.Command {
+	var containerName string
+	var dir string
+
+	whyCmd := &cobra.Command{
+		Use:   "why <setting|package|library|fragment>",
+		Short: "Explain which extension, profile or flag introduced something",
+		Long: `Explain why a setting, apt package, shared_preload_libraries entry or
+init.sql fragment is present in a pgbox container or exported scaffold, for
+example which extension set wal_level to logical.
+
+For containers, pgbox reads the settings the container was started with and,
+while it is running, its init.sql and installed packages. For scaffolds
+written by pgbox export, it reads the Dockerfile, docker-compose.yml, init SQL
+and postgresql.conf.pgbox, which records the source of every setting.`,
+		Example: `  # Why is wal_level not the default?
+  pgbox why wal_level
+
+  # Why is pg_cron preloaded in an exported project?
+  pgbox why pg_cron --dir ./db
+
+  # Which extension needs this package?
+  pgbox why postgresql-17-cron -n my-postgres`,
+		Annotations: noDaemon,
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := docker.NewClient()
+			if dir == "" {
+				if err := client.CheckDaemon(cmd.Context()); err != nil {
+					return err
+				}
+			}
+			orch := orchestrator.NewWhyOrchestrator(client, cmd.OutOrStdout())
+			return orch.Run(cmd.Context(), orchestrator.WhyConfig{
+				ContainerName: containerName,
+				Dir:           dir,
+				Item:          args[0],
+			})
+		},
+	}
+
+	whyCmd.Flags().StringVarP(&containerName, "name", "n", "", "Container name (default: auto-detect)")
+	whyCmd.Flags().StringVar(&dir, "dir", "", "Explain a scaffold written by pgbox export instead of a container")
+	whyCmd.MarkFlagsMutuallyExclusive("name", "dir")
+
+	return whyCmd
+}
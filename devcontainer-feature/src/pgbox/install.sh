@@ -0,0 +1,56 @@
+#!/bin/sh
+#
+# devcontainer feature install script for pgbox.
+# Installs the release binary using the project installer and writes the
+# start script run by postStartCommand.
+#
+
+set -e
+
+PGBOX_VERSION="${VERSION:-latest}"
+AUTOSTART="${AUTOSTART:-true}"
+
+if ! command -v curl >/dev/null 2>&1; then
+    if command -v apt-get >/dev/null 2>&1; then
+        apt-get update
+        apt-get install -y --no-install-recommends curl ca-certificates
+        rm -rf /var/lib/apt/lists/*
+    elif command -v apk >/dev/null 2>&1; then
+        apk add --no-cache curl ca-certificates
+    else
+        echo "curl is required to install pgbox" >&2
+        exit 1
+    fi
+fi
+
+ref="main"
+if [ "$PGBOX_VERSION" != "latest" ]; then
+    ref="$PGBOX_VERSION"
+fi
+
+curl -fsSL "https://raw.githubusercontent.com/ahacop/pgbox/$ref/install.sh" | \
+    INSTALL_DIR=/usr/local/bin PGBOX_VERSION="$PGBOX_VERSION" PGBOX_FORCE=true sh
+
+mkdir -p /usr/local/share/pgbox
+cat > /usr/local/share/pgbox/start.sh <<SCRIPT
+#!/bin/sh
+# Starts the workspace database on container start. Generated by the pgbox feature.
+
+if [ "$AUTOSTART" != "true" ] || [ ! -f pgbox.toml ]; then
+    exit 0
+fi
+
+# docker-in-docker starts the daemon in the background; give it time to come up
+i=0
+while ! docker info >/dev/null 2>&1; do
+    i=\$((i + 1))
+    if [ "\$i" -ge 30 ]; then
+        echo "pgbox: docker is not available, skipping pgbox up" >&2
+        exit 0
+    fi
+    sleep 1
+done
+
+pgbox up
+SCRIPT
+chmod +x /usr/local/share/pgbox/start.sh
@@ -26,7 +26,7 @@
 
           src = ./.;
 
-          vendorHash = "sha256-d74qMuSWFX12B9tPKT7DfTr50ssOmyCJLAMg6hSka78=";
+          vendorHash = "sha256-KZ6vwxbysy+4mZ+J6r/PE6QjVgiZYs1igdIq6c65IAc=";
 
           ldflags = [
             "-s"
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ArtifactManifestFile lists the files in a container's artifacts directory.
+const ArtifactManifestFile = "manifest.toml"
+
+// ArtifactManifest describes the files pgbox generated for a container and
+// mounted into it. They live as long as the container, so unlike files in
+// the system temp directory they are still there when it restarts.
+type ArtifactManifest struct {
+	Container string     `toml:"container"`
+	Written   time.Time  `toml:"written"`
+	Files     []Artifact `toml:"files"`
+}
+
+// Artifact is one generated file.
+type Artifact struct {
+	File   string `toml:"file"`  // Name within the artifacts directory
+	Mount  string `toml:"mount"` // Path it is mounted at in the container
+	SHA256 string `toml:"sha256"`
+}
+
+// ArtifactsRoot returns the directory holding every container's artifacts
+// directory: <DataDir>/containers.
+func ArtifactsRoot() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "containers"), nil
+}
+
+// ArtifactsDir returns the directory of a container's generated files:
+// <DataDir>/containers/<name>.
+func ArtifactsDir(name string) (string, error) {
+	root, err := ArtifactsRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, name), nil
+}
+
+// WriteArtifact writes a generated file into a container's artifacts
+// directory and records it in the manifest, replacing an earlier record of
+// the same file. It returns the file's path on the host.
+func WriteArtifact(name, file, mount string, content []byte) (string, error) {
+	dir, err := ArtifactsDir(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, file)
+	// Readable by the postgres user in the container, which is not the owner
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	manifest, err := LoadArtifactManifest(name)
+	if err != nil {
+		return "", err
+	}
+	if manifest == nil {
+		manifest = &ArtifactManifest{Container: name}
+	}
+	sum := sha256.Sum256(content)
+	files := []Artifact{{File: file, Mount: mount, SHA256: hex.EncodeToString(sum[:])}}
+	for _, a := range manifest.Files {
+		if a.File != file {
+			files = append(files, a)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+	manifest.Files = files
+	manifest.Written = time.Now().UTC().Truncate(time.Second)
+
+	var b strings.Builder
+	b.WriteString("# Written by pgbox; removed with the container.\n")
+	if err := toml.NewEncoder(&b).Encode(manifest); err != nil {
+		return "", fmt.Errorf("failed to encode artifact manifest: %w", err)
+	}
+	manifestPath := filepath.Join(dir, ArtifactManifestFile)
+	if err := os.WriteFile(manifestPath, []byte(b.String()), 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", manifestPath, err)
+	}
+	return path, nil
+}
+
+// LoadArtifactManifest reads the manifest of a container's artifacts.
+// Returns nil when there is none.
+func LoadArtifactManifest(name string) (*ArtifactManifest, error) {
+	dir, err := ArtifactsDir(name)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, ArtifactManifestFile)
+	var manifest ArtifactManifest
+	if _, err := toml.DecodeFile(path, &manifest); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// ListArtifacts returns the names of the containers that have an artifacts
+// directory, sorted.
+func ListArtifacts() ([]string, error) {
+	root, err := ArtifactsRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// RemoveArtifacts deletes a container's artifacts directory, if any.
+func RemoveArtifacts(name string) error {
+	dir, err := ArtifactsDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifacts_WriteListRemove(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+
+	names, err := ListArtifacts()
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	path, err := WriteArtifact("pgbox-pg17", "init.sql", "/docker-entrypoint-initdb.d/init.sql", []byte("SELECT 1;\n"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "pgbox", "containers", "pgbox-pg17", "init.sql"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1;\n", string(content))
+
+	_, err = WriteArtifact("pgbox-pg17", "init.sql", "/docker-entrypoint-initdb.d/init.sql", []byte("SELECT 2;\n"))
+	require.NoError(t, err)
+	manifest, err := LoadArtifactManifest("pgbox-pg17")
+	require.NoError(t, err)
+	require.NotNil(t, manifest)
+	assert.Equal(t, "pgbox-pg17", manifest.Container)
+	assert.Equal(t, []Artifact{{
+		File:   "init.sql",
+		Mount:  "/docker-entrypoint-initdb.d/init.sql",
+		SHA256: "a41109d24069b4822ddc5f367b25d484dc7e839bff338ce7a3e5da641caacda0",
+	}}, manifest.Files)
+
+	names, err = ListArtifacts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17"}, names)
+
+	require.NoError(t, RemoveArtifacts("pgbox-pg17"))
+	require.NoError(t, RemoveArtifacts("pgbox-pg17"))
+	assert.NoDirExists(t, filepath.Dir(path))
+	manifest, err = LoadArtifactManifest("pgbox-pg17")
+	require.NoError(t, err)
+	assert.Nil(t, manifest)
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ImageRecord describes a custom image pgbox built, so a later build for a
+// different set of extensions can reuse it when it already contains
+// everything that build would install.
+type ImageRecord struct {
+	Image     string    `toml:"image"`
+	Version   string    `toml:"version"`    // PostgreSQL major version
+	BaseImage string    `toml:"base_image"` // Image the Dockerfile starts from
+	Packages  []string  `toml:"packages"`   // apt packages and .deb/.zip URLs, sorted
+	Built     time.Time `toml:"built"`
+}
+
+// Contains reports whether the image has every package in packages.
+func (r ImageRecord) Contains(packages []string) bool {
+	for _, pkg := range packages {
+		if !slices.Contains(r.Packages, pkg) {
+			return false
+		}
+	}
+	return true
+}
+
+// imageCache is the layout of the image cache file.
+type imageCache struct {
+	Images []ImageRecord `toml:"images"`
+}
+
+// ImageCachePath returns the file custom images are recorded in:
+// <DataDir>/images.toml.
+func ImageCachePath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "images.toml"), nil
+}
+
+// LoadImageRecords reads the recorded custom images. Returns nil when none
+// were recorded yet.
+func LoadImageRecords() ([]ImageRecord, error) {
+	path, err := ImageCachePath()
+	if err != nil {
+		return nil, err
+	}
+	var cache imageCache
+	if _, err := toml.DecodeFile(path, &cache); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cache.Images, nil
+}
+
+// SaveImageRecord records a custom image, replacing an earlier record of the
+// same image.
+func SaveImageRecord(record ImageRecord) error {
+	records, err := LoadImageRecords()
+	if err != nil {
+		return err
+	}
+	records = slices.DeleteFunc(records, func(r ImageRecord) bool { return r.Image == record.Image })
+	return writeImageRecords(append(records, record))
+}
+
+// RemoveImageRecord forgets a custom image, e.g. once it no longer exists.
+func RemoveImageRecord(image string) error {
+	records, err := LoadImageRecords()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(slices.Clone(records), func(r ImageRecord) bool { return r.Image == image })
+	if len(kept) == len(records) {
+		return nil
+	}
+	return writeImageRecords(kept)
+}
+
+// writeImageRecords replaces the image cache file. It writes a temporary
+// file and renames it, so a concurrent reader never sees a partial file.
+func writeImageRecords(records []ImageRecord) error {
+	path, err := ImageCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	var b strings.Builder
+	b.WriteString("# Custom images built by pgbox, reused by builds they have all packages for.\n")
+	if err := toml.NewEncoder(&b).Encode(imageCache{Images: records}); err != nil {
+		return fmt.Errorf("failed to encode image cache: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "images-*.toml")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRecords_RoundTrip(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	records, err := LoadImageRecords()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	built := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	a := ImageRecord{Image: "pgbox-pg17-custom:a", Version: "17", BaseImage: "postgres:17", Packages: []string{"postgresql-17-cron", "postgresql-17-pgvector"}, Built: built}
+	b := ImageRecord{Image: "pgbox-pg17-custom:b", Version: "17", BaseImage: "postgres:17", Packages: []string{"postgresql-17-pgvector"}, Built: built}
+	require.NoError(t, SaveImageRecord(a))
+	require.NoError(t, SaveImageRecord(b))
+	b.Packages = []string{"postgresql-17-hypopg"}
+	require.NoError(t, SaveImageRecord(b))
+
+	records, err = LoadImageRecords()
+	require.NoError(t, err)
+	assert.Equal(t, []ImageRecord{a, b}, records)
+
+	require.NoError(t, RemoveImageRecord(a.Image))
+	require.NoError(t, RemoveImageRecord(a.Image))
+	records, err = LoadImageRecords()
+	require.NoError(t, err)
+	assert.Equal(t, []ImageRecord{b}, records)
+}
+
+func TestImageRecord_Contains(t *testing.T) {
+	r := ImageRecord{Packages: []string{"postgresql-17-cron", "postgresql-17-pgvector"}}
+
+	assert.True(t, r.Contains([]string{"postgresql-17-pgvector"}))
+	assert.True(t, r.Contains(nil))
+	assert.False(t, r.Contains([]string{"postgresql-17-pgvector", "postgresql-17-hypopg"}))
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ErrContainerLocked is returned by TryLockContainer while another pgbox
+// process holds the lock.
+var ErrContainerLocked = errors.New("container is locked by another pgbox process")
+
+// ContainerLock is an exclusive lock on a container name, held while pgbox
+// creates or starts it. The operating system releases it if pgbox exits.
+type ContainerLock struct {
+	file *os.File
+}
+
+// ContainerLockPath returns the lock file for a container, next to its state
+// file.
+func ContainerLockPath(name string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "containers", name+".lock"), nil
+}
+
+// TryLockContainer takes the lock for a container without waiting. Returns
+// ErrContainerLocked when another process holds it.
+func TryLockContainer(name string) (*ContainerLock, error) {
+	path, err := ContainerLockPath(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	// The file is never removed, since removing it would let a waiting
+	// process lock a file that a new process no longer sees
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrContainerLocked
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &ContainerLock{file: file}, nil
+}
+
+// Release gives up the lock. It is safe to call more than once.
+func (l *ContainerLock) Release() {
+	if l == nil || l.file == nil {
+		return
+	}
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	_ = l.file.Close()
+	l.file = nil
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryLockContainer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	lock, err := TryLockContainer("pgbox-pg17")
+	require.NoError(t, err)
+
+	_, err = TryLockContainer("pgbox-pg17")
+	assert.ErrorIs(t, err, ErrContainerLocked)
+
+	other, err := TryLockContainer("pgbox-pg16")
+	require.NoError(t, err, "locks are per container")
+	other.Release()
+
+	lock.Release()
+	lock.Release()
+	again, err := TryLockContainer("pgbox-pg17")
+	require.NoError(t, err)
+	again.Release()
+}
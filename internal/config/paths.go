@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DataDir returns the directory pgbox keeps local data in:
+// $XDG_DATA_HOME/pgbox, or ~/.local/share/pgbox when that is unset.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "pgbox"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "pgbox"), nil
+}
+
+// SnapshotDir returns the directory volume snapshots are stored in.
+func SnapshotDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshots"), nil
+}
+
+// ConfigDir returns the directory for per-user configuration:
+// $XDG_CONFIG_HOME/pgbox, or ~/.config/pgbox when that is unset.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "pgbox"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "pgbox"), nil
+}
+
+// UpgradeDir returns the directory pgbox upgrade keeps its dumps in.
+func UpgradeDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "upgrades"), nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/tmp/xdg")
+	dir, err := SnapshotDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/tmp/xdg", "pgbox", "snapshots"), dir)
+
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("HOME", "/home/dev")
+	dir, err = SnapshotDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/dev", ".local", "share", "pgbox", "snapshots"), dir)
+}
+
+func TestConfigDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg-config")
+	dir, err := ConfigDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/tmp/xdg-config", "pgbox"), dir)
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/dev")
+	dir, err = ConfigDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/dev", ".config", "pgbox"), dir)
+}
@@ -1,11 +1,21 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // DefaultVersion is the default PostgreSQL version used throughout pgbox.
 // This is the single source of truth for the default version.
 const DefaultVersion = "18"
 
+// SupportedVersions lists the supported PostgreSQL major versions, oldest first.
+var SupportedVersions = []string{"16", "17", "18"}
+
+// LatestKeyword may be given instead of a version to mean the newest
+// supported version.
+const LatestKeyword = "latest"
+
 // PostgresConfig holds PostgreSQL-specific configuration
 type PostgresConfig struct {
 	Version     string // PostgreSQL version (e.g., "16", "17")
@@ -13,7 +23,12 @@ type PostgresConfig struct {
 	Database    string
 	User        string
 	Password    string
-	CustomImage string // Custom Docker image name when using extensions
+	CustomImage string   // Custom Docker image name when using extensions
+	Locale      string   // initdb --locale of a new data directory
+	Encoding    string   // initdb --encoding of a new data directory
+	Timezone    string   // TZ of the container; initdb also takes the timezone setting from it
+	InitdbArgs  []string // Further initdb arguments, such as --locale-provider=icu
+	SSL         bool     // Serve TLS with a self-signed certificate, so clients connect with sslmode=require
 }
 
 // NewPostgresConfig returns a PostgresConfig with default values
@@ -34,3 +49,76 @@ func (c *PostgresConfig) Image() string {
 	}
 	return fmt.Sprintf("postgres:%s", c.Version)
 }
+
+// InitdbEnv returns the environment variables that pass Locale, Encoding,
+// InitdbArgs and Timezone to the postgres image: POSTGRES_INITDB_ARGS, which
+// its entrypoint hands to initdb when the data directory is empty, and TZ.
+func (c *PostgresConfig) InitdbEnv() map[string]string {
+	env := make(map[string]string)
+	var args []string
+	if c.Locale != "" {
+		args = append(args, "--locale="+c.Locale)
+	}
+	if c.Encoding != "" {
+		args = append(args, "--encoding="+c.Encoding)
+	}
+	args = append(args, c.InitdbArgs...)
+	if len(args) > 0 {
+		// The entrypoint evaluates the value as part of a shell command
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellWord(arg)
+		}
+		env["POSTGRES_INITDB_ARGS"] = strings.Join(quoted, " ")
+	}
+	if c.Timezone != "" {
+		env["TZ"] = c.Timezone
+	}
+	return env
+}
+
+// shellWord quotes s for a POSIX shell when it contains special characters.
+func shellWord(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SplitInitdbArgs splits a POSTGRES_INITDB_ARGS value into arguments, undoing
+// the quoting InitdbEnv adds, so a container's arguments can be given to a
+// new one.
+func SplitInitdbArgs(value string) []string {
+	var args []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range value {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args
+}
@@ -45,3 +45,27 @@ func TestPostgresConfig_Image_CustomImageOverridesVersion(t *testing.T) {
 	// CustomImage should take precedence
 	assert.Equal(t, "myregistry/postgres:latest", cfg.Image())
 }
+
+func TestPostgresConfig_InitdbEnv(t *testing.T) {
+	cfg := NewPostgresConfig()
+	assert.Empty(t, cfg.InitdbEnv())
+
+	cfg.Locale = "de_DE.UTF-8"
+	cfg.Encoding = "UTF8"
+	cfg.Timezone = "Europe/Berlin"
+	cfg.InitdbArgs = []string{"--data-checksums", "--icu-rules=&a < b's"}
+
+	assert.Equal(t, map[string]string{
+		"POSTGRES_INITDB_ARGS": `--locale=de_DE.UTF-8 --encoding=UTF8 --data-checksums '--icu-rules=&a < b'\''s'`,
+		"TZ":                   "Europe/Berlin",
+	}, cfg.InitdbEnv())
+}
+
+func TestSplitInitdbArgs(t *testing.T) {
+	cfg := &PostgresConfig{Locale: "de_DE.UTF-8", InitdbArgs: []string{"--data-checksums", "--icu-rules=&a < b's"}}
+
+	assert.Equal(t, []string{"--locale=de_DE.UTF-8", "--data-checksums", "--icu-rules=&a < b's"},
+		SplitInitdbArgs(cfg.InitdbEnv()["POSTGRES_INITDB_ARGS"]))
+	assert.Equal(t, []string{"--auth-host=scram-sha-256", "-E", "UTF8"}, SplitInitdbArgs("  --auth-host=scram-sha-256 -E \"UTF8\" "))
+	assert.Empty(t, SplitInitdbArgs(""))
+}
@@ -0,0 +1,342 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectFileName is the name of the project-level configuration file.
+const ProjectFileName = "pgbox.toml"
+
+// InstanceConfig describes one PostgreSQL instance. Empty fields mean
+// "not set", so command-line defaults apply.
+type InstanceConfig struct {
+	Version    string            `toml:"version"`
+	Port       string            `toml:"port"`
+	Name       string            `toml:"name"`
+	User       string            `toml:"user"`
+	Password   string            `toml:"password"`
+	Database   string            `toml:"database"`
+	Extensions []string          `toml:"extensions"`
+	Prefer     []string          `toml:"prefer"`
+	BaseImage  string            `toml:"base_image"`
+	Settings   map[string]string `toml:"-"`
+
+	// RawSettings holds the [settings] table as decoded; LoadProject
+	// flattens it into Settings.
+	RawSettings map[string]any `toml:"settings"`
+
+	// DependsOn lists instances that must be started before this one.
+	DependsOn []string `toml:"depends_on"`
+	// FDW lists sibling instances to expose as postgres_fdw servers.
+	FDW []string `toml:"fdw"`
+}
+
+// ProjectConfig is the contents of a pgbox.toml file. Top-level values
+// configure the default instance and act as defaults for named instances.
+type ProjectConfig struct {
+	InstanceConfig
+
+	// Instances declares named instances started together by pgbox up --all.
+	Instances map[string]*InstanceConfig `toml:"instances"`
+
+	// Path is the file the configuration was loaded from.
+	Path string `toml:"-"`
+}
+
+// FindProjectFile looks for pgbox.toml in dir and its parents.
+// Returns an empty string if none is found.
+func FindProjectFile(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads and validates a pgbox.toml file.
+func LoadProject(path string) (*ProjectConfig, error) {
+	cfg, unknown, err := DecodeProject(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%s: unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	return cfg, nil
+}
+
+// DecodeProject reads a pgbox.toml file like LoadProject but returns the
+// keys pgbox does not know, in file order, instead of failing on them.
+func DecodeProject(path string) (*ProjectConfig, []string, error) {
+	var cfg ProjectConfig
+	meta, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var unknown []string
+	for _, key := range meta.Undecoded() {
+		// Nested tables under [settings] are dotted setting names, not unknown keys
+		if key[0] == "settings" || (key[0] == "instances" && len(key) > 2 && key[2] == "settings") {
+			continue
+		}
+		unknown = append(unknown, key.String())
+	}
+	cfg.Settings = make(map[string]string)
+	flattenSettings("", cfg.RawSettings, cfg.Settings)
+	for _, inst := range cfg.Instances {
+		inst.Settings = make(map[string]string)
+		flattenSettings("", inst.RawSettings, inst.Settings)
+	}
+	cfg.Path = path
+	return &cfg, unknown, nil
+}
+
+// Instance returns the named instance with unset fields filled in from the
+// top-level values. Settings are merged, with the instance's values winning.
+func (p *ProjectConfig) Instance(name string) (InstanceConfig, bool) {
+	inst, ok := p.Instances[name]
+	if !ok {
+		return InstanceConfig{}, false
+	}
+
+	merged := *inst
+	pick := func(value, fallback string) string {
+		if value != "" {
+			return value
+		}
+		return fallback
+	}
+	merged.Version = pick(inst.Version, p.Version)
+	merged.User = pick(inst.User, p.User)
+	merged.Password = pick(inst.Password, p.Password)
+	merged.Database = pick(inst.Database, p.Database)
+	merged.BaseImage = pick(inst.BaseImage, p.BaseImage)
+	merged.Name = pick(inst.Name, name)
+	if len(inst.Extensions) == 0 {
+		merged.Extensions = p.Extensions
+	}
+	if len(inst.Prefer) == 0 {
+		merged.Prefer = p.Prefer
+	}
+	merged.Settings = make(map[string]string)
+	for key, value := range p.Settings {
+		merged.Settings[key] = value
+	}
+	for key, value := range inst.Settings {
+		merged.Settings[key] = value
+	}
+	return merged, true
+}
+
+// InstanceOrder returns the instance names ordered so every instance comes
+// after the instances it depends on (including its FDW targets). It reports
+// unknown references, dependency cycles and instances sharing a host port.
+func (p *ProjectConfig) InstanceOrder() ([]string, error) {
+	if len(p.Instances) == 0 {
+		return nil, fmt.Errorf("%s declares no [instances]", p.Path)
+	}
+
+	names := make([]string, 0, len(p.Instances))
+	for name := range p.Instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ports := make(map[string]string)
+	for _, name := range names {
+		port := p.Instances[name].Port
+		if port == "" {
+			return nil, fmt.Errorf("instance %s: port is required when running multiple instances", name)
+		}
+		if other, ok := ports[port]; ok {
+			return nil, fmt.Errorf("instances %s and %s both use port %s", other, name, port)
+		}
+		ports[port] = name
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("instance dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		inst := p.Instances[name]
+		deps := append(append([]string{}, inst.DependsOn...), inst.FDW...)
+		for _, dep := range deps {
+			if _, ok := p.Instances[dep]; !ok {
+				return fmt.Errorf("instance %s: unknown instance %q", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// flattenSettings converts the decoded [settings] table into name/value pairs.
+// Unquoted dotted keys such as cron.database_name decode as nested tables and
+// are joined back together; numbers and booleans are formatted as strings.
+func flattenSettings(prefix string, raw map[string]any, out map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flattenSettings(key, v, out)
+		case string:
+			out[key] = v
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// LoadProjectFrom finds and loads pgbox.toml starting at dir.
+// Returns nil without error when there is no project file.
+func LoadProjectFrom(dir string) (*ProjectConfig, error) {
+	path := FindProjectFile(dir)
+	if path == "" {
+		return nil, nil
+	}
+	return LoadProject(path)
+}
+
+// WriteProjectFile writes a commented pgbox.toml scaffold to dir.
+// It refuses to overwrite an existing file unless force is set.
+func WriteProjectFile(dir string, cfg ProjectConfig, force bool) (string, error) {
+	path := filepath.Join(dir, ProjectFileName)
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, []byte(renderProjectFile(cfg)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// renderProjectFile renders cfg as a commented pgbox.toml.
+func renderProjectFile(cfg ProjectConfig) string {
+	quoted := make([]string, len(cfg.Extensions))
+	for i, ext := range cfg.Extensions {
+		quoted[i] = fmt.Sprintf("%q", ext)
+	}
+
+	var b strings.Builder
+	b.WriteString("# pgbox project configuration. Command-line flags override these values.\n\n")
+	fmt.Fprintf(&b, "version = %q\n", cfg.Version)
+	fmt.Fprintf(&b, "port = %q\n", cfg.Port)
+	b.WriteString("# name = \"myapp-db\"\n\n")
+	fmt.Fprintf(&b, "user = %q\n", cfg.User)
+	fmt.Fprintf(&b, "password = %q\n", cfg.Password)
+	fmt.Fprintf(&b, "database = %q\n\n", cfg.Database)
+	fmt.Fprintf(&b, "extensions = [%s]\n\n", strings.Join(quoted, ", "))
+	b.WriteString("# PostgreSQL settings; these win over extension defaults.\n")
+	b.WriteString("[settings]\n")
+	b.WriteString("# work_mem = \"64MB\"\n")
+	b.WriteString("# max_connections = 200\n")
+	b.WriteString("# cron.database_name = \"postgres\"\n")
+	return b.String()
+}
+
+// SetProjectExtensions replaces the top-level extensions list of the project
+// file at path. See setProjectValue.
+func SetProjectExtensions(path string, exts []string) error {
+	quoted := make([]string, len(exts))
+	for i, ext := range exts {
+		quoted[i] = fmt.Sprintf("%q", ext)
+	}
+	return setProjectValue(path, "extensions", "["+strings.Join(quoted, ", ")+"]")
+}
+
+// SetProjectPort replaces the top-level port of the project file at path.
+// See setProjectValue.
+func SetProjectPort(path, port string) error {
+	return setProjectValue(path, "port", fmt.Sprintf("%q", port))
+}
+
+// setProjectValue sets a top-level key of the project file at path to value,
+// which must already be TOML, leaving the rest of the file, comments included,
+// untouched. When the file does not set key, it is added before the first table.
+func setProjectValue(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	line := key + " = " + value
+	keyPattern := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(key) + `\s*=\s*(.*)$`)
+
+	lines := strings.Split(string(data), "\n")
+	insertAt := len(lines)
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "[") {
+			insertAt = i
+			break
+		}
+		m := keyPattern.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		if current := m[1]; strings.HasPrefix(current, "[") && !strings.Contains(current, "]") ||
+			strings.HasPrefix(current, `"""`) || strings.HasPrefix(current, "'''") {
+			return fmt.Errorf("%s:%d: %s spans several lines; set it to %s by hand", path, i+1, key, value)
+		}
+		lines[i] = line
+		return writeLines(path, lines)
+	}
+
+	added := []string{line}
+	if insertAt < len(lines) {
+		added = append(added, "")
+	} else if insertAt > 0 && lines[insertAt-1] == "" {
+		// Keep the trailing newline last
+		insertAt--
+	}
+	lines = slices.Insert(lines, insertAt, added...)
+	return writeLines(path, lines)
+}
+
+// writeLines writes lines to path, joined by newlines.
+func writeLines(path string, lines []string) error {
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
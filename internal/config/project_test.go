@@ -0,0 +1,280 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProjectFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ProjectFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadProject(t *testing.T) {
+	path := writeProjectFile(t, t.TempDir(), `
+version = "17"
+port = "5433"
+database = "myapp"
+extensions = ["pgvector", "pg_cron"]
+
+[settings]
+work_mem = "64MB"
+max_connections = 200
+cron.database_name = "myapp"
+"cron.max_running_jobs" = "10"
+`)
+
+	cfg, err := LoadProject(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "17", cfg.Version)
+	assert.Equal(t, "5433", cfg.Port)
+	assert.Equal(t, "myapp", cfg.Database)
+	assert.Empty(t, cfg.User)
+	assert.Equal(t, []string{"pgvector", "pg_cron"}, cfg.Extensions)
+	assert.Equal(t, map[string]string{
+		"work_mem":              "64MB",
+		"max_connections":       "200",
+		"cron.database_name":    "myapp",
+		"cron.max_running_jobs": "10",
+	}, cfg.Settings)
+	assert.Equal(t, path, cfg.Path)
+}
+
+func TestLoadProject_UnknownKey(t *testing.T) {
+	path := writeProjectFile(t, t.TempDir(), "version = \"17\"\nextension = [\"pgvector\"]\n")
+
+	_, err := LoadProject(path)
+
+	assert.ErrorContains(t, err, "unknown keys: extension")
+}
+
+func TestDecodeProject_ReturnsUnknownKeys(t *testing.T) {
+	path := writeProjectFile(t, t.TempDir(), "version = \"17\"\nextension = [\"pgvector\"]\n\n[settings]\ncron.database_name = \"app\"\n\n[instances.replica]\nprot = \"5433\"\n")
+
+	cfg, unknown, err := DecodeProject(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"extension", "instances.replica.prot"}, unknown)
+	assert.Equal(t, "17", cfg.Version)
+	assert.Equal(t, map[string]string{"cron.database_name": "app"}, cfg.Settings)
+}
+
+func TestLoadProject_InvalidTOML(t *testing.T) {
+	path := writeProjectFile(t, t.TempDir(), "version = \n")
+
+	_, err := LoadProject(path)
+
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestLoadProjectFrom_SearchesParents(t *testing.T) {
+	root := t.TempDir()
+	path := writeProjectFile(t, root, "version = \"16\"\n")
+	nested := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0755))
+
+	cfg, err := LoadProjectFrom(nested)
+
+	require.NoError(t, err)
+	assert.Equal(t, "16", cfg.Version)
+	assert.Equal(t, path, cfg.Path)
+}
+
+func TestLoadProjectFrom_NoFile(t *testing.T) {
+	cfg, err := LoadProjectFrom(t.TempDir())
+
+	assert.NoError(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestWriteProjectFile_RoundTrips(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := WriteProjectFile(dir, ProjectConfig{InstanceConfig: InstanceConfig{
+		Version:    "18",
+		Port:       "5432",
+		User:       "postgres",
+		Password:   "postgres",
+		Database:   "postgres",
+		Extensions: []string{"pgvector"},
+	}}, false)
+	require.NoError(t, err)
+
+	cfg, err := LoadProject(path)
+	require.NoError(t, err)
+	assert.Equal(t, "18", cfg.Version)
+	assert.Equal(t, []string{"pgvector"}, cfg.Extensions)
+	assert.Empty(t, cfg.Settings)
+
+	_, err = WriteProjectFile(dir, ProjectConfig{InstanceConfig: InstanceConfig{Version: "17"}}, false)
+	assert.ErrorContains(t, err, "already exists")
+
+	_, err = WriteProjectFile(dir, ProjectConfig{InstanceConfig: InstanceConfig{Version: "17"}}, true)
+	assert.NoError(t, err)
+}
+
+const multiInstanceProject = `
+version = "17"
+password = "secret"
+
+[settings]
+work_mem = "16MB"
+
+[instances.app-db]
+port = "5432"
+extensions = ["pgvector"]
+fdw = ["analytics-db"]
+
+[instances.app-db.settings]
+work_mem = "64MB"
+
+[instances.analytics-db]
+port = "5433"
+version = "16"
+database = "analytics"
+depends_on = ["cache-db"]
+
+[instances.cache-db]
+port = "5434"
+`
+
+func TestProjectConfig_Instance(t *testing.T) {
+	cfg, err := LoadProject(writeProjectFile(t, t.TempDir(), multiInstanceProject))
+	require.NoError(t, err)
+
+	app, ok := cfg.Instance("app-db")
+	require.True(t, ok)
+	assert.Equal(t, "app-db", app.Name)
+	assert.Equal(t, "17", app.Version)
+	assert.Equal(t, "secret", app.Password)
+	assert.Equal(t, []string{"pgvector"}, app.Extensions)
+	assert.Equal(t, "64MB", app.Settings["work_mem"])
+
+	analytics, _ := cfg.Instance("analytics-db")
+	assert.Equal(t, "16", analytics.Version)
+	assert.Equal(t, "16MB", analytics.Settings["work_mem"])
+
+	_, ok = cfg.Instance("missing")
+	assert.False(t, ok)
+}
+
+func TestProjectConfig_InstanceOrder(t *testing.T) {
+	cfg, err := LoadProject(writeProjectFile(t, t.TempDir(), multiInstanceProject))
+	require.NoError(t, err)
+
+	order, err := cfg.InstanceOrder()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache-db", "analytics-db", "app-db"}, order)
+}
+
+func TestProjectConfig_InstanceOrderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no instances", `version = "17"`, "declares no [instances]"},
+		{"cycle", `
+[instances.a]
+port = "1"
+depends_on = ["b"]
+[instances.b]
+port = "2"
+fdw = ["a"]
+`, "dependency cycle: a -> b -> a"},
+		{"unknown", `
+[instances.a]
+port = "1"
+depends_on = ["nope"]
+`, `unknown instance "nope"`},
+		{"shared port", `
+[instances.a]
+port = "5432"
+[instances.b]
+port = "5432"
+`, "both use port 5432"},
+		{"missing port", `
+[instances.a]
+version = "17"
+`, "port is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadProject(writeProjectFile(t, t.TempDir(), tt.content))
+			require.NoError(t, err)
+
+			_, err = cfg.InstanceOrder()
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSetProjectExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "replaces list and keeps comments",
+			content:  "# my project\nversion = \"17\"\nextensions = [\"pgvector\"] # search\n\n[settings]\nwork_mem = \"64MB\"\n",
+			expected: "# my project\nversion = \"17\"\nextensions = [\"pgvector\", \"hstore\"]\n\n[settings]\nwork_mem = \"64MB\"\n",
+		},
+		{
+			name:     "adds list before first table",
+			content:  "version = \"17\"\n[settings]\n",
+			expected: "version = \"17\"\nextensions = [\"pgvector\", \"hstore\"]\n\n[settings]\n",
+		},
+		{
+			name:     "adds list at end",
+			content:  "version = \"17\"\n",
+			expected: "version = \"17\"\nextensions = [\"pgvector\", \"hstore\"]\n",
+		},
+		{
+			name:     "leaves instance lists alone",
+			content:  "[instances.app]\nextensions = [\"pg_cron\"]\n",
+			expected: "extensions = [\"pgvector\", \"hstore\"]\n\n[instances.app]\nextensions = [\"pg_cron\"]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeProjectFile(t, t.TempDir(), tt.content)
+
+			require.NoError(t, SetProjectExtensions(path, []string{"pgvector", "hstore"}))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestSetProjectExtensions_MultiLineList(t *testing.T) {
+	path := writeProjectFile(t, t.TempDir(), "extensions = [\n  \"pgvector\",\n]\n")
+
+	err := SetProjectExtensions(path, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "extensions spans several lines")
+}
+
+func TestSetProjectPort(t *testing.T) {
+	path := writeProjectFile(t, t.TempDir(), "version = \"17\"\nport = \"5432\" # default\n\n[instances.app]\nport = \"5440\"\n")
+
+	require.NoError(t, SetProjectPort(path, "5433"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "version = \"17\"\nport = \"5433\"\n\n[instances.app]\nport = \"5440\"\n", string(data))
+}
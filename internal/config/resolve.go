@@ -0,0 +1,125 @@
+package config
+
+import "os"
+
+// Keys of the values a Resolver resolves. They match the pgbox.toml keys.
+const (
+	KeyVersion   = "version"
+	KeyPort      = "port"
+	KeyName      = "name"
+	KeyUser      = "user"
+	KeyPassword  = "password"
+	KeyDatabase  = "database"
+	KeyBaseImage = "base_image"
+)
+
+// SourceDefault is the source Get reports for a built-in default.
+const SourceDefault = "built-in default"
+
+// EnvVars maps each key to the environment variable that sets it.
+var EnvVars = map[string]string{
+	KeyVersion:   "PGBOX_VERSION",
+	KeyPort:      "PGBOX_PORT",
+	KeyName:      "PGBOX_NAME",
+	KeyUser:      "PGBOX_USER",
+	KeyPassword:  "PGBOX_PASSWORD",
+	KeyDatabase:  "PGBOX_DATABASE",
+	KeyBaseImage: "PGBOX_BASE_IMAGE",
+}
+
+// Resolver looks up configuration values in order of precedence: command
+// line flags, PGBOX_* environment variables, pgbox.toml, the user config and
+// built-in defaults. Every command that takes these values resolves them
+// through a Resolver so the order is the same everywhere.
+type Resolver struct {
+	Flags   map[string]string // Values given on the command line, by key
+	Project *ProjectConfig    // nil when there is no pgbox.toml
+	User    *UserConfig       // nil when there is no user config
+	Getenv  func(string) string
+}
+
+// NewResolver creates a Resolver that reads the process environment.
+func NewResolver(flags map[string]string, project *ProjectConfig, user *UserConfig) *Resolver {
+	return &Resolver{Flags: flags, Project: project, User: user, Getenv: os.Getenv}
+}
+
+// Get returns the value for key and a description of where it came from.
+// Keys without a built-in default, such as name and base_image, resolve to
+// an empty value.
+func (r *Resolver) Get(key string) (value, source string) {
+	if v := r.Flags[key]; v != "" {
+		return v, "--" + FlagName(key) + " flag"
+	}
+	if env := EnvVars[key]; env != "" && r.Getenv != nil {
+		if v := r.Getenv(env); v != "" {
+			return v, "$" + env
+		}
+	}
+	if r.Project != nil {
+		if v := r.Project.value(key); v != "" {
+			return v, key + " in " + r.Project.Path
+		}
+	}
+	if key == KeyVersion && r.User != nil && r.User.DefaultVersion != "" {
+		return r.User.DefaultVersion, "default_version in " + r.User.Path
+	}
+	return builtinDefault(key), SourceDefault
+}
+
+// Version resolves the PostgreSQL version, turning "latest" into the newest
+// supported version.
+func (r *Resolver) Version() (version, source string) {
+	version, source = r.Get(KeyVersion)
+	if version == LatestKeyword {
+		version = SupportedVersions[len(SupportedVersions)-1]
+		source += ", latest supported"
+	}
+	return version, source
+}
+
+// value returns the project's top-level value for key.
+func (p *ProjectConfig) value(key string) string {
+	switch key {
+	case KeyVersion:
+		return p.Version
+	case KeyPort:
+		return p.Port
+	case KeyName:
+		return p.Name
+	case KeyUser:
+		return p.User
+	case KeyPassword:
+		return p.Password
+	case KeyDatabase:
+		return p.Database
+	case KeyBaseImage:
+		return p.BaseImage
+	}
+	return ""
+}
+
+// builtinDefault returns pgbox's default for key.
+func builtinDefault(key string) string {
+	defaults := NewPostgresConfig()
+	switch key {
+	case KeyVersion:
+		return defaults.Version
+	case KeyPort:
+		return defaults.Port
+	case KeyUser:
+		return defaults.User
+	case KeyPassword:
+		return defaults.Password
+	case KeyDatabase:
+		return defaults.Database
+	}
+	return ""
+}
+
+// FlagName returns the command line flag for key.
+func FlagName(key string) string {
+	if key == KeyBaseImage {
+		return "base-image"
+	}
+	return key
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolver_Precedence(t *testing.T) {
+	project := &ProjectConfig{
+		InstanceConfig: InstanceConfig{Version: "16", Port: "5433", User: "app", Database: "shop"},
+		Path:           "/repo/pgbox.toml",
+	}
+	user := &UserConfig{DefaultVersion: "17", Path: "/home/dev/.config/pgbox/config.toml"}
+	env := map[string]string{"PGBOX_USER": "envuser", "PGBOX_PORT": "6000"}
+
+	r := &Resolver{
+		Flags:   map[string]string{KeyPort: "7000"},
+		Project: project,
+		User:    user,
+		Getenv:  func(name string) string { return env[name] },
+	}
+
+	tests := []struct {
+		key        string
+		wantValue  string
+		wantSource string
+	}{
+		{KeyPort, "7000", "--port flag"},
+		{KeyUser, "envuser", "$PGBOX_USER"},
+		{KeyDatabase, "shop", "database in /repo/pgbox.toml"},
+		{KeyVersion, "16", "version in /repo/pgbox.toml"},
+		{KeyPassword, "postgres", "built-in default"},
+		{KeyName, "", "built-in default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			value, source := r.Get(tt.key)
+			assert.Equal(t, tt.wantValue, value)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+}
+
+func TestResolver_BaseImageFlag(t *testing.T) {
+	r := &Resolver{Flags: map[string]string{KeyBaseImage: "postgres:17-alpine"}}
+
+	value, source := r.Get(KeyBaseImage)
+
+	assert.Equal(t, "postgres:17-alpine", value)
+	assert.Equal(t, "--base-image flag", source)
+}
+
+func TestResolver_Version(t *testing.T) {
+	project := &ProjectConfig{InstanceConfig: InstanceConfig{Version: "16"}, Path: "/repo/pgbox.toml"}
+	user := &UserConfig{DefaultVersion: "17", Path: "/home/dev/.config/pgbox/config.toml"}
+	latest := SupportedVersions[len(SupportedVersions)-1]
+
+	tests := []struct {
+		name        string
+		flags       map[string]string
+		env         map[string]string
+		project     *ProjectConfig
+		user        *UserConfig
+		wantVersion string
+		wantSource  string
+	}{
+		{"flag wins", map[string]string{KeyVersion: "18"}, map[string]string{"PGBOX_VERSION": "17"}, project, user, "18", "--version flag"},
+		{"env over project", nil, map[string]string{"PGBOX_VERSION": "17"}, project, user, "17", "$PGBOX_VERSION"},
+		{"project over user", nil, nil, project, user, "16", "version in /repo/pgbox.toml"},
+		{"user default", nil, nil, &ProjectConfig{}, user, "17", "default_version in /home/dev/.config/pgbox/config.toml"},
+		{"built-in default", nil, nil, nil, nil, DefaultVersion, "built-in default"},
+		{"latest flag", map[string]string{KeyVersion: "latest"}, nil, nil, nil, latest, "--version flag, latest supported"},
+		{"latest user default", nil, nil, nil, &UserConfig{DefaultVersion: "latest", Path: "c.toml"}, latest, "default_version in c.toml, latest supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Resolver{Flags: tt.flags, Project: tt.project, User: tt.user, Getenv: func(name string) string { return tt.env[name] }}
+			version, source := r.Version()
+			assert.Equal(t, tt.wantVersion, version)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ContainerState is what pgbox remembers about a container it created. It
+// is kept out of the project directory, so it is never committed.
+type ContainerState struct {
+	// Password is the generated password the container's data volume was
+	// initialized with.
+	Password string `toml:"password,omitempty"`
+
+	// GUCChoices are the values picked at the prompt for GUCs the
+	// container's extensions disagree on, by GUC.
+	GUCChoices map[string]string `toml:"guc_choices,omitempty"`
+
+	// Timings records how long the steps of the up that created the
+	// container took, at TimedAt.
+	Timings []StepTiming `toml:"timings,omitempty"`
+	TimedAt time.Time    `toml:"timed_at,omitempty"`
+}
+
+// StepTiming is how long one step of creating a container took.
+type StepTiming struct {
+	Step    string  `toml:"step"`    // build, create, init or start
+	Seconds float64 `toml:"seconds"` // Rounded to milliseconds
+	Detail  string  `toml:"detail,omitempty"`
+}
+
+// Duration returns the step's time as a time.Duration.
+func (t StepTiming) Duration() time.Duration {
+	return time.Duration(t.Seconds * float64(time.Second))
+}
+
+// ContainerStatePath returns the state file for a container:
+// <ConfigDir>/containers/<name>.toml.
+func ContainerStatePath(name string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "containers", name+".toml"), nil
+}
+
+// LoadContainerState reads the state file of a container. Returns nil when
+// there is none.
+func LoadContainerState(name string) (*ContainerState, error) {
+	path, err := ContainerStatePath(name)
+	if err != nil {
+		return nil, err
+	}
+	var state ContainerState
+	if _, err := toml.DecodeFile(path, &state); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// SaveContainerState writes the state file of a container, readable only by
+// the current user, and returns its path.
+func SaveContainerState(name string, state ContainerState) (string, error) {
+	path, err := ContainerStatePath(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	var b strings.Builder
+	b.WriteString("# Written by pgbox; removed with the container's data volume.\n")
+	if err := toml.NewEncoder(&b).Encode(state); err != nil {
+		return "", fmt.Errorf("failed to encode container state: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// RemoveContainerState deletes the state file of a container, if any.
+func RemoveContainerState(name string) error {
+	path, err := ContainerStatePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerState_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	state, err := LoadContainerState("pgbox-pg17")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	path, err := SaveContainerState("pgbox-pg17", ContainerState{Password: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "pgbox", "containers", "pgbox-pg17.toml"), path)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	state, err = LoadContainerState("pgbox-pg17")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", state.Password)
+
+	require.NoError(t, RemoveContainerState("pgbox-pg17"))
+	require.NoError(t, RemoveContainerState("pgbox-pg17"))
+	state, err = LoadContainerState("pgbox-pg17")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+}
+
+func TestContainerState_Timings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	timedAt := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	_, err := SaveContainerState("pgbox-pg17", ContainerState{
+		Timings: []StepTiming{
+			{Step: "build", Seconds: 42.125, Detail: "3 packages"},
+			{Step: "start", Seconds: 0.5},
+		},
+		TimedAt: timedAt,
+	})
+	require.NoError(t, err)
+
+	state, err := LoadContainerState("pgbox-pg17")
+	require.NoError(t, err)
+	assert.Empty(t, state.Password)
+	require.Len(t, state.Timings, 2)
+	assert.Equal(t, "build", state.Timings[0].Step)
+	assert.Equal(t, "3 packages", state.Timings[0].Detail)
+	assert.Equal(t, 42125*time.Millisecond, state.Timings[0].Duration())
+	assert.True(t, timedAt.Equal(state.TimedAt))
+}
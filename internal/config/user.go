@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// UserConfigFileName is the name of the per-user configuration file in ConfigDir.
+const UserConfigFileName = "config.toml"
+
+// UserConfig holds per-user defaults that apply to every project.
+type UserConfig struct {
+	// DefaultVersion is the PostgreSQL version used when neither the command
+	// line nor pgbox.toml picks one. May be "latest".
+	DefaultVersion string `toml:"default_version"`
+
+	// Path is the file the configuration was loaded from.
+	Path string `toml:"-"`
+}
+
+// LoadUserConfig reads the user configuration from ConfigDir. Returns nil
+// when the file does not exist.
+func LoadUserConfig() (*UserConfig, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, UserConfigFileName)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	var cfg UserConfig
+	meta, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		unknown := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			unknown[i] = key.String()
+		}
+		return nil, fmt.Errorf("%s: unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	cfg.Path = path
+	return &cfg, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeUserConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "pgbox", UserConfigFileName)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadUserConfig(t *testing.T) {
+	path := writeUserConfig(t, `default_version = "17"`)
+
+	cfg, err := LoadUserConfig()
+
+	require.NoError(t, err)
+	require.NotNil(t, cfg)
+	assert.Equal(t, "17", cfg.DefaultVersion)
+	assert.Equal(t, path, cfg.Path)
+}
+
+func TestLoadUserConfig_Missing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := LoadUserConfig()
+
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestLoadUserConfig_UnknownKey(t *testing.T) {
+	writeUserConfig(t, `default_verison = "17"`)
+
+	_, err := LoadUserConfig()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown keys: default_verison")
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -46,6 +48,9 @@ func extensionHash(extNames []string) string {
 				h.Write([]byte(k + "=" + ext.GUCs[k]))
 			}
 			h.Write([]byte(ext.InitSQL))
+			for _, script := range ext.Script.Initdb {
+				h.Write([]byte(script.Name + "\x00" + script.Run))
+			}
 		}
 	}
 
@@ -71,6 +76,55 @@ func (m *Manager) ImageName(version string, extensions []string) string {
 	return fmt.Sprintf("pgbox-pg%s-custom:%s", version, hash)
 }
 
+// Labels pgbox sets on the containers it creates, recording what they were
+// created with.
+const (
+	LabelExtensions      = "dev.pgbox.extensions"       // Comma-separated catalog names, sorted
+	LabelPostgresVersion = "dev.pgbox.postgres-version" // PostgreSQL major version
+	LabelExtensionHash   = "dev.pgbox.extension-hash"   // Hash of the extensions' catalog entries
+	LabelHardened        = "dev.pgbox.hardened"         // "true" for containers started with --hardened
+	LabelEnv             = "dev.pgbox.env"              // Comma-separated names of the --env variables, sorted
+)
+
+// Labels returns the labels for a container created with the given version
+// and extensions.
+func (m *Manager) Labels(version string, extensions []string) map[string]string {
+	sorted := slices.Clone(extensions)
+	sort.Strings(sorted)
+	return map[string]string{
+		LabelExtensions:      strings.Join(sorted, ","),
+		LabelPostgresVersion: version,
+		LabelExtensionHash:   extensionHash(extensions),
+	}
+}
+
+// ExtensionHash returns the hash recorded in LabelExtensionHash for extensions.
+func (m *Manager) ExtensionHash(extensions []string) string {
+	return extensionHash(extensions)
+}
+
+// ParseExtensionsLabel returns the extensions recorded in a LabelExtensions value.
+func ParseExtensionsLabel(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// replicaSuffix ends the name of the replica pgbox up --with-replica runs
+// next to a container.
+const replicaSuffix = "-replica"
+
+// ReplicaName returns the name of the read-only replica of a container.
+func ReplicaName(primary string) string {
+	return primary + replicaSuffix
+}
+
+// IsReplicaName reports whether name is that of a replica.
+func IsReplicaName(name string) bool {
+	return strings.HasSuffix(name, replicaSuffix)
+}
+
 // DefaultName returns the default container name for the default PostgreSQL version
 func (m *Manager) DefaultName() string {
 	return fmt.Sprintf("pgbox-pg%s", config.DefaultVersion)
@@ -80,7 +134,8 @@ func (m *Manager) DefaultName() string {
 var ErrNoContainerFound = errors.New("no pgbox or postgres container found")
 
 // SelectPgboxContainer selects the best pgbox container from docker ps output.
-// Priority: 1) containers starting with "pgbox-", 2) any postgres container
+// Priority: 1) containers starting with "pgbox-", 2) any postgres container.
+// Replicas are skipped, since they refuse writes.
 func SelectPgboxContainer(dockerPsOutput string) (string, error) {
 	if dockerPsOutput == "" {
 		return "", ErrNoContainerFound
@@ -92,7 +147,7 @@ func SelectPgboxContainer(dockerPsOutput string) (string, error) {
 		parts := strings.Split(line, "\t")
 		if len(parts) >= 1 {
 			name := strings.TrimSpace(parts[0])
-			if strings.HasPrefix(name, "pgbox-") {
+			if strings.HasPrefix(name, "pgbox-") && !IsReplicaName(name) {
 				return name, nil
 			}
 		}
@@ -102,7 +157,10 @@ func SelectPgboxContainer(dockerPsOutput string) (string, error) {
 		parts := strings.Split(line, "\t")
 		if len(parts) >= 2 {
 			name := strings.TrimSpace(parts[0])
-			image := strings.TrimSpace(parts[1])
+			image := shortImageName(strings.TrimSpace(parts[1]))
+			if IsReplicaName(name) {
+				continue
+			}
 			if strings.HasPrefix(image, "postgres:") || strings.HasPrefix(image, "pgbox-pg") {
 				return name, nil
 			}
@@ -111,3 +169,27 @@ func SelectPgboxContainer(dockerPsOutput string) (string, error) {
 
 	return "", ErrNoContainerFound
 }
+
+// shortImageName strips the registry prefixes that podman and nerdctl show
+// for Docker Hub and locally built images (e.g., docker.io/library/postgres:17).
+func shortImageName(image string) string {
+	for _, prefix := range []string{"docker.io/library/", "docker.io/", "localhost/"} {
+		if strings.HasPrefix(image, prefix) {
+			return strings.TrimPrefix(image, prefix)
+		}
+	}
+	return image
+}
+
+// instanceLabelPattern matches labels usable in container and volume names.
+var instanceLabelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// InstanceName returns the container name for a named instance. Instances let
+// several independent environments run side by side, each with its own
+// container (pgbox-<label>) and data volume (pgbox-<label>-data).
+func InstanceName(label string) (string, error) {
+	if !instanceLabelPattern.MatchString(label) {
+		return "", fmt.Errorf("invalid instance name %q (use lowercase letters, digits, '.', '_' and '-')", label)
+	}
+	return "pgbox-" + label, nil
+}
@@ -30,6 +30,11 @@ func TestSelectPgboxContainer(t *testing.T) {
 			dockerPsOutput: "my-postgres\tpostgres:16\npgbox-custom\tpostgres:17\nnginx\tnginx:latest\n",
 			expected:       "pgbox-custom",
 		},
+		{
+			name:           "skips replicas",
+			dockerPsOutput: "pgbox-pg17-replica\tpostgres:17\npgbox-pg17\tpostgres:17\n",
+			expected:       "pgbox-pg17",
+		},
 		{
 			name:           "falls back to postgres image when no pgbox- prefix",
 			dockerPsOutput: "my-postgres\tpostgres:16-alpine\nnginx\tnginx:latest\n",
@@ -61,6 +66,16 @@ func TestSelectPgboxContainer(t *testing.T) {
 			dockerPsOutput: "pgbox-pg17\tpgbox-pg17-custom:183329\t\"docker-entrypoint.s…\"\t4 minutes ago\tUp 4 minutes\t0.0.0.0:5432->5432/tcp, :::5432->5432/tcp",
 			expected:       "pgbox-pg17",
 		},
+		{
+			name:           "detects podman-qualified postgres image",
+			dockerPsOutput: "my-postgres\tdocker.io/library/postgres:17\n",
+			expected:       "my-postgres",
+		},
+		{
+			name:           "detects podman-local custom image",
+			dockerPsOutput: "my-postgres\tlocalhost/pgbox-pg17-custom:192484\n",
+			expected:       "my-postgres",
+		},
 		{
 			name:           "regression test - detects container with custom image built for extensions",
 			dockerPsOutput: "pgbox-pg17\tpgbox-pg17-custom:192484",
@@ -81,3 +96,24 @@ func TestSelectPgboxContainer(t *testing.T) {
 		})
 	}
 }
+
+func TestInstanceName(t *testing.T) {
+	name, err := InstanceName("shop")
+	assert.NoError(t, err)
+	assert.Equal(t, "pgbox-shop", name)
+
+	_, err = InstanceName("My Project")
+	assert.Error(t, err)
+}
+
+func TestManager_Labels(t *testing.T) {
+	m := NewManager()
+
+	labels := m.Labels("17", []string{"pgvector", "hstore"})
+
+	assert.Equal(t, "hstore,pgvector", labels[LabelExtensions])
+	assert.Equal(t, "17", labels[LabelPostgresVersion])
+	assert.Equal(t, m.ExtensionHash([]string{"hstore", "pgvector"}), labels[LabelExtensionHash])
+	assert.Equal(t, []string{"hstore", "pgvector"}, ParseExtensionsLabel(labels[LabelExtensions]))
+	assert.Nil(t, ParseExtensionsLabel(""))
+}
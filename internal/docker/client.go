@@ -2,47 +2,104 @@ package docker
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/container"
+	"github.com/ahacop/pgbox/internal/model"
 )
 
-// Client provides an interface to Docker operations
-type Client struct{}
+// Client provides an interface to Docker operations. It drives the docker
+// CLI by default, or a compatible runtime such as podman or nerdctl.
+type Client struct {
+	runtime Runtime
+}
 
-// NewClient creates a new Docker client that implements the Docker interface.
+// NewClient creates a new Docker client that implements the Docker interface,
+// using the runtime chosen with SelectRuntime.
 func NewClient() Docker {
-	return &Client{}
+	return &Client{runtime: currentRuntime}
+}
+
+// NewClientForRuntime creates a client that drives the given runtime.
+func NewClientForRuntime(r Runtime) Docker {
+	return &Client{runtime: r}
+}
+
+// command returns a runtime command that is killed when ctx is done.
+func (c *Client) command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, c.binary(), args...)
+}
+
+// binary returns the CLI executable for the client's runtime.
+func (c *Client) binary() string {
+	if c.runtime == "" {
+		return string(RuntimeDocker)
+	}
+	return string(c.runtime)
+}
+
+// enableBuildKit turns on BuildKit for docker build, which generated
+// Dockerfiles need for their cache mounts. Docker releases before 23.0 use
+// the legacy builder unless asked; an explicit DOCKER_BUILDKIT is respected.
+func (c *Client) enableBuildKit(cmd *exec.Cmd) {
+	if c.binary() != string(RuntimeDocker) || len(cmd.Args) < 2 || cmd.Args[1] != "build" {
+		return
+	}
+	if _, set := os.LookupEnv("DOCKER_BUILDKIT"); set {
+		return
+	}
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
 }
 
 // RunCommand executes a docker command with the given arguments
-func (c *Client) RunCommand(args ...string) error {
-	cmd := exec.Command("docker", args...)
+func (c *Client) RunCommand(ctx context.Context, args ...string) error {
+	cmd := c.command(ctx, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	return c.run(ctx, cmd)
 }
 
 // RunCommandWithOutput executes a docker command and returns its output
-func (c *Client) RunCommandWithOutput(args ...string) (string, error) {
-	cmd := exec.Command("docker", args...)
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+func (c *Client) RunCommandWithOutput(ctx context.Context, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := c.command(ctx, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := c.run(ctx, cmd)
+	return out.String(), err
 }
 
-// RunInteractive executes a docker command interactively with TTY support
-func (c *Client) RunInteractive(args ...string) error {
-	return c.RunCommand(args...)
+// RunInteractive executes a docker command interactively with TTY support.
+// The session is not bound to ctx, so a --timeout does not end it.
+func (c *Client) RunInteractive(ctx context.Context, args ...string) error {
+	cmd := exec.Command(c.binary(), args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	return c.run(ctx, cmd)
+}
+
+// RunCommandWithIO executes a docker command wired to the given stdin, stdout and stderr
+func (c *Client) RunCommandWithIO(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	cmd := c.command(ctx, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return c.run(ctx, cmd)
 }
 
 // IsContainerRunning checks if a container with the given name is running
-func (c *Client) IsContainerRunning(name string) (bool, error) {
-	output, err := c.RunCommandWithOutput("ps", "--format", "{{.Names}}")
+func (c *Client) IsContainerRunning(ctx context.Context, name string) (bool, error) {
+	output, err := c.RunCommandWithOutput(ctx, "ps", "--format", "{{.Names}}")
 	if err != nil {
 		return false, err
 	}
@@ -57,8 +114,8 @@ func (c *Client) IsContainerRunning(name string) (bool, error) {
 }
 
 // GetContainerEnv retrieves an environment variable from a running container
-func (c *Client) GetContainerEnv(containerName, envVar string) (string, error) {
-	output, err := c.RunCommandWithOutput("exec", containerName, "printenv", envVar)
+func (c *Client) GetContainerEnv(ctx context.Context, containerName, envVar string) (string, error) {
+	output, err := c.RunCommandWithOutput(ctx, "exec", containerName, "printenv", envVar)
 	if err != nil {
 		return "", err
 	}
@@ -66,8 +123,8 @@ func (c *Client) GetContainerEnv(containerName, envVar string) (string, error) {
 }
 
 // ListContainers returns a list of running container names matching a prefix
-func (c *Client) ListContainers(prefix string) ([]string, error) {
-	output, err := c.RunCommandWithOutput("ps", "--format", "{{.Names}}")
+func (c *Client) ListContainers(ctx context.Context, prefix string) ([]string, error) {
+	output, err := c.RunCommandWithOutput(ctx, "ps", "--format", "{{.Names}}")
 	if err != nil {
 		return nil, err
 	}
@@ -83,45 +140,53 @@ func (c *Client) ListContainers(prefix string) ([]string, error) {
 }
 
 // StopContainer stops a running container
-func (c *Client) StopContainer(name string) error {
-	return c.RunCommand("stop", name)
+func (c *Client) StopContainer(ctx context.Context, name string) error {
+	return c.RunCommand(ctx, "stop", name)
 }
 
 // RemoveContainer removes a container
-func (c *Client) RemoveContainer(name string) error {
-	return c.RunCommand("rm", "-f", name)
+func (c *Client) RemoveContainer(ctx context.Context, name string) error {
+	return c.RunCommand(ctx, "rm", "-f", name)
 }
 
 // ExecCommand executes a command inside a container and returns the output
-func (c *Client) ExecCommand(containerName string, command ...string) (string, error) {
+func (c *Client) ExecCommand(ctx context.Context, containerName string, command ...string) (string, error) {
 	args := append([]string{"exec", containerName}, command...)
 	var out bytes.Buffer
-	cmd := exec.Command("docker", args...)
+	cmd := c.command(ctx, args...)
 	cmd.Stdout = &out
 	cmd.Stderr = &out
-	err := cmd.Run()
+	err := c.run(ctx, cmd)
 	return out.String(), err
 }
 
 // ContainerOptions holds Docker-specific options for running a container
 type ContainerOptions struct {
 	Name      string
+	HostIP    string // Address to publish the port on; empty means all interfaces
 	ExtraEnv  []string
 	ExtraArgs []string
 	Command   []string
+	Labels    map[string]string
+	Restart   string             // Restart policy (no, on-failure, unless-stopped); empty keeps the runtime's default
+	Health    *model.Healthcheck // Health check reported by docker inspect and ps; nil for none
 }
 
 // RunPostgres runs a PostgreSQL container with the specified configuration
-func (c *Client) RunPostgres(pgConfig *config.PostgresConfig, opts ContainerOptions) error {
+func (c *Client) RunPostgres(ctx context.Context, pgConfig *config.PostgresConfig, opts ContainerOptions) error {
 	args := c.buildPostgresArgs(pgConfig, opts)
-	return c.RunCommand(args...)
+	return c.RunCommand(ctx, args...)
 }
 
 // buildPostgresArgs builds the docker run arguments for PostgreSQL
 func (c *Client) buildPostgresArgs(pgConfig *config.PostgresConfig, opts ContainerOptions) []string {
 	args := []string{"run"}
 	args = append(args, "--name", opts.Name)
-	args = append(args, "-p", fmt.Sprintf("%s:5432", pgConfig.Port))
+	publish := fmt.Sprintf("%s:5432", pgConfig.Port)
+	if opts.HostIP != "" {
+		publish = opts.HostIP + ":" + publish
+	}
+	args = append(args, "-p", publish)
 
 	args = append(args, "-e", fmt.Sprintf("POSTGRES_DB=%s", pgConfig.Database))
 	args = append(args, "-e", fmt.Sprintf("POSTGRES_USER=%s", pgConfig.User))
@@ -132,12 +197,43 @@ func (c *Client) buildPostgresArgs(pgConfig *config.PostgresConfig, opts Contain
 		args = append(args, "-e", "POSTGRES_HOST_AUTH_METHOD=trust")
 	}
 
+	initdbEnv := pgConfig.InitdbEnv()
+	initdbKeys := make([]string, 0, len(initdbEnv))
+	for k := range initdbEnv {
+		initdbKeys = append(initdbKeys, k)
+	}
+	sort.Strings(initdbKeys)
+	for _, k := range initdbKeys {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, initdbEnv[k]))
+	}
+
 	for _, env := range opts.ExtraEnv {
 		args = append(args, "-e", env)
 	}
 
+	labelKeys := make([]string, 0, len(opts.Labels))
+	for k := range opts.Labels {
+		labelKeys = append(labelKeys, k)
+	}
+	sort.Strings(labelKeys)
+	for _, k := range labelKeys {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, opts.Labels[k]))
+	}
+
+	if opts.Restart != "" {
+		args = append(args, "--restart", opts.Restart)
+	}
+	if h := opts.Health; h != nil {
+		args = append(args, "--health-cmd", h.Cmd, "--health-interval", h.Interval,
+			"--health-timeout", h.Timeout, "--health-retries", strconv.Itoa(h.Retries))
+	}
+
 	args = append(args, opts.ExtraArgs...)
-	args = append(args, pgConfig.Image())
+	image := pgConfig.Image()
+	if pgConfig.CustomImage == "" {
+		image = c.runtime.qualifyImage(image)
+	}
+	args = append(args, image)
 	args = append(args, opts.Command...)
 
 	return args
@@ -145,8 +241,8 @@ func (c *Client) buildPostgresArgs(pgConfig *config.PostgresConfig, opts Contain
 
 // FindPgboxContainer searches for running pgbox containers.
 // Returns the best matching container name or error if none found.
-func (c *Client) FindPgboxContainer() (string, error) {
-	output, err := c.RunCommandWithOutput("ps", "--format", "{{.Names}}\t{{.Image}}")
+func (c *Client) FindPgboxContainer(ctx context.Context) (string, error) {
+	output, err := c.RunCommandWithOutput(ctx, "ps", "--format", "{{.Names}}\t{{.Image}}")
 	if err != nil {
 		return "", fmt.Errorf("failed to list containers: %w", err)
 	}
@@ -1,10 +1,17 @@
 package docker
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
 	"testing"
+	"time"
 
 	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBuildPostgresArgs(t *testing.T) {
@@ -106,6 +113,104 @@ func TestBuildPostgresArgs(t *testing.T) {
 				"-c", "shared_buffers=256MB",
 			},
 		},
+		{
+			name: "config with labels",
+			pgConfig: &config.PostgresConfig{
+				Version:  "17",
+				Port:     "5432",
+				Database: "testdb",
+				User:     "testuser",
+				Password: "secret",
+			},
+			opts: ContainerOptions{
+				Name:   "test-pg",
+				Labels: map[string]string{"dev.pgbox.postgres-version": "17", "dev.pgbox.extensions": "pgvector"},
+			},
+			expected: []string{
+				"run", "--name", "test-pg",
+				"-p", "5432:5432",
+				"-e", "POSTGRES_DB=testdb",
+				"-e", "POSTGRES_USER=testuser",
+				"-e", "POSTGRES_PASSWORD=secret",
+				"--label", "dev.pgbox.extensions=pgvector",
+				"--label", "dev.pgbox.postgres-version=17",
+				"postgres:17",
+			},
+		},
+		{
+			name: "port on a host address",
+			pgConfig: &config.PostgresConfig{
+				Version:  "17",
+				Port:     "5433",
+				Database: "testdb",
+				User:     "testuser",
+				Password: "secret",
+			},
+			opts: ContainerOptions{
+				Name:   "test-pg",
+				HostIP: "127.0.0.1",
+			},
+			expected: []string{
+				"run", "--name", "test-pg",
+				"-p", "127.0.0.1:5433:5432",
+				"-e", "POSTGRES_DB=testdb",
+				"-e", "POSTGRES_USER=testuser",
+				"-e", "POSTGRES_PASSWORD=secret",
+				"postgres:17",
+			},
+		},
+		{
+			name: "restart policy and healthcheck",
+			pgConfig: &config.PostgresConfig{
+				Version:  "17",
+				Port:     "5432",
+				Database: "testdb",
+				User:     "testuser",
+				Password: "secret",
+			},
+			opts: ContainerOptions{
+				Name:    "test-pg",
+				Restart: "unless-stopped",
+				Health:  &model.Healthcheck{Cmd: "pg_isready", Interval: "10s", Timeout: "5s", Retries: 5},
+			},
+			expected: []string{
+				"run", "--name", "test-pg",
+				"-p", "5432:5432",
+				"-e", "POSTGRES_DB=testdb",
+				"-e", "POSTGRES_USER=testuser",
+				"-e", "POSTGRES_PASSWORD=secret",
+				"--restart", "unless-stopped",
+				"--health-cmd", "pg_isready", "--health-interval", "10s", "--health-timeout", "5s", "--health-retries", "5",
+				"postgres:17",
+			},
+		},
+		{
+			name: "locale, encoding, time zone and initdb args",
+			pgConfig: &config.PostgresConfig{
+				Version:    "17",
+				Port:       "5432",
+				Database:   "testdb",
+				User:       "testuser",
+				Password:   "secret",
+				Locale:     "tr_TR.UTF-8",
+				Encoding:   "UTF8",
+				Timezone:   "Europe/Istanbul",
+				InitdbArgs: []string{"--data-checksums"},
+			},
+			opts: ContainerOptions{
+				Name: "test-pg",
+			},
+			expected: []string{
+				"run", "--name", "test-pg",
+				"-p", "5432:5432",
+				"-e", "POSTGRES_DB=testdb",
+				"-e", "POSTGRES_USER=testuser",
+				"-e", "POSTGRES_PASSWORD=secret",
+				"-e", "POSTGRES_INITDB_ARGS=--locale=tr_TR.UTF-8 --encoding=UTF8 --data-checksums",
+				"-e", "TZ=Europe/Istanbul",
+				"postgres:17",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,3 +220,51 @@ func TestBuildPostgresArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildPostgresArgs_PodmanQualifiesOfficialImage(t *testing.T) {
+	client := &Client{runtime: RuntimePodman}
+	pgConfig := &config.PostgresConfig{Version: "17", Port: "5432", Database: "db", User: "u", Password: "p"}
+
+	args := client.buildPostgresArgs(pgConfig, ContainerOptions{Name: "pg"})
+	assert.Contains(t, args, "docker.io/library/postgres:17")
+
+	// Locally built images are left for podman to resolve from local storage
+	pgConfig.CustomImage = "pgbox-pg17-custom:123"
+	args = client.buildPostgresArgs(pgConfig, ContainerOptions{Name: "pg"})
+	assert.Contains(t, args, "pgbox-pg17-custom:123")
+}
+
+func TestEnableBuildKit(t *testing.T) {
+	t.Setenv("DOCKER_BUILDKIT", "")
+	require.NoError(t, os.Unsetenv("DOCKER_BUILDKIT"))
+
+	build := exec.Command("docker", "build", ".")
+	(&Client{runtime: RuntimeDocker}).enableBuildKit(build)
+	assert.Contains(t, build.Env, "DOCKER_BUILDKIT=1")
+
+	ps := exec.Command("docker", "ps")
+	(&Client{runtime: RuntimeDocker}).enableBuildKit(ps)
+	assert.Nil(t, ps.Env)
+
+	podman := exec.Command("podman", "build", ".")
+	(&Client{runtime: RuntimePodman}).enableBuildKit(podman)
+	assert.Nil(t, podman.Env)
+
+	// An explicit choice is left alone
+	t.Setenv("DOCKER_BUILDKIT", "0")
+	explicit := exec.Command("docker", "build", ".")
+	(&Client{runtime: RuntimeDocker}).enableBuildKit(explicit)
+	assert.Nil(t, explicit.Env)
+}
+
+func TestClient_ContextEndsCommands(t *testing.T) {
+	fakeRuntime(t, "docker", "while :; do :; done")
+	expired := errors.New("--timeout 50ms expired")
+	ctx, cancel := context.WithTimeoutCause(t.Context(), 50*time.Millisecond, expired)
+	defer cancel()
+
+	_, err := NewClientForRuntime(RuntimeDocker).RunCommandWithOutput(ctx, "ps", "--format", "{{.Names}}")
+
+	require.ErrorIs(t, err, expired)
+	assert.EqualError(t, err, "docker ps did not finish: --timeout 50ms expired")
+}
@@ -0,0 +1,160 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Error codes reported by DaemonError. They are stable so scripts and CI can
+// match on them.
+const (
+	ErrCodeRuntimeNotFound    = "RUNTIME_NOT_FOUND"
+	ErrCodeDaemonUnavailable  = "DAEMON_UNAVAILABLE"
+	ErrCodeDaemonPermission   = "DAEMON_PERMISSION_DENIED"
+	ErrCodeDaemonCheckTimeout = "DAEMON_CHECK_TIMEOUT"
+)
+
+// ExitDaemonUnavailable is the process exit status for a DaemonError
+// (EX_UNAVAILABLE from sysexits.h).
+const ExitDaemonUnavailable = 69
+
+// daemonCheckTimeout bounds how long CheckDaemon waits for the runtime to
+// answer. Docker Desktop can hang for a while when it is still starting.
+var daemonCheckTimeout = 10 * time.Second
+
+// DaemonError reports that the container runtime cannot be used, with steps
+// to fix it for the current OS.
+type DaemonError struct {
+	Code     string   // One of the ErrCode constants
+	Runtime  Runtime  // Runtime that was checked
+	Detail   string   // Output of the failed check, if any
+	Guidance []string // Commands or actions that usually fix the problem
+}
+
+func (e *DaemonError) Error() string {
+	var b strings.Builder
+	switch e.Code {
+	case ErrCodeRuntimeNotFound:
+		fmt.Fprintf(&b, "%s is not installed or not on PATH", e.Runtime)
+	case ErrCodeDaemonPermission:
+		fmt.Fprintf(&b, "permission denied connecting to the %s daemon", e.Runtime)
+	case ErrCodeDaemonCheckTimeout:
+		fmt.Fprintf(&b, "%s did not respond within %s", e.Runtime, daemonCheckTimeout)
+	default:
+		fmt.Fprintf(&b, "cannot connect to the %s daemon", e.Runtime)
+	}
+	fmt.Fprintf(&b, " [%s]", e.Code)
+	if len(e.Guidance) > 0 {
+		b.WriteString("\n\nTo fix it:")
+		for _, step := range e.Guidance {
+			fmt.Fprintf(&b, "\n  %s", step)
+		}
+	}
+	if e.Detail != "" {
+		fmt.Fprintf(&b, "\n\n%s info said: %s", e.Runtime, e.Detail)
+	}
+	return b.String()
+}
+
+// IsDaemonError reports whether err is or wraps a DaemonError.
+func IsDaemonError(err error) bool {
+	var daemonErr *DaemonError
+	return errors.As(err, &daemonErr)
+}
+
+// CheckDaemon verifies that the runtime CLI is installed and its daemon is
+// reachable, returning a *DaemonError with guidance when it is not.
+func (c *Client) CheckDaemon(ctx context.Context) error {
+	r := Runtime(c.binary())
+	if _, err := exec.LookPath(c.binary()); err != nil {
+		return &DaemonError{Code: ErrCodeRuntimeNotFound, Runtime: r, Guidance: installGuidance(r)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, daemonCheckTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.binary(), "info")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := c.run(ctx, cmd); err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return &DaemonError{Code: ErrCodeDaemonCheckTimeout, Runtime: r, Guidance: startGuidance(r, runtime.GOOS)}
+	}
+	return classifyDaemonError(r, runtime.GOOS, failureReason(stdout.String(), stderr.String()))
+}
+
+// classifyDaemonError builds the DaemonError for a failed info command.
+func classifyDaemonError(r Runtime, goos, detail string) *DaemonError {
+	if strings.Contains(strings.ToLower(detail), "permission denied") {
+		return &DaemonError{Code: ErrCodeDaemonPermission, Runtime: r, Detail: detail, Guidance: permissionGuidance(r, goos)}
+	}
+	return &DaemonError{Code: ErrCodeDaemonUnavailable, Runtime: r, Detail: detail, Guidance: startGuidance(r, goos)}
+}
+
+// startGuidance returns how to start the runtime's daemon on goos.
+func startGuidance(r Runtime, goos string) []string {
+	switch r {
+	case RuntimePodman:
+		if goos == "linux" {
+			return []string{"systemctl --user start podman.socket"}
+		}
+		return []string{"podman machine start (run 'podman machine init' first if you have no machine)"}
+	case RuntimeNerdctl:
+		if goos == "darwin" {
+			return []string{"colima start --runtime containerd", "or with Lima: limactl start"}
+		}
+		return []string{"sudo systemctl start containerd"}
+	}
+	switch goos {
+	case "darwin":
+		return []string{"Start Docker Desktop: open -a Docker", "or with Colima: colima start", "or with OrbStack: orb start"}
+	case "windows":
+		return []string{"Start Docker Desktop from the Start menu and wait until it reports that it is running"}
+	default:
+		return []string{"sudo systemctl start docker", "or, for rootless Docker: systemctl --user start docker"}
+	}
+}
+
+// permissionGuidance returns how to get access to the daemon socket.
+func permissionGuidance(r Runtime, goos string) []string {
+	if r == RuntimeDocker && goos == "linux" {
+		return []string{"sudo usermod -aG docker $USER", "then log out and back in (or run: newgrp docker)"}
+	}
+	return startGuidance(r, goos)
+}
+
+// installGuidance returns where to get the runtime.
+func installGuidance(r Runtime) []string {
+	steps := map[Runtime]string{
+		RuntimeDocker:  "Install Docker: https://docs.docker.com/get-docker/",
+		RuntimePodman:  "Install Podman: https://podman.io/docs/installation",
+		RuntimeNerdctl: "Install nerdctl: https://github.com/containerd/nerdctl#install",
+	}
+	return []string{steps[r], "or pick another runtime with --runtime or $" + RuntimeEnvVar}
+}
+
+// failureReason picks the reason the info command failed. Recent Docker
+// versions print the server error as "ERROR: ..." on stdout and only
+// "errors pretty printing info" on stderr.
+func failureReason(stdout, stderr string) string {
+	for _, line := range strings.Split(stdout+"\n"+stderr, "\n") {
+		if reason, ok := strings.CutPrefix(strings.TrimSpace(line), "ERROR: "); ok {
+			return reason
+		}
+	}
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != "errors pretty printing info" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRuntime puts an executable script named after the runtime first on PATH.
+func fakeRuntime(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script runtimes need a Unix shell")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	t.Setenv("PATH", dir)
+}
+
+func TestCheckDaemon_Running(t *testing.T) {
+	fakeRuntime(t, "docker", "exit 0")
+
+	assert.NoError(t, NewClientForRuntime(RuntimeDocker).CheckDaemon(t.Context()))
+}
+
+func TestCheckDaemon_DaemonDown(t *testing.T) {
+	fakeRuntime(t, "docker", `echo "Server:"
+echo "ERROR: Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"
+echo "errors pretty printing info" >&2
+exit 1`)
+
+	err := NewClientForRuntime(RuntimeDocker).CheckDaemon(t.Context())
+
+	var daemonErr *DaemonError
+	require.ErrorAs(t, err, &daemonErr)
+	assert.Equal(t, ErrCodeDaemonUnavailable, daemonErr.Code)
+	assert.Equal(t, "Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", daemonErr.Detail)
+	assert.Contains(t, err.Error(), "cannot connect to the docker daemon [DAEMON_UNAVAILABLE]")
+	assert.True(t, IsDaemonError(err))
+}
+
+func TestCheckDaemon_NotInstalled(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	err := NewClientForRuntime(RuntimePodman).CheckDaemon(t.Context())
+
+	var daemonErr *DaemonError
+	require.ErrorAs(t, err, &daemonErr)
+	assert.Equal(t, ErrCodeRuntimeNotFound, daemonErr.Code)
+	assert.Contains(t, err.Error(), "podman is not installed or not on PATH [RUNTIME_NOT_FOUND]")
+	assert.Contains(t, err.Error(), "https://podman.io/docs/installation")
+}
+
+func TestClassifyDaemonError(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtime  Runtime
+		goos     string
+		detail   string
+		code     string
+		guidance string
+	}{
+		{"docker on macOS", RuntimeDocker, "darwin", "Cannot connect", ErrCodeDaemonUnavailable, "open -a Docker"},
+		{"colima on macOS", RuntimeDocker, "darwin", "Cannot connect", ErrCodeDaemonUnavailable, "colima start"},
+		{"docker on Linux", RuntimeDocker, "linux", "Cannot connect", ErrCodeDaemonUnavailable, "sudo systemctl start docker"},
+		{"docker on Windows", RuntimeDocker, "windows", "error during connect", ErrCodeDaemonUnavailable, "Start Docker Desktop"},
+		{"socket permissions", RuntimeDocker, "linux", "permission denied while trying to connect to the Docker daemon socket", ErrCodeDaemonPermission, "usermod -aG docker"},
+		{"podman machine", RuntimePodman, "darwin", "Cannot connect to Podman", ErrCodeDaemonUnavailable, "podman machine start"},
+		{"containerd", RuntimeNerdctl, "linux", "cannot access containerd socket", ErrCodeDaemonUnavailable, "systemctl start containerd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyDaemonError(tt.runtime, tt.goos, tt.detail)
+
+			assert.Equal(t, tt.code, err.Code)
+			assert.Contains(t, err.Error(), tt.guidance)
+			assert.Contains(t, err.Error(), tt.detail)
+		})
+	}
+}
@@ -1,45 +1,60 @@
 // Package docker provides Docker container operations
 package docker
 
-import "github.com/ahacop/pgbox/internal/config"
+import (
+	"context"
+	"io"
+
+	"github.com/ahacop/pgbox/internal/config"
+)
 
 // Docker defines the interface for Docker operations.
 // This interface enables unit testing by allowing mock implementations.
+// Commands are stopped when the context passed to them is done, except
+// interactive sessions, which run until the user ends them.
 type Docker interface {
 	// RunCommand executes a docker command with the given arguments,
 	// streaming output to stdout/stderr.
-	RunCommand(args ...string) error
+	RunCommand(ctx context.Context, args ...string) error
 
 	// RunCommandWithOutput executes a docker command and returns its output.
-	RunCommandWithOutput(args ...string) (string, error)
+	RunCommandWithOutput(ctx context.Context, args ...string) (string, error)
 
 	// RunInteractive executes a docker command interactively with TTY support.
-	RunInteractive(args ...string) error
+	RunInteractive(ctx context.Context, args ...string) error
+
+	// RunCommandWithIO executes a docker command wired to the given stdin,
+	// stdout and stderr. A nil stdin leaves the command without input.
+	RunCommandWithIO(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, args ...string) error
 
 	// IsContainerRunning checks if a container with the given name is running.
-	IsContainerRunning(name string) (bool, error)
+	IsContainerRunning(ctx context.Context, name string) (bool, error)
 
 	// GetContainerEnv retrieves an environment variable from a running container.
-	GetContainerEnv(containerName, envVar string) (string, error)
+	GetContainerEnv(ctx context.Context, containerName, envVar string) (string, error)
 
 	// ListContainers returns a list of running container names matching a prefix.
-	ListContainers(prefix string) ([]string, error)
+	ListContainers(ctx context.Context, prefix string) ([]string, error)
 
 	// StopContainer stops a running container.
-	StopContainer(name string) error
+	StopContainer(ctx context.Context, name string) error
 
 	// RemoveContainer removes a container.
-	RemoveContainer(name string) error
+	RemoveContainer(ctx context.Context, name string) error
 
 	// ExecCommand executes a command inside a container and returns the output.
-	ExecCommand(containerName string, command ...string) (string, error)
+	ExecCommand(ctx context.Context, containerName string, command ...string) (string, error)
 
 	// RunPostgres runs a PostgreSQL container with the specified configuration.
-	RunPostgres(pgConfig *config.PostgresConfig, opts ContainerOptions) error
+	RunPostgres(ctx context.Context, pgConfig *config.PostgresConfig, opts ContainerOptions) error
+
+	// CheckDaemon verifies that the runtime is installed and its daemon is
+	// reachable. Returns a *DaemonError describing how to fix it otherwise.
+	CheckDaemon(ctx context.Context) error
 
 	// FindPgboxContainer searches for running pgbox containers.
 	// Returns the best matching container name or error if none found.
-	FindPgboxContainer() (string, error)
+	FindPgboxContainer(ctx context.Context) (string, error)
 }
 
 // Verify that Client implements Docker interface at compile time
@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ahacop/pgbox/internal/config"
+)
+
+// DryRun wraps a Docker for --dry-run. Commands that only read state, such
+// as ps, inspect and volume ls, run as usual; commands that would change
+// anything are printed as "Would run: <command line>" and report success.
+type DryRun struct {
+	Docker
+	output io.Writer
+}
+
+// Verify that DryRun implements Docker interface at compile time
+var _ Docker = (*DryRun)(nil)
+
+// NewDryRun returns a DryRun that reads through d and prints to w.
+func NewDryRun(d Docker, w io.Writer) *DryRun {
+	return &DryRun{Docker: d, output: w}
+}
+
+// CommandLine formats a runtime command as the shell command line the
+// current runtime would run, with passwords masked.
+func CommandLine(args ...string) string {
+	return quoteCommand(append([]string{string(currentRuntime)}, args...))
+}
+
+// PostgresRunArgs returns the arguments RunPostgres passes to the current
+// runtime.
+func PostgresRunArgs(pgConfig *config.PostgresConfig, opts ContainerOptions) []string {
+	return (&Client{runtime: currentRuntime}).buildPostgresArgs(pgConfig, opts)
+}
+
+// print reports a command instead of running it.
+func (d *DryRun) print(args ...string) {
+	_, _ = fmt.Fprintf(d.output, "Would run: %s\n", CommandLine(args...))
+}
+
+// readOnly reports whether a command only reads state.
+func readOnly(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "ps", "images", "inspect", "logs", "port", "version", "info":
+		return true
+	case "container", "image", "network", "volume":
+		return len(args) > 1 && (args[1] == "ls" || args[1] == "inspect")
+	}
+	return false
+}
+
+// RunCommand prints the command.
+func (d *DryRun) RunCommand(ctx context.Context, args ...string) error {
+	d.print(args...)
+	return nil
+}
+
+// RunCommandWithOutput runs read-only commands and prints the others.
+func (d *DryRun) RunCommandWithOutput(ctx context.Context, args ...string) (string, error) {
+	if readOnly(args) {
+		return d.Docker.RunCommandWithOutput(ctx, args...)
+	}
+	d.print(args...)
+	return "", nil
+}
+
+// RunInteractive prints the command.
+func (d *DryRun) RunInteractive(ctx context.Context, args ...string) error {
+	d.print(args...)
+	return nil
+}
+
+// RunCommandWithIO prints the command.
+func (d *DryRun) RunCommandWithIO(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	d.print(args...)
+	return nil
+}
+
+// StopContainer prints the docker stop command.
+func (d *DryRun) StopContainer(ctx context.Context, name string) error {
+	d.print("stop", name)
+	return nil
+}
+
+// RemoveContainer prints the docker rm command.
+func (d *DryRun) RemoveContainer(ctx context.Context, name string) error {
+	d.print("rm", "-f", name)
+	return nil
+}
+
+// RunPostgres prints the docker run command.
+func (d *DryRun) RunPostgres(ctx context.Context, pgConfig *config.PostgresConfig, opts ContainerOptions) error {
+	d.print(PostgresRunArgs(pgConfig, opts)...)
+	return nil
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun_ReadsThroughAndPrintsChanges(t *testing.T) {
+	mock := NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) { return "pgbox-pg17\n", nil }
+	var buf bytes.Buffer
+	d := NewDryRun(mock, &buf)
+
+	out, err := d.RunCommandWithOutput(t.Context(), "ps", "-a", "--format", "{{.Names}}")
+	require.NoError(t, err)
+	assert.Equal(t, "pgbox-pg17\n", out)
+	_, _ = d.RunCommandWithOutput(t.Context(), "volume", "ls")
+
+	_, _ = d.RunCommandWithOutput(t.Context(), "volume", "rm", "pgbox-pg17-data")
+	require.NoError(t, d.RunCommand(t.Context(), "start", "pgbox-pg17"))
+	require.NoError(t, d.StopContainer(t.Context(), "pgbox-pg17"))
+	require.NoError(t, d.RemoveContainer(t.Context(), "pgbox-pg17"))
+	pg := config.NewPostgresConfig()
+	pg.Password = "secret"
+	require.NoError(t, d.RunPostgres(t.Context(), pg, ContainerOptions{Name: "pgbox-pg17"}))
+
+	assert.Equal(t, [][]string{{"ps", "-a", "--format", "{{.Names}}"}, {"volume", "ls"}}, mock.Calls.RunCommandWithOutput)
+	assert.Empty(t, mock.Calls.RunCommand)
+	assert.Empty(t, mock.Calls.StopContainer)
+	assert.Empty(t, mock.Calls.RemoveContainer)
+	assert.Empty(t, mock.Calls.RunPostgres)
+	assert.Equal(t, `Would run: docker volume rm pgbox-pg17-data
+Would run: docker start pgbox-pg17
+Would run: docker stop pgbox-pg17
+Would run: docker rm -f pgbox-pg17
+Would run: docker run --name pgbox-pg17 -p 5432:5432 -e POSTGRES_DB=postgres -e POSTGRES_USER=postgres -e 'POSTGRES_PASSWORD=***' postgres:18
+`, buf.String())
+}
@@ -1,8 +1,17 @@
 package docker
 
-import "github.com/ahacop/pgbox/internal/config"
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/ahacop/pgbox/internal/config"
+)
 
 // MockDocker is a mock implementation of the Docker interface for testing.
+// Its Func fields leave out the context the methods are called with; like
+// Client, the methods other than RunInteractive are recorded but fail with
+// the context's cause, without calling their Func, once it is done.
 type MockDocker struct {
 	// RunCommandFunc is called when RunCommand is invoked.
 	RunCommandFunc func(args ...string) error
@@ -10,6 +19,8 @@ type MockDocker struct {
 	RunCommandWithOutputFunc func(args ...string) (string, error)
 	// RunInteractiveFunc is called when RunInteractive is invoked.
 	RunInteractiveFunc func(args ...string) error
+	// RunCommandWithIOFunc is called when RunCommandWithIO is invoked.
+	RunCommandWithIOFunc func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error
 	// IsContainerRunningFunc is called when IsContainerRunning is invoked.
 	IsContainerRunningFunc func(name string) (bool, error)
 	// GetContainerEnvFunc is called when GetContainerEnv is invoked.
@@ -24,6 +35,8 @@ type MockDocker struct {
 	ExecCommandFunc func(containerName string, command ...string) (string, error)
 	// RunPostgresFunc is called when RunPostgres is invoked.
 	RunPostgresFunc func(pgConfig *config.PostgresConfig, opts ContainerOptions) error
+	// CheckDaemonFunc is called when CheckDaemon is invoked.
+	CheckDaemonFunc func() error
 	// FindPgboxContainerFunc is called when FindPgboxContainer is invoked.
 	FindPgboxContainerFunc func() (string, error)
 
@@ -32,6 +45,7 @@ type MockDocker struct {
 		RunCommand           [][]string
 		RunCommandWithOutput [][]string
 		RunInteractive       [][]string
+		RunCommandWithIO     [][]string
 		IsContainerRunning   []string
 		GetContainerEnv      []struct{ Container, EnvVar string }
 		ListContainers       []string
@@ -45,8 +59,13 @@ type MockDocker struct {
 			Config *config.PostgresConfig
 			Opts   ContainerOptions
 		}
+		CheckDaemon        int
 		FindPgboxContainer int
 	}
+
+	// mu guards Calls.RunCommandWithIO, which pgbox runs from several
+	// goroutines when it pulls images.
+	mu sync.Mutex
 }
 
 // NewMockDocker creates a new MockDocker with default no-op implementations.
@@ -55,6 +74,7 @@ func NewMockDocker() *MockDocker {
 	m.RunCommandFunc = func(args ...string) error { return nil }
 	m.RunCommandWithOutputFunc = func(args ...string) (string, error) { return "", nil }
 	m.RunInteractiveFunc = func(args ...string) error { return nil }
+	m.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error { return nil }
 	m.IsContainerRunningFunc = func(name string) (bool, error) { return false, nil }
 	m.GetContainerEnvFunc = func(containerName, envVar string) (string, error) { return "", nil }
 	m.ListContainersFunc = func(prefix string) ([]string, error) { return nil, nil }
@@ -62,68 +82,117 @@ func NewMockDocker() *MockDocker {
 	m.RemoveContainerFunc = func(name string) error { return nil }
 	m.ExecCommandFunc = func(containerName string, command ...string) (string, error) { return "", nil }
 	m.RunPostgresFunc = func(pgConfig *config.PostgresConfig, opts ContainerOptions) error { return nil }
+	m.CheckDaemonFunc = func() error { return nil }
 	m.FindPgboxContainerFunc = func() (string, error) { return "", nil }
 	return m
 }
 
-func (m *MockDocker) RunCommand(args ...string) error {
+func (m *MockDocker) RunCommand(ctx context.Context, args ...string) error {
 	m.Calls.RunCommand = append(m.Calls.RunCommand, args)
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
 	return m.RunCommandFunc(args...)
 }
 
-func (m *MockDocker) RunCommandWithOutput(args ...string) (string, error) {
+func (m *MockDocker) RunCommandWithOutput(ctx context.Context, args ...string) (string, error) {
 	m.Calls.RunCommandWithOutput = append(m.Calls.RunCommandWithOutput, args)
+	if err := context.Cause(ctx); err != nil {
+		return "", err
+	}
 	return m.RunCommandWithOutputFunc(args...)
 }
 
-func (m *MockDocker) RunInteractive(args ...string) error {
+func (m *MockDocker) RunInteractive(ctx context.Context, args ...string) error {
 	m.Calls.RunInteractive = append(m.Calls.RunInteractive, args)
 	return m.RunInteractiveFunc(args...)
 }
 
-func (m *MockDocker) IsContainerRunning(name string) (bool, error) {
+func (m *MockDocker) RunCommandWithIO(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	m.mu.Lock()
+	m.Calls.RunCommandWithIO = append(m.Calls.RunCommandWithIO, args)
+	m.mu.Unlock()
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	return m.RunCommandWithIOFunc(stdin, stdout, stderr, args...)
+}
+
+func (m *MockDocker) IsContainerRunning(ctx context.Context, name string) (bool, error) {
 	m.Calls.IsContainerRunning = append(m.Calls.IsContainerRunning, name)
+	if err := context.Cause(ctx); err != nil {
+		return false, err
+	}
 	return m.IsContainerRunningFunc(name)
 }
 
-func (m *MockDocker) GetContainerEnv(containerName, envVar string) (string, error) {
+func (m *MockDocker) GetContainerEnv(ctx context.Context, containerName, envVar string) (string, error) {
 	m.Calls.GetContainerEnv = append(m.Calls.GetContainerEnv, struct{ Container, EnvVar string }{containerName, envVar})
+	if err := context.Cause(ctx); err != nil {
+		return "", err
+	}
 	return m.GetContainerEnvFunc(containerName, envVar)
 }
 
-func (m *MockDocker) ListContainers(prefix string) ([]string, error) {
+func (m *MockDocker) ListContainers(ctx context.Context, prefix string) ([]string, error) {
 	m.Calls.ListContainers = append(m.Calls.ListContainers, prefix)
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
 	return m.ListContainersFunc(prefix)
 }
 
-func (m *MockDocker) StopContainer(name string) error {
+func (m *MockDocker) StopContainer(ctx context.Context, name string) error {
 	m.Calls.StopContainer = append(m.Calls.StopContainer, name)
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
 	return m.StopContainerFunc(name)
 }
 
-func (m *MockDocker) RemoveContainer(name string) error {
+func (m *MockDocker) RemoveContainer(ctx context.Context, name string) error {
 	m.Calls.RemoveContainer = append(m.Calls.RemoveContainer, name)
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
 	return m.RemoveContainerFunc(name)
 }
 
-func (m *MockDocker) ExecCommand(containerName string, command ...string) (string, error) {
+func (m *MockDocker) ExecCommand(ctx context.Context, containerName string, command ...string) (string, error) {
 	m.Calls.ExecCommand = append(m.Calls.ExecCommand, struct {
 		Container string
 		Command   []string
 	}{containerName, command})
+	if err := context.Cause(ctx); err != nil {
+		return "", err
+	}
 	return m.ExecCommandFunc(containerName, command...)
 }
 
-func (m *MockDocker) RunPostgres(pgConfig *config.PostgresConfig, opts ContainerOptions) error {
+func (m *MockDocker) RunPostgres(ctx context.Context, pgConfig *config.PostgresConfig, opts ContainerOptions) error {
 	m.Calls.RunPostgres = append(m.Calls.RunPostgres, struct {
 		Config *config.PostgresConfig
 		Opts   ContainerOptions
 	}{pgConfig, opts})
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
 	return m.RunPostgresFunc(pgConfig, opts)
 }
 
-func (m *MockDocker) FindPgboxContainer() (string, error) {
+func (m *MockDocker) CheckDaemon(ctx context.Context) error {
+	m.Calls.CheckDaemon++
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	return m.CheckDaemonFunc()
+}
+
+func (m *MockDocker) FindPgboxContainer(ctx context.Context) (string, error) {
 	m.Calls.FindPgboxContainer++
+	if err := context.Cause(ctx); err != nil {
+		return "", err
+	}
 	return m.FindPgboxContainerFunc()
 }
 
@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Runtime identifies the container CLI that pgbox drives.
+type Runtime string
+
+const (
+	RuntimeDocker  Runtime = "docker"
+	RuntimePodman  Runtime = "podman"
+	RuntimeNerdctl Runtime = "nerdctl"
+)
+
+// RuntimeEnvVar selects the container runtime when --runtime is not given.
+const RuntimeEnvVar = "PGBOX_RUNTIME"
+
+// Runtimes lists the supported container runtimes.
+var Runtimes = []Runtime{RuntimeDocker, RuntimePodman, RuntimeNerdctl}
+
+// currentRuntime is the runtime used by clients created with NewClient.
+var currentRuntime = RuntimeDocker
+
+// ParseRuntime validates a runtime name.
+func ParseRuntime(name string) (Runtime, error) {
+	for _, r := range Runtimes {
+		if Runtime(name) == r {
+			return r, nil
+		}
+	}
+	names := make([]string, len(Runtimes))
+	for i, r := range Runtimes {
+		names[i] = string(r)
+	}
+	return "", fmt.Errorf("unsupported container runtime %q (must be one of: %s)", name, strings.Join(names, ", "))
+}
+
+// SelectRuntime sets the runtime for new clients from the --runtime flag value,
+// falling back to $PGBOX_RUNTIME and then Docker.
+func SelectRuntime(flag string) error {
+	name := flag
+	if name == "" {
+		name = os.Getenv(RuntimeEnvVar)
+	}
+	if name == "" {
+		currentRuntime = RuntimeDocker
+		return nil
+	}
+	r, err := ParseRuntime(name)
+	if err != nil {
+		return err
+	}
+	currentRuntime = r
+	return nil
+}
+
+// CurrentRuntime returns the runtime used by clients created with NewClient.
+func CurrentRuntime() Runtime {
+	return currentRuntime
+}
+
+// qualifyImage returns the image reference to pass to the runtime. Podman
+// does not assume Docker Hub for short names and may prompt or fail without a
+// TTY, so official images are fully qualified there.
+func (r Runtime) qualifyImage(image string) string {
+	if r != RuntimePodman {
+		return image
+	}
+	first, _, hasSlash := strings.Cut(image, "/")
+	if hasSlash && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return image
+	}
+	if !hasSlash {
+		return "docker.io/library/" + image
+	}
+	return "docker.io/" + image
+}
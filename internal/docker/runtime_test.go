@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRuntime(t *testing.T) {
+	for _, name := range []string{"docker", "podman", "nerdctl"} {
+		r, err := ParseRuntime(name)
+		assert.NoError(t, err)
+		assert.Equal(t, Runtime(name), r)
+	}
+
+	_, err := ParseRuntime("lxc")
+	assert.ErrorContains(t, err, "must be one of: docker, podman, nerdctl")
+}
+
+func TestSelectRuntime(t *testing.T) {
+	t.Cleanup(func() { currentRuntime = RuntimeDocker })
+
+	t.Setenv(RuntimeEnvVar, "podman")
+	require.NoError(t, SelectRuntime(""))
+	assert.Equal(t, RuntimePodman, CurrentRuntime())
+	assert.Equal(t, "podman", NewClient().(*Client).binary())
+
+	// The flag wins over the environment
+	require.NoError(t, SelectRuntime("nerdctl"))
+	assert.Equal(t, RuntimeNerdctl, CurrentRuntime())
+
+	t.Setenv(RuntimeEnvVar, "")
+	require.NoError(t, SelectRuntime(""))
+	assert.Equal(t, RuntimeDocker, CurrentRuntime())
+
+	assert.Error(t, SelectRuntime("bogus"))
+}
+
+func TestQualifyImage(t *testing.T) {
+	tests := []struct {
+		runtime Runtime
+		image   string
+		want    string
+	}{
+		{RuntimeDocker, "postgres:17", "postgres:17"},
+		{RuntimeNerdctl, "postgres:17", "postgres:17"},
+		{RuntimePodman, "postgres:17", "docker.io/library/postgres:17"},
+		{RuntimePodman, "timescale/timescaledb:latest-pg17", "docker.io/timescale/timescaledb:latest-pg17"},
+		{RuntimePodman, "ghcr.io/org/pg:17", "ghcr.io/org/pg:17"},
+		{RuntimePodman, "localhost/pgbox-pg17:1", "localhost/pgbox-pg17:1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.runtime.qualifyImage(tt.image), "%s %s", tt.runtime, tt.image)
+	}
+}
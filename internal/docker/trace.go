@@ -0,0 +1,229 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// traceOutputLimit is how much of a command's captured output is traced.
+const traceOutputLimit = 1024
+
+// traceOutput receives one entry per runtime invocation when tracing is on.
+// traceMu guards it and keeps the entries of commands run at the same time,
+// such as parallel pulls, whole.
+var (
+	traceMu     sync.Mutex
+	traceOutput io.Writer
+)
+
+// SetTrace logs every runtime invocation made by clients to w: the command
+// line, duration, exit code and the start of its output. A nil w turns
+// tracing off.
+func SetTrace(w io.Writer) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceOutput = w
+}
+
+// tracing reports whether SetTrace turned tracing on.
+func tracing() bool {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	return traceOutput != nil
+}
+
+// writeTrace writes one trace entry, unless tracing was turned off meanwhile.
+func writeTrace(entry string) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceOutput != nil {
+		_, _ = io.WriteString(traceOutput, entry)
+	}
+}
+
+// secretArgPattern matches arguments that carry a password, such as
+// POSTGRES_PASSWORD=secret or PGPASSWORD=secret.
+var secretArgPattern = regexp.MustCompile(`(?i)^([A-Z_]*PASSWORD)=.+$`)
+
+// sqlSecretPattern matches a password literal inside SQL, such as the
+// password option of CREATE USER MAPPING or ALTER ROLE ... PASSWORD 'secret'.
+var sqlSecretPattern = regexp.MustCompile(`(?i)(\bpassword\s*)E?'(?:[^']|'')*'`)
+
+// uriSecretPattern matches the password of a connection URI.
+var uriSecretPattern = regexp.MustCompile(`(://[^:/@\s]*:)[^@\s]*@`)
+
+// secretOutput reports whether a command prints values that may hold
+// credentials, such as the container environment, so its output is not
+// traced.
+func secretOutput(args []string) bool {
+	for i, arg := range args {
+		switch {
+		case arg == "printenv", strings.Contains(arg, ".Config.Env"):
+			return true
+		case arg == "inspect" && !slices.ContainsFunc(args[i+1:], func(a string) bool {
+			return a == "-f" || a == "--format" || strings.HasPrefix(a, "--format=")
+		}):
+			// Without a format, inspect prints everything, the environment included
+			return true
+		}
+	}
+	return false
+}
+
+// run runs cmd, tracing it when tracing is enabled. Otherwise --verbose
+// logs just its command line. A command killed because ctx ended fails with
+// the context's cause, such as an expired --timeout.
+func (c *Client) run(ctx context.Context, cmd *exec.Cmd) (err error) {
+	c.enableBuildKit(cmd)
+	defer func() {
+		if err != nil && cmd.Cancel != nil && ctx.Err() != nil {
+			err = fmt.Errorf("%s did not finish: %w", quoteCommand(cmd.Args[:min(len(cmd.Args), 2)]), context.Cause(ctx))
+		}
+	}()
+	if !tracing() {
+		logging.Debugf(os.Stderr, "+ %s", quoteCommand(cmd.Args))
+		return cmd.Run()
+	}
+
+	captured := &limitedBuffer{limit: traceOutputLimit}
+	var stdoutStreamed, stderrStreamed bool
+	redacted := secretOutput(cmd.Args[1:])
+	switch {
+	case redacted:
+		// The command's writers are left alone so nothing is captured
+	case sameWriter(cmd.Stdout, cmd.Stderr):
+		// One writer for both keeps os/exec copying them in a single goroutine
+		cmd.Stdout, stdoutStreamed = teeForTrace(cmd.Stdout, captured)
+		cmd.Stderr = cmd.Stdout
+	default:
+		cmd.Stdout, stdoutStreamed = teeForTrace(cmd.Stdout, captured)
+		cmd.Stderr, stderrStreamed = teeForTrace(cmd.Stderr, captured)
+	}
+	streamed := stdoutStreamed || stderrStreamed
+
+	start := time.Now()
+	err = cmd.Run()
+	elapsed := time.Since(start)
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "+ %s\n", quoteCommand(cmd.Args))
+	fmt.Fprintf(&b, "  took %s, exit %d", elapsed.Round(time.Millisecond), exitCode)
+	if err != nil && exitErr == nil {
+		fmt.Fprintf(&b, " (%v)", err)
+	}
+	b.WriteString("\n")
+	if output := strings.TrimRight(captured.String(), "\n"); output != "" {
+		for _, line := range strings.Split(output, "\n") {
+			fmt.Fprintf(&b, "  | %s\n", line)
+		}
+		if captured.truncated {
+			fmt.Fprintf(&b, "  | ... (output truncated to %d bytes)\n", traceOutputLimit)
+		}
+	} else if redacted {
+		b.WriteString("  | (output not traced since it may hold credentials)\n")
+	} else if streamed {
+		b.WriteString("  | (output streamed to the terminal)\n")
+	}
+	writeTrace(b.String())
+	return err
+}
+
+// sameWriter reports whether stdout and stderr are the same writer, as when
+// a command's output is collected in one buffer. Writers that cannot be
+// compared are different.
+func sameWriter(stdout, stderr io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return stdout != nil && stdout == stderr
+}
+
+// teeForTrace copies w's output into captured, unless w is a terminal or file
+// that the command should keep writing to directly (so TTY detection inside
+// the runtime still works). Returns the writer to use and whether its output
+// bypasses the capture.
+func teeForTrace(w io.Writer, captured io.Writer) (io.Writer, bool) {
+	switch w.(type) {
+	case nil:
+		return nil, false
+	case *os.File:
+		return w, true
+	}
+	return io.MultiWriter(w, captured), false
+}
+
+// quoteCommand formats args as a shell command line that reproduces the
+// invocation, with passwords masked, including those in SQL and connection
+// URIs.
+func quoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if m := secretArgPattern.FindStringSubmatch(arg); m != nil {
+			arg = m[1] + "=***"
+		}
+		arg = sqlSecretPattern.ReplaceAllString(arg, "${1}'***'")
+		arg = uriSecretPattern.ReplaceAllString(arg, "${1}***@")
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s for POSIX shells when it contains special characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// limitedBuffer keeps the first limit bytes written to it. It is safe for
+// the stdout and stderr copies of a command to write to at once.
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.limit - len(b.buf); room > 0 {
+		if len(p) > room {
+			b.buf = append(b.buf, p[:room]...)
+			b.truncated = true
+		} else {
+			b.buf = append(b.buf, p...)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
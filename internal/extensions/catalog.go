@@ -3,7 +3,10 @@ package extensions
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -11,40 +14,126 @@ import (
 type Extension struct {
 	// Package is the apt package pattern (e.g., "postgresql-{v}-pgvector").
 	// Empty for built-in contrib extensions.
-	Package string
+	Package string `toml:"package"`
+
+	// ApkPackage is the apk package pattern installed instead of Package,
+	// DebURL and ZipURL when the base image is Alpine-based (e.g.,
+	// "postgresql{v}-pgvector"). Extensions that need a package but have no
+	// ApkPackage cannot be used with an Alpine base image.
+	ApkPackage string `toml:"apk_package"`
 
 	// DebURL is a URL template for downloading a .deb package directly.
 	// Supports placeholders: {v} (PG version), {arch} (amd64/arm64).
 	// If set, this is used instead of Package for installation.
-	DebURL string
+	DebURL string `toml:"deb_url"`
 
 	// ZipURL is a URL template for downloading a .zip file containing a .deb package.
 	// Supports placeholders: {v} (PG version), {arch} (amd64/arm64).
 	// The zip is extracted and the .deb inside is installed.
-	ZipURL string
+	ZipURL string `toml:"zip_url"`
+
+	// SHA256 maps "<v>/<arch>" (e.g., "17/amd64") to the hex SHA-256 of the
+	// file DebURL or ZipURL resolves to. Downloads without an entry are not
+	// verified. The built-in catalog's are recorded in checksums.go.
+	SHA256 map[string]string `toml:"sha256"`
+
+	// Debs maps a Debian architecture (amd64, arm64) to the .deb to download
+	// for it. Use this instead of DebURL when a project names its per-arch
+	// artifacts differently or the downloads should be checksum-verified.
+	Debs map[string]Artifact `toml:"debs"`
+
+	// Zips is the .zip equivalent of Debs.
+	Zips map[string]Artifact `toml:"zips"`
 
 	// BaseImage overrides the default postgres:{v} image.
 	// Use this when a .deb requires a specific distro (e.g., "postgres:{v}-bookworm").
-	BaseImage string
+	BaseImage string `toml:"base_image"`
 
 	// SQLName is the CREATE EXTENSION name if different from the catalog key.
-	SQLName string
+	SQLName string `toml:"sql_name"`
 
 	// Preload lists shared_preload_libraries entries needed.
-	Preload []string
+	Preload []string `toml:"preload"`
 
 	// GUCs contains PostgreSQL configuration parameters.
-	GUCs map[string]string
+	GUCs map[string]string `toml:"gucs"`
 
 	// InitSQL is custom initialization SQL. Empty means default CREATE EXTENSION.
-	InitSQL string
+	InitSQL string `toml:"init_sql"`
+
+	// Script holds shell steps the extension needs besides its SQL.
+	Script Scripts `toml:"script"`
+
+	// Versions lists the PostgreSQL major versions the extension is available for.
+	// Empty means all supported versions.
+	Versions []string `toml:"versions"`
+
+	// MinPG and MaxPG bound the PostgreSQL major versions the extension is
+	// available for, such as MinPG "17" for one that needs 17 or newer. Unlike
+	// Versions, a range keeps up with new PostgreSQL releases. Empty means
+	// unbounded.
+	MinPG string `toml:"min_pg"`
+	MaxPG string `toml:"max_pg"`
+
+	// Description is a one-line summary shown by pgbox list-extensions.
+	// Built-in entries take theirs from the descriptions table (see Describe).
+	Description string `toml:"description"`
+
+	// Category groups the extension for pgbox list-extensions --category; one
+	// of Categories. Built-in entries take theirs from the categories table
+	// (see Category).
+	Category string `toml:"category"`
+
+	// DocURL links to the extension's documentation.
+	DocURL string `toml:"doc_url"`
+
+	// Tips are short getting-started hints printed after pgbox up and by
+	// pgbox info --tips, such as the statement for a first use.
+	Tips []string `toml:"tips"`
+
+	// File is the spec file a custom extension was loaded from by LoadDir.
+	// Empty for the built-in catalog.
+	File string `toml:"-"`
+}
+
+// Artifact is a downloadable package for one architecture.
+type Artifact struct {
+	// URL supports the {v} (PG version) placeholder.
+	URL string `toml:"url"`
+
+	// SHA256 maps PostgreSQL major version to the hex SHA-256 of the file
+	// downloaded for it. Versions without an entry are not verified.
+	SHA256 map[string]string `toml:"sha256"`
+}
+
+// Scripts are an extension's shell steps, by when they run.
+type Scripts struct {
+	// Initdb fragments run once, when a new data volume is initialized and
+	// before the init SQL, as the postgres user with PGDATA set. Use them for
+	// steps SQL cannot do, such as creating directories under PGDATA.
+	Initdb []InitdbScript `toml:"initdb"`
+}
+
+// InitdbScript is one shell fragment run at initdb time.
+type InitdbScript struct {
+	// Name tells the fragment apart from the extension's others. Optional.
+	Name string `toml:"name"`
+
+	// Run is the shell code, run with sh -e.
+	Run string `toml:"run"`
+}
+
+// Download is a resolved artifact URL and its expected checksum, if known.
+type Download struct {
+	URL    string
+	SHA256 string
 }
 
 // Catalog maps extension name to its configuration.
 // The key is the name users specify (e.g., "pgvector", "pg_cron").
 var Catalog = map[string]Extension{
 	// ===== Built-in PostgreSQL contrib extensions (no apt package needed) =====
-	"adminpack":          {},
+	"adminpack":          {MaxPG: "16"}, // removed from contrib in PostgreSQL 17
 	"amcheck":            {},
 	"autoinc":            {},
 	"bloom":              {},
@@ -71,7 +160,7 @@ var Catalog = map[string]Extension{
 	"pg_buffercache":     {},
 	"pg_freespacemap":    {},
 	"pg_prewarm":         {},
-	"pg_stat_statements": {},
+	"pg_stat_statements": {Preload: []string{"pg_stat_statements"}},
 	"pg_surgery":         {},
 	"pg_trgm":            {},
 	"pg_visibility":      {},
@@ -174,6 +263,11 @@ var Catalog = map[string]Extension{
 			"CREATE EXTENSION IF NOT EXISTS postgis;\n\n" +
 			"-- Grant usage on spatial_ref_sys to public\n" +
 			"GRANT SELECT ON spatial_ref_sys TO PUBLIC;",
+		DocURL: "https://postgis.net/documentation/",
+		Tips: []string{
+			"Check the installation: SELECT postgis_full_version();",
+			"Spatial index: CREATE INDEX ON places USING gist (geom);",
+		},
 	},
 	"postgis-3-scripts": {Package: "postgresql-{v}-postgis-3-scripts"},
 	"powa":              {Package: "postgresql-{v}-powa"},
@@ -203,7 +297,16 @@ var Catalog = map[string]Extension{
 	"unit":              {Package: "postgresql-{v}-unit"},
 
 	// Extensions with different SQL names
-	"pgvector": {Package: "postgresql-{v}-pgvector", SQLName: "vector"},
+	"pgvector": {
+		Package: "postgresql-{v}-pgvector",
+		SQLName: "vector",
+		DocURL:  "https://github.com/pgvector/pgvector",
+		Tips: []string{
+			"Store embeddings: CREATE TABLE items (id bigserial PRIMARY KEY, embedding vector(3));",
+			"Nearest neighbours: SELECT * FROM items ORDER BY embedding <-> '[1,2,3]' LIMIT 5;",
+			"Approximate index: CREATE INDEX ON items USING hnsw (embedding vector_l2_ops);",
+		},
+	},
 
 	// ===== Complex extensions (need shared_preload_libraries and/or GUCs) =====
 	"pg_cron": {
@@ -214,6 +317,11 @@ var Catalog = map[string]Extension{
 			"cron.max_running_jobs": "5",
 		},
 		InitSQL: "CREATE EXTENSION IF NOT EXISTS pg_cron;\nGRANT USAGE ON SCHEMA cron TO postgres;",
+		DocURL:  "https://github.com/citusdata/pg_cron",
+		Tips: []string{
+			"Schedule a job: SELECT cron.schedule('nightly-vacuum', '0 3 * * *', 'VACUUM');",
+			"Jobs run in the postgres database (cron.database_name); list runs with SELECT * FROM cron.job_run_details;",
+		},
 	},
 	"wal2json": {
 		Package: "postgresql-{v}-wal2json",
@@ -226,6 +334,12 @@ var Catalog = map[string]Extension{
 		InitSQL: "-- wal2json logical decoding plugin is now available\n" +
 			"-- To use it, create a replication slot with:\n" +
 			"-- SELECT pg_create_logical_replication_slot('slot_name', 'wal2json');",
+		DocURL: "https://github.com/eulerto/wal2json",
+		Tips: []string{
+			"Create a slot: SELECT pg_create_logical_replication_slot('slot_name', 'wal2json');",
+			"Read changes: SELECT data FROM pg_logical_slot_get_changes('slot_name', NULL, NULL, 'pretty-print', '1');",
+			"Drop unused slots (SELECT pg_drop_replication_slot('slot_name');), since they keep WAL around",
+		},
 	},
 
 	// ===== Extensions installed from .deb URLs (GitHub releases, etc.) =====
@@ -234,16 +348,31 @@ var Catalog = map[string]Extension{
 		BaseImage: "postgres:{v}-bookworm",
 		SQLName:   "pg_search",
 		InitSQL:   "CREATE EXTENSION IF NOT EXISTS pg_search;",
+		DocURL:    "https://docs.paradedb.com/",
+		Tips: []string{
+			"Full-text index: CREATE INDEX ON items USING bm25 (id, description) WITH (key_field = 'id');",
+			"Search it: SELECT * FROM items WHERE description @@@ 'shoes';",
+		},
 	},
 
 	// ===== Extensions installed from .zip files containing .deb packages =====
-	// pg_textsearch: BM25 ranked text search (supports PostgreSQL 17 and 18 only)
+	// pg_textsearch: BM25 ranked text search (PostgreSQL 17 and newer)
 	"pg_textsearch": {
 		ZipURL:    "https://github.com/timescale/pg_textsearch/releases/download/v0.1.0/pg-textsearch-v0.1.0-pg{v}-{arch}.zip",
 		BaseImage: "postgres:{v}-bookworm",
+		MinPG:     "17",
 	},
 }
 
+// init attaches the recorded checksums to the built-in catalog entries.
+func init() {
+	for name, checksums := range recordedChecksums {
+		ext := Catalog[name]
+		ext.SHA256 = checksums
+		Catalog[name] = ext
+	}
+}
+
 // Get returns the extension configuration for the given name.
 // Returns false if the extension is not found.
 func Get(name string) (Extension, bool) {
@@ -288,6 +417,44 @@ func GetInitSQL(name string) string {
 	return fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s;", sqlName)
 }
 
+// GetInitdbScripts returns an extension's initdb shell fragments, each named
+// after the extension and the fragment's own name or position.
+func GetInitdbScripts(name string) []InitdbScript {
+	ext, ok := Catalog[name]
+	if !ok {
+		return nil
+	}
+	scripts := make([]InitdbScript, 0, len(ext.Script.Initdb))
+	for i, script := range ext.Script.Initdb {
+		label := script.Name
+		if label == "" {
+			label = strconv.Itoa(i + 1)
+		}
+		scripts = append(scripts, InitdbScript{Name: name + "-" + label, Run: script.Run})
+	}
+	return scripts
+}
+
+// createExtensionPattern matches the extension name in a CREATE EXTENSION statement.
+var createExtensionPattern = regexp.MustCompile(`(?i)^\s*CREATE\s+EXTENSION\s+(?:IF\s+NOT\s+EXISTS\s+)?"?([\w-]+)"?`)
+
+// GetCreatedSQLNames returns the SQL extension names created by the init SQL
+// of the given extensions. Extensions whose init SQL does not run
+// CREATE EXTENSION (e.g., wal2json) contribute nothing.
+func GetCreatedSQLNames(names []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		for _, line := range strings.Split(GetInitSQL(name), "\n") {
+			if m := createExtensionPattern.FindStringSubmatch(line); m != nil && !seen[m[1]] {
+				result = append(result, m[1])
+				seen[m[1]] = true
+			}
+		}
+	}
+	return result
+}
+
 // ValidateExtensions checks that all extension names exist in the catalog.
 func ValidateExtensions(names []string) error {
 	var unknown []string
@@ -302,6 +469,52 @@ func ValidateExtensions(names []string) error {
 	return nil
 }
 
+// ValidateVersion checks that all extensions are available for the given PostgreSQL version.
+func ValidateVersion(names []string, version string) error {
+	var unsupported []string
+	for _, name := range names {
+		ext, ok := Catalog[name]
+		if !ok || ext.SupportsVersion(version) {
+			continue
+		}
+		unsupported = append(unsupported, fmt.Sprintf("%s requires PostgreSQL %s, requested %s", name, ext.VersionRequirement(), version))
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s", strings.Join(unsupported, "; "))
+	}
+	return nil
+}
+
+// SupportsVersion reports whether the extension is available for a
+// PostgreSQL major version, per its Versions list and MinPG/MaxPG range.
+func (e Extension) SupportsVersion(version string) bool {
+	if len(e.Versions) > 0 && !slices.Contains(e.Versions, version) {
+		return false
+	}
+	major, _ := strconv.Atoi(version)
+	if min, err := strconv.Atoi(e.MinPG); err == nil && major < min {
+		return false
+	}
+	if max, err := strconv.Atoi(e.MaxPG); err == nil && major > max {
+		return false
+	}
+	return true
+}
+
+// VersionRequirement describes the PostgreSQL versions the extension is
+// available for, as in "17+", "16 or older", "16 to 18" or "16, 18".
+func (e Extension) VersionRequirement() string {
+	switch {
+	case len(e.Versions) > 0:
+		return strings.Join(e.Versions, ", ")
+	case e.MinPG != "" && e.MaxPG != "":
+		return e.MinPG + " to " + e.MaxPG
+	case e.MinPG != "":
+		return e.MinPG + "+"
+	}
+	return e.MaxPG + " or older"
+}
+
 // ListExtensions returns all extension names sorted alphabetically.
 func ListExtensions() []string {
 	names := make([]string, 0, len(Catalog))
@@ -333,6 +546,18 @@ func NeedsPackages(names []string) bool {
 	return false
 }
 
+// NeedsRebuild reports whether the extension changes the image or the server
+// command line (packages, downloads, shared_preload_libraries or settings), so
+// an existing container cannot pick it up with CREATE EXTENSION alone.
+func NeedsRebuild(name string) bool {
+	ext, ok := Catalog[name]
+	if !ok {
+		return false
+	}
+	return ext.Package != "" || ext.ApkPackage != "" || HasDebURL(name) || HasZipURL(name) || ext.BaseImage != "" ||
+		len(ext.Preload) > 0 || len(ext.GUCs) > 0
+}
+
 // GetPackages returns all apt packages needed for the given extensions and version.
 func GetPackages(names []string, version string) []string {
 	var packages []string
@@ -347,6 +572,47 @@ func GetPackages(names []string, version string) []string {
 	return packages
 }
 
+// GetApkPackage returns the apk package name for an extension and PostgreSQL
+// version. Returns empty string if the extension has no apk package.
+func GetApkPackage(name, version string) string {
+	ext, ok := Catalog[name]
+	if !ok {
+		return ""
+	}
+	return strings.ReplaceAll(ext.ApkPackage, "{v}", version)
+}
+
+// GetApkPackages returns all apk packages needed for the given extensions and version.
+func GetApkPackages(names []string, version string) []string {
+	var packages []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		pkg := GetApkPackage(name, version)
+		if pkg != "" && !seen[pkg] {
+			packages = append(packages, pkg)
+			seen[pkg] = true
+		}
+	}
+	return packages
+}
+
+// MissingApkPackages returns the extensions that install a Debian package or
+// download but have no apk package, so they cannot be installed on an Alpine
+// base image.
+func MissingApkPackages(names []string) []string {
+	var missing []string
+	for _, name := range names {
+		ext, ok := Catalog[name]
+		if !ok || ext.ApkPackage != "" {
+			continue
+		}
+		if ext.Package != "" || HasDebURL(name) || HasZipURL(name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
 // GetPreloadLibraries returns all shared_preload_libraries needed.
 func GetPreloadLibraries(names []string) []string {
 	var libs []string
@@ -366,54 +632,155 @@ func GetPreloadLibraries(names []string) []string {
 
 // GetGUCs returns all GUC settings needed, detecting conflicts.
 func GetGUCs(names []string) (map[string]string, error) {
-	gucs := make(map[string]string)
-	sources := make(map[string]string) // Track which extension set each GUC
+	return ResolveGUCs(names, nil, nil)
+}
+
+// GUCConflict describes extensions that set the same GUC to different values.
+type GUCConflict struct {
+	Key        string
+	Extensions []string          // Extensions setting the key, in request order
+	Values     map[string]string // Extension name -> value
+}
+
+// ConflictError reports every GUC conflict between the requested extensions.
+type ConflictError struct {
+	Conflicts []GUCConflict
+}
+
+func (e *ConflictError) Error() string {
+	var parts []string
+	for _, c := range e.Conflicts {
+		var sets []string
+		for _, ext := range c.Extensions {
+			sets = append(sets, fmt.Sprintf("%s sets '%s'", ext, c.Values[ext]))
+		}
+		parts = append(parts, fmt.Sprintf("GUC conflict for '%s': %s", c.Key, strings.Join(sets, ", ")))
+	}
+	return strings.Join(parts, "; ") + " (use --prefer <extension>, --prefer <guc>=<extension> or --set <guc>=<value> to choose)"
+}
+
+// ResolveGUCs returns all GUC settings needed by the given extensions.
+// When extensions disagree on a value, prefer breaks the tie: entries of the
+// form "key=extension" apply to a single GUC and take precedence over plain
+// "extension" entries, which apply to every GUC that extension sets.
+// A GUC in userSet is set by the user, whose value wins anyway, so when the
+// extensions disagree on it, it is left out of the result instead.
+// Unresolved disagreements are returned as a *ConflictError.
+func ResolveGUCs(names []string, prefer []string, userSet []string) (map[string]string, error) {
+	byKey := make(map[string]*GUCConflict)
+	var keys []string
 
 	for _, name := range names {
-		if ext, ok := Catalog[name]; ok {
-			for k, v := range ext.GUCs {
-				if existing, hasKey := gucs[k]; hasKey && existing != v {
-					return nil, fmt.Errorf("GUC conflict for '%s': %s sets '%s', %s sets '%s'",
-						k, sources[k], existing, name, v)
-				}
-				gucs[k] = v
-				sources[k] = name
+		ext, ok := Catalog[name]
+		if !ok {
+			continue
+		}
+		for k, v := range ext.GUCs {
+			c, exists := byKey[k]
+			if !exists {
+				c = &GUCConflict{Key: k, Values: make(map[string]string)}
+				byKey[k] = c
+				keys = append(keys, k)
+			}
+			if _, seen := c.Values[name]; !seen {
+				c.Extensions = append(c.Extensions, name)
 			}
+			c.Values[name] = v
 		}
 	}
+	sort.Strings(keys)
+
+	gucs := make(map[string]string)
+	var conflicts []GUCConflict
+	for _, k := range keys {
+		c := byKey[k]
+		value, resolved := resolveGUC(c, prefer)
+		if !resolved && slices.Contains(userSet, k) {
+			continue
+		}
+		if !resolved {
+			conflicts = append(conflicts, *c)
+			continue
+		}
+		gucs[k] = value
+	}
+
+	if len(conflicts) > 0 {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
 	return gucs, nil
 }
 
+// resolveGUC picks a single value for a GUC, using prefer to break ties.
+func resolveGUC(c *GUCConflict, prefer []string) (string, bool) {
+	first := c.Values[c.Extensions[0]]
+	agree := true
+	for _, ext := range c.Extensions[1:] {
+		if c.Values[ext] != first {
+			agree = false
+			break
+		}
+	}
+	if agree {
+		return first, true
+	}
+	for _, p := range prefer {
+		if key, ext, ok := strings.Cut(p, "="); ok && key == c.Key {
+			if v, ok := c.Values[ext]; ok {
+				return v, true
+			}
+		}
+	}
+	for _, p := range prefer {
+		if v, ok := c.Values[p]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// GetGUCProvider returns the first of the given extensions that sets key to value.
+// Returns empty string if none of them does.
+func GetGUCProvider(names []string, key, value string) string {
+	for _, name := range names {
+		if ext, ok := Catalog[name]; ok && ext.GUCs[key] == value {
+			if _, set := ext.GUCs[key]; set {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
 // GetDebURL returns the resolved .deb URL for an extension.
-// Returns empty string if the extension doesn't use .deb installation.
+// Returns empty string if the extension doesn't use .deb installation or has
+// no .deb for arch.
 func GetDebURL(name, version, arch string) string {
 	ext, ok := Catalog[name]
-	if !ok || ext.DebURL == "" {
+	if !ok {
 		return ""
 	}
-	url := strings.ReplaceAll(ext.DebURL, "{v}", version)
-	url = strings.ReplaceAll(url, "{arch}", arch)
-	return url
+	d, _ := resolveDownload(ext.DebURL, ext.SHA256, ext.Debs, version, arch)
+	return d.URL
 }
 
 // GetDebURLs returns all .deb URLs needed for the given extensions.
 func GetDebURLs(names []string, version, arch string) []string {
-	var urls []string
-	seen := make(map[string]bool)
-	for _, name := range names {
-		url := GetDebURL(name, version, arch)
-		if url != "" && !seen[url] {
-			urls = append(urls, url)
-			seen[url] = true
-		}
-	}
-	return urls
+	return urlsOf(names, func(name string) string { return GetDebURL(name, version, arch) })
+}
+
+// GetDebDownloads returns the .deb downloads needed for the given extensions
+// on arch, with their checksums. It fails when an extension has no .deb for arch.
+func GetDebDownloads(names []string, version, arch string) ([]Download, error) {
+	return downloadsFor(names, version, arch, ".deb", func(ext Extension) (string, map[string]Artifact) {
+		return ext.DebURL, ext.Debs
+	})
 }
 
 // NeedsDebPackages returns true if any of the given extensions require .deb downloads.
 func NeedsDebPackages(names []string) bool {
 	for _, name := range names {
-		if ext, ok := Catalog[name]; ok && ext.DebURL != "" {
+		if HasDebURL(name) {
 			return true
 		}
 	}
@@ -423,39 +790,38 @@ func NeedsDebPackages(names []string) bool {
 // HasDebURL returns true if the extension uses .deb installation.
 func HasDebURL(name string) bool {
 	ext, ok := Catalog[name]
-	return ok && ext.DebURL != ""
+	return ok && (ext.DebURL != "" || len(ext.Debs) > 0)
 }
 
 // GetZipURL returns the resolved .zip URL for an extension.
-// Returns empty string if the extension doesn't use .zip installation.
+// Returns empty string if the extension doesn't use .zip installation or has
+// no .zip for arch.
 func GetZipURL(name, version, arch string) string {
 	ext, ok := Catalog[name]
-	if !ok || ext.ZipURL == "" {
+	if !ok {
 		return ""
 	}
-	url := strings.ReplaceAll(ext.ZipURL, "{v}", version)
-	url = strings.ReplaceAll(url, "{arch}", arch)
-	return url
+	d, _ := resolveDownload(ext.ZipURL, ext.SHA256, ext.Zips, version, arch)
+	return d.URL
 }
 
 // GetZipURLs returns all .zip URLs needed for the given extensions.
 func GetZipURLs(names []string, version, arch string) []string {
-	var urls []string
-	seen := make(map[string]bool)
-	for _, name := range names {
-		url := GetZipURL(name, version, arch)
-		if url != "" && !seen[url] {
-			urls = append(urls, url)
-			seen[url] = true
-		}
-	}
-	return urls
+	return urlsOf(names, func(name string) string { return GetZipURL(name, version, arch) })
+}
+
+// GetZipDownloads returns the .zip downloads needed for the given extensions
+// on arch, with their checksums. It fails when an extension has no .zip for arch.
+func GetZipDownloads(names []string, version, arch string) ([]Download, error) {
+	return downloadsFor(names, version, arch, ".zip", func(ext Extension) (string, map[string]Artifact) {
+		return ext.ZipURL, ext.Zips
+	})
 }
 
 // NeedsZipPackages returns true if any of the given extensions require .zip downloads.
 func NeedsZipPackages(names []string) bool {
 	for _, name := range names {
-		if ext, ok := Catalog[name]; ok && ext.ZipURL != "" {
+		if HasZipURL(name) {
 			return true
 		}
 	}
@@ -465,7 +831,100 @@ func NeedsZipPackages(names []string) bool {
 // HasZipURL returns true if the extension uses .zip installation.
 func HasZipURL(name string) bool {
 	ext, ok := Catalog[name]
-	return ok && ext.ZipURL != ""
+	return ok && (ext.ZipURL != "" || len(ext.Zips) > 0)
+}
+
+// UnverifiedDownloads returns the extensions among names whose .deb or .zip
+// download for version and arch has no SHA-256 to verify it against.
+func UnverifiedDownloads(names []string, version, arch string) []string {
+	var unverified []string
+	for _, name := range names {
+		ext, ok := Catalog[name]
+		if !ok {
+			continue
+		}
+		for _, d := range []struct {
+			template  string
+			artifacts map[string]Artifact
+		}{{ext.DebURL, ext.Debs}, {ext.ZipURL, ext.Zips}} {
+			download, ok := resolveDownload(d.template, ext.SHA256, d.artifacts, version, arch)
+			if ok && download.URL != "" && download.SHA256 == "" {
+				unverified = append(unverified, name)
+				break
+			}
+		}
+	}
+	return unverified
+}
+
+// resolveDownload resolves a URL template, with its checksums by
+// ChecksumKey, or a per-arch artifact map. The second result is false when
+// the extension has artifacts, but none for arch.
+func resolveDownload(template string, checksums map[string]string, artifacts map[string]Artifact, version, arch string) (Download, bool) {
+	if len(artifacts) > 0 {
+		artifact, ok := artifacts[arch]
+		if !ok {
+			return Download{}, false
+		}
+		return Download{
+			URL:    strings.ReplaceAll(artifact.URL, "{v}", version),
+			SHA256: artifact.SHA256[version],
+		}, true
+	}
+	if template == "" {
+		return Download{}, true
+	}
+	url := strings.ReplaceAll(template, "{v}", version)
+	url = strings.ReplaceAll(url, "{arch}", arch)
+	return Download{URL: url, SHA256: checksums[ChecksumKey(version, arch)]}, true
+}
+
+// ChecksumKey is the key of Extension.SHA256 for a PostgreSQL major version
+// and architecture, e.g. "17/amd64".
+func ChecksumKey(version, arch string) string {
+	return version + "/" + arch
+}
+
+// downloadsFor resolves the downloads of one kind for the given extensions.
+func downloadsFor(names []string, version, arch, kind string, source func(Extension) (string, map[string]Artifact)) ([]Download, error) {
+	var downloads []Download
+	seen := make(map[string]bool)
+	for _, name := range names {
+		ext, ok := Catalog[name]
+		if !ok {
+			continue
+		}
+		template, artifacts := source(ext)
+		d, ok := resolveDownload(template, ext.SHA256, artifacts, version, arch)
+		if !ok {
+			available := make([]string, 0, len(artifacts))
+			for a := range artifacts {
+				available = append(available, a)
+			}
+			sort.Strings(available)
+			return nil, fmt.Errorf("extension %s has no %s package for %s (available for: %s)",
+				name, kind, arch, strings.Join(available, ", "))
+		}
+		if d.URL != "" && !seen[d.URL] {
+			downloads = append(downloads, d)
+			seen[d.URL] = true
+		}
+	}
+	return downloads, nil
+}
+
+// urlsOf collects the distinct non-empty URLs returned by url for names.
+func urlsOf(names []string, url func(string) string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		u := url(name)
+		if u != "" && !seen[u] {
+			urls = append(urls, u)
+			seen[u] = true
+		}
+	}
+	return urls
 }
 
 // GetBaseImage returns the required base image for extensions.
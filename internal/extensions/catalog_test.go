@@ -47,6 +47,9 @@ func TestGetPackage(t *testing.T) {
 		{"third-party v16", "hypopg", "16", "postgresql-16-hypopg"},
 		{"pgvector v17", "pgvector", "17", "postgresql-17-pgvector"},
 		{"pg_cron v17", "pg_cron", "17", "postgresql-17-cron"},
+		{"third-party v18", "hypopg", "18", "postgresql-18-hypopg"},
+		{"pgvector v18", "pgvector", "18", "postgresql-18-pgvector"},
+		{"pg_cron v18", "pg_cron", "18", "postgresql-18-cron"},
 	}
 
 	for _, tt := range tests {
@@ -79,6 +82,14 @@ func TestGetInitSQL(t *testing.T) {
 	assert.Contains(t, sql, "GRANT USAGE ON SCHEMA cron")
 }
 
+func TestGetCreatedSQLNames(t *testing.T) {
+	names := GetCreatedSQLNames([]string{"pgvector", "pg_cron", "postgis-3", "wal2json"})
+	assert.Equal(t, []string{"vector", "pg_cron", "postgis"}, names)
+
+	assert.Empty(t, GetCreatedSQLNames([]string{"wal2json"}))
+	assert.Empty(t, GetCreatedSQLNames([]string{"nonexistent"}))
+}
+
 func TestValidateExtensions(t *testing.T) {
 	// Valid extensions
 	err := ValidateExtensions([]string{"hstore", "pgvector", "pg_cron"})
@@ -90,6 +101,112 @@ func TestValidateExtensions(t *testing.T) {
 	assert.Contains(t, err.Error(), "nonexistent")
 }
 
+func TestValidateVersion(t *testing.T) {
+	assert.NoError(t, ValidateVersion([]string{"hstore", "pgvector", "pg_textsearch"}, "18"))
+	assert.NoError(t, ValidateVersion([]string{"adminpack"}, "16"))
+	assert.NoError(t, ValidateVersion([]string{"pg_textsearch"}, "19"), "a range keeps up with new releases")
+
+	err := ValidateVersion([]string{"adminpack", "pg_textsearch"}, "17")
+	assert.EqualError(t, err, "adminpack requires PostgreSQL 16 or older, requested 17")
+
+	err = ValidateVersion([]string{"pg_textsearch"}, "16")
+	assert.EqualError(t, err, "pg_textsearch requires PostgreSQL 17+, requested 16")
+}
+
+func TestSupportsVersion(t *testing.T) {
+	ranged := Extension{MinPG: "16", MaxPG: "17"}
+	assert.False(t, ranged.SupportsVersion("15"))
+	assert.True(t, ranged.SupportsVersion("16"))
+	assert.True(t, ranged.SupportsVersion("17"))
+	assert.False(t, ranged.SupportsVersion("18"))
+	assert.Equal(t, "16 to 17", ranged.VersionRequirement())
+
+	listed := Extension{Versions: []string{"16", "18"}, MinPG: "17"}
+	assert.False(t, listed.SupportsVersion("16"), "both the list and the range apply")
+	assert.True(t, listed.SupportsVersion("18"))
+	assert.True(t, Extension{}.SupportsVersion("18"))
+}
+
+func TestVersionSubstitution_PG18(t *testing.T) {
+	debURLs := GetDebURLs([]string{"pg_search"}, "18", "arm64")
+	assert.Equal(t, []string{"https://github.com/paradedb/paradedb/releases/download/v0.20.5/postgresql-18-pg-search_0.20.5-1PARADEDB-bookworm_arm64.deb"}, debURLs)
+
+	zipURLs := GetZipURLs([]string{"pg_textsearch"}, "18", "amd64")
+	assert.Equal(t, []string{"https://github.com/timescale/pg_textsearch/releases/download/v0.1.0/pg-textsearch-v0.1.0-pg18-amd64.zip"}, zipURLs)
+
+	assert.Equal(t, "postgres:18-bookworm", GetBaseImage([]string{"pg_search"}, "18"))
+}
+
+// withPerArchExtension temporarily adds an extension whose .deb names differ by architecture.
+func withPerArchExtension(t *testing.T) {
+	t.Helper()
+	Catalog["test_per_arch"] = Extension{Debs: map[string]Artifact{
+		"amd64": {URL: "https://example.com/pg{v}-ext-x86_64.deb", SHA256: map[string]string{"17": "abc123"}},
+		"arm64": {URL: "https://example.com/pg{v}-ext-aarch64.deb"},
+	}}
+	t.Cleanup(func() { delete(Catalog, "test_per_arch") })
+}
+
+func TestGetDebDownloads_PerArch(t *testing.T) {
+	withPerArchExtension(t)
+
+	downloads, err := GetDebDownloads([]string{"test_per_arch", "pg_search"}, "17", "amd64")
+	assert.NoError(t, err)
+	assert.Equal(t, []Download{
+		{URL: "https://example.com/pg17-ext-x86_64.deb", SHA256: "abc123"},
+		{URL: "https://github.com/paradedb/paradedb/releases/download/v0.20.5/postgresql-17-pg-search_0.20.5-1PARADEDB-bookworm_amd64.deb"},
+	}, downloads)
+
+	downloads, err = GetDebDownloads([]string{"test_per_arch"}, "18", "arm64")
+	assert.NoError(t, err)
+	assert.Equal(t, []Download{{URL: "https://example.com/pg18-ext-aarch64.deb"}}, downloads)
+
+	assert.True(t, HasDebURL("test_per_arch"))
+	assert.Equal(t, "https://example.com/pg18-ext-aarch64.deb", GetDebURL("test_per_arch", "18", "arm64"))
+}
+
+func TestGetDebDownloads_MissingArch(t *testing.T) {
+	withPerArchExtension(t)
+
+	_, err := GetDebDownloads([]string{"test_per_arch"}, "17", "riscv64")
+	assert.EqualError(t, err, "extension test_per_arch has no .deb package for riscv64 (available for: amd64, arm64)")
+	assert.Empty(t, GetDebURL("test_per_arch", "17", "riscv64"))
+}
+
+func TestGetZipDownloads_TemplateChecksums(t *testing.T) {
+	Catalog["test_zip"] = Extension{
+		ZipURL: "https://example.com/pg{v}-ext-{arch}.zip",
+		SHA256: map[string]string{"17/amd64": "abc123"},
+	}
+	t.Cleanup(func() { delete(Catalog, "test_zip") })
+
+	downloads, err := GetZipDownloads([]string{"test_zip"}, "17", "amd64")
+	assert.NoError(t, err)
+	assert.Equal(t, []Download{{URL: "https://example.com/pg17-ext-amd64.zip", SHA256: "abc123"}}, downloads)
+
+	downloads, err = GetZipDownloads([]string{"test_zip"}, "17", "arm64")
+	assert.NoError(t, err)
+	assert.Equal(t, []Download{{URL: "https://example.com/pg17-ext-arm64.zip"}}, downloads, "no checksum recorded for arm64")
+}
+
+func TestUnverifiedDownloads(t *testing.T) {
+	withPerArchExtension(t)
+
+	assert.Empty(t, UnverifiedDownloads([]string{"test_per_arch", "hstore"}, "17", "amd64"))
+	assert.Equal(t, []string{"test_per_arch"}, UnverifiedDownloads([]string{"test_per_arch", "hstore"}, "17", "arm64"))
+	assert.Equal(t, []string{"test_per_arch"}, UnverifiedDownloads([]string{"test_per_arch"}, "18", "amd64"), "checksums are per version")
+}
+
+func TestRecordedChecksums(t *testing.T) {
+	for name, checksums := range recordedChecksums {
+		ext, ok := Catalog[name]
+		if assert.True(t, ok, "checksums recorded for %s, which is not in the catalog", name) {
+			assert.True(t, ext.DebURL != "" || ext.ZipURL != "", "checksums recorded for %s, which has no deb_url or zip_url", name)
+			assert.Equal(t, checksums, ext.SHA256)
+		}
+	}
+}
+
 func TestListExtensions(t *testing.T) {
 	list := ListExtensions()
 	assert.Greater(t, len(list), 100) // Should have 150+ extensions
@@ -112,6 +229,16 @@ func TestGetPackages(t *testing.T) {
 	assert.Contains(t, packages, "postgresql-17-hypopg")
 }
 
+func TestGetApkPackages(t *testing.T) {
+	restoreCatalog(t)
+	Catalog["test_apk"] = Extension{Package: "postgresql-{v}-test", ApkPackage: "postgresql{v}-test"}
+
+	assert.Equal(t, []string{"postgresql17-test"}, GetApkPackages([]string{"test_apk", "hstore", "test_apk"}, "17"))
+	assert.Empty(t, MissingApkPackages([]string{"test_apk", "hstore"}))
+	assert.Equal(t, []string{"pgvector", "pg_search"}, MissingApkPackages([]string{"pgvector", "test_apk", "pg_search"}))
+	assert.True(t, NeedsRebuild("test_apk"))
+}
+
 func TestGetPreloadLibraries(t *testing.T) {
 	// No preload needed
 	libs := GetPreloadLibraries([]string{"hstore", "pgvector"})
@@ -142,6 +269,67 @@ func TestGetGUCs(t *testing.T) {
 	assert.Equal(t, "logical", gucs["wal_level"])
 }
 
+// withConflictingExtension temporarily adds an extension whose wal_level disagrees with wal2json.
+func withConflictingExtension(t *testing.T) {
+	t.Helper()
+	Catalog["test_replica"] = Extension{GUCs: map[string]string{"wal_level": "replica", "max_wal_senders": "10"}}
+	t.Cleanup(func() { delete(Catalog, "test_replica") })
+}
+
+func TestResolveGUCs_ReportsAllConflicts(t *testing.T) {
+	withConflictingExtension(t)
+
+	_, err := ResolveGUCs([]string{"wal2json", "test_replica"}, nil, nil)
+
+	var conflictErr *ConflictError
+	assert.ErrorAs(t, err, &conflictErr)
+	assert.Len(t, conflictErr.Conflicts, 1)
+	c := conflictErr.Conflicts[0]
+	assert.Equal(t, "wal_level", c.Key)
+	assert.Equal(t, []string{"wal2json", "test_replica"}, c.Extensions)
+	assert.Equal(t, "logical", c.Values["wal2json"])
+	assert.Equal(t, "replica", c.Values["test_replica"])
+	assert.Contains(t, err.Error(), "wal2json sets 'logical'")
+	assert.Contains(t, err.Error(), "--prefer")
+}
+
+func TestResolveGUCs_PreferExtension(t *testing.T) {
+	withConflictingExtension(t)
+
+	gucs, err := ResolveGUCs([]string{"wal2json", "test_replica"}, []string{"test_replica"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "replica", gucs["wal_level"])
+	assert.Equal(t, "10", gucs["max_wal_senders"])
+}
+
+func TestResolveGUCs_PreferPerKeyWins(t *testing.T) {
+	withConflictingExtension(t)
+
+	gucs, err := ResolveGUCs([]string{"wal2json", "test_replica"}, []string{"test_replica", "wal_level=wal2json"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "logical", gucs["wal_level"])
+}
+
+func TestResolveGUCs_UserSetKeyDoesNotConflict(t *testing.T) {
+	withConflictingExtension(t)
+
+	gucs, err := ResolveGUCs([]string{"wal2json", "test_replica"}, nil, []string{"wal_level", "max_wal_senders"})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, gucs, "wal_level", "the user's value is applied instead")
+	assert.Equal(t, "10", gucs["max_wal_senders"], "keys the extensions agree on are kept")
+}
+
+func TestGetGUCProvider(t *testing.T) {
+	withConflictingExtension(t)
+
+	assert.Equal(t, "wal2json", GetGUCProvider([]string{"wal2json", "test_replica"}, "wal_level", "logical"))
+	assert.Equal(t, "test_replica", GetGUCProvider([]string{"wal2json", "test_replica"}, "wal_level", "replica"))
+	assert.Equal(t, "", GetGUCProvider([]string{"hstore"}, "wal_level", "logical"))
+}
+
 func TestNeedsPackages(t *testing.T) {
 	assert.False(t, NeedsPackages([]string{"hstore", "ltree"}))
 	assert.True(t, NeedsPackages([]string{"hstore", "pgvector"}))
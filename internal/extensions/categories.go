@@ -0,0 +1,194 @@
+package extensions
+
+// Extension categories, for pgbox list-extensions --category.
+const (
+	CategoryAdmin       = "admin"       // Maintenance, scheduling and repair
+	CategoryAnalytics   = "analytics"   // Aggregates, time series and reporting
+	CategoryFDW         = "fdw"         // Foreign-data wrappers and remote queries
+	CategoryGIS         = "gis"         // Geospatial types and routing
+	CategoryIndex       = "index"       // Index access methods and index tuning
+	CategoryLanguage    = "language"    // Procedural languages
+	CategoryMonitoring  = "monitoring"  // Statistics and inspection of the server
+	CategoryReplication = "replication" // Replication, logical decoding and queues
+	CategorySearch      = "search"      // Full-text, fuzzy and vector search
+	CategorySecurity    = "security"    // Auditing, access control and cryptography
+	CategoryTesting     = "testing"     // Testing, debugging and profiling
+	CategoryTypes       = "types"       // Data types
+	CategoryUtil        = "util"        // Functions, triggers and compatibility helpers
+)
+
+// Categories lists the extension categories, sorted.
+var Categories = []string{
+	CategoryAdmin, CategoryAnalytics, CategoryFDW, CategoryGIS, CategoryIndex, CategoryLanguage, CategoryMonitoring,
+	CategoryReplication, CategorySearch, CategorySecurity, CategoryTesting, CategoryTypes, CategoryUtil,
+}
+
+// categories holds the category of each built-in catalog entry. Custom specs
+// set Extension.Category instead.
+var categories = map[string]string{
+	// Built-in contrib extensions
+	"adminpack":          CategoryAdmin,
+	"amcheck":            CategoryAdmin,
+	"autoinc":            CategoryUtil,
+	"bloom":              CategoryIndex,
+	"btree_gin":          CategoryIndex,
+	"btree_gist":         CategoryIndex,
+	"citext":             CategoryTypes,
+	"cube":               CategoryTypes,
+	"dblink":             CategoryFDW,
+	"dict_int":           CategorySearch,
+	"dict_xsyn":          CategorySearch,
+	"earthdistance":      CategoryGIS,
+	"file_fdw":           CategoryFDW,
+	"fuzzystrmatch":      CategorySearch,
+	"hstore":             CategoryTypes,
+	"insert_username":    CategoryUtil,
+	"intagg":             CategoryAnalytics,
+	"intarray":           CategoryTypes,
+	"isn":                CategoryTypes,
+	"lo":                 CategoryAdmin,
+	"ltree":              CategoryTypes,
+	"moddatetime":        CategoryUtil,
+	"old_snapshot":       CategoryMonitoring,
+	"pageinspect":        CategoryMonitoring,
+	"pg_buffercache":     CategoryMonitoring,
+	"pg_freespacemap":    CategoryMonitoring,
+	"pg_prewarm":         CategoryAdmin,
+	"pg_stat_statements": "monitoring",
+	"pg_surgery":         CategoryAdmin,
+	"pg_trgm":            CategorySearch,
+	"pg_visibility":      CategoryMonitoring,
+	"pg_walinspect":      CategoryMonitoring,
+	"pgcrypto":           CategorySecurity,
+	"pgrowlocks":         CategoryMonitoring,
+	"pgstattuple":        CategoryMonitoring,
+	"plpgsql":            CategoryLanguage,
+	"postgres_fdw":       CategoryFDW,
+	"refint":             CategoryUtil,
+	"seg":                CategoryTypes,
+	"sslinfo":            CategorySecurity,
+	"tablefunc":          CategoryAnalytics,
+	"tcn":                CategoryUtil,
+	"tsm_system_rows":    CategoryUtil,
+	"tsm_system_time":    CategoryUtil,
+	"unaccent":           CategorySearch,
+	"uuid-ossp":          CategoryUtil,
+	"xml2":               CategoryUtil,
+
+	// Third-party extensions
+	"age":                    CategoryAnalytics,
+	"asn1oid":                CategoryTypes,
+	"auto-failover":          CategoryReplication,
+	"bgw-replstatus":         CategoryReplication,
+	"credcheck":              CategorySecurity,
+	"debversion":             CategoryTypes,
+	"decoderbufs":            CategoryReplication,
+	"dirtyread":              CategoryAdmin,
+	"extra-window-functions": "analytics",
+	"first-last-agg":         CategoryAnalytics,
+	"h3":                     CategoryGIS,
+	"hll":                    CategoryAnalytics,
+	"http":                   CategoryUtil,
+	"hypopg":                 CategoryIndex,
+	"icu-ext":                CategoryUtil,
+	"ip4r":                   CategoryTypes,
+	"jsquery":                CategoryIndex,
+	"londiste-sql":           CategoryReplication,
+	"mimeo":                  CategoryReplication,
+	"mobilitydb":             CategoryGIS,
+	"mysql-fdw":              CategoryFDW,
+	"numeral":                CategoryUtil,
+	"ogr-fdw":                CategoryFDW,
+	"omnidb":                 CategoryTesting,
+	"oracle-fdw":             CategoryFDW,
+	"orafce":                 CategoryUtil,
+	"partman":                CategoryAdmin,
+	"periods":                CategoryUtil,
+	"pg-catcheck":            CategoryAdmin,
+	"pg-checksums":           CategoryAdmin,
+	"pg-crash":               CategoryTesting,
+	"pg-fact-loader":         CategoryAnalytics,
+	"pg-failover-slots":      CategoryReplication,
+	"pg-gvm":                 CategoryUtil,
+	"pg-hint-plan":           CategoryAdmin,
+	"pg-permissions":         CategorySecurity,
+	"pg-qualstats":           CategoryMonitoring,
+	"pg-rewrite":             CategoryAdmin,
+	"pg-rrule":               CategoryUtil,
+	"pg-stat-kcache":         CategoryMonitoring,
+	"pg-track-settings":      CategoryMonitoring,
+	"pg-wait-sampling":       CategoryMonitoring,
+	"pgaudit":                CategorySecurity,
+	"pgauditlogtofile":       CategorySecurity,
+	"pgextwlist":             CategorySecurity,
+	"pgfaceting":             CategorySearch,
+	"pgfincore":              CategoryAdmin,
+	"pgl-ddl-deploy":         CategoryReplication,
+	"pglogical":              CategoryReplication,
+	"pglogical-ticker":       CategoryReplication,
+	"pgmemcache":             CategoryUtil,
+	"pgmp":                   CategoryTypes,
+	"pgnodemx":               CategoryMonitoring,
+	"pgpcre":                 CategoryUtil,
+	"pgpool2":                CategoryReplication,
+	"pgq-node":               CategoryReplication,
+	"pgq3":                   CategoryReplication,
+	"pgrouting":              CategoryGIS,
+	"pgrouting-doc":          CategoryGIS,
+	"pgrouting-scripts":      CategoryGIS,
+	"pgsentinel":             CategoryMonitoring,
+	"pgsphere":               CategoryGIS,
+	"pgtap":                  CategoryTesting,
+	"pgtt":                   CategoryUtil,
+	"pldebugger":             CategoryTesting,
+	"pljava":                 CategoryLanguage,
+	"pljs":                   CategoryLanguage,
+	"pllua":                  CategoryLanguage,
+	"plpgsql-check":          CategoryTesting,
+	"plprofiler":             CategoryTesting,
+	"plproxy":                CategoryLanguage,
+	"plr":                    CategoryLanguage,
+	"plsh":                   CategoryLanguage,
+	"pointcloud":             CategoryGIS,
+	"postgis-3":              CategoryGIS,
+	"postgis-3-scripts":      CategoryGIS,
+	"powa":                   CategoryMonitoring,
+	"prefix":                 CategoryTypes,
+	"preprepare":             CategoryUtil,
+	"prioritize":             CategoryUtil,
+	"q3c":                    CategoryGIS,
+	"rational":               CategoryTypes,
+	"rdkit":                  CategoryTypes,
+	"repack":                 CategoryAdmin,
+	"repmgr":                 CategoryReplication,
+	"roaringbitmap":          CategoryTypes,
+	"rum":                    CategorySearch,
+	"semver":                 CategoryTypes,
+	"set-user":               CategorySecurity,
+	"show-plans":             CategoryMonitoring,
+	"similarity":             CategorySearch,
+	"slony1-2":               CategoryReplication,
+	"snakeoil":               CategorySecurity,
+	"squeeze":                CategoryAdmin,
+	"statviz":                CategoryMonitoring,
+	"tablelog":               CategoryUtil,
+	"tdigest":                CategoryAnalytics,
+	"tds-fdw":                CategoryFDW,
+	"timescaledb":            CategoryAnalytics,
+	"toastinfo":              CategoryMonitoring,
+	"unit":                   CategoryTypes,
+	"pgvector":               CategorySearch,
+	"pg_cron":                CategoryAdmin,
+	"wal2json":               CategoryReplication,
+	"pg_search":              CategorySearch,
+	"pg_textsearch":          CategorySearch,
+}
+
+// Category returns an extension's category: the category of a custom spec,
+// otherwise the built-in one. Empty when there is none.
+func Category(name string) string {
+	if ext, ok := Catalog[name]; ok && ext.Category != "" {
+		return ext.Category
+	}
+	return categories[name]
+}
@@ -0,0 +1,155 @@
+package extensions
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ExtDirEnvVar names the environment variable that points at a directory of
+// custom extension specs, like the --ext-dir flag.
+const ExtDirEnvVar = "PGBOX_EXT_DIR"
+
+// specNamePattern matches the extension names a spec file may define.
+var specNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// sha256Pattern matches a hex SHA-256 checksum.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// LoadDir merges the extension specs in dir over the catalog. Each *.toml
+// file defines one extension named after the file, using the same fields as
+// the built-in entries (package, deb_url, preload, gucs, init_sql,
+// [[script.initdb]], ...). A spec with the name of a built-in extension
+// replaces it. Returns the names loaded, sorted.
+func LoadDir(dir string) ([]string, error) {
+	loaded, problems, err := CheckDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Merge only once every file parsed, so a bad spec changes nothing
+	if len(problems) > 0 {
+		paths := slices.Sorted(maps.Keys(problems))
+		return nil, problems[paths[0]]
+	}
+
+	names := make([]string, 0, len(loaded))
+	for name, ext := range loaded {
+		Catalog[name] = ext
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CheckDir reads the extension specs in dir without merging them into the
+// catalog. It returns the valid specs by name and the problem with each
+// invalid file by path; err is set when dir cannot be read.
+func CheckDir(dir string) (loaded map[string]Extension, problems map[string]error, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, nil, fmt.Errorf("extension directory: %w", err)
+		}
+	}
+
+	loaded = make(map[string]Extension, len(paths))
+	problems = make(map[string]error)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".toml")
+		if !specNamePattern.MatchString(name) {
+			problems[path] = fmt.Errorf("%s: extension names may only contain letters, digits, _ and -", path)
+			continue
+		}
+		ext, err := loadSpec(path)
+		if err != nil {
+			problems[path] = err
+			continue
+		}
+		loaded[name] = ext
+	}
+	return loaded, problems, nil
+}
+
+// loadSpec reads one extension spec file.
+func loadSpec(path string) (Extension, error) {
+	var ext Extension
+	meta, err := toml.DecodeFile(path, &ext)
+	if err != nil {
+		return Extension{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		unknown := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			unknown[i] = key.String()
+		}
+		return Extension{}, fmt.Errorf("%s: unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	if ext.Category != "" && !slices.Contains(Categories, ext.Category) {
+		return Extension{}, fmt.Errorf("%s: category %q is not one of: %s", path, ext.Category, strings.Join(Categories, ", "))
+	}
+	bounds := make(map[string]int)
+	for key, bound := range map[string]string{"min_pg": ext.MinPG, "max_pg": ext.MaxPG} {
+		if bound == "" {
+			continue
+		}
+		n, err := strconv.Atoi(bound)
+		if err != nil {
+			return Extension{}, fmt.Errorf("%s: %s: %q is not a PostgreSQL major version", path, key, bound)
+		}
+		bounds[key] = n
+	}
+	if ext.MinPG != "" && ext.MaxPG != "" && bounds["min_pg"] > bounds["max_pg"] {
+		return Extension{}, fmt.Errorf("%s: min_pg %s is newer than max_pg %s", path, ext.MinPG, ext.MaxPG)
+	}
+	for arch := range ext.Debs {
+		if arch != "amd64" && arch != "arm64" {
+			return Extension{}, fmt.Errorf("%s: debs.%s: architecture must be amd64 or arm64", path, arch)
+		}
+	}
+	for arch := range ext.Zips {
+		if arch != "amd64" && arch != "arm64" {
+			return Extension{}, fmt.Errorf("%s: zips.%s: architecture must be amd64 or arm64", path, arch)
+		}
+	}
+	if len(ext.SHA256) > 0 && ext.DebURL == "" && ext.ZipURL == "" {
+		return Extension{}, fmt.Errorf("%s: sha256 needs deb_url or zip_url (use the sha256 of debs/zips entries instead)", path)
+	}
+	for key, sum := range ext.SHA256 {
+		version, arch, _ := strings.Cut(key, "/")
+		if version == "" || (arch != "amd64" && arch != "arm64") {
+			return Extension{}, fmt.Errorf("%s: sha256.%q: key must be <version>/<arch>, e.g. \"17/amd64\"", path, key)
+		}
+		if !sha256Pattern.MatchString(sum) {
+			return Extension{}, fmt.Errorf("%s: sha256.%q: not a hex SHA-256", path, key)
+		}
+	}
+	names := make(map[string]bool)
+	for i, script := range ext.Script.Initdb {
+		if strings.TrimSpace(script.Run) == "" {
+			return Extension{}, fmt.Errorf("%s: script.initdb[%d]: run is empty", path, i)
+		}
+		if script.Name == "" {
+			continue
+		}
+		if !specNamePattern.MatchString(script.Name) {
+			return Extension{}, fmt.Errorf("%s: script.initdb[%d]: names may only contain letters, digits, _ and -", path, i)
+		}
+		if names[script.Name] {
+			return Extension{}, fmt.Errorf("%s: script.initdb[%d]: name %q is used twice", path, i, script.Name)
+		}
+		names[script.Name] = true
+	}
+	ext.File = path
+	return ext, nil
+}
@@ -0,0 +1,212 @@
+package extensions
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// restoreCatalog undoes the changes a test makes to the catalog.
+func restoreCatalog(t *testing.T) {
+	saved := maps.Clone(Catalog)
+	t.Cleanup(func() { Catalog = saved })
+}
+
+func writeSpec(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestLoadDir(t *testing.T) {
+	restoreCatalog(t)
+	dir := t.TempDir()
+	writeSpec(t, dir, "acme_audit.toml", `
+deb_url = "https://artifacts.example.com/acme-audit/pg{v}_{arch}.deb"
+preload = ["acme_audit"]
+init_sql = "CREATE EXTENSION IF NOT EXISTS acme_audit;"
+versions = ["17"]
+category = "security"
+
+[sha256]
+"17/arm64" = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+[gucs]
+"acme_audit.level" = "ddl"
+`)
+	writeSpec(t, dir, "hstore.toml", `sql_name = "hstore"
+tips = ["Our hstore"]
+`)
+	writeSpec(t, dir, "README.md", "not a spec")
+
+	names, err := LoadDir(dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme_audit", "hstore"}, names)
+	ext, ok := Get("acme_audit")
+	require.True(t, ok)
+	assert.Equal(t, []string{"acme_audit"}, ext.Preload)
+	assert.Equal(t, map[string]string{"acme_audit.level": "ddl"}, ext.GUCs)
+	assert.Equal(t, filepath.Join(dir, "acme_audit.toml"), ext.File)
+	assert.Equal(t, CategorySecurity, Category("acme_audit"))
+	assert.Equal(t, CategoryTypes, Category("hstore"), "a spec without a category keeps the built-in one")
+	assert.Equal(t, "https://artifacts.example.com/acme-audit/pg17_arm64.deb", GetDebURL("acme_audit", "17", "arm64"))
+	downloads, err := GetDebDownloads([]string{"acme_audit"}, "17", "arm64")
+	require.NoError(t, err)
+	assert.Equal(t, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", downloads[0].SHA256)
+	assert.True(t, NeedsRebuild("acme_audit"))
+	assert.Equal(t, []string{"Our hstore"}, Catalog["hstore"].Tips, "specs replace built-in entries")
+}
+
+func TestCheckDir_ReportsEveryInvalidSpec(t *testing.T) {
+	restoreCatalog(t)
+	dir := t.TempDir()
+	writeSpec(t, dir, "good.toml", `package = "postgresql-{version}-good"`)
+	writeSpec(t, dir, "typo.toml", `pakage = "postgresql-{version}-typo"`)
+	writeSpec(t, dir, "broken.toml", "package =\n")
+
+	loaded, problems, err := CheckDir(dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"good"}, slices.Collect(maps.Keys(loaded)))
+	require.Len(t, problems, 2)
+	assert.ErrorContains(t, problems[filepath.Join(dir, "typo.toml")], "unknown keys: pakage")
+	assert.ErrorContains(t, problems[filepath.Join(dir, "broken.toml")], "failed to parse")
+	_, merged := Catalog["good"]
+	assert.False(t, merged, "CheckDir does not change the catalog")
+}
+
+func TestLoadDir_InitdbScripts(t *testing.T) {
+	restoreCatalog(t)
+	dir := t.TempDir()
+	writeSpec(t, dir, "acme_store.toml", `
+init_sql = "CREATE EXTENSION IF NOT EXISTS acme_store;"
+
+[[script.initdb]]
+name = "dirs"
+run = """
+mkdir -p "$PGDATA/acme_store"
+chmod 700 "$PGDATA/acme_store"
+"""
+
+[[script.initdb]]
+run = "echo ready"
+`)
+
+	_, err := LoadDir(dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, []InitdbScript{
+		{Name: "acme_store-dirs", Run: "mkdir -p \"$PGDATA/acme_store\"\nchmod 700 \"$PGDATA/acme_store\"\n"},
+		{Name: "acme_store-2", Run: "echo ready"},
+	}, GetInitdbScripts("acme_store"))
+	assert.Empty(t, GetInitdbScripts("hstore"))
+}
+
+func TestLoadDir_Errors(t *testing.T) {
+	restoreCatalog(t)
+
+	t.Run("unknown key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "ok.toml", "")
+		writeSpec(t, dir, "bad.toml", "preload_libraries = [\"x\"]\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, "unknown keys: preload_libraries")
+		_, ok := Get("ok")
+		assert.False(t, ok, "nothing is merged when a spec is invalid")
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "category = \"misc\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, `category "misc" is not one of: admin, analytics`)
+	})
+
+	t.Run("bad version bound", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "min_pg = \"17.2\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, `min_pg: "17.2" is not a PostgreSQL major version`)
+	})
+
+	t.Run("empty version range", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "min_pg = \"18\"\nmax_pg = \"16\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, "min_pg 18 is newer than max_pg 16")
+	})
+
+	t.Run("bad architecture", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "[debs.x86_64]\nurl = \"https://example.com/x.deb\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, "architecture must be amd64 or arm64")
+	})
+
+	t.Run("bad checksum key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "deb_url = \"https://example.com/pg{v}_{arch}.deb\"\n[sha256]\n\"17\" = \""+strings.Repeat("a", 64)+"\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, `sha256."17": key must be <version>/<arch>`)
+	})
+
+	t.Run("bad checksum", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "deb_url = \"https://example.com/pg{v}_{arch}.deb\"\n[sha256]\n\"17/amd64\" = \"abc\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, `sha256."17/amd64": not a hex SHA-256`)
+	})
+
+	t.Run("checksum without download", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "package = \"postgresql-{v}-x\"\n[sha256]\n\"17/amd64\" = \""+strings.Repeat("a", 64)+"\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, "sha256 needs deb_url or zip_url")
+	})
+
+	t.Run("empty initdb script", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "[[script.initdb]]\nname = \"dirs\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, "script.initdb[0]: run is empty")
+	})
+
+	t.Run("initdb script name used twice", func(t *testing.T) {
+		dir := t.TempDir()
+		writeSpec(t, dir, "x.toml", "[[script.initdb]]\nname = \"dirs\"\nrun = \"true\"\n[[script.initdb]]\nname = \"dirs\"\nrun = \"true\"\n")
+
+		_, err := LoadDir(dir)
+
+		assert.ErrorContains(t, err, `script.initdb[1]: name "dirs" is used twice`)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := LoadDir(filepath.Join(t.TempDir(), "nope"))
+
+		assert.ErrorContains(t, err, "extension directory")
+	})
+}
@@ -0,0 +1,186 @@
+package extensions
+
+// descriptions holds one-line summaries of the built-in catalog entries.
+// Custom specs set Extension.Description instead.
+var descriptions = map[string]string{
+	// Built-in contrib extensions
+	"adminpack":          "Server-side file management functions used by pgAdmin",
+	"amcheck":            "Verify the integrity of B-tree indexes and heap relations",
+	"autoinc":            "Trigger that fills a column from a sequence",
+	"bloom":              "Bloom filter index access method",
+	"btree_gin":          "GIN operator classes with B-tree behavior for common types",
+	"btree_gist":         "GiST operator classes with B-tree behavior, e.g. for exclusion constraints",
+	"citext":             "Case-insensitive text type",
+	"cube":               "Multidimensional cube type",
+	"dblink":             "Query other PostgreSQL databases from within a session",
+	"dict_int":           "Text search dictionary template for integers",
+	"dict_xsyn":          "Text search dictionary template for extended synonyms",
+	"earthdistance":      "Great-circle distances on the surface of the Earth",
+	"file_fdw":           "Foreign-data wrapper for files on the server, such as CSV",
+	"fuzzystrmatch":      "Soundex, Metaphone and Levenshtein string matching",
+	"hstore":             "Key/value pairs in a single value",
+	"insert_username":    "Trigger that records the user who changed a row",
+	"intagg":             "Integer aggregator and enumerator (obsolete)",
+	"intarray":           "Functions, operators and index support for integer arrays",
+	"isn":                "Product numbering standard types such as ISBN, EAN and UPC",
+	"lo":                 "Large object maintenance",
+	"ltree":              "Hierarchical label paths for tree-like data",
+	"moddatetime":        "Trigger that records the last modification time",
+	"old_snapshot":       "Inspect the state of old_snapshot_threshold",
+	"pageinspect":        "Inspect the contents of database pages",
+	"pg_buffercache":     "Inspect the shared buffer cache",
+	"pg_freespacemap":    "Inspect the free space map",
+	"pg_prewarm":         "Load relation data into the buffer cache",
+	"pg_stat_statements": "Planning and execution statistics of SQL statements",
+	"pg_surgery":         "Repair damaged relations",
+	"pg_trgm":            "Trigram similarity and index support for LIKE and ILIKE",
+	"pg_visibility":      "Inspect the visibility map",
+	"pg_walinspect":      "Inspect the contents of the write-ahead log",
+	"pgcrypto":           "Cryptographic hashing and encryption functions",
+	"pgrowlocks":         "Show row-level locks of a table",
+	"pgstattuple":        "Tuple-level statistics such as table and index bloat",
+	"plpgsql":            "PL/pgSQL procedural language",
+	"postgres_fdw":       "Foreign-data wrapper for remote PostgreSQL servers",
+	"refint":             "Triggers implementing referential integrity",
+	"seg":                "Line segment and floating-point interval type",
+	"sslinfo":            "Information about the client's SSL certificate",
+	"tablefunc":          "Functions that return tables, including crosstab",
+	"tcn":                "Trigger that notifies listeners of table changes",
+	"tsm_system_rows":    "TABLESAMPLE method that takes a row count",
+	"tsm_system_time":    "TABLESAMPLE method that takes a time limit",
+	"unaccent":           "Text search dictionary that removes accents",
+	"uuid-ossp":          "UUID generation functions",
+	"xml2":               "XPath queries and XSLT (deprecated)",
+
+	// Third-party extensions
+	"age":                    "Apache AGE graph database with openCypher queries",
+	"asn1oid":                "ASN.1 object identifier type",
+	"auto-failover":          "pg_auto_failover automated failover and high availability",
+	"bgw-replstatus":         "Background worker that reports the replication role over TCP",
+	"credcheck":              "Username and password checks",
+	"debversion":             "Debian package version type",
+	"decoderbufs":            "Logical decoding output plugin emitting Protocol Buffers",
+	"dirtyread":              "Read dead but not yet vacuumed rows",
+	"extra-window-functions": "Additional window functions",
+	"first-last-agg":         "first() and last() aggregates",
+	"h3":                     "H3 hexagonal geospatial indexing",
+	"hll":                    "HyperLogLog type for approximate distinct counts",
+	"http":                   "HTTP client for calling web services from SQL",
+	"hypopg":                 "Hypothetical indexes for testing whether an index would help",
+	"icu-ext":                "Functions exposing ICU collation and locale support",
+	"ip4r":                   "IPv4 and IPv6 address and range types",
+	"jsquery":                "jsonb query language with index support",
+	"londiste-sql":           "SQL parts of the Londiste replication tool",
+	"mimeo":                  "Per-table replication between PostgreSQL instances",
+	"mobilitydb":             "Temporal and spatio-temporal types for moving objects",
+	"mysql-fdw":              "Foreign-data wrapper for MySQL",
+	"numeral":                "Numbers spelled out in words",
+	"ogr-fdw":                "Foreign-data wrapper for GDAL/OGR data sources",
+	"omnidb":                 "Debugger plugin for OmniDB",
+	"oracle-fdw":             "Foreign-data wrapper for Oracle",
+	"orafce":                 "Oracle compatibility functions and packages",
+	"partman":                "pg_partman time- and serial-based partition management",
+	"periods":                "SQL standard periods and system-versioned tables",
+	"pg-catcheck":            "Check the system catalogs for corruption",
+	"pg-checksums":           "Enable, disable and verify data checksums",
+	"pg-crash":               "Fault injection that periodically kills backends",
+	"pg-fact-loader":         "Build fact tables from queued changes",
+	"pg-failover-slots":      "Keep logical replication slots usable after failover",
+	"pg-gvm":                 "Helper functions for Greenbone Vulnerability Management",
+	"pg-hint-plan":           "Control execution plans with hints in SQL comments",
+	"pg-permissions":         "Views to review and compare object permissions",
+	"pg-qualstats":           "Statistics on predicates in WHERE and JOIN clauses",
+	"pg-rewrite":             "Rewrite a table, e.g. into a partitioned one, without blocking access",
+	"pg-rrule":               "iCalendar recurrence rules",
+	"pg-stat-kcache":         "Per-statement CPU and filesystem statistics",
+	"pg-track-settings":      "Track configuration changes over time",
+	"pg-wait-sampling":       "Sampling of wait events",
+	"pgaudit":                "Session and object audit logging",
+	"pgauditlogtofile":       "Write pgaudit logs to a separate file",
+	"pgextwlist":             "Let non-superusers create allow-listed extensions",
+	"pgfaceting":             "Faceted search counts using roaring bitmaps",
+	"pgfincore":              "Inspect and manage relation pages in the OS page cache",
+	"pgl-ddl-deploy":         "DDL replication for pglogical",
+	"pglogical":              "Logical replication provider and subscriber",
+	"pglogical-ticker":       "Replication delay monitoring for pglogical",
+	"pgmemcache":             "memcached client functions",
+	"pgmp":                   "Arbitrary precision integers and rationals using GMP",
+	"pgnodemx":               "Operating system metrics from SQL",
+	"pgpcre":                 "Perl-compatible regular expressions",
+	"pgpool2":                "Server-side functions for pgpool-II",
+	"pgq-node":               "Cascaded queue infrastructure for PgQ",
+	"pgq3":                   "PgQ generic queue",
+	"pgrouting":              "Geospatial routing on top of PostGIS",
+	"pgrouting-doc":          "pgRouting documentation",
+	"pgrouting-scripts":      "pgRouting upgrade scripts",
+	"pgsentinel":             "Active session history sampling",
+	"pgsphere":               "Spherical geometry types and operators",
+	"pgtap":                  "Unit testing framework for SQL and PL/pgSQL",
+	"pgtt":                   "Oracle-style global temporary tables",
+	"pldebugger":             "PL/pgSQL debugger used by pgAdmin",
+	"pljava":                 "Java procedural language",
+	"pljs":                   "JavaScript procedural language",
+	"pllua":                  "Lua procedural language",
+	"plpgsql-check":          "Static analysis of PL/pgSQL functions",
+	"plprofiler":             "Profiler for PL/pgSQL functions",
+	"plproxy":                "Procedural language for remote calls and sharding",
+	"plr":                    "R procedural language",
+	"plsh":                   "Shell procedural language",
+	"pointcloud":             "Storage of LIDAR point cloud data",
+	"postgis-3":              "PostGIS spatial types, functions and indexes",
+	"postgis-3-scripts":      "PostGIS install and upgrade scripts",
+	"powa":                   "PostgreSQL Workload Analyzer",
+	"prefix":                 "Prefix range type for longest-prefix matching",
+	"preprepare":             "Prepare statements automatically on connection",
+	"prioritize":             "Change the OS scheduling priority of backends",
+	"q3c":                    "Spherical indexing for astronomical catalogs",
+	"rational":               "Exact fractions, e.g. for user-defined ordering",
+	"rdkit":                  "Cheminformatics types and substructure search",
+	"repack":                 "pg_repack: remove bloat with minimal locking",
+	"repmgr":                 "Replication and failover management",
+	"roaringbitmap":          "Roaring bitmap type",
+	"rum":                    "RUM index access method for ranked full-text search",
+	"semver":                 "Semantic version type",
+	"set-user":               "Audited privilege escalation",
+	"show-plans":             "Show the plans of running queries",
+	"similarity":             "String similarity functions",
+	"slony1-2":               "Slony-I trigger-based replication",
+	"snakeoil":               "ClamAV virus scanning from SQL",
+	"squeeze":                "pg_squeeze: remove bloat using logical decoding",
+	"statviz":                "Snapshots and analysis of server statistics",
+	"tablelog":               "Log table changes to view past states",
+	"tdigest":                "t-digest for approximate percentiles",
+	"tds-fdw":                "Foreign-data wrapper for SQL Server and Sybase",
+	"timescaledb":            "Time-series hypertables and continuous aggregates",
+	"toastinfo":              "Show how values are stored in TOAST",
+	"unit":                   "SI units type",
+	"pgvector":               "Vector similarity search for embeddings",
+	"pg_cron":                "Cron-based job scheduler",
+	"wal2json":               "Logical decoding output plugin emitting JSON",
+	"pg_search":              "ParadeDB BM25 full-text search",
+	"pg_textsearch":          "BM25 ranked text search",
+}
+
+// Describe returns a one-line summary of an extension: the description of a
+// custom spec, otherwise the built-in one. Empty when there is none.
+func Describe(name string) string {
+	if ext, ok := Catalog[name]; ok && ext.Description != "" {
+		return ext.Description
+	}
+	return descriptions[name]
+}
+
+// CatalogName returns the catalog name of the extension whose CREATE
+// EXTENSION name is sqlName (e.g., "pgvector" for "vector"), or false when
+// no catalog entry creates it.
+func CatalogName(sqlName string) (string, bool) {
+	if _, ok := Catalog[sqlName]; ok {
+		return sqlName, true
+	}
+	for _, name := range ListExtensions() {
+		if GetSQLName(name) == sqlName {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,54 @@
+package extensions
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe_EveryCatalogEntry(t *testing.T) {
+	for _, name := range ListExtensions() {
+		assert.NotEmpty(t, Describe(name), "%s has no description", name)
+	}
+	for name := range descriptions {
+		_, ok := Catalog[name]
+		assert.True(t, ok, "description for %s, which is not in the catalog", name)
+	}
+}
+
+func TestCategory_EveryCatalogEntry(t *testing.T) {
+	for _, name := range ListExtensions() {
+		assert.Contains(t, Categories, Category(name), "%s has no category", name)
+	}
+	for name := range categories {
+		_, ok := Catalog[name]
+		assert.True(t, ok, "category for %s, which is not in the catalog", name)
+	}
+	assert.True(t, slices.IsSorted(Categories))
+}
+
+func TestDescribe_CustomSpecWins(t *testing.T) {
+	Catalog["acme_audit"] = Extension{Description: "Audit trail for Acme"}
+	t.Cleanup(func() { delete(Catalog, "acme_audit") })
+
+	assert.Equal(t, "Audit trail for Acme", Describe("acme_audit"))
+	assert.Equal(t, "", Describe("no_such_extension"))
+}
+
+func TestCatalogName(t *testing.T) {
+	name, ok := CatalogName("vector")
+	assert.True(t, ok)
+	assert.Equal(t, "pgvector", name)
+
+	name, ok = CatalogName("postgis")
+	assert.True(t, ok)
+	assert.Equal(t, "postgis-3", name)
+
+	name, ok = CatalogName("hstore")
+	assert.True(t, ok)
+	assert.Equal(t, "hstore", name)
+
+	_, ok = CatalogName("acme_audit")
+	assert.False(t, ok)
+}
@@ -0,0 +1,204 @@
+package extensions
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Parameter documents a PostgreSQL configuration parameter (GUC).
+type Parameter struct {
+	// Description is the short description from pg_settings.
+	Description string
+
+	// Context is when a change takes effect, as in pg_settings.context
+	// (e.g., "postmaster" needs a restart, "sighup" a reload, "user" is per session).
+	Context string
+
+	// Type is bool, integer, real, string or enum.
+	Type string
+
+	// Default is the built-in default, in Unit when Unit is set.
+	Default string
+
+	// VersionDefaults overrides Default for specific PostgreSQL major versions.
+	VersionDefaults map[string]string
+
+	// Unit is the unit of integer values (e.g., "kB", "8kB", "ms").
+	Unit string
+
+	// Min and Max bound numeric values. Empty for non-numeric parameters.
+	Min, Max string
+
+	// Values lists the accepted values of an enum parameter.
+	Values []string
+
+	// Extension is the catalog extension that defines the parameter, if any.
+	Extension string
+
+	// Versions lists the PostgreSQL major versions that have the parameter.
+	// Empty means all supported versions.
+	Versions []string
+}
+
+// Parameters maps parameter name to its documentation. It covers the
+// parameters pgbox sets itself, through extensions or profiles, and the ones
+// most often tuned for local development.
+var Parameters = map[string]Parameter{
+	// ===== Write-ahead log and durability =====
+	"fsync": {
+		Description: "Forces synchronization of updates to disk.",
+		Context:     "sighup", Type: "bool", Default: "on",
+	},
+	"full_page_writes": {
+		Description: "Writes full pages to WAL when first modified after a checkpoint.",
+		Context:     "sighup", Type: "bool", Default: "on",
+	},
+	"synchronous_commit": {
+		Description: "Sets the current transaction's synchronization level.",
+		Context:     "user", Type: "enum", Default: "on",
+		Values: []string{"local", "remote_write", "remote_apply", "on", "off"},
+	},
+	"wal_level": {
+		Description: "Sets the level of information written to the WAL.",
+		Context:     "postmaster", Type: "enum", Default: "replica",
+		Values: []string{"minimal", "replica", "logical"},
+	},
+
+	// ===== Replication =====
+	"max_replication_slots": {
+		Description: "Sets the maximum number of simultaneously defined replication slots.",
+		Context:     "postmaster", Type: "integer", Default: "10", Min: "0", Max: "262143",
+	},
+	"max_wal_senders": {
+		Description: "Sets the maximum number of simultaneously running WAL sender processes.",
+		Context:     "postmaster", Type: "integer", Default: "10", Min: "0", Max: "262143",
+	},
+
+	// ===== Connections and memory =====
+	"listen_addresses": {
+		Description: "Sets the host name or IP address(es) to listen to.",
+		Context:     "postmaster", Type: "string", Default: "localhost",
+	},
+	"max_connections": {
+		Description: "Sets the maximum number of concurrent connections.",
+		Context:     "postmaster", Type: "integer", Default: "100", Min: "1", Max: "262143",
+	},
+	"shared_buffers": {
+		Description: "Sets the number of shared memory buffers used by the server.",
+		Context:     "postmaster", Type: "integer", Default: "16384", Unit: "8kB", Min: "16", Max: "1073741823",
+	},
+	"work_mem": {
+		Description: "Sets the maximum memory to be used for query workspaces.",
+		Context:     "user", Type: "integer", Default: "4096", Unit: "kB", Min: "64", Max: "2147483647",
+	},
+	"maintenance_work_mem": {
+		Description: "Sets the maximum memory to be used for maintenance operations.",
+		Context:     "user", Type: "integer", Default: "65536", Unit: "kB", Min: "1024", Max: "2147483647",
+	},
+	"shared_preload_libraries": {
+		Description: "Lists shared libraries to preload into server.",
+		Context:     "postmaster", Type: "string", Default: "",
+	},
+	"io_method": {
+		Description: "Selects the method for executing asynchronous I/O.",
+		Context:     "postmaster", Type: "enum", Default: "worker",
+		Values:   []string{"sync", "worker", "io_uring"},
+		Versions: []string{"18"},
+	},
+
+	// ===== Statements and logging =====
+	"statement_timeout": {
+		Description: "Sets the maximum allowed duration of any statement.",
+		Context:     "user", Type: "integer", Default: "0", Unit: "ms", Min: "0", Max: "2147483647",
+	},
+	"log_min_duration_statement": {
+		Description: "Sets the minimum execution time above which all statements will be logged.",
+		Context:     "superuser", Type: "integer", Default: "-1", Unit: "ms", Min: "-1", Max: "2147483647",
+	},
+	"log_statement": {
+		Description: "Sets the type of statements logged.",
+		Context:     "superuser", Type: "enum", Default: "none",
+		Values: []string{"none", "ddl", "mod", "all"},
+	},
+
+	// ===== Vacuum =====
+	"autovacuum": {
+		Description: "Starts the autovacuum subprocess.",
+		Context:     "sighup", Type: "bool", Default: "on",
+	},
+	"track_counts": {
+		Description: "Collects statistics on database activity.",
+		Context:     "superuser", Type: "bool", Default: "on",
+	},
+	"autovacuum_vacuum_max_threshold": {
+		Description: "Maximum number of tuple updates or deletes prior to vacuum.",
+		Context:     "sighup", Type: "integer", Default: "100000000", Min: "-1", Max: "2147483647",
+		Versions: []string{"18"},
+	},
+	"vacuum_buffer_usage_limit": {
+		Description: "Sets the buffer pool size for VACUUM, ANALYZE, and autovacuum.",
+		Context:     "user", Type: "integer", Default: "2048", Unit: "kB", Min: "0", Max: "16777216",
+		VersionDefaults: map[string]string{"16": "256"},
+	},
+	"old_snapshot_threshold": {
+		Description: "Time before a snapshot is too old to read pages changed after the snapshot was taken.",
+		Context:     "postmaster", Type: "integer", Default: "-1", Unit: "min", Min: "-1", Max: "86400",
+		Versions: []string{"16"}, // removed in PostgreSQL 17
+	},
+
+	// ===== Extension parameters =====
+	"cron.database_name": {
+		Description: "Database in which pg_cron metadata is kept.",
+		Context:     "postmaster", Type: "string", Default: "postgres",
+		Extension: "pg_cron",
+	},
+	"cron.max_running_jobs": {
+		Description: "Maximum number of jobs that can run concurrently.",
+		Context:     "postmaster", Type: "integer", Default: "32", Min: "0", Max: "10000",
+		Extension: "pg_cron",
+	},
+}
+
+// GetParameter returns the documentation for a parameter in the given
+// PostgreSQL version. Returns false if the parameter is unknown or does not
+// exist in that version.
+func GetParameter(name, version string) (Parameter, bool) {
+	param, ok := Parameters[strings.ToLower(name)]
+	if !ok {
+		return Parameter{}, false
+	}
+	if len(param.Versions) > 0 && !slices.Contains(param.Versions, version) {
+		return Parameter{}, false
+	}
+	if def, ok := param.VersionDefaults[version]; ok {
+		param.Default = def
+	}
+	return param, true
+}
+
+// SearchParameters returns the sorted names of documented parameters
+// containing substr.
+func SearchParameters(substr string) []string {
+	substr = strings.ToLower(substr)
+	var names []string
+	for name := range Parameters {
+		if strings.Contains(name, substr) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetGUCSetters returns the catalog extensions that set the parameter, mapped
+// to the value each sets.
+func GetGUCSetters(name string) map[string]string {
+	setters := make(map[string]string)
+	for extName, ext := range Catalog {
+		if value, ok := ext.GUCs[strings.ToLower(name)]; ok {
+			setters[extName] = value
+		}
+	}
+	return setters
+}
@@ -0,0 +1,46 @@
+package extensions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetParameter(t *testing.T) {
+	param, ok := GetParameter("Work_Mem", "17")
+	assert.True(t, ok)
+	assert.Equal(t, "4096", param.Default)
+	assert.Equal(t, "kB", param.Unit)
+
+	param, ok = GetParameter("vacuum_buffer_usage_limit", "16")
+	assert.True(t, ok)
+	assert.Equal(t, "256", param.Default)
+
+	_, ok = GetParameter("io_method", "17")
+	assert.False(t, ok)
+}
+
+// Every GUC set by a catalog extension should be documented so pgbox guc can
+// explain what pgbox configures.
+func TestParameters_CoverCatalogGUCs(t *testing.T) {
+	for name, ext := range Catalog {
+		for key := range ext.GUCs {
+			_, ok := Parameters[key]
+			assert.True(t, ok, "GUC %s set by %s is missing from Parameters", key, name)
+		}
+	}
+}
+
+func TestParameters_ExtensionsExist(t *testing.T) {
+	for name, param := range Parameters {
+		if param.Extension != "" {
+			_, ok := Catalog[param.Extension]
+			assert.True(t, ok, "parameter %s refers to unknown extension %s", name, param.Extension)
+		}
+	}
+}
+
+func TestGetGUCSetters(t *testing.T) {
+	setters := GetGUCSetters("wal_level")
+	assert.Equal(t, "logical", setters["wal2json"])
+}
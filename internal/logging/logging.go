@@ -0,0 +1,100 @@
+// Package logging writes pgbox's progress messages, warnings and debug
+// output, filtered by the level --quiet and --verbose select and formatted
+// as plain text or, with --log-format json, as one JSON object per line.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Level is the importance of a message.
+type Level int
+
+const (
+	LevelDebug Level = iota // Diagnostics shown with --verbose, such as the runtime commands run
+	LevelInfo               // Progress messages, hidden by --quiet
+	LevelWarn               // Problems that do not stop the command; always shown
+)
+
+// String returns the level's name as used in JSON output.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	}
+	return "info"
+}
+
+// Formats lists the formats Configure accepts.
+var Formats = []string{"text", "json"}
+
+var (
+	minLevel   = LevelInfo
+	jsonOutput bool
+	now        = time.Now
+)
+
+// Configure sets the lowest level that is written and the output format,
+// "text" or "json".
+func Configure(level Level, format string) error {
+	switch format {
+	case "", "text":
+		jsonOutput = false
+	case "json":
+		jsonOutput = true
+	default:
+		return fmt.Errorf("unknown log format %q (available: %s)", format, strings.Join(Formats, ", "))
+	}
+	minLevel = level
+	return nil
+}
+
+// Enabled reports whether messages of level are written.
+func Enabled(level Level) bool {
+	return level >= minLevel
+}
+
+// Debugf writes a diagnostic message to w when --verbose is set.
+func Debugf(w io.Writer, format string, args ...any) {
+	if Enabled(LevelDebug) {
+		Log(w, LevelDebug, format, args...)
+	}
+}
+
+// Infof writes a progress message to w unless --quiet is set.
+func Infof(w io.Writer, format string, args ...any) {
+	if Enabled(LevelInfo) {
+		Log(w, LevelInfo, format, args...)
+	}
+}
+
+// Warnf writes a warning to w, prefixed with "Warning: " in text format.
+func Warnf(w io.Writer, format string, args ...any) {
+	Log(w, LevelWarn, format, args...)
+}
+
+// Log writes a message of level to w regardless of the configured level. In
+// text format it is written as is, on its own line; in JSON format its
+// surrounding blank lines are dropped.
+func Log(w io.Writer, level Level, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if jsonOutput {
+		line, _ := json.Marshal(struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}{now().UTC().Format(time.RFC3339Nano), level.String(), strings.TrimSpace(msg)})
+		_, _ = fmt.Fprintf(w, "%s\n", line)
+		return
+	}
+	if level == LevelWarn {
+		msg = "Warning: " + msg
+	}
+	_, _ = fmt.Fprintln(w, msg)
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configure sets the level and format for one test.
+func configure(t *testing.T, level Level, format string) {
+	t.Helper()
+	require.NoError(t, Configure(level, format))
+	t.Cleanup(func() { _ = Configure(LevelInfo, "text") })
+}
+
+func TestLevels(t *testing.T) {
+	for _, tc := range []struct {
+		level Level
+		want  string
+	}{
+		{LevelDebug, "running docker ps\nBuilding image...\nWarning: port 5432 is taken\n"},
+		{LevelInfo, "Building image...\nWarning: port 5432 is taken\n"},
+		{LevelWarn, "Warning: port 5432 is taken\n"},
+	} {
+		configure(t, tc.level, "text")
+		var buf bytes.Buffer
+
+		Debugf(&buf, "running %s", "docker ps")
+		Infof(&buf, "Building image...")
+		Warnf(&buf, "port %d is taken", 5432)
+
+		assert.Equal(t, tc.want, buf.String(), tc.level.String())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	configure(t, LevelInfo, "json")
+	now = func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+	var buf bytes.Buffer
+
+	Infof(&buf, "\nRunning in background. Use 'pgbox down -n %s' to stop.", "pgbox-pg17")
+	Warnf(&buf, "%s keeps its existing settings", "pgbox-pg17")
+
+	assert.Equal(t, `{"time":"2026-10-16T09:30:00Z","level":"info","msg":"Running in background. Use 'pgbox down -n pgbox-pg17' to stop."}
+{"time":"2026-10-16T09:30:00Z","level":"warn","msg":"pgbox-pg17 keeps its existing settings"}
+`, buf.String())
+}
+
+func TestConfigure_UnknownFormat(t *testing.T) {
+	assert.EqualError(t, Configure(LevelInfo, "xml"), `unknown log format "xml" (available: text, json)`)
+}
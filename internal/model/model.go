@@ -12,18 +12,32 @@ import (
 type DockerfileModel struct {
 	BaseImage   string              // Base Docker image (e.g., "postgres:17")
 	AptPackages []string            // Debian/Ubuntu packages to install
+	ApkPackages []string            // Alpine packages to install
 	DebURLs     []string            // Direct .deb URLs to download and install
 	ZipURLs     []string            // .zip URLs containing .deb packages to download and install
+	Checksums   map[string]string   // Expected SHA-256 of downloads, by URL
+	ArchDebs    []ArchDownload      // .deb downloads picked by the architecture the image is built for
+	ArchZips    []ArchDownload      // .zip downloads picked by the architecture the image is built for
 	Blocks      map[string][]string // Named blocks for custom content
 }
 
+// ArchDownload is a .deb or .zip download whose URL depends on the
+// architecture the image is built for
+type ArchDownload struct {
+	Name      string            // Extension it installs
+	URLs      map[string]string // By Debian architecture
+	Checksums map[string]string // Expected SHA-256, by Debian architecture, where known
+}
+
 // NewDockerfileModel creates a new Dockerfile model with defaults
 func NewDockerfileModel(baseImage string) *DockerfileModel {
 	return &DockerfileModel{
 		BaseImage:   baseImage,
 		AptPackages: []string{},
+		ApkPackages: []string{},
 		DebURLs:     []string{},
 		ZipURLs:     []string{},
+		Checksums:   make(map[string]string),
 		Blocks:      make(map[string][]string),
 	}
 }
@@ -38,23 +52,95 @@ func (d *DockerfileModel) AddZipURLs(urls ...string) {
 	d.ZipURLs = appendUnique(d.ZipURLs, urls...)
 }
 
-// AddPackages adds packages to install via apt
+// AddArchDownload adds a download picked by architecture; kind is "deb" or
+// "zip"
+func (d *DockerfileModel) AddArchDownload(kind string, download ArchDownload) {
+	switch kind {
+	case "deb":
+		d.ArchDebs = append(d.ArchDebs, download)
+	case "zip":
+		d.ArchZips = append(d.ArchZips, download)
+	}
+}
+
+// AddChecksum records the expected SHA-256 of a .deb or .zip download
+func (d *DockerfileModel) AddChecksum(url, sha256 string) {
+	if sha256 != "" {
+		d.Checksums[url] = sha256
+	}
+}
+
+// AddPackages adds packages to install via apt or apk
 func (d *DockerfileModel) AddPackages(packages []string, packageType string) {
-	if packageType == "apt" {
+	switch packageType {
+	case "apt":
 		d.AptPackages = appendUnique(d.AptPackages, packages...)
+	case "apk":
+		d.ApkPackages = appendUnique(d.ApkPackages, packages...)
+	}
+}
+
+// GetPackageManager returns the package manager of the base image: "apk" for
+// Alpine-based images (e.g., "postgres:17-alpine"), otherwise "apt"
+func (d *DockerfileModel) GetPackageManager() string {
+	if strings.Contains(strings.ToLower(d.BaseImage), "alpine") {
+		return "apk"
 	}
+	return "apt"
+}
+
+// Healthcheck is how a container reports whether PostgreSQL is up, as a
+// compose healthcheck or docker run's --health-* options.
+type Healthcheck struct {
+	Cmd      string // Shell command run in the container; exit status 0 means healthy
+	Interval string
+	Timeout  string
+	Retries  int
+}
+
+// PostgresHealthcheck is the healthcheck of the database service in exported
+// compose files and of containers pgbox up creates, so tools that wait for a
+// healthy container behave the same with either.
+var PostgresHealthcheck = Healthcheck{
+	Cmd:      "pg_isready -U ${POSTGRES_USER:-postgres} -d ${POSTGRES_DB:-postgres}",
+	Interval: "10s",
+	Timeout:  "5s",
+	Retries:  5,
 }
 
 // ComposeModel represents docker-compose.yml configuration
 type ComposeModel struct {
-	ServiceName string            // Service name (usually "db")
-	Image       string            // Docker image or build config
-	BuildPath   string            // Path to Dockerfile if building
-	Env         map[string]string // Environment variables
-	Ports       []string          // Port mappings "host:container"
-	Volumes     []string          // Volume mounts
-	Networks    []string          // Networks to join
-	Anchored    map[string]any    // Anchored blocks for preservation
+	ServiceName   string            // Service name (usually "db")
+	ContainerName string            // Overrides the default container name, pgbox-<service>
+	Image         string            // Docker image or build config
+	BuildPath     string            // Path to Dockerfile if building
+	Env           map[string]string // Environment variables
+	Entrypoint    []string          // Overrides the image's entrypoint
+	Ports         []string          // Port mappings "host:container"
+	Volumes       []string          // Volume mounts
+	Networks      []string          // Networks to join
+	SecurityOpt   []string          // security_opt entries, e.g. no-new-privileges:true
+	CapDrop       []string          // Capabilities to drop
+	CapAdd        []string          // Capabilities to add back after CapDrop
+	ReadOnly      bool              // Mount the root filesystem read-only
+	Tmpfs         []string          // Writable tmpfs mounts, for a read-only root
+	Extra         []string          // Further service keys as YAML lines, indented for the service
+	Sidecars      []Sidecar         // Extra services that run next to the database
+	Anchored      map[string]any    // Anchored blocks for preservation
+}
+
+// Sidecar is an extra compose service, such as a database UI
+type Sidecar struct {
+	Name       string            // Service name
+	Image      string            // Docker image
+	Build      string            // Build context with a Dockerfile, used instead of Image
+	Env        map[string]string // Environment variables
+	Entrypoint []string          // Overrides the image's entrypoint
+	Command    []string          // Arguments passed to the entrypoint
+	Ports      []string          // Port mappings "host:container"
+	Volumes    []string          // Volume mounts "source:target[:mode]"
+	DependsOn  string            // Service that must be healthy first
+	Profiles   []string          // Compose profiles that enable it; empty means always on
 }
 
 // NewComposeModel creates a new Compose model with defaults
@@ -96,11 +182,57 @@ func (c *ComposeModel) SetEnv(key, value string) {
 	c.Env[key] = value
 }
 
+// AddSidecar adds a service, replacing any with the same name
+func (c *ComposeModel) AddSidecar(s Sidecar) {
+	for i, existing := range c.Sidecars {
+		if existing.Name == s.Name {
+			c.Sidecars[i] = s
+			return
+		}
+	}
+	c.Sidecars = append(c.Sidecars, s)
+}
+
+// GUC source kinds, in increasing order of precedence
+const (
+	SourceExtension = "extension" // Default required by an extension
+	SourceProfile   = "profile"   // Value from a tuning profile
+	SourceUser      = "user"      // Explicit user override (--set)
+)
+
+// GUCSource records where a GUC value came from
+type GUCSource struct {
+	Kind string // SourceExtension, SourceProfile or SourceUser
+	Name string // Extension or profile name; empty for user overrides
+}
+
+// String describes the source for display (e.g., "extension pg_cron")
+func (s GUCSource) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return fmt.Sprintf("%s %s", s.Kind, s.Name)
+}
+
+// rank returns the precedence of the source kind; higher wins
+func (s GUCSource) rank() int {
+	switch s.Kind {
+	case SourceUser:
+		return 3
+	case SourceProfile:
+		return 2
+	case SourceExtension:
+		return 1
+	}
+	return 0
+}
+
 // PGConfModel holds PostgreSQL server configuration
 type PGConfModel struct {
-	SharedPreload  []string          // shared_preload_libraries values
-	GUCs           map[string]string // Generic GUC key-value pairs
-	RequireRestart bool              // Whether changes require restart
+	SharedPreload  []string             // shared_preload_libraries values
+	GUCs           map[string]string    // Generic GUC key-value pairs
+	Sources        map[string]GUCSource // Provenance of each GUC
+	RequireRestart bool                 // Whether changes require restart
 }
 
 // NewPGConfModel creates a new PostgreSQL config model
@@ -108,7 +240,29 @@ func NewPGConfModel() *PGConfModel {
 	return &PGConfModel{
 		SharedPreload: []string{},
 		GUCs:          make(map[string]string),
+		Sources:       make(map[string]GUCSource),
+	}
+}
+
+// ApplyGUC sets a GUC from the given source unless a higher-precedence source
+// already set it (user > profile > extension). Returns whether the value was applied.
+func (p *PGConfModel) ApplyGUC(key, value string, source GUCSource) bool {
+	if existing, ok := p.Sources[key]; ok && existing.rank() > source.rank() {
+		return false
+	}
+	p.GUCs[key] = value
+	p.Sources[key] = source
+	return true
+}
+
+// SortedGUCKeys returns the GUC keys in alphabetical order
+func (p *PGConfModel) SortedGUCKeys() []string {
+	keys := make([]string, 0, len(p.GUCs))
+	for k := range p.GUCs {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
 }
 
 // AddSharedPreload adds libraries to shared_preload_libraries
@@ -136,9 +290,11 @@ func (p *PGConfModel) GetSharedPreloadString() string {
 	return strings.Join(p.SharedPreload, ",")
 }
 
-// InitModel holds ordered SQL initialization fragments
+// InitModel holds ordered SQL initialization fragments and the shell
+// fragments that run before them
 type InitModel struct {
 	Fragments []InitFragment
+	Scripts   []InitFragment
 }
 
 // InitFragment represents a SQL initialization fragment
@@ -173,6 +329,16 @@ func (i *InitModel) AddFragment(name, content string) {
 	})
 }
 
+// AddScript adds a shell fragment run at initdb time, keeping the order
+// scripts were added in
+func (i *InitModel) AddScript(name, content string) {
+	i.Scripts = append(i.Scripts, InitFragment{
+		Name:    name,
+		SHA256:  fmt.Sprintf("%x", sha256.Sum256([]byte(strings.TrimSpace(content)))),
+		Content: content,
+	})
+}
+
 // GetOrderedFragments returns fragments in a stable order
 func (i *InitModel) GetOrderedFragments() []InitFragment {
 	sorted := make([]InitFragment, len(i.Fragments))
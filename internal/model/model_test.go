@@ -40,8 +40,30 @@ func TestDockerfileModel_AddPackages_NonApt(t *testing.T) {
 
 	m.AddPackages([]string{"some-package"}, "yum")
 
-	// Non-apt packages should be ignored
+	// Unknown package types should be ignored
 	assert.Empty(t, m.AptPackages)
+	assert.Empty(t, m.ApkPackages)
+}
+
+func TestDockerfileModel_AddPackages_Apk(t *testing.T) {
+	m := NewDockerfileModel("postgres:17-alpine")
+
+	m.AddPackages([]string{"postgresql17-pgvector"}, "apk")
+
+	assert.Equal(t, []string{"postgresql17-pgvector"}, m.ApkPackages)
+	assert.Empty(t, m.AptPackages)
+}
+
+func TestDockerfileModel_GetPackageManager(t *testing.T) {
+	for image, want := range map[string]string{
+		"postgres:17":              "apt",
+		"postgres:18-bookworm":     "apt",
+		"postgres:17-alpine":       "apk",
+		"postgres:16-alpine3.20":   "apk",
+		"registry.local/pg:Alpine": "apk",
+	} {
+		assert.Equal(t, want, NewDockerfileModel(image).GetPackageManager(), image)
+	}
 }
 
 func TestDockerfileModel_AddDebURLs(t *testing.T) {
@@ -165,6 +187,28 @@ func TestPGConfModel_SetGUC_SameValue(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestPGConfModel_ApplyGUC_Precedence(t *testing.T) {
+	m := NewPGConfModel()
+
+	assert.True(t, m.ApplyGUC("wal_level", "logical", GUCSource{Kind: SourceExtension, Name: "wal2json"}))
+	assert.True(t, m.ApplyGUC("wal_level", "replica", GUCSource{Kind: SourceUser}))
+	// A lower-precedence source cannot override a user value
+	assert.False(t, m.ApplyGUC("wal_level", "minimal", GUCSource{Kind: SourceProfile, Name: "test"}))
+
+	assert.Equal(t, "replica", m.GUCs["wal_level"])
+	assert.Equal(t, "user", m.Sources["wal_level"].String())
+}
+
+func TestPGConfModel_ApplyGUC_ProfileOverridesExtension(t *testing.T) {
+	m := NewPGConfModel()
+
+	m.ApplyGUC("max_wal_senders", "10", GUCSource{Kind: SourceExtension, Name: "wal2json"})
+	m.ApplyGUC("max_wal_senders", "0", GUCSource{Kind: SourceProfile, Name: "test"})
+
+	assert.Equal(t, "0", m.GUCs["max_wal_senders"])
+	assert.Equal(t, "profile test", m.Sources["max_wal_senders"].String())
+}
+
 func TestPGConfModel_GetSharedPreloadString(t *testing.T) {
 	m := NewPGConfModel()
 
@@ -0,0 +1,245 @@
+package orchestrator
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/render"
+)
+
+// dataVolumeTarget is where the generated db service mounts its data volume.
+const dataVolumeTarget = "/var/lib/postgresql/data"
+
+// hardenedServiceKeys are the service keys hardenService sets, which replace
+// an adopted service's own.
+var hardenedServiceKeys = []string{"security_opt", "cap_drop", "cap_add", "read_only", "tmpfs"}
+
+// imageMajorPattern matches the PostgreSQL major version at the start of an
+// image tag, as in 16, 16.4-alpine, 16-3.4 (postgis) or pg16 (pgvector).
+var imageMajorPattern = regexp.MustCompile(`^(?:pg)?(\d+)`)
+
+// stockTagPattern matches the version tags of the Debian-based postgres image.
+var stockTagPattern = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// adoptCompose takes over the PostgreSQL service of the compose file at
+// composePath for export --adopt. Its credentials, other environment values
+// and host port carry into cfg where the command line left them empty, as do
+// settings from -c arguments of its command, and a custom image becomes the
+// base image. The data in its volume only works with the major version it was
+// created with, so a different -v is refused.
+func (o *ExportOrchestrator) adoptCompose(cfg *ExportConfig, composePath string) error {
+	if _, err := os.Stat(composePath); err != nil {
+		return fmt.Errorf("--adopt needs an existing compose file: %w", err)
+	}
+	svc, err := render.AdoptComposeFile(composePath)
+	if err != nil {
+		return err
+	}
+
+	major := svc.BuildMajor
+	if svc.Image != "" {
+		major = imageMajor(svc.Image)
+	}
+	if major != "" && major != cfg.Version {
+		return fmt.Errorf("service %s runs PostgreSQL %s, and the data in its volume only works with that major version; export with -v %s",
+			svc.Name, major, major)
+	}
+	if svc.Image != "" {
+		if major == "" {
+			logging.Warnf(o.output, "cannot tell the PostgreSQL version of service %s from %s; check that %s matches the data in its volume",
+				svc.Name, svc.Image, cfg.Version)
+		}
+		if cfg.BaseImage == "" && !isStockImage(svc.Image) {
+			cfg.BaseImage = svc.Image
+		}
+	}
+
+	for key, field := range map[string]*string{
+		"POSTGRES_USER":     &cfg.User,
+		"POSTGRES_PASSWORD": &cfg.Password,
+		"POSTGRES_DB":       &cfg.Database,
+	} {
+		if *field == "" {
+			*field = svc.Env[key]
+		}
+	}
+	// The locale and initdb arguments only matter for a new volume, but keep
+	// the service's for one
+	if cfg.Timezone == "" {
+		cfg.Timezone = svc.Env["TZ"]
+	}
+	if cfg.Locale == "" && cfg.Encoding == "" && len(cfg.InitdbArgs) == 0 {
+		cfg.InitdbArgs = config.SplitInitdbArgs(svc.Env["POSTGRES_INITDB_ARGS"])
+	}
+	env := maps.Clone(cfg.Env)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	for key, value := range svc.Env {
+		if _, ok := env[key]; !ok && credentialEnv[key] == "" && initdbEnvFlags[key] == "" {
+			env[key] = value
+		}
+	}
+	cfg.Env = env
+
+	if cfg.Port == "" {
+		cfg.Port = "5432"
+		for _, port := range svc.Ports {
+			if containerPortOf(port) == "5432" {
+				cfg.Port = hostPortOf(port)
+			}
+		}
+	}
+
+	settings, rest := commandSettings(svc.Command)
+	merged := maps.Clone(cfg.Settings)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for key, value := range settings {
+		if _, ok := merged[key]; !ok {
+			merged[key] = value
+		}
+	}
+	cfg.Settings = merged
+	if len(rest) > 0 {
+		logging.Warnf(o.output, "service %s's command arguments %s are not carried over; pgbox generates the command from extensions and --set",
+			svc.Name, strings.Join(rest, " "))
+	}
+	if len(svc.Replaced) > 0 {
+		logging.Infof(o.output, "Service %s's %s replaced by pgbox's", svc.Name, strings.Join(svc.Replaced, " and "))
+	}
+
+	o.adopted = svc
+	return nil
+}
+
+// mergeAdopted adds what the adopted service had to the generated model: its
+// container name, its ports and volumes, with its own data volume in place of
+// postgres_data, and its other keys as written.
+func (o *ExportOrchestrator) mergeAdopted(m *model.ComposeModel, hardened bool) {
+	svc := o.adopted
+	m.ContainerName = svc.ContainerName
+
+	for _, port := range svc.Ports {
+		if containerPortOf(port) != "5432" {
+			m.AddPort(port)
+			continue
+		}
+		// Keep the service's own mapping, such as a host IP, for the same port
+		for i, generated := range m.Ports {
+			if !hardened && containerPortOf(generated) == "5432" && hostPortOf(generated) == hostPortOf(port) {
+				m.Ports[i] = port
+			}
+		}
+	}
+
+	dataVolume := ""
+	for _, volume := range svc.Volumes {
+		if strings.HasPrefix(volumeTarget(volume), "/var/lib/postgresql") {
+			dataVolume = volume
+		}
+	}
+	m.Volumes = slices.DeleteFunc(m.Volumes, func(volume string) bool { return volumeTarget(volume) == dataVolumeTarget })
+	if dataVolume != "" {
+		m.Volumes = append([]string{dataVolume}, m.Volumes...)
+	}
+	for _, volume := range svc.Volumes {
+		if volume == dataVolume || slices.ContainsFunc(m.Volumes, func(v string) bool { return volumeTarget(v) == volumeTarget(volume) }) {
+			continue
+		}
+		m.AddVolume(volume)
+	}
+
+	for _, key := range svc.Extra {
+		if hardened && slices.Contains(hardenedServiceKeys, key.Name) {
+			logging.Infof(o.output, "Service %s's %s replaced by --hardened", svc.Name, key.Name)
+			continue
+		}
+		if key.Name == "entrypoint" && len(m.Entrypoint) > 0 {
+			logging.Infof(o.output, "Service %s's entrypoint replaced by --ssl", svc.Name)
+			continue
+		}
+		m.Extra = append(m.Extra, key.Lines...)
+	}
+}
+
+// imageMajor returns the PostgreSQL major version in an image's tag, or ""
+// when the tag does not tell.
+func imageMajor(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	if m := imageMajorPattern.FindStringSubmatch(image[i+1:]); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// isStockImage reports whether image is the official Debian-based postgres
+// image, which pgbox picks itself for the version and extensions. Other
+// variants, such as alpine, stay the base image, since the data's collations
+// depend on the C library.
+func isStockImage(image string) bool {
+	name, tag, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return slices.Contains([]string{"postgres", "library/postgres", "docker.io/library/postgres"}, name) &&
+		(tag == "" || tag == "latest" || stockTagPattern.MatchString(tag))
+}
+
+// containerPortOf returns the container side of a port mapping such as
+// 127.0.0.1:5433:5432/tcp.
+func containerPortOf(mapping string) string {
+	port, _, _ := strings.Cut(mapping[strings.LastIndex(mapping, ":")+1:], "/")
+	return port
+}
+
+// volumeTarget returns the path a volume entry such as data:/path:ro mounts
+// at.
+func volumeTarget(volume string) string {
+	parts := strings.Split(volume, ":")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// commandSettings returns the settings given as -c key=value or --key=value
+// arguments of a postgres command, and the arguments that are neither.
+func commandSettings(args []string) (map[string]string, []string) {
+	settings := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var setting string
+		switch {
+		case i == 0 && arg == "postgres":
+			continue
+		case arg == "-c" && i+1 < len(args):
+			i++
+			setting = args[i]
+		case strings.HasPrefix(arg, "-c"):
+			setting = arg[2:]
+		case strings.HasPrefix(arg, "--"):
+			setting = arg[2:]
+		}
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			rest = append(rest, arg)
+			continue
+		}
+		settings[strings.ReplaceAll(key, "-", "_")] = value
+	}
+	return settings, rest
+}
@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const adoptedComposeFile = `services:
+  app:
+    build: .
+
+  db:
+    image: postgres:16-alpine
+    container_name: shop-db
+    command: postgres -c max_connections=200
+    environment:
+      POSTGRES_USER: shop
+      POSTGRES_PASSWORD: secret
+      TZ: UTC
+    ports:
+      - "127.0.0.1:5433:5432"
+    volumes:
+      - shopdata:/var/lib/postgresql/data
+
+volumes:
+  shopdata:
+`
+
+func TestExportOrchestrator_Adopt(t *testing.T) {
+	dir := t.TempDir()
+	composePath := filepath.Join(dir, "docker-compose.yml")
+	require.NoError(t, os.WriteFile(composePath, []byte(adoptedComposeFile), 0644))
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "16", Adopt: true})
+
+	require.NoError(t, err)
+	compose, err := os.ReadFile(composePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), `    container_name: shop-db
+    environment:
+      POSTGRES_DB: postgres
+      POSTGRES_PASSWORD: secret
+      POSTGRES_USER: shop
+      TZ: UTC
+    command:
+      - postgres
+      - -c
+      - max_connections=200
+    ports:
+      - "127.0.0.1:5433:5432"
+`)
+	assert.Contains(t, string(compose), "      - shopdata:/var/lib/postgresql/data\n")
+	assert.NotContains(t, string(compose), "postgres_data")
+	assert.Contains(t, string(compose), "# pgbox: END\n  app:\n    build: .\n\nvolumes:\n  shopdata:\n")
+	dockerfile, err := os.ReadFile(filepath.Join(dir, "Dockerfile"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerfile), "FROM postgres:16-alpine")
+
+	// The data in the volume only works with PostgreSQL 16
+	err = NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Adopt: true})
+	assert.EqualError(t, err, "service db runs PostgreSQL 16, and the data in its volume only works with that major version; export with -v 16")
+}
+
+func TestCommandSettings(t *testing.T) {
+	settings, rest := commandSettings([]string{"postgres", "-c", "max_connections=200", "-cwork_mem=64MB", "--shared-buffers=1GB", "-N", "50"})
+
+	assert.Equal(t, map[string]string{"max_connections": "200", "work_mem": "64MB", "shared_buffers": "1GB"}, settings)
+	assert.Equal(t, []string{"-N", "50"}, rest)
+}
+
+func TestImageMajor(t *testing.T) {
+	for image, want := range map[string]string{
+		"postgres:16":                       "16",
+		"postgres:16.4-alpine":              "16",
+		"postgis/postgis:17-3.5":            "17",
+		"pgvector/pgvector:pg15":            "15",
+		"postgres":                          "",
+		"registry:5000/postgres":            "",
+		"timescale/timescaledb:latest-pg16": "",
+	} {
+		assert.Equal(t, want, imageMajor(image), image)
+	}
+}
@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// BackupConfig holds configuration for the backup command.
+type BackupConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Output        string // Local file (or directory for the directory format)
+	Format        string // custom, plain or directory
+	Compress      int    // pg_dump compression level, or -1 for the pg_dump default
+	Jobs          int    // Parallel dump jobs (directory format only)
+}
+
+// BackupOrchestrator handles dumping a database from a container to the host.
+type BackupOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewBackupOrchestrator creates a new BackupOrchestrator.
+func NewBackupOrchestrator(d docker.Docker, w io.Writer) *BackupOrchestrator {
+	return &BackupOrchestrator{docker: d, output: w}
+}
+
+// dumpFormats maps format names to pg_dump/pg_restore --format letters.
+var dumpFormats = map[string]string{
+	"custom":    "c",
+	"plain":     "p",
+	"directory": "d",
+}
+
+// Run dumps the database with pg_dump inside the container and writes the
+// result to cfg.Output on the host.
+func (o *BackupOrchestrator) Run(ctx context.Context, cfg BackupConfig) error {
+	if cfg.Format == "" {
+		cfg.Format = "custom"
+	}
+	formatFlag, ok := dumpFormats[cfg.Format]
+	if !ok {
+		return fmt.Errorf("invalid format %q (must be custom, plain, or directory)", cfg.Format)
+	}
+	if cfg.Jobs > 1 && cfg.Format != "directory" {
+		return fmt.Errorf("--jobs requires --format directory")
+	}
+	if cfg.Compress < -1 || cfg.Compress > 9 {
+		return fmt.Errorf("invalid compression level %d (must be 0-9)", cfg.Compress)
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	if cfg.ContainerName != "" {
+		running, err := o.docker.IsContainerRunning(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to check container status: %w", err)
+		}
+		if !running {
+			return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+		}
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	output := cfg.Output
+	if output == "" {
+		output = defaultBackupName(database, cfg.Format, time.Now())
+	}
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists; choose another output path or remove it first", output)
+	}
+
+	dumpArgs := []string{"pg_dump", "-U", user, "-d", database, "-F", formatFlag}
+	if cfg.Compress >= 0 {
+		dumpArgs = append(dumpArgs, "-Z", fmt.Sprintf("%d", cfg.Compress))
+	}
+
+	logging.Infof(o.output, "Backing up database '%s' from %s (%s format)...", database, name, cfg.Format)
+
+	if cfg.Format == "directory" {
+		err = o.dumpDirectory(ctx, name, dumpArgs, cfg.Jobs, output)
+	} else {
+		err = o.dumpFile(ctx, name, dumpArgs, output)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Backup written to %s\n", output)
+	_, _ = fmt.Fprintf(o.output, "Restore with: pgbox restore %s -n %s\n", output, name)
+	return nil
+}
+
+// dumpFile streams pg_dump output into a local file. The dump is written to a
+// temporary file first so a failed backup never leaves a truncated file behind.
+func (o *BackupOrchestrator) dumpFile(ctx context.Context, name string, dumpArgs []string, output string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(output), ".pgbox-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	var stderr strings.Builder
+	args := append([]string{"exec"}, passwordEnv(name)...)
+	args = append(args, name)
+	args = append(args, dumpArgs...)
+	runErr := o.docker.RunCommandWithIO(ctx, nil, tmp, &stderr, args...)
+	closeErr := tmp.Close()
+	if runErr != nil {
+		return fmt.Errorf("pg_dump failed: %s: %w", strings.TrimSpace(stderr.String()), runErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write backup: %w", closeErr)
+	}
+
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return nil
+}
+
+// dumpDirectory runs a directory-format dump inside the container and copies
+// the resulting directory to the host.
+func (o *BackupOrchestrator) dumpDirectory(ctx context.Context, name string, dumpArgs []string, jobs int, output string) error {
+	remote := fmt.Sprintf("/tmp/pgbox-backup-%d", os.Getpid())
+	defer func() {
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		_, _ = o.docker.ExecCommand(ctx, name, "rm", "-rf", remote)
+	}()
+
+	args := append(dumpArgs, "-f", remote)
+	if jobs > 1 {
+		args = append(args, "-j", fmt.Sprintf("%d", jobs))
+	}
+	if env := passwordEnv(name); env != nil {
+		args = append([]string{"env", env[1]}, args...)
+	}
+	if out, err := o.docker.ExecCommand(ctx, name, args...); err != nil {
+		return fmt.Errorf("pg_dump failed: %s: %w", strings.TrimSpace(out), err)
+	}
+
+	if out, err := o.docker.RunCommandWithOutput(ctx, "cp", name+":"+remote, output); err != nil {
+		return fmt.Errorf("failed to copy backup to %s: %s: %w", output, strings.TrimSpace(out), err)
+	}
+	return nil
+}
+
+// defaultBackupName returns a timestamped file name for a backup of database.
+func defaultBackupName(database, format string, now time.Time) string {
+	base := fmt.Sprintf("%s-%s", database, now.Format("20060102-150405"))
+	switch format {
+	case "plain":
+		return base + ".sql"
+	case "directory":
+		return base
+	default:
+		return base + ".dump"
+	}
+}
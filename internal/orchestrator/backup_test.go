@@ -0,0 +1,106 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupOrchestrator_CustomFormatStreamsToFile(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		_, _ = io.WriteString(stdout, "PGDMP...")
+		return nil
+	}
+	output := filepath.Join(t.TempDir(), "db.dump")
+	var buf bytes.Buffer
+
+	err := NewBackupOrchestrator(mock, &buf).Run(t.Context(), BackupConfig{Output: output, Compress: 6})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithIO, 1)
+	assert.Equal(t, []string{"exec", "pgbox-pg17", "pg_dump", "-U", "postgres", "-d", "postgres", "-F", "c", "-Z", "6"},
+		mock.Calls.RunCommandWithIO[0])
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "PGDMP...", string(content))
+	assert.Contains(t, buf.String(), "Backup written to "+output)
+}
+
+func TestBackupOrchestrator_FailureLeavesNoFile(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		_, _ = io.WriteString(stdout, "partial")
+		_, _ = io.WriteString(stderr, `pg_dump: error: database "nope" does not exist`)
+		return errors.New("exit status 1")
+	}
+	dir := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewBackupOrchestrator(mock, &buf).Run(t.Context(), BackupConfig{Output: filepath.Join(dir, "db.dump"), Compress: -1})
+
+	assert.ErrorContains(t, err, `database "nope" does not exist`)
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestBackupOrchestrator_DirectoryFormat(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	output := filepath.Join(t.TempDir(), "db-backup")
+	var buf bytes.Buffer
+
+	err := NewBackupOrchestrator(mock, &buf).Run(t.Context(), BackupConfig{Output: output, Format: "directory", Compress: -1, Jobs: 4})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.ExecCommand, 2)
+	dump := mock.Calls.ExecCommand[0].Command
+	assert.Equal(t, []string{"pg_dump", "-U", "postgres", "-d", "postgres", "-F", "d"}, dump[:7])
+	assert.Contains(t, dump, "-j")
+	assert.Equal(t, []string{"rm", "-rf"}, mock.Calls.ExecCommand[1].Command[:2])
+	require.Len(t, mock.Calls.RunCommandWithOutput, 1)
+	assert.Equal(t, "cp", mock.Calls.RunCommandWithOutput[0][0])
+	assert.Equal(t, output, mock.Calls.RunCommandWithOutput[0][2])
+}
+
+func TestBackupOrchestrator_InvalidOptions(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+	orch := NewBackupOrchestrator(mock, &buf)
+
+	assert.ErrorContains(t, orch.Run(t.Context(), BackupConfig{Format: "tar", Compress: -1}), "invalid format")
+	assert.ErrorContains(t, orch.Run(t.Context(), BackupConfig{Jobs: 2, Compress: -1}), "--jobs requires --format directory")
+	assert.ErrorContains(t, orch.Run(t.Context(), BackupConfig{Compress: 12}), "invalid compression level")
+	assert.Zero(t, mock.Calls.FindPgboxContainer)
+}
+
+func TestBackupOrchestrator_RefusesExistingOutput(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	output := filepath.Join(t.TempDir(), "db.dump")
+	require.NoError(t, os.WriteFile(output, []byte("keep"), 0644))
+	var buf bytes.Buffer
+
+	err := NewBackupOrchestrator(mock, &buf).Run(t.Context(), BackupConfig{Output: output, Compress: -1})
+
+	assert.ErrorContains(t, err, "already exists")
+	assert.Empty(t, mock.Calls.RunCommandWithIO)
+}
+
+func TestDefaultBackupName(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, "app-20240305-143000.dump", defaultBackupName("app", "custom", now))
+	assert.Equal(t, "app-20240305-143000.sql", defaultBackupName("app", "plain", now))
+	assert.Equal(t, "app-20240305-143000", defaultBackupName("app", "directory", now))
+}
@@ -0,0 +1,255 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// BenchConfig holds configuration for the bench command.
+type BenchConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Init          bool          // Recreate the pgbench tables before the run
+	Scale         int           // Scale factor the tables are created with
+	Clients       int           // Concurrent database sessions
+	Threads       int           // pgbench worker threads
+	Duration      time.Duration // How long the workload runs; whole seconds
+	Builtin       string        // One of BenchBuiltins; empty means tpcb-like
+	Scripts       []string      // Host SQL files, each optionally suffixed @weight
+	JSON          bool          // Print the result as JSON
+}
+
+// BenchBuiltins lists the built-in pgbench workloads BenchConfig.Builtin
+// accepts.
+var BenchBuiltins = []string{"tpcb-like", "simple-update", "select-only"}
+
+// benchProgressInterval is how often pgbench reports progress in text mode, in
+// seconds.
+const benchProgressInterval = 5
+
+// BenchResult is the summary of a pgbench run.
+type BenchResult struct {
+	Container        string   `json:"container"`
+	Database         string   `json:"database"`
+	Workload         []string `json:"workload"` // The builtin or the scripts, with their weights
+	Scale            int      `json:"scale,omitempty"`
+	Clients          int      `json:"clients"`
+	Threads          int      `json:"threads"`
+	DurationSeconds  int      `json:"duration_seconds"`
+	Transactions     int64    `json:"transactions"`
+	Failed           int64    `json:"failed"`
+	TPS              float64  `json:"tps"`
+	LatencyAvgMs     float64  `json:"latency_avg_ms"`
+	LatencyStddevMs  float64  `json:"latency_stddev_ms,omitempty"`
+	ConnectionTimeMs float64  `json:"connection_time_ms,omitempty"`
+}
+
+// BenchOrchestrator runs pgbench against a running container.
+type BenchOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewBenchOrchestrator creates a new BenchOrchestrator.
+func NewBenchOrchestrator(d docker.Docker, w io.Writer) *BenchOrchestrator {
+	return &BenchOrchestrator{docker: d, output: w}
+}
+
+// benchScriptPattern splits a --script argument into the file and its weight.
+var benchScriptPattern = regexp.MustCompile(`^(.+?)(?:@(\d+))?$`)
+
+// Run benchmarks the container with pgbench and prints a summary of the
+// throughput and latency. The built-in workloads need the pgbench tables,
+// which are created at cfg.Scale when they are missing or cfg.Init is set.
+// Custom scripts are copied into the container and run against whatever
+// tables they use, the pgbench ones only with cfg.Init.
+func (o *BenchOrchestrator) Run(ctx context.Context, cfg BenchConfig) error {
+	seconds := int(cfg.Duration / time.Second)
+	switch {
+	case cfg.Scale < 1:
+		return fmt.Errorf("--scale must be at least 1")
+	case cfg.Clients < 1:
+		return fmt.Errorf("--clients must be at least 1")
+	case cfg.Threads < 1:
+		return fmt.Errorf("--threads must be at least 1")
+	case cfg.Threads > cfg.Clients:
+		return fmt.Errorf("--threads (%d) must not exceed --clients (%d)", cfg.Threads, cfg.Clients)
+	case seconds < 1:
+		return fmt.Errorf("--time must be at least 1s")
+	case cfg.Builtin != "" && !slices.Contains(BenchBuiltins, cfg.Builtin):
+		return fmt.Errorf("invalid builtin %q (must be one of: %s)", cfg.Builtin, strings.Join(BenchBuiltins, ", "))
+	case cfg.Builtin != "" && len(cfg.Scripts) > 0:
+		return fmt.Errorf("--builtin and --script cannot be combined")
+	}
+	var scripts [][2]string
+	for _, arg := range cfg.Scripts {
+		m := benchScriptPattern.FindStringSubmatch(arg)
+		if _, err := os.Stat(m[1]); err != nil {
+			return fmt.Errorf("failed to read script: %w", err)
+		}
+		scripts = append(scripts, [2]string{m[1], m[2]})
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	result := BenchResult{Container: name, Database: database, Clients: cfg.Clients, Threads: cfg.Threads, DurationSeconds: seconds}
+	args := []string{"exec", name, "pgbench", "-U", user, "-c", strconv.Itoa(cfg.Clients), "-j", strconv.Itoa(cfg.Threads), "-T", strconv.Itoa(seconds)}
+	if cfg.Init || len(scripts) == 0 {
+		if err := o.ensureTables(ctx, name, user, database, cfg); err != nil {
+			return err
+		}
+	}
+	if len(scripts) == 0 {
+		builtin := cfg.Builtin
+		if builtin == "" {
+			builtin = BenchBuiltins[0]
+		}
+		result.Workload = []string{builtin}
+		args = append(args, "-b", builtin)
+	}
+	for i, script := range scripts {
+		remote := fmt.Sprintf("/tmp/pgbox-bench-%d-%d.sql", os.Getpid(), i)
+		defer func() {
+			ctx, cancel := cleanupContext(ctx)
+			defer cancel()
+			_, _ = o.docker.ExecCommand(ctx, name, "rm", "-f", remote)
+		}()
+		if out, err := o.docker.RunCommandWithOutput(ctx, "cp", script[0], name+":"+remote); err != nil {
+			return fmt.Errorf("failed to copy %s into %s: %s: %w", script[0], name, strings.TrimSpace(out), err)
+		}
+		arg, workload := remote, filepath.Base(script[0])
+		if script[1] != "" {
+			arg += "@" + script[1]
+			workload += "@" + script[1]
+		}
+		result.Workload = append(result.Workload, workload)
+		args = append(args, "-f", arg)
+	}
+
+	// pgbench reports progress on stderr and the summary on stdout
+	var stdout, stderr bytes.Buffer
+	progress := io.Writer(&stderr)
+	if !cfg.JSON && logging.Enabled(logging.LevelInfo) {
+		args = append(args, "-P", strconv.Itoa(benchProgressInterval))
+		progress = o.output
+		logging.Infof(o.output, "Running %s on %s for %ds with %d clients and %d threads...",
+			strings.Join(result.Workload, ", "), name, seconds, cfg.Clients, cfg.Threads)
+	}
+	args = append(args, database)
+	if err := o.docker.RunCommandWithIO(ctx, nil, &stdout, progress, args...); err != nil {
+		return fmt.Errorf("pgbench failed: %s: %w", strings.TrimSpace(stderr.String()+stdout.String()), err)
+	}
+	if err := parseBenchOutput(stdout.String(), &result); err != nil {
+		return err
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(o.output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	o.printResult(result)
+	return nil
+}
+
+// ensureTables creates the pgbench tables at cfg.Scale when cfg.Init is set
+// or they are not there yet, replacing any that are.
+func (o *BenchOrchestrator) ensureTables(ctx context.Context, name, user, database string, cfg BenchConfig) error {
+	if !cfg.Init {
+		rows, err := QueryLines(ctx, o.docker, name, user, database, "SELECT to_regclass('pgbench_accounts') IS NOT NULL")
+		if err != nil {
+			return fmt.Errorf("failed to check for the pgbench tables: %w", err)
+		}
+		if len(rows) > 0 && rows[0] == "t" {
+			return nil
+		}
+	}
+	if !cfg.JSON {
+		logging.Infof(o.output, "Initializing the pgbench tables in %s at scale %d...", database, cfg.Scale)
+	}
+	var out bytes.Buffer
+	err := o.docker.RunCommandWithIO(ctx, nil, &out, &out,
+		"exec", name, "pgbench", "-U", user, "-i", "-q", "-s", strconv.Itoa(cfg.Scale), database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize the pgbench tables: %s: %w", strings.TrimSpace(out.String()), err)
+	}
+	return nil
+}
+
+// benchOutputPatterns match the summary lines pgbench prints after a run.
+var benchOutputPatterns = map[string]*regexp.Regexp{
+	"scale":        regexp.MustCompile(`(?m)^scaling factor: (\d+)`),
+	"transactions": regexp.MustCompile(`(?m)^number of transactions actually processed: (\d+)`),
+	"failed":       regexp.MustCompile(`(?m)^number of failed transactions: (\d+)`),
+	"latency":      regexp.MustCompile(`(?m)^latency average = ([\d.]+) ms`),
+	"stddev":       regexp.MustCompile(`(?m)^latency stddev = ([\d.]+) ms`),
+	"connection":   regexp.MustCompile(`(?m)^initial connection time = ([\d.]+) ms`),
+	"tps":          regexp.MustCompile(`(?m)^tps = ([\d.]+)`),
+}
+
+// parseBenchOutput fills in result from the summary pgbench prints on stdout.
+func parseBenchOutput(output string, result *BenchResult) error {
+	values := make(map[string]string)
+	for key, pattern := range benchOutputPatterns {
+		if m := pattern.FindStringSubmatch(output); m != nil {
+			values[key] = m[1]
+		}
+	}
+	if values["tps"] == "" {
+		return fmt.Errorf("pgbench printed no summary: %s", strings.TrimSpace(output))
+	}
+	result.Scale, _ = strconv.Atoi(values["scale"])
+	result.Transactions, _ = strconv.ParseInt(values["transactions"], 10, 64)
+	result.Failed, _ = strconv.ParseInt(values["failed"], 10, 64)
+	result.TPS, _ = strconv.ParseFloat(values["tps"], 64)
+	result.LatencyAvgMs, _ = strconv.ParseFloat(values["latency"], 64)
+	result.LatencyStddevMs, _ = strconv.ParseFloat(values["stddev"], 64)
+	result.ConnectionTimeMs, _ = strconv.ParseFloat(values["connection"], 64)
+	return nil
+}
+
+// printResult prints the summary of a run as aligned text.
+func (o *BenchOrchestrator) printResult(r BenchResult) {
+	_, _ = fmt.Fprintf(o.output, "\nBenchmark of %s (database %s):\n", r.Container, r.Database)
+	line := func(label, format string, args ...any) {
+		_, _ = fmt.Fprintf(o.output, "  %-14s "+format+"\n", append([]any{label + ":"}, args...)...)
+	}
+	line("Workload", "%s", strings.Join(r.Workload, ", "))
+	if r.Scale > 0 {
+		line("Scale", "%d", r.Scale)
+	}
+	line("Clients", "%d (%d threads)", r.Clients, r.Threads)
+	line("Duration", "%ds", r.DurationSeconds)
+	line("Transactions", "%d (%d failed)", r.Transactions, r.Failed)
+	line("TPS", "%.1f", r.TPS)
+	if r.LatencyStddevMs > 0 {
+		line("Latency", "%.3f ms avg, %.3f ms stddev", r.LatencyAvgMs, r.LatencyStddevMs)
+	} else {
+		line("Latency", "%.3f ms avg", r.LatencyAvgMs)
+	}
+}
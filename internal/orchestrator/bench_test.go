@@ -0,0 +1,172 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// benchSummary is what pgbench 17 prints on stdout after a run.
+const benchSummary = `pgbench (17.2 (Debian 17.2-1.pgdg120+1))
+transaction type: <builtin: TPC-B (sort of)>
+scaling factor: 10
+query mode: simple
+number of clients: 4
+number of threads: 2
+maximum number of tries: 1
+duration: 10 s
+number of transactions actually processed: 12345
+number of failed transactions: 0 (0.000%)
+latency average = 3.240 ms
+latency stddev = 1.125 ms
+initial connection time = 5.120 ms
+tps = 1234.567890 (without initial connection time)
+`
+
+// benchMock returns a mock of a running container whose pgbench tables exist
+// when tables is set, and whose pgbench runs print benchSummary.
+func benchMock(tables bool) *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		if strings.Contains(command[len(command)-1], "to_regclass('pgbench_accounts')") && tables {
+			return "t\n", nil
+		}
+		return "f\n", nil
+	}
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		if !slices.Contains(args, "-i") {
+			_, _ = io.WriteString(stderr, "progress: 5.0 s, 1230.2 tps, lat 3.241 ms stddev 1.100, 0 failed\n")
+			_, _ = io.WriteString(stdout, benchSummary)
+		}
+		return nil
+	}
+	return mock
+}
+
+func TestBenchOrchestrator_Text(t *testing.T) {
+	mock := benchMock(true)
+	var buf bytes.Buffer
+
+	err := NewBenchOrchestrator(mock, &buf).Run(t.Context(), BenchConfig{ContainerName: "my-postgres", Database: "app", User: "postgres",
+		Scale: 1, Clients: 4, Threads: 2, Duration: 10 * time.Second})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithIO, 1, "the tables are there")
+	assert.Equal(t, []string{"exec", "my-postgres", "pgbench", "-U", "postgres", "-c", "4", "-j", "2", "-T", "10",
+		"-b", "tpcb-like", "-P", "5", "app"}, mock.Calls.RunCommandWithIO[0])
+	out := buf.String()
+	assert.Contains(t, out, "Running tpcb-like on my-postgres for 10s with 4 clients and 2 threads...")
+	assert.Contains(t, out, "progress: 5.0 s, 1230.2 tps")
+	assert.Contains(t, out, "Transactions:  12345 (0 failed)")
+	assert.Contains(t, out, "TPS:           1234.6")
+	assert.Contains(t, out, "Latency:       3.240 ms avg, 1.125 ms stddev")
+	assert.NotContains(t, out, "latency average", "pgbench's own summary is replaced")
+}
+
+func TestBenchOrchestrator_InitializesTables(t *testing.T) {
+	mock := benchMock(false)
+	var buf bytes.Buffer
+
+	err := NewBenchOrchestrator(mock, &buf).Run(t.Context(), BenchConfig{ContainerName: "my-postgres", Database: "app", User: "postgres",
+		Scale: 10, Clients: 1, Threads: 1, Duration: time.Minute, Builtin: "select-only"})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithIO, 2)
+	assert.Equal(t, []string{"exec", "my-postgres", "pgbench", "-U", "postgres", "-i", "-q", "-s", "10", "app"}, mock.Calls.RunCommandWithIO[0])
+	assert.Contains(t, mock.Calls.RunCommandWithIO[1], "select-only")
+	assert.Contains(t, mock.Calls.RunCommandWithIO[1], "60")
+	assert.Contains(t, buf.String(), "Initializing the pgbench tables in app at scale 10...")
+
+	mock = benchMock(true)
+	err = NewBenchOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), BenchConfig{ContainerName: "my-postgres", Scale: 5, Clients: 1, Threads: 1,
+		Duration: time.Second, Init: true})
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithIO[0], "-i", "--init recreates existing tables")
+	for _, call := range mock.Calls.ExecCommand {
+		assert.NotContains(t, call.Command[len(call.Command)-1], "to_regclass", "--init does not check for the tables")
+	}
+}
+
+func TestBenchOrchestrator_Scripts(t *testing.T) {
+	dir := t.TempDir()
+	read := filepath.Join(dir, "read.sql")
+	require.NoError(t, os.WriteFile(read, []byte("SELECT 1;\n"), 0644))
+	write := filepath.Join(dir, "write.sql")
+	require.NoError(t, os.WriteFile(write, []byte("SELECT 2;\n"), 0644))
+	mock := benchMock(false)
+
+	err := NewBenchOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), BenchConfig{ContainerName: "my-postgres", Scale: 1, Clients: 2, Threads: 1,
+		Duration: 5 * time.Second, Scripts: []string{read + "@9", write}})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithOutput, 2)
+	assert.Equal(t, []string{"cp", read}, mock.Calls.RunCommandWithOutput[0][:2])
+	remote := strings.TrimPrefix(mock.Calls.RunCommandWithOutput[0][2], "my-postgres:")
+	require.Len(t, mock.Calls.RunCommandWithIO, 1, "custom scripts do not need the pgbench tables")
+	run := mock.Calls.RunCommandWithIO[0]
+	assert.Contains(t, run, remote+"@9")
+	assert.NotContains(t, run, "-b")
+	var removed []string
+	for _, call := range mock.Calls.ExecCommand {
+		if call.Command[0] == "rm" {
+			removed = append(removed, call.Command[2])
+		}
+	}
+	assert.Contains(t, removed, remote, "copied scripts are removed afterwards")
+}
+
+func TestBenchOrchestrator_JSON(t *testing.T) {
+	mock := benchMock(true)
+	var buf bytes.Buffer
+
+	err := NewBenchOrchestrator(mock, &buf).Run(t.Context(), BenchConfig{ContainerName: "my-postgres", Database: "app", Scale: 1, Clients: 4, Threads: 2,
+		Duration: 10 * time.Second, JSON: true})
+
+	require.NoError(t, err)
+	assert.NotContains(t, mock.Calls.RunCommandWithIO[0], "-P", "no progress in JSON mode")
+	var result BenchResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &result), buf.String())
+	assert.Equal(t, BenchResult{Container: "my-postgres", Database: "app", Workload: []string{"tpcb-like"}, Scale: 10, Clients: 4, Threads: 2,
+		DurationSeconds: 10, Transactions: 12345, TPS: 1234.56789, LatencyAvgMs: 3.24, LatencyStddevMs: 1.125, ConnectionTimeMs: 5.12}, result)
+}
+
+func TestBenchOrchestrator_InvalidConfig(t *testing.T) {
+	valid := BenchConfig{ContainerName: "my-postgres", Scale: 1, Clients: 2, Threads: 1, Duration: time.Second}
+	for want, change := range map[string]func(*BenchConfig){
+		"--clients must be at least 1":                func(c *BenchConfig) { c.Clients = 0 },
+		"--threads (4) must not exceed --clients (2)": func(c *BenchConfig) { c.Threads = 4 },
+		"--time must be at least 1s":                  func(c *BenchConfig) { c.Duration = 500 * time.Millisecond },
+		`invalid builtin "tpcc"`:                      func(c *BenchConfig) { c.Builtin = "tpcc" },
+		"failed to read script":                       func(c *BenchConfig) { c.Scripts = []string{"missing.sql@2"} },
+		"--builtin and --script cannot be combined":   func(c *BenchConfig) { c.Builtin = "select-only"; c.Scripts = []string{"x.sql"} },
+		"--scale must be at least 1":                  func(c *BenchConfig) { c.Scale = 0 },
+	} {
+		cfg := valid
+		change(&cfg)
+		mock := benchMock(true)
+
+		err := NewBenchOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), cfg)
+
+		assert.ErrorContains(t, err, want)
+		assert.Empty(t, mock.Calls.RunCommandWithIO, want)
+	}
+}
+
+func TestParseBenchOutput_NoSummary(t *testing.T) {
+	var result BenchResult
+
+	err := parseBenchOutput("pgbench: error: connection to server failed\n", &result)
+
+	assert.EqualError(t, err, "pgbench printed no summary: pgbench: error: connection to server failed")
+}
@@ -0,0 +1,165 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// CheckConfig holds configuration for the check commands.
+type CheckConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Threshold     float64 // Percent of the usable range at which a sequence is reported
+}
+
+// CheckOrchestrator runs health checks against a database in a running container.
+type CheckOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewCheckOrchestrator creates a new CheckOrchestrator.
+func NewCheckOrchestrator(d docker.Docker, w io.Writer) *CheckOrchestrator {
+	return &CheckOrchestrator{docker: d, output: w}
+}
+
+// SequenceRisk describes a sequence that is close to running out, or that
+// can produce values its column cannot store.
+type SequenceRisk struct {
+	Sequence  string
+	Column    string // schema.table.column owning the sequence, if any
+	Kind      string // identity, serial or empty for a standalone sequence
+	Type      string // Column type
+	LastValue int64
+	Limit     int64   // Smaller of the sequence maximum and the column type maximum
+	Percent   float64 // LastValue as a percentage of Limit
+	Mismatch  bool    // Sequence maximum exceeds what the column type can hold
+}
+
+// sequenceQuery lists ascending sequences with the column that owns them.
+const sequenceQuery = `SELECT s.schemaname || '.' || s.sequencename,
+  COALESCE(s.last_value, 0), s.max_value,
+  COALESCE(tn.nspname || '.' || t.relname || '.' || a.attname, ''),
+  COALESCE(format_type(a.atttypid, a.atttypmod), ''),
+  CASE WHEN a.attidentity IN ('a', 'd') THEN 'identity' WHEN d.deptype = 'a' THEN 'serial' ELSE '' END
+FROM pg_sequences s
+JOIN pg_namespace n ON n.nspname = s.schemaname
+JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = s.sequencename
+LEFT JOIN pg_depend d ON d.objid = c.oid AND d.classid = 'pg_class'::regclass
+  AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+LEFT JOIN pg_class t ON t.oid = d.refobjid
+LEFT JOIN pg_namespace tn ON tn.oid = t.relnamespace
+LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+WHERE s.increment_by > 0
+ORDER BY 1`
+
+// integerTypeMax is the largest value each integer column type can store.
+var integerTypeMax = map[string]int64{
+	"smallint": 32767,
+	"integer":  2147483647,
+	"bigint":   9223372036854775807,
+}
+
+// Sequences reports sequences and identity/serial columns that have used at
+// least cfg.Threshold percent of their range, and serial columns whose type
+// is smaller than their sequence. It returns an error when any are found so
+// it can gate CI.
+func (o *CheckOrchestrator) Sequences(ctx context.Context, cfg CheckConfig) error {
+	if cfg.Threshold <= 0 || cfg.Threshold > 100 {
+		return fmt.Errorf("invalid threshold %g (must be between 0 and 100)", cfg.Threshold)
+	}
+	name, user, database, err := o.resolve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	rows, err := QueryLines(ctx, o.docker, name, user, database, sequenceQuery)
+	if err != nil {
+		return fmt.Errorf("failed to list sequences: %w", err)
+	}
+
+	var risks []SequenceRisk
+	for _, row := range rows {
+		risk, ok := parseSequenceRisk(row)
+		if ok && (risk.Percent >= cfg.Threshold || risk.Mismatch) {
+			risks = append(risks, risk)
+		}
+	}
+
+	if len(risks) == 0 {
+		_, _ = fmt.Fprintf(o.output, "No sequences in %s have used %g%% or more of their range (%d checked)\n", database, cfg.Threshold, len(rows))
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Sequences at risk in %s:\n", database)
+	for _, risk := range risks {
+		owner := risk.Sequence
+		if risk.Column != "" {
+			owner = fmt.Sprintf("%s (%s %s, %s)", risk.Column, risk.Kind, risk.Type, risk.Sequence)
+		}
+		_, _ = fmt.Fprintf(o.output, "  %s: %d of %d used (%.1f%%)\n", owner, risk.LastValue, risk.Limit, risk.Percent)
+		if risk.Mismatch {
+			_, _ = fmt.Fprintf(o.output, "    sequence can exceed the %s column; inserts fail at %d\n", risk.Type, risk.Limit)
+		}
+		if risk.Type == "integer" || risk.Type == "smallint" {
+			_, _ = fmt.Fprintf(o.output, "    fix: ALTER TABLE ... ALTER COLUMN ... TYPE bigint (and ALTER SEQUENCE %s AS bigint)\n", risk.Sequence)
+		}
+	}
+	return fmt.Errorf("%d sequences at risk of overflow", len(risks))
+}
+
+// resolve finds the running container and the credentials to connect with.
+func (o *CheckOrchestrator) resolve(ctx context.Context, cfg CheckConfig) (name, user, database string, err error) {
+	name, _, err = ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return "", "", "", fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	user, database = ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+	return name, user, database, nil
+}
+
+// parseSequenceRisk parses one row of sequenceQuery.
+func parseSequenceRisk(row string) (SequenceRisk, bool) {
+	fields := strings.Split(row, "\t")
+	if len(fields) != 6 {
+		return SequenceRisk{}, false
+	}
+	lastValue, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return SequenceRisk{}, false
+	}
+	maxValue, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return SequenceRisk{}, false
+	}
+
+	risk := SequenceRisk{
+		Sequence:  fields[0],
+		Column:    fields[3],
+		Type:      fields[4],
+		Kind:      fields[5],
+		LastValue: lastValue,
+		Limit:     maxValue,
+	}
+	if typeMax, ok := integerTypeMax[risk.Type]; ok && typeMax < maxValue {
+		risk.Limit = typeMax
+		risk.Mismatch = true
+	}
+	if risk.Limit > 0 {
+		risk.Percent = float64(lastValue) / float64(risk.Limit) * 100
+	}
+	return risk, true
+}
@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOrchestrator_Sequences(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "public.events_id_seq\t1900000000\t2147483647\tpublic.events.id\tinteger\tidentity\n" +
+			"public.orders_id_seq\t42\t9223372036854775807\tpublic.orders.id\tinteger\tserial\n" +
+			"public.users_id_seq\t10\t9223372036854775807\tpublic.users.id\tbigint\tidentity\n" +
+			"public.tickets\t0\t9223372036854775807\t\t\t\n", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewCheckOrchestrator(mock, &buf)
+	err := orch.Sequences(t.Context(), CheckConfig{ContainerName: "my-postgres", Database: "app", Threshold: 75})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 sequences at risk")
+	out := buf.String()
+	assert.Contains(t, out, "public.events.id (identity integer, public.events_id_seq): 1900000000 of 2147483647 used (88.5%)")
+	assert.Contains(t, out, "public.orders.id (serial integer, public.orders_id_seq): 42 of 2147483647 used")
+	assert.Contains(t, out, "sequence can exceed the integer column")
+	assert.NotContains(t, out, "public.users")
+	assert.NotContains(t, out, "public.tickets")
+}
+
+func TestCheckOrchestrator_SequencesHealthy(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "public.users_id_seq\t10\t9223372036854775807\tpublic.users.id\tbigint\tidentity\n", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewCheckOrchestrator(mock, &buf)
+	err := orch.Sequences(t.Context(), CheckConfig{ContainerName: "my-postgres", Database: "app", Threshold: 50})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No sequences in app have used 50% or more of their range (1 checked)")
+}
+
+func TestCheckOrchestrator_InvalidThreshold(t *testing.T) {
+	orch := NewCheckOrchestrator(docker.NewMockDocker(), &bytes.Buffer{})
+	err := orch.Sequences(t.Context(), CheckConfig{Threshold: 150})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid threshold")
+}
@@ -2,17 +2,58 @@ package orchestrator
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
 )
 
 // CleanConfig holds configuration for the clean command.
 type CleanConfig struct {
 	Force bool // Skip confirmation prompt
 	All   bool // Also remove PostgreSQL base images
+	// ContainerName limits the clean to one container and its data volume
+	ContainerName string
+	// Match limits the clean to containers, volumes (by their container's
+	// name) and images (by repository) matching any of these glob patterns
+	Match []string
+	// Scopes limits the clean to these kinds of resource: containers,
+	// volumes, images and temp files. Empty means all of them.
+	Scopes []string
+	JSON   bool // Print a JSON summary instead of progress messages
+	DryRun bool // Print the runtime commands instead of running them
+}
+
+// Clean scopes accepted by CleanConfig.Scopes.
+const (
+	CleanScopeContainers = "containers"
+	CleanScopeVolumes    = "volumes"
+	CleanScopeImages     = "images"
+	CleanScopeTemp       = "temp" // Files pgbox left in /tmp and generated files of removed containers
+)
+
+// CleanSummary is what clean removed, printed by clean --json.
+type CleanSummary struct {
+	Containers     []string       `json:"containers"`
+	Volumes        []string       `json:"volumes"`
+	Images         []string       `json:"images"`
+	Failed         []CleanFailure `json:"failed"`
+	ReclaimedBytes int64          `json:"reclaimed_bytes"` // Approximate; image layers may be shared
+}
+
+// CleanFailure is a resource clean could not remove.
+type CleanFailure struct {
+	Kind  string `json:"kind"` // container, volume or image
+	Name  string `json:"name"`
+	Error string `json:"error"`
 }
 
 // CleanOrchestrator handles cleaning up pgbox resources.
@@ -28,47 +69,89 @@ func NewCleanOrchestrator(d docker.Docker, w io.Writer, r io.Reader) *CleanOrche
 }
 
 // Run cleans up pgbox containers, volumes, and images.
-func (o *CleanOrchestrator) Run(cfg CleanConfig) error {
-	_, _ = fmt.Fprintln(o.output, "Searching for pgbox containers...")
-	containersOutput, err := o.docker.RunCommandWithOutput("ps", "-a", "--filter", "name=pgbox", "--format", "{{.Names}}")
-	if err != nil {
-		return fmt.Errorf("failed to list containers: %w", err)
+func (o *CleanOrchestrator) Run(ctx context.Context, cfg CleanConfig) error {
+	for _, scope := range cfg.Scopes {
+		switch scope {
+		case CleanScopeContainers, CleanScopeVolumes, CleanScopeImages, CleanScopeTemp:
+		default:
+			return fmt.Errorf("invalid scope %q (must be containers, volumes, images or temp)", scope)
+		}
+	}
+	for _, pattern := range cfg.Match {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --match pattern %q: %w", pattern, err)
+		}
+	}
+	if cfg.JSON && cfg.DryRun {
+		return fmt.Errorf("--json reports what was removed, so it cannot be combined with --dry-run")
+	}
+	if cfg.JSON && !cfg.Force {
+		return fmt.Errorf("--json cannot ask for confirmation; add --force")
+	}
+	if cfg.DryRun {
+		o.docker = docker.NewDryRun(o.docker, o.output)
+	}
+	w := o.output
+	if cfg.JSON {
+		w = io.Discard
+	}
+	wants := func(scope string) bool { return len(cfg.Scopes) == 0 || slices.Contains(cfg.Scopes, scope) }
+	// Temp files are not tied to one instance, so a clean limited by name
+	// leaves them unless asked for
+	cleanTemp := cfg.ContainerName == "" && (slices.Contains(cfg.Scopes, CleanScopeTemp) || len(cfg.Scopes) == 0 && len(cfg.Match) == 0)
+	selected := func(name string) bool {
+		if cfg.ContainerName != "" {
+			return name == cfg.ContainerName
+		}
+		return len(cfg.Match) == 0 || matchesAny(cfg.Match, name)
 	}
 
 	containers := []string{}
-	if containersOutput != "" {
+	if wants(CleanScopeContainers) {
+		logging.Infof(w, "Searching for pgbox containers...")
+		containersOutput, err := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", "name=pgbox", "--format", "{{.Names}}")
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
 		for _, line := range strings.Split(strings.TrimSpace(containersOutput), "\n") {
-			if line != "" {
+			if line != "" && selected(line) {
 				containers = append(containers, line)
 			}
 		}
 	}
 
-	_, _ = fmt.Fprintln(o.output, "Searching for pgbox volumes...")
-	volumesOutput, err := o.docker.RunCommandWithOutput("volume", "ls", "--format", "{{.Name}}")
-	if err != nil {
-		return fmt.Errorf("failed to list volumes: %w", err)
-	}
-
 	volumes := []string{}
-	if volumesOutput != "" {
+	if wants(CleanScopeVolumes) {
+		logging.Infof(w, "Searching for pgbox volumes...")
+		volumesOutput, err := o.docker.RunCommandWithOutput(ctx, "volume", "ls", "--format", "{{.Name}}")
+		if err != nil {
+			return fmt.Errorf("failed to list volumes: %w", err)
+		}
 		for _, line := range strings.Split(strings.TrimSpace(volumesOutput), "\n") {
-			if line != "" && strings.HasPrefix(line, "pgbox-") && strings.HasSuffix(line, "-data") {
+			if !strings.HasSuffix(line, "-data") || (cfg.ContainerName == "" && !strings.HasPrefix(line, "pgbox-")) {
+				continue
+			}
+			if selected(strings.TrimSuffix(line, "-data")) {
 				volumes = append(volumes, line)
 			}
 		}
 	}
 
-	_, _ = fmt.Fprintln(o.output, "Searching for pgbox images...")
-	imagesOutput, err := o.docker.RunCommandWithOutput("images", "--format", "{{.Repository}}:{{.Tag}}")
-	if err != nil {
-		return fmt.Errorf("failed to list images: %w", err)
-	}
-
 	images := []string{}
 	baseImages := []string{}
-	for _, line := range strings.Split(strings.TrimSpace(imagesOutput), "\n") {
-		if line != "" {
+	// Images are shared between instances, so a single-instance clean keeps them
+	if cfg.ContainerName == "" && wants(CleanScopeImages) {
+		logging.Infof(w, "Searching for pgbox images...")
+		imagesOutput, err := o.docker.RunCommandWithOutput(ctx, "images", "--format", "{{.Repository}}:{{.Tag}}")
+		if err != nil {
+			return fmt.Errorf("failed to list images: %w", err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(imagesOutput), "\n") {
+			repository, _, _ := strings.Cut(line, ":")
+			if line == "" || !selected(repository) {
+				continue
+			}
 			if strings.HasPrefix(line, "pgbox-") {
 				images = append(images, line)
 			} else if cfg.All && (strings.HasPrefix(line, "postgres:") || strings.HasPrefix(line, "pgvector/pgvector:")) {
@@ -77,101 +160,311 @@ func (o *CleanOrchestrator) Run(cfg CleanConfig) error {
 		}
 	}
 
-	if len(containers) == 0 && len(volumes) == 0 && len(images) == 0 && len(baseImages) == 0 {
-		_, _ = fmt.Fprintln(o.output, "No pgbox resources found to clean.")
+	plan := removalPlan{containers: containers, volumes: volumes, images: images, baseImages: baseImages}
+	if plan.empty() && !(cleanTemp && slices.Contains(cfg.Scopes, CleanScopeTemp)) {
+		if cfg.JSON {
+			return o.printSummary(CleanSummary{Containers: []string{}, Volumes: []string{}, Images: []string{}, Failed: []CleanFailure{}})
+		}
+		_, _ = fmt.Fprintln(w, "No pgbox resources found to clean.")
 		return nil
 	}
 
-	_, _ = fmt.Fprintln(o.output, "\nThe following resources will be removed:")
-	if len(containers) > 0 {
-		_, _ = fmt.Fprintf(o.output, "\nContainers (%d):\n", len(containers))
-		for _, c := range containers {
-			_, _ = fmt.Fprintf(o.output, "  - %s\n", c)
+	plan.print(w)
+	kinds := plan.kinds()
+	if cleanTemp {
+		_, _ = fmt.Fprintln(w, "\nTemporary files:")
+		_, _ = fmt.Fprintln(w, "  - /tmp/pgbox-*.sql and /tmp/pgbox-*.yml on the runtime's host")
+		_, _ = fmt.Fprintln(w, "  - generated files of containers that no longer exist")
+		kinds = append(kinds, "temporary files")
+	}
+
+	if cfg.DryRun {
+		_, _ = fmt.Fprintln(w)
+		plan.dryRun(ctx, o.docker)
+		if cleanTemp {
+			_, _ = o.docker.RunCommandWithOutput(ctx, cleanTempFilesArgs...)
+			o.pruneArtifacts(ctx, w, true)
+		}
+		_, _ = fmt.Fprintln(w, "\nDry run: nothing was removed.")
+		return nil
+	}
+
+	if !cfg.Force {
+		ok, err := confirm(w, o.input, fmt.Sprintf("\nAre you sure you want to remove these %s? (y/N): ", joinAnd(kinds)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			_, _ = fmt.Fprintln(w, "Clean cancelled.")
+			return nil
 		}
 	}
-	if len(volumes) > 0 {
-		_, _ = fmt.Fprintf(o.output, "\nVolumes (%d):\n", len(volumes))
-		for _, v := range volumes {
-			_, _ = fmt.Fprintf(o.output, "  - %s\n", v)
+
+	// Measuring runs a helper container per volume, so only do it when the
+	// summary reports the result
+	var sizes map[string]int64
+	if cfg.JSON {
+		sizes = o.measure(ctx, plan)
+	}
+	summary := plan.remove(ctx, o.docker, w)
+	for _, names := range [][]string{summary.Containers, summary.Volumes, summary.Images} {
+		for _, name := range names {
+			summary.ReclaimedBytes += sizes[name]
 		}
 	}
-	if len(images) > 0 {
-		_, _ = fmt.Fprintf(o.output, "\nImages (%d):\n", len(images))
-		for _, img := range images {
-			_, _ = fmt.Fprintf(o.output, "  - %s\n", img)
+
+	if cleanTemp {
+		logging.Infof(w, "\nCleaning temporary files...")
+		if output, err := o.docker.RunCommandWithOutput(ctx, cleanTempFilesArgs...); err != nil {
+			// Non-critical error, just warn
+			logging.Warnf(w, "could not clean temp files: %v", err)
+		} else if output != "" {
+			_, _ = fmt.Fprintf(w, "  Cleaned: %s\n", output)
 		}
+		o.pruneArtifacts(ctx, w, false)
 	}
-	if len(baseImages) > 0 {
-		_, _ = fmt.Fprintf(o.output, "\nBase Images (%d):\n", len(baseImages))
-		for _, img := range baseImages {
-			_, _ = fmt.Fprintf(o.output, "  - %s\n", img)
+
+	if cfg.JSON {
+		if err := o.printSummary(summary); err != nil {
+			return err
 		}
 	}
+	if len(summary.Failed) > 0 {
+		return fmt.Errorf("failed to remove %d of the resources", len(summary.Failed))
+	}
+	_, _ = fmt.Fprintln(w, "\nClean completed successfully.")
+	return nil
+}
 
-	if !cfg.Force {
-		_, _ = fmt.Fprint(o.output, "\nAre you sure you want to remove these resources? (y/N): ")
-		reader := bufio.NewReader(o.input)
-		response, err := reader.ReadString('\n')
+// pruneArtifacts removes the generated files of containers that no longer
+// exist, such as ones removed with docker rm, or with dryRun lists them.
+func (o *CleanOrchestrator) pruneArtifacts(ctx context.Context, w io.Writer, dryRun bool) {
+	names, err := config.ListArtifacts()
+	if err != nil || len(names) == 0 {
+		return
+	}
+	out, err := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--format", "{{.Names}}")
+	if err != nil {
+		return
+	}
+	existing := strings.Fields(out)
+	for _, name := range names {
+		if slices.Contains(existing, name) {
+			continue
+		}
+		dir, err := config.ArtifactsDir(name)
 		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			continue
 		}
-		response = strings.TrimSpace(response)
-		if response != "y" && response != "Y" {
-			_, _ = fmt.Fprintln(o.output, "Clean cancelled.")
-			return nil
+		if dryRun {
+			_, _ = fmt.Fprintf(w, "Would remove %s\n", dir)
+			continue
+		}
+		if err := config.RemoveArtifacts(name); err != nil {
+			logging.Warnf(w, "%v", err)
+		} else {
+			_, _ = fmt.Fprintf(w, "  Removed %s\n", dir)
+		}
+	}
+}
+
+// cleanTempFilesArgs removes the init SQL and compose files pgbox left in /tmp.
+var cleanTempFilesArgs = []string{"run", "--rm", "-v", "/tmp:/tmp", "alpine", "sh", "-c", "rm -f /tmp/pgbox-*.sql /tmp/pgbox-*.yml"}
+
+// printSummary writes the summary as indented JSON.
+func (o *CleanOrchestrator) printSummary(summary CleanSummary) error {
+	enc := json.NewEncoder(o.output)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
+
+// measure returns the disk space of each planned resource that can be
+// measured: a container's writable layer, the files in a volume and an
+// image's size. Resources that cannot be measured count as zero.
+func (o *CleanOrchestrator) measure(ctx context.Context, p removalPlan) map[string]int64 {
+	sizes := make(map[string]int64)
+	parse := func(name, output string, unit int64) {
+		fields := strings.Fields(output)
+		if len(fields) == 0 {
+			return
+		}
+		if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			sizes[name] = n * unit
+		}
+	}
+	for _, c := range p.containers {
+		output, _ := o.docker.RunCommandWithOutput(ctx, "inspect", "--size", "-f", "{{.SizeRw}}", c)
+		parse(c, output, 1)
+	}
+	for _, v := range p.volumes {
+		output, _ := o.docker.RunCommandWithOutput(ctx, "run", "--rm", "-v", v+":/v:ro", volumeHelperImage, "du", "-sk", "/v")
+		parse(v, output, 1024)
+	}
+	for _, img := range append(append([]string{}, p.images...), p.baseImages...) {
+		output, _ := o.docker.RunCommandWithOutput(ctx, "image", "inspect", "-f", "{{.Size}}", img)
+		parse(img, output, 1)
+	}
+	return sizes
+}
+
+// matchesAny reports whether name matches one of the glob patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(containers) > 0 {
-		_, _ = fmt.Fprintln(o.output, "\nRemoving containers...")
-		for _, container := range containers {
-			_, _ = fmt.Fprintf(o.output, "  Removing %s...", container)
-			if err := o.docker.RemoveContainer(container); err != nil {
-				_, _ = fmt.Fprintf(o.output, " failed: %v\n", err)
+// removalPlan lists pgbox resources to remove. It is shared by clean and
+// down --rm.
+type removalPlan struct {
+	containers []string
+	volumes    []string
+	images     []string
+	baseImages []string
+}
+
+// empty reports whether the plan has nothing to remove.
+func (p removalPlan) empty() bool {
+	return len(p.containers) == 0 && len(p.volumes) == 0 && len(p.images) == 0 && len(p.baseImages) == 0
+}
+
+// kinds names the kinds of resource the plan removes, for a confirmation
+// prompt.
+func (p removalPlan) kinds() []string {
+	var kinds []string
+	if len(p.containers) > 0 {
+		kinds = append(kinds, "containers")
+	}
+	if len(p.volumes) > 0 {
+		kinds = append(kinds, "data volumes")
+	}
+	if len(p.images) > 0 || len(p.baseImages) > 0 {
+		kinds = append(kinds, "images")
+	}
+	return kinds
+}
+
+// joinAnd joins words as a list in a sentence: a, b and c.
+func joinAnd(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// print lists the resources the plan will remove.
+func (p removalPlan) print(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "\nThe following resources will be removed:")
+	if len(p.containers) > 0 {
+		_, _ = fmt.Fprintf(w, "\nContainers (%d):\n", len(p.containers))
+		for _, c := range p.containers {
+			_, _ = fmt.Fprintf(w, "  - %s\n", c)
+		}
+	}
+	if len(p.volumes) > 0 {
+		_, _ = fmt.Fprintf(w, "\nVolumes (%d):\n", len(p.volumes))
+		for _, v := range p.volumes {
+			_, _ = fmt.Fprintf(w, "  - %s\n", v)
+		}
+	}
+	if len(p.images) > 0 {
+		_, _ = fmt.Fprintf(w, "\nImages (%d):\n", len(p.images))
+		for _, img := range p.images {
+			_, _ = fmt.Fprintf(w, "  - %s\n", img)
+		}
+	}
+	if len(p.baseImages) > 0 {
+		_, _ = fmt.Fprintf(w, "\nBase Images (%d):\n", len(p.baseImages))
+		for _, img := range p.baseImages {
+			_, _ = fmt.Fprintf(w, "  - %s\n", img)
+		}
+	}
+}
+
+// remove deletes the planned resources, reporting each one, and returns what
+// was removed. Failures are reported but do not stop the remaining removals.
+func (p removalPlan) remove(ctx context.Context, d docker.Docker, w io.Writer) CleanSummary {
+	summary := CleanSummary{Containers: []string{}, Volumes: []string{}, Images: []string{}, Failed: []CleanFailure{}}
+	failed := func(kind, name string, err error) {
+		_, _ = fmt.Fprintf(w, " failed: %v\n", err)
+		summary.Failed = append(summary.Failed, CleanFailure{Kind: kind, Name: name, Error: err.Error()})
+	}
+
+	if len(p.containers) > 0 {
+		_, _ = fmt.Fprintln(w, "\nRemoving containers...")
+		for _, container := range p.containers {
+			_, _ = fmt.Fprintf(w, "  Removing %s...", container)
+			if err := d.RemoveContainer(ctx, container); err != nil {
+				failed("container", container, err)
 			} else {
-				_, _ = fmt.Fprintln(o.output, " done")
+				_, _ = fmt.Fprintln(w, " done")
+				summary.Containers = append(summary.Containers, container)
+				// Its init SQL is only mounted by this container
+				_ = config.RemoveArtifacts(container)
 			}
 		}
 	}
 
-	if len(volumes) > 0 {
-		_, _ = fmt.Fprintln(o.output, "\nRemoving volumes...")
-		for _, volume := range volumes {
-			_, _ = fmt.Fprintf(o.output, "  Removing %s...", volume)
-			if _, err := o.docker.RunCommandWithOutput("volume", "rm", volume); err != nil {
-				_, _ = fmt.Fprintf(o.output, " failed: %v\n", err)
+	if len(p.volumes) > 0 {
+		_, _ = fmt.Fprintln(w, "\nRemoving volumes...")
+		for _, volume := range p.volumes {
+			_, _ = fmt.Fprintf(w, "  Removing %s...", volume)
+			if _, err := d.RunCommandWithOutput(ctx, "volume", "rm", volume); err != nil {
+				failed("volume", volume, err)
 			} else {
-				_, _ = fmt.Fprintln(o.output, " done")
+				_, _ = fmt.Fprintln(w, " done")
+				summary.Volumes = append(summary.Volumes, volume)
+				// A generated password is only valid for the volume it initialized
+				_ = config.RemoveContainerState(strings.TrimSuffix(volume, "-data"))
 			}
 		}
 	}
 
-	allImages := append(images, baseImages...)
+	allImages := append(append([]string{}, p.images...), p.baseImages...)
 	if len(allImages) > 0 {
-		_, _ = fmt.Fprintln(o.output, "\nRemoving images...")
+		_, _ = fmt.Fprintln(w, "\nRemoving images...")
 		for _, image := range allImages {
-			_, _ = fmt.Fprintf(o.output, "  Removing %s...", image)
-			if _, err := o.docker.RunCommandWithOutput("rmi", image); err != nil {
+			_, _ = fmt.Fprintf(w, "  Removing %s...", image)
+			if _, err := d.RunCommandWithOutput(ctx, "rmi", image); err != nil {
 				// Try force remove if normal remove fails
-				if _, err := o.docker.RunCommandWithOutput("rmi", "-f", image); err != nil {
-					_, _ = fmt.Fprintf(o.output, " failed: %v\n", err)
+				if _, err := d.RunCommandWithOutput(ctx, "rmi", "-f", image); err != nil {
+					failed("image", image, err)
 				} else {
-					_, _ = fmt.Fprintln(o.output, " done (forced)")
+					_, _ = fmt.Fprintln(w, " done (forced)")
+					summary.Images = append(summary.Images, image)
 				}
 			} else {
-				_, _ = fmt.Fprintln(o.output, " done")
+				_, _ = fmt.Fprintln(w, " done")
+				summary.Images = append(summary.Images, image)
 			}
 		}
 	}
+	return summary
+}
 
-	_, _ = fmt.Fprintln(o.output, "\nCleaning temporary files...")
-	if output, err := o.docker.RunCommandWithOutput("run", "--rm", "-v", "/tmp:/tmp", "alpine", "sh", "-c", "rm -f /tmp/pgbox-*.sql /tmp/pgbox-*.yml"); err != nil {
-		// Non-critical error, just warn
-		_, _ = fmt.Fprintf(o.output, "  Warning: Could not clean temp files: %v\n", err)
-	} else if output != "" {
-		_, _ = fmt.Fprintf(o.output, "  Cleaned: %s\n", output)
+// dryRun passes the plan's removals to d, a docker.DryRun, which prints them.
+func (p removalPlan) dryRun(ctx context.Context, d docker.Docker) {
+	for _, c := range p.containers {
+		_ = d.RemoveContainer(ctx, c)
 	}
+	for _, v := range p.volumes {
+		_, _ = d.RunCommandWithOutput(ctx, "volume", "rm", v)
+	}
+	for _, image := range append(append([]string{}, p.images...), p.baseImages...) {
+		_, _ = d.RunCommandWithOutput(ctx, "rmi", image)
+	}
+}
 
-	_, _ = fmt.Fprintln(o.output, "\nClean completed successfully.")
-	return nil
+// confirm asks question on w and reports whether the answer read from r is yes.
+func confirm(w io.Writer, r io.Reader, question string) (bool, error) {
+	_, _ = fmt.Fprint(w, question)
+	response, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+	response = strings.TrimSpace(response)
+	return response == "y" || response == "Y", nil
 }
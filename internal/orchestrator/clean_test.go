@@ -2,12 +2,16 @@ package orchestrator
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCleanOrchestrator_NoResources(t *testing.T) {
@@ -19,7 +23,7 @@ func TestCleanOrchestrator_NoResources(t *testing.T) {
 	input := strings.NewReader("")
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: true})
+	err := orch.Run(t.Context(), CleanConfig{Force: true})
 
 	assert.NoError(t, err)
 	assert.Contains(t, buf.String(), "No pgbox resources found to clean")
@@ -39,7 +43,7 @@ func TestCleanOrchestrator_RemovesContainers(t *testing.T) {
 	input := strings.NewReader("")
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: true})
+	err := orch.Run(t.Context(), CleanConfig{Force: true})
 
 	assert.NoError(t, err)
 	assert.Len(t, mock.Calls.RemoveContainer, 2)
@@ -60,7 +64,7 @@ func TestCleanOrchestrator_RemovesVolumes(t *testing.T) {
 	input := strings.NewReader("")
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: true})
+	err := orch.Run(t.Context(), CleanConfig{Force: true})
 
 	assert.NoError(t, err)
 	// Should have called volume rm for the two pgbox volumes
@@ -86,7 +90,7 @@ func TestCleanOrchestrator_RemovesImages(t *testing.T) {
 	input := strings.NewReader("")
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: true})
+	err := orch.Run(t.Context(), CleanConfig{Force: true})
 
 	assert.NoError(t, err)
 	// Should have called rmi for the two pgbox images
@@ -112,7 +116,7 @@ func TestCleanOrchestrator_AllFlag_IncludesBaseImages(t *testing.T) {
 	input := strings.NewReader("")
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: true, All: true})
+	err := orch.Run(t.Context(), CleanConfig{Force: true, All: true})
 
 	assert.NoError(t, err)
 	// Should have called rmi for pgbox image AND postgres base images
@@ -138,7 +142,7 @@ func TestCleanOrchestrator_ConfirmationRequired(t *testing.T) {
 	input := strings.NewReader("n\n") // User says no
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: false})
+	err := orch.Run(t.Context(), CleanConfig{Force: false})
 
 	assert.NoError(t, err)
 	assert.Contains(t, buf.String(), "Are you sure")
@@ -158,7 +162,7 @@ func TestCleanOrchestrator_ConfirmationAccepted(t *testing.T) {
 	input := strings.NewReader("y\n") // User says yes
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: false})
+	err := orch.Run(t.Context(), CleanConfig{Force: false})
 
 	assert.NoError(t, err)
 	assert.Len(t, mock.Calls.RemoveContainer, 1)
@@ -176,8 +180,258 @@ func TestCleanOrchestrator_ListContainersFails(t *testing.T) {
 	input := strings.NewReader("")
 
 	orch := NewCleanOrchestrator(mock, &buf, input)
-	err := orch.Run(CleanConfig{Force: true})
+	err := orch.Run(t.Context(), CleanConfig{Force: true})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to list containers")
 }
+
+func TestCleanOrchestrator_SingleInstance(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch args[0] {
+		case "ps":
+			return "pgbox-shop\npgbox-blog", nil
+		case "volume":
+			return "pgbox-shop-data\npgbox-blog-data", nil
+		case "images":
+			return "pgbox-pg17-custom:abc", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewCleanOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), CleanConfig{Force: true, ContainerName: "pgbox-shop"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-shop"}, mock.Calls.RemoveContainer)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-shop-data"})
+	assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-blog-data"})
+	for _, call := range mock.Calls.RunCommandWithOutput {
+		assert.NotEqual(t, "images", call[0])
+		assert.NotEqual(t, "rmi", call[0])
+		assert.NotEqual(t, "run", call[0])
+	}
+}
+
+func TestCleanOrchestrator_MatchScopeJSON(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch {
+		case args[0] == "ps":
+			return "pgbox-ci-1-a\npgbox-ci-1-b\npgbox-pg17", nil
+		case args[0] == "volume" && args[1] == "ls":
+			return "pgbox-ci-1-a-data\npgbox-pg17-data", nil
+		case args[0] == "inspect":
+			return "2048\n", nil
+		case args[0] == "run":
+			return "3\t/v\n", nil
+		}
+		return "", nil
+	}
+	mock.RemoveContainerFunc = func(name string) error {
+		if name == "pgbox-ci-1-b" {
+			return errors.New("removal in progress")
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+
+	err := NewCleanOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), CleanConfig{
+		Force:  true,
+		Match:  []string{"pgbox-ci-1-*"},
+		Scopes: []string{CleanScopeContainers},
+		JSON:   true,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to remove 1 of the resources")
+	assert.Equal(t, []string{"pgbox-ci-1-a", "pgbox-ci-1-b"}, mock.Calls.RemoveContainer)
+	for _, call := range mock.Calls.RunCommandWithOutput {
+		assert.NotEqual(t, []string{"volume", "ls", "--format", "{{.Name}}"}, call, "volumes are out of scope")
+		assert.NotEqual(t, "images", call[0])
+	}
+
+	var summary CleanSummary
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &summary), buf.String())
+	assert.Equal(t, []string{"pgbox-ci-1-a"}, summary.Containers)
+	assert.Empty(t, summary.Volumes)
+	assert.Equal(t, []CleanFailure{{Kind: "container", Name: "pgbox-ci-1-b", Error: "removal in progress"}}, summary.Failed)
+	assert.Equal(t, int64(2048), summary.ReclaimedBytes)
+}
+
+func TestCleanOrchestrator_MatchVolumes(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "volume" && args[1] == "ls" {
+			return "pgbox-ci-1-a-data\npgbox-pg17-data", nil
+		}
+		if args[0] == "run" {
+			return "3\t/v\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	err := NewCleanOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), CleanConfig{
+		Force:  true,
+		Match:  []string{"pgbox-ci-*"},
+		Scopes: []string{CleanScopeVolumes},
+		JSON:   true,
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-ci-1-a-data"})
+	assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg17-data"})
+	assert.Empty(t, mock.Calls.RemoveContainer)
+	assert.Contains(t, buf.String(), `"reclaimed_bytes": 3072`)
+}
+
+func TestCleanOrchestrator_JSONNeedsForce(t *testing.T) {
+	err := NewCleanOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}, strings.NewReader("")).Run(t.Context(), CleanConfig{JSON: true})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "add --force")
+}
+
+func TestCleanOrchestrator_InvalidMatch(t *testing.T) {
+	err := NewCleanOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}, strings.NewReader("")).Run(t.Context(), CleanConfig{Force: true, Match: []string{"pgbox-["}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid --match pattern "pgbox-["`)
+}
+
+func TestCleanOrchestrator_DryRun(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch args[0] {
+		case "ps":
+			return "pgbox-pg17", nil
+		case "volume":
+			return "pgbox-pg17-data", nil
+		case "images":
+			return "pgbox-pg17-custom:abc123", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	err := NewCleanOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), CleanConfig{DryRun: true})
+
+	require.NoError(t, err)
+	assert.Empty(t, mock.Calls.RemoveContainer)
+	for _, call := range mock.Calls.RunCommandWithOutput {
+		assert.Contains(t, []string{"ps", "volume", "images"}, call[0])
+		assert.NotEqual(t, "rm", call[1])
+	}
+	out := buf.String()
+	assert.Contains(t, out, "Containers (1):\n  - pgbox-pg17\n")
+	assert.Contains(t, out, "Would run: docker rm -f pgbox-pg17\nWould run: docker volume rm pgbox-pg17-data\nWould run: docker rmi pgbox-pg17-custom:abc123\n")
+	assert.Contains(t, out, "Would run: docker run --rm -v /tmp:/tmp alpine sh -c 'rm -f /tmp/pgbox-*.sql /tmp/pgbox-*.yml'\n")
+	assert.Contains(t, out, "Dry run: nothing was removed.")
+	assert.NotContains(t, out, "Are you sure")
+
+	err = NewCleanOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), CleanConfig{DryRun: true, JSON: true, Force: true})
+	assert.EqualError(t, err, "--json reports what was removed, so it cannot be combined with --dry-run")
+}
+
+func TestCleanOrchestrator_RemovesGeneratedFiles(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	for _, name := range []string{"pgbox-pg17", "pgbox-gone", "my-postgres"} {
+		_, err := config.WriteArtifact(name, "init.sql", initSQLMountPath, []byte("SELECT 1;\n"))
+		require.NoError(t, err)
+	}
+	mock := docker.NewMockDocker()
+	removed := false
+	mock.RemoveContainerFunc = func(string) error {
+		removed = true
+		return nil
+	}
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch {
+		case args[0] != "ps":
+		case slices.Contains(args, "name=pgbox"):
+			return "pgbox-pg17\n", nil
+		case removed:
+			return "my-postgres\n", nil
+		default:
+			return "pgbox-pg17\nmy-postgres\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	err := NewCleanOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), CleanConfig{Force: true})
+
+	require.NoError(t, err)
+	names, err := config.ListArtifacts()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my-postgres"}, names, "files of a container clean did not remove are kept")
+	gone, err := config.ArtifactsDir("pgbox-gone")
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "  Removed "+gone+"\n")
+}
+
+func TestCleanOrchestrator_Scopes(t *testing.T) {
+	newMock := func() *docker.MockDocker {
+		mock := docker.NewMockDocker()
+		mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+			switch args[0] {
+			case "ps":
+				return "pgbox-pg17", nil
+			case "volume":
+				return "pgbox-pg17-data", nil
+			case "images":
+				return "pgbox-pg17-custom:abc123", nil
+			}
+			return "", nil
+		}
+		return mock
+	}
+
+	t.Run("images and temp files keep containers and volumes", func(t *testing.T) {
+		mock := newMock()
+		var buf bytes.Buffer
+
+		err := NewCleanOrchestrator(mock, &buf, strings.NewReader("y\n")).Run(t.Context(), CleanConfig{Scopes: []string{CleanScopeImages, CleanScopeTemp}})
+
+		require.NoError(t, err)
+		assert.Empty(t, mock.Calls.RemoveContainer)
+		assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg17-data"})
+		assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"rmi", "pgbox-pg17-custom:abc123"})
+		assert.Contains(t, mock.Calls.RunCommandWithOutput, cleanTempFilesArgs)
+		assert.Contains(t, buf.String(), "Are you sure you want to remove these images and temporary files? (y/N): ")
+	})
+
+	t.Run("containers and volumes leave temp files", func(t *testing.T) {
+		mock := newMock()
+		var buf bytes.Buffer
+
+		err := NewCleanOrchestrator(mock, &buf, strings.NewReader("y\n")).Run(t.Context(), CleanConfig{Scopes: []string{CleanScopeContainers, CleanScopeVolumes}})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.RemoveContainer)
+		assert.NotContains(t, mock.Calls.RunCommandWithOutput, cleanTempFilesArgs)
+		assert.Contains(t, buf.String(), "Are you sure you want to remove these containers and data volumes? (y/N): ")
+		assert.NotContains(t, buf.String(), "Temporary files")
+	})
+
+	t.Run("temp files alone", func(t *testing.T) {
+		mock := newMock()
+		var buf bytes.Buffer
+
+		err := NewCleanOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), CleanConfig{Scopes: []string{CleanScopeTemp}, DryRun: true})
+
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Temporary files:\n")
+		assert.Contains(t, buf.String(), "Would run: docker run --rm -v /tmp:/tmp alpine")
+		assert.NotContains(t, buf.String(), "Would run: docker rmi")
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		err := NewCleanOrchestrator(newMock(), &bytes.Buffer{}, nil).Run(t.Context(), CleanConfig{Scopes: []string{"networks"}})
+
+		assert.EqualError(t, err, `invalid scope "networks" (must be containers, volumes, images or temp)`)
+	})
+}
@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// CpConfig holds configuration for the cp command. Exactly one of Source and
+// Destination is a container path, written with a leading ":" (e.g.
+// ":/tmp/dump.sql").
+type CpConfig struct {
+	ContainerName string
+	Source        string
+	Destination   string
+}
+
+// CpOrchestrator copies files between the host and a container.
+type CpOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewCpOrchestrator creates a new CpOrchestrator.
+func NewCpOrchestrator(d docker.Docker, w io.Writer) *CpOrchestrator {
+	return &CpOrchestrator{docker: d, output: w}
+}
+
+// Run copies cfg.Source to cfg.Destination with docker cp, putting the
+// resolved container name in front of the container path.
+func (o *CpOrchestrator) Run(ctx context.Context, cfg CpConfig) error {
+	srcInContainer := strings.HasPrefix(cfg.Source, ":")
+	dstInContainer := strings.HasPrefix(cfg.Destination, ":")
+	switch {
+	case srcInContainer && dstInContainer:
+		return errors.New("only one of the paths can be in the container")
+	case !srcInContainer && !dstInContainer:
+		return errors.New("one of the paths must be in the container; prefix it with \":\" (e.g. :/tmp/dump.sql)")
+	case cfg.Source == ":" || cfg.Destination == ":":
+		return errors.New("the container path after \":\" is empty")
+	}
+
+	name, autoDetected, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	if autoDetected {
+		logging.Infof(o.output, "Using container: %s", name)
+	}
+
+	src, dst := cfg.Source, cfg.Destination
+	if srcInContainer {
+		src = name + src
+	} else {
+		dst = name + dst
+	}
+	if out, err := o.docker.RunCommandWithOutput(ctx, "cp", src, dst); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %s: %w", src, dst, strings.TrimSpace(out), err)
+	}
+	_, _ = fmt.Fprintf(o.output, "Copied %s to %s\n", src, dst)
+	return nil
+}
@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCpOrchestrator_ToContainer(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	err := NewCpOrchestrator(mock, &buf).Run(t.Context(), CpConfig{ContainerName: "my-postgres", Source: "./data.csv", Destination: ":/tmp/data.csv"})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"cp", "./data.csv", "my-postgres:/tmp/data.csv"}}, mock.Calls.RunCommandWithOutput)
+	assert.Equal(t, "Copied ./data.csv to my-postgres:/tmp/data.csv\n", buf.String())
+}
+
+func TestCpOrchestrator_FromAutoDetectedContainer(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	var buf bytes.Buffer
+
+	err := NewCpOrchestrator(mock, &buf).Run(t.Context(), CpConfig{Source: ":/var/lib/postgresql/data/log", Destination: "."})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"cp", "pgbox-pg17:/var/lib/postgresql/data/log", "."}}, mock.Calls.RunCommandWithOutput)
+	assert.Contains(t, buf.String(), "Using container: pgbox-pg17")
+}
+
+func TestCpOrchestrator_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		src, dst, want string
+	}{
+		{"a.sql", "b.sql", "one of the paths must be in the container"},
+		{":/a.sql", ":/b.sql", "only one of the paths can be in the container"},
+		{"a.sql", ":", "the container path after \":\" is empty"},
+	} {
+		mock := docker.NewMockDocker()
+		err := NewCpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), CpConfig{ContainerName: "my-postgres", Source: tc.src, Destination: tc.dst})
+		assert.ErrorContains(t, err, tc.want)
+		assert.Empty(t, mock.Calls.RunCommandWithOutput)
+	}
+
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		return "Error response from daemon: Could not find the file /nope in container my-postgres\n", errors.New("exit status 1")
+	}
+	err := NewCpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), CpConfig{ContainerName: "my-postgres", Source: ":/nope", Destination: "."})
+	assert.EqualError(t, err, "failed to copy my-postgres:/nope to .: Error response from daemon: Could not find the file /nope in container my-postgres: exit status 1")
+}
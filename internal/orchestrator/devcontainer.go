@@ -0,0 +1,192 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/extensions"
+)
+
+const (
+	// FormatCompose exports a docker-compose.yml, Dockerfile and init SQL.
+	FormatCompose = "compose"
+	// FormatDevcontainer exports the compose service as an additional compose
+	// file under .devcontainer, with the devcontainer.json settings that attach
+	// it to an existing compose-based devcontainer.
+	FormatDevcontainer = "devcontainer"
+	// FormatDevcontainerFeature exports a .devcontainer/devcontainer.json that
+	// installs pgbox as a devcontainer feature and a pgbox.toml for it to start.
+	FormatDevcontainerFeature = "devcontainer-feature"
+
+	// devcontainerFeatureRef is the published pgbox devcontainer feature.
+	devcontainerFeatureRef = "ghcr.io/ahacop/pgbox/pgbox:1"
+	// dockerInDockerFeatureRef provides the Docker daemon pgbox runs against.
+	dockerInDockerFeatureRef = "ghcr.io/devcontainers/features/docker-in-docker:2"
+
+	// devcontainerComposeFile is the compose file added to dockerComposeFile.
+	devcontainerComposeFile = "docker-compose.pgbox.yml"
+	// devcontainerSnippetFile holds the devcontainer.json settings to merge.
+	devcontainerSnippetFile = "devcontainer.pgbox.json"
+	// devcontainerScaffoldDir holds the Dockerfile and init SQL, so they do not
+	// clash with the devcontainer's own.
+	devcontainerScaffoldDir = "pgbox"
+	// devcontainerWaitCommand waits for the db service to accept connections,
+	// using bash alone since the dev container may not have a PostgreSQL client.
+	devcontainerWaitCommand = `bash -c 'for i in $(seq 60); do (echo > /dev/tcp/db/5432) 2>/dev/null && exit 0; sleep 1; done; echo "PostgreSQL at db:5432 is not reachable" >&2; exit 1'`
+)
+
+// ExportFormats lists the values accepted by ExportConfig.Format.
+var ExportFormats = []string{FormatCompose, FormatDevcontainer, FormatDevcontainerFeature}
+
+// devcontainerFile is the subset of devcontainer.json written by pgbox.
+type devcontainerFile struct {
+	Name         string                    `json:"name"`
+	Image        string                    `json:"image"`
+	Features     map[string]map[string]any `json:"features"`
+	ForwardPorts []int                     `json:"forwardPorts,omitempty"`
+}
+
+// devcontainerSnippet is the part of devcontainer.json that attaches the
+// exported db service to a compose-based devcontainer.
+type devcontainerSnippet struct {
+	DockerComposeFile []string          `json:"dockerComposeFile"`
+	ForwardPorts      []string          `json:"forwardPorts"`
+	PostCreateCommand string            `json:"postCreateCommand"`
+	RemoteEnv         map[string]string `json:"remoteEnv"`
+}
+
+// exportDevcontainer writes the db service to .devcontainer/docker-compose.pgbox.yml,
+// its build files to .devcontainer/pgbox, and the devcontainer.json settings
+// to add it to an existing devcontainer, which pgbox cannot merge itself
+// since devcontainer.json may contain comments.
+func (o *ExportOrchestrator) exportDevcontainer(cfg ExportConfig) error {
+	devcontainerDir := filepath.Join(cfg.TargetDir, ".devcontainer")
+	composePath := filepath.Join(devcontainerDir, devcontainerComposeFile)
+	if _, _, _, err := o.writeScaffold(cfg, filepath.Join(devcontainerDir, devcontainerScaffoldDir), composePath); err != nil {
+		return err
+	}
+
+	defaults := config.NewPostgresConfig()
+	pgConfig := &config.PostgresConfig{User: defaults.User, Password: defaults.Password, Database: defaults.Database}
+	if cfg.User != "" {
+		pgConfig.User = cfg.User
+	}
+	if cfg.Password != "" {
+		pgConfig.Password = cfg.Password
+	}
+	if cfg.Database != "" {
+		pgConfig.Database = cfg.Database
+	}
+	databaseURL := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(pgConfig.User, pgConfig.Password),
+		Host:   "db:5432",
+		Path:   "/" + pgConfig.Database,
+	}
+	snippet := devcontainerSnippet{
+		DockerComposeFile: []string{"docker-compose.yml", devcontainerComposeFile},
+		ForwardPorts:      []string{"db:5432"},
+		PostCreateCommand: devcontainerWaitCommand,
+		RemoteEnv: map[string]string{
+			"DATABASE_URL": databaseURL.String(),
+		},
+	}
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snippet); err != nil {
+		return fmt.Errorf("failed to encode devcontainer settings: %w", err)
+	}
+	snippetPath := filepath.Join(devcontainerDir, devcontainerSnippetFile)
+	if err := os.WriteFile(snippetPath, content.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", snippetPath, err)
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Exported the db service to %s (build files in %s)\n",
+		composePath, filepath.Join(devcontainerDir, devcontainerScaffoldDir))
+	_, _ = fmt.Fprintf(o.output, "Wrote devcontainer.json settings to %s\n", snippetPath)
+	if cfg.EnvFile {
+		_, _ = fmt.Fprintf(o.output, "Credentials and other environment values written to %s; keep it out of version control\n",
+			filepath.Join(devcontainerDir, envFileName))
+	}
+	_, _ = fmt.Fprintf(o.output, "\nMerge them into .devcontainer/devcontainer.json, keeping your own compose files first:\n%s", content.String())
+	_, _ = fmt.Fprintf(o.output, "\nThe dev container reaches PostgreSQL at db:5432 ($DATABASE_URL); the host at localhost:%s.\n", cfg.Port)
+	return nil
+}
+
+// exportDevcontainerFeature writes a devcontainer.json that installs the pgbox
+// feature, which runs pgbox up against the pgbox.toml in the workspace when
+// the container starts. An existing pgbox.toml is left untouched.
+func (o *ExportOrchestrator) exportDevcontainerFeature(cfg ExportConfig) error {
+	if err := extensions.ValidateExtensions(cfg.Extensions); err != nil {
+		return err
+	}
+	if err := extensions.ValidateVersion(cfg.Extensions, cfg.Version); err != nil {
+		return err
+	}
+
+	devcontainerDir := filepath.Join(cfg.TargetDir, ".devcontainer")
+	if err := os.MkdirAll(devcontainerDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file := devcontainerFile{
+		Name:  "pgbox",
+		Image: "mcr.microsoft.com/devcontainers/base:ubuntu",
+		Features: map[string]map[string]any{
+			dockerInDockerFeatureRef: {},
+			devcontainerFeatureRef:   {"autoStart": true},
+		},
+	}
+	var port int
+	if _, err := fmt.Sscanf(cfg.Port, "%d", &port); err == nil {
+		file.ForwardPorts = []int{port}
+	}
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode devcontainer.json: %w", err)
+	}
+	devcontainerPath := filepath.Join(devcontainerDir, "devcontainer.json")
+	if err := os.WriteFile(devcontainerPath, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", devcontainerPath, err)
+	}
+
+	defaults := config.NewPostgresConfig()
+	pick := func(value, fallback string) string {
+		if value != "" {
+			return value
+		}
+		return fallback
+	}
+	project := config.ProjectConfig{InstanceConfig: config.InstanceConfig{
+		Version:    cfg.Version,
+		Port:       cfg.Port,
+		User:       pick(cfg.User, defaults.User),
+		Password:   pick(cfg.Password, defaults.Password),
+		Database:   pick(cfg.Database, defaults.Database),
+		Extensions: cfg.Extensions,
+	}}
+	projectPath := filepath.Join(cfg.TargetDir, config.ProjectFileName)
+	_, err = os.Stat(projectPath)
+	keptProject := err == nil
+	if !keptProject {
+		if projectPath, err = config.WriteProjectFile(cfg.TargetDir, project, false); err != nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Exported devcontainer configuration to %s\n", devcontainerPath)
+	if keptProject {
+		_, _ = fmt.Fprintf(o.output, "Using existing %s\n", projectPath)
+	} else {
+		_, _ = fmt.Fprintf(o.output, "Created %s\n", projectPath)
+	}
+	_, _ = fmt.Fprintf(o.output, "\nCommit both files; the pgbox feature runs pgbox up when the codespace starts.\n")
+	return nil
+}
@@ -1,45 +1,122 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/ahacop/pgbox/internal/container"
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
 )
 
 // DownConfig holds configuration for the down command.
 type DownConfig struct {
 	ContainerName string
+	Remove        bool // Also remove the container
+	Volumes       bool // Also remove the data volume and custom image (implies Remove)
+	Force         bool // Skip the confirmation prompt for removals
+	DryRun        bool // Print the runtime commands instead of running them
 }
 
 // DownOrchestrator handles stopping PostgreSQL containers.
 type DownOrchestrator struct {
 	docker docker.Docker
 	output io.Writer
+	input  io.Reader
 }
 
-// NewDownOrchestrator creates a new DownOrchestrator.
-func NewDownOrchestrator(d docker.Docker, w io.Writer) *DownOrchestrator {
-	return &DownOrchestrator{docker: d, output: w}
+// NewDownOrchestrator creates a new DownOrchestrator. r is read for the
+// confirmation prompt of removals.
+func NewDownOrchestrator(d docker.Docker, w io.Writer, r io.Reader) *DownOrchestrator {
+	return &DownOrchestrator{docker: d, output: w, input: r}
 }
 
 // Run stops the PostgreSQL container.
-func (o *DownOrchestrator) Run(cfg DownConfig) error {
-	name, autoDetected, err := ResolveContainerName(o.docker, cfg.ContainerName)
+func (o *DownOrchestrator) Run(ctx context.Context, cfg DownConfig) error {
+	if cfg.DryRun {
+		o.docker = docker.NewDryRun(o.docker, o.output)
+	}
+	name, autoDetected, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
 	if err != nil {
 		return fmt.Errorf("%w. Specify container name with -n flag", err)
 	}
 	if autoDetected {
-		_, _ = fmt.Fprintf(o.output, "Found running container: %s\n", name)
+		logging.Infof(o.output, "Found running container: %s", name)
+	}
+
+	var plan removalPlan
+	if cfg.Remove || cfg.Volumes {
+		if plan, err = o.teardownPlan(ctx, name, cfg.Volumes); err != nil {
+			return err
+		}
+		plan.print(o.output)
+		if !cfg.Force && !cfg.DryRun {
+			ok, err := confirm(o.output, o.input, fmt.Sprintf("\nAre you sure you want to remove these %s? (y/N): ", joinAnd(plan.kinds())))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				_, _ = fmt.Fprintln(o.output, "Down cancelled.")
+				return nil
+			}
+		}
 	}
 
-	_, _ = fmt.Fprintf(o.output, "Stopping container %s...\n", name)
+	logging.Infof(o.output, "Stopping container %s...", name)
 
-	err = o.docker.StopContainer(name)
+	err = o.docker.StopContainer(ctx, name)
 	if err != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 
-	_, _ = fmt.Fprintf(o.output, "Container %s stopped successfully\n", name)
+	if !cfg.DryRun {
+		_, _ = fmt.Fprintf(o.output, "Container %s stopped successfully\n", name)
+	}
+	o.stopSidecars(ctx, name)
+
+	if plan.empty() {
+		return nil
+	}
+	if cfg.DryRun {
+		plan.dryRun(ctx, o.docker)
+		return nil
+	}
+	plan.remove(ctx, o.docker, o.output)
 	return nil
 }
+
+// teardownPlan lists what down --rm removes for the container: the container
+// itself and its replica, if any, and, with volumes, their data volumes and
+// the custom image it runs.
+func (o *DownOrchestrator) teardownPlan(ctx context.Context, name string, volumes bool) (removalPlan, error) {
+	plan := removalPlan{containers: []string{name}}
+	replica := container.ReplicaName(name)
+	if existing, _ := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", replica), "--format", "{{.Names}}"); strings.TrimSpace(existing) == replica {
+		plan.containers = append(plan.containers, replica)
+	}
+	if !volumes {
+		return plan, nil
+	}
+
+	volumesOutput, err := o.docker.RunCommandWithOutput(ctx, "volume", "ls", "--format", "{{.Name}}")
+	if err != nil {
+		return plan, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(volumesOutput), "\n") {
+		if line == name+"-data" || line == replica+"-data" {
+			plan.volumes = append(plan.volumes, line)
+		}
+	}
+
+	// Only images pgbox built are removed; base images are shared
+	image, err := o.docker.RunCommandWithOutput(ctx, "inspect", "-f", "{{.Config.Image}}", name)
+	if err != nil {
+		return plan, fmt.Errorf("failed to inspect container %s: %s: %w", name, strings.TrimSpace(image), err)
+	}
+	if image = strings.TrimSpace(image); strings.HasPrefix(image, "pgbox-") {
+		plan.images = append(plan.images, image)
+	}
+	return plan, nil
+}
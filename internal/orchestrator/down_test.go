@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"bytes"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/ahacop/pgbox/internal/docker"
@@ -13,8 +14,8 @@ func TestDownOrchestrator_StopsNamedContainer(t *testing.T) {
 	mock := docker.NewMockDocker()
 	var buf bytes.Buffer
 
-	orch := NewDownOrchestrator(mock, &buf)
-	err := orch.Run(DownConfig{
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), DownConfig{
 		ContainerName: "my-postgres",
 	})
 
@@ -32,8 +33,8 @@ func TestDownOrchestrator_FindsRunningContainer(t *testing.T) {
 	}
 	var buf bytes.Buffer
 
-	orch := NewDownOrchestrator(mock, &buf)
-	err := orch.Run(DownConfig{})
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), DownConfig{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, mock.Calls.FindPgboxContainer)
@@ -49,8 +50,8 @@ func TestDownOrchestrator_NoContainerFound(t *testing.T) {
 	}
 	var buf bytes.Buffer
 
-	orch := NewDownOrchestrator(mock, &buf)
-	err := orch.Run(DownConfig{})
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), DownConfig{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no running pgbox container found")
@@ -64,8 +65,8 @@ func TestDownOrchestrator_StopFails(t *testing.T) {
 	}
 	var buf bytes.Buffer
 
-	orch := NewDownOrchestrator(mock, &buf)
-	err := orch.Run(DownConfig{
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), DownConfig{
 		ContainerName: "my-postgres",
 	})
 
@@ -73,3 +74,102 @@ func TestDownOrchestrator_StopFails(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to stop container")
 	assert.Contains(t, err.Error(), "docker daemon not responding")
 }
+
+func TestDownOrchestrator_RemoveAsksForConfirmation(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader("n\n"))
+	err := orch.Run(t.Context(), DownConfig{ContainerName: "pgbox-pg17", Remove: true})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "Containers (1):\n  - pgbox-pg17")
+	assert.Contains(t, buf.String(), "Down cancelled.")
+	assert.Len(t, mock.Calls.StopContainer, 0)
+	assert.Len(t, mock.Calls.RemoveContainer, 0)
+}
+
+func TestDownOrchestrator_RemoveKeepsVolume(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader("y\n"))
+	err := orch.Run(t.Context(), DownConfig{ContainerName: "pgbox-pg17", Remove: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.StopContainer)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.RemoveContainer)
+	for _, call := range mock.Calls.RunCommandWithOutput {
+		assert.NotEqual(t, "volume", call[0])
+	}
+}
+
+func TestDownOrchestrator_VolumesFullTeardown(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch args[0] {
+		case "volume":
+			if args[1] == "ls" {
+				return "pgbox-pg17-data\npgbox-pg16-data\n", nil
+			}
+		case "inspect":
+			return "pgbox-pg17-custom:abc123\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), DownConfig{ContainerName: "pgbox-pg17", Volumes: true, Force: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.RemoveContainer)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg17-data"})
+	assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg16-data"})
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"rmi", "pgbox-pg17-custom:abc123"})
+}
+
+func TestDownOrchestrator_VolumesKeepsBaseImage(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "inspect" {
+			return "postgres:17\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewDownOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), DownConfig{ContainerName: "pgbox-pg17", Volumes: true, Force: true})
+
+	assert.NoError(t, err)
+	for _, call := range mock.Calls.RunCommandWithOutput {
+		assert.NotEqual(t, "rmi", call[0])
+	}
+}
+
+func TestDownOrchestrator_DryRun(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch args[0] {
+		case "volume":
+			return "pgbox-pg17-data\n", nil
+		case "inspect":
+			return "pgbox-pg17-custom:abc123\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	err := NewDownOrchestrator(mock, &buf, strings.NewReader("")).Run(t.Context(), DownConfig{ContainerName: "pgbox-pg17", Volumes: true, DryRun: true})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mock.Calls.StopContainer)
+	assert.Empty(t, mock.Calls.RemoveContainer)
+	assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg17-data"})
+	out := buf.String()
+	assert.NotContains(t, out, "Are you sure")
+	assert.NotContains(t, out, "stopped successfully")
+	assert.Contains(t, out, "Would run: docker stop pgbox-pg17\n")
+	assert.Contains(t, out, "Would run: docker rm -f pgbox-pg17\nWould run: docker volume rm pgbox-pg17-data\nWould run: docker rmi pgbox-pg17-custom:abc123\n")
+}
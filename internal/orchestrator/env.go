@@ -0,0 +1,157 @@
+package orchestrator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/model"
+)
+
+// envFileName is the file export --env-file writes next to the compose
+// file, where compose reads it for interpolation.
+const envFileName = ".env"
+
+// credentialEnv maps the variables pgbox sets from its own flags to those
+// flags, so --env cannot set them behind pgbox's back.
+var credentialEnv = map[string]string{
+	"POSTGRES_USER":     "--user",
+	"POSTGRES_PASSWORD": "--password",
+	"POSTGRES_DB":       "--database",
+}
+
+// initdbEnvFlags maps the variables pgbox sets from --locale, --encoding,
+// --timezone and --initdb-arg to those flags.
+var initdbEnvFlags = map[string]string{
+	"POSTGRES_INITDB_ARGS": "--locale, --encoding and --initdb-arg",
+	"TZ":                   "--timezone",
+}
+
+// envNamePattern matches the variable names a shell and compose accept.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envPlainValue matches .env values that need no quotes.
+var envPlainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// checkEnv validates --env variables: names must be valid, and the
+// credentials pgbox passes itself come from their own flags.
+func checkEnv(env map[string]string) error {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !envNamePattern.MatchString(key) {
+			return fmt.Errorf("invalid --env name %q", key)
+		}
+		if flag, ok := credentialEnv[key]; ok {
+			return fmt.Errorf("--env %s is set by pgbox; use %s instead", key, flag)
+		}
+	}
+	return nil
+}
+
+// checkInitdbEnv fails when --env sets a variable that pgConfig's locale,
+// encoding, time zone or initdb arguments set too.
+func checkInitdbEnv(env map[string]string, pgConfig *config.PostgresConfig) error {
+	initdbEnv := pgConfig.InitdbEnv()
+	keys := make([]string, 0, len(initdbEnv))
+	for key := range initdbEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := env[key]; ok {
+			return fmt.Errorf("--env %s conflicts with %s; give only one of them", key, initdbEnvFlags[key])
+		}
+	}
+	return nil
+}
+
+// moveEnvToFile replaces the environment values of the database service and
+// its sidecars with ${KEY} references and returns the values, for a .env
+// file. A sidecar variable whose name the database service or another
+// sidecar already uses with a different value stays inline.
+func moveEnvToFile(m *model.ComposeModel) map[string]string {
+	values := make(map[string]string)
+	move := func(env map[string]string) {
+		for key, value := range env {
+			if prev, ok := values[key]; ok && prev != value {
+				continue
+			}
+			values[key] = value
+			env[key] = "${" + key + "}"
+		}
+	}
+	move(m.Env)
+	for _, sidecar := range m.Sidecars {
+		move(sidecar.Env)
+	}
+	return values
+}
+
+// writeEnvFile sets values in the .env file at path. Other variables and
+// comments already in the file are kept. The file holds credentials, so a
+// new one is readable by its owner only.
+func writeEnvFile(path string, values map[string]string) error {
+	var lines []string
+	seen := make(map[string]bool)
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if existing != nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if key, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "="); ok {
+				key = strings.TrimSpace(key)
+				if value, ours := values[key]; ours {
+					line = key + "=" + envFileValue(value)
+					seen[key] = true
+				}
+			}
+			lines = append(lines, line)
+		}
+		_ = existing.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	} else {
+		lines = append(lines, "# Written by pgbox export; referenced from the compose file.")
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !seen[key] {
+			lines = append(lines, key+"="+envFileValue(values[key]))
+		}
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// envFileValue quotes a .env value when compose would otherwise read it
+// differently: single quotes keep it literal, and values that contain a
+// single quote are double-quoted with backslash escapes.
+func envFileValue(value string) string {
+	if envPlainValue.MatchString(value) {
+		return value
+	}
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,127 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpOrchestrator_Env(t *testing.T) {
+	mock := docker.NewMockDocker()
+
+	err := newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Hardened: true,
+		Env: map[string]string{"TZ": "UTC", "POSTGRES_HOST_AUTH_METHOD": "md5"}})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	env := mock.Calls.RunPostgres[0].Opts.ExtraEnv
+	assert.Equal(t, []string{"POSTGRES_HOST_AUTH_METHOD=md5", "TZ=UTC"}, env[len(env)-2:])
+
+	err = newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true,
+		Env: map[string]string{"POSTGRES_PASSWORD": "x"}})
+	assert.EqualError(t, err, "--env POSTGRES_PASSWORD is set by pgbox; use --password instead")
+
+	err = newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true,
+		Env: map[string]string{"BAD-NAME": "x"}})
+	assert.EqualError(t, err, `invalid --env name "BAD-NAME"`)
+}
+
+func TestUpOrchestrator_InitdbEnv(t *testing.T) {
+	mock := docker.NewMockDocker()
+
+	err := newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Hardened: true,
+		Locale: "C", Encoding: "LATIN1", Timezone: "Europe/Istanbul", InitdbArgs: []string{"--data-checksums"}})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	assert.Equal(t, map[string]string{
+		"POSTGRES_INITDB_ARGS": "--locale=C --encoding=LATIN1 --auth-host=scram-sha-256 --data-checksums",
+		"TZ":                   "Europe/Istanbul",
+	}, mock.Calls.RunPostgres[0].Config.InitdbEnv())
+
+	err = newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true,
+		Timezone: "UTC", Env: map[string]string{"TZ": "Europe/Berlin"}})
+	assert.EqualError(t, err, "--env TZ conflicts with --timezone; give only one of them")
+}
+
+func TestUpOrchestrator_InitdbEnvExistingContainer(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "ps" {
+			return "pgbox-pg17\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	err := newTestUpOrchestrator(mock, &buf).Run(t.Context(), UpConfig{Version: "17", ContainerName: "pgbox-pg17", Detach: true, Locale: "C"})
+
+	require.NoError(t, err)
+	assert.Empty(t, mock.Calls.RunPostgres)
+	assert.Contains(t, buf.String(), "--locale, --encoding, --timezone and --initdb-arg only apply to new containers; pgbox-pg17 keeps its existing ones")
+}
+
+func TestExportOrchestrator_InitdbEnv(t *testing.T) {
+	dir := t.TempDir()
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432",
+		Locale: "tr_TR.UTF-8", Timezone: "Europe/Istanbul", InitdbArgs: []string{"--locale-provider=icu", "--icu-locale=tr-TR"}})
+
+	require.NoError(t, err)
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), `      POSTGRES_INITDB_ARGS: "--locale=tr_TR.UTF-8 --locale-provider=icu --icu-locale=tr-TR"`)
+	assert.Contains(t, string(compose), "      TZ: Europe/Istanbul\n")
+
+	err = NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: t.TempDir(), Version: "17", Format: FormatDevcontainerFeature, Locale: "C"})
+	assert.EqualError(t, err, "--locale, --encoding, --timezone and --initdb-arg are not supported with --format devcontainer-feature")
+}
+
+func TestExportOrchestrator_EnvFile(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(envPath, []byte("# mine\nAPP_PORT=3000\nPOSTGRES_PASSWORD=old\n"), 0644))
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir: dir,
+		Version:   "17",
+		Port:      "5432",
+		Password:  "it's secret",
+		UI:        []string{"adminer"},
+		Env:       map[string]string{"TZ": "Europe/Berlin"},
+		EnvFile:   true,
+	})
+
+	require.NoError(t, err)
+	data, err := os.ReadFile(envPath)
+	require.NoError(t, err)
+	assert.Equal(t, `# mine
+APP_PORT=3000
+POSTGRES_PASSWORD="it's secret"
+ADMINER_DEFAULT_SERVER=db
+POSTGRES_DB=postgres
+POSTGRES_USER=postgres
+TZ=Europe/Berlin
+`, string(data))
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), `    environment:
+      POSTGRES_DB: ${POSTGRES_DB}
+      POSTGRES_PASSWORD: ${POSTGRES_PASSWORD}
+      POSTGRES_USER: ${POSTGRES_USER}
+      TZ: ${TZ}
+`)
+	assert.Contains(t, string(compose), `      ADMINER_DEFAULT_SERVER: "${ADMINER_DEFAULT_SERVER}"`)
+	assert.NotContains(t, string(compose), "secret")
+}
+
+func TestEnvFileValue(t *testing.T) {
+	assert.Equal(t, "postgres", envFileValue("postgres"))
+	assert.Equal(t, "'a b$c'", envFileValue("a b$c"))
+	assert.Equal(t, `"it's \"x\""`, envFileValue(`it's "x"`))
+}
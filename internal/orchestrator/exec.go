@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// ExecConfig holds configuration for the exec command.
+type ExecConfig struct {
+	ContainerName string
+	User          string   // OS user to run as inside the container (default: image default)
+	Command       []string // Command and arguments; defaults to bash
+	// For testing: allows overriding stdin terminal detection
+	StdinIsTerminal *bool
+}
+
+// ExecOrchestrator handles running arbitrary commands inside the container.
+type ExecOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewExecOrchestrator creates a new ExecOrchestrator.
+func NewExecOrchestrator(d docker.Docker, w io.Writer) *ExecOrchestrator {
+	return &ExecOrchestrator{docker: d, output: w}
+}
+
+// Run executes cfg.Command in the PostgreSQL container, allocating a TTY
+// when stdin is a terminal.
+func (o *ExecOrchestrator) Run(ctx context.Context, cfg ExecConfig) error {
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+
+	if cfg.ContainerName != "" {
+		running, err := o.docker.IsContainerRunning(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to check container status: %w", err)
+		}
+		if !running {
+			return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+		}
+	}
+
+	command := cfg.Command
+	if len(command) == 0 {
+		command = []string{"bash"}
+	}
+
+	stdinIsTerminal := util.StdinIsTerminal()
+	if cfg.StdinIsTerminal != nil {
+		stdinIsTerminal = *cfg.StdinIsTerminal
+	}
+
+	dockerArgs := []string{"exec"}
+	if stdinIsTerminal {
+		dockerArgs = append(dockerArgs, "-it")
+	} else {
+		dockerArgs = append(dockerArgs, "-i")
+	}
+	if cfg.User != "" {
+		dockerArgs = append(dockerArgs, "-u", cfg.User)
+	}
+	dockerArgs = append(dockerArgs, name)
+	dockerArgs = append(dockerArgs, command...)
+
+	return o.docker.RunInteractive(ctx, dockerArgs...)
+}
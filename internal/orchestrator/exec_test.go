@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecOrchestrator_DefaultsToBash(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	var buf bytes.Buffer
+	terminal := true
+
+	orch := NewExecOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), ExecConfig{ContainerName: "my-postgres", StdinIsTerminal: &terminal})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "-it", "my-postgres", "bash"}, mock.Calls.RunInteractive[0])
+}
+
+func TestExecOrchestrator_CommandWithoutTerminal(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	var buf bytes.Buffer
+	terminal := false
+
+	orch := NewExecOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), ExecConfig{
+		User:            "postgres",
+		Command:         []string{"pg_basebackup", "-D", "/tmp/base"},
+		StdinIsTerminal: &terminal,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "-i", "-u", "postgres", "pgbox-pg17", "pg_basebackup", "-D", "/tmp/base"},
+		mock.Calls.RunInteractive[0])
+}
+
+func TestExecOrchestrator_ContainerNotRunning(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewExecOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), ExecConfig{ContainerName: "my-postgres"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not running")
+	assert.Empty(t, mock.Calls.RunInteractive)
+}
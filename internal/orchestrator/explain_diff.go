@@ -0,0 +1,177 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// ExplainDiffConfig holds configuration for the explain-analyze-diff command.
+type ExplainDiffConfig struct {
+	Containers []string // The two containers to compare
+	Query      string
+	Database   string
+	User       string
+	Width      int // Total width of the side-by-side output (default: 160)
+}
+
+// ExplainDiffOrchestrator runs a query with EXPLAIN ANALYZE on two containers
+// and compares the plans and timings.
+type ExplainDiffOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewExplainDiffOrchestrator creates a new ExplainDiffOrchestrator.
+func NewExplainDiffOrchestrator(d docker.Docker, w io.Writer) *ExplainDiffOrchestrator {
+	return &ExplainDiffOrchestrator{docker: d, output: w}
+}
+
+// explainedPlan is the EXPLAIN ANALYZE output of one container.
+type explainedPlan struct {
+	name     string
+	version  string
+	lines    []string
+	planning float64 // Planning time in ms, -1 when not reported
+	execute  float64 // Execution time in ms, -1 when not reported
+}
+
+var (
+	planTimePattern = regexp.MustCompile(`^\s*(Planning|Execution) Time: ([0-9.]+) ms`)
+	// planNoisePattern matches the parts of a plan line that change from run
+	// to run, so only differences in the plan shape are marked.
+	planNoisePattern = regexp.MustCompile(`\s*\((?:cost|actual)[^)]*\)|^\s*(?:Buffers|Planning|Execution|I/O Timings|Rows Removed by|Heap Fetches)[ A-Za-z]*:.*$`)
+)
+
+// Run prints the plans of cfg.Query on both containers side by side, marking
+// lines that differ in more than costs and timings, followed by the planning
+// and execution times.
+func (o *ExplainDiffOrchestrator) Run(ctx context.Context, cfg ExplainDiffConfig) error {
+	if len(cfg.Containers) != 2 {
+		return errors.New("explain-analyze-diff compares exactly two containers")
+	}
+	query := strings.TrimRight(strings.TrimSpace(cfg.Query), ";")
+	if query == "" {
+		return errors.New("no query given. Pass it as an argument or on stdin")
+	}
+	width := cfg.Width
+	if width <= 0 {
+		width = 160
+	}
+
+	var plans [2]*explainedPlan
+	for i, name := range cfg.Containers {
+		plan, err := o.explain(ctx, name, query, cfg)
+		if err != nil {
+			return err
+		}
+		plans[i] = plan
+	}
+
+	o.printSideBySide(plans, width)
+	_, _ = fmt.Fprintln(o.output)
+	o.printTiming("Planning time", plans[0].planning, plans[1].planning)
+	o.printTiming("Execution time", plans[0].execute, plans[1].execute)
+	return nil
+}
+
+// explain runs the query with EXPLAIN ANALYZE in a transaction that is rolled
+// back, so statements that write leave no changes behind.
+func (o *ExplainDiffOrchestrator) explain(ctx context.Context, name, query string, cfg ExplainDiffConfig) (*explainedPlan, error) {
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return nil, fmt.Errorf("container %s is not running. Start it with: pgbox up -n %s", name, name)
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+	output, err := o.docker.ExecCommand(ctx, name, "psql", "-X", "-q", "-U", user, "-d", database,
+		"-t", "-A", "-v", "ON_ERROR_STOP=1",
+		"-c", "BEGIN", "-c", "EXPLAIN (ANALYZE, BUFFERS) "+query, "-c", "ROLLBACK")
+	if err != nil {
+		return nil, fmt.Errorf("EXPLAIN ANALYZE failed on %s: %s: %w", name, strings.TrimSpace(output), err)
+	}
+
+	version, _ := o.docker.GetContainerEnv(ctx, name, "PG_MAJOR")
+	plan := &explainedPlan{name: name, version: version, planning: -1, execute: -1}
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if m := planTimePattern.FindStringSubmatch(line); m != nil {
+			ms, _ := strconv.ParseFloat(m[2], 64)
+			if m[1] == "Planning" {
+				plan.planning = ms
+			} else {
+				plan.execute = ms
+			}
+		}
+		plan.lines = append(plan.lines, line)
+	}
+	return plan, nil
+}
+
+// printSideBySide prints both plans in two columns. Lines whose plan shape
+// differs are marked with * between the columns.
+func (o *ExplainDiffOrchestrator) printSideBySide(plans [2]*explainedPlan, width int) {
+	col := (width - 3) / 2
+	header := func(p *explainedPlan) string {
+		if p.version == "" {
+			return p.name
+		}
+		return fmt.Sprintf("%s (PostgreSQL %s)", p.name, p.version)
+	}
+	_, _ = fmt.Fprintf(o.output, "%-*s   %s\n", col, fitColumn(header(plans[0]), col), fitColumn(header(plans[1]), col))
+	_, _ = fmt.Fprintf(o.output, "%s   %s\n", strings.Repeat("-", col), strings.Repeat("-", col))
+
+	rows := max(len(plans[0].lines), len(plans[1].lines))
+	for i := range rows {
+		var left, right string
+		if i < len(plans[0].lines) {
+			left = plans[0].lines[i]
+		}
+		if i < len(plans[1].lines) {
+			right = plans[1].lines[i]
+		}
+		marker := "|"
+		if planShape(left) != planShape(right) {
+			marker = "*"
+		}
+		line := fmt.Sprintf("%-*s %s %s", col, fitColumn(left, col), marker, fitColumn(right, col))
+		_, _ = fmt.Fprintln(o.output, strings.TrimRight(line, " "))
+	}
+}
+
+// printTiming prints one timing of both plans and how the second compares.
+func (o *ExplainDiffOrchestrator) printTiming(label string, a, b float64) {
+	if a < 0 || b < 0 {
+		return
+	}
+	change := ""
+	if a > 0 {
+		change = fmt.Sprintf(" (%+.1f%%)", (b-a)/a*100)
+	}
+	_, _ = fmt.Fprintf(o.output, "%-15s %10.3f ms -> %10.3f ms%s\n", label+":", a, b, change)
+}
+
+// planShape returns a plan line without costs, row counts and timings.
+func planShape(line string) string {
+	return strings.TrimSpace(planNoisePattern.ReplaceAllString(line, ""))
+}
+
+// fitColumn shortens s to width characters, marking the cut with "~".
+func fitColumn(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	return string(r[:width-1]) + "~"
+}
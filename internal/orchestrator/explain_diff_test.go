@@ -0,0 +1,100 @@
+package orchestrator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainDiffOrchestrator_Run(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.GetContainerEnvFunc = func(containerName, envVar string) (string, error) {
+		if envVar == "PG_MAJOR" {
+			return strings.TrimPrefix(containerName, "pgbox-pg"), nil
+		}
+		return "", nil
+	}
+	plans := map[string]string{
+		"pgbox-pg16": `Seq Scan on orders  (cost=0.00..35.50 rows=10 width=4) (actual time=0.010..0.900 rows=12 loops=1)
+  Filter: (total > 100)
+Planning Time: 0.100 ms
+Execution Time: 2.000 ms
+`,
+		"pgbox-pg17": `Seq Scan on orders  (cost=0.00..30.00 rows=12 width=4) (actual time=0.008..0.500 rows=12 loops=1)
+  Filter: (total > 100)
+Planning Time: 0.150 ms
+Execution Time: 1.000 ms
+`,
+	}
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return plans[containerName], nil
+	}
+	var buf bytes.Buffer
+
+	err := NewExplainDiffOrchestrator(mock, &buf).Run(t.Context(), ExplainDiffConfig{
+		Containers: []string{"pgbox-pg16", "pgbox-pg17"},
+		Query:      "SELECT id FROM orders WHERE total > 100;\n",
+		Width:      200,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.ExecCommand, 2)
+	cmd := mock.Calls.ExecCommand[0].Command
+	assert.Equal(t, []string{"-c", "BEGIN", "-c", "EXPLAIN (ANALYZE, BUFFERS) SELECT id FROM orders WHERE total > 100", "-c", "ROLLBACK"}, cmd[len(cmd)-6:])
+
+	out := buf.String()
+	assert.Contains(t, out, "pgbox-pg16 (PostgreSQL 16)")
+	assert.Contains(t, out, "pgbox-pg17 (PostgreSQL 17)")
+	assert.Contains(t, out, "  Filter: (total > 100)")
+	assert.NotContains(t, out, " * ", "costs and timings alone do not mark a line")
+	assert.Contains(t, out, "Planning time:       0.100 ms ->      0.150 ms (+50.0%)")
+	assert.Contains(t, out, "Execution time:      2.000 ms ->      1.000 ms (-50.0%)")
+}
+
+func TestExplainDiffOrchestrator_MarksPlanChanges(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		if containerName == "app-index" {
+			return "Index Scan using orders_total_idx on orders  (cost=0.15..8.17 rows=1 width=4)\n", nil
+		}
+		return "Seq Scan on orders  (cost=0.00..35.50 rows=10 width=4)\n", nil
+	}
+	var buf bytes.Buffer
+
+	err := NewExplainDiffOrchestrator(mock, &buf).Run(t.Context(), ExplainDiffConfig{
+		Containers: []string{"app-noindex", "app-index"},
+		Query:      "SELECT id FROM orders",
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), " * Index Scan using orders_total_idx")
+}
+
+func TestExplainDiffOrchestrator_NotRunning(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return name == "a", nil }
+
+	err := NewExplainDiffOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), ExplainDiffConfig{
+		Containers: []string{"a", "b"},
+		Query:      "SELECT 1",
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container b is not running")
+}
+
+func TestExplainDiffOrchestrator_NoQuery(t *testing.T) {
+	err := NewExplainDiffOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}).Run(t.Context(), ExplainDiffConfig{
+		Containers: []string{"a", "b"},
+		Query:      " ;\n",
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no query given")
+}
@@ -3,11 +3,15 @@ package orchestrator
 import (
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/logging"
 	"github.com/ahacop/pgbox/internal/model"
 	"github.com/ahacop/pgbox/internal/render"
 	"github.com/ahacop/pgbox/internal/util"
@@ -16,19 +20,62 @@ import (
 // ExportConfig holds configuration for the export command.
 type ExportConfig struct {
 	TargetDir  string
+	Format     string // compose (default), devcontainer or devcontainer-feature
 	Version    string
 	Port       string
 	Extensions []string
 	BaseImage  string
+	SplitInit  bool              // Write one numbered init file per extension
+	Prefer     []string          // Extensions whose GUC values win conflicts
+	Profile    string            // Tuning profile (see profiles.Catalog)
+	Settings   map[string]string // User GUC overrides; win over extension defaults
+	GUCChoices map[string]string // Values picked for conflicting GUCs (see GUCConflicts)
+	Hardened   bool              // Restricted service, SCRAM auth, non-default superuser, localhost-only port
+	UI         []string          // Database UIs to run next to PostgreSQL (see UITools)
+	Replica    bool              // Add a read-only streaming replica service
+	Pgbouncer  bool              // Add a pgbouncer service in transaction pooling mode
+	Env        map[string]string // Extra environment variables for the db service
+	// EnvFile moves the services' environment values to a .env file next to
+	// the compose file, which references them as ${KEY}
+	EnvFile bool
+	// ComposeProfiles also adds every other UI, behind a compose profile named after it
+	ComposeProfiles bool
+	// Check leaves the directory as it is and fails if the export would
+	// change it, to catch drift in CI
+	Check bool
+	// Arch is the architecture to resolve .deb and .zip downloads for: one of
+	// util.DebArches, ArchAll for a Dockerfile that builds on each, or empty
+	// for this machine's
+	Arch string
+	// Adopt takes over the PostgreSQL service of an existing docker-compose.yml
+	// (see adoptCompose) instead of writing a db service next to it
+	Adopt bool
+	// Locale, Encoding and InitdbArgs go to initdb through POSTGRES_INITDB_ARGS
+	// when the data volume is created; Timezone is the service's TZ
+	Locale     string
+	Encoding   string
+	Timezone   string
+	InitdbArgs []string
+	// SSL serves TLS with a self-signed certificate written next to the
+	// compose file (see writeSSLFiles)
+	SSL bool
 	// Environment overrides
 	User     string
 	Password string
 	Database string
 }
 
+// ArchAll is the ExportConfig.Arch value for a Dockerfile that builds on
+// every architecture in util.DebArches, picking downloads by TARGETARCH.
+const ArchAll = "all"
+
+// ExportArches lists the values accepted by ExportConfig.Arch.
+var ExportArches = append(slices.Clone(util.DebArches), ArchAll)
+
 // ExportOrchestrator handles exporting Docker configurations.
 type ExportOrchestrator struct {
-	output io.Writer
+	output  io.Writer
+	adopted *render.ComposeService // The service export --adopt took over, for the current run
 }
 
 // NewExportOrchestrator creates a new ExportOrchestrator.
@@ -36,8 +83,143 @@ func NewExportOrchestrator(w io.Writer) *ExportOrchestrator {
 	return &ExportOrchestrator{output: w}
 }
 
-// Run exports Docker configuration to the target directory.
+// GUCConflicts returns the conflicts between the GUC values of cfg's
+// extensions that cfg does not decide, for the caller to settle with
+// cfg.GUCChoices before Run. Returns nil when there are none.
+func (o *ExportOrchestrator) GUCConflicts(cfg ExportConfig) *extensions.ConflictError {
+	prefer, settings := applyGUCChoices(cfg.Extensions, cfg.GUCChoices, cfg.Prefer, cfg.Settings)
+	return gucConflicts(cfg.Extensions, prefer, settings)
+}
+
+// Run exports Docker configuration to the target directory. Exporting into
+// a directory with an earlier export prints what changed. With cfg.Check the
+// directory is left as it was, and Run fails if the export would change it.
 func (o *ExportOrchestrator) Run(cfg ExportConfig) error {
+	before, err := snapshotExport(cfg.TargetDir)
+	if err != nil {
+		return err
+	}
+	output := o.output
+	if cfg.Check {
+		o.output = io.Discard
+	}
+	exportErr := o.export(cfg)
+	o.output = output
+
+	after, err := snapshotExport(cfg.TargetDir)
+	if err != nil {
+		return err
+	}
+	changes := before.diff(after)
+	if cfg.Check {
+		if err := before.restore(cfg.TargetDir, after); err != nil {
+			return err
+		}
+	}
+	if exportErr != nil {
+		return exportErr
+	}
+
+	switch {
+	case cfg.Check && len(changes) > 0:
+		_, _ = fmt.Fprintf(o.output, "Export in %s is out of date:\n", cfg.TargetDir)
+		printChanges(o.output, changes)
+		return fmt.Errorf("export would change %d file(s) in %s; run it without --check to update them", len(changes), cfg.TargetDir)
+	case cfg.Check:
+		_, _ = fmt.Fprintf(o.output, "Export in %s is up to date\n", cfg.TargetDir)
+	case !before.hasGeneratedFiles():
+	case len(changes) > 0:
+		_, _ = fmt.Fprintf(o.output, "\nChanges to the earlier export:\n")
+		printChanges(o.output, changes)
+	default:
+		_, _ = fmt.Fprintf(o.output, "\nNo changes to the earlier export\n")
+	}
+	return nil
+}
+
+// export writes the configuration for cfg.Format.
+func (o *ExportOrchestrator) export(cfg ExportConfig) error {
+	if err := validateUITools(cfg.UI); err != nil {
+		return err
+	}
+	cfg.Prefer, cfg.Settings = applyGUCChoices(cfg.Extensions, cfg.GUCChoices, cfg.Prefer, cfg.Settings)
+
+	if cfg.Replica && cfg.Format == FormatDevcontainerFeature {
+		return fmt.Errorf("--with-replica is not supported with --format %s", FormatDevcontainerFeature)
+	}
+	if cfg.Pgbouncer && cfg.Format == FormatDevcontainerFeature {
+		return fmt.Errorf("--with-pgbouncer is not supported with --format %s", FormatDevcontainerFeature)
+	}
+	if (len(cfg.Env) > 0 || cfg.EnvFile) && cfg.Format == FormatDevcontainerFeature {
+		return fmt.Errorf("--env and --env-file are not supported with --format %s", FormatDevcontainerFeature)
+	}
+	if err := checkEnv(cfg.Env); err != nil {
+		return err
+	}
+	initdb := &config.PostgresConfig{Locale: cfg.Locale, Encoding: cfg.Encoding, Timezone: cfg.Timezone, InitdbArgs: cfg.InitdbArgs}
+	if len(initdb.InitdbEnv()) > 0 && cfg.Format == FormatDevcontainerFeature {
+		return fmt.Errorf("--locale, --encoding, --timezone and --initdb-arg are not supported with --format %s", FormatDevcontainerFeature)
+	}
+	if err := checkInitdbEnv(cfg.Env, initdb); err != nil {
+		return err
+	}
+	if cfg.SSL && cfg.Format == FormatDevcontainerFeature {
+		return fmt.Errorf("--ssl is not supported with --format %s", FormatDevcontainerFeature)
+	}
+	if cfg.Arch != "" {
+		if !slices.Contains(ExportArches, cfg.Arch) {
+			return fmt.Errorf("invalid arch %q (must be %s)", cfg.Arch, strings.Join(ExportArches, ", "))
+		}
+		if cfg.Format == FormatDevcontainerFeature {
+			return fmt.Errorf("--arch is not supported with --format %s, which installs extensions where the container runs", FormatDevcontainerFeature)
+		}
+	} else {
+		cfg.Arch = util.GetDebArch()
+	}
+
+	o.adopted = nil
+	if cfg.Adopt {
+		if cfg.Format != "" && cfg.Format != FormatCompose {
+			return fmt.Errorf("--adopt only applies to --format %s", FormatCompose)
+		}
+		// Before --hardened, so the service keeps the credentials of its data
+		if err := o.adoptCompose(&cfg, filepath.Join(cfg.TargetDir, "docker-compose.yml")); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Hardened {
+		if cfg.Format == FormatDevcontainerFeature {
+			return fmt.Errorf("--hardened is not supported with --format %s", FormatDevcontainerFeature)
+		}
+		if err := o.hardenCredentials(&cfg); err != nil {
+			return err
+		}
+	}
+
+	switch cfg.Format {
+	case "", FormatCompose:
+	case FormatDevcontainerFeature:
+		return o.exportDevcontainerFeature(cfg)
+	case FormatDevcontainer:
+		return o.exportDevcontainer(cfg)
+	default:
+		return fmt.Errorf("invalid format %q (must be %s)", cfg.Format, strings.Join(ExportFormats, ", "))
+	}
+
+	pgConfModel, layout, sidecars, err := o.writeScaffold(cfg, cfg.TargetDir, filepath.Join(cfg.TargetDir, "docker-compose.yml"))
+	if err != nil {
+		return err
+	}
+	o.printSuccess(cfg, pgConfModel, layout, sidecars)
+
+	return nil
+}
+
+// writeScaffold writes the Dockerfile, init SQL and postgresql.conf to
+// scaffoldDir and the compose service to composePath. Paths in the compose
+// file are relative to its own directory.
+func (o *ExportOrchestrator) writeScaffold(cfg ExportConfig, scaffoldDir, composePath string) (*model.PGConfModel, initLayout, []model.Sidecar, error) {
 	baseImage := cfg.BaseImage
 	if baseImage == "" {
 		baseImage = extensions.GetBaseImage(cfg.Extensions, cfg.Version)
@@ -58,58 +240,288 @@ func (o *ExportOrchestrator) Run(cfg ExportConfig) error {
 	if cfg.Database != "" {
 		pgConfig.Database = cfg.Database
 	}
+	pgConfig.Locale, pgConfig.Encoding, pgConfig.Timezone = cfg.Locale, cfg.Encoding, cfg.Timezone
+	pgConfig.InitdbArgs = slices.Clone(cfg.InitdbArgs)
+	pgConfig.SSL = cfg.SSL
+	if cfg.Hardened {
+		hardenInitdbArgs(pgConfig)
+	}
 
-	if err := os.MkdirAll(cfg.TargetDir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	if err := os.MkdirAll(scaffoldDir, 0755); err != nil {
+		return nil, initLayout{}, nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	dockerfileModel := model.NewDockerfileModel(baseImage)
-	composeModel := model.NewComposeModel("db")
+	serviceName := "db"
+	if o.adopted != nil {
+		serviceName = o.adopted.Name
+	}
+	composeModel := model.NewComposeModel(serviceName)
 	pgConfModel := model.NewPGConfModel()
 	initModel := model.NewInitModel()
 
-	composeModel.BuildPath = "."
+	// Paths in the compose file are relative to its directory
+	relDir := "."
+	if rel, err := filepath.Rel(filepath.Dir(composePath), scaffoldDir); err == nil && rel != "." {
+		relDir = "./" + filepath.ToSlash(rel)
+	}
+	composeModel.BuildPath = relDir
 	composeModel.Image = baseImage
-	composeModel.AddPort(fmt.Sprintf("%s:5432", cfg.Port))
+	if cfg.Hardened {
+		composeModel.AddPort(fmt.Sprintf("%s:%s:5432", hardenedHostIP, cfg.Port))
+		hardenService(composeModel)
+	} else {
+		composeModel.AddPort(fmt.Sprintf("%s:5432", cfg.Port))
+	}
 	composeModel.AddVolume("postgres_data:/var/lib/postgresql/data")
-	composeModel.AddVolume("./init.sql:/docker-entrypoint-initdb.d/init.sql:ro")
+	if cfg.SplitInit {
+		if err := os.MkdirAll(filepath.Join(scaffoldDir, initDirName), 0755); err != nil {
+			return nil, initLayout{}, nil, fmt.Errorf("failed to create %s: %w", initDirName, err)
+		}
+	}
+	layout, err := detectInitLayout(scaffoldDir)
+	if err != nil {
+		return nil, initLayout{}, nil, err
+	}
+	for _, mount := range layout.Mounts {
+		composeModel.AddVolume(relDir + strings.TrimPrefix(mount, "."))
+	}
 	composeModel.SetEnv("POSTGRES_USER", pgConfig.User)
 	composeModel.SetEnv("POSTGRES_PASSWORD", pgConfig.Password)
 	composeModel.SetEnv("POSTGRES_DB", pgConfig.Database)
+	for key, value := range pgConfig.InitdbEnv() {
+		composeModel.SetEnv(key, value)
+	}
+	for key, value := range cfg.Env {
+		composeModel.SetEnv(key, value)
+	}
+	addUISidecars(composeModel, pgConfig, cfg.UI, cfg.ComposeProfiles)
 
 	if len(cfg.Extensions) > 0 {
-		if err := o.processExtensions(cfg.Version, cfg.Extensions, dockerfileModel, pgConfModel, initModel); err != nil {
-			return err
+		if err := o.processExtensions(cfg.Version, cfg.Arch, cfg.Extensions, cfg.Prefer, cfg.Settings, dockerfileModel, pgConfModel, initModel); err != nil {
+			return nil, initLayout{}, nil, err
 		}
 	}
+	// Also run without extensions, to remove the scripts of an earlier export
+	scriptDir := filepath.Dir(layout.Path)
+	scripts, err := render.RenderInitScripts(initModel, scriptDir)
+	if err != nil {
+		return nil, initLayout{}, nil, fmt.Errorf("failed to render initdb scripts: %w", err)
+	}
+	if scriptDir == scaffoldDir {
+		// Unlike docker-entrypoint-initdb.d, the scaffold directory is not
+		// mounted as a whole
+		for _, name := range scripts {
+			composeModel.AddVolume(fmt.Sprintf("%s/%s:%s/%s:ro", relDir, name, initDirMountPath, name))
+		}
+	}
+	if cfg.Profile != "" {
+		profile, err := applyProfile(pgConfModel, cfg.Profile)
+		if err != nil {
+			return nil, initLayout{}, nil, err
+		}
+		if profile.Unsafe {
+			logging.Warnf(o.output, "profile %s disables fsync, synchronous_commit and full_page_writes; only use it for throwaway data", profile.Name)
+		}
+	}
+	if cfg.Hardened {
+		applyHardenedSettings(pgConfModel)
+	}
+	if cfg.SSL {
+		applySSLSettings(pgConfModel)
+		if err := o.writeSSLFiles(composeModel, scaffoldDir, relDir); err != nil {
+			return nil, initLayout{}, nil, err
+		}
+	}
+	for _, warning := range applyUserSettings(pgConfModel, cfg.Settings) {
+		logging.Warnf(o.output, "%s", warning)
+	}
+	if cfg.Replica {
+		if err := checkReplicaSettings(pgConfModel); err != nil {
+			return nil, initLayout{}, nil, err
+		}
+		setupPath := filepath.Join(scaffoldDir, replicaSetupFile)
+		if err := os.WriteFile(setupPath, []byte(replicaSetupHeader+replicaSetupScript), 0755); err != nil {
+			return nil, initLayout{}, nil, fmt.Errorf("failed to write %s: %w", setupPath, err)
+		}
+		hostIP := ""
+		if cfg.Hardened {
+			hostIP = hardenedHostIP
+		}
+		addReplicaSidecar(composeModel, pgConfig, pgConfModel, relDir, hostIP)
+	}
+	if cfg.Pgbouncer {
+		files := []struct{ name, content string }{
+			{pgbouncerConfigFile, pgbouncerConfig(composeModel.ServiceName, pgConfig)},
+			{pgbouncerUserlistFile, pgbouncerUserlist(pgConfig)},
+		}
+		for _, f := range files {
+			path := filepath.Join(scaffoldDir, f.name)
+			// Readable by the pgbouncer user in the container, which is not the owner
+			if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+				return nil, initLayout{}, nil, fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+		hostIP := ""
+		if cfg.Hardened {
+			hostIP = hardenedHostIP
+		}
+		addPgbouncerSidecar(composeModel, relDir, hostIP)
+	}
 
-	if err := render.RenderDockerfile(dockerfileModel, cfg.TargetDir); err != nil {
-		return fmt.Errorf("failed to render Dockerfile: %w", err)
+	if o.adopted != nil {
+		o.mergeAdopted(composeModel, cfg.Hardened)
 	}
 
-	if err := render.RenderCompose(composeModel, pgConfModel, cfg.TargetDir); err != nil {
-		return fmt.Errorf("failed to render docker-compose.yml: %w", err)
+	if cfg.EnvFile {
+		envPath := filepath.Join(filepath.Dir(composePath), envFileName)
+		if err := writeEnvFile(envPath, moveEnvToFile(composeModel)); err != nil {
+			return nil, initLayout{}, nil, err
+		}
 	}
 
-	if err := render.RenderInitSQL(initModel, cfg.TargetDir); err != nil {
-		return fmt.Errorf("failed to render init.sql: %w", err)
+	if err := render.RenderDockerfile(dockerfileModel, scaffoldDir); err != nil {
+		return nil, initLayout{}, nil, fmt.Errorf("failed to render Dockerfile: %w", err)
+	}
+
+	if o.adopted != nil {
+		err = render.RenderAdoptedComposeFile(composeModel, pgConfModel, o.adopted, composePath)
+	} else {
+		err = render.RenderComposeFile(composeModel, pgConfModel, composePath)
+	}
+	if err != nil {
+		return nil, initLayout{}, nil, fmt.Errorf("failed to render %s: %w", filepath.Base(composePath), err)
+	}
+
+	if cfg.SplitInit {
+		if _, err := render.RenderInitSQLFiles(initModel, filepath.Dir(layout.Path)); err != nil {
+			return nil, initLayout{}, nil, fmt.Errorf("failed to render init files: %w", err)
+		}
+	} else {
+		// Split files of an earlier export would run the init SQL twice
+		if dir := filepath.Dir(layout.Path); filepath.Base(dir) == initDirName {
+			if err := render.RemoveInitSQLFiles(dir, filepath.Base(layout.Path)); err != nil {
+				return nil, initLayout{}, nil, err
+			}
+		}
+		if err := render.RenderInitSQLFile(initModel, layout.Path); err != nil {
+			return nil, initLayout{}, nil, fmt.Errorf("failed to render %s: %w", layout.relPath(scaffoldDir), err)
+		}
 	}
 
 	if len(pgConfModel.SharedPreload) > 0 || len(pgConfModel.GUCs) > 0 {
-		if err := render.RenderPostgreSQLConf(pgConfModel, cfg.TargetDir); err != nil {
-			return fmt.Errorf("failed to render postgresql.conf: %w", err)
+		if err := render.RenderPostgreSQLConf(pgConfModel, scaffoldDir); err != nil {
+			return nil, initLayout{}, nil, fmt.Errorf("failed to render postgresql.conf: %w", err)
 		}
 	}
 
-	o.printSuccess(cfg, pgConfModel)
+	return pgConfModel, layout, composeModel.Sidecars, nil
+}
 
+// hardenCredentials gives a hardened export the non-default superuser when
+// no user was given, and a generated password when none was given.
+func (o *ExportOrchestrator) hardenCredentials(cfg *ExportConfig) error {
+	if cfg.User == "" {
+		cfg.User = HardenedUser
+	}
+	if cfg.Password == "" {
+		password, err := util.GeneratePassword(24)
+		if err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+		cfg.Password = password
+		_, _ = fmt.Fprintln(o.output, "Generated a password for the hardened service; it is in POSTGRES_PASSWORD of the compose file")
+	}
 	return nil
 }
 
+const (
+	// initDirName is the conventional directory for user init scripts in an export.
+	initDirName = "docker-entrypoint-initdb.d"
+	// pgboxInitFile is the numbered file pgbox uses when it shares the init directory,
+	// so its extensions are created before user scripts run.
+	pgboxInitFile = "00-pgbox-init.sql"
+)
+
+// initLayout describes where pgbox writes its init SQL inside an export directory.
+type initLayout struct {
+	Path     string   // Absolute path of the pgbox init SQL file
+	Mounts   []string // Compose volume entries for init content
+	Existing []string // Init files found in the directory that pgbox does not manage
+}
+
+// relPath returns the init file path relative to the export directory.
+func (l initLayout) relPath(targetDir string) string {
+	if rel, err := filepath.Rel(targetDir, l.Path); err == nil {
+		return rel
+	}
+	return l.Path
+}
+
+// detectInitLayout inspects the export directory for existing init content.
+// A docker-entrypoint-initdb.d directory is mounted as a whole with pgbox's SQL
+// added as 00-pgbox-init.sql; a user-owned init.sql is kept and mounted next to it.
+func detectInitLayout(targetDir string) (initLayout, error) {
+	initDir := filepath.Join(targetDir, initDirName)
+	if info, err := os.Stat(initDir); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(initDir)
+		if err != nil {
+			return initLayout{}, fmt.Errorf("failed to read %s: %w", initDir, err)
+		}
+		var existing []string
+		for _, entry := range entries {
+			if !entry.IsDir() && isInitScript(entry.Name()) && !isPgboxManaged(filepath.Join(initDir, entry.Name())) {
+				existing = append(existing, filepath.Join(initDirName, entry.Name()))
+			}
+		}
+		return initLayout{
+			Path:     filepath.Join(initDir, pgboxInitFile),
+			Mounts:   []string{fmt.Sprintf("./%s:/docker-entrypoint-initdb.d:ro", initDirName)},
+			Existing: existing,
+		}, nil
+	}
+
+	initPath := filepath.Join(targetDir, "init.sql")
+	if _, err := os.Stat(initPath); err != nil || isPgboxManaged(initPath) {
+		return initLayout{
+			Path:   initPath,
+			Mounts: []string{"./init.sql:/docker-entrypoint-initdb.d/init.sql:ro"},
+		}, nil
+	}
+
+	return initLayout{
+		Path: filepath.Join(targetDir, pgboxInitFile),
+		Mounts: []string{
+			fmt.Sprintf("./%s:/docker-entrypoint-initdb.d/%s:ro", pgboxInitFile, pgboxInitFile),
+			"./init.sql:/docker-entrypoint-initdb.d/init.sql:ro",
+		},
+		Existing: []string{"init.sql"},
+	}, nil
+}
+
+// isInitScript reports whether the postgres entrypoint would run the file.
+func isInitScript(name string) bool {
+	for _, suffix := range []string{".sql", ".sql.gz", ".sql.xz", ".sql.zst", ".sh"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPgboxManaged reports whether the file at path was generated by pgbox.
+func isPgboxManaged(path string) bool {
+	content, err := os.ReadFile(path)
+	return err == nil && render.IsGeneratedInitSQL(string(content))
+}
+
 // processExtensions loads and applies extension configurations.
 func (o *ExportOrchestrator) processExtensions(
 	pgVersion string,
+	arch string,
 	extNames []string,
+	prefer []string,
+	settings map[string]string,
 	dockerfileModel *model.DockerfileModel,
 	pgConfModel *model.PGConfModel,
 	initModel *model.InitModel,
@@ -117,20 +529,18 @@ func (o *ExportOrchestrator) processExtensions(
 	if err := extensions.ValidateExtensions(extNames); err != nil {
 		return err
 	}
-
-	packages := extensions.GetPackages(extNames, pgVersion)
-	if len(packages) > 0 {
-		dockerfileModel.AddPackages(packages, "apt")
+	if err := extensions.ValidateVersion(extNames, pgVersion); err != nil {
+		return err
 	}
-
-	debURLs := extensions.GetDebURLs(extNames, pgVersion, util.GetDebArch())
-	if len(debURLs) > 0 {
-		dockerfileModel.AddDebURLs(debURLs...)
+	if err := validatePrefer(prefer, extNames); err != nil {
+		return err
 	}
 
-	zipURLs := extensions.GetZipURLs(extNames, pgVersion, util.GetDebArch())
-	if len(zipURLs) > 0 {
-		dockerfileModel.AddZipURLs(zipURLs...)
+	if _, err := addPackages(dockerfileModel, extNames, pgVersion, arch); err != nil {
+		return err
+	}
+	if unverified := unverifiedDownloads(dockerfileModel, extNames, pgVersion, arch); len(unverified) > 0 {
+		logging.Warnf(o.output, unverifiedWarning, strings.Join(unverified, ", "))
 	}
 
 	preload := extensions.GetPreloadLibraries(extNames)
@@ -138,12 +548,13 @@ func (o *ExportOrchestrator) processExtensions(
 		pgConfModel.AddSharedPreload(preload...)
 	}
 
-	gucs, err := extensions.GetGUCs(extNames)
+	gucs, err := extensions.ResolveGUCs(extNames, prefer, slices.Collect(maps.Keys(settings)))
 	if err != nil {
 		return fmt.Errorf("extension configuration conflict: %w", err)
 	}
 	for key, value := range gucs {
-		pgConfModel.GUCs[key] = value
+		provider := extensions.GetGUCProvider(extNames, key, value)
+		pgConfModel.ApplyGUC(key, value, model.GUCSource{Kind: model.SourceExtension, Name: provider})
 	}
 
 	for _, name := range extNames {
@@ -151,20 +562,59 @@ func (o *ExportOrchestrator) processExtensions(
 		if sql != "" {
 			initModel.AddFragment(name+"-init", sql)
 		}
+		for _, script := range extensions.GetInitdbScripts(name) {
+			initModel.AddScript(script.Name, script.Run)
+		}
 	}
 
 	return nil
 }
 
 // printSuccess prints the success message.
-func (o *ExportOrchestrator) printSuccess(cfg ExportConfig, pgConfModel *model.PGConfModel) {
+func (o *ExportOrchestrator) printSuccess(cfg ExportConfig, pgConfModel *model.PGConfModel, layout initLayout, sidecars []model.Sidecar) {
 	_, _ = fmt.Fprintf(o.output, "Exported Docker configuration to %s\n", cfg.TargetDir)
+	if o.adopted != nil {
+		_, _ = fmt.Fprintf(o.output, "Adopted service %s of docker-compose.yml; run export with --adopt again to update it\n", o.adopted.Name)
+	}
 	if len(cfg.Extensions) > 0 {
 		_, _ = fmt.Fprintf(o.output, "With extensions: %s\n", strings.Join(cfg.Extensions, ", "))
 	}
+	if len(layout.Existing) > 0 {
+		_, _ = fmt.Fprintf(o.output, "\nFound existing init scripts: %s\n", strings.Join(layout.Existing, ", "))
+		if !cfg.SplitInit {
+			_, _ = fmt.Fprintf(o.output, "pgbox init SQL written to %s (runs first)\n", layout.relPath(cfg.TargetDir))
+		}
+	}
+	if cfg.SplitInit {
+		_, _ = fmt.Fprintf(o.output, "Init SQL written per extension to %s/\n", initDirName)
+	}
+	if cfg.SSL {
+		_, _ = fmt.Fprintf(o.output, "Self-signed server certificate written to %s; connect with sslmode=require, and keep %s out of version control\n",
+			sslCertFile, sslKeyFile)
+	}
+	if cfg.EnvFile {
+		_, _ = fmt.Fprintf(o.output, "Credentials and other environment values written to %s; keep it out of version control\n",
+			filepath.Join(cfg.TargetDir, envFileName))
+	}
 	_, _ = fmt.Fprintf(o.output, "\nTo start PostgreSQL:\n")
 	_, _ = fmt.Fprintf(o.output, "  cd %s\n", cfg.TargetDir)
 	_, _ = fmt.Fprintf(o.output, "  docker-compose up -d\n")
+	for _, sidecar := range sidecars {
+		if sidecar.Name == replicaService {
+			_, _ = fmt.Fprintf(o.output, "  %s starts with it, read-only on port %s\n", sidecar.Name, hostPortOf(sidecar.Ports[0]))
+			continue
+		}
+		if sidecar.Name == pgbouncerService {
+			_, _ = fmt.Fprintf(o.output, "  %s starts with it, transaction pooling on port %s\n", sidecar.Name, hostPortOf(sidecar.Ports[0]))
+			continue
+		}
+		if len(sidecar.Profiles) > 0 {
+			_, _ = fmt.Fprintf(o.output, "  docker-compose --profile %s up -d   # also start %s on http://localhost:%s\n",
+				sidecar.Profiles[0], sidecar.Name, strings.Split(sidecar.Ports[0], ":")[0])
+		} else {
+			_, _ = fmt.Fprintf(o.output, "  %s starts with it on http://localhost:%s\n", sidecar.Name, strings.Split(sidecar.Ports[0], ":")[0])
+		}
+	}
 
 	if pgConfModel.RequireRestart {
 		_, _ = fmt.Fprintf(o.output, "\nNote: Some extensions require server configuration changes.\n")
@@ -2,10 +2,13 @@ package orchestrator
 
 import (
 	"bytes"
+	"encoding/json"
+	"maps"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/ahacop/pgbox/internal/extensions"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -108,6 +111,76 @@ func TestExportOrchestrator_CustomBaseImage(t *testing.T) {
 	assert.Contains(t, string(dockerfileContent), "FROM postgres:17-alpine")
 }
 
+func TestExportOrchestrator_AlpineBaseImage(t *testing.T) {
+	saved := maps.Clone(extensions.Catalog)
+	t.Cleanup(func() { extensions.Catalog = saved })
+	extensions.Catalog["acme_audit"] = extensions.Extension{Package: "postgresql-{v}-acme-audit", ApkPackage: "postgresql{v}-acme-audit"}
+	dir := t.TempDir()
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		BaseImage:  "postgres:17-alpine",
+		Extensions: []string{"acme_audit", "hstore"},
+	})
+
+	require.NoError(t, err)
+	dockerfile, err := os.ReadFile(filepath.Join(dir, "Dockerfile"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dockerfile), "RUN apk add --no-cache postgresql17-acme-audit")
+	assert.NotContains(t, string(dockerfile), "apt-get")
+}
+
+func TestExportOrchestrator_InitdbScripts(t *testing.T) {
+	saved := maps.Clone(extensions.Catalog)
+	t.Cleanup(func() { extensions.Catalog = saved })
+	extensions.Catalog["acme_store"] = extensions.Extension{Script: extensions.Scripts{Initdb: []extensions.InitdbScript{
+		{Name: "dirs", Run: `mkdir -p "$PGDATA/acme_store"`},
+	}}}
+	dir := t.TempDir()
+	script := "00-pgbox-hook-10-acme_store-dirs.sh"
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"acme_store"}})
+
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, script))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), "./"+script+":/docker-entrypoint-initdb.d/"+script+":ro")
+
+	t.Run("written into docker-entrypoint-initdb.d", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, initDirName), 0755))
+
+		err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"acme_store"}})
+
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, initDirName, script))
+		compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(compose), script, "the directory is mounted as a whole")
+	})
+
+	err = NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"hstore"}})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, script), "removed with the extension")
+}
+
+func TestExportOrchestrator_AlpineBaseImageWithoutApkPackage(t *testing.T) {
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir:  t.TempDir(),
+		Version:    "17",
+		Port:       "5432",
+		BaseImage:  "postgres:17-alpine",
+		Extensions: []string{"pgvector", "hstore"},
+	})
+
+	assert.EqualError(t, err, "pgvector has no apk package for the Alpine base image postgres:17-alpine; use a Debian-based image or set apk_package in a custom extension spec")
+}
+
 func TestExportOrchestrator_WithPreloadExtensions(t *testing.T) {
 	dir, err := os.MkdirTemp("", "pgbox-export-test")
 	require.NoError(t, err)
@@ -134,6 +207,153 @@ func TestExportOrchestrator_WithPreloadExtensions(t *testing.T) {
 	assert.Contains(t, string(composeContent), "shared_preload_libraries")
 }
 
+func TestExportOrchestrator_UserSettingOverridesExtension(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pgbox-export-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err = orch.Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		Extensions: []string{"pg_cron"},
+		Settings:   map[string]string{"cron.max_running_jobs": "20"},
+	})
+
+	require.NoError(t, err)
+
+	confContent, err := os.ReadFile(filepath.Join(dir, "postgresql.conf.pgbox"))
+	require.NoError(t, err)
+	assert.Contains(t, string(confContent), "cron.max_running_jobs = 20  # user")
+	assert.Contains(t, string(confContent), "cron.database_name = postgres  # extension pg_cron")
+
+	composeContent, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(composeContent), "cron.max_running_jobs=20")
+	assert.NotContains(t, string(composeContent), "cron.max_running_jobs=5")
+}
+
+func TestExportOrchestrator_UserSettingResolvesConflict(t *testing.T) {
+	saved := maps.Clone(extensions.Catalog)
+	t.Cleanup(func() { extensions.Catalog = saved })
+	extensions.Catalog["acme_replica"] = extensions.Extension{GUCs: map[string]string{"wal_level": "replica"}}
+	dir := t.TempDir()
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Extensions: []string{"wal2json", "acme_replica"},
+		Settings:   map[string]string{"wal_level": "logical"},
+	})
+
+	require.NoError(t, err)
+	confContent, err := os.ReadFile(filepath.Join(dir, "postgresql.conf.pgbox"))
+	require.NoError(t, err)
+	assert.Contains(t, string(confContent), "wal_level = logical  # user")
+}
+
+func TestExportOrchestrator_GUCChoices(t *testing.T) {
+	saved := maps.Clone(extensions.Catalog)
+	t.Cleanup(func() { extensions.Catalog = saved })
+	extensions.Catalog["acme_replica"] = extensions.Extension{GUCs: map[string]string{"wal_level": "replica"}}
+	cfg := ExportConfig{TargetDir: t.TempDir(), Version: "17", Extensions: []string{"wal2json", "acme_replica"}}
+	orch := NewExportOrchestrator(&bytes.Buffer{})
+	require.NotNil(t, orch.GUCConflicts(cfg))
+
+	cfg.GUCChoices = map[string]string{"wal_level": "replica"}
+	assert.Nil(t, orch.GUCConflicts(cfg))
+	require.NoError(t, orch.Run(cfg))
+
+	confContent, err := os.ReadFile(filepath.Join(cfg.TargetDir, "postgresql.conf.pgbox"))
+	require.NoError(t, err)
+	assert.Contains(t, string(confContent), "wal_level = replica  # extension acme_replica")
+}
+
+func TestExportOrchestrator_UserSettingsPreload(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewExportOrchestrator(&buf).Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		Extensions: []string{"pg_cron"},
+		Settings:   map[string]string{"shared_preload_libraries": "auto_explain", "cron.database_name": "app"},
+	})
+
+	require.NoError(t, err)
+	confContent, err := os.ReadFile(filepath.Join(dir, "postgresql.conf.pgbox"))
+	require.NoError(t, err)
+	assert.Contains(t, string(confContent), "shared_preload_libraries = 'auto_explain,pg_cron'")
+	assert.Contains(t, string(confContent), "cron.database_name = app  # user")
+	assert.Contains(t, buf.String(), "Warning: --set cron.database_name=app overrides postgres required by extension pg_cron")
+}
+
+func TestExportOrchestrator_Profile(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewExportOrchestrator(&buf).Run(ExportConfig{
+		TargetDir: dir,
+		Version:   "17",
+		Port:      "5432",
+		Profile:   "ci",
+		Settings:  map[string]string{"autovacuum": "on"},
+	})
+
+	require.NoError(t, err)
+	confContent, err := os.ReadFile(filepath.Join(dir, "postgresql.conf.pgbox"))
+	require.NoError(t, err)
+	assert.Contains(t, string(confContent), "fsync = off  # profile ci")
+	assert.Contains(t, string(confContent), "autovacuum = on  # user")
+	composeContent, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(composeContent), "jit=off")
+	assert.Contains(t, buf.String(), "Warning: profile ci disables fsync")
+}
+
+func TestExportOrchestrator_Hardened(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewExportOrchestrator(&buf).Run(ExportConfig{
+		TargetDir: dir,
+		Version:   "17",
+		Port:      "5433",
+		Hardened:  true,
+	})
+
+	require.NoError(t, err)
+	composeContent, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	compose := string(composeContent)
+	assert.Contains(t, compose, `"127.0.0.1:5433:5432"`)
+	assert.Contains(t, compose, "POSTGRES_USER: pgbox_admin")
+	assert.NotContains(t, compose, "POSTGRES_PASSWORD: postgres")
+	assert.Contains(t, compose, "POSTGRES_HOST_AUTH_METHOD: scram-sha-256")
+	assert.Contains(t, compose, "security_opt:\n      - no-new-privileges:true")
+	assert.Contains(t, compose, "cap_drop:\n      - ALL")
+	assert.Contains(t, compose, "read_only: true")
+	assert.Contains(t, compose, "tmpfs:\n      - /tmp\n      - /var/run/postgresql")
+	assert.Contains(t, compose, "password_encryption=scram-sha-256")
+	assert.Contains(t, buf.String(), "Generated a password")
+}
+
+func TestExportOrchestrator_HardenedDevcontainerFeature(t *testing.T) {
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir: t.TempDir(),
+		Format:    FormatDevcontainerFeature,
+		Version:   "17",
+		Hardened:  true,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--hardened is not supported")
+}
+
 func TestExportOrchestrator_InvalidExtension(t *testing.T) {
 	dir, err := os.MkdirTemp("", "pgbox-export-test")
 	require.NoError(t, err)
@@ -179,3 +399,302 @@ func TestExportOrchestrator_CustomCredentials(t *testing.T) {
 	assert.Contains(t, string(composeContent), "POSTGRES_PASSWORD: mypassword")
 	assert.Contains(t, string(composeContent), "POSTGRES_DB: mydb")
 }
+
+func TestExportOrchestrator_MergesIntoInitDirectory(t *testing.T) {
+	dir := t.TempDir()
+	initDir := filepath.Join(dir, "docker-entrypoint-initdb.d")
+	require.NoError(t, os.MkdirAll(initDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(initDir, "10-schema.sql"), []byte("CREATE TABLE t (id int);\n"), 0644))
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		Extensions: []string{"hstore"},
+	})
+
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(initDir, "00-pgbox-init.sql"))
+	assert.NoFileExists(t, filepath.Join(dir, "init.sql"))
+
+	schema, err := os.ReadFile(filepath.Join(initDir, "10-schema.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t (id int);\n", string(schema))
+
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), "./docker-entrypoint-initdb.d:/docker-entrypoint-initdb.d:ro")
+	assert.Contains(t, buf.String(), "docker-entrypoint-initdb.d/10-schema.sql")
+}
+
+func TestExportOrchestrator_KeepsUserInitSQL(t *testing.T) {
+	dir := t.TempDir()
+	userSQL := "-- my seed data\nINSERT INTO t VALUES (1);\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "init.sql"), []byte(userSQL), 0644))
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		Extensions: []string{"hstore"},
+	})
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dir, "init.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, userSQL, string(content))
+
+	pgboxInit, err := os.ReadFile(filepath.Join(dir, "00-pgbox-init.sql"))
+	require.NoError(t, err)
+	assert.Contains(t, string(pgboxInit), "CREATE EXTENSION IF NOT EXISTS hstore;")
+
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), "./00-pgbox-init.sql:/docker-entrypoint-initdb.d/00-pgbox-init.sql:ro")
+	assert.Contains(t, string(compose), "./init.sql:/docker-entrypoint-initdb.d/init.sql:ro")
+}
+
+func TestExportOrchestrator_ReexportReusesPgboxInitSQL(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+	cfg := ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"hstore"}}
+
+	require.NoError(t, orch.Run(cfg))
+	require.NoError(t, orch.Run(cfg))
+
+	assert.FileExists(t, filepath.Join(dir, "init.sql"))
+	assert.NoFileExists(t, filepath.Join(dir, "00-pgbox-init.sql"))
+}
+
+func TestExportOrchestrator_SplitInit(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		Extensions: []string{"pgvector", "hstore"},
+		SplitInit:  true,
+	})
+
+	require.NoError(t, err)
+	initDir := filepath.Join(dir, "docker-entrypoint-initdb.d")
+	assert.FileExists(t, filepath.Join(initDir, "00-pgbox-init-10-pgvector.sql"))
+	assert.FileExists(t, filepath.Join(initDir, "00-pgbox-init-20-hstore.sql"))
+	assert.NoFileExists(t, filepath.Join(dir, "init.sql"))
+	assert.NoFileExists(t, filepath.Join(initDir, "00-pgbox-init.sql"))
+	assert.NotContains(t, buf.String(), "Found existing init scripts")
+
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), "./docker-entrypoint-initdb.d:/docker-entrypoint-initdb.d:ro")
+
+	t.Run("exporting without it again removes the split files", func(t *testing.T) {
+		err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+			TargetDir:  dir,
+			Version:    "17",
+			Port:       "5432",
+			Extensions: []string{"pgvector", "hstore"},
+		})
+
+		require.NoError(t, err)
+		assert.NoFileExists(t, filepath.Join(initDir, "00-pgbox-init-10-pgvector.sql"))
+		assert.NoFileExists(t, filepath.Join(initDir, "00-pgbox-init-20-hstore.sql"))
+		content, err := os.ReadFile(filepath.Join(initDir, "00-pgbox-init.sql"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "CREATE EXTENSION IF NOT EXISTS vector;")
+	})
+}
+
+func TestExportOrchestrator_DevcontainerFeature(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{
+		TargetDir:  dir,
+		Format:     FormatDevcontainerFeature,
+		Version:    "17",
+		Port:       "5433",
+		Extensions: []string{"pgvector"},
+	})
+
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "docker-compose.yml"))
+
+	devcontainer, err := os.ReadFile(filepath.Join(dir, ".devcontainer", "devcontainer.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(devcontainer), `"ghcr.io/ahacop/pgbox/pgbox:1"`)
+	assert.Contains(t, string(devcontainer), `"ghcr.io/devcontainers/features/docker-in-docker:2"`)
+	assert.Contains(t, string(devcontainer), "5433")
+
+	project, err := os.ReadFile(filepath.Join(dir, "pgbox.toml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(project), `version = "17"`)
+	assert.Contains(t, string(project), `extensions = ["pgvector"]`)
+	assert.Contains(t, buf.String(), "Created")
+}
+
+func TestExportOrchestrator_DevcontainerFeatureKeepsProjectFile(t *testing.T) {
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, "pgbox.toml")
+	require.NoError(t, os.WriteFile(projectPath, []byte("version = \"16\"\n"), 0644))
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{
+		TargetDir: dir,
+		Format:    FormatDevcontainerFeature,
+		Version:   "17",
+		Port:      "5432",
+	})
+
+	require.NoError(t, err)
+	project, err := os.ReadFile(projectPath)
+	require.NoError(t, err)
+	assert.Equal(t, "version = \"16\"\n", string(project))
+	assert.Contains(t, buf.String(), "Using existing")
+}
+
+func TestExportOrchestrator_Devcontainer(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{
+		TargetDir:  dir,
+		Format:     FormatDevcontainer,
+		Version:    "17",
+		Port:       "5433",
+		Extensions: []string{"pgvector"},
+		Password:   "secret",
+	})
+
+	require.NoError(t, err)
+	devcontainerDir := filepath.Join(dir, ".devcontainer")
+	assert.NoFileExists(t, filepath.Join(dir, "docker-compose.yml"))
+	assert.NoFileExists(t, filepath.Join(devcontainerDir, "Dockerfile"))
+	assert.FileExists(t, filepath.Join(devcontainerDir, "pgbox", "Dockerfile"))
+	assert.FileExists(t, filepath.Join(devcontainerDir, "pgbox", "init.sql"))
+
+	compose, err := os.ReadFile(filepath.Join(devcontainerDir, "docker-compose.pgbox.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), "context: ./pgbox")
+	assert.Contains(t, string(compose), "./pgbox/init.sql:/docker-entrypoint-initdb.d/init.sql:ro")
+	assert.Contains(t, string(compose), `"5433:5432"`)
+
+	snippet, err := os.ReadFile(filepath.Join(devcontainerDir, "devcontainer.pgbox.json"))
+	require.NoError(t, err)
+	var settings devcontainerSnippet
+	require.NoError(t, json.Unmarshal(snippet, &settings))
+	assert.Equal(t, []string{"docker-compose.yml", "docker-compose.pgbox.yml"}, settings.DockerComposeFile)
+	assert.Equal(t, []string{"db:5432"}, settings.ForwardPorts)
+	assert.Contains(t, settings.PostCreateCommand, "/dev/tcp/db/5432")
+	assert.Equal(t, "postgres://postgres:secret@db:5432/postgres", settings.RemoteEnv["DATABASE_URL"])
+	assert.Contains(t, buf.String(), "Merge them into .devcontainer/devcontainer.json")
+}
+
+func TestExportOrchestrator_DevcontainerEscapesDatabaseURL(t *testing.T) {
+	dir := t.TempDir()
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir: dir,
+		Format:    FormatDevcontainer,
+		Version:   "17",
+		Port:      "5432",
+		Password:  "p@ss/w:rd",
+	})
+
+	require.NoError(t, err)
+	snippet, err := os.ReadFile(filepath.Join(dir, ".devcontainer", "devcontainer.pgbox.json"))
+	require.NoError(t, err)
+	var settings devcontainerSnippet
+	require.NoError(t, json.Unmarshal(snippet, &settings))
+	assert.Equal(t, "postgres://postgres:p%40ss%2Fw%3Ard@db:5432/postgres", settings.RemoteEnv["DATABASE_URL"])
+}
+
+func TestExportOrchestrator_InvalidFormat(t *testing.T) {
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{TargetDir: t.TempDir(), Format: "helm", Version: "17"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid format "helm"`)
+}
+
+func TestExportOrchestrator_Arch(t *testing.T) {
+	for _, arch := range []string{"amd64", "arm64"} {
+		t.Run(arch, func(t *testing.T) {
+			dir := t.TempDir()
+			var buf bytes.Buffer
+			orch := NewExportOrchestrator(&buf)
+
+			err := orch.Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"pg_search"}, Arch: arch})
+
+			require.NoError(t, err)
+			dockerfile, err := os.ReadFile(filepath.Join(dir, "Dockerfile"))
+			require.NoError(t, err)
+			assert.Contains(t, string(dockerfile), "postgresql-17-pg-search_0.20.5-1PARADEDB-bookworm_"+arch+".deb")
+			assert.NotContains(t, string(dockerfile), "TARGETARCH")
+		})
+	}
+}
+
+func TestExportOrchestrator_ArchAll(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	orch := NewExportOrchestrator(&buf)
+
+	err := orch.Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"pg_search", "pgvector"}, Arch: ArchAll})
+
+	require.NoError(t, err)
+	dockerfile, err := os.ReadFile(filepath.Join(dir, "Dockerfile"))
+	require.NoError(t, err)
+	content := string(dockerfile)
+	assert.Contains(t, content, "ARG TARGETARCH")
+	assert.Contains(t, content, "# Install pg_search (.deb for amd64, arm64)")
+	assert.Contains(t, content, "amd64) url='https://github.com/paradedb/paradedb/releases/download/v0.20.5/postgresql-17-pg-search_0.20.5-1PARADEDB-bookworm_amd64.deb'")
+	assert.Contains(t, content, "arm64) url='https://github.com/paradedb/paradedb/releases/download/v0.20.5/postgresql-17-pg-search_0.20.5-1PARADEDB-bookworm_arm64.deb'")
+	// apt packages resolve for the target architecture by themselves
+	assert.Contains(t, content, "apt-get install -y --no-install-recommends postgresql-17-pgvector")
+}
+
+func TestExportOrchestrator_ArchAllMissingArtifact(t *testing.T) {
+	saved := extensions.Catalog["pg_search"]
+	t.Cleanup(func() { extensions.Catalog["pg_search"] = saved })
+	ext := saved
+	ext.DebURL = ""
+	ext.Debs = map[string]extensions.Artifact{"amd64": {URL: "https://example.com/pg{v}-search-amd64.deb"}}
+	extensions.Catalog["pg_search"] = ext
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: t.TempDir(), Version: "17", Extensions: []string{"pg_search"}, Arch: ArchAll})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no .deb package for arm64 (available for: amd64)")
+	assert.Contains(t, err.Error(), "export with --arch for one architecture instead")
+}
+
+func TestExportOrchestrator_InvalidArch(t *testing.T) {
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: t.TempDir(), Version: "17", Arch: "riscv64"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid arch "riscv64" (must be amd64, arm64, all)`)
+
+	err = NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: t.TempDir(), Format: FormatDevcontainerFeature, Version: "17", Arch: "arm64"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--arch is not supported with --format devcontainer-feature")
+}
@@ -0,0 +1,283 @@
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxSnapshotFile bounds the files snapshotExport keeps; pgbox's own files
+// are far smaller, and the directory may hold large unrelated ones.
+const maxSnapshotFile = 1 << 20
+
+// snapshotFile is one file of an export directory as it was.
+type snapshotFile struct {
+	content []byte
+	mode    fs.FileMode
+}
+
+// exportSnapshot holds the files export may write, by path relative to the
+// target directory, and which of their directories existed.
+type exportSnapshot struct {
+	files map[string]snapshotFile
+	dirs  map[string]bool
+}
+
+// exportDirs are the directories, relative to the target directory, that
+// the export formats write files to.
+var exportDirs = []string{
+	".",
+	initDirName,
+	".devcontainer",
+	filepath.Join(".devcontainer", devcontainerScaffoldDir),
+	filepath.Join(".devcontainer", devcontainerScaffoldDir, initDirName),
+}
+
+// snapshotExport reads the files directly in the export directories of
+// targetDir.
+func snapshotExport(targetDir string) (*exportSnapshot, error) {
+	snap := &exportSnapshot{files: make(map[string]snapshotFile), dirs: make(map[string]bool)}
+	for _, dir := range exportDirs {
+		entries, err := os.ReadDir(filepath.Join(targetDir, dir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(targetDir, dir), err)
+		}
+		snap.dirs[dir] = true
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || info.Size() > maxSnapshotFile {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			content, err := os.ReadFile(filepath.Join(targetDir, path))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(targetDir, path), err)
+			}
+			snap.files[path] = snapshotFile{content: content, mode: info.Mode().Perm()}
+		}
+	}
+	return snap, nil
+}
+
+// hasGeneratedFiles reports whether an earlier export wrote to the directory.
+func (s *exportSnapshot) hasGeneratedFiles() bool {
+	for _, f := range s.files {
+		if isGenerated(f.content) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGenerated reports whether content comes from pgbox: it has a pgbox block
+// or header.
+func isGenerated(content []byte) bool {
+	for _, marker := range []string{"pgbox: BEGIN", "-- pgbox: begin ", "generated by pgbox", "Generated by pgbox", "Written by pgbox"} {
+		if bytes.Contains(content, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// restore puts the directory back as the snapshot found it: files the export
+// added are removed, changed ones get their old content back, and
+// directories it created are removed once empty.
+func (s *exportSnapshot) restore(targetDir string, after *exportSnapshot) error {
+	for path := range after.files {
+		if _, ok := s.files[path]; !ok {
+			if err := os.Remove(filepath.Join(targetDir, path)); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+	}
+	for path, f := range s.files {
+		if now, ok := after.files[path]; ok && bytes.Equal(now.content, f.content) {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(targetDir, path), f.content, f.mode); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+	// Deepest first, so a parent is empty by the time it is removed
+	for i := len(exportDirs) - 1; i >= 0; i-- {
+		if dir := exportDirs[i]; !s.dirs[dir] && after.dirs[dir] {
+			_ = os.Remove(filepath.Join(targetDir, dir))
+		}
+	}
+	return nil
+}
+
+// fileChange is how one file of an export changed.
+type fileChange struct {
+	path    string
+	mark    string   // + added, - removed, ~ changed
+	details []string // What changed inside pgbox's blocks, such as "+ package X"
+}
+
+// diff returns the files that differ between the snapshot and after, in
+// path order.
+func (s *exportSnapshot) diff(after *exportSnapshot) []fileChange {
+	paths := make([]string, 0, len(s.files)+len(after.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	for path := range after.files {
+		if _, ok := s.files[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []fileChange
+	for _, path := range paths {
+		old, hadOld := s.files[path]
+		now, hasNow := after.files[path]
+		switch {
+		case !hadOld:
+			changes = append(changes, fileChange{path: path, mark: "+", details: diffItems(path, nil, now.content)})
+		case !hasNow:
+			changes = append(changes, fileChange{path: path, mark: "-", details: diffItems(path, old.content, nil)})
+		case !bytes.Equal(old.content, now.content):
+			changes = append(changes, fileChange{path: path, mark: "~", details: diffItems(path, old.content, now.content)})
+		}
+	}
+	return changes
+}
+
+// printChanges prints the changes as a diff-style summary.
+func printChanges(w io.Writer, changes []fileChange) {
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(w, "  %s %s\n", change.mark, change.path)
+		for _, detail := range change.details {
+			_, _ = fmt.Fprintf(w, "      %s\n", detail)
+		}
+	}
+}
+
+var (
+	// installComment marks each package layer in the Dockerfile's pgbox block.
+	installComment = regexp.MustCompile(`^# Install (\S+)`)
+	// settingLine matches a setting in postgresql.conf.pgbox, with the
+	// comment naming where it comes from.
+	settingLine = regexp.MustCompile(`^(\w+(?:\.\w+)*) = (.*?)(?:  # .*)?$`)
+	// fragmentBegin matches the start of an init SQL fragment.
+	fragmentBegin = regexp.MustCompile(`^-- pgbox: begin (\S+)`)
+)
+
+// diffItems describes what changed between two versions of the file at
+// path, for the files whose pgbox blocks list something: packages in the
+// Dockerfile, settings in postgresql.conf.pgbox and fragments in init SQL.
+func diffItems(path string, old, now []byte) []string {
+	switch name := filepath.Base(path); {
+	case name == "Dockerfile":
+		return diffLists("package", dockerfilePackages(old), dockerfilePackages(now))
+	case name == "postgresql.conf.pgbox":
+		return diffSettings(confSettings(old), confSettings(now))
+	case strings.HasSuffix(name, ".sql"):
+		return diffLists("fragment", initFragments(old), initFragments(now))
+	}
+	return nil
+}
+
+// dockerfilePackages lists the packages installed in a Dockerfile's pgbox
+// block.
+func dockerfilePackages(content []byte) []string {
+	var packages []string
+	inBlock := false
+	for _, line := range strings.Split(string(content), "\n") {
+		switch {
+		case strings.Contains(line, "# pgbox: BEGIN"):
+			inBlock = true
+		case strings.Contains(line, "# pgbox: END"):
+			inBlock = false
+		case inBlock:
+			if m := installComment.FindStringSubmatch(line); m != nil {
+				packages = append(packages, m[1])
+			}
+		}
+	}
+	return packages
+}
+
+// confSettings reads the settings of postgresql.conf.pgbox, up to the ALTER
+// SYSTEM commands that repeat them.
+func confSettings(content []byte) map[string]string {
+	settings := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "-- ") {
+			break
+		}
+		if m := settingLine.FindStringSubmatch(line); m != nil {
+			settings[m[1]] = m[2]
+		}
+	}
+	return settings
+}
+
+// initFragments lists the fragments of an init SQL file.
+func initFragments(content []byte) []string {
+	var fragments []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if m := fragmentBegin.FindStringSubmatch(line); m != nil {
+			fragments = append(fragments, m[1])
+		}
+	}
+	return fragments
+}
+
+// diffLists returns "- kind item" for items only in old and "+ kind item"
+// for items only in now.
+func diffLists(kind string, old, now []string) []string {
+	var lines []string
+	for _, item := range old {
+		if !slices.Contains(now, item) {
+			lines = append(lines, fmt.Sprintf("- %s %s", kind, item))
+		}
+	}
+	for _, item := range now {
+		if !slices.Contains(old, item) {
+			lines = append(lines, fmt.Sprintf("+ %s %s", kind, item))
+		}
+	}
+	return lines
+}
+
+// diffSettings returns the settings added, removed and changed, by name.
+func diffSettings(old, now map[string]string) []string {
+	keys := make([]string, 0, len(old)+len(now))
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range now {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		before, hadBefore := old[key]
+		after, hasAfter := now[key]
+		switch {
+		case !hadBefore:
+			lines = append(lines, fmt.Sprintf("+ %s = %s", key, after))
+		case !hasAfter:
+			lines = append(lines, fmt.Sprintf("- %s = %s", key, before))
+		case before != after:
+			lines = append(lines, fmt.Sprintf("~ %s = %s -> %s", key, before, after))
+		}
+	}
+	return lines
+}
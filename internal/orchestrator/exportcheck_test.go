@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportOrchestrator_ReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"pgvector"}}))
+
+	var buf bytes.Buffer
+	err := NewExportOrchestrator(&buf).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"pgvector"}})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No changes to the earlier export\n")
+
+	buf.Reset()
+	err = NewExportOrchestrator(&buf).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"pg_cron"}})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `Changes to the earlier export:
+  ~ Dockerfile
+      - package postgresql-17-pgvector
+      + package postgresql-17-cron
+  ~ docker-compose.yml
+  ~ init.sql
+      + fragment pg_cron-init
+  + postgresql.conf.pgbox
+`)
+	assert.Contains(t, buf.String(), "      + shared_preload_libraries = 'pg_cron'\n")
+}
+
+func TestExportOrchestrator_Check(t *testing.T) {
+	dir := t.TempDir()
+	cfg := ExportConfig{TargetDir: dir, Version: "17", Port: "5432", Extensions: []string{"pgvector"}}
+	require.NoError(t, NewExportOrchestrator(&bytes.Buffer{}).Run(cfg))
+	before, err := os.ReadFile(filepath.Join(dir, "init.sql"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	cfg.Check = true
+	require.NoError(t, NewExportOrchestrator(&buf).Run(cfg))
+	assert.Equal(t, "Export in "+dir+" is up to date\n", buf.String())
+
+	buf.Reset()
+	cfg.Extensions = []string{"pg_cron"}
+	err = NewExportOrchestrator(&buf).Run(cfg)
+	assert.EqualError(t, err, "export would change 4 file(s) in "+dir+"; run it without --check to update them")
+	assert.Contains(t, buf.String(), "  + postgresql.conf.pgbox\n")
+	after, err := os.ReadFile(filepath.Join(dir, "init.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after))
+	assert.NoFileExists(t, filepath.Join(dir, "postgresql.conf.pgbox"))
+
+	// A directory that does not exist yet is not left behind
+	fresh := filepath.Join(dir, "fresh")
+	err = NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: fresh, Version: "17", Port: "5432", Check: true})
+	assert.Error(t, err)
+	assert.NoDirExists(t, fresh)
+}
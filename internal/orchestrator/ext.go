@@ -0,0 +1,253 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// ExtConfig holds configuration for enabling or dropping extensions in a
+// running container.
+type ExtConfig struct {
+	ContainerName string
+	Extensions    []string
+	Project       *config.ProjectConfig // pgbox.toml whose extensions list is kept in sync, if any
+	Yes           bool                  // Rebuild without asking when an extension needs a new image
+	Cascade       bool                  // Also drop objects that depend on removed extensions
+}
+
+// ExtOrchestrator enables and drops extensions in a running container.
+type ExtOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+	input  io.Reader
+	newUp  func() *UpOrchestrator
+}
+
+// NewExtOrchestrator creates a new ExtOrchestrator. Confirmation answers are
+// read from r.
+func NewExtOrchestrator(d docker.Docker, w io.Writer, r io.Reader) *ExtOrchestrator {
+	return &ExtOrchestrator{
+		docker: d,
+		output: w,
+		input:  r,
+		newUp:  func() *UpOrchestrator { return NewUpOrchestrator(d, w) },
+	}
+}
+
+// Add enables extensions in a running container. Extensions the image
+// already ships, with their shared_preload_libraries already loaded, are
+// created in place. The rest need a rebuilt image, so after confirmation the
+// container is recreated with them, keeping its data volume.
+func (o *ExtOrchestrator) Add(ctx context.Context, cfg ExtConfig) error {
+	name, err := o.resolve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	version, err := o.docker.GetContainerEnv(ctx, name, "PG_MAJOR")
+	if err != nil || version == "" {
+		return fmt.Errorf("failed to read the PostgreSQL version of %s (is it a postgres image?)", name)
+	}
+	if err := extensions.ValidateVersion(cfg.Extensions, version); err != nil {
+		return err
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+
+	available, err := QueryLines(ctx, o.docker, name, user, database, "SELECT name FROM pg_available_extensions")
+	if err != nil {
+		return fmt.Errorf("failed to list available extensions: %w", err)
+	}
+	loaded, err := preloadedLibraries(ctx, o.docker, name, user, database)
+	if err != nil {
+		return err
+	}
+
+	var rebuild []string
+	for _, ext := range cfg.Extensions {
+		if !readyInPlace(ext, available, loaded) {
+			rebuild = append(rebuild, ext)
+		}
+	}
+
+	if len(rebuild) > 0 {
+		_, _ = fmt.Fprintf(o.output, "%s cannot be enabled in place: the image lacks its packages or it must be in shared_preload_libraries.\n",
+			strings.Join(rebuild, ", "))
+		if !cfg.Yes {
+			if o.input == nil {
+				return fmt.Errorf("enabling %s requires rebuilding the image and recreating %s; rerun with --yes", strings.Join(rebuild, ", "), name)
+			}
+			ok, err := confirm(o.output, o.input, fmt.Sprintf("Rebuild the image and recreate %s? The data volume is kept. (y/N): ", name))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				_, _ = fmt.Fprintln(o.output, "Nothing changed.")
+				return nil
+			}
+		}
+
+		upCfg, err := inspectUpConfig(ctx, o.docker, name)
+		if err != nil {
+			return err
+		}
+		for _, ext := range cfg.Extensions {
+			if !slices.Contains(upCfg.Extensions, ext) {
+				upCfg.Extensions = append(upCfg.Extensions, ext)
+			}
+		}
+		if err := recreateContainer(ctx, o.docker, o.newUp(), upCfg); err != nil {
+			return err
+		}
+	}
+
+	// Init scripts only run on an empty volume, so create the extensions here
+	for _, ext := range cfg.Extensions {
+		if _, err := QueryLines(ctx, o.docker, name, user, database, extensions.GetInitSQL(ext)); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", ext, err)
+		}
+		_, _ = fmt.Fprintf(o.output, "Enabled %s in %s\n", ext, name)
+		if len(extensions.GetInitdbScripts(ext)) > 0 {
+			logging.Warnf(o.output, "%s's initdb scripts only run on a new data volume and did not run in %s", ext, name)
+		}
+	}
+	return o.syncProject(cfg.Project, func(exts []string) []string {
+		for _, ext := range cfg.Extensions {
+			if !slices.Contains(exts, ext) {
+				exts = append(exts, ext)
+			}
+		}
+		return exts
+	})
+}
+
+// Remove drops extensions from a running container's database. Packages and
+// shared_preload_libraries entries stay in place until the container is
+// recreated.
+func (o *ExtOrchestrator) Remove(ctx context.Context, cfg ExtConfig) error {
+	name, err := o.resolve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+
+	for _, ext := range cfg.Extensions {
+		statement := "DROP EXTENSION IF EXISTS " + quoteIdent(extensions.GetSQLName(ext))
+		if cfg.Cascade {
+			statement += " CASCADE"
+		}
+		if _, err := QueryLines(ctx, o.docker, name, user, database, statement); err != nil {
+			return fmt.Errorf("failed to drop %s (use --cascade to drop objects that depend on it): %w", ext, err)
+		}
+		_, _ = fmt.Fprintf(o.output, "Dropped %s from %s\n", ext, name)
+		if preload := extensions.GetPreloadLibraries([]string{ext}); len(preload) > 0 {
+			_, _ = fmt.Fprintf(o.output, "Note: %s stays in shared_preload_libraries until the container is recreated\n", strings.Join(preload, ", "))
+		}
+	}
+	return o.syncProject(cfg.Project, func(exts []string) []string {
+		return slices.DeleteFunc(exts, func(ext string) bool { return slices.Contains(cfg.Extensions, ext) })
+	})
+}
+
+// resolve validates the extension names and finds the running container.
+func (o *ExtOrchestrator) resolve(ctx context.Context, cfg ExtConfig) (string, error) {
+	if len(cfg.Extensions) == 0 {
+		return "", fmt.Errorf("no extensions given")
+	}
+	if err := extensions.ValidateExtensions(cfg.Extensions); err != nil {
+		return "", err
+	}
+	return o.runningContainer(ctx, cfg.ContainerName)
+}
+
+// runningContainer resolves the container name and checks that the container
+// is running.
+func (o *ExtOrchestrator) runningContainer(ctx context.Context, containerName string) (string, error) {
+	name, _, err := ResolveContainerName(ctx, o.docker, containerName)
+	if err != nil {
+		return "", fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return "", fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	return name, nil
+}
+
+// InstalledExtension is an extension created in a container's database.
+type InstalledExtension struct {
+	SQLName string
+	Version string
+	Catalog string // Catalog name, empty when no catalog entry creates it
+}
+
+// Installed returns the extensions created in the database of a running
+// container, sorted by SQL name, along with the container and database names.
+func (o *ExtOrchestrator) Installed(ctx context.Context, containerName string) (string, string, []InstalledExtension, error) {
+	name, err := o.runningContainer(ctx, containerName)
+	if err != nil {
+		return "", "", nil, err
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+	rows, err := QueryLines(ctx, o.docker, name, user, database, "SELECT extname, extversion FROM pg_extension ORDER BY extname")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to list extensions in %s: %w", name, err)
+	}
+
+	var installed []InstalledExtension
+	for _, row := range rows {
+		sqlName, version, _ := strings.Cut(row, "\t")
+		catalogName, _ := extensions.CatalogName(sqlName)
+		installed = append(installed, InstalledExtension{SQLName: sqlName, Version: version, Catalog: catalogName})
+	}
+	return name, database, installed, nil
+}
+
+// syncProject rewrites the extensions list of the project file, if any, when
+// update changes it.
+func (o *ExtOrchestrator) syncProject(project *config.ProjectConfig, update func([]string) []string) error {
+	if project == nil {
+		return nil
+	}
+	exts := update(slices.Clone(project.Extensions))
+	if slices.Equal(exts, project.Extensions) {
+		return nil
+	}
+	if err := config.SetProjectExtensions(project.Path, exts); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(o.output, "Updated extensions in %s\n", project.Path)
+	return nil
+}
+
+// readyInPlace reports whether ext can be created without changing the image
+// or restarting the server: everything it creates is available and its
+// shared_preload_libraries entries are already loaded.
+func readyInPlace(ext string, available, loaded []string) bool {
+	created := extensions.GetCreatedSQLNames([]string{ext})
+	// Extensions that are not created with CREATE EXTENSION (e.g. output
+	// plugins) cannot be checked, so assume they need their package
+	if len(created) == 0 && (extensions.GetPackage(ext, "") != "" || extensions.HasDebURL(ext) || extensions.HasZipURL(ext)) {
+		return false
+	}
+	for _, name := range created {
+		if !slices.Contains(available, name) {
+			return false
+		}
+	}
+	for _, lib := range extensions.GetPreloadLibraries([]string{ext}) {
+		if !slices.Contains(loaded, lib) {
+			return false
+		}
+	}
+	return true
+}
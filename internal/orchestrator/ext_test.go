@@ -0,0 +1,218 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExtMock returns a mock of a running PostgreSQL 17 container that ships
+// the contrib extensions and has hstore installed.
+func newExtMock() *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.GetContainerEnvFunc = func(containerName, envVar string) (string, error) {
+		switch envVar {
+		case "PG_MAJOR":
+			return "17", nil
+		case "POSTGRES_PASSWORD":
+			return "secret", nil
+		}
+		return "", nil
+	}
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch args[0] {
+		case "port":
+			return "0.0.0.0:5433\n", nil
+		case "inspect":
+			return `["postgres","-c","work_mem=64MB","-c","shared_preload_libraries=pg_stat_statements"]`, nil
+		}
+		return "", nil
+	}
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		switch query := command[len(command)-1]; {
+		case strings.Contains(query, "pg_available_extensions"):
+			return "hstore\npg_trgm\nplpgsql\n", nil
+		case strings.Contains(query, "shared_preload_libraries"):
+			return "pg_stat_statements\n", nil
+		case strings.Contains(query, "pg_extension"):
+			return "plpgsql\nhstore\n", nil
+		}
+		return "", nil
+	}
+	return mock
+}
+
+// execQueries returns the SQL passed to psql -c in each ExecCommand call.
+func execQueries(mock *docker.MockDocker) []string {
+	var queries []string
+	for _, call := range mock.Calls.ExecCommand {
+		queries = append(queries, call.Command[len(call.Command)-1])
+	}
+	return queries
+}
+
+func TestExtOrchestrator_AddInPlace(t *testing.T) {
+	mock := newExtMock()
+	var buf bytes.Buffer
+	dir := t.TempDir()
+	path := filepath.Join(dir, config.ProjectFileName)
+	require.NoError(t, os.WriteFile(path, []byte("version = \"17\"\nextensions = [\"hstore\"]\n"), 0644))
+	project, err := config.LoadProject(path)
+	require.NoError(t, err)
+
+	err = NewExtOrchestrator(mock, &buf, nil).Add(t.Context(), ExtConfig{ContainerName: "pgbox-pg17", Extensions: []string{"pg_trgm"}, Project: project})
+
+	require.NoError(t, err)
+	assert.Contains(t, execQueries(mock), "CREATE EXTENSION IF NOT EXISTS pg_trgm;")
+	assert.Empty(t, mock.Calls.RemoveContainer)
+	assert.Contains(t, buf.String(), "Enabled pg_trgm in pgbox-pg17")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `extensions = ["hstore", "pg_trgm"]`)
+}
+
+func TestExtOrchestrator_AddRebuildsImage(t *testing.T) {
+	mock := newExtMock()
+	var buf bytes.Buffer
+
+	orch := NewExtOrchestrator(mock, &buf, nil)
+	orch.newUp = func() *UpOrchestrator {
+		up := newTestUpOrchestrator(mock, &buf)
+		up.readyTimeout = 0
+		return up
+	}
+	err := orch.Add(t.Context(), ExtConfig{ContainerName: "pgbox-pg17", Extensions: []string{"pgvector"}, Yes: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.StopContainer)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.RemoveContainer)
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	run := mock.Calls.RunPostgres[0]
+	assert.Equal(t, "pgbox-pg17", run.Opts.Name)
+	assert.Equal(t, "5433", run.Config.Port)
+	assert.Equal(t, "secret", run.Config.Password)
+	assert.True(t, strings.HasPrefix(run.Config.CustomImage, "pgbox-pg17-custom:"))
+	assert.Contains(t, run.Opts.ExtraArgs, "pgbox-pg17-data:/var/lib/postgresql/data")
+	assert.Contains(t, run.Opts.Command, "work_mem=64MB")
+	assert.Contains(t, execQueries(mock), "CREATE EXTENSION IF NOT EXISTS vector;")
+}
+
+func TestExtOrchestrator_AddRebuildKeepsHardeningAndEnv(t *testing.T) {
+	mock := newExtMock()
+	mock.GetContainerEnvFunc = func(containerName, envVar string) (string, error) {
+		return map[string]string{"PG_MAJOR": "17", "POSTGRES_PASSWORD": "secret", "POSTGRES_USER": "app", "APP_MODE": "dev"}[envVar], nil
+	}
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch {
+		case args[0] == "port":
+			return "127.0.0.1:5433\n", nil
+		case args[0] == "inspect" && strings.Contains(args[2], "Labels"):
+			return `{"dev.pgbox.extensions":"hstore","dev.pgbox.hardened":"true","dev.pgbox.env":"APP_MODE"}`, nil
+		case args[0] == "inspect":
+			return `["postgres","-c","password_encryption=scram-sha-256"]`, nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewExtOrchestrator(mock, &buf, nil)
+	orch.newUp = func() *UpOrchestrator {
+		up := newTestUpOrchestrator(mock, &buf)
+		up.readyTimeout = 0
+		return up
+	}
+	err := orch.Add(t.Context(), ExtConfig{ContainerName: "pgbox-pg17", Extensions: []string{"pgvector"}, Yes: true})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	run := mock.Calls.RunPostgres[0]
+	assert.Equal(t, "127.0.0.1", run.Opts.HostIP)
+	assert.Equal(t, "app", run.Config.User)
+	assert.Contains(t, run.Opts.ExtraArgs, "--read-only")
+	assert.Contains(t, run.Opts.ExtraArgs, "no-new-privileges")
+	assert.Contains(t, run.Opts.ExtraEnv, "POSTGRES_HOST_AUTH_METHOD=scram-sha-256")
+	assert.Contains(t, run.Opts.ExtraEnv, "APP_MODE=dev")
+	assert.Equal(t, "true", run.Opts.Labels["dev.pgbox.hardened"])
+	assert.Equal(t, "APP_MODE", run.Opts.Labels["dev.pgbox.env"])
+}
+
+func TestExtOrchestrator_AddRebuildDeclined(t *testing.T) {
+	mock := newExtMock()
+	var buf bytes.Buffer
+
+	err := NewExtOrchestrator(mock, &buf, strings.NewReader("n\n")).Add(t.Context(), ExtConfig{ContainerName: "pgbox-pg17", Extensions: []string{"pg_cron"}})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "pg_cron cannot be enabled in place")
+	assert.Contains(t, buf.String(), "Nothing changed.")
+	assert.Empty(t, mock.Calls.StopContainer)
+	assert.NotContains(t, strings.Join(execQueries(mock), "\n"), "CREATE EXTENSION")
+}
+
+func TestExtOrchestrator_AddRebuildNeedsConfirmation(t *testing.T) {
+	mock := newExtMock()
+
+	err := NewExtOrchestrator(mock, &bytes.Buffer{}, nil).Add(t.Context(), ExtConfig{ContainerName: "pgbox-pg17", Extensions: []string{"pgvector"}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rerun with --yes")
+}
+
+func TestExtOrchestrator_Remove(t *testing.T) {
+	mock := newExtMock()
+	var buf bytes.Buffer
+
+	err := NewExtOrchestrator(mock, &buf, nil).Remove(t.Context(), ExtConfig{ContainerName: "pgbox-pg17", Extensions: []string{"pgvector", "pg_cron"}, Cascade: true})
+
+	require.NoError(t, err)
+	queries := execQueries(mock)
+	assert.Contains(t, queries, `DROP EXTENSION IF EXISTS "vector" CASCADE`)
+	assert.Contains(t, queries, `DROP EXTENSION IF EXISTS "pg_cron" CASCADE`)
+	assert.Contains(t, buf.String(), "Note: pg_cron stays in shared_preload_libraries until the container is recreated")
+}
+
+func TestExtOrchestrator_UnknownExtension(t *testing.T) {
+	err := NewExtOrchestrator(newExtMock(), &bytes.Buffer{}, nil).Add(t.Context(), ExtConfig{Extensions: []string{"nope"}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown extensions: nope")
+}
+
+func TestExtOrchestrator_Installed(t *testing.T) {
+	mock := newExtMock()
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "acme_audit\t1.0\nplpgsql\t1.0\nvector\t0.8.0\n", nil
+	}
+	orch := NewExtOrchestrator(mock, &bytes.Buffer{}, strings.NewReader(""))
+
+	name, database, installed, err := orch.Installed(t.Context(), "pgbox-pg17")
+
+	require.NoError(t, err)
+	assert.Equal(t, "pgbox-pg17", name)
+	assert.Equal(t, "postgres", database)
+	assert.Equal(t, []InstalledExtension{
+		{SQLName: "acme_audit", Version: "1.0"},
+		{SQLName: "plpgsql", Version: "1.0", Catalog: "plpgsql"},
+		{SQLName: "vector", Version: "0.8.0", Catalog: "pgvector"},
+	}, installed)
+}
+
+func TestExtOrchestrator_InstalledNeedsRunningContainer(t *testing.T) {
+	mock := newExtMock()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return false, nil }
+	orch := NewExtOrchestrator(mock, &bytes.Buffer{}, strings.NewReader(""))
+
+	_, _, _, err := orch.Installed(t.Context(), "pgbox-pg17")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not running")
+	assert.Empty(t, mock.Calls.ExecCommand)
+}
@@ -0,0 +1,343 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// GrantsConfig holds configuration for the grants command.
+type GrantsConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Role          string // Only report grants to this role
+	DesiredFile   string // Compare against the grants listed in this file
+}
+
+// Grant is one privilege held by a role, in the canonical statement form
+// printed by pgbox grants and accepted in a desired grants file.
+type Grant struct {
+	Role      string // Grantee, unquoted; PUBLIC for grants to everyone
+	Statement string
+}
+
+// GrantsOrchestrator reports role memberships and privileges for a running container.
+type GrantsOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewGrantsOrchestrator creates a new GrantsOrchestrator.
+func NewGrantsOrchestrator(d docker.Docker, w io.Writer) *GrantsOrchestrator {
+	return &GrantsOrchestrator{docker: d, output: w}
+}
+
+// membershipQuery lists role memberships, leaving out the built-in pg_ roles
+// as members.
+const membershipQuery = `SELECT m.rolname, quote_ident(r.rolname), quote_ident(m.rolname)
+FROM pg_auth_members am
+JOIN pg_roles r ON r.oid = am.roleid
+JOIN pg_roles m ON m.oid = am.member
+WHERE m.rolname !~ '^pg_'
+ORDER BY 1, 2`
+
+// objectPrivilegeQuery lists privileges granted on the current database,
+// schemas, tables and sequences, leaving out the owner's implicit privileges.
+const objectPrivilegeQuery = `SELECT COALESCE(g.rolname, 'PUBLIC'), COALESCE(quote_ident(g.rolname), 'PUBLIC'), o.kind, o.object, a.privilege_type
+FROM (
+  SELECT 'DATABASE' AS kind, quote_ident(datname) AS object, datacl AS acl, datdba AS owner
+  FROM pg_database WHERE datname = current_database()
+  UNION ALL
+  SELECT 'SCHEMA', quote_ident(nspname), nspacl, nspowner
+  FROM pg_namespace WHERE nspname !~ '^pg_' AND nspname <> 'information_schema'
+  UNION ALL
+  SELECT CASE c.relkind WHEN 'S' THEN 'SEQUENCE' ELSE 'TABLE' END,
+    quote_ident(n.nspname) || '.' || quote_ident(c.relname), c.relacl, c.relowner
+  FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+  WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+    AND n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+) o
+CROSS JOIN LATERAL aclexplode(o.acl) a
+LEFT JOIN pg_roles g ON g.oid = a.grantee
+WHERE a.grantee <> o.owner AND COALESCE(g.rolname, '') !~ '^pg_'
+ORDER BY 1, 3, 4, 5`
+
+// defaultPrivilegeQuery lists ALTER DEFAULT PRIVILEGES entries.
+const defaultPrivilegeQuery = `SELECT COALESCE(g.rolname, 'PUBLIC'), COALESCE(quote_ident(g.rolname), 'PUBLIC'),
+  quote_ident(o.rolname), COALESCE(quote_ident(n.nspname), ''), d.defaclobjtype, a.privilege_type
+FROM pg_default_acl d
+JOIN pg_roles o ON o.oid = d.defaclrole
+LEFT JOIN pg_namespace n ON n.oid = d.defaclnamespace
+CROSS JOIN LATERAL aclexplode(d.defaclacl) a
+LEFT JOIN pg_roles g ON g.oid = a.grantee
+WHERE a.grantee <> d.defaclrole
+ORDER BY 1, 3, 4, 5, 6`
+
+// defaultPrivilegeKinds maps pg_default_acl.defaclobjtype to the object kind
+// used in ALTER DEFAULT PRIVILEGES.
+var defaultPrivilegeKinds = map[string]string{
+	"r": "TABLES",
+	"S": "SEQUENCES",
+	"f": "FUNCTIONS",
+	"T": "TYPES",
+	"n": "SCHEMAS",
+}
+
+// Statements accepted in a desired grants file.
+var (
+	membershipPattern       = regexp.MustCompile(`(?i)^GRANT\s+(\S+)\s+TO\s+(\S+)$`)
+	objectPrivilegePattern  = regexp.MustCompile(`(?i)^GRANT\s+(.+?)\s+ON\s+(DATABASE|SCHEMA|TABLE|SEQUENCE)\s+(\S+)\s+TO\s+(\S+)$`)
+	defaultPrivilegePattern = regexp.MustCompile(`(?i)^ALTER\s+DEFAULT\s+PRIVILEGES\s+FOR\s+ROLE\s+(\S+)(?:\s+IN\s+SCHEMA\s+(\S+))?\s+GRANT\s+(.+?)\s+ON\s+(TABLES|SEQUENCES|FUNCTIONS|TYPES|SCHEMAS)\s+TO\s+(\S+)$`)
+)
+
+// Run prints the grants in cfg.Database, or with cfg.DesiredFile set, the
+// differences from that file. Differences are returned as an error so the
+// command can gate CI.
+func (o *GrantsOrchestrator) Run(ctx context.Context, cfg GrantsConfig) error {
+	var desired []Grant
+	if cfg.DesiredFile != "" {
+		var err error
+		if desired, err = readGrantsFile(cfg.DesiredFile); err != nil {
+			return err
+		}
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	memberships, err := o.query(ctx, name, user, database, membershipQuery, 3, func(f []string) string {
+		return membershipGrant(f[1], f[2])
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read role memberships: %w", err)
+	}
+	objects, err := o.query(ctx, name, user, database, objectPrivilegeQuery, 5, func(f []string) string {
+		return objectGrant(f[4], f[2], f[3], f[1])
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read object privileges: %w", err)
+	}
+	defaults, err := o.query(ctx, name, user, database, defaultPrivilegeQuery, 6, func(f []string) string {
+		return defaultGrant(f[2], f[3], f[5], defaultPrivilegeKinds[f[4]], f[1])
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read default privileges: %w", err)
+	}
+
+	memberships = filterGrants(memberships, cfg.Role)
+	objects = filterGrants(objects, cfg.Role)
+	defaults = filterGrants(defaults, cfg.Role)
+
+	if cfg.DesiredFile == "" {
+		o.printSection("Role memberships", memberships)
+		o.printSection("Object privileges in "+database, objects)
+		o.printSection("Default privileges in "+database, defaults)
+		return nil
+	}
+
+	actual := append(append(memberships, objects...), defaults...)
+	missing, extra := diffGrants(filterGrants(desired, cfg.Role), actual)
+	if len(missing) == 0 && len(extra) == 0 {
+		_, _ = fmt.Fprintf(o.output, "Grants in %s match %s\n", database, cfg.DesiredFile)
+		return nil
+	}
+	for _, g := range missing {
+		_, _ = fmt.Fprintf(o.output, "+ %s\n", g.Statement)
+	}
+	for _, g := range extra {
+		_, _ = fmt.Fprintf(o.output, "- %s\n", g.Statement)
+	}
+	return fmt.Errorf("grants in %s differ from %s: %d missing, %d extra", database, cfg.DesiredFile, len(missing), len(extra))
+}
+
+// query runs a grants query whose first column is the unquoted grantee and
+// builds the canonical statement for each row with statement.
+func (o *GrantsOrchestrator) query(ctx context.Context, name, user, database, query string, columns int, statement func([]string) string) ([]Grant, error) {
+	rows, err := QueryLines(ctx, o.docker, name, user, database, query)
+	if err != nil {
+		return nil, err
+	}
+	var grants []Grant
+	for _, row := range rows {
+		fields := strings.Split(row, "\t")
+		if len(fields) != columns {
+			continue
+		}
+		grants = append(grants, Grant{Role: fields[0], Statement: statement(fields)})
+	}
+	return grants, nil
+}
+
+// printSection prints one group of grants as statements that can be saved
+// as a desired grants file.
+func (o *GrantsOrchestrator) printSection(title string, grants []Grant) {
+	_, _ = fmt.Fprintf(o.output, "-- %s\n", title)
+	if len(grants) == 0 {
+		_, _ = fmt.Fprintln(o.output, "-- (none)")
+	}
+	for _, g := range grants {
+		_, _ = fmt.Fprintf(o.output, "%s;\n", g.Statement)
+	}
+	_, _ = fmt.Fprintln(o.output)
+}
+
+func membershipGrant(parent, role string) string {
+	return fmt.Sprintf("GRANT %s TO %s", parent, role)
+}
+
+func objectGrant(privilege, kind, object, role string) string {
+	return fmt.Sprintf("GRANT %s ON %s %s TO %s", privilege, kind, object, role)
+}
+
+func defaultGrant(owner, schema, privilege, kind, role string) string {
+	in := ""
+	if schema != "" {
+		in = " IN SCHEMA " + schema
+	}
+	return fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s%s GRANT %s ON %s TO %s", owner, in, privilege, kind, role)
+}
+
+// filterGrants keeps the grants to role, or all grants when role is empty.
+func filterGrants(grants []Grant, role string) []Grant {
+	if role == "" {
+		return grants
+	}
+	var filtered []Grant
+	for _, g := range grants {
+		if g.Role == role {
+			filtered = append(filtered, g)
+		}
+	}
+	return filtered
+}
+
+// diffGrants returns the desired grants that are not held and the held
+// grants that are not desired, each sorted by statement.
+func diffGrants(desired, actual []Grant) (missing, extra []Grant) {
+	held := make(map[string]bool)
+	for _, g := range actual {
+		held[g.Statement] = true
+	}
+	wanted := make(map[string]bool)
+	for _, g := range desired {
+		wanted[g.Statement] = true
+		if !held[g.Statement] {
+			missing = append(missing, g)
+		}
+	}
+	for _, g := range actual {
+		if !wanted[g.Statement] {
+			extra = append(extra, g)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Statement < missing[j].Statement })
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Statement < extra[j].Statement })
+	return missing, extra
+}
+
+// readGrantsFile reads a desired grants file: one GRANT or ALTER DEFAULT
+// PRIVILEGES statement per line, with -- and # comments.
+func readGrantsFile(path string) ([]Grant, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open grants file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var grants []Grant
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parsed, err := parseGrant(strings.TrimSpace(strings.TrimSuffix(line, ";")))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		grants = append(grants, parsed...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read grants file: %w", err)
+	}
+	return grants, nil
+}
+
+// parseGrant parses one statement into canonical grants, expanding
+// comma-separated privilege lists.
+func parseGrant(statement string) ([]Grant, error) {
+	statement = strings.Join(strings.Fields(statement), " ")
+	var grants []Grant
+	switch {
+	case defaultPrivilegePattern.MatchString(statement):
+		m := defaultPrivilegePattern.FindStringSubmatch(statement)
+		for _, privilege := range splitPrivileges(m[3]) {
+			grants = append(grants, Grant{
+				Role:      unquoteIdent(m[5]),
+				Statement: defaultGrant(m[1], m[2], privilege, strings.ToUpper(m[4]), grantee(m[5])),
+			})
+		}
+	case objectPrivilegePattern.MatchString(statement):
+		m := objectPrivilegePattern.FindStringSubmatch(statement)
+		for _, privilege := range splitPrivileges(m[1]) {
+			grants = append(grants, Grant{
+				Role:      unquoteIdent(m[4]),
+				Statement: objectGrant(privilege, strings.ToUpper(m[2]), m[3], grantee(m[4])),
+			})
+		}
+	case membershipPattern.MatchString(statement):
+		m := membershipPattern.FindStringSubmatch(statement)
+		grants = append(grants, Grant{Role: unquoteIdent(m[2]), Statement: membershipGrant(m[1], m[2])})
+	default:
+		return nil, fmt.Errorf("unrecognized grant %q", statement)
+	}
+	return grants, nil
+}
+
+// splitPrivileges splits "SELECT, insert" into upper-case privilege names.
+func splitPrivileges(list string) []string {
+	var privileges []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.ToUpper(strings.TrimSpace(p)); p != "" {
+			privileges = append(privileges, p)
+		}
+	}
+	return privileges
+}
+
+// grantee normalizes the PUBLIC pseudo-role, which may be written in any case.
+func grantee(role string) string {
+	if strings.EqualFold(role, "PUBLIC") {
+		return "PUBLIC"
+	}
+	return role
+}
+
+// unquoteIdent strips double quotes from a quoted identifier.
+func unquoteIdent(ident string) string {
+	if strings.EqualFold(ident, "PUBLIC") {
+		return "PUBLIC"
+	}
+	if len(ident) >= 2 && strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return ident
+}
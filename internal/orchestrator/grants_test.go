@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func grantsMock() *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		query := command[len(command)-1]
+		switch {
+		case strings.Contains(query, "pg_auth_members"):
+			return "app\treadonly\tapp\n", nil
+		case strings.Contains(query, "pg_default_acl"):
+			return "app\tapp\tpostgres\tpublic\tr\tSELECT\n", nil
+		case strings.Contains(query, "aclexplode"):
+			return "app\tapp\tDATABASE\tshop\tCONNECT\n" +
+				"app\tapp\tTABLE\tpublic.orders\tSELECT\n" +
+				"app\tapp\tTABLE\tpublic.orders\tINSERT\n" +
+				"PUBLIC\tPUBLIC\tSCHEMA\tpublic\tUSAGE\n", nil
+		}
+		return "", nil
+	}
+	return mock
+}
+
+func TestGrantsOrchestrator_Report(t *testing.T) {
+	var buf bytes.Buffer
+
+	orch := NewGrantsOrchestrator(grantsMock(), &buf)
+	err := orch.Run(t.Context(), GrantsConfig{ContainerName: "my-postgres", Database: "shop", Role: "app"})
+
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "-- Role memberships\nGRANT readonly TO app;\n")
+	assert.Contains(t, out, "GRANT CONNECT ON DATABASE shop TO app;")
+	assert.Contains(t, out, "GRANT INSERT ON TABLE public.orders TO app;")
+	assert.Contains(t, out, "ALTER DEFAULT PRIVILEGES FOR ROLE postgres IN SCHEMA public GRANT SELECT ON TABLES TO app;")
+	assert.NotContains(t, out, "PUBLIC")
+}
+
+func TestGrantsOrchestrator_Diff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grants.sql")
+	require.NoError(t, os.WriteFile(path, []byte(`-- desired grants for the app role
+grant readonly to app;
+GRANT CONNECT ON DATABASE shop TO app;
+GRANT select, update ON TABLE public.orders TO app;
+ALTER DEFAULT PRIVILEGES FOR ROLE postgres IN SCHEMA public GRANT SELECT ON TABLES TO app;
+GRANT USAGE ON SCHEMA public TO public;
+`), 0644))
+	var buf bytes.Buffer
+
+	orch := NewGrantsOrchestrator(grantsMock(), &buf)
+	err := orch.Run(t.Context(), GrantsConfig{ContainerName: "my-postgres", Database: "shop", DesiredFile: path})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 missing, 1 extra")
+	assert.Equal(t, "+ GRANT UPDATE ON TABLE public.orders TO app\n- GRANT INSERT ON TABLE public.orders TO app\n", buf.String())
+}
+
+func TestGrantsOrchestrator_DiffMatches(t *testing.T) {
+	var report bytes.Buffer
+	require.NoError(t, NewGrantsOrchestrator(grantsMock(), &report).Run(t.Context(), GrantsConfig{Database: "shop", ContainerName: "my-postgres"}))
+	path := filepath.Join(t.TempDir(), "grants.sql")
+	require.NoError(t, os.WriteFile(path, report.Bytes(), 0644))
+	var buf bytes.Buffer
+
+	err := NewGrantsOrchestrator(grantsMock(), &buf).Run(t.Context(), GrantsConfig{ContainerName: "my-postgres", Database: "shop", DesiredFile: path})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Grants in shop match")
+}
+
+func TestParseGrant_Invalid(t *testing.T) {
+	_, err := parseGrant("REVOKE ALL ON TABLE t FROM app")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unrecognized grant")
+}
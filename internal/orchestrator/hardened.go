@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/model"
+)
+
+// HardenedUser is the superuser --hardened creates when no user is given,
+// instead of the well-known postgres.
+const HardenedUser = "pgbox_admin"
+
+// hardenedCapabilities are the capabilities the postgres entrypoint needs to
+// take ownership of the data directory and drop to the postgres user.
+// --hardened drops all others.
+var hardenedCapabilities = []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "SETGID", "SETUID"}
+
+// hardenedTmpfs are the paths that stay writable on the read-only root
+// filesystem, next to the data volume.
+var hardenedTmpfs = []string{"/tmp", "/var/run/postgresql"}
+
+// hardenedEnv and hardenedInitdbArgs make TCP connections authenticate with
+// SCRAM, including ones over the container's loopback, which initdb would
+// otherwise trust.
+var hardenedEnv = map[string]string{
+	"POSTGRES_HOST_AUTH_METHOD": "scram-sha-256",
+}
+
+var hardenedInitdbArgs = []string{"--auth-host=scram-sha-256"}
+
+// HardenedSettings are the server settings --hardened applies.
+var HardenedSettings = map[string]string{
+	"password_encryption": "scram-sha-256",
+}
+
+// hardenedHostIP is the address --hardened publishes the port on.
+const hardenedHostIP = "127.0.0.1"
+
+// applyHardenedSettings adds HardenedSettings to the model as a profile, so
+// --set can still override them.
+func applyHardenedSettings(pgConfModel *model.PGConfModel) {
+	for key, value := range HardenedSettings {
+		pgConfModel.ApplyGUC(key, value, model.GUCSource{Kind: model.SourceProfile, Name: "hardened"})
+	}
+}
+
+// hardenContainer adds the --hardened runtime restrictions to opts.
+func hardenContainer(opts *docker.ContainerOptions) {
+	opts.HostIP = hardenedHostIP
+	opts.ExtraArgs = append(opts.ExtraArgs, "--security-opt", "no-new-privileges", "--cap-drop", "ALL")
+	for _, capability := range hardenedCapabilities {
+		opts.ExtraArgs = append(opts.ExtraArgs, "--cap-add", capability)
+	}
+	opts.ExtraArgs = append(opts.ExtraArgs, "--read-only")
+	for _, path := range hardenedTmpfs {
+		opts.ExtraArgs = append(opts.ExtraArgs, "--tmpfs", path)
+	}
+	keys := make([]string, 0, len(hardenedEnv))
+	for key := range hardenedEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		opts.ExtraEnv = append(opts.ExtraEnv, key+"="+hardenedEnv[key])
+	}
+}
+
+// hardenInitdbArgs adds hardenedInitdbArgs to pgConfig's initdb arguments.
+// They go first, so a later --initdb-arg can still override them.
+func hardenInitdbArgs(pgConfig *config.PostgresConfig) {
+	var args []string
+	for _, arg := range hardenedInitdbArgs {
+		if !slices.Contains(pgConfig.InitdbArgs, arg) {
+			args = append(args, arg)
+		}
+	}
+	pgConfig.InitdbArgs = append(args, pgConfig.InitdbArgs...)
+}
+
+// hardenService adds the --hardened restrictions to an exported compose
+// service. Its port is expected to be published on hardenedHostIP already.
+func hardenService(m *model.ComposeModel) {
+	m.SecurityOpt = append(m.SecurityOpt, "no-new-privileges:true")
+	m.CapDrop = append(m.CapDrop, "ALL")
+	m.CapAdd = append(m.CapAdd, hardenedCapabilities...)
+	m.ReadOnly = true
+	m.Tmpfs = append(m.Tmpfs, hardenedTmpfs...)
+	for key, value := range hardenedEnv {
+		m.SetEnv(key, value)
+	}
+}
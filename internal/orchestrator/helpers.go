@@ -1,9 +1,22 @@
 package orchestrator
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/profiles"
+	"github.com/ahacop/pgbox/internal/util"
 )
 
 // ErrNoContainer is returned when no pgbox container is found.
@@ -12,15 +25,381 @@ var ErrNoContainer = fmt.Errorf("no running pgbox container found")
 // ResolveContainerName resolves the container name, finding a running pgbox container
 // if name is empty. Returns the resolved name and whether it was auto-detected.
 // Returns ErrNoContainer if name is empty and no container is found.
-func ResolveContainerName(d docker.Docker, name string) (resolvedName string, autoDetected bool, err error) {
+func ResolveContainerName(ctx context.Context, d docker.Docker, name string) (resolvedName string, autoDetected bool, err error) {
 	if name != "" {
 		return name, false, nil
 	}
 
-	foundName, err := d.FindPgboxContainer()
+	foundName, err := d.FindPgboxContainer(ctx)
 	if err != nil {
 		return "", false, ErrNoContainer
 	}
 
 	return foundName, true, nil
 }
+
+// ResolveCredentials fills in the user and database for connecting to a container.
+// Empty values are read from the container's POSTGRES_USER/POSTGRES_DB environment,
+// falling back to "postgres".
+func ResolveCredentials(ctx context.Context, d docker.Docker, name, user, database string) (string, string) {
+	if user == "" {
+		if envUser, err := d.GetContainerEnv(ctx, name, "POSTGRES_USER"); err == nil && envUser != "" {
+			user = envUser
+		} else {
+			user = "postgres"
+		}
+	}
+	if database == "" {
+		if envDB, err := d.GetContainerEnv(ctx, name, "POSTGRES_DB"); err == nil && envDB != "" {
+			database = envDB
+		} else {
+			database = "postgres"
+		}
+	}
+	return user, database
+}
+
+// containerPassword returns the password for connecting to a container: the
+// one pgbox generated and stored for it, else its POSTGRES_PASSWORD.
+func containerPassword(ctx context.Context, d docker.Docker, name string) string {
+	if state, err := config.LoadContainerState(name); err == nil && state != nil && state.Password != "" {
+		return state.Password
+	}
+	password, _ := d.GetContainerEnv(ctx, name, "POSTGRES_PASSWORD")
+	return password
+}
+
+// passwordEnv returns docker exec arguments that hand the stored password of
+// a container to client tools run inside it, which matters once local
+// connections require one (e.g. after pgbox reload --hba).
+func passwordEnv(name string) []string {
+	if state, err := config.LoadContainerState(name); err == nil && state != nil && state.Password != "" {
+		return []string{"-e", "PGPASSWORD=" + state.Password}
+	}
+	return nil
+}
+
+// QueryLines runs a SQL query inside the container with psql in unaligned,
+// tuples-only mode and returns the non-empty output lines. Columns are
+// separated by a tab character.
+func QueryLines(ctx context.Context, d docker.Docker, name, user, database, query string) ([]string, error) {
+	output, err := d.ExecCommand(ctx, name, "psql", "-X", "-U", user, "-d", database,
+		"-t", "-A", "-F", "\t", "-v", "ON_ERROR_STOP=1", "-c", query)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %s: %w", strings.TrimSpace(output), err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// preloadedLibraries returns the shared_preload_libraries the server in a
+// container was started with.
+func preloadedLibraries(ctx context.Context, d docker.Docker, name, user, database string) ([]string, error) {
+	rows, err := QueryLines(ctx, d, name, user, database, "SHOW shared_preload_libraries")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared_preload_libraries: %w", err)
+	}
+	var loaded []string
+	if len(rows) > 0 {
+		for _, lib := range strings.Split(rows[0], ",") {
+			if lib = strings.Trim(strings.TrimSpace(lib), `"`); lib != "" {
+				loaded = append(loaded, lib)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// containerLabels returns the labels of a container, or nil when they cannot
+// be read.
+func containerLabels(ctx context.Context, d docker.Docker, name string) map[string]string {
+	output, err := d.RunCommandWithOutput(ctx, "inspect", "-f", "{{json .Config.Labels}}", name)
+	if err != nil {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &labels); err != nil {
+		return nil
+	}
+	return labels
+}
+
+// sleepContext waits for d, returning early with the context's cause, such
+// as an expired --timeout, when ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cleanupTimeout bounds cleanup that runs after the command's context may
+// have ended.
+const cleanupTimeout = 30 * time.Second
+
+// cleanupContext returns a context for cleanup that has to run even when ctx
+// is done, such as stopping a container after --timeout expired. It carries
+// ctx's values but not its cancellation, and ends after cleanupTimeout.
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// validatePrefer checks that every preferred extension was actually requested.
+func validatePrefer(prefer, extNames []string) error {
+	requested := make(map[string]bool)
+	for _, name := range extNames {
+		requested[name] = true
+	}
+	for _, p := range prefer {
+		name := p
+		if _, ext, ok := strings.Cut(p, "="); ok {
+			name = ext
+		}
+		if !requested[name] {
+			return fmt.Errorf("--prefer %s: extension %s is not in the requested extension list", p, name)
+		}
+	}
+	return nil
+}
+
+// applyGUCChoices adds the values picked for conflicting GUCs to prefer and
+// settings: a value one of extNames sets becomes a "key=extension"
+// preference, any other value a setting. Choices for GUCs that prefer or
+// settings already decide, or that none of extNames sets, are left out.
+func applyGUCChoices(extNames []string, choices map[string]string, prefer []string, settings map[string]string) ([]string, map[string]string) {
+	if len(choices) == 0 {
+		return prefer, settings
+	}
+	prefer = slices.Clone(prefer)
+	settings = maps.Clone(settings)
+	if settings == nil {
+		settings = make(map[string]string)
+	}
+	for _, key := range slices.Sorted(maps.Keys(choices)) {
+		if _, set := settings[key]; set || preferred(prefer, key) {
+			continue
+		}
+		value := choices[key]
+		if ext := extensions.GetGUCProvider(extNames, key, value); ext != "" {
+			prefer = append(prefer, key+"="+ext)
+		} else if setsGUC(extNames, key) {
+			settings[key] = value
+		}
+	}
+	return prefer, settings
+}
+
+// preferred reports whether prefer decides the value of key, with a
+// "key=extension" entry or an extension that sets key.
+func preferred(prefer []string, key string) bool {
+	for _, p := range prefer {
+		if k, _, ok := strings.Cut(p, "="); ok {
+			if k == key {
+				return true
+			}
+		} else if setsGUC([]string{p}, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// setsGUC reports whether any of extNames sets key.
+func setsGUC(extNames []string, key string) bool {
+	for _, name := range extNames {
+		if ext, ok := extensions.Get(name); ok {
+			if _, set := ext.GUCs[key]; set {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gucConflicts returns the conflicts between the GUC values of extNames that
+// neither prefer nor settings decide, or nil when there are none.
+func gucConflicts(extNames, prefer []string, settings map[string]string) *extensions.ConflictError {
+	var conflictErr *extensions.ConflictError
+	if _, err := extensions.ResolveGUCs(extNames, prefer, slices.Collect(maps.Keys(settings))); errors.As(err, &conflictErr) {
+		return conflictErr
+	}
+	return nil
+}
+
+// applyProfile records the settings of a tuning profile, which take
+// precedence over values contributed by extensions.
+func applyProfile(pgConfModel *model.PGConfModel, name string) (profiles.Profile, error) {
+	profile, err := profiles.Get(name)
+	if err != nil {
+		return profiles.Profile{}, err
+	}
+	for _, key := range profile.SortedKeys() {
+		pgConfModel.ApplyGUC(key, profile.Settings[key], model.GUCSource{Kind: model.SourceProfile, Name: profile.Name})
+	}
+	return profile, nil
+}
+
+// applyUserSettings records user-supplied GUC overrides, which take precedence
+// over values contributed by extensions and profiles. Libraries given for
+// shared_preload_libraries are loaded in addition to the extensions' ones.
+// Returns a warning for each value that replaces one an extension requires.
+func applyUserSettings(pgConfModel *model.PGConfModel, settings map[string]string) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var warnings []string
+	for _, key := range keys {
+		value := settings[key]
+		if key == "shared_preload_libraries" {
+			for _, lib := range strings.Split(value, ",") {
+				if lib = strings.TrimSpace(lib); lib != "" {
+					pgConfModel.AddSharedPreload(lib)
+				}
+			}
+			continue
+		}
+		if source, ok := pgConfModel.Sources[key]; ok && source.Kind == model.SourceExtension && pgConfModel.GUCs[key] != value {
+			warnings = append(warnings, fmt.Sprintf("--set %s=%s overrides %s required by %s", key, value, pgConfModel.GUCs[key], source))
+		}
+		pgConfModel.ApplyGUC(key, value, model.GUCSource{Kind: model.SourceUser})
+	}
+	return warnings
+}
+
+// addPackages adds the packages and downloads the extensions need to the
+// Dockerfile model, using apk packages when its base image is Alpine-based
+// and apt packages and .deb/.zip downloads for arch otherwise. Returns
+// whether there were any.
+func addPackages(m *model.DockerfileModel, extNames []string, pgVersion, arch string) (bool, error) {
+	if m.GetPackageManager() == "apk" {
+		if missing := extensions.MissingApkPackages(extNames); len(missing) > 0 {
+			return false, fmt.Errorf("%s has no apk package for the Alpine base image %s; use a Debian-based image or set apk_package in a custom extension spec",
+				strings.Join(missing, ", "), m.BaseImage)
+		}
+		packages := extensions.GetApkPackages(extNames, pgVersion)
+		m.AddPackages(packages, "apk")
+		return len(packages) > 0, nil
+	}
+
+	packages := extensions.GetPackages(extNames, pgVersion)
+	m.AddPackages(packages, "apt")
+	var downloads bool
+	var err error
+	if arch == ArchAll {
+		downloads, err = addArchDownloads(m, extNames, pgVersion)
+	} else {
+		downloads, err = addDownloads(m, extNames, pgVersion, arch)
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(packages) > 0 || downloads, nil
+}
+
+// addDownloads adds the .deb and .zip downloads the extensions need on arch
+// to the Dockerfile model. Returns whether there were any.
+func addDownloads(m *model.DockerfileModel, extNames []string, pgVersion, arch string) (bool, error) {
+	debs, err := extensions.GetDebDownloads(extNames, pgVersion, arch)
+	if err != nil {
+		return false, err
+	}
+	zips, err := extensions.GetZipDownloads(extNames, pgVersion, arch)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range debs {
+		m.AddDebURLs(d.URL)
+		m.AddChecksum(d.URL, d.SHA256)
+	}
+	for _, d := range zips {
+		m.AddZipURLs(d.URL)
+		m.AddChecksum(d.URL, d.SHA256)
+	}
+	return len(debs) > 0 || len(zips) > 0, nil
+}
+
+// unverifiedDownloads returns the extensions whose downloads for arch, or for
+// any of util.DebArches when arch is ArchAll, have no recorded SHA-256, and
+// so would be installed without checking what was downloaded. Alpine images
+// install from apk and download nothing.
+func unverifiedDownloads(m *model.DockerfileModel, extNames []string, pgVersion, arch string) []string {
+	if m.GetPackageManager() == "apk" {
+		return nil
+	}
+	arches := []string{arch}
+	if arch == ArchAll {
+		arches = util.DebArches
+	}
+	var unverified []string
+	for _, a := range arches {
+		for _, name := range extensions.UnverifiedDownloads(extNames, pgVersion, a) {
+			if !slices.Contains(unverified, name) {
+				unverified = append(unverified, name)
+			}
+		}
+	}
+	return unverified
+}
+
+// unverifiedWarning is the warning for downloads unverifiedDownloads found.
+const unverifiedWarning = "no SHA-256 is recorded for the download of %s, so it is installed unverified; record one with scripts/checksums, or sha256 in a custom extension spec"
+
+// addArchDownloads adds the .deb and .zip downloads the extensions need on
+// each of util.DebArches, for a Dockerfile that picks them by the
+// architecture it is built for. Returns whether there were any.
+func addArchDownloads(m *model.DockerfileModel, extNames []string, pgVersion string) (bool, error) {
+	kinds := []struct {
+		name    string
+		resolve func([]string, string, string) ([]extensions.Download, error)
+	}{
+		{"deb", extensions.GetDebDownloads},
+		{"zip", extensions.GetZipDownloads},
+	}
+	found := false
+	for _, name := range extNames {
+		for _, kind := range kinds {
+			download := model.ArchDownload{Name: name, URLs: make(map[string]string), Checksums: make(map[string]string)}
+			for _, arch := range util.DebArches {
+				resolved, err := kind.resolve([]string{name}, pgVersion, arch)
+				if err != nil {
+					return false, fmt.Errorf("%w; export with --arch for one architecture instead", err)
+				}
+				for _, d := range resolved {
+					download.URLs[arch] = d.URL
+					if d.SHA256 != "" {
+						download.Checksums[arch] = d.SHA256
+					}
+				}
+			}
+			switch urls := slices.Compact(slices.Sorted(maps.Values(download.URLs))); {
+			case len(urls) == 0:
+				continue
+			case len(urls) == 1:
+				// The same file on every architecture needs no TARGETARCH
+				if kind.name == "deb" {
+					m.AddDebURLs(urls[0])
+				} else {
+					m.AddZipURLs(urls[0])
+				}
+				m.AddChecksum(urls[0], download.Checksums[util.DebArches[0]])
+			default:
+				m.AddArchDownload(kind.name, download)
+			}
+			found = true
+		}
+	}
+	return found, nil
+}
@@ -2,16 +2,35 @@ package orchestrator
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/stretchr/testify/assert"
 )
 
+// TestMain points the user config and data directories at a temporary
+// directory, so state files and image records written by orchestrators under
+// test never reach the real ones.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "pgbox-orchestrator-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_ = os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	_ = os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestResolveContainerName_WithExplicitName(t *testing.T) {
 	mock := docker.NewMockDocker()
 
-	name, autoDetected, err := ResolveContainerName(mock, "my-container")
+	name, autoDetected, err := ResolveContainerName(t.Context(), mock, "my-container")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "my-container", name)
@@ -26,7 +45,7 @@ func TestResolveContainerName_AutoDetect(t *testing.T) {
 		return "pgbox-pg17", nil
 	}
 
-	name, autoDetected, err := ResolveContainerName(mock, "")
+	name, autoDetected, err := ResolveContainerName(t.Context(), mock, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "pgbox-pg17", name)
@@ -39,9 +58,37 @@ func TestResolveContainerName_NoContainerFound(t *testing.T) {
 		return "", errors.New("no container found")
 	}
 
-	name, autoDetected, err := ResolveContainerName(mock, "")
+	name, autoDetected, err := ResolveContainerName(t.Context(), mock, "")
 
 	assert.ErrorIs(t, err, ErrNoContainer)
 	assert.Empty(t, name)
 	assert.False(t, autoDetected)
 }
+
+func TestQueryLines_SplitsRows(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "vector\t0.8.0\n\nplpgsql\t1.0\n", nil
+	}
+
+	lines, err := QueryLines(t.Context(), mock, "pgbox-pg17", "postgres", "postgres", "SELECT extname, extversion FROM pg_extension")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vector\t0.8.0", "plpgsql\t1.0"}, lines)
+	cmd := mock.Calls.ExecCommand[0].Command
+	assert.Equal(t, "psql", cmd[0])
+	assert.Contains(t, cmd, "-A")
+	assert.Equal(t, "SELECT extname, extversion FROM pg_extension", cmd[len(cmd)-1])
+}
+
+func TestQueryLines_IncludesOutputInError(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		return "ERROR:  relation \"nope\" does not exist\n", errors.New("exit status 1")
+	}
+
+	_, err := QueryLines(t.Context(), mock, "pgbox-pg17", "postgres", "postgres", "SELECT * FROM nope")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/extensions"
+)
+
+// InitConfig holds configuration for scaffolding a pgbox.toml file.
+type InitConfig struct {
+	Dir        string
+	Version    string
+	Port       string
+	Extensions []string
+	Force      bool // Overwrite an existing pgbox.toml
+}
+
+// InitOrchestrator handles creating a project configuration file.
+type InitOrchestrator struct {
+	output io.Writer
+}
+
+// NewInitOrchestrator creates a new InitOrchestrator.
+func NewInitOrchestrator(w io.Writer) *InitOrchestrator {
+	return &InitOrchestrator{output: w}
+}
+
+// Run writes a pgbox.toml to cfg.Dir.
+func (o *InitOrchestrator) Run(cfg InitConfig) error {
+	if err := extensions.ValidateExtensions(cfg.Extensions); err != nil {
+		return err
+	}
+	if err := extensions.ValidateVersion(cfg.Extensions, cfg.Version); err != nil {
+		return err
+	}
+
+	defaults := config.NewPostgresConfig()
+	project := config.ProjectConfig{InstanceConfig: config.InstanceConfig{
+		Version:    cfg.Version,
+		Port:       cfg.Port,
+		User:       defaults.User,
+		Password:   defaults.Password,
+		Database:   defaults.Database,
+		Extensions: cfg.Extensions,
+	}}
+
+	path, err := config.WriteProjectFile(cfg.Dir, project, cfg.Force)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Created %s\n", path)
+	_, _ = fmt.Fprintln(o.output, "\nNext steps:")
+	_, _ = fmt.Fprintln(o.output, "  Commit it so everyone gets the same database")
+	_, _ = fmt.Fprintln(o.output, "  Start PostgreSQL with: pgbox up")
+	return nil
+}
@@ -0,0 +1,40 @@
+package orchestrator
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitOrchestrator_WritesProjectFile(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewInitOrchestrator(&buf).Run(InitConfig{
+		Dir:        dir,
+		Version:    "17",
+		Port:       "5433",
+		Extensions: []string{"pgvector"},
+	})
+
+	require.NoError(t, err)
+	cfg, err := config.LoadProject(filepath.Join(dir, config.ProjectFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "17", cfg.Version)
+	assert.Equal(t, "5433", cfg.Port)
+	assert.Equal(t, "postgres", cfg.User)
+	assert.Equal(t, []string{"pgvector"}, cfg.Extensions)
+	assert.Contains(t, buf.String(), "pgbox up")
+}
+
+func TestInitOrchestrator_InvalidExtension(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := NewInitOrchestrator(&buf).Run(InitConfig{Dir: t.TempDir(), Version: "17", Extensions: []string{"nope"}})
+
+	assert.ErrorContains(t, err, "unknown extensions: nope")
+}
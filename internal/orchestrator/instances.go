@@ -0,0 +1,178 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// InstancesConfig holds configuration for starting or stopping all instances
+// declared in a project file.
+type InstancesConfig struct {
+	Project *config.ProjectConfig
+}
+
+// InstancesOrchestrator brings the [instances] of a pgbox.toml up and down
+// together, in dependency order, on a shared Docker network.
+type InstancesOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+	newUp  func() *UpOrchestrator
+}
+
+// NewInstancesOrchestrator creates a new InstancesOrchestrator.
+func NewInstancesOrchestrator(d docker.Docker, w io.Writer) *InstancesOrchestrator {
+	return &InstancesOrchestrator{
+		docker: d,
+		output: w,
+		newUp:  func() *UpOrchestrator { return NewUpOrchestrator(d, w) },
+	}
+}
+
+// Up starts every instance after the instances it depends on, then creates
+// postgres_fdw servers for the declared FDW links.
+func (o *InstancesOrchestrator) Up(ctx context.Context, cfg InstancesConfig) error {
+	order, err := cfg.Project.InstanceOrder()
+	if err != nil {
+		return err
+	}
+
+	network := projectNetworkName(cfg.Project.Path)
+	if _, err := o.docker.RunCommandWithOutput(ctx, "network", "inspect", network); err != nil {
+		if out, err := o.docker.RunCommandWithOutput(ctx, "network", "create", network); err != nil {
+			return fmt.Errorf("failed to create network %s: %s: %w", network, strings.TrimSpace(out), err)
+		}
+	}
+
+	for _, name := range order {
+		inst, _ := cfg.Project.Instance(name)
+		upCfg := instanceUpConfig(inst)
+
+		_, _ = fmt.Fprintf(o.output, "==> Starting instance %s\n", name)
+		up := o.newUp()
+		if err := up.Run(ctx, upCfg); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+
+		// Containers are started by the single-instance path, so join the
+		// network afterwards. Restarted containers may already be attached.
+		out, err := o.docker.RunCommandWithOutput(ctx, "network", "connect", "--alias", name, network, upCfg.ContainerName)
+		if err != nil && !strings.Contains(out, "already exists") {
+			return fmt.Errorf("failed to attach %s to network %s: %s: %w", name, network, strings.TrimSpace(out), err)
+		}
+
+		if len(inst.FDW) > 0 {
+			pgConfig := &config.PostgresConfig{User: upCfg.User, Database: upCfg.Database}
+			if !up.waitForReady(ctx, upCfg.ContainerName, pgConfig) {
+				if err := context.Cause(ctx); err != nil {
+					return fmt.Errorf("instance %s did not become ready for FDW setup: %w", name, err)
+				}
+				return fmt.Errorf("instance %s did not become ready for FDW setup", name)
+			}
+			for _, target := range inst.FDW {
+				if err := o.linkFDW(ctx, cfg.Project, upCfg, target); err != nil {
+					return fmt.Errorf("instance %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintf(o.output, "\nStarted %d instances on network %s: %s\n", len(order), network, strings.Join(order, ", "))
+	return nil
+}
+
+// Down stops every instance in reverse dependency order and removes the
+// shared network. It keeps going after a failure and reports all errors.
+func (o *InstancesOrchestrator) Down(ctx context.Context, cfg InstancesConfig) error {
+	order, err := cfg.Project.InstanceOrder()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	down := NewDownOrchestrator(o.docker, o.output, nil)
+	for i := len(order) - 1; i >= 0; i-- {
+		inst, _ := cfg.Project.Instance(order[i])
+		if err := down.Run(ctx, DownConfig{ContainerName: inst.Name}); err != nil {
+			errs = append(errs, fmt.Errorf("instance %s: %w", order[i], err))
+		}
+	}
+
+	_, _ = o.docker.RunCommandWithOutput(ctx, "network", "rm", projectNetworkName(cfg.Project.Path))
+	return errors.Join(errs...)
+}
+
+// linkFDW creates a postgres_fdw server and user mapping in the instance
+// described by from that points at the sibling instance target.
+func (o *InstancesOrchestrator) linkFDW(ctx context.Context, project *config.ProjectConfig, from UpConfig, target string) error {
+	t, _ := project.Instance(target)
+	targetCfg := instanceUpConfig(t)
+
+	server := quoteIdent(target)
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS postgres_fdw",
+		fmt.Sprintf("CREATE SERVER IF NOT EXISTS %s FOREIGN DATA WRAPPER postgres_fdw OPTIONS (host %s, port '5432', dbname %s)",
+			server, quoteLiteral(targetCfg.ContainerName), quoteLiteral(targetCfg.Database)),
+		fmt.Sprintf("CREATE USER MAPPING IF NOT EXISTS FOR CURRENT_USER SERVER %s OPTIONS (user %s, password %s)",
+			server, quoteLiteral(targetCfg.User), quoteLiteral(targetCfg.Password)),
+	}
+	for _, stmt := range statements {
+		if _, err := QueryLines(ctx, o.docker, from.ContainerName, from.User, from.Database, stmt); err != nil {
+			return fmt.Errorf("failed to link FDW server %s: %w", target, err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Linked postgres_fdw server %s -> %s/%s\n", server, targetCfg.ContainerName, targetCfg.Database)
+	_, _ = fmt.Fprintf(o.output, "  Import tables with: IMPORT FOREIGN SCHEMA public FROM SERVER %s INTO <local_schema>;\n", server)
+	return nil
+}
+
+// instanceUpConfig converts a merged instance definition into an UpConfig,
+// filling in pgbox's defaults for anything left unset.
+func instanceUpConfig(inst config.InstanceConfig) UpConfig {
+	defaults := config.NewPostgresConfig()
+	pick := func(value, fallback string) string {
+		if value != "" {
+			return value
+		}
+		return fallback
+	}
+	return UpConfig{
+		Version:       pick(inst.Version, defaults.Version),
+		Port:          inst.Port,
+		ContainerName: inst.Name,
+		Password:      pick(inst.Password, defaults.Password),
+		Database:      pick(inst.Database, defaults.Database),
+		User:          pick(inst.User, defaults.User),
+		Detach:        true,
+		Extensions:    inst.Extensions,
+		Prefer:        inst.Prefer,
+		Settings:      inst.Settings,
+	}
+}
+
+// networkNameInvalid matches characters Docker does not allow in network names.
+var networkNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// projectNetworkName derives the shared network name from the directory
+// containing the project file.
+func projectNetworkName(projectPath string) string {
+	base := networkNameInvalid.ReplaceAllString(filepath.Base(filepath.Dir(projectPath)), "-")
+	base = strings.Trim(base, "-.")
+	if base == "" {
+		base = "project"
+	}
+	return "pgbox-" + strings.ToLower(base)
+}
+
+// quoteLiteral quotes a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestProject(t *testing.T, content string) *config.ProjectConfig {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "My App")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, config.ProjectFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	project, err := config.LoadProject(path)
+	require.NoError(t, err)
+	return project
+}
+
+const twoInstanceProject = `
+password = "secret"
+
+[instances.app-db]
+port = "5432"
+fdw = ["analytics-db"]
+
+[instances.analytics-db]
+port = "5433"
+database = "analytics"
+`
+
+func TestInstancesOrchestrator_UpStartsInOrderAndLinksFDW(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	var buf bytes.Buffer
+
+	orch := NewInstancesOrchestrator(mock, &buf)
+	orch.newUp = func() *UpOrchestrator {
+		up := newTestUpOrchestrator(mock, &buf)
+		up.readyTimeout = 0
+		return up
+	}
+	err := orch.Up(t.Context(), InstancesConfig{Project: loadTestProject(t, twoInstanceProject)})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunPostgres, 2)
+	assert.Equal(t, "analytics-db", mock.Calls.RunPostgres[0].Opts.Name)
+	assert.Equal(t, "app-db", mock.Calls.RunPostgres[1].Opts.Name)
+	assert.Equal(t, "5433", mock.Calls.RunPostgres[0].Config.Port)
+	assert.Equal(t, "secret", mock.Calls.RunPostgres[1].Config.Password)
+
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"network", "connect", "--alias", "app-db", "pgbox-my-app", "app-db"})
+
+	var fdwStatements []string
+	for _, call := range mock.Calls.ExecCommand {
+		if call.Container == "app-db" && call.Command[0] == "psql" {
+			fdwStatements = append(fdwStatements, call.Command[len(call.Command)-1])
+		}
+	}
+	require.Len(t, fdwStatements, 3)
+	assert.Equal(t, "CREATE EXTENSION IF NOT EXISTS postgres_fdw", fdwStatements[0])
+	assert.Contains(t, fdwStatements[1], `CREATE SERVER IF NOT EXISTS "analytics-db" FOREIGN DATA WRAPPER postgres_fdw OPTIONS (host 'analytics-db', port '5432', dbname 'analytics')`)
+	assert.Contains(t, fdwStatements[2], "OPTIONS (user 'postgres', password 'secret')")
+	assert.Contains(t, buf.String(), "Started 2 instances on network pgbox-my-app: analytics-db, app-db")
+}
+
+func TestInstancesOrchestrator_UpStopsOnFailure(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunPostgresFunc = func(pgConfig *config.PostgresConfig, opts docker.ContainerOptions) error {
+		return errors.New("port is already allocated")
+	}
+	var buf bytes.Buffer
+
+	err := NewInstancesOrchestrator(mock, &buf).Up(t.Context(), InstancesConfig{Project: loadTestProject(t, twoInstanceProject)})
+
+	assert.ErrorContains(t, err, "instance analytics-db: port is already allocated")
+	assert.Len(t, mock.Calls.RunPostgres, 1)
+}
+
+func TestInstancesOrchestrator_DownReverseOrder(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.StopContainerFunc = func(name string) error {
+		if name == "app-db" {
+			return errors.New("no such container")
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+
+	err := NewInstancesOrchestrator(mock, &buf).Down(t.Context(), InstancesConfig{Project: loadTestProject(t, twoInstanceProject)})
+
+	assert.ErrorContains(t, err, "instance app-db")
+	assert.Equal(t, []string{"app-db", "analytics-db"}, mock.Calls.StopContainer)
+	last := mock.Calls.RunCommandWithOutput[len(mock.Calls.RunCommandWithOutput)-1]
+	assert.Equal(t, "network rm pgbox-my-app", strings.Join(last, " "))
+}
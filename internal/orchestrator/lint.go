@@ -0,0 +1,396 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/extensions"
+)
+
+// LintConfig holds configuration for the config lint command.
+type LintConfig struct {
+	ProjectFile string // pgbox.toml to check; empty finds it from the working directory
+	ExtDir      string // Directory of custom extension specs to check, if any
+}
+
+// LintOrchestrator checks pgbox.toml, the user config and custom extension
+// specs without starting anything.
+type LintOrchestrator struct {
+	output io.Writer
+}
+
+// NewLintOrchestrator creates a new LintOrchestrator.
+func NewLintOrchestrator(w io.Writer) *LintOrchestrator {
+	return &LintOrchestrator{output: w}
+}
+
+// lintIssue is one problem found by lint. Line and column are 1-based and
+// zero when the position is not known.
+type lintIssue struct {
+	file         string
+	line, column int
+	message      string
+}
+
+func (i lintIssue) String() string {
+	switch {
+	case i.file == "":
+		return i.message
+	case i.line == 0:
+		return fmt.Sprintf("%s: %s", i.file, i.message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", i.file, i.line, i.column, i.message)
+}
+
+// Run checks the configuration files and prints each problem found. It
+// returns an error when there is at least one.
+func (o *LintOrchestrator) Run(cfg LintConfig) error {
+	var issues []lintIssue
+	var checked []string
+
+	user, userPath, userIssues := lintUserConfig()
+	if userPath != "" {
+		checked = append(checked, userPath)
+	}
+	issues = append(issues, userIssues...)
+
+	specs := make(map[string]extensions.Extension)
+	if cfg.ExtDir != "" {
+		loaded, problems, err := extensions.CheckDir(cfg.ExtDir)
+		if err != nil {
+			return err
+		}
+		for name, ext := range loaded {
+			specs[name] = ext
+			checked = append(checked, ext.File)
+		}
+		for path, err := range problems {
+			checked = append(checked, path)
+			issues = append(issues, tomlIssue(path, err))
+		}
+	}
+	lookup := func(name string) (extensions.Extension, bool) {
+		if ext, ok := specs[name]; ok {
+			return ext, true
+		}
+		ext, ok := extensions.Catalog[name]
+		return ext, ok
+	}
+
+	projectPath := cfg.ProjectFile
+	if projectPath == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		projectPath = config.FindProjectFile(wd)
+	}
+	if projectPath != "" {
+		checked = append(checked, projectPath)
+		issues = append(issues, lintProject(projectPath, user, lookup)...)
+	}
+
+	if len(checked) == 0 {
+		_, _ = fmt.Fprintln(o.output, "Nothing to lint: no pgbox.toml, user config or extension specs found")
+		return nil
+	}
+	if len(issues) == 0 {
+		sort.Strings(checked)
+		_, _ = fmt.Fprintf(o.output, "No problems found in %s\n", strings.Join(checked, ", "))
+		return nil
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].file != issues[j].file {
+			return issues[i].file < issues[j].file
+		}
+		return issues[i].line < issues[j].line
+	})
+	for _, issue := range issues {
+		_, _ = fmt.Fprintln(o.output, issue)
+	}
+	if len(issues) == 1 {
+		return errors.New("found 1 problem")
+	}
+	return fmt.Errorf("found %d problems", len(issues))
+}
+
+// lintUserConfig loads the user config, returning its path when it exists
+// and any problems with it.
+func lintUserConfig() (*config.UserConfig, string, []lintIssue) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return nil, "", nil
+	}
+	path := filepath.Join(dir, config.UserConfigFileName)
+	if _, err := os.Stat(path); err != nil {
+		return nil, "", nil
+	}
+	user, err := config.LoadUserConfig()
+	if err != nil {
+		return nil, path, []lintIssue{tomlIssue(path, err)}
+	}
+	if v := user.DefaultVersion; v != "" && v != config.LatestKeyword && !slices.Contains(config.SupportedVersions, v) {
+		data, _ := os.ReadFile(path)
+		pos := tomlKeyPositions(data)["default_version"]
+		return nil, path, []lintIssue{{path, pos.line, pos.column, unsupportedVersion("default_version", v)}}
+	}
+	return user, path, nil
+}
+
+// lintScope is the top level of pgbox.toml or one of its [instances]: the
+// values it sets itself and the values in effect once defaults are merged.
+type lintScope struct {
+	prefix      []string
+	own, merged config.InstanceConfig
+}
+
+// lintProject checks a pgbox.toml file: unknown keys, versions, ports,
+// extensions and the PostgreSQL versions they support, and settings.
+func lintProject(path string, user *config.UserConfig, lookup func(string) (extensions.Extension, bool)) []lintIssue {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []lintIssue{{message: fmt.Sprintf("failed to read %s: %v", path, err)}}
+	}
+	project, unknown, err := config.DecodeProject(path)
+	if err != nil {
+		return []lintIssue{tomlIssue(path, err)}
+	}
+
+	positions := tomlKeyPositions(data)
+	var issues []lintIssue
+	report := func(key []string, format string, args ...any) {
+		pos := positions[strings.Join(key, ".")]
+		issues = append(issues, lintIssue{path, pos.line, pos.column, fmt.Sprintf(format, args...)})
+	}
+	for _, key := range unknown {
+		report([]string{key}, "unknown key %s", key)
+	}
+
+	defaultVersion, _ := (&config.Resolver{User: user}).Version()
+	scopes := []lintScope{{nil, project.InstanceConfig, project.InstanceConfig}}
+	for _, name := range slices.Sorted(maps.Keys(project.Instances)) {
+		merged, _ := project.Instance(name)
+		scopes = append(scopes, lintScope{[]string{"instances", name}, *project.Instances[name], merged})
+	}
+
+	for _, scope := range scopes {
+		key := func(parts ...string) []string { return append(slices.Clone(scope.prefix), parts...) }
+		own, merged := scope.own, scope.merged
+
+		version := merged.Version
+		versionValid := true
+		switch {
+		case version == "":
+			version = defaultVersion
+		case version == config.LatestKeyword:
+			version = config.SupportedVersions[len(config.SupportedVersions)-1]
+		case !slices.Contains(config.SupportedVersions, version):
+			versionValid = false
+			if own.Version != "" {
+				report(key("version"), "%s", unsupportedVersion("version", version))
+			}
+		}
+
+		if own.Port != "" {
+			if port, err := strconv.Atoi(own.Port); err != nil || port < 1 || port > 65535 {
+				report(key("port"), "port %q is not a port number between 1 and 65535", own.Port)
+			}
+		}
+
+		for _, list := range []struct {
+			name  string
+			names []string
+		}{{"extensions", own.Extensions}, {"prefer", own.Prefer}} {
+			for _, name := range list.names {
+				if _, ok := lookup(name); !ok {
+					report(key(list.name), "unknown extension %s (see pgbox list-extensions)", name)
+				}
+			}
+		}
+
+		// Only report versions the scope itself sets, so an instance does
+		// not repeat the problems of the top-level values it inherits
+		if versionValid && (own.Version != "" || len(own.Extensions) > 0) {
+			at := key("version")
+			if len(own.Extensions) > 0 || own.Version == "" {
+				at = key("extensions")
+			}
+			for _, name := range merged.Extensions {
+				if ext, ok := lookup(name); ok && !ext.SupportsVersion(version) {
+					report(at, "%s requires PostgreSQL %s, but the configured version is %s", name, ext.VersionRequirement(), version)
+				}
+			}
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(own.Settings)) {
+			if problem := lintSetting(name, own.Settings[name], version); problem != "" {
+				report(key(append([]string{"settings"}, strings.Split(name, ".")...)...), "%s", problem)
+			}
+		}
+	}
+
+	if len(project.Instances) > 0 {
+		if _, err := project.InstanceOrder(); err != nil {
+			issues = append(issues, lintIssue{file: path, message: err.Error()})
+		}
+	}
+	return issues
+}
+
+// lintSetting checks a setting's name and, for the parameters pgbox
+// documents, that it exists in version and that its value is one the
+// parameter accepts. Returns an empty string when nothing is wrong.
+func lintSetting(name, value, version string) string {
+	if !gucNamePattern.MatchString(name) {
+		return fmt.Sprintf("invalid setting name %q", name)
+	}
+	if _, documented := extensions.Parameters[strings.ToLower(name)]; !documented {
+		return ""
+	}
+	param, ok := extensions.GetParameter(name, version)
+	if !ok {
+		return fmt.Sprintf("setting %s does not exist in PostgreSQL %s", name, version)
+	}
+	switch param.Type {
+	case "enum":
+		if !slices.ContainsFunc(param.Values, func(v string) bool { return strings.EqualFold(v, value) }) {
+			return fmt.Sprintf("setting %s: %q is not one of: %s", name, value, strings.Join(param.Values, ", "))
+		}
+	case "bool":
+		switch strings.ToLower(value) {
+		case "on", "off", "true", "false", "yes", "no", "1", "0":
+		default:
+			return fmt.Sprintf("setting %s: %q is not a boolean (on or off)", name, value)
+		}
+	}
+	return ""
+}
+
+// unsupportedVersion describes a version key set to a PostgreSQL version
+// pgbox does not support.
+func unsupportedVersion(key, version string) string {
+	return fmt.Sprintf("%s %q is not a supported PostgreSQL version (%s or %s)",
+		key, version, strings.Join(config.SupportedVersions, ", "), config.LatestKeyword)
+}
+
+// tomlIssue turns an error from loading the TOML file at path into an issue,
+// positioned at the line and column the TOML parser reported.
+func tomlIssue(path string, err error) lintIssue {
+	var parseErr toml.ParseError
+	if !errors.As(err, &parseErr) {
+		return lintIssue{message: err.Error()}
+	}
+	message := parseErr.Message
+	if message == "" {
+		// Errors without a message only carry their text in Error(), after
+		// the "toml: line N (last key ...): " prefix
+		message = parseErr.Error()
+		if i := strings.Index(message, "): "); parseErr.LastKey != "" && i >= 0 {
+			message = message[i+len("): "):]
+		} else {
+			_, message, _ = strings.Cut(strings.TrimPrefix(message, "toml: "), ": ")
+		}
+	}
+	issue := lintIssue{file: path, line: parseErr.Position.Line, message: message}
+	// The parser counts a newline it stopped at as the start of the next
+	// line, so derive both from the byte offset
+	if data, err := os.ReadFile(path); err == nil && parseErr.Position.Start <= len(data) {
+		before := string(data[:parseErr.Position.Start])
+		issue.line = strings.Count(before, "\n") + 1
+		issue.column = len(before) - strings.LastIndexByte(before, '\n')
+	}
+	return issue
+}
+
+// keyPosition is where a TOML key is defined, 1-based.
+type keyPosition struct {
+	line, column int
+}
+
+// tomlKeyPositions maps each key defined in a TOML document, its parts
+// joined by dots, to where it is first defined, along with the tables that
+// contain it. It reads table headers and key/value lines only, which is
+// enough to point a message at the right line; keys inside inline tables
+// are not found.
+func tomlKeyPositions(data []byte) map[string]keyPosition {
+	positions := make(map[string]keyPosition)
+	record := func(parts []string, pos keyPosition) {
+		for n := 1; n <= len(parts); n++ {
+			key := strings.Join(parts[:n], ".")
+			if _, ok := positions[key]; !ok {
+				positions[key] = pos
+			}
+		}
+	}
+
+	var table []string
+	closing := "" // Delimiter that ends the multi-line string being read
+	for i, line := range strings.Split(string(data), "\n") {
+		if closing != "" {
+			if strings.Contains(line, closing) {
+				closing = ""
+			}
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		column := len(line) - len(trimmed) + 1
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			header := strings.TrimLeft(trimmed, "[")
+			end := strings.Index(header, "]")
+			if end < 0 {
+				continue
+			}
+			table = splitTOMLKey(header[:end])
+			record(table, keyPosition{i + 1, column + len(trimmed) - len(header)})
+			continue
+		}
+		name, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		record(append(slices.Clone(table), splitTOMLKey(name)...), keyPosition{i + 1, column})
+		for _, delim := range []string{`"""`, `'''`} {
+			if strings.Count(value, delim) == 1 {
+				closing = delim
+			}
+		}
+	}
+	return positions
+}
+
+// splitTOMLKey splits a dotted TOML key into its parts, removing quotes.
+func splitTOMLKey(key string) []string {
+	var parts []string
+	var part strings.Builder
+	var quote rune
+	for _, r := range key {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			part.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '.':
+			parts = append(parts, strings.TrimSpace(part.String()))
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(parts, strings.TrimSpace(part.String()))
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLintFile writes content to name in dir and returns its path.
+func writeLintFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLintOrchestrator_ReportsProblemsWithPositions(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := writeLintFile(t, dir, "pgbox.toml", `version = "17"
+port = "54321x"
+extensions = ["pgvector", "no_such_ext"]
+colour = "blue"
+
+[settings]
+wal_level = "maximal"
+  fsync = "maybe"
+cron.database_name = "postgres"
+
+[instances.legacy]
+version = "16"
+port = "5434"
+extensions = ["acme"]
+`)
+	extDir := filepath.Join(dir, "ext")
+	writeLintFile(t, extDir, "acme.toml", "package = \"postgresql-{version}-acme\"\nmin_pg = \"17\"\n")
+	broken := writeLintFile(t, extDir, "broken.toml", "package = \"x\"\nsql_name =\n")
+	var buf bytes.Buffer
+
+	err := NewLintOrchestrator(&buf).Run(LintConfig{ProjectFile: path, ExtDir: extDir})
+
+	assert.EqualError(t, err, "found 7 problems")
+	assert.Equal(t, broken+`:2:11: expected value but found '\n' instead
+`+path+`:2:1: port "54321x" is not a port number between 1 and 65535
+`+path+`:3:1: unknown extension no_such_ext (see pgbox list-extensions)
+`+path+`:4:1: unknown key colour
+`+path+`:7:1: setting wal_level: "maximal" is not one of: minimal, replica, logical
+`+path+`:8:3: setting fsync: "maybe" is not a boolean (on or off)
+`+path+`:14:1: acme requires PostgreSQL 17+, but the configured version is 16
+`, buf.String())
+}
+
+func TestLintOrchestrator_CleanFiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := writeLintFile(t, t.TempDir(), "pgbox.toml", `version = "latest"
+port = "5433"
+extensions = ["pgvector", "pg_cron"]
+
+[settings]
+cron.database_name = "app"
+wal_level = "logical"
+`)
+	var buf bytes.Buffer
+
+	err := NewLintOrchestrator(&buf).Run(LintConfig{ProjectFile: path})
+
+	require.NoError(t, err)
+	assert.Equal(t, "No problems found in "+path+"\n", buf.String())
+}
+
+func TestLintOrchestrator_UserConfig(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	userPath := writeLintFile(t, configHome, filepath.Join("pgbox", "config.toml"), "\ndefault_version = \"12\"\n")
+	project := writeLintFile(t, t.TempDir(), "pgbox.toml", "extensions = [\"pgvector\"]\n")
+	var buf bytes.Buffer
+
+	err := NewLintOrchestrator(&buf).Run(LintConfig{ProjectFile: project})
+
+	assert.EqualError(t, err, "found 1 problem")
+	assert.Equal(t, userPath+`:2:1: default_version "12" is not a supported PostgreSQL version (16, 17, 18 or latest)
+`, buf.String())
+}
+
+func TestTOMLKeyPositions(t *testing.T) {
+	positions := tomlKeyPositions([]byte(`name = "x"
+notes = """
+fake = 1
+"""
+[instances."db.main"]
+  settings.work_mem = "4MB"
+`))
+
+	assert.Equal(t, keyPosition{1, 1}, positions["name"])
+	assert.NotContains(t, positions, "fake", "keys inside multi-line strings are not keys")
+	assert.Equal(t, keyPosition{5, 2}, positions["instances.db.main"])
+	assert.Equal(t, keyPosition{6, 3}, positions["instances.db.main.settings.work_mem"])
+}
@@ -1,16 +1,23 @@
 package orchestrator
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"regexp"
 
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
 )
 
 // LogsConfig holds configuration for the logs command.
 type LogsConfig struct {
 	ContainerName string
 	Follow        bool
+	Since         string // Passed to docker logs --since (e.g. 10m, 2024-01-02T15:04:05)
+	Tail          string // Passed to docker logs --tail (number of lines or "all")
+	Grep          string // Regular expression; only matching lines are shown
 }
 
 // LogsOrchestrator handles showing PostgreSQL container logs.
@@ -25,20 +32,77 @@ func NewLogsOrchestrator(d docker.Docker, w io.Writer) *LogsOrchestrator {
 }
 
 // Run shows logs from the PostgreSQL container.
-func (o *LogsOrchestrator) Run(cfg LogsConfig) error {
-	name, autoDetected, err := ResolveContainerName(o.docker, cfg.ContainerName)
+func (o *LogsOrchestrator) Run(ctx context.Context, cfg LogsConfig) error {
+	var pattern *regexp.Regexp
+	if cfg.Grep != "" {
+		var err error
+		if pattern, err = regexp.Compile(cfg.Grep); err != nil {
+			return fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+	}
+
+	name, autoDetected, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
 	if err != nil {
 		return fmt.Errorf("%w. Start one with: pgbox up", err)
 	}
 	if autoDetected {
-		_, _ = fmt.Fprintf(o.output, "Showing logs for container: %s\n", name)
+		logging.Infof(o.output, "Showing logs for container: %s", name)
 	}
 
 	args := []string{"logs"}
 	if cfg.Follow {
 		args = append(args, "-f")
 	}
+	if cfg.Since != "" {
+		args = append(args, "--since", cfg.Since)
+	}
+	if cfg.Tail != "" {
+		args = append(args, "--tail", cfg.Tail)
+	}
 	args = append(args, name)
 
-	return o.docker.RunCommand(args...)
+	if pattern == nil {
+		return o.docker.RunCommand(ctx, args...)
+	}
+
+	// PostgreSQL logs to stderr, so filter both streams through one writer.
+	filter := &lineFilter{pattern: pattern, output: o.output}
+	err = o.docker.RunCommandWithIO(ctx, nil, filter, filter, args...)
+	filter.Flush()
+	return err
+}
+
+// lineFilter is a writer that passes through only complete lines matching pattern.
+type lineFilter struct {
+	pattern *regexp.Regexp
+	output  io.Writer
+	pending []byte
+}
+
+// Write buffers p and emits each complete line that matches the pattern.
+func (f *lineFilter) Write(p []byte) (int, error) {
+	f.pending = append(f.pending, p...)
+	for {
+		i := bytes.IndexByte(f.pending, '\n')
+		if i < 0 {
+			break
+		}
+		f.emit(f.pending[:i+1])
+		f.pending = f.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits a trailing line that was not newline-terminated.
+func (f *lineFilter) Flush() {
+	if len(f.pending) > 0 {
+		f.emit(append(f.pending, '\n'))
+		f.pending = nil
+	}
+}
+
+func (f *lineFilter) emit(line []byte) {
+	if f.pattern.Match(line) {
+		_, _ = f.output.Write(line)
+	}
 }
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"bytes"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/ahacop/pgbox/internal/docker"
@@ -14,7 +15,7 @@ func TestLogsOrchestrator_ShowsLogsForNamedContainer(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewLogsOrchestrator(mock, &buf)
-	err := orch.Run(LogsConfig{
+	err := orch.Run(t.Context(), LogsConfig{
 		ContainerName: "my-postgres",
 	})
 
@@ -28,7 +29,7 @@ func TestLogsOrchestrator_FollowFlag(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewLogsOrchestrator(mock, &buf)
-	err := orch.Run(LogsConfig{
+	err := orch.Run(t.Context(), LogsConfig{
 		ContainerName: "my-postgres",
 		Follow:        true,
 	})
@@ -46,7 +47,7 @@ func TestLogsOrchestrator_FindsRunningContainer(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewLogsOrchestrator(mock, &buf)
-	err := orch.Run(LogsConfig{})
+	err := orch.Run(t.Context(), LogsConfig{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, mock.Calls.FindPgboxContainer)
@@ -62,8 +63,57 @@ func TestLogsOrchestrator_NoContainerFound(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewLogsOrchestrator(mock, &buf)
-	err := orch.Run(LogsConfig{})
+	err := orch.Run(t.Context(), LogsConfig{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no running pgbox container found")
 }
+
+func TestLogsOrchestrator_SinceAndTail(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewLogsOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), LogsConfig{
+		ContainerName: "my-postgres",
+		Since:         "10m",
+		Tail:          "50",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logs", "--since", "10m", "--tail", "50", "my-postgres"}, mock.Calls.RunCommand[0])
+}
+
+func TestLogsOrchestrator_GrepFiltersLines(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		_, _ = stdout.Write([]byte("LOG:  database system is ready\nERROR:  relation \"us"))
+		_, _ = stderr.Write([]byte("ers\" does not exist\nLOG:  checkpoint starting\nFATAL:  role \"x\" does not exist"))
+		return nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewLogsOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), LogsConfig{
+		ContainerName: "my-postgres",
+		Follow:        true,
+		Grep:          "ERROR|FATAL",
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, mock.Calls.RunCommand)
+	assert.Equal(t, []string{"logs", "-f", "my-postgres"}, mock.Calls.RunCommandWithIO[0])
+	assert.Equal(t, "ERROR:  relation \"users\" does not exist\nFATAL:  role \"x\" does not exist\n", buf.String())
+}
+
+func TestLogsOrchestrator_InvalidGrep(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewLogsOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), LogsConfig{ContainerName: "my-postgres", Grep: "("})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid --grep pattern")
+	assert.Empty(t, mock.Calls.RunCommand)
+}
@@ -0,0 +1,198 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// MigrationsTable records which migration files were applied.
+const MigrationsTable = "pgbox_migrations"
+
+// MigrateConfig holds configuration for the migrate command.
+type MigrateConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Dir           string // Directory of .sql files, applied in name order
+	Target        string // Stop after this file, given by name or version prefix (e.g. "0003")
+	DryRun        bool   // Only list the files that would be applied
+}
+
+// MigrateOrchestrator applies a directory of SQL migrations to a container.
+type MigrateOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewMigrateOrchestrator creates a new MigrateOrchestrator.
+func NewMigrateOrchestrator(d docker.Docker, w io.Writer) *MigrateOrchestrator {
+	return &MigrateOrchestrator{docker: d, output: w}
+}
+
+// migration is a .sql file in the migrations directory.
+type migration struct {
+	File     string // Base name, e.g. 0001_create_users.sql
+	SQL      string
+	Checksum string // Hex SHA-256 of SQL
+}
+
+// Run applies the migrations in cfg.Dir that are not yet recorded in
+// MigrationsTable, in name order up to cfg.Target. Each file runs in its own
+// transaction together with its record, and the run stops at the first
+// failure.
+func (o *MigrateOrchestrator) Run(ctx context.Context, cfg MigrateConfig) error {
+	migrations, err := readMigrations(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	if cfg.Target != "" {
+		if migrations, err = migrationsUpTo(migrations, cfg.Target); err != nil {
+			return err
+		}
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	applied, err := o.appliedMigrations(ctx, name, user, database, cfg.DryRun)
+	if err != nil {
+		return err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		checksum, ok := applied[m.File]
+		if !ok {
+			pending = append(pending, m)
+			continue
+		}
+		if checksum != m.Checksum {
+			logging.Warnf(o.output, "%s changed since it was applied; it is not applied again", m.File)
+		}
+	}
+
+	if len(pending) == 0 {
+		_, _ = fmt.Fprintf(o.output, "%s is up to date (%d applied)\n", database, len(applied))
+		return nil
+	}
+	if cfg.DryRun {
+		_, _ = fmt.Fprintf(o.output, "Would apply %d of %d migrations to %s on %s:\n", len(pending), len(migrations), database, name)
+		for _, m := range pending {
+			_, _ = fmt.Fprintf(o.output, "  %s\n", m.File)
+		}
+		return nil
+	}
+
+	for i, m := range pending {
+		logging.Infof(o.output, "Applying %s...", m.File)
+		if err := o.apply(ctx, name, user, database, m); err != nil {
+			return fmt.Errorf("migration %s failed and was rolled back; the %d before it stay applied: %w", m.File, i, err)
+		}
+	}
+	_, _ = fmt.Fprintf(o.output, "Applied %d migrations to %s on %s\n", len(pending), database, name)
+	return nil
+}
+
+// appliedMigrations returns the checksums of the applied migrations, by file.
+// It creates MigrationsTable unless dryRun is set, in which case a missing
+// table means nothing was applied.
+func (o *MigrateOrchestrator) appliedMigrations(ctx context.Context, name, user, database string, dryRun bool) (map[string]string, error) {
+	if dryRun {
+		rows, err := QueryLines(ctx, o.docker, name, user, database, fmt.Sprintf("SELECT to_regclass(%s) IS NOT NULL", quoteLiteral(MigrationsTable)))
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 || strings.TrimSpace(rows[0]) != "t" {
+			return map[string]string{}, nil
+		}
+	} else {
+		create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (filename text PRIMARY KEY, checksum text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())", MigrationsTable)
+		if _, err := QueryLines(ctx, o.docker, name, user, database, create); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", MigrationsTable, err)
+		}
+	}
+
+	rows, err := QueryLines(ctx, o.docker, name, user, database, fmt.Sprintf("SELECT filename, checksum FROM %s", MigrationsTable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", MigrationsTable, err)
+	}
+	applied := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if file, checksum, ok := strings.Cut(row, "\t"); ok {
+			applied[file] = checksum
+		}
+	}
+	return applied, nil
+}
+
+// apply runs a migration and records it in one transaction.
+func (o *MigrateOrchestrator) apply(ctx context.Context, name, user, database string, m migration) error {
+	// The lone semicolon ends a last statement the file left unterminated
+	record := fmt.Sprintf("\n;\nINSERT INTO %s (filename, checksum) VALUES (%s, %s);\n",
+		MigrationsTable, quoteLiteral(m.File), quoteLiteral(m.Checksum))
+	script := io.MultiReader(strings.NewReader(m.SQL), strings.NewReader(record))
+	return o.docker.RunCommandWithIO(ctx, script, o.output, o.output, "exec", "-i", name,
+		"psql", "-X", "-q", "-U", user, "-d", database, "-v", "ON_ERROR_STOP=1", "--single-transaction", "-f", "-")
+}
+
+// readMigrations reads the .sql files in dir, sorted by name.
+func readMigrations(dir string) ([]migration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("migrations directory: %w", err)
+		}
+		return nil, fmt.Errorf("no .sql files in %s", dir)
+	}
+	sort.Strings(paths)
+
+	migrations := make([]migration, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, migration{
+			File:     filepath.Base(path),
+			SQL:      string(data),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	return migrations, nil
+}
+
+// migrationsUpTo returns the migrations up to and including target, given as
+// a file name, with or without .sql, or as the version before its first "_".
+func migrationsUpTo(migrations []migration, target string) ([]migration, error) {
+	for i, m := range migrations {
+		version, _, _ := strings.Cut(m.File, "_")
+		if m.File == target || strings.TrimSuffix(m.File, ".sql") == target || version == target {
+			return migrations[:i+1], nil
+		}
+	}
+	return nil, fmt.Errorf("no migration matches --target %s", target)
+}
@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeMigrations creates a migrations directory with the given files.
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+// migrationsMock returns a mock container that has the given migrations
+// recorded, keyed by file with their checksums, and collects the scripts
+// psql receives.
+func migrationsMock(applied map[string]string, tableExists bool, scripts *[]string) *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		query := command[len(command)-1]
+		switch {
+		case strings.HasPrefix(query, "SELECT to_regclass"):
+			if tableExists {
+				return "t\n", nil
+			}
+			return "f\n", nil
+		case strings.HasPrefix(query, "SELECT filename"):
+			var rows []string
+			for file, checksum := range applied {
+				rows = append(rows, file+"\t"+checksum)
+			}
+			return strings.Join(rows, "\n"), nil
+		}
+		return "", nil
+	}
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		data, _ := io.ReadAll(stdin)
+		*scripts = append(*scripts, string(data))
+		return nil
+	}
+	return mock
+}
+
+func TestMigrateOrchestrator_AppliesPendingInOrder(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"0002_add_email.sql":    "ALTER TABLE users ADD COLUMN email text;",
+		"0001_create_users.sql": "CREATE TABLE users (id int);\n",
+		"0003_seed.sql":         "INSERT INTO users VALUES (1);\n",
+		"README.md":             "not a migration",
+	})
+	first, err := readMigrations(dir)
+	require.NoError(t, err)
+	var scripts []string
+	mock := migrationsMock(map[string]string{"0001_create_users.sql": first[0].Checksum}, true, &scripts)
+	var buf bytes.Buffer
+
+	err = NewMigrateOrchestrator(mock, &buf).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir})
+
+	require.NoError(t, err)
+	require.Len(t, scripts, 2)
+	assert.True(t, strings.HasPrefix(scripts[0], "ALTER TABLE users ADD COLUMN email text;\n;\nINSERT INTO pgbox_migrations (filename, checksum) VALUES ('0002_add_email.sql', '"))
+	assert.True(t, strings.HasPrefix(scripts[1], "INSERT INTO users VALUES (1);"))
+	assert.Equal(t, []string{"exec", "-i", "pgbox-pg17", "psql", "-X", "-q", "-U", "postgres", "-d", "postgres",
+		"-v", "ON_ERROR_STOP=1", "--single-transaction", "-f", "-"}, mock.Calls.RunCommandWithIO[0])
+	assert.Contains(t, mock.Calls.ExecCommand[0].Command[len(mock.Calls.ExecCommand[0].Command)-1], "CREATE TABLE IF NOT EXISTS pgbox_migrations")
+	out := buf.String()
+	assert.NotContains(t, out, "Applying 0001_create_users.sql")
+	assert.Contains(t, out, "Applying 0002_add_email.sql...\nApplying 0003_seed.sql...\n")
+	assert.Contains(t, out, "Applied 2 migrations to postgres on pgbox-pg17")
+}
+
+func TestMigrateOrchestrator_QuietHidesProgress(t *testing.T) {
+	require.NoError(t, logging.Configure(logging.LevelWarn, "text"))
+	t.Cleanup(func() { _ = logging.Configure(logging.LevelInfo, "text") })
+	dir := writeMigrations(t, map[string]string{"0001_create_users.sql": "CREATE TABLE users (id int);\n"})
+	var scripts []string
+	var buf bytes.Buffer
+
+	err := NewMigrateOrchestrator(migrationsMock(nil, true, &scripts), &buf).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir})
+
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "Applying")
+	assert.Contains(t, buf.String(), "Applied 1 migrations to postgres on pgbox-pg17")
+}
+
+func TestMigrateOrchestrator_Target(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"0001_a.sql": "SELECT 1;",
+		"0002_b.sql": "SELECT 2;",
+		"0003_c.sql": "SELECT 3;",
+	})
+
+	for _, target := range []string{"0002", "0002_b", "0002_b.sql"} {
+		var scripts []string
+		var buf bytes.Buffer
+		err := NewMigrateOrchestrator(migrationsMock(nil, true, &scripts), &buf).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir, Target: target})
+
+		require.NoError(t, err, target)
+		assert.Len(t, scripts, 2, target)
+	}
+
+	var scripts []string
+	err := NewMigrateOrchestrator(migrationsMock(nil, true, &scripts), io.Discard).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir, Target: "0009"})
+	assert.EqualError(t, err, "no migration matches --target 0009")
+	assert.Empty(t, scripts)
+}
+
+func TestMigrateOrchestrator_DryRun(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{"0001_a.sql": "SELECT 1;", "0002_b.sql": "SELECT 2;"})
+	var scripts []string
+	mock := migrationsMock(nil, false, &scripts)
+	var buf bytes.Buffer
+
+	err := NewMigrateOrchestrator(mock, &buf).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir, DryRun: true})
+
+	require.NoError(t, err)
+	assert.Empty(t, scripts)
+	assert.Len(t, mock.Calls.ExecCommand, 1, "a dry run neither creates nor reads a missing table")
+	assert.Equal(t, "Would apply 2 of 2 migrations to postgres on pgbox-pg17:\n  0001_a.sql\n  0002_b.sql\n", buf.String())
+}
+
+func TestMigrateOrchestrator_UpToDateWarnsAboutChangedFiles(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{"0001_a.sql": "SELECT 1;"})
+	var scripts []string
+	var buf bytes.Buffer
+
+	err := NewMigrateOrchestrator(migrationsMock(map[string]string{"0001_a.sql": "old"}, true, &scripts), &buf).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir})
+
+	require.NoError(t, err)
+	assert.Empty(t, scripts)
+	assert.Contains(t, buf.String(), "Warning: 0001_a.sql changed since it was applied")
+	assert.Contains(t, buf.String(), "postgres is up to date (1 applied)")
+}
+
+func TestMigrateOrchestrator_StopsAtFailure(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{"0001_a.sql": "SELECT 1;", "0002_b.sql": "SELECT broken;", "0003_c.sql": "SELECT 3;"})
+	var scripts []string
+	mock := migrationsMock(nil, true, &scripts)
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		data, _ := io.ReadAll(stdin)
+		scripts = append(scripts, string(data))
+		if strings.Contains(string(data), "broken") {
+			return errors.New("exit status 3")
+		}
+		return nil
+	}
+
+	err := NewMigrateOrchestrator(mock, io.Discard).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir})
+
+	assert.EqualError(t, err, "migration 0002_b.sql failed and was rolled back; the 1 before it stay applied: exit status 3")
+	assert.Len(t, scripts, 2)
+}
+
+func TestMigrateOrchestrator_Errors(t *testing.T) {
+	_, err := readMigrations(filepath.Join(t.TempDir(), "nope"))
+	assert.ErrorContains(t, err, "migrations directory")
+
+	_, err = readMigrations(t.TempDir())
+	assert.ErrorContains(t, err, "no .sql files in")
+
+	dir := writeMigrations(t, map[string]string{"0001_a.sql": "SELECT 1;"})
+	mock := docker.NewMockDocker()
+	err = NewMigrateOrchestrator(mock, io.Discard).Run(t.Context(), MigrateConfig{ContainerName: "pgbox-pg17", Dir: dir})
+	assert.EqualError(t, err, "container pgbox-pg17 is not running. Start it with: pgbox up")
+}
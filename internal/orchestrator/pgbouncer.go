@@ -0,0 +1,167 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// pgbouncerImage runs pgbouncer with the configuration mounted at
+// /etc/pgbouncer.
+const pgbouncerImage = "edoburu/pgbouncer"
+
+// pgbouncerPort is the port pgbouncer listens on, inside its container and,
+// by default, on the host.
+const pgbouncerPort = 6432
+
+// pgbouncerService is the compose service export adds for pgbouncer.
+const pgbouncerService = "pgbouncer"
+
+// pgbouncerConfigFile and pgbouncerUserlistFile are the generated
+// configuration, written next to the compose file by export and into the
+// container's artifacts directory by up.
+const (
+	pgbouncerConfigFile   = "pgbouncer.ini"
+	pgbouncerUserlistFile = "userlist.txt"
+)
+
+// pgbouncerConfigDir is where the image reads its configuration.
+const pgbouncerConfigDir = "/etc/pgbouncer"
+
+// pgbouncerContainerName is the container pgbox up runs pgbouncer in.
+func pgbouncerContainerName(containerName string) string {
+	return containerName + "-" + pgbouncerService
+}
+
+// pgbouncerConfig renders pgbouncer.ini: every database on host is pooled in
+// transaction mode, and the configured user can also use the admin console.
+func pgbouncerConfig(host string, pg *config.PostgresConfig) string {
+	auth := "scram-sha-256"
+	if pg.Password == "" {
+		auth = "trust"
+	}
+	return fmt.Sprintf(`; Written by pgbox: transaction pooling in front of %[1]s.
+[databases]
+* = host=%[1]s port=5432
+
+[pgbouncer]
+listen_addr = 0.0.0.0
+listen_port = %[2]d
+auth_type = %[3]s
+auth_file = %[4]s/%[5]s
+admin_users = %[6]s
+pool_mode = transaction
+max_client_conn = 200
+default_pool_size = 20
+ignore_startup_parameters = extra_float_digits
+`, host, pgbouncerPort, auth, pgbouncerConfigDir, pgbouncerUserlistFile, pg.User)
+}
+
+// pgbouncerUserlist renders userlist.txt. The password is kept in plain text
+// so pgbouncer can also use it to log in to the server.
+func pgbouncerUserlist(pg *config.PostgresConfig) string {
+	quote := func(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` }
+	return quote(pg.User) + " " + quote(pg.Password) + "\n"
+}
+
+// writePgbouncerFiles writes pgbouncer's configuration into containerName's
+// artifacts directory and returns the -v arguments that mount it. A dry run
+// prints the files instead.
+func (o *UpOrchestrator) writePgbouncerFiles(containerName string, pg *config.PostgresConfig) ([]string, error) {
+	files := []struct{ name, content string }{
+		{pgbouncerConfigFile, pgbouncerConfig(containerName, pg)},
+		{pgbouncerUserlistFile, pgbouncerUserlist(pg)},
+	}
+	var mounts []string
+	for _, f := range files {
+		mount := pgbouncerConfigDir + "/" + f.name
+		var path string
+		if o.dryRun {
+			dir, err := config.ArtifactsDir(containerName)
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(dir, f.name)
+			_, _ = fmt.Fprintf(o.output, "Would write %s:\n", path)
+			for _, line := range strings.Split(strings.TrimRight(f.content, "\n"), "\n") {
+				_, _ = fmt.Fprintf(o.output, "  %s\n", line)
+			}
+		} else {
+			var err error
+			if path, err = config.WriteArtifact(containerName, f.name, mount, []byte(f.content)); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+			}
+		}
+		mounts = append(mounts, "-v", fmt.Sprintf("%s:%s:ro", path, mount))
+	}
+	return mounts, nil
+}
+
+// startPgbouncer runs pgbouncer in front of containerName in its own
+// container, on the network it shares with its UIs, publishing the first
+// free host port from 6432 on hostIP, if given. Like the UIs, it is replaced
+// on every pgbox up and removed when stopped.
+func (o *UpOrchestrator) startPgbouncer(ctx context.Context, containerName string, pg *config.PostgresConfig, hostIP string) error {
+	network, err := o.joinNetwork(ctx, containerName)
+	if err != nil {
+		return err
+	}
+	mounts, err := o.writePgbouncerFiles(containerName, pg)
+	if err != nil {
+		return err
+	}
+
+	name := pgbouncerContainerName(containerName)
+	// Replace a pgbouncer left over from an earlier pgbox up
+	_, _ = o.docker.RunCommandWithOutput(ctx, "rm", "-f", name)
+
+	hostPort, err := util.FreePort(pgbouncerPort, 100)
+	if err != nil {
+		return fmt.Errorf("no free port for pgbouncer: %w", err)
+	}
+	publish := fmt.Sprintf("%d:%d", hostPort, pgbouncerPort)
+	if hostIP != "" {
+		publish = hostIP + ":" + publish
+	}
+
+	logging.Infof(o.output, "Starting pgbouncer %s...", name)
+	args := []string{"run", "-d", "--rm", "--name", name, "--network", network, "-p", publish}
+	args = append(args, mounts...)
+	args = append(args, pgbouncerImage)
+	if out, err := o.docker.RunCommandWithOutput(ctx, args...); err != nil {
+		return fmt.Errorf("failed to start pgbouncer %s: %s: %w", name, strings.TrimSpace(out), err)
+	}
+	if !o.dryRun {
+		// pgbouncer itself takes plain connections, whatever the server does
+		plain := *pg
+		plain.SSL = false
+		_, _ = fmt.Fprintf(o.output, "PgBouncer (transaction pooling): %s\n", connectionString(&plain, fmt.Sprint(hostPort)))
+	}
+	return nil
+}
+
+// addPgbouncerSidecar adds a compose service running pgbouncer in front of
+// the database service, with the configuration export wrote to relDir
+// mounted read-only. It is published on port 6432, on hostIP if given.
+func addPgbouncerSidecar(m *model.ComposeModel, relDir, hostIP string) {
+	port := fmt.Sprintf("%d:%d", pgbouncerPort, pgbouncerPort)
+	if hostIP != "" {
+		port = hostIP + ":" + port
+	}
+	m.AddSidecar(model.Sidecar{
+		Name:  pgbouncerService,
+		Image: pgbouncerImage,
+		Ports: []string{port},
+		Volumes: []string{
+			fmt.Sprintf("%s/%s:%s/%s:ro", relDir, pgbouncerConfigFile, pgbouncerConfigDir, pgbouncerConfigFile),
+			fmt.Sprintf("%s/%s:%s/%s:ro", relDir, pgbouncerUserlistFile, pgbouncerConfigDir, pgbouncerUserlistFile),
+		},
+		DependsOn: m.ServiceName,
+	})
+}
@@ -1,12 +1,15 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
+	"github.com/ahacop/pgbox/internal/container"
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/util"
 )
 
 // PsqlConfig holds configuration for the psql command.
@@ -15,6 +18,8 @@ type PsqlConfig struct {
 	Database      string
 	User          string
 	ExtraArgs     []string // Additional psql arguments after --
+	Start         bool     // Start a stopped container without asking
+	Replica       bool     // Connect to the container's read-only replica instead
 	// For testing: allows overriding stdin terminal detection
 	StdinIsTerminal *bool
 }
@@ -23,60 +28,50 @@ type PsqlConfig struct {
 type PsqlOrchestrator struct {
 	docker docker.Docker
 	output io.Writer
+	input  io.Reader
+	newUp  func() *UpOrchestrator
 }
 
-// NewPsqlOrchestrator creates a new PsqlOrchestrator.
-func NewPsqlOrchestrator(d docker.Docker, w io.Writer) *PsqlOrchestrator {
-	return &PsqlOrchestrator{docker: d, output: w}
+// NewPsqlOrchestrator creates a new PsqlOrchestrator. r is read for the
+// prompt to start a stopped container.
+func NewPsqlOrchestrator(d docker.Docker, w io.Writer, r io.Reader) *PsqlOrchestrator {
+	return &PsqlOrchestrator{
+		docker: d,
+		output: w,
+		input:  r,
+		newUp:  func() *UpOrchestrator { return NewUpOrchestrator(d, w) },
+	}
 }
 
 // Run connects to PostgreSQL via psql.
-func (o *PsqlOrchestrator) Run(cfg PsqlConfig) error {
-	name, _, err := ResolveContainerName(o.docker, cfg.ContainerName)
+func (o *PsqlOrchestrator) Run(ctx context.Context, cfg PsqlConfig) error {
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
 	if err != nil {
 		return fmt.Errorf("%w. Start one with: pgbox up", err)
 	}
 
-	if cfg.ContainerName != "" {
-		running, err := o.docker.IsContainerRunning(name)
-		if err != nil {
-			return fmt.Errorf("failed to check container status: %w", err)
-		}
-		if !running {
-			return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
-		}
+	stdinIsTerminal := util.StdinIsTerminal()
+	if cfg.StdinIsTerminal != nil {
+		stdinIsTerminal = *cfg.StdinIsTerminal
 	}
 
-	user := cfg.User
-	database := cfg.Database
-
-	if user == "" {
-		if envUser, err := o.docker.GetContainerEnv(name, "POSTGRES_USER"); err == nil && envUser != "" {
-			user = envUser
-		} else {
-			user = "postgres"
+	if cfg.Replica {
+		primary := name
+		name = container.ReplicaName(primary)
+		if running, _ := o.docker.IsContainerRunning(ctx, name); !running {
+			return fmt.Errorf("replica %s is not running. Start it with: pgbox up --with-replica -n %s", name, primary)
 		}
-	}
-	if database == "" {
-		if envDB, err := o.docker.GetContainerEnv(name, "POSTGRES_DB"); err == nil && envDB != "" {
-			database = envDB
-		} else {
-			database = "postgres"
+	} else if cfg.ContainerName != "" {
+		if err := o.ensureRunning(ctx, name, cfg.Start, stdinIsTerminal); err != nil {
+			return err
 		}
 	}
 
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
 	psqlArgs := []string{"psql", "-U", user, "-d", database}
 	psqlArgs = append(psqlArgs, cfg.ExtraArgs...)
 
-	stdinIsTerminal := false
-	if cfg.StdinIsTerminal != nil {
-		stdinIsTerminal = *cfg.StdinIsTerminal
-	} else {
-		if fileInfo, _ := os.Stdin.Stat(); (fileInfo.Mode() & os.ModeCharDevice) != 0 {
-			stdinIsTerminal = true
-		}
-	}
-
 	isInteractive := stdinIsTerminal
 	for _, arg := range psqlArgs {
 		if arg == "-c" || arg == "--command" ||
@@ -89,7 +84,7 @@ func (o *PsqlOrchestrator) Run(cfg PsqlConfig) error {
 	}
 
 	if isInteractive {
-		_, _ = fmt.Fprintf(o.output, "Connecting to %s as user '%s' to database '%s'...\n", name, user, database)
+		logging.Infof(o.output, "Connecting to %s as user '%s' to database '%s'...", name, user, database)
 		_, _ = fmt.Fprintln(o.output, "Type \\q to exit")
 		_, _ = fmt.Fprintln(o.output, strings.Repeat("-", 40))
 	}
@@ -100,8 +95,42 @@ func (o *PsqlOrchestrator) Run(cfg PsqlConfig) error {
 	} else if !stdinIsTerminal {
 		dockerArgs = append(dockerArgs, "-i")
 	}
+	dockerArgs = append(dockerArgs, passwordEnv(name)...)
 	dockerArgs = append(dockerArgs, name)
 	dockerArgs = append(dockerArgs, psqlArgs...)
 
-	return o.docker.RunInteractive(dockerArgs...)
+	return o.docker.RunInteractive(ctx, dockerArgs...)
+}
+
+// ensureRunning checks that the container runs. A stopped one is started and
+// waited for when start is set or the user agrees at the prompt, which is
+// only shown on a terminal.
+func (o *PsqlOrchestrator) ensureRunning(ctx context.Context, name string, start, interactive bool) error {
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if running {
+		return nil
+	}
+	existing, _ := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
+	if strings.TrimSpace(existing) != name {
+		return fmt.Errorf("container %s does not exist. Start it with: pgbox up", name)
+	}
+
+	if !start {
+		if !interactive {
+			return fmt.Errorf("container %s is not running. Start it with: pgbox up, or pass --start", name)
+		}
+		ok, err := confirm(o.output, o.input, fmt.Sprintf("Container %s is stopped. Start it? (y/N): ", name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("container %s is not running. Start it with: pgbox up, or pass --start", name)
+		}
+	}
+
+	logging.Infof(o.output, "Starting %s...", name)
+	return o.newUp().startStopped(ctx, name)
 }
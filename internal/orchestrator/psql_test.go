@@ -3,10 +3,13 @@ package orchestrator
 import (
 	"bytes"
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPsqlOrchestrator_ConnectsToNamedContainer(t *testing.T) {
@@ -20,8 +23,8 @@ func TestPsqlOrchestrator_ConnectsToNamedContainer(t *testing.T) {
 	var buf bytes.Buffer
 	notTerminal := false
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
 		ContainerName:   "my-postgres",
 		User:            "testuser",
 		Database:        "testdb",
@@ -42,8 +45,8 @@ func TestPsqlOrchestrator_InteractiveSession(t *testing.T) {
 	var buf bytes.Buffer
 	isTerminal := true
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
 		ContainerName:   "my-postgres",
 		User:            "postgres",
 		Database:        "postgres",
@@ -66,8 +69,8 @@ func TestPsqlOrchestrator_NonInteractiveWithCommand(t *testing.T) {
 	var buf bytes.Buffer
 	isTerminal := true // Even with terminal, -c makes it non-interactive
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
 		ContainerName:   "my-postgres",
 		User:            "postgres",
 		Database:        "postgres",
@@ -101,8 +104,8 @@ func TestPsqlOrchestrator_FindsRunningContainer(t *testing.T) {
 	var buf bytes.Buffer
 	notTerminal := false
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
 		StdinIsTerminal: &notTerminal,
 	})
 
@@ -121,27 +124,110 @@ func TestPsqlOrchestrator_NoContainerFound(t *testing.T) {
 	}
 	var buf bytes.Buffer
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{})
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no running pgbox container found")
 }
 
-func TestPsqlOrchestrator_ContainerNotRunning(t *testing.T) {
+// newStoppedContainerMock returns a mock where my-postgres exists but is
+// stopped until docker start is run.
+func newStoppedContainerMock() *docker.MockDocker {
 	mock := docker.NewMockDocker()
+	started := false
 	mock.IsContainerRunningFunc = func(name string) (bool, error) {
-		return false, nil
+		return started, nil
 	}
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch args[0] {
+		case "ps":
+			return "my-postgres\n", nil
+		case "start":
+			started = true
+		}
+		return "", nil
+	}
+	return mock
+}
+
+func TestPsqlOrchestrator_ContainerNotRunning(t *testing.T) {
+	mock := newStoppedContainerMock()
 	var buf bytes.Buffer
+	notTerminal := false
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{
-		ContainerName: "my-postgres",
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
+		ContainerName:   "my-postgres",
+		StdinIsTerminal: &notTerminal,
 	})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "container my-postgres is not running")
+	assert.Contains(t, err.Error(), "--start")
+	assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"start", "my-postgres"})
+	assert.Empty(t, mock.Calls.RunInteractive)
+}
+
+func TestPsqlOrchestrator_ContainerMissing(t *testing.T) {
+	mock := docker.NewMockDocker()
+	notTerminal := false
+
+	orch := NewPsqlOrchestrator(mock, &bytes.Buffer{}, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
+		ContainerName:   "my-postgres",
+		Start:           true,
+		StdinIsTerminal: &notTerminal,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container my-postgres does not exist")
+}
+
+func TestPsqlOrchestrator_StartsStoppedContainer(t *testing.T) {
+	mock := newStoppedContainerMock()
+	var buf bytes.Buffer
+	notTerminal := false
+
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
+		ContainerName:   "my-postgres",
+		Start:           true,
+		StdinIsTerminal: &notTerminal,
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"start", "my-postgres"})
+	require.NotEmpty(t, mock.Calls.ExecCommand)
+	assert.Equal(t, "pg_isready", mock.Calls.ExecCommand[0].Command[0])
+	assert.Len(t, mock.Calls.RunInteractive, 1)
+	assert.Contains(t, buf.String(), "Starting my-postgres...")
+}
+
+func TestPsqlOrchestrator_PromptsToStart(t *testing.T) {
+	isTerminal := true
+
+	mock := newStoppedContainerMock()
+	var buf bytes.Buffer
+	err := NewPsqlOrchestrator(mock, &buf, strings.NewReader("y\n")).Run(t.Context(), PsqlConfig{
+		ContainerName:   "my-postgres",
+		StdinIsTerminal: &isTerminal,
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Container my-postgres is stopped. Start it? (y/N):")
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"start", "my-postgres"})
+	assert.Len(t, mock.Calls.RunInteractive, 1)
+
+	mock = newStoppedContainerMock()
+	err = NewPsqlOrchestrator(mock, &bytes.Buffer{}, strings.NewReader("n\n")).Run(t.Context(), PsqlConfig{
+		ContainerName:   "my-postgres",
+		StdinIsTerminal: &isTerminal,
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "container my-postgres is not running")
+	assert.Empty(t, mock.Calls.RunInteractive)
 }
 
 func TestPsqlOrchestrator_ExtraArgs(t *testing.T) {
@@ -152,8 +238,8 @@ func TestPsqlOrchestrator_ExtraArgs(t *testing.T) {
 	var buf bytes.Buffer
 	notTerminal := false
 
-	orch := NewPsqlOrchestrator(mock, &buf)
-	err := orch.Run(PsqlConfig{
+	orch := NewPsqlOrchestrator(mock, &buf, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{
 		ContainerName:   "my-postgres",
 		User:            "postgres",
 		Database:        "postgres",
@@ -168,3 +254,44 @@ func TestPsqlOrchestrator_ExtraArgs(t *testing.T) {
 	assert.Contains(t, args, "-c")
 	assert.Contains(t, args, "SELECT 1;")
 }
+
+func TestPsqlOrchestrator_StoredPassword(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_, err := config.SaveContainerState("my-postgres", config.ContainerState{Password: "s3cret"})
+	require.NoError(t, err)
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) {
+		return true, nil
+	}
+	notTerminal := false
+
+	orch := NewPsqlOrchestrator(mock, &bytes.Buffer{}, strings.NewReader(""))
+	err = orch.Run(t.Context(), PsqlConfig{
+		ContainerName:   "my-postgres",
+		User:            "postgres",
+		Database:        "postgres",
+		StdinIsTerminal: &notTerminal,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunInteractive, 1)
+	assert.Equal(t, []string{"exec", "-i", "-e", "PGPASSWORD=s3cret", "my-postgres", "psql", "-U", "postgres", "-d", "postgres"}, mock.Calls.RunInteractive[0])
+}
+
+func TestPsqlOrchestrator_Replica(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) {
+		return name == "pgbox-pg17-replica", nil
+	}
+	notTerminal := false
+
+	orch := NewPsqlOrchestrator(mock, &bytes.Buffer{}, strings.NewReader(""))
+	err := orch.Run(t.Context(), PsqlConfig{ContainerName: "pgbox-pg17", Replica: true, StdinIsTerminal: &notTerminal})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "-i", "pgbox-pg17-replica", "psql", "-U", "postgres", "-d", "postgres"}, mock.Calls.RunInteractive[0])
+
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return false, nil }
+	err = orch.Run(t.Context(), PsqlConfig{ContainerName: "pgbox-pg17", Replica: true, StdinIsTerminal: &notTerminal})
+	assert.EqualError(t, err, "replica pgbox-pg17-replica is not running. Start it with: pgbox up --with-replica -n pgbox-pg17")
+}
@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// The image pull policies of pgbox up --pull.
+const (
+	PullAlways  = "always"  // Pull every image, picking up new patch releases
+	PullMissing = "missing" // Pull only the images not present locally
+	PullNever   = "never"   // Fail rather than pull
+)
+
+// PullPolicies are the pull policies pgbox up --pull accepts.
+var PullPolicies = []string{PullAlways, PullMissing, PullNever}
+
+// buildOutputPrefix marks the lines docker build prints for a custom image.
+const buildOutputPrefix = "build | "
+
+// pullImages pulls the images the current run creates containers from before
+// it creates any, all at once, with every line of output prefixed by its
+// image. The pull policy decides which: all of them with PullAlways, those
+// not present locally with PullMissing, and none with PullNever, which fails
+// for a missing image instead.
+func (o *UpOrchestrator) pullImages(ctx context.Context, images []string) error {
+	var pull []string
+	for _, image := range images {
+		if image == "" || slices.Contains(pull, image) {
+			continue
+		}
+		if o.pull != PullAlways && o.imageExists(ctx, image) {
+			continue
+		}
+		if o.pull == PullNever {
+			return fmt.Errorf("image %s is not present locally, and --pull never does not pull it", image)
+		}
+		pull = append(pull, image)
+	}
+	if len(pull) == 0 {
+		return nil
+	}
+
+	if o.dryRun {
+		for _, image := range pull {
+			_ = o.docker.RunCommandWithIO(ctx, nil, o.output, o.output, "pull", image)
+		}
+		return nil
+	}
+	logging.Infof(o.output, "Pulling %s...", strings.Join(pull, ", "))
+	start := time.Now()
+	var mu sync.Mutex
+	errs := make([]error, len(pull))
+	var wg sync.WaitGroup
+	for i, image := range pull {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := newPrefixWriter(o.output, image+" | ", &mu)
+			if err := o.docker.RunCommandWithIO(ctx, nil, w, w, "pull", image); err != nil {
+				errs[i] = fmt.Errorf("failed to pull %s: %w", image, err)
+			}
+			w.Flush()
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	o.timeStep(stepPull, start, strings.Join(pull, ", "))
+	return nil
+}
+
+// sidecarImages returns the images of the containers the current run starts
+// next to PostgreSQL: pgbouncer and the UIs. A replica uses PostgreSQL's.
+func sidecarImages(cfg UpConfig) []string {
+	var images []string
+	if cfg.Pgbouncer {
+		images = append(images, pgbouncerImage)
+	}
+	for _, name := range cfg.UI {
+		images = append(images, UITools[name].Image)
+	}
+	return images
+}
+
+// prefixWriter writes complete lines to output with a prefix, holding a
+// partial line until the rest of it arrives. Writers sharing mu keep their
+// lines whole when they write at the same time.
+type prefixWriter struct {
+	output  io.Writer
+	prefix  string
+	mu      *sync.Mutex
+	pending []byte
+}
+
+// newPrefixWriter returns a prefixWriter; mu may be nil for one that writes
+// alone.
+func newPrefixWriter(output io.Writer, prefix string, mu *sync.Mutex) *prefixWriter {
+	if mu == nil {
+		mu = &sync.Mutex{}
+	}
+	return &prefixWriter{output: output, prefix: prefix, mu: mu}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.pending = append(p.pending, b...)
+	for {
+		// Progress bars redraw their line with carriage returns
+		i := bytes.IndexAny(p.pending, "\r\n")
+		if i < 0 {
+			return len(b), nil
+		}
+		if line := p.pending[:i]; len(bytes.TrimSpace(line)) > 0 {
+			p.writeLine(line)
+		}
+		p.pending = p.pending[i+1:]
+	}
+}
+
+// Flush writes what is left of an unterminated last line.
+func (p *prefixWriter) Flush() {
+	if len(bytes.TrimSpace(p.pending)) > 0 {
+		p.writeLine(p.pending)
+	}
+	p.pending = nil
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = fmt.Fprintf(p.output, "%s%s\n", p.prefix, line)
+}
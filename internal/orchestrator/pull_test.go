@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pullMock returns a mock on which only the given images are present, and
+// whose pulls print a progress line for their image.
+func pullMock(local ...string) *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "images" {
+			for _, image := range local {
+				if args[2] == image {
+					return "0123456789ab\n", nil
+				}
+			}
+		}
+		return "", nil
+	}
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		if args[0] == "pull" {
+			_, _ = io.WriteString(stdout, "Pulling fs layer\rDownload complete\nStatus: Downloaded newer image for "+args[1])
+		}
+		return nil
+	}
+	return mock
+}
+
+// pulled returns the images the mock was asked to pull, in any order.
+func pulled(mock *docker.MockDocker) []string {
+	var images []string
+	for _, call := range mock.Calls.RunCommandWithIO {
+		if call[0] == "pull" {
+			images = append(images, call[1])
+		}
+	}
+	return images
+}
+
+func TestUpOrchestrator_PullMissing(t *testing.T) {
+	mock := pullMock("adminer")
+	var buf bytes.Buffer
+
+	err := newTestUpOrchestrator(mock, &buf).Run(t.Context(), UpConfig{Version: "17", Detach: true, Pgbouncer: true, UI: []string{"adminer"}})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"postgres:17", pgbouncerImage}, pulled(mock))
+	out := buf.String()
+	assert.Contains(t, out, "postgres:17 | Pulling fs layer\npostgres:17 | Download complete\npostgres:17 | Status: Downloaded newer image for postgres:17\n")
+	assert.Contains(t, out, pgbouncerImage+" | Status: Downloaded newer image for "+pgbouncerImage+"\n")
+	assert.Less(t, strings.Index(out, "Pulling postgres:17, "+pgbouncerImage+"..."), strings.Index(out, "PostgreSQL is ready"))
+}
+
+func TestUpOrchestrator_PullAlways(t *testing.T) {
+	mock := pullMock("postgres:17")
+
+	err := newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Pull: PullAlways})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"postgres:17"}, pulled(mock))
+
+	mock = pullMock()
+	err = newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Pull: PullAlways, Extensions: []string{"pgvector"}})
+	require.NoError(t, err)
+	assert.Empty(t, pulled(mock), "the build pulls the base image of a custom one")
+	var build []string
+	for _, call := range mock.Calls.RunCommandWithIO {
+		if call[0] == "build" {
+			build = call
+		}
+	}
+	assert.Contains(t, build, "--pull")
+}
+
+func TestUpOrchestrator_PullNever(t *testing.T) {
+	mock := pullMock()
+
+	err := newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Pull: PullNever})
+
+	assert.EqualError(t, err, "image postgres:17 is not present locally, and --pull never does not pull it")
+	assert.Empty(t, mock.Calls.RunPostgres)
+
+	mock = pullMock("postgres:17")
+	require.NoError(t, newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Pull: PullNever}))
+	assert.Empty(t, pulled(mock))
+	assert.Len(t, mock.Calls.RunPostgres, 1)
+}
+
+func TestUpOrchestrator_InvalidPull(t *testing.T) {
+	err := newTestUpOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", Detach: true, Pull: "sometimes"})
+
+	assert.EqualError(t, err, `invalid pull policy "sometimes" (must be one of: always, missing, never)`)
+}
+
+func TestUpOrchestrator_BuildOutputPrefixed(t *testing.T) {
+	mock := pullMock()
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		if args[0] == "build" {
+			_, _ = io.WriteString(stderr, "#5 [2/3] RUN apt-get update\n\n#5 DONE 3.1s\n")
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+
+	err := newTestUpOrchestrator(mock, &buf).Run(t.Context(), UpConfig{Version: "17", Detach: true, Extensions: []string{"pgvector"}})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "build | #5 [2/3] RUN apt-get update\nbuild | #5 DONE 3.1s\n")
+}
@@ -0,0 +1,120 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/container"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+)
+
+// inspectUpConfig reads back the settings a running container was started
+// with, so it can be recreated with pgbox up without losing any of them. The
+// extensions are the ones in its labels plus the catalog extensions installed
+// in its database.
+func inspectUpConfig(ctx context.Context, d docker.Docker, name string) (UpConfig, error) {
+	version, err := d.GetContainerEnv(ctx, name, "PG_MAJOR")
+	if err != nil || version == "" {
+		return UpConfig{}, fmt.Errorf("failed to read the PostgreSQL version of %s (is it a postgres image?)", name)
+	}
+	password, _ := d.GetContainerEnv(ctx, name, "POSTGRES_PASSWORD")
+	user, database := ResolveCredentials(ctx, d, name, "", "")
+	cfg := UpConfig{
+		Version:       version,
+		ContainerName: name,
+		Password:      password,
+		Database:      database,
+		User:          user,
+		Detach:        true,
+	}
+
+	// The data volume is kept, so initdb does not run again, but pgbox upgrade
+	// initializes the new version's volume with the same arguments
+	cfg.Timezone, _ = d.GetContainerEnv(ctx, name, "TZ")
+	if args, _ := d.GetContainerEnv(ctx, name, "POSTGRES_INITDB_ARGS"); args != "" {
+		cfg.InitdbArgs = config.SplitInitdbArgs(args)
+	}
+
+	if output, err := d.RunCommandWithOutput(ctx, "port", name, "5432/tcp"); err == nil {
+		cfg.Port = parseHostPort(output)
+	}
+	if cfg.Port == "" {
+		return UpConfig{}, fmt.Errorf("%s does not publish port 5432", name)
+	}
+
+	if output, err := d.RunCommandWithOutput(ctx, "inspect", "-f", "{{.HostConfig.RestartPolicy.Name}}", name); err == nil {
+		if policy := strings.TrimSpace(output); slices.Contains(RestartPolicies, policy) && policy != "no" {
+			cfg.Restart = policy
+		}
+	}
+
+	// Labels record what the container was created with, including extensions
+	// that are never created, such as output plugins, and the names of the
+	// --env variables, whose values are read back from its environment
+	labels := containerLabels(ctx, d, name)
+	cfg.Extensions = container.ParseExtensionsLabel(labels[container.LabelExtensions])
+	cfg.Hardened = labels[container.LabelHardened] == "true"
+	for _, key := range strings.Split(labels[container.LabelEnv], ",") {
+		if key == "" {
+			continue
+		}
+		if cfg.Env == nil {
+			cfg.Env = make(map[string]string)
+		}
+		cfg.Env[key], _ = d.GetContainerEnv(ctx, name, key)
+	}
+	installed, err := QueryLines(ctx, d, name, user, database, "SELECT extname FROM pg_extension")
+	if err != nil {
+		return UpConfig{}, fmt.Errorf("failed to list installed extensions: %w", err)
+	}
+	for _, ext := range extensions.ListExtensions() {
+		if ext != "plpgsql" && slices.Contains(installed, extensions.GetSQLName(ext)) && !slices.Contains(cfg.Extensions, ext) {
+			cfg.Extensions = append(cfg.Extensions, ext)
+		}
+	}
+
+	// Settings are passed as -c arguments; shared_preload_libraries is
+	// rebuilt from the extensions, and the --ssl settings by --ssl
+	if output, err := d.RunCommandWithOutput(ctx, "inspect", "-f", "{{json .Config.Cmd}}", name); err == nil {
+		var args []string
+		if json.Unmarshal([]byte(strings.TrimSpace(output)), &args) == nil {
+			args, cfg.SSL = stripSSLEntrypoint(args)
+			for i := 0; i+1 < len(args); i++ {
+				if args[i] != "-c" {
+					continue
+				}
+				key, value, ok := strings.Cut(args[i+1], "=")
+				_, fromSSL := SSLSettings[key]
+				if ok && key != "shared_preload_libraries" && !(cfg.SSL && fromSSL) {
+					if cfg.Settings == nil {
+						cfg.Settings = make(map[string]string)
+					}
+					cfg.Settings[key] = value
+				}
+				i++
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// recreateContainer replaces a container with one started from cfg. The data
+// volume is named after the container, so the new container keeps its data.
+func recreateContainer(ctx context.Context, d docker.Docker, up *UpOrchestrator, cfg UpConfig) error {
+	name := cfg.ContainerName
+	if err := d.StopContainer(ctx, name); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", name, err)
+	}
+	if err := d.RemoveContainer(ctx, name); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", name, err)
+	}
+	if err := up.Run(ctx, cfg); err != nil {
+		return fmt.Errorf("failed to recreate %s (its data is kept in volume %s-data): %w", name, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// ReloadConfig holds configuration for the reload command.
+type ReloadConfig struct {
+	ContainerName string
+	ConfFile      string            // postgresql.conf snippet whose settings are applied with ALTER SYSTEM
+	HBAFile       string            // Replacement pg_hba.conf
+	Settings      map[string]string // Individual settings; win over ConfFile
+}
+
+// ReloadOrchestrator applies configuration changes to a running container
+// and reloads PostgreSQL without recreating it.
+type ReloadOrchestrator struct {
+	docker      docker.Docker
+	output      io.Writer
+	settleDelay time.Duration
+}
+
+// NewReloadOrchestrator creates a new ReloadOrchestrator.
+func NewReloadOrchestrator(d docker.Docker, w io.Writer) *ReloadOrchestrator {
+	return &ReloadOrchestrator{docker: d, output: w, settleDelay: 200 * time.Millisecond}
+}
+
+// gucNamePattern matches valid PostgreSQL setting names, including custom
+// dotted names such as cron.database_name.
+var gucNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// listSettings are the settings PostgreSQL parses as comma-separated lists
+// of quoted elements; ALTER SYSTEM needs each element as its own literal.
+var listSettings = map[string]bool{
+	"local_preload_libraries":   true,
+	"search_path":               true,
+	"session_preload_libraries": true,
+	"shared_preload_libraries":  true,
+	"temp_tablespaces":          true,
+	"unix_socket_directories":   true,
+}
+
+// settingLiteral renders value as the right-hand side of ALTER SYSTEM SET.
+// List settings become one literal per element, with any double quotes
+// around an element dropped since the server re-quotes it.
+func settingLiteral(key, value string) string {
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	if !listSettings[strings.ToLower(key)] {
+		return quote(value)
+	}
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		element = strings.TrimSpace(element)
+		if len(element) >= 2 && strings.HasPrefix(element, `"`) && strings.HasSuffix(element, `"`) {
+			element = strings.ReplaceAll(element[1:len(element)-1], `""`, `"`)
+		}
+		if element != "" {
+			elements = append(elements, quote(element))
+		}
+	}
+	if len(elements) == 0 {
+		return "''"
+	}
+	return strings.Join(elements, ", ")
+}
+
+// settingsQuery lists every setting with its human-readable current value.
+const settingsQuery = "SELECT name, current_setting(name) FROM pg_settings ORDER BY name"
+
+// Run applies the requested changes and reloads the server configuration.
+func (o *ReloadOrchestrator) Run(ctx context.Context, cfg ReloadConfig) error {
+	settings := make(map[string]string)
+	if cfg.ConfFile != "" {
+		fileSettings, err := ParseConfFile(cfg.ConfFile)
+		if err != nil {
+			return err
+		}
+		for key, value := range fileSettings {
+			settings[key] = value
+		}
+	}
+	for key, value := range cfg.Settings {
+		settings[key] = value
+	}
+	for key := range settings {
+		if !gucNamePattern.MatchString(key) {
+			return fmt.Errorf("invalid setting name %q", key)
+		}
+	}
+
+	var hba []byte
+	if cfg.HBAFile != "" {
+		var err error
+		hba, err = os.ReadFile(cfg.HBAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", cfg.HBAFile, err)
+		}
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+
+	before, err := o.snapshotSettings(ctx, name, user, database)
+	if err != nil {
+		return fmt.Errorf("failed to read current settings: %w", err)
+	}
+
+	logging.Infof(o.output, "Reloading configuration for %s...", name)
+
+	var hbaPath string
+	var previousHBA []byte
+	if hba != nil {
+		hbaPath, err = o.locateHBA(ctx, name, user, database)
+		if err != nil {
+			return err
+		}
+		previousHBA, err = o.readHBA(ctx, name, hbaPath)
+		if err != nil {
+			return err
+		}
+		if err := o.writeHBA(ctx, name, hbaPath, hba); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(o.output, "Updated pg_hba.conf (%s)\n", hbaPath)
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		stmt := fmt.Sprintf("ALTER SYSTEM SET %s = %s", key, settingLiteral(key, settings[key]))
+		if _, err := QueryLines(ctx, o.docker, name, user, database, stmt); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
+	if _, err := QueryLines(ctx, o.docker, name, user, database, "SELECT pg_reload_conf()"); err != nil {
+		return fmt.Errorf("failed to reload configuration: %w", err)
+	}
+	if err := sleepContext(ctx, o.settleDelay); err != nil {
+		return err
+	}
+
+	after, err := o.snapshotSettings(ctx, name, user, database)
+	if err != nil {
+		return fmt.Errorf("failed to read reloaded settings: %w", err)
+	}
+	pending, err := QueryLines(ctx, o.docker, name, user, database,
+		"SELECT name FROM pg_settings WHERE pending_restart ORDER BY name")
+	if err != nil {
+		return fmt.Errorf("failed to check pending restarts: %w", err)
+	}
+
+	o.printChanges(name, before, after, pending)
+
+	if hba != nil {
+		hbaErrors, err := QueryLines(ctx, o.docker, name, user, database,
+			"SELECT line_number, error FROM pg_hba_file_rules WHERE error IS NOT NULL ORDER BY line_number")
+		if err != nil {
+			return fmt.Errorf("failed to check pg_hba.conf: %w", err)
+		}
+		if len(hbaErrors) > 0 {
+			for _, line := range hbaErrors {
+				lineNo, msg, _ := strings.Cut(line, "\t")
+				_, _ = fmt.Fprintf(o.output, "  pg_hba.conf line %s: %s\n", lineNo, msg)
+			}
+			if err := o.writeHBA(ctx, name, hbaPath, previousHBA); err != nil {
+				return fmt.Errorf("pg_hba.conf has errors and restoring the previous file failed: %w", err)
+			}
+			if _, err := QueryLines(ctx, o.docker, name, user, database, "SELECT pg_reload_conf()"); err != nil {
+				return fmt.Errorf("pg_hba.conf has errors and reloading the restored file failed: %w", err)
+			}
+			return fmt.Errorf("pg_hba.conf has errors; the previous file was restored and its rules remain active")
+		}
+	}
+
+	return nil
+}
+
+// snapshotSettings returns the current value of every server setting.
+func (o *ReloadOrchestrator) snapshotSettings(ctx context.Context, name, user, database string) (map[string]string, error) {
+	lines, err := QueryLines(ctx, o.docker, name, user, database, settingsQuery)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(lines))
+	for _, line := range lines {
+		key, value, _ := strings.Cut(line, "\t")
+		values[key] = value
+	}
+	return values, nil
+}
+
+// locateHBA returns the path of the server's pg_hba.conf inside the container.
+func (o *ReloadOrchestrator) locateHBA(ctx context.Context, name, user, database string) (string, error) {
+	lines, err := QueryLines(ctx, o.docker, name, user, database, "SHOW hba_file")
+	if err != nil || len(lines) == 0 {
+		return "", fmt.Errorf("failed to locate pg_hba.conf: %w", err)
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
+
+// readHBA returns the current content of pg_hba.conf so it can be put back
+// if the replacement turns out to be invalid.
+func (o *ReloadOrchestrator) readHBA(ctx context.Context, name, path string) ([]byte, error) {
+	var stdout bytes.Buffer
+	var stderr strings.Builder
+	err := o.docker.RunCommandWithIO(ctx, nil, &stdout, &stderr,
+		"exec", "-u", "postgres", name, "cat", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s: %w", path, strings.TrimSpace(stderr.String()), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeHBA replaces the server's pg_hba.conf with the given content.
+func (o *ReloadOrchestrator) writeHBA(ctx context.Context, name, path string, content []byte) error {
+	var stderr strings.Builder
+	err := o.docker.RunCommandWithIO(ctx, bytes.NewReader(content), io.Discard, &stderr,
+		"exec", "-i", "-u", "postgres", name, "tee", path)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %s: %w", path, strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+// printChanges reports settings whose values changed and those awaiting a restart.
+func (o *ReloadOrchestrator) printChanges(name string, before, after map[string]string, pending []string) {
+	var changed []string
+	for key, value := range after {
+		if old, ok := before[key]; !ok || old != value {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+
+	if len(changed) == 0 {
+		_, _ = fmt.Fprintln(o.output, "No settings changed")
+	} else {
+		_, _ = fmt.Fprintln(o.output, "Changed settings:")
+		for _, key := range changed {
+			_, _ = fmt.Fprintf(o.output, "  %s: %s -> %s\n", key, before[key], after[key])
+		}
+	}
+
+	if len(pending) > 0 {
+		_, _ = fmt.Fprintf(o.output, "Pending restart (run 'pgbox restart -n %s' to apply):\n", name)
+		for _, key := range pending {
+			_, _ = fmt.Fprintf(o.output, "  %s\n", strings.TrimSpace(key))
+		}
+	}
+}
+
+// ParseConfFile reads settings from a postgresql.conf-style file. Comments,
+// blank lines and SQL comment lines (as written by pgbox export) are ignored,
+// and surrounding single quotes are removed from values.
+func ParseConfFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripConfComment(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected 'name = value'", path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+		settings[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// stripConfComment removes a trailing '#' comment that is not inside quotes.
+func stripConfComment(line string) string {
+	inQuote := false
+	for i, r := range line {
+		switch r {
+		case '\'':
+			inQuote = !inQuote
+		case '#':
+			if !inQuote {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,218 @@
+package orchestrator
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReloadMock returns a running-container mock whose settings snapshot
+// changes from before to after once pg_reload_conf() has been called.
+func newReloadMock(before, after, pending string) *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	reloaded := false
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		query := command[len(command)-1]
+		switch {
+		case query == "SELECT pg_reload_conf()":
+			reloaded = true
+			return "t\n", nil
+		case query == settingsQuery && reloaded:
+			return after, nil
+		case query == settingsQuery:
+			return before, nil
+		case strings.Contains(query, "pending_restart"):
+			return pending, nil
+		case query == "SHOW hba_file":
+			return "/var/lib/postgresql/data/pg_hba.conf\n", nil
+		}
+		return "", nil
+	}
+	return mock
+}
+
+func TestReloadOrchestrator_AppliesSettings(t *testing.T) {
+	mock := newReloadMock(
+		"shared_buffers\t128MB\nwork_mem\t4MB\n",
+		"shared_buffers\t128MB\nwork_mem\t64MB\n",
+		"shared_buffers\n",
+	)
+	var buf bytes.Buffer
+
+	orch := NewReloadOrchestrator(mock, &buf)
+	orch.settleDelay = 0
+	err := orch.Run(t.Context(), ReloadConfig{
+		ContainerName: "pgbox-pg17",
+		Settings:      map[string]string{"work_mem": "64MB", "shared_buffers": "1GB"},
+	})
+
+	require.NoError(t, err)
+
+	var statements []string
+	for _, call := range mock.Calls.ExecCommand {
+		statements = append(statements, call.Command[len(call.Command)-1])
+	}
+	assert.Contains(t, statements, "ALTER SYSTEM SET shared_buffers = '1GB'")
+	assert.Contains(t, statements, "ALTER SYSTEM SET work_mem = '64MB'")
+	assert.Contains(t, buf.String(), "work_mem: 4MB -> 64MB")
+	assert.NotContains(t, buf.String(), "shared_buffers: ")
+	assert.Contains(t, buf.String(), "Pending restart (run 'pgbox restart -n pgbox-pg17' to apply):\n  shared_buffers")
+}
+
+func TestReloadOrchestrator_NoChanges(t *testing.T) {
+	mock := newReloadMock("work_mem\t4MB\n", "work_mem\t4MB\n", "")
+	var buf bytes.Buffer
+
+	orch := NewReloadOrchestrator(mock, &buf)
+	orch.settleDelay = 0
+	err := orch.Run(t.Context(), ReloadConfig{ContainerName: "pgbox-pg17"})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No settings changed")
+	assert.NotContains(t, buf.String(), "Pending restart")
+}
+
+func TestReloadOrchestrator_WritesHBA(t *testing.T) {
+	dir := t.TempDir()
+	hbaPath := filepath.Join(dir, "pg_hba.conf")
+	require.NoError(t, os.WriteFile(hbaPath, []byte("host all all 0.0.0.0/0 md5\n"), 0644))
+
+	mock := newReloadMock("", "", "")
+	written := fakeHBAFile(mock, "local all all trust\n")
+	var buf bytes.Buffer
+
+	orch := NewReloadOrchestrator(mock, &buf)
+	orch.settleDelay = 0
+	err := orch.Run(t.Context(), ReloadConfig{ContainerName: "pgbox-pg17", HBAFile: hbaPath})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithIO, 2)
+	assert.Equal(t, []string{"exec", "-u", "postgres", "pgbox-pg17", "cat", "/var/lib/postgresql/data/pg_hba.conf"}, mock.Calls.RunCommandWithIO[0])
+	assert.Equal(t, []string{"exec", "-i", "-u", "postgres", "pgbox-pg17", "tee", "/var/lib/postgresql/data/pg_hba.conf"}, mock.Calls.RunCommandWithIO[1])
+	assert.Equal(t, []string{"host all all 0.0.0.0/0 md5\n"}, *written)
+	assert.Contains(t, buf.String(), "Updated pg_hba.conf")
+}
+
+// fakeHBAFile makes mock serve current as the container's pg_hba.conf and
+// returns every content written over it, in order.
+func fakeHBAFile(mock *docker.MockDocker, current string) *[]string {
+	var written []string
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		switch args[len(args)-2] {
+		case "cat":
+			_, _ = io.WriteString(stdout, current)
+		case "tee":
+			data, _ := io.ReadAll(stdin)
+			written = append(written, string(data))
+		}
+		return nil
+	}
+	return &written
+}
+
+func TestReloadOrchestrator_ReportsHBAErrors(t *testing.T) {
+	dir := t.TempDir()
+	hbaPath := filepath.Join(dir, "pg_hba.conf")
+	require.NoError(t, os.WriteFile(hbaPath, []byte("bogus\n"), 0644))
+
+	mock := newReloadMock("", "", "")
+	exec := mock.ExecCommandFunc
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		if strings.Contains(command[len(command)-1], "pg_hba_file_rules") {
+			return "1\tinvalid connection type \"bogus\"\n", nil
+		}
+		return exec(containerName, command...)
+	}
+	written := fakeHBAFile(mock, "local all all trust\n")
+	var buf bytes.Buffer
+
+	orch := NewReloadOrchestrator(mock, &buf)
+	orch.settleDelay = 0
+	err := orch.Run(t.Context(), ReloadConfig{ContainerName: "pgbox-pg17", HBAFile: hbaPath})
+
+	assert.ErrorContains(t, err, "pg_hba.conf has errors; the previous file was restored")
+	assert.Contains(t, buf.String(), "pg_hba.conf line 1: invalid connection type")
+	assert.Equal(t, []string{"bogus\n", "local all all trust\n"}, *written)
+
+	reloads := 0
+	for _, call := range mock.Calls.ExecCommand {
+		if call.Command[len(call.Command)-1] == "SELECT pg_reload_conf()" {
+			reloads++
+		}
+	}
+	assert.Equal(t, 2, reloads, "the restored file must be reloaded")
+}
+
+func TestReloadOrchestrator_SplitsListSettings(t *testing.T) {
+	mock := newReloadMock("", "", "")
+	var buf bytes.Buffer
+
+	orch := NewReloadOrchestrator(mock, &buf)
+	orch.settleDelay = 0
+	err := orch.Run(t.Context(), ReloadConfig{
+		ContainerName: "pgbox-pg17",
+		Settings: map[string]string{
+			"search_path":              `"$user", public`,
+			"shared_preload_libraries": "pg_stat_statements,auto_explain",
+		},
+	})
+
+	require.NoError(t, err)
+	var statements []string
+	for _, call := range mock.Calls.ExecCommand {
+		statements = append(statements, call.Command[len(call.Command)-1])
+	}
+	assert.Contains(t, statements, "ALTER SYSTEM SET search_path = '$user', 'public'")
+	assert.Contains(t, statements, "ALTER SYSTEM SET shared_preload_libraries = 'pg_stat_statements', 'auto_explain'")
+}
+
+func TestReloadOrchestrator_ContainerNotRunning(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	err := NewReloadOrchestrator(mock, &buf).Run(t.Context(), ReloadConfig{ContainerName: "pgbox-pg17"})
+
+	assert.ErrorContains(t, err, "is not running")
+	assert.Empty(t, mock.Calls.ExecCommand)
+}
+
+func TestReloadOrchestrator_InvalidSettingName(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	err := NewReloadOrchestrator(mock, &buf).Run(t.Context(), ReloadConfig{
+		Settings: map[string]string{"work_mem; DROP TABLE x": "1"},
+	})
+
+	assert.ErrorContains(t, err, "invalid setting name")
+}
+
+func TestParseConfFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "postgresql.conf.pgbox")
+	content := `# PostgreSQL configuration generated by pgbox
+
+shared_preload_libraries = 'pg_cron'
+cron.database_name = postgres  # extension pg_cron
+search_path = '"$user", public # not a comment'
+
+-- ALTER SYSTEM SET cron.database_name = 'postgres';
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	settings, err := ParseConfFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"shared_preload_libraries": "pg_cron",
+		"cron.database_name":       "postgres",
+		"search_path":              `"$user", public # not a comment`,
+	}, settings)
+}
@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// RemapPortConfig holds configuration for moving a container to a new host port.
+type RemapPortConfig struct {
+	ContainerName string
+	Port          string
+	Project       *config.ProjectConfig // pgbox.toml whose port is kept in sync, if any
+}
+
+// RemapPortOrchestrator recreates a container with a new published port.
+type RemapPortOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+	newUp  func() *UpOrchestrator
+}
+
+// NewRemapPortOrchestrator creates a new RemapPortOrchestrator.
+func NewRemapPortOrchestrator(d docker.Docker, w io.Writer) *RemapPortOrchestrator {
+	return &RemapPortOrchestrator{
+		docker: d,
+		output: w,
+		newUp:  func() *UpOrchestrator { return NewUpOrchestrator(d, w) },
+	}
+}
+
+// Run recreates the container publishing cfg.Port, with the same image,
+// credentials, extensions and settings, keeping its data volume.
+func (o *RemapPortOrchestrator) Run(ctx context.Context, cfg RemapPortConfig) error {
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", cfg.Port)
+	}
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+
+	upCfg, err := inspectUpConfig(ctx, o.docker, name)
+	if err != nil {
+		return err
+	}
+	oldPort := upCfg.Port
+	if oldPort == cfg.Port {
+		_, _ = fmt.Fprintf(o.output, "%s already publishes port %s\n", name, cfg.Port)
+		return nil
+	}
+
+	// Check before the container is removed, so a taken port leaves it running
+	up := o.newUp()
+	upCfg.Port = cfg.Port
+	pgConfig := config.NewPostgresConfig()
+	pgConfig.Port = cfg.Port
+	if err := up.checkPort(ctx, pgConfig, false, ""); err != nil {
+		return err
+	}
+
+	logging.Infof(o.output, "Recreating %s on port %s (data volume %s-data is kept)...", name, cfg.Port, name)
+	if err := recreateContainer(ctx, o.docker, up, upCfg); err != nil {
+		return err
+	}
+
+	pgConfig.User = upCfg.User
+	pgConfig.Password = upCfg.Password
+	pgConfig.Database = upCfg.Database
+	_, _ = fmt.Fprintf(o.output, "Moved %s from port %s to %s\n", name, oldPort, cfg.Port)
+	_, _ = fmt.Fprintf(o.output, "Connection: %s\n", connectionString(pgConfig, cfg.Port))
+
+	if cfg.Project != nil && cfg.Project.Port != cfg.Port {
+		if err := config.SetProjectPort(cfg.Project.Path, cfg.Port); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(o.output, "Updated port in %s\n", cfg.Project.Path)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/container"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// replicaSlot is the physical replication slot the replica streams from, so
+// the primary keeps the WAL the replica has not received yet.
+const replicaSlot = "pgbox_replica"
+
+// replicaService is the compose service export adds for the replica.
+const replicaService = "replica"
+
+// replicaSetupFile is the script export writes next to the compose file and
+// mounts into the primary's init directory.
+const replicaSetupFile = "replica-setup.sh"
+
+// replicaSetupScript allows replication connections with the password and
+// creates the replication slot. It runs in the primary, as an init script or
+// through exec, and can run again without effect.
+const replicaSetupScript = `set -e
+grep -qxF 'host replication all all scram-sha-256' "$PGDATA/pg_hba.conf" || echo 'host replication all all scram-sha-256' >> "$PGDATA/pg_hba.conf"
+psql -X -q -v ON_ERROR_STOP=1 -U "$POSTGRES_USER" -d "$POSTGRES_DB" \
+  -c "SELECT pg_create_physical_replication_slot('` + replicaSlot + `') WHERE NOT EXISTS (SELECT FROM pg_replication_slots WHERE slot_name = '` + replicaSlot + `')" \
+  -c "SELECT pg_reload_conf()"
+`
+
+// replicaSetupHeader starts the setup script export writes.
+const replicaSetupHeader = `#!/bin/sh
+# Written by pgbox export: lets the replica service stream from this server.
+`
+
+// replicaBootstrapScript is the replica's entrypoint. On an empty volume it
+// waits for the primary named by PGBOX_PRIMARY_HOST and copies it with
+// pg_basebackup, which also writes standby.signal and primary_conninfo; then
+// it installs the server key of a primary with --ssl, and starts the server
+// through the image's entrypoint with the arguments given after the script.
+const replicaBootstrapScript = `set -e
+if [ ! -s "$PGDATA/PG_VERSION" ]; then
+  until pg_isready -q -h "$PGBOX_PRIMARY_HOST" -U "$POSTGRES_USER"; do sleep 1; done
+  mkdir -p "$PGDATA"
+  chown postgres:postgres "$PGDATA"
+  chmod 0700 "$PGDATA"
+  if command -v gosu >/dev/null; then as=gosu; else as=su-exec; fi
+  PGPASSWORD="$POSTGRES_PASSWORD" "$as" postgres pg_basebackup -h "$PGBOX_PRIMARY_HOST" -U "$POSTGRES_USER" \
+    -D "$PGDATA" -X stream -R -S ` + replicaSlot + `
+fi
+` + sslInstallKey + `
+exec docker-entrypoint.sh postgres "$@"
+`
+
+// checkReplicaSettings makes sure the primary writes enough WAL to stream.
+// replica is PostgreSQL's default, so only an explicit minimal is a problem.
+func checkReplicaSettings(pgConfModel *model.PGConfModel) error {
+	if level := pgConfModel.GUCs["wal_level"]; level == "minimal" {
+		return fmt.Errorf("--with-replica needs wal_level replica or logical, not %s", level)
+	}
+	return nil
+}
+
+// replicaEnv is the environment of the replica container.
+func replicaEnv(primaryHost string, pg *config.PostgresConfig) map[string]string {
+	env := map[string]string{
+		"PGBOX_PRIMARY_HOST": primaryHost,
+		"POSTGRES_USER":      pg.User,
+		"POSTGRES_DB":        pg.Database,
+	}
+	if pg.Password != "" {
+		env["POSTGRES_PASSWORD"] = pg.Password
+	}
+	return env
+}
+
+// startReplica runs a read-only streaming replica of containerName in its own
+// container, on the network it shares with its UIs, with its own volume and
+// the first free host port after the primary's. An existing replica is
+// started again. The port is published on opts.HostIP, and opts.Command holds
+// the server arguments to use when the primary's cannot be read, as in a dry
+// run.
+func (o *UpOrchestrator) startReplica(ctx context.Context, containerName string, pg *config.PostgresConfig, opts docker.ContainerOptions) error {
+	replica := container.ReplicaName(containerName)
+	if out, err := o.docker.RunCommandWithOutput(ctx, "exec", containerName, "sh", "-c", replicaSetupScript); err != nil {
+		return fmt.Errorf("failed to set up %s for replication: %s: %w", containerName, strings.TrimSpace(out), err)
+	}
+
+	existing, _ := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", replica), "--format", "{{.Names}}")
+	if strings.TrimSpace(existing) == replica {
+		if running, _ := o.docker.IsContainerRunning(ctx, replica); !running {
+			logging.Infof(o.output, "Restarting replica %s...", replica)
+			if err := o.docker.RunCommand(ctx, "start", replica); err != nil {
+				return fmt.Errorf("failed to start replica %s: %w", replica, err)
+			}
+		}
+		return o.waitForReplica(ctx, containerName, pg)
+	}
+
+	network, err := o.joinNetwork(ctx, containerName)
+	if err != nil {
+		return err
+	}
+	image, command := pg.Image(), opts.Command
+	command, _ = stripSSLEntrypoint(command)
+	if out, err := o.docker.RunCommandWithOutput(ctx, "inspect", "-f", "{{.Config.Image}}\t{{json .Config.Cmd}}", containerName); err == nil {
+		if name, cmd, ok := strings.Cut(strings.TrimSpace(out), "\t"); ok && name != "" {
+			image = name
+			var args []string
+			if json.Unmarshal([]byte(cmd), &args) == nil {
+				// The image's default command is just "postgres"
+				args, _ = stripSSLEntrypoint(args)
+				if len(args) > 0 && args[0] == "postgres" {
+					args = args[1:]
+				}
+				command = args
+			}
+		}
+	}
+
+	start := 5433
+	if port, err := strconv.Atoi(pg.Port); err == nil {
+		start = port + 1
+	}
+	hostPort, err := util.FreePort(start, 100)
+	if err != nil {
+		return fmt.Errorf("no free port for the replica: %w", err)
+	}
+
+	publish := fmt.Sprintf("%d:5432", hostPort)
+	if opts.HostIP != "" {
+		publish = opts.HostIP + ":" + publish
+	}
+
+	logging.Infof(o.output, "Starting replica %s...", replica)
+	args := []string{"run", "-d", "--name", replica, "--network", network, "-p", publish,
+		"-v", fmt.Sprintf("%s-data:/var/lib/postgresql/data", replica)}
+	for _, kv := range sortedEnv(replicaEnv(containerName, pg)) {
+		args = append(args, "-e", kv)
+	}
+	if pg.SSL {
+		// The same certificate as the primary's, which names the host too
+		dir, err := config.ArtifactsDir(containerName)
+		if err != nil {
+			return err
+		}
+		args = append(args, sslMounts(dir)...)
+	}
+	args = append(args, "--entrypoint", "sh", image, "-c", replicaBootstrapScript, "pgbox-replica")
+	args = append(args, command...)
+	if out, err := o.docker.RunCommandWithOutput(ctx, args...); err != nil {
+		return fmt.Errorf("failed to start replica %s: %s: %w", replica, strings.TrimSpace(out), err)
+	}
+	return o.waitForReplica(ctx, containerName, pg)
+}
+
+// waitForReplica waits for the replica of containerName to accept read-only
+// connections and prints how to connect to it.
+func (o *UpOrchestrator) waitForReplica(ctx context.Context, containerName string, pg *config.PostgresConfig) error {
+	if o.dryRun {
+		return nil
+	}
+	replica := container.ReplicaName(containerName)
+	if !o.waitForReady(ctx, replica, pg) {
+		if err := context.Cause(ctx); err != nil {
+			return fmt.Errorf("stopped waiting for replica %s to accept connections: %w", replica, err)
+		}
+		return fmt.Errorf("replica %s did not accept connections within %s. Check the logs with: pgbox logs -n %s",
+			replica, o.readyTimeout, replica)
+	}
+	hostPort := ""
+	if out, err := o.docker.RunCommandWithOutput(ctx, "port", replica, "5432/tcp"); err == nil {
+		hostPort = parseHostPort(out)
+	}
+	_, _ = fmt.Fprintf(o.output, "Replica (read-only): %s\n", connectionString(pg, hostPort))
+	_, _ = fmt.Fprintf(o.output, "Connect with: pgbox psql --replica -n %s\n", containerName)
+	return nil
+}
+
+// addReplicaSidecar adds a compose service running a streaming replica of
+// the database service, built from the same Dockerfile and started with the
+// same server settings, and mounts the setup script into the primary's init
+// directory. The replica is published on the port after the primary's, on
+// hostIP if given.
+func addReplicaSidecar(m *model.ComposeModel, pg *config.PostgresConfig, pgConfModel *model.PGConfModel, relDir, hostIP string) {
+	var volumes []string
+	if pg.SSL {
+		// It starts with the same settings, so it needs the same key
+		volumes = sslComposeVolumes(relDir)
+	}
+	m.AddVolume(fmt.Sprintf("%s/%s:/docker-entrypoint-initdb.d/00-pgbox-replica.sh:ro", relDir, replicaSetupFile))
+	port := "5433:5432"
+	if p, err := strconv.Atoi(pg.Port); err == nil {
+		port = fmt.Sprintf("%d:5432", p+1)
+	}
+	if hostIP != "" {
+		port = hostIP + ":" + port
+	}
+	m.AddSidecar(model.Sidecar{
+		Name:  replicaService,
+		Build: m.BuildPath,
+		Env:   replicaEnv(m.ServiceName, pg),
+		// Compose would substitute the script's variables itself
+		Entrypoint: []string{"sh", "-c", strings.ReplaceAll(replicaBootstrapScript, "$", "$$"), "pgbox-replica"},
+		Command:    serverArgs(pgConfModel),
+		Ports:      []string{port},
+		Volumes:    volumes,
+		DependsOn:  m.ServiceName,
+	})
+}
+
+// hostPortOf returns the host port of a "[ip:]host:container" port mapping.
+func hostPortOf(mapping string) string {
+	parts := strings.Split(mapping, ":")
+	if len(parts) < 2 {
+		return mapping
+	}
+	return parts[len(parts)-2]
+}
+
+// sortedEnv returns env as KEY=value pairs sorted by key.
+func sortedEnv(env map[string]string) []string {
+	pairs := make([]string, 0, len(env))
+	for k, v := range env {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// ReportConfig holds configuration for the report command.
+type ReportConfig struct {
+	ContainerName string
+	OutputFile    string        // Zip file to write
+	PgboxVersion  string        // Version of the running pgbox binary
+	LogLines      int           // Number of recent container log lines to include
+	ProjectFile   string        // pgbox.toml in use, if any
+	Scaffold      *ExportConfig // Project configuration to render, if any
+}
+
+// ReportOrchestrator gathers sanitized diagnostics into a zip file for
+// attaching to bug reports.
+type ReportOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewReportOrchestrator creates a new ReportOrchestrator.
+func NewReportOrchestrator(d docker.Docker, w io.Writer) *ReportOrchestrator {
+	return &ReportOrchestrator{docker: d, output: w}
+}
+
+// secretPatterns match credentials in logs, configuration and command lines.
+// The first group is kept and the rest replaced.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)([A-Z_]*PASSWORD["']?\s*[=:]\s*["']?)[^\s"',]+`),
+	regexp.MustCompile(`(?i)(postgres(?:ql)?://[^:/@\s]+:)[^@\s]+`),
+	regexp.MustCompile(`(?i)(password\s+')[^']*`),
+}
+
+// Run collects the diagnostics and writes them to cfg.OutputFile.
+func (o *ReportOrchestrator) Run(ctx context.Context, cfg ReportConfig) error {
+	files := make(map[string]string)
+	var secrets []string
+
+	files["system.txt"] = o.systemInfo(ctx, cfg.PgboxVersion)
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		files["instance.txt"] = "No pgbox container found: " + err.Error() + "\n"
+	} else {
+		if password, err := o.docker.GetContainerEnv(ctx, name, "POSTGRES_PASSWORD"); err == nil && password != "" {
+			secrets = append(secrets, password)
+		}
+		files["instance.txt"] = o.instanceInfo(ctx, name)
+		files["logs.txt"] = o.containerLogs(ctx, name, cfg.LogLines)
+	}
+
+	if cfg.ProjectFile != "" {
+		if content, err := os.ReadFile(cfg.ProjectFile); err == nil {
+			files["pgbox.toml"] = string(content)
+		}
+	}
+	if cfg.Scaffold != nil {
+		if cfg.Scaffold.Password != "" {
+			secrets = append(secrets, cfg.Scaffold.Password)
+		}
+		rendered, err := renderScaffold(*cfg.Scaffold)
+		if err != nil {
+			files["scaffold/error.txt"] = err.Error() + "\n"
+		}
+		for path, content := range rendered {
+			files["scaffold/"+path] = content
+		}
+	}
+
+	for path, content := range files {
+		files[path] = scrubSecrets(content, secrets)
+	}
+	if err := writeZip(cfg.OutputFile, files); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Wrote %s with:\n", cfg.OutputFile)
+	for _, path := range sortedKeys(files) {
+		_, _ = fmt.Fprintf(o.output, "  %s\n", path)
+	}
+	_, _ = fmt.Fprintln(o.output, "\nPasswords were replaced with ***. Review the files before attaching them to an issue.")
+	return nil
+}
+
+// systemInfo describes pgbox, the host and the container runtime.
+func (o *ReportOrchestrator) systemInfo(ctx context.Context, version string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pgbox version: %s\n", version)
+	fmt.Fprintf(&b, "os/arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "go version: %s\n", runtime.Version())
+	fmt.Fprintf(&b, "container runtime: %s\n", docker.CurrentRuntime())
+	fmt.Fprintf(&b, "generated: %s\n", time.Now().UTC().Format(time.RFC3339))
+
+	if err := o.docker.CheckDaemon(ctx); err != nil {
+		fmt.Fprintf(&b, "\ndaemon check failed:\n%v\n", err)
+		return b.String()
+	}
+	out, err := o.docker.RunCommandWithOutput(ctx, "version")
+	b.WriteString("\n$ " + string(docker.CurrentRuntime()) + " version\n")
+	b.WriteString(out)
+	if err != nil {
+		fmt.Fprintf(&b, "(failed: %v)\n", err)
+	}
+	return b.String()
+}
+
+// instanceInfo describes the container's state, image, ports and command.
+// The full inspect output is left out since its environment holds credentials.
+func (o *ReportOrchestrator) instanceInfo(ctx context.Context, name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "container: %s\n", name)
+	out, err := o.docker.RunCommandWithOutput(ctx, "inspect", "-f",
+		"status: {{.State.Status}}\nstarted: {{.State.StartedAt}}\nexit code: {{.State.ExitCode}}\n"+
+			"image: {{.Config.Image}}\nports: {{json .NetworkSettings.Ports}}\ncommand: {{json .Config.Cmd}}", name)
+	b.WriteString(strings.TrimSpace(out) + "\n")
+	if err != nil {
+		fmt.Fprintf(&b, "(inspect failed: %v)\n", err)
+	}
+
+	if ps, err := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", "name=pgbox", "--format", "{{.Names}}\t{{.Image}}\t{{.Status}}"); err == nil {
+		b.WriteString("\nAll pgbox containers:\n" + ps)
+	}
+	return b.String()
+}
+
+// containerLogs returns the last lines of the container's logs.
+func (o *ReportOrchestrator) containerLogs(ctx context.Context, name string, lines int) string {
+	out, err := o.docker.RunCommandWithOutput(ctx, "logs", "--tail", strconv.Itoa(lines), name)
+	if err != nil {
+		return out + fmt.Sprintf("\n(logs failed: %v)\n", err)
+	}
+	return out
+}
+
+// renderScaffold exports cfg to a temporary directory and returns the
+// rendered files by relative path.
+func renderScaffold(cfg ExportConfig) (map[string]string, error) {
+	dir, err := os.MkdirTemp("", "pgbox-report-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	cfg.TargetDir = dir
+	if err := NewExportOrchestrator(io.Discard).Run(cfg); err != nil {
+		return nil, fmt.Errorf("failed to render scaffold: %w", err)
+	}
+
+	files := make(map[string]string)
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	return files, err
+}
+
+// scrubSecrets masks the given secret values and anything that looks like a
+// password assignment or a password in a connection URL.
+func scrubSecrets(content string, secrets []string) string {
+	for _, secret := range secrets {
+		content = strings.ReplaceAll(content, secret, "***")
+	}
+	for _, pattern := range secretPatterns {
+		content = pattern.ReplaceAllString(content, "${1}***")
+	}
+	return content
+}
+
+// writeZip writes files to a new zip archive at path.
+func writeZip(path string, files map[string]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	zw := zip.NewWriter(f)
+	for _, name := range sortedKeys(files) {
+		w, err := zw.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to report: %w", name, err)
+		}
+		if _, err := io.WriteString(w, files[name]); err != nil {
+			return fmt.Errorf("failed to add %s to report: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,10 +1,12 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
 )
 
 // RestartConfig holds configuration for the restart command.
@@ -24,17 +26,17 @@ func NewRestartOrchestrator(d docker.Docker, w io.Writer) *RestartOrchestrator {
 }
 
 // Run restarts the PostgreSQL container.
-func (o *RestartOrchestrator) Run(cfg RestartConfig) error {
-	name, autoDetected, err := ResolveContainerName(o.docker, cfg.ContainerName)
+func (o *RestartOrchestrator) Run(ctx context.Context, cfg RestartConfig) error {
+	name, autoDetected, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
 	if err != nil {
 		return fmt.Errorf("%w. Start one with: pgbox up", err)
 	}
 	if autoDetected {
-		_, _ = fmt.Fprintf(o.output, "Restarting container: %s\n", name)
+		logging.Infof(o.output, "Restarting container: %s", name)
 	}
 
-	_, _ = fmt.Fprintf(o.output, "Restarting container %s...\n", name)
-	err = o.docker.RunCommand("restart", name)
+	logging.Infof(o.output, "Restarting container %s...", name)
+	err = o.docker.RunCommand(ctx, "restart", name)
 	if err != nil {
 		return fmt.Errorf("failed to restart container: %w", err)
 	}
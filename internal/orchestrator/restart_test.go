@@ -14,7 +14,7 @@ func TestRestartOrchestrator_RestartsNamedContainer(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewRestartOrchestrator(mock, &buf)
-	err := orch.Run(RestartConfig{
+	err := orch.Run(t.Context(), RestartConfig{
 		ContainerName: "my-postgres",
 	})
 
@@ -33,7 +33,7 @@ func TestRestartOrchestrator_FindsRunningContainer(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewRestartOrchestrator(mock, &buf)
-	err := orch.Run(RestartConfig{})
+	err := orch.Run(t.Context(), RestartConfig{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, mock.Calls.FindPgboxContainer)
@@ -49,7 +49,7 @@ func TestRestartOrchestrator_NoContainerFound(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewRestartOrchestrator(mock, &buf)
-	err := orch.Run(RestartConfig{})
+	err := orch.Run(t.Context(), RestartConfig{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no running pgbox container found")
@@ -64,7 +64,7 @@ func TestRestartOrchestrator_RestartFails(t *testing.T) {
 	var buf bytes.Buffer
 
 	orch := NewRestartOrchestrator(mock, &buf)
-	err := orch.Run(RestartConfig{
+	err := orch.Run(t.Context(), RestartConfig{
 		ContainerName: "my-postgres",
 	})
 
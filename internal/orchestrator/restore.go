@@ -0,0 +1,179 @@
+package orchestrator
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// RestoreConfig holds configuration for the restore command.
+type RestoreConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Input         string // Backup file or directory on the host
+	Format        string // custom, plain or directory; detected when empty
+	Jobs          int    // Parallel restore jobs (custom and directory formats)
+}
+
+// RestoreOrchestrator handles loading a backup from the host into a container.
+type RestoreOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewRestoreOrchestrator creates a new RestoreOrchestrator.
+func NewRestoreOrchestrator(d docker.Docker, w io.Writer) *RestoreOrchestrator {
+	return &RestoreOrchestrator{docker: d, output: w}
+}
+
+// Run restores cfg.Input into the container's database.
+func (o *RestoreOrchestrator) Run(ctx context.Context, cfg RestoreConfig) error {
+	info, err := os.Stat(cfg.Input)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	format := cfg.Format
+	if format == "" {
+		format, err = detectDumpFormat(cfg.Input, info)
+		if err != nil {
+			return err
+		}
+	} else if _, ok := dumpFormats[format]; !ok {
+		return fmt.Errorf("invalid format %q (must be custom, plain, or directory)", format)
+	}
+	if cfg.Jobs > 1 && format == "plain" {
+		return fmt.Errorf("--jobs is not supported for plain-format backups")
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	if cfg.ContainerName != "" {
+		running, err := o.docker.IsContainerRunning(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to check container status: %w", err)
+		}
+		if !running {
+			return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+		}
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	logging.Infof(o.output, "Restoring %s into database '%s' on %s (%s format)...", cfg.Input, database, name, format)
+
+	switch {
+	case format == "plain":
+		err = o.restorePlain(ctx, name, user, database, cfg.Input)
+	case format == "custom" && cfg.Jobs <= 1:
+		err = o.restoreStream(ctx, name, user, database, cfg.Input)
+	default:
+		err = o.restoreCopy(ctx, name, user, database, cfg.Input, cfg.Jobs)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(o.output, "Restore completed successfully")
+	return nil
+}
+
+// restorePlain feeds a plain SQL dump, optionally gzip-compressed, to psql.
+func (o *RestoreOrchestrator) restorePlain(ctx context.Context, name, user, database, input string) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	br := bufio.NewReader(f)
+	var script io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("failed to decompress backup: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		script = gz
+	}
+
+	err = o.docker.RunCommandWithIO(ctx, script, io.Discard, o.output,
+		"exec", "-i", name, "psql", "-X", "-q", "-U", user, "-d", database, "-v", "ON_ERROR_STOP=1", "-f", "-")
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+// restoreStream pipes a custom-format dump to pg_restore over stdin.
+func (o *RestoreOrchestrator) restoreStream(ctx context.Context, name, user, database, input string) error {
+	f, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	err = o.docker.RunCommandWithIO(ctx, f, o.output, o.output,
+		"exec", "-i", name, "pg_restore", "-U", user, "-d", database, "--no-owner")
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+// restoreCopy copies the backup into the container so pg_restore can read it
+// directly, which parallel restores and directory-format backups require.
+func (o *RestoreOrchestrator) restoreCopy(ctx context.Context, name, user, database, input string, jobs int) error {
+	remote := fmt.Sprintf("/tmp/pgbox-restore-%d", os.Getpid())
+	defer func() {
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		_, _ = o.docker.ExecCommand(ctx, name, "rm", "-rf", remote)
+	}()
+
+	if out, err := o.docker.RunCommandWithOutput(ctx, "cp", input, name+":"+remote); err != nil {
+		return fmt.Errorf("failed to copy backup into %s: %s: %w", name, strings.TrimSpace(out), err)
+	}
+
+	args := []string{"exec", name, "pg_restore", "-U", user, "-d", database, "--no-owner"}
+	if jobs > 1 {
+		args = append(args, "-j", fmt.Sprintf("%d", jobs))
+	}
+	args = append(args, remote)
+	if err := o.docker.RunCommandWithIO(ctx, nil, o.output, o.output, args...); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+// detectDumpFormat infers the pg_dump format of a backup: directories are
+// directory-format dumps, files starting with PGDMP are custom-format and
+// anything else is treated as plain SQL.
+func detectDumpFormat(path string, info os.FileInfo) (string, error) {
+	if info.IsDir() {
+		return "directory", nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	header := make([]byte, 5)
+	n, _ := io.ReadFull(f, header)
+	if string(header[:n]) == "PGDMP" {
+		return "custom", nil
+	}
+	return "plain", nil
+}
@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBackup(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	return path
+}
+
+func TestRestoreOrchestrator_CustomFormatStreams(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	input := writeBackup(t, "db.dump", []byte("PGDMP\x01\x0e"))
+	var buf bytes.Buffer
+
+	err := NewRestoreOrchestrator(mock, &buf).Run(t.Context(), RestoreConfig{Input: input})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithIO, 1)
+	assert.Equal(t, []string{"exec", "-i", "pgbox-pg17", "pg_restore", "-U", "postgres", "-d", "postgres", "--no-owner"},
+		mock.Calls.RunCommandWithIO[0])
+	assert.Contains(t, buf.String(), "(custom format)")
+	assert.Contains(t, buf.String(), "Restore completed successfully")
+}
+
+func TestRestoreOrchestrator_PlainGzip(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte("CREATE TABLE t (id int);\n"))
+	require.NoError(t, w.Close())
+	input := writeBackup(t, "db.sql.gz", gz.Bytes())
+
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	var script string
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		data, _ := io.ReadAll(stdin)
+		script = string(data)
+		return nil
+	}
+	var buf bytes.Buffer
+
+	err := NewRestoreOrchestrator(mock, &buf).Run(t.Context(), RestoreConfig{Input: input})
+
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE t (id int);\n", script)
+	assert.Contains(t, mock.Calls.RunCommandWithIO[0], "psql")
+}
+
+func TestRestoreOrchestrator_ParallelCopiesIntoContainer(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	input := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewRestoreOrchestrator(mock, &buf).Run(t.Context(), RestoreConfig{Input: input, Jobs: 4})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunCommandWithOutput, 1)
+	assert.Equal(t, []string{"cp", input}, mock.Calls.RunCommandWithOutput[0][:2])
+	restore := mock.Calls.RunCommandWithIO[0]
+	assert.Contains(t, restore, "-j")
+	assert.Contains(t, restore, "4")
+	assert.Contains(t, buf.String(), "(directory format)")
+	require.Len(t, mock.Calls.ExecCommand, 1)
+	assert.Equal(t, "rm", mock.Calls.ExecCommand[0].Command[0])
+}
+
+func TestRestoreOrchestrator_PlainRejectsJobs(t *testing.T) {
+	mock := docker.NewMockDocker()
+	input := writeBackup(t, "db.sql", []byte("SELECT 1;\n"))
+	var buf bytes.Buffer
+
+	err := NewRestoreOrchestrator(mock, &buf).Run(t.Context(), RestoreConfig{Input: input, Jobs: 2})
+
+	assert.ErrorContains(t, err, "--jobs is not supported")
+}
+
+func TestRestoreOrchestrator_MissingFile(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	err := NewRestoreOrchestrator(mock, &buf).Run(t.Context(), RestoreConfig{Input: "/nonexistent/db.dump"})
+
+	assert.ErrorContains(t, err, "failed to read backup")
+}
@@ -0,0 +1,168 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// SizeConfig holds configuration for the size command.
+type SizeConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Top           int  // Number of relations to list
+	JSON          bool // Print the report as JSON
+}
+
+// DatabaseSize is the on-disk size of one database.
+type DatabaseSize struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// RelationSize breaks down the on-disk size of a table, materialized view or index.
+type RelationSize struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	TotalBytes int64  `json:"total_bytes"` // Heap, indexes and TOAST together
+	TableBytes int64  `json:"table_bytes"` // Main fork only
+	IndexBytes int64  `json:"index_bytes"`
+	ToastBytes int64  `json:"toast_bytes"`
+}
+
+// SizeReport is the result of the size command.
+type SizeReport struct {
+	Database  string         `json:"database"`
+	Databases []DatabaseSize `json:"databases"`
+	Relations []RelationSize `json:"relations"`
+}
+
+// SizeOrchestrator reports database and relation sizes for a running container.
+type SizeOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewSizeOrchestrator creates a new SizeOrchestrator.
+func NewSizeOrchestrator(d docker.Docker, w io.Writer) *SizeOrchestrator {
+	return &SizeOrchestrator{docker: d, output: w}
+}
+
+const databaseSizeQuery = `SELECT datname, pg_database_size(datname)
+FROM pg_database WHERE datallowconn ORDER BY 2 DESC, 1`
+
+// relationSizeQuery lists the largest user relations. %d is the row limit.
+const relationSizeQuery = `SELECT n.nspname, c.relname, c.relkind,
+  pg_total_relation_size(c.oid),
+  pg_relation_size(c.oid),
+  CASE WHEN c.relkind IN ('r', 'm') THEN pg_indexes_size(c.oid) ELSE 0 END,
+  CASE WHEN c.reltoastrelid <> 0 THEN pg_total_relation_size(c.reltoastrelid) ELSE 0 END
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'm', 'i')
+  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+  AND n.nspname NOT LIKE 'pg_toast%%'
+ORDER BY 4 DESC, 1, 2
+LIMIT %d`
+
+// relationKinds maps pg_class.relkind to display names.
+var relationKinds = map[string]string{
+	"r": "table",
+	"m": "matview",
+	"i": "index",
+}
+
+// Run prints per-database sizes and the largest relations in cfg.Database.
+func (o *SizeOrchestrator) Run(ctx context.Context, cfg SizeConfig) error {
+	if cfg.Top < 1 {
+		return fmt.Errorf("--top must be at least 1")
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+	report := SizeReport{Database: database, Databases: []DatabaseSize{}, Relations: []RelationSize{}}
+
+	rows, err := QueryLines(ctx, o.docker, name, user, database, databaseSizeQuery)
+	if err != nil {
+		return fmt.Errorf("failed to query database sizes: %w", err)
+	}
+	for _, row := range rows {
+		fields := strings.Split(row, "\t")
+		if len(fields) != 2 {
+			continue
+		}
+		report.Databases = append(report.Databases, DatabaseSize{Name: fields[0], Bytes: parseSize(fields[1])})
+	}
+
+	rows, err = QueryLines(ctx, o.docker, name, user, database, fmt.Sprintf(relationSizeQuery, cfg.Top))
+	if err != nil {
+		return fmt.Errorf("failed to query relation sizes: %w", err)
+	}
+	for _, row := range rows {
+		fields := strings.Split(row, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		report.Relations = append(report.Relations, RelationSize{
+			Schema:     fields[0],
+			Name:       fields[1],
+			Kind:       relationKinds[fields[2]],
+			TotalBytes: parseSize(fields[3]),
+			TableBytes: parseSize(fields[4]),
+			IndexBytes: parseSize(fields[5]),
+			ToastBytes: parseSize(fields[6]),
+		})
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(o.output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	o.printReport(report)
+	return nil
+}
+
+// printReport prints the size report as aligned text.
+func (o *SizeOrchestrator) printReport(report SizeReport) {
+	_, _ = fmt.Fprintln(o.output, "Databases:")
+	for _, db := range report.Databases {
+		_, _ = fmt.Fprintf(o.output, "  %-30s %10s\n", db.Name, formatBytes(db.Bytes))
+	}
+
+	_, _ = fmt.Fprintf(o.output, "\nLargest relations in %s:\n", report.Database)
+	if len(report.Relations) == 0 {
+		_, _ = fmt.Fprintln(o.output, "  (none)")
+		return
+	}
+	_, _ = fmt.Fprintf(o.output, "  %-40s %-8s %10s %10s %10s %10s\n", "RELATION", "KIND", "TOTAL", "TABLE", "INDEXES", "TOAST")
+	for _, rel := range report.Relations {
+		_, _ = fmt.Fprintf(o.output, "  %-40s %-8s %10s %10s %10s %10s\n",
+			rel.Schema+"."+rel.Name, rel.Kind,
+			formatBytes(rel.TotalBytes), formatBytes(rel.TableBytes),
+			formatBytes(rel.IndexBytes), formatBytes(rel.ToastBytes))
+	}
+}
+
+// parseSize parses a byte count returned by psql, treating bad values as 0.
+func parseSize(s string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return n
+}
@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sizeMock() *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		query := command[len(command)-1]
+		if strings.Contains(query, "pg_database_size") {
+			return "app\t52428800\npostgres\t7500000\n", nil
+		}
+		return "public\tevents\tr\t41943040\t31457280\t8388608\t2097152\n" +
+			"public\tevents_pkey\ti\t8388608\t8388608\t0\t0\n", nil
+	}
+	return mock
+}
+
+func TestSizeOrchestrator_Text(t *testing.T) {
+	mock := sizeMock()
+	var buf bytes.Buffer
+
+	orch := NewSizeOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SizeConfig{ContainerName: "my-postgres", Database: "app", Top: 5})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.ExecCommand, 2)
+	assert.Contains(t, mock.Calls.ExecCommand[1].Command[len(mock.Calls.ExecCommand[1].Command)-1], "LIMIT 5")
+
+	out := buf.String()
+	assert.Contains(t, out, "app")
+	assert.Contains(t, out, "50.0 MiB")
+	assert.Contains(t, out, "Largest relations in app:")
+	assert.Contains(t, out, "public.events")
+	assert.Contains(t, out, "2.0 MiB")
+	assert.Contains(t, out, "index")
+}
+
+func TestSizeOrchestrator_JSON(t *testing.T) {
+	mock := sizeMock()
+	var buf bytes.Buffer
+
+	orch := NewSizeOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SizeConfig{ContainerName: "my-postgres", Database: "app", Top: 10, JSON: true})
+
+	require.NoError(t, err)
+	var report SizeReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, "app", report.Database)
+	assert.Equal(t, []DatabaseSize{{Name: "app", Bytes: 52428800}, {Name: "postgres", Bytes: 7500000}}, report.Databases)
+	require.Len(t, report.Relations, 2)
+	assert.Equal(t, RelationSize{
+		Schema: "public", Name: "events", Kind: "table",
+		TotalBytes: 41943040, TableBytes: 31457280, IndexBytes: 8388608, ToastBytes: 2097152,
+	}, report.Relations[0])
+}
+
+func TestSizeOrchestrator_NotRunning(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewSizeOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SizeConfig{ContainerName: "my-postgres", Top: 10})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not running")
+}
@@ -0,0 +1,260 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// SnapshotConfig holds configuration for the snapshot commands.
+type SnapshotConfig struct {
+	ContainerName string
+	Name          string // Snapshot name
+	Force         bool   // Overwrite an existing snapshot with the same name
+}
+
+// Snapshot describes a stored snapshot of a container's data volume.
+type Snapshot struct {
+	Container string
+	Name      string
+	Path      string
+	Size      int64
+	Created   time.Time
+}
+
+// SnapshotOrchestrator saves and restores named copies of a container's data
+// volume under the pgbox data directory, using the same tar streams as
+// pgbox volume export/import.
+type SnapshotOrchestrator struct {
+	docker      docker.Docker
+	output      io.Writer
+	volumes     *VolumeOrchestrator
+	snapshotDir func() (string, error)
+}
+
+// NewSnapshotOrchestrator creates a new SnapshotOrchestrator.
+func NewSnapshotOrchestrator(d docker.Docker, w io.Writer) *SnapshotOrchestrator {
+	return &SnapshotOrchestrator{
+		docker:      d,
+		output:      w,
+		volumes:     NewVolumeOrchestrator(d, w, nil),
+		snapshotDir: config.SnapshotDir,
+	}
+}
+
+// snapshotNamePattern keeps snapshot names usable as file names.
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// snapshotExt is the file extension of stored snapshots.
+const snapshotExt = ".tar.gz"
+
+// validateSnapshotName rejects names that are not plain file names, so a
+// snapshot path cannot point outside the snapshot directory.
+func validateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// Create snapshots the container's data volume. A running container is
+// stopped for the copy and started again afterwards.
+func (o *SnapshotOrchestrator) Create(ctx context.Context, cfg SnapshotConfig) error {
+	if err := validateSnapshotName(cfg.Name); err != nil {
+		return err
+	}
+	name, volume, err := o.resolve(ctx, cfg.ContainerName)
+	if err != nil {
+		return err
+	}
+	path, err := o.snapshotPath(name, cfg.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && !cfg.Force {
+		return fmt.Errorf("snapshot %s of %s already exists (use --force to overwrite)", cfg.Name, name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	restart, err := o.stopForCopy(ctx, name)
+	if err != nil {
+		return err
+	}
+	logging.Infof(o.output, "Saving snapshot %s of %s...", cfg.Name, volume)
+	size, _, _, err := o.volumes.archiveVolume(ctx, volume, path)
+	if err := errors.Join(err, restart()); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Created snapshot %s (%s)\n", cfg.Name, formatBytes(size))
+	_, _ = fmt.Fprintf(o.output, "Restore it with: pgbox snapshot restore %s -n %s\n", cfg.Name, name)
+	return nil
+}
+
+// Restore replaces the container's data volume with a snapshot. A running
+// container is stopped for the copy and started again afterwards.
+func (o *SnapshotOrchestrator) Restore(ctx context.Context, cfg SnapshotConfig) error {
+	if err := validateSnapshotName(cfg.Name); err != nil {
+		return err
+	}
+	name, volume, err := o.resolve(ctx, cfg.ContainerName)
+	if err != nil {
+		return err
+	}
+	path, err := o.snapshotPath(name, cfg.Name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no snapshot named %s for %s. List snapshots with: pgbox snapshot list", cfg.Name, name)
+	}
+	if err := verifyChecksumFile(path); err != nil {
+		return err
+	}
+	if _, err := verifyArchive(path); err != nil {
+		return fmt.Errorf("snapshot %s is damaged: %w", cfg.Name, err)
+	}
+
+	restart, err := o.stopForCopy(ctx, name)
+	if err != nil {
+		return err
+	}
+	logging.Infof(o.output, "Restoring snapshot %s into %s...", cfg.Name, volume)
+	_, err = o.volumes.restoreVolume(ctx, volume, path, true)
+	if err := errors.Join(err, restart()); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Restored snapshot %s\n", cfg.Name)
+	return nil
+}
+
+// List prints the stored snapshots, for one container when cfg.ContainerName
+// is set, and returns them.
+func (o *SnapshotOrchestrator) List(cfg SnapshotConfig) ([]Snapshot, error) {
+	root, err := o.snapshotDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []string
+	if cfg.ContainerName != "" {
+		containers = []string{cfg.ContainerName}
+	} else {
+		entries, err := os.ReadDir(root)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", root, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				containers = append(containers, entry.Name())
+			}
+		}
+	}
+
+	var snapshots []Snapshot
+	for _, container := range containers {
+		entries, err := os.ReadDir(filepath.Join(root, container))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshots for %s: %w", container, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotExt) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			snapshots = append(snapshots, Snapshot{
+				Container: container,
+				Name:      strings.TrimSuffix(entry.Name(), snapshotExt),
+				Path:      filepath.Join(root, container, entry.Name()),
+				Size:      info.Size(),
+				Created:   info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Container != snapshots[j].Container {
+			return snapshots[i].Container < snapshots[j].Container
+		}
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+
+	if len(snapshots) == 0 {
+		_, _ = fmt.Fprintln(o.output, "No snapshots found.")
+		_, _ = fmt.Fprintln(o.output, "\nCreate one with: pgbox snapshot create <name>")
+		return nil, nil
+	}
+	_, _ = fmt.Fprintf(o.output, "%-30s %-24s %10s  %s\n", "NAME", "CONTAINER", "SIZE", "CREATED")
+	for _, s := range snapshots {
+		_, _ = fmt.Fprintf(o.output, "%-30s %-24s %10s  %s\n", s.Name, s.Container, formatBytes(s.Size), s.Created.Format("2006-01-02 15:04"))
+	}
+	return snapshots, nil
+}
+
+// resolve finds the container to snapshot and checks that its data volume exists.
+func (o *SnapshotOrchestrator) resolve(ctx context.Context, containerName string) (name, volume string, err error) {
+	name, _, err = ResolveContainerName(ctx, o.docker, containerName)
+	if err != nil {
+		return "", "", fmt.Errorf("%w. Specify container name with -n flag", err)
+	}
+	volume = volumeName(name)
+	if _, err := o.docker.RunCommandWithOutput(ctx, "volume", "inspect", volume); err != nil {
+		return "", "", fmt.Errorf("container %s has no data volume %s", name, volume)
+	}
+	return name, volume, nil
+}
+
+// snapshotPath returns where the named snapshot of container is stored.
+func (o *SnapshotOrchestrator) snapshotPath(container, name string) (string, error) {
+	root, err := o.snapshotDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, container, name+snapshotExt), nil
+}
+
+// stopForCopy stops the container if it is running so the data directory is
+// consistent, and returns a function that starts it again, also when ctx is
+// done by then.
+func (o *SnapshotOrchestrator) stopForCopy(ctx context.Context, name string) (func() error, error) {
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return func() error { return nil }, nil
+	}
+
+	logging.Infof(o.output, "Stopping %s for a consistent copy...", name)
+	if err := o.docker.StopContainer(ctx, name); err != nil {
+		return nil, fmt.Errorf("failed to stop container: %w", err)
+	}
+	return func() error {
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		if out, err := o.docker.RunCommandWithOutput(ctx, "start", name); err != nil {
+			return fmt.Errorf("failed to start %s again: %s: %w", name, strings.TrimSpace(out), err)
+		}
+		logging.Infof(o.output, "Started %s again", name)
+		return nil
+	}, nil
+}
@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSnapshotOrchestrator(mock *docker.MockDocker, w io.Writer, dir string) *SnapshotOrchestrator {
+	orch := NewSnapshotOrchestrator(mock, w)
+	orch.snapshotDir = func() (string, error) { return dir, nil }
+	return orch
+}
+
+func TestSnapshotOrchestrator_CreateStopsAndRestarts(t *testing.T) {
+	dir := t.TempDir()
+	archive := volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"})
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		_, err := stdout.Write(archive)
+		return err
+	}
+	var buf bytes.Buffer
+
+	orch := newTestSnapshotOrchestrator(mock, &buf, dir)
+	err := orch.Create(t.Context(), SnapshotConfig{ContainerName: "pgbox-pg17", Name: "before-migration"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.StopContainer)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"start", "pgbox-pg17"})
+	path := filepath.Join(dir, "pgbox-pg17", "before-migration.tar.gz")
+	assert.FileExists(t, path)
+	assert.FileExists(t, path+".sha256")
+
+	err = orch.Create(t.Context(), SnapshotConfig{ContainerName: "pgbox-pg17", Name: "before-migration"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+}
+
+func TestSnapshotOrchestrator_RestartsAfterContextEnds(t *testing.T) {
+	dir := t.TempDir()
+	archive := volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"})
+	ctx, cancel := context.WithCancelCause(t.Context())
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		cancel(errors.New("--timeout 1m expired"))
+		_, err := stdout.Write(archive)
+		return err
+	}
+	var buf bytes.Buffer
+
+	orch := newTestSnapshotOrchestrator(mock, &buf, dir)
+	_ = orch.Create(ctx, SnapshotConfig{ContainerName: "pgbox-pg17", Name: "before-migration"})
+
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"start", "pgbox-pg17"})
+	assert.Contains(t, buf.String(), "Started pgbox-pg17 again")
+}
+
+func TestSnapshotOrchestrator_CreateInvalidName(t *testing.T) {
+	orch := newTestSnapshotOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}, t.TempDir())
+	err := orch.Create(t.Context(), SnapshotConfig{ContainerName: "pgbox-pg17", Name: "../escape"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid snapshot name")
+}
+
+func TestSnapshotOrchestrator_RestoreReplacesVolume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pgbox-pg17", "clean.tar.gz")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"}), 0644))
+
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "run" {
+			return "PG_VERSION\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := newTestSnapshotOrchestrator(mock, &buf, dir)
+	err := orch.Restore(t.Context(), SnapshotConfig{ContainerName: "pgbox-pg17", Name: "clean"})
+
+	require.NoError(t, err)
+	assert.Empty(t, mock.Calls.StopContainer)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput,
+		[]string{"run", "--rm", "-v", "pgbox-pg17-data:/to", volumeHelperImage, "find", "/to", "-mindepth", "1", "-delete"})
+	require.Len(t, mock.Calls.RunCommandWithIO, 1)
+	assert.Contains(t, buf.String(), "Restored snapshot clean")
+}
+
+func TestSnapshotOrchestrator_RestoreInvalidName(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "elsewhere", "clean.tar.gz")
+	require.NoError(t, os.MkdirAll(filepath.Dir(outside), 0755))
+	require.NoError(t, os.WriteFile(outside, volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"}), 0644))
+
+	mock := docker.NewMockDocker()
+	orch := newTestSnapshotOrchestrator(mock, &bytes.Buffer{}, filepath.Join(dir, "snapshots"))
+	err := orch.Restore(t.Context(), SnapshotConfig{ContainerName: "pgbox-pg17", Name: "../../elsewhere/clean"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid snapshot name")
+	assert.Empty(t, mock.Calls.RunCommandWithOutput, "nothing is restored")
+	assert.Empty(t, mock.Calls.RunCommandWithIO)
+}
+
+func TestSnapshotOrchestrator_RestoreMissing(t *testing.T) {
+	orch := newTestSnapshotOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}, t.TempDir())
+	err := orch.Restore(t.Context(), SnapshotConfig{ContainerName: "pgbox-pg17", Name: "nope"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no snapshot named nope for pgbox-pg17")
+}
+
+func TestSnapshotOrchestrator_List(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"pgbox-pg17/one.tar.gz", "pgbox-pg17/two.tar.gz", "other/three.tar.gz"} {
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte("data"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pgbox-pg17", "one.tar.gz.sha256"), []byte("x"), 0644))
+	var buf bytes.Buffer
+
+	orch := newTestSnapshotOrchestrator(docker.NewMockDocker(), &buf, dir)
+	snapshots, err := orch.List(SnapshotConfig{})
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, "other", snapshots[0].Container)
+	assert.Contains(t, buf.String(), "4 B")
+
+	snapshots, err = orch.List(SnapshotConfig{ContainerName: "pgbox-pg17"})
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+}
+
+func TestSnapshotOrchestrator_ListEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	orch := newTestSnapshotOrchestrator(docker.NewMockDocker(), &buf, filepath.Join(t.TempDir(), "missing"))
+
+	snapshots, err := orch.List(SnapshotConfig{})
+
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+	assert.Contains(t, buf.String(), "No snapshots found.")
+}
@@ -0,0 +1,85 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// SQLConfig holds configuration for the sql command.
+type SQLConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Atomic        bool      // Wrap the whole script in a single transaction
+	Input         io.Reader // SQL script source (usually stdin)
+	// For testing: allows overriding stdin terminal detection
+	StdinIsTerminal *bool
+}
+
+// SQLOrchestrator handles running SQL batches read from stdin.
+type SQLOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewSQLOrchestrator creates a new SQLOrchestrator.
+func NewSQLOrchestrator(d docker.Docker, w io.Writer) *SQLOrchestrator {
+	return &SQLOrchestrator{docker: d, output: w}
+}
+
+// Run executes the SQL script from cfg.Input inside the container.
+// Each statement is echoed and timed; execution stops at the first error.
+func (o *SQLOrchestrator) Run(ctx context.Context, cfg SQLConfig) error {
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+
+	if cfg.ContainerName != "" {
+		running, err := o.docker.IsContainerRunning(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to check container status: %w", err)
+		}
+		if !running {
+			return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+		}
+	}
+
+	stdinIsTerminal := util.StdinIsTerminal()
+	if cfg.StdinIsTerminal != nil {
+		stdinIsTerminal = *cfg.StdinIsTerminal
+	}
+	if stdinIsTerminal || cfg.Input == nil {
+		return errors.New("no SQL provided on stdin. Pipe a script, e.g.: pgbox sql < script.sql")
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	args := []string{"exec", "-i", name, "psql", "-U", user, "-d", database,
+		"-v", "ON_ERROR_STOP=1", "--echo-queries"}
+	if cfg.Atomic {
+		args = append(args, "--single-transaction")
+	}
+	args = append(args, "-f", "-")
+
+	script := io.MultiReader(strings.NewReader("\\timing on\n"), cfg.Input)
+	if err := o.docker.RunCommandWithIO(ctx, script, o.output, o.output, args...); err != nil {
+		if cfg.Atomic {
+			return fmt.Errorf("SQL batch failed, transaction rolled back (no changes applied): %w", err)
+		}
+		return fmt.Errorf("SQL batch failed, statements before the error were applied: %w", err)
+	}
+
+	if cfg.Atomic {
+		_, _ = fmt.Fprintln(o.output, "SQL batch committed successfully")
+	} else {
+		_, _ = fmt.Fprintln(o.output, "SQL batch applied successfully")
+	}
+	return nil
+}
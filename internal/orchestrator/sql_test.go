@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLOrchestrator_AtomicBatch(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) {
+		return true, nil
+	}
+	var script string
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		data, _ := io.ReadAll(stdin)
+		script = string(data)
+		return nil
+	}
+	var buf bytes.Buffer
+	notTerminal := false
+
+	orch := NewSQLOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SQLConfig{
+		ContainerName:   "my-postgres",
+		User:            "postgres",
+		Database:        "postgres",
+		Atomic:          true,
+		Input:           strings.NewReader("CREATE TABLE t (id int);\n"),
+		StdinIsTerminal: &notTerminal,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, mock.Calls.RunCommandWithIO, 1)
+	assert.Equal(t, []string{
+		"exec", "-i", "my-postgres", "psql", "-U", "postgres", "-d", "postgres",
+		"-v", "ON_ERROR_STOP=1", "--echo-queries", "--single-transaction", "-f", "-",
+	}, mock.Calls.RunCommandWithIO[0])
+	assert.True(t, strings.HasPrefix(script, "\\timing on\n"))
+	assert.Contains(t, script, "CREATE TABLE t")
+	assert.Contains(t, buf.String(), "committed successfully")
+}
+
+func TestSQLOrchestrator_NonAtomicOmitsSingleTransaction(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) {
+		return "pgbox-pg17", nil
+	}
+	var buf bytes.Buffer
+	notTerminal := false
+
+	orch := NewSQLOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SQLConfig{
+		Input:           strings.NewReader("SELECT 1;"),
+		StdinIsTerminal: &notTerminal,
+	})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, mock.Calls.RunCommandWithIO[0], "--single-transaction")
+}
+
+func TestSQLOrchestrator_FailureReportsRollback(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) {
+		return true, nil
+	}
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		return errors.New("exit status 3")
+	}
+	var buf bytes.Buffer
+	notTerminal := false
+
+	orch := NewSQLOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SQLConfig{
+		ContainerName:   "my-postgres",
+		Atomic:          true,
+		Input:           strings.NewReader("SELECT broken;"),
+		StdinIsTerminal: &notTerminal,
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "rolled back")
+}
+
+func TestSQLOrchestrator_RequiresPipedInput(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) {
+		return true, nil
+	}
+	var buf bytes.Buffer
+	isTerminal := true
+
+	orch := NewSQLOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), SQLConfig{
+		ContainerName:   "my-postgres",
+		Input:           strings.NewReader(""),
+		StdinIsTerminal: &isTerminal,
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no SQL provided on stdin")
+	assert.Empty(t, mock.Calls.RunCommandWithIO)
+}
@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// sslCertFile and sslKeyFile are the generated certificate and key, written
+// next to the compose file by export and into the container's artifacts
+// directory by up.
+const (
+	sslCertFile = "server.crt"
+	sslKeyFile  = "server.key"
+)
+
+// sslMountDir is where the certificate and key are mounted in the container.
+const sslMountDir = "/etc/pgbox/ssl"
+
+// sslKeyPath is where sslEntrypointScript copies the key. The server refuses
+// a key that is not owned by its user or root, or that others can read,
+// which a file mounted from the host cannot promise.
+const sslKeyPath = "/var/run/postgresql/server.key"
+
+// SSLSettings are the server settings --ssl applies.
+var SSLSettings = map[string]string{
+	"ssl":           "on",
+	"ssl_cert_file": sslMountDir + "/" + sslCertFile,
+	"ssl_key_file":  sslKeyPath,
+}
+
+// sslInstallKey copies the mounted key to sslKeyPath, when there is one. It
+// runs as root, before the image's entrypoint drops to the postgres user.
+const sslInstallKey = `[ ! -f ` + sslMountDir + `/` + sslKeyFile + ` ] || install -o postgres -g postgres -m 600 ` +
+	sslMountDir + `/` + sslKeyFile + ` ` + sslKeyPath
+
+// sslEntrypointScript is the entrypoint of a container with --ssl: it
+// installs the key, then starts the server through the image's entrypoint
+// with the arguments given after the script.
+const sslEntrypointScript = "set -e\n" + sslInstallKey + "\nexec docker-entrypoint.sh \"$@\"\n"
+
+// sslEntrypointName is the script's $0, which marks a command that starts
+// with it.
+const sslEntrypointName = "pgbox-ssl"
+
+// sslCertValidFor is how long an existing certificate must still be valid
+// to be kept rather than replaced.
+const sslCertValidFor = 30 * 24 * time.Hour
+
+// applySSLSettings adds SSLSettings to the model as a profile, so --set can
+// still override them.
+func applySSLSettings(pgConfModel *model.PGConfModel) {
+	for key, value := range SSLSettings {
+		pgConfModel.ApplyGUC(key, value, model.GUCSource{Kind: model.SourceProfile, Name: "ssl"})
+	}
+}
+
+// sslHosts are the names the certificate is issued for: the host, and the
+// container or service name other containers reach the server by.
+func sslHosts(name string) []string {
+	return []string{"localhost", "127.0.0.1", "::1", name}
+}
+
+// ensureServerCert returns the certificate and key in dir, generating a
+// self-signed pair for name when there is none yet or it expires soon.
+// Keeping the pair lets clients that trust the certificate keep trusting it.
+func ensureServerCert(dir, name string) (certPEM, keyPEM []byte, generated bool, err error) {
+	certPEM, certErr := os.ReadFile(filepath.Join(dir, sslCertFile))
+	keyPEM, keyErr := os.ReadFile(filepath.Join(dir, sslKeyFile))
+	if certErr == nil && keyErr == nil && util.ServerCertValid(certPEM, keyPEM, sslCertValidFor) {
+		return certPEM, keyPEM, false, nil
+	}
+	certPEM, keyPEM, err = util.GenerateServerCert(name, sslHosts(name))
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to generate a certificate: %w", err)
+	}
+	return certPEM, keyPEM, true, nil
+}
+
+// configureSSL writes the server certificate and key into containerName's
+// artifacts directory, mounts them, and starts the server through
+// sslEntrypointScript. A dry run only says where they would go.
+func (o *UpOrchestrator) configureSSL(opts *docker.ContainerOptions, containerName string) error {
+	dir, err := config.ArtifactsDir(containerName)
+	if err != nil {
+		return err
+	}
+	if o.dryRun {
+		_, _ = fmt.Fprintf(o.output, "Would write a self-signed certificate to %s and its key to %s\n",
+			filepath.Join(dir, sslCertFile), filepath.Join(dir, sslKeyFile))
+	} else {
+		certPEM, keyPEM, _, err := ensureServerCert(dir, containerName)
+		if err != nil {
+			return err
+		}
+		if _, err := config.WriteArtifact(containerName, sslCertFile, sslMountDir+"/"+sslCertFile, certPEM); err != nil {
+			return fmt.Errorf("failed to write %s: %w", sslCertFile, err)
+		}
+		keyPath, err := config.WriteArtifact(containerName, sslKeyFile, sslMountDir+"/"+sslKeyFile, keyPEM)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", sslKeyFile, err)
+		}
+		// Only root in the container reads it, before handing a copy to postgres
+		if err := os.Chmod(keyPath, 0600); err != nil {
+			return fmt.Errorf("failed to restrict %s: %w", keyPath, err)
+		}
+	}
+	opts.ExtraArgs = append(opts.ExtraArgs, sslMounts(dir)...)
+	opts.ExtraArgs = append(opts.ExtraArgs, "--entrypoint", "sh")
+	opts.Command = append([]string{"-c", sslEntrypointScript, sslEntrypointName}, opts.Command...)
+	return nil
+}
+
+// sslMounts returns the -v arguments that mount the certificate and key in
+// dir.
+func sslMounts(dir string) []string {
+	var args []string
+	for _, file := range []string{sslCertFile, sslKeyFile} {
+		args = append(args, "-v", fmt.Sprintf("%s:%s/%s:ro", filepath.Join(dir, file), sslMountDir, file))
+	}
+	return args
+}
+
+// writeSSLFiles writes the certificate and key for the compose service to
+// scaffoldDir, keeping an earlier export's, and mounts them into the service,
+// which starts through sslEntrypointScript.
+func (o *ExportOrchestrator) writeSSLFiles(m *model.ComposeModel, scaffoldDir, relDir string) error {
+	certPEM, keyPEM, generated, err := ensureServerCert(scaffoldDir, m.ServiceName)
+	if err != nil {
+		return err
+	}
+	if generated {
+		for _, f := range []struct {
+			name    string
+			content []byte
+			mode    os.FileMode
+		}{{sslCertFile, certPEM, 0644}, {sslKeyFile, keyPEM, 0600}} {
+			path := filepath.Join(scaffoldDir, f.name)
+			if err := os.WriteFile(path, f.content, f.mode); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+	}
+	for _, volume := range sslComposeVolumes(relDir) {
+		m.AddVolume(volume)
+	}
+	// Compose would substitute the script's variables itself
+	m.Entrypoint = []string{"sh", "-c", strings.ReplaceAll(sslEntrypointScript, "$", "$$"), sslEntrypointName}
+	return nil
+}
+
+// sslComposeVolumes mounts the certificate and key written next to the
+// compose file, relDir being their directory relative to it.
+func sslComposeVolumes(relDir string) []string {
+	var volumes []string
+	for _, file := range []string{sslCertFile, sslKeyFile} {
+		volumes = append(volumes, fmt.Sprintf("%s/%s:%s/%s:ro", relDir, file, sslMountDir, file))
+	}
+	return volumes
+}
+
+// stripSSLEntrypoint returns a container's command without the
+// sslEntrypointScript it starts with, and whether it did.
+func stripSSLEntrypoint(args []string) ([]string, bool) {
+	if len(args) >= 3 && args[0] == "-c" && args[2] == sslEntrypointName {
+		return args[3:], true
+	}
+	return args, false
+}
+
+// containerSSL reports whether a container's server has ssl turned on in its
+// command, as up --ssl and export --ssl do.
+func containerSSL(ctx context.Context, d docker.Docker, name string) bool {
+	output, err := d.RunCommandWithOutput(ctx, "inspect", "-f", "{{json .Config.Cmd}}", name)
+	if err != nil {
+		return false
+	}
+	var args []string
+	if json.Unmarshal([]byte(strings.TrimSpace(output)), &args) != nil {
+		return false
+	}
+	args, _ = stripSSLEntrypoint(args)
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-c" && slices.Contains([]string{"ssl=on", "ssl=true", "ssl=1"}, args[i+1]) {
+			return true
+		}
+	}
+	return false
+}
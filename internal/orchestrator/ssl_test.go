@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpOrchestrator_SSL(t *testing.T) {
+	name := "pgbox-ssl-test"
+	t.Cleanup(func() { _ = config.RemoveArtifacts(name) })
+	mock := docker.NewMockDocker()
+
+	err := newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", ContainerName: name, Detach: true, SSL: true})
+
+	require.NoError(t, err)
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	call := mock.Calls.RunPostgres[0]
+	assert.True(t, call.Config.SSL)
+	assert.Equal(t, []string{"-c", sslEntrypointScript, "pgbox-ssl"}, call.Opts.Command[:3])
+	assert.Contains(t, call.Opts.Command, "ssl=on")
+	assert.Contains(t, call.Opts.Command, "ssl_cert_file=/etc/pgbox/ssl/server.crt")
+	assert.Contains(t, call.Opts.Command, "ssl_key_file=/var/run/postgresql/server.key")
+
+	dir, err := config.ArtifactsDir(name)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(call.Opts.ExtraArgs, " "),
+		"-v "+filepath.Join(dir, "server.crt")+":/etc/pgbox/ssl/server.crt:ro -v "+filepath.Join(dir, "server.key")+":/etc/pgbox/ssl/server.key:ro --entrypoint sh")
+	info, err := os.Stat(filepath.Join(dir, "server.key"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	cert, err := os.ReadFile(filepath.Join(dir, "server.crt"))
+	require.NoError(t, err)
+
+	mock = docker.NewMockDocker()
+	err = newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", ContainerName: name, Detach: true, SSL: true,
+		Settings: map[string]string{"ssl": "off"}})
+	require.NoError(t, err)
+	again, err := os.ReadFile(filepath.Join(dir, "server.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, cert, again, "a valid certificate is kept")
+	assert.Contains(t, mock.Calls.RunPostgres[0].Opts.Command, "ssl=off", "--set wins over --ssl")
+}
+
+func TestUpOrchestrator_SSLExistingContainer(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "ps" {
+			return "pgbox-pg17\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	err := newTestUpOrchestrator(mock, &buf).Run(t.Context(), UpConfig{Version: "17", ContainerName: "pgbox-pg17", Detach: true, SSL: true})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "--ssl only applies to new containers; recreate pgbox-pg17 with --recreate --ssl to turn it on")
+}
+
+func TestInspectUpConfig_SSL(t *testing.T) {
+	mock, _ := newUpgradeMock()
+	inspect := mock.RunCommandWithOutputFunc
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if len(args) > 2 && args[2] == "{{json .Config.Cmd}}" {
+			return `["-c","set -e\nexec docker-entrypoint.sh \"$@\"\n","pgbox-ssl","-c","ssl=on","-c","ssl_cert_file=/etc/pgbox/ssl/server.crt",` +
+				`"-c","ssl_key_file=/var/run/postgresql/server.key","-c","work_mem=64MB"]`, nil
+		}
+		return inspect(args...)
+	}
+
+	cfg, err := inspectUpConfig(t.Context(), mock, "pgbox-pg16")
+
+	require.NoError(t, err)
+	assert.True(t, cfg.SSL)
+	assert.Equal(t, map[string]string{"work_mem": "64MB"}, cfg.Settings, "--ssl brings its own settings back")
+	assert.True(t, containerSSL(t.Context(), mock, "pgbox-pg16"))
+}
+
+func TestStatusOrchestrator_SSL(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.FindPgboxContainerFunc = func() (string, error) { return "pgbox-pg17", nil }
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "inspect" {
+			return `["postgres","-c","ssl=on"]`, nil
+		}
+		return "0.0.0.0:5433\n", nil
+	}
+
+	for format, want := range map[string]string{
+		"uri":  "postgres://postgres@localhost:5433/postgres?sslmode=require",
+		"dsn":  "host=localhost port=5433 user=postgres dbname=postgres sslmode=require",
+		"jdbc": "jdbc:postgresql://localhost:5433/postgres?sslmode=require&user=postgres",
+	} {
+		var buf bytes.Buffer
+		err := NewStatusOrchestrator(mock, &buf).Run(t.Context(), StatusConfig{Format: format})
+		require.NoError(t, err)
+		assert.Equal(t, want+"\n", buf.String(), format)
+	}
+}
+
+func TestExportOrchestrator_SSL(t *testing.T) {
+	dir := t.TempDir()
+
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", SSL: true, Replica: true})
+
+	require.NoError(t, err)
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(compose), `    entrypoint:
+      - "sh"
+      - "-c"
+      - |
+        set -e
+        [ ! -f /etc/pgbox/ssl/server.key ] || install -o postgres -g postgres -m 600 /etc/pgbox/ssl/server.key /var/run/postgresql/server.key
+        exec docker-entrypoint.sh "$$@"
+      - "pgbox-ssl"
+    command:
+`)
+	assert.Contains(t, string(compose), "      - ssl=on\n")
+	assert.Equal(t, 4, strings.Count(string(compose), ":/etc/pgbox/ssl/server."), "mounted into db and the replica")
+	info, err := os.Stat(filepath.Join(dir, "server.key"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	var buf bytes.Buffer
+	err = NewExportOrchestrator(&buf).Run(ExportConfig{TargetDir: dir, Version: "17", Port: "5432", SSL: true, Replica: true, Check: true})
+	require.NoError(t, err, "the certificate is kept")
+	assert.Contains(t, buf.String(), "is up to date")
+
+	err = NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: t.TempDir(), Version: "17", Format: FormatDevcontainerFeature, SSL: true})
+	assert.EqualError(t, err, "--ssl is not supported with --format devcontainer-feature")
+}
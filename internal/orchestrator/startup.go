@@ -0,0 +1,305 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/extensions"
+)
+
+const (
+	// defaultReadyTimeout is how long up waits for PostgreSQL to accept connections.
+	defaultReadyTimeout = 60 * time.Second
+	// defaultPollInterval is the delay between readiness checks.
+	defaultPollInterval = 500 * time.Millisecond
+	// defaultLockTimeout is how long up waits for a concurrent up of the same
+	// container, which may be building an image.
+	defaultLockTimeout = 10 * time.Minute
+)
+
+// StartupReport holds the results of the post-start verification pass.
+type StartupReport struct {
+	Ready             bool          // Whether PostgreSQL accepted connections in time
+	HostPort          string        // Host port actually bound to 5432/tcp
+	Extensions        []string      // SQL extension names verified in pg_extension
+	MissingExtensions []string      // Requested SQL extensions not found in pg_extension
+	LogErrors         []string      // ERROR lines found in the container logs
+	InitErrors        []InitError   // Failed statements from docker-entrypoint-initdb.d scripts
+	ReadyAfter        time.Duration // How long the wait for connections took
+	InitTime          time.Duration // How long initdb and the init scripts took; 0 when the volume was already initialized
+}
+
+// InitError describes a statement that failed while running docker-entrypoint-initdb.d scripts.
+type InitError struct {
+	File      string // Script path inside the container
+	Line      string // Line number reported by psql
+	Message   string // Error message
+	Statement string // Offending statement, when the server logged it
+}
+
+// String formats the init error for display.
+func (e InitError) String() string {
+	s := fmt.Sprintf("%s:%s: %s", e.File, e.Line, e.Message)
+	if e.Statement != "" {
+		s += fmt.Sprintf("\n      statement: %s", e.Statement)
+	}
+	return s
+}
+
+var (
+	// psqlErrorPattern matches psql errors such as "psql:/docker-entrypoint-initdb.d/init.sql:4: ERROR:  msg".
+	psqlErrorPattern = regexp.MustCompile(`psql:([^:]+):(\d+): (?:ERROR|FATAL):\s+(.*)$`)
+	// serverErrorPattern matches server log errors such as "... [64] ERROR:  msg".
+	serverErrorPattern = regexp.MustCompile(`(?:ERROR|FATAL):\s+(.*)$`)
+	// statementPattern matches the server's STATEMENT log line following an error.
+	statementPattern = regexp.MustCompile(`STATEMENT:\s+(.*)$`)
+)
+
+// waitForReady polls pg_isready inside the container until it succeeds, the timeout expires
+// or ctx is done. It connects over TCP so the temporary server used during initdb is not mistaken for the real one.
+func (o *UpOrchestrator) waitForReady(ctx context.Context, containerName string, pgConfig *config.PostgresConfig) bool {
+	deadline := time.Now().Add(o.readyTimeout)
+	for {
+		_, err := o.docker.ExecCommand(ctx, containerName, "pg_isready", "-h", "127.0.0.1", "-U", pgConfig.User, "-d", pgConfig.Database)
+		if err == nil {
+			return true
+		}
+		// The entrypoint exits when an init script fails, so stop waiting early
+		if running, _ := o.docker.IsContainerRunning(ctx, containerName); !running {
+			return false
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		if sleepContext(ctx, o.pollInterval) != nil {
+			return false
+		}
+	}
+}
+
+// startStopped starts an existing, stopped container and waits until it
+// accepts connections, for commands that need it running.
+func (o *UpOrchestrator) startStopped(ctx context.Context, containerName string) error {
+	if out, err := o.docker.RunCommandWithOutput(ctx, "start", containerName); err != nil {
+		return fmt.Errorf("failed to start %s: %s: %w", containerName, strings.TrimSpace(out), err)
+	}
+	pgConfig := config.NewPostgresConfig()
+	pgConfig.User, pgConfig.Database = ResolveCredentials(ctx, o.docker, containerName, "", "")
+	if !o.waitForReady(ctx, containerName, pgConfig) {
+		return o.notReadyError(ctx, containerName)
+	}
+	return nil
+}
+
+// verifyStartup waits for the server and gathers what actually happened during startup.
+func (o *UpOrchestrator) verifyStartup(ctx context.Context, containerName string, pgConfig *config.PostgresConfig, extNames []string) StartupReport {
+	report := StartupReport{HostPort: pgConfig.Port}
+
+	start := time.Now()
+	report.Ready = o.waitForReady(ctx, containerName, pgConfig)
+	report.ReadyAfter = time.Since(start)
+
+	if output, err := o.docker.RunCommandWithOutput(ctx, "port", containerName, "5432/tcp"); err == nil {
+		if port := parseHostPort(output); port != "" {
+			report.HostPort = port
+		}
+	}
+
+	if logs, err := o.docker.RunCommandWithOutput(ctx, "logs", "-t", containerName); err == nil {
+		logs, report.InitTime = splitLogTimestamps(logs)
+		report.LogErrors = findLogErrors(logs)
+		report.InitErrors = parseInitErrors(logs)
+	}
+
+	if !report.Ready {
+		return report
+	}
+
+	expected := extensions.GetCreatedSQLNames(extNames)
+	if len(expected) == 0 {
+		return report
+	}
+	installed, err := QueryLines(ctx, o.docker, containerName, pgConfig.User, pgConfig.Database, "SELECT extname FROM pg_extension")
+	if err != nil {
+		report.MissingExtensions = expected
+		return report
+	}
+	have := make(map[string]bool)
+	for _, name := range installed {
+		have[strings.TrimSpace(name)] = true
+	}
+	for _, name := range expected {
+		if have[name] {
+			report.Extensions = append(report.Extensions, name)
+		} else {
+			report.MissingExtensions = append(report.MissingExtensions, name)
+		}
+	}
+	return report
+}
+
+// parseHostPort extracts the host port from `docker port` output (e.g., "0.0.0.0:5433").
+func parseHostPort(output string) string {
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		line = strings.TrimSpace(line)
+		if idx := strings.LastIndex(line, ":"); idx >= 0 && idx < len(line)-1 {
+			return line[idx+1:]
+		}
+	}
+	return ""
+}
+
+// initCompleteLine is what the postgres entrypoint logs once initdb and the
+// init scripts have run.
+const initCompleteLine = "PostgreSQL init process complete"
+
+// splitLogTimestamps strips the timestamps `docker logs -t` puts in front of
+// each line, and returns the logs without them along with the time from the
+// first line to the end of initialization. That time is 0 when the logs show
+// no initialization.
+func splitLogTimestamps(logs string) (string, time.Duration) {
+	lines := strings.Split(logs, "\n")
+	var first time.Time
+	var initTime time.Duration
+	for i, line := range lines {
+		stamp, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			continue
+		}
+		lines[i] = rest
+		if first.IsZero() {
+			first = at
+		}
+		if initTime == 0 && strings.Contains(rest, initCompleteLine) {
+			initTime = at.Sub(first)
+		}
+	}
+	return strings.Join(lines, "\n"), initTime
+}
+
+// findLogErrors returns the ERROR and FATAL lines from PostgreSQL container logs.
+func findLogErrors(logs string) []string {
+	var errs []string
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.Contains(line, "ERROR:") || strings.Contains(line, "FATAL:") {
+			errs = append(errs, strings.TrimSpace(line))
+		}
+	}
+	return errs
+}
+
+// parseInitErrors extracts failed init script statements from container logs.
+// psql reports the file and line, while the server log carries the full statement.
+func parseInitErrors(logs string) []InitError {
+	statements := make(map[string]string)
+	pending := ""
+	var errs []InitError
+
+	for _, line := range strings.Split(logs, "\n") {
+		if m := psqlErrorPattern.FindStringSubmatch(line); m != nil {
+			message := strings.TrimSpace(m[3])
+			errs = append(errs, InitError{
+				File:      m[1],
+				Line:      m[2],
+				Message:   message,
+				Statement: statements[message],
+			})
+			continue
+		}
+		if m := statementPattern.FindStringSubmatch(line); m != nil {
+			if pending != "" {
+				statements[pending] = strings.TrimSpace(m[1])
+				pending = ""
+			}
+			continue
+		}
+		if m := serverErrorPattern.FindStringSubmatch(line); m != nil {
+			pending = strings.TrimSpace(m[1])
+		}
+	}
+	return errs
+}
+
+// printSummary prints the post-start summary with connection details and next steps.
+func (o *UpOrchestrator) printSummary(containerName string, pgConfig *config.PostgresConfig, extNames []string, report StartupReport) {
+	if report.Ready {
+		_, _ = fmt.Fprintln(o.output, "PostgreSQL is ready")
+	} else {
+		_, _ = fmt.Fprintf(o.output, "PostgreSQL did not accept connections within %s\n", o.readyTimeout)
+	}
+
+	_, _ = fmt.Fprintf(o.output, "  Port:       %s\n", report.HostPort)
+	// Only hand out a connection string once the server actually accepts connections
+	if report.Ready {
+		_, _ = fmt.Fprintf(o.output, "  Connection: %s\n", connectionString(pgConfig, report.HostPort))
+	}
+	if len(report.Extensions) > 0 {
+		_, _ = fmt.Fprintf(o.output, "  Extensions: %s (verified)\n", strings.Join(report.Extensions, ", "))
+	}
+
+	if len(report.MissingExtensions) > 0 || len(report.LogErrors) > 0 {
+		_, _ = fmt.Fprintln(o.output, "\nIssues detected:")
+		for _, name := range report.MissingExtensions {
+			_, _ = fmt.Fprintf(o.output, "  - extension %s is not installed\n", name)
+		}
+		if len(report.InitErrors) > 0 {
+			for _, initErr := range report.InitErrors {
+				_, _ = fmt.Fprintf(o.output, "  - init script failed at %s\n", initErr)
+			}
+		} else {
+			for _, line := range report.LogErrors {
+				_, _ = fmt.Fprintf(o.output, "  - %s\n", line)
+			}
+		}
+	}
+
+	if report.Ready {
+		o.printTips(extNames)
+	}
+
+	_, _ = fmt.Fprintln(o.output, "\nNext steps:")
+	_, _ = fmt.Fprintf(o.output, "  pgbox psql -n %s     # connect\n", containerName)
+	_, _ = fmt.Fprintf(o.output, "  pgbox logs -n %s     # view logs\n", containerName)
+	_, _ = fmt.Fprintf(o.output, "  pgbox down -n %s     # stop\n", containerName)
+}
+
+// printTips prints the catalog's getting-started tips for the extensions
+// that have them.
+func (o *UpOrchestrator) printTips(extNames []string) {
+	for _, name := range extNames {
+		ext, _ := extensions.Get(name)
+		if len(ext.Tips) == 0 {
+			continue
+		}
+		if ext.DocURL != "" {
+			_, _ = fmt.Fprintf(o.output, "\nGetting started with %s (%s):\n", name, ext.DocURL)
+		} else {
+			_, _ = fmt.Fprintf(o.output, "\nGetting started with %s:\n", name)
+		}
+		for _, tip := range ext.Tips {
+			_, _ = fmt.Fprintf(o.output, "  - %s\n", tip)
+		}
+	}
+}
+
+// connectionString is the URL for connecting to pgConfig's database on the
+// given host port, over TLS when the server has it.
+func connectionString(pgConfig *config.PostgresConfig, hostPort string) string {
+	u := url.URL{Scheme: "postgres", User: url.User(pgConfig.User), Host: "localhost:" + hostPort, Path: "/" + pgConfig.Database}
+	if pgConfig.Password != "" {
+		u.User = url.UserPassword(pgConfig.User, pgConfig.Password)
+	}
+	if pgConfig.SSL {
+		u.RawQuery = "sslmode=require"
+	}
+	return u.String()
+}
@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// StatsConfig holds configuration for the stats command.
+type StatsConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Limit         int    // Number of statements to list
+	Sort          string // One of StatsSorts; empty means total
+	Reset         bool   // Clear the statistics instead of printing them
+	JSON          bool   // Print the statements as JSON
+}
+
+// StatsSorts lists the values accepted by StatsConfig.Sort.
+var StatsSorts = []string{"total", "mean", "calls", "rows"}
+
+// statsSortColumns maps StatsConfig.Sort to the pg_stat_statements column
+// the statements are ordered by.
+var statsSortColumns = map[string]string{
+	"total": "total_exec_time",
+	"mean":  "mean_exec_time",
+	"calls": "calls",
+	"rows":  "rows",
+}
+
+// StatementStats is one statement of pg_stat_statements.
+type StatementStats struct {
+	QueryID     string  `json:"queryid"`
+	Database    string  `json:"database"`
+	User        string  `json:"user"`
+	Calls       int64   `json:"calls"`
+	TotalTimeMs float64 `json:"total_time_ms"`
+	MeanTimeMs  float64 `json:"mean_time_ms"`
+	Rows        int64   `json:"rows"`
+	Query       string  `json:"query"`
+}
+
+// StatsOrchestrator reports the top statements of pg_stat_statements for a
+// running container.
+type StatsOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewStatsOrchestrator creates a new StatsOrchestrator.
+func NewStatsOrchestrator(d docker.Docker, w io.Writer) *StatsOrchestrator {
+	return &StatsOrchestrator{docker: d, output: w}
+}
+
+// statementStatsQuery lists the statements of every database. %s is the
+// column to order by and %d the row limit.
+const statementStatsQuery = `SELECT s.queryid, COALESCE(d.datname, ''), COALESCE(r.rolname, ''), s.calls,
+  round(s.total_exec_time::numeric, 3), round(s.mean_exec_time::numeric, 3), s.rows,
+  regexp_replace(s.query, '\s+', ' ', 'g')
+FROM pg_stat_statements s
+LEFT JOIN pg_database d ON d.oid = s.dbid
+LEFT JOIN pg_roles r ON r.oid = s.userid
+ORDER BY s.%s DESC, s.queryid
+LIMIT %d`
+
+// statsQueryWidth is where queries are cut off in the table.
+const statsQueryWidth = 80
+
+// Run prints the top statements by cfg.Sort, or clears the statistics with
+// cfg.Reset. The extension is created in cfg.Database first if it is missing
+// there; its library must already be preloaded, which takes a restart.
+func (o *StatsOrchestrator) Run(ctx context.Context, cfg StatsConfig) error {
+	if cfg.Limit < 1 {
+		return fmt.Errorf("--limit must be at least 1")
+	}
+	sort := cfg.Sort
+	if sort == "" {
+		sort = "total"
+	}
+	if !slices.Contains(StatsSorts, sort) {
+		return fmt.Errorf("invalid sort %q (must be %s)", cfg.Sort, strings.Join(StatsSorts, ", "))
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	loaded, err := preloadedLibraries(ctx, o.docker, name, user, database)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(loaded, "pg_stat_statements") {
+		return fmt.Errorf("pg_stat_statements is not in shared_preload_libraries of %s, so it collects no statistics; "+
+			"load it with: pgbox ext add pg_stat_statements -n %s (recreates the container, keeping its data volume)", name, name)
+	}
+	if err := o.ensureExtension(ctx, name, user, database); err != nil {
+		return err
+	}
+
+	if cfg.Reset {
+		if _, err := QueryLines(ctx, o.docker, name, user, database, "SELECT pg_stat_statements_reset()"); err != nil {
+			return fmt.Errorf("failed to reset pg_stat_statements: %w", err)
+		}
+		_, _ = fmt.Fprintf(o.output, "Reset the statement statistics of %s\n", name)
+		return nil
+	}
+
+	rows, err := QueryLines(ctx, o.docker, name, user, database, fmt.Sprintf(statementStatsQuery, statsSortColumns[sort], cfg.Limit))
+	if err != nil {
+		return fmt.Errorf("failed to read pg_stat_statements: %w", err)
+	}
+	statements := []StatementStats{}
+	for _, row := range rows {
+		if stats, ok := parseStatementStats(row); ok {
+			statements = append(statements, stats)
+		}
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(o.output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statements)
+	}
+	o.printStatements(name, sort, statements)
+	return nil
+}
+
+// ensureExtension creates pg_stat_statements in the database, whose view is
+// only there once the extension is.
+func (o *StatsOrchestrator) ensureExtension(ctx context.Context, name, user, database string) error {
+	rows, err := QueryLines(ctx, o.docker, name, user, database, "SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements'")
+	if err != nil {
+		return fmt.Errorf("failed to check for pg_stat_statements: %w", err)
+	}
+	if len(rows) > 0 {
+		return nil
+	}
+	if _, err := QueryLines(ctx, o.docker, name, user, database, "CREATE EXTENSION IF NOT EXISTS pg_stat_statements"); err != nil {
+		return fmt.Errorf("failed to enable pg_stat_statements: %w", err)
+	}
+	logging.Infof(o.output, "Enabled pg_stat_statements in %s", database)
+	return nil
+}
+
+// printStatements prints the statements as aligned text.
+func (o *StatsOrchestrator) printStatements(name, sort string, statements []StatementStats) {
+	by := map[string]string{"total": "total time", "mean": "mean time", "calls": "calls", "rows": "rows"}[sort]
+	_, _ = fmt.Fprintf(o.output, "Top statements in %s by %s:\n", name, by)
+	if len(statements) == 0 {
+		_, _ = fmt.Fprintln(o.output, "  (none)")
+		return
+	}
+	_, _ = fmt.Fprintf(o.output, "  %10s %12s %10s %10s  %-12s %s\n", "CALLS", "TOTAL MS", "MEAN MS", "ROWS", "DATABASE", "QUERY")
+	for _, s := range statements {
+		query := s.Query
+		if runes := []rune(query); len(runes) > statsQueryWidth {
+			query = string(runes[:statsQueryWidth-3]) + "..."
+		}
+		_, _ = fmt.Fprintf(o.output, "  %10d %12.1f %10.2f %10d  %-12s %s\n",
+			s.Calls, s.TotalTimeMs, s.MeanTimeMs, s.Rows, s.Database, query)
+	}
+}
+
+// parseStatementStats parses one row of statementStatsQuery.
+func parseStatementStats(row string) (StatementStats, bool) {
+	fields := strings.SplitN(row, "\t", 8)
+	if len(fields) != 8 {
+		return StatementStats{}, false
+	}
+	total, _ := strconv.ParseFloat(strings.TrimSpace(fields[4]), 64)
+	mean, _ := strconv.ParseFloat(strings.TrimSpace(fields[5]), 64)
+	return StatementStats{
+		QueryID:     fields[0],
+		Database:    fields[1],
+		User:        fields[2],
+		Calls:       parseSize(fields[3]),
+		TotalTimeMs: total,
+		MeanTimeMs:  mean,
+		Rows:        parseSize(fields[6]),
+		Query:       fields[7],
+	}, true
+}
@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statsMock answers the stats queries: preload holds shared_preload_libraries
+// and created whether the extension exists in the database.
+func statsMock(preload string, created bool) *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		query := command[len(command)-1]
+		switch {
+		case query == "SHOW shared_preload_libraries":
+			return preload + "\n", nil
+		case strings.Contains(query, "FROM pg_extension"):
+			if created {
+				return "1\n", nil
+			}
+			return "", nil
+		case strings.Contains(query, "FROM pg_stat_statements"):
+			return "-4211\tapp\tpostgres\t1200\t5400.125\t4.500\t1200\tSELECT * FROM events WHERE id = $1\n" +
+				"77\tapp\tpostgres\t3\t900.000\t300.000\t0\tUPDATE accounts SET balance = balance - $1\n", nil
+		}
+		return "", nil
+	}
+	return mock
+}
+
+// statsQueries returns the queries the mock was sent.
+func statsQueries(mock *docker.MockDocker) []string {
+	var queries []string
+	for _, call := range mock.Calls.ExecCommand {
+		queries = append(queries, call.Command[len(call.Command)-1])
+	}
+	return queries
+}
+
+func TestStatsOrchestrator_Text(t *testing.T) {
+	mock := statsMock("pg_stat_statements", false)
+	var buf bytes.Buffer
+
+	err := NewStatsOrchestrator(mock, &buf).Run(t.Context(), StatsConfig{ContainerName: "my-postgres", Database: "app", Limit: 5, Sort: "mean"})
+
+	require.NoError(t, err)
+	queries := statsQueries(mock)
+	assert.Contains(t, queries, "CREATE EXTENSION IF NOT EXISTS pg_stat_statements")
+	assert.Contains(t, queries[len(queries)-1], "ORDER BY s.mean_exec_time DESC")
+	assert.Contains(t, queries[len(queries)-1], "LIMIT 5")
+
+	out := buf.String()
+	assert.Contains(t, out, "Enabled pg_stat_statements in app")
+	assert.Contains(t, out, "Top statements in my-postgres by mean time:")
+	assert.Contains(t, out, "SELECT * FROM events WHERE id = $1")
+	assert.Contains(t, out, "5400.1")
+}
+
+func TestStatsOrchestrator_JSON(t *testing.T) {
+	mock := statsMock("pg_cron, pg_stat_statements", true)
+	var buf bytes.Buffer
+
+	err := NewStatsOrchestrator(mock, &buf).Run(t.Context(), StatsConfig{ContainerName: "my-postgres", Limit: 10, JSON: true})
+
+	require.NoError(t, err)
+	assert.NotContains(t, statsQueries(mock), "CREATE EXTENSION IF NOT EXISTS pg_stat_statements")
+	var statements []StatementStats
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &statements))
+	require.Len(t, statements, 2)
+	assert.Equal(t, StatementStats{
+		QueryID: "-4211", Database: "app", User: "postgres", Calls: 1200,
+		TotalTimeMs: 5400.125, MeanTimeMs: 4.5, Rows: 1200, Query: "SELECT * FROM events WHERE id = $1",
+	}, statements[0])
+}
+
+func TestStatsOrchestrator_Reset(t *testing.T) {
+	mock := statsMock("pg_stat_statements", true)
+	var buf bytes.Buffer
+
+	err := NewStatsOrchestrator(mock, &buf).Run(t.Context(), StatsConfig{ContainerName: "my-postgres", Limit: 10, Reset: true})
+
+	require.NoError(t, err)
+	queries := statsQueries(mock)
+	assert.Equal(t, "SELECT pg_stat_statements_reset()", queries[len(queries)-1])
+	assert.Contains(t, buf.String(), "Reset the statement statistics of my-postgres")
+}
+
+func TestStatsOrchestrator_NotPreloaded(t *testing.T) {
+	mock := statsMock("", false)
+	var buf bytes.Buffer
+
+	err := NewStatsOrchestrator(mock, &buf).Run(t.Context(), StatsConfig{ContainerName: "my-postgres", Limit: 10})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pg_stat_statements is not in shared_preload_libraries of my-postgres")
+	assert.Contains(t, err.Error(), "pgbox ext add pg_stat_statements -n my-postgres")
+	assert.Len(t, mock.Calls.ExecCommand, 1)
+}
+
+func TestStatsOrchestrator_InvalidSort(t *testing.T) {
+	err := NewStatsOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}).Run(t.Context(), StatsConfig{Limit: 10, Sort: "io"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid sort "io" (must be total, mean, calls, rows)`)
+}
@@ -1,16 +1,87 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/container"
 	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
 )
 
 // StatusConfig holds configuration for the status command.
 type StatusConfig struct {
 	ContainerName string
+	Format        string // Print only the connection info in this format (see ConnectionFormats)
+	Full          bool   // Also report resource use, uptime, connections and database sizes
+}
+
+// ConnectionFormats lists the formats ConnectionInfo.Format supports.
+var ConnectionFormats = []string{"uri", "dsn", "jdbc", "env"}
+
+// ConnectionInfo holds what a client needs to connect to a container.
+type ConnectionInfo struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Database string
+	SSLMode  string // libpq sslmode, such as require; empty for the client's default
+}
+
+// Format renders the connection info as a libpq URI ("uri"), a libpq
+// key/value string ("dsn"), a JDBC URL ("jdbc") or a .env line ("env").
+func (c ConnectionInfo) Format(format string) (string, error) {
+	switch format {
+	case "uri":
+		u := url.URL{Scheme: "postgres", Host: c.Host + ":" + c.Port, Path: "/" + c.Database}
+		if c.Password != "" {
+			u.User = url.UserPassword(c.User, c.Password)
+		} else {
+			u.User = url.User(c.User)
+		}
+		if c.SSLMode != "" {
+			u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+		}
+		return u.String(), nil
+	case "dsn":
+		pairs := []string{"host=" + dsnValue(c.Host), "port=" + dsnValue(c.Port), "user=" + dsnValue(c.User)}
+		if c.Password != "" {
+			pairs = append(pairs, "password="+dsnValue(c.Password))
+		}
+		pairs = append(pairs, "dbname="+dsnValue(c.Database))
+		if c.SSLMode != "" {
+			pairs = append(pairs, "sslmode="+dsnValue(c.SSLMode))
+		}
+		return strings.Join(pairs, " "), nil
+	case "jdbc":
+		query := url.Values{"user": {c.User}}
+		if c.Password != "" {
+			query.Set("password", c.Password)
+		}
+		if c.SSLMode != "" {
+			query.Set("sslmode", c.SSLMode)
+		}
+		return fmt.Sprintf("jdbc:postgresql://%s:%s/%s?%s", c.Host, c.Port, url.PathEscape(c.Database), query.Encode()), nil
+	case "env":
+		uri, _ := c.Format("uri")
+		return "DATABASE_URL=" + uri, nil
+	}
+	return "", fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(ConnectionFormats, ", "))
+}
+
+// dsnValue quotes a libpq key/value connection string value when needed.
+func dsnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " '\\") {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
 }
 
 // StatusOrchestrator handles showing PostgreSQL container status.
@@ -25,9 +96,12 @@ func NewStatusOrchestrator(d docker.Docker, w io.Writer) *StatusOrchestrator {
 }
 
 // Run shows the status of PostgreSQL containers.
-func (o *StatusOrchestrator) Run(cfg StatusConfig) error {
+func (o *StatusOrchestrator) Run(ctx context.Context, cfg StatusConfig) error {
+	if cfg.Format != "" {
+		return o.printConnection(ctx, cfg)
+	}
 	if cfg.ContainerName == "" {
-		containers, err := o.docker.ListContainers("pgbox")
+		containers, err := o.docker.ListContainers(ctx, "pgbox")
 		if err != nil {
 			return fmt.Errorf("failed to list containers: %w", err)
 		}
@@ -39,15 +113,21 @@ func (o *StatusOrchestrator) Run(cfg StatusConfig) error {
 		}
 
 		_, _ = fmt.Fprintln(o.output, "PostgreSQL containers:")
-		output, err := o.docker.RunCommandWithOutput("ps", "--filter", "name=pgbox", "--format", "table {{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}")
+		output, err := o.docker.RunCommandWithOutput(ctx, "ps", "--filter", "name=pgbox", "--format", "table {{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}")
 		if err != nil {
 			return fmt.Errorf("failed to get container status: %w", err)
 		}
 		_, _ = fmt.Fprintln(o.output, output)
+		if cfg.Full {
+			for _, name := range containers {
+				_, _ = fmt.Fprintf(o.output, "\n== %s ==\n", name)
+				o.printResources(ctx, name)
+			}
+		}
 		return nil
 	}
 
-	running, err := o.docker.IsContainerRunning(cfg.ContainerName)
+	running, err := o.docker.IsContainerRunning(ctx, cfg.ContainerName)
 	if err != nil {
 		return fmt.Errorf("failed to check container status: %w", err)
 	}
@@ -56,7 +136,7 @@ func (o *StatusOrchestrator) Run(cfg StatusConfig) error {
 		return nil
 	}
 
-	output, err := o.docker.RunCommandWithOutput("ps", "--filter", fmt.Sprintf("name=%s", cfg.ContainerName), "--format", "table {{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}")
+	output, err := o.docker.RunCommandWithOutput(ctx, "ps", "--filter", fmt.Sprintf("name=%s", cfg.ContainerName), "--format", "table {{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}")
 	if err != nil {
 		return fmt.Errorf("failed to get container details: %w", err)
 	}
@@ -64,34 +144,101 @@ func (o *StatusOrchestrator) Run(cfg StatusConfig) error {
 	_, _ = fmt.Fprintln(o.output, "Container status:")
 	_, _ = fmt.Fprintln(o.output, output)
 
-	dbName, _ := o.docker.GetContainerEnv(cfg.ContainerName, "POSTGRES_DB")
-	userName, _ := o.docker.GetContainerEnv(cfg.ContainerName, "POSTGRES_USER")
+	conn := o.connection(ctx, cfg.ContainerName)
+	_, _ = fmt.Fprintln(o.output, "\nDatabase configuration:")
+	_, _ = fmt.Fprintf(o.output, "  Database: %s\n", conn.Database)
+	_, _ = fmt.Fprintf(o.output, "  User: %s\n", conn.User)
 
-	if dbName != "" || userName != "" {
-		_, _ = fmt.Fprintln(o.output, "\nDatabase configuration:")
-		if dbName != "" {
-			_, _ = fmt.Fprintf(o.output, "  Database: %s\n", dbName)
-		}
-		if userName != "" {
-			_, _ = fmt.Fprintf(o.output, "  User: %s\n", userName)
+	if conn.Port != "" {
+		_, _ = fmt.Fprintln(o.output, "\nConnection:")
+		for _, format := range ConnectionFormats {
+			value, _ := conn.Format(format)
+			_, _ = fmt.Fprintf(o.output, "  %-5s %s\n", format+":", value)
 		}
+	}
+
+	o.printExtensions(ctx, cfg.ContainerName)
+	if cfg.Full {
+		o.printResources(ctx, cfg.ContainerName)
+	}
+	if state, err := config.LoadContainerState(cfg.ContainerName); err == nil && state != nil && len(state.Timings) > 0 {
+		_, _ = fmt.Fprintln(o.output)
+		printTimings(o.output, cfg.ContainerName, state)
+	}
+	return nil
+}
 
-		lines := strings.Split(output, "\n")
-		if len(lines) > 1 {
-			fields := strings.Fields(lines[1])
-			if len(fields) >= 4 {
-				ports := fields[3]
-				if strings.Contains(ports, "->") {
-					portMapping := strings.Split(ports, "->")[0]
-					port := strings.TrimPrefix(portMapping, "0.0.0.0:")
-					port = strings.TrimPrefix(port, ":")
-
-					_, _ = fmt.Fprintln(o.output, "\nConnection string:")
-					_, _ = fmt.Fprintf(o.output, "  postgres://%s@localhost:%s/%s\n", userName, port, dbName)
-				}
+// printConnection prints only the container's connection info in cfg.Format,
+// for use in scripts.
+func (o *StatusOrchestrator) printConnection(ctx context.Context, cfg StatusConfig) error {
+	if _, err := (ConnectionInfo{}).Format(cfg.Format); err != nil {
+		return err
+	}
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+
+	conn := o.connection(ctx, name)
+	if conn.Port == "" {
+		return fmt.Errorf("%s does not publish port 5432", name)
+	}
+	value, _ := conn.Format(cfg.Format)
+	_, _ = fmt.Fprintln(o.output, value)
+	return nil
+}
+
+// connection reads a container's connection info from its environment,
+// command and published port. Port is empty when 5432 is not published.
+func (o *StatusOrchestrator) connection(ctx context.Context, name string) ConnectionInfo {
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+	password := containerPassword(ctx, o.docker, name)
+	conn := ConnectionInfo{Host: "localhost", User: user, Password: password, Database: database}
+	if containerSSL(ctx, o.docker, name) {
+		conn.SSLMode = "require"
+	}
+	if output, err := o.docker.RunCommandWithOutput(ctx, "port", name, "5432/tcp"); err == nil {
+		conn.Port = parseHostPort(output)
+	}
+	return conn
+}
+
+// printExtensions lists the extensions the container was created with, from
+// its labels, with the versions installed in its database.
+func (o *StatusOrchestrator) printExtensions(ctx context.Context, name string) {
+	createdWith, ok := containerLabels(ctx, o.docker, name)[container.LabelExtensions]
+	if !ok {
+		return
+	}
+	exts := container.ParseExtensionsLabel(createdWith)
+	if len(exts) == 0 {
+		_, _ = fmt.Fprintln(o.output, "\nExtensions: none")
+		return
+	}
+
+	versions := make(map[string]string)
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+	if rows, err := QueryLines(ctx, o.docker, name, user, database, "SELECT extname, extversion FROM pg_extension"); err == nil {
+		for _, row := range rows {
+			if extName, version, ok := strings.Cut(row, "\t"); ok {
+				versions[extName] = version
 			}
 		}
 	}
 
-	return nil
+	_, _ = fmt.Fprintln(o.output, "\nExtensions (as created):")
+	for _, ext := range exts {
+		if version, ok := versions[extensions.GetSQLName(ext)]; ok {
+			_, _ = fmt.Fprintf(o.output, "  %s %s\n", ext, version)
+		} else {
+			_, _ = fmt.Fprintf(o.output, "  %s (not created in %s)\n", ext, database)
+		}
+	}
 }
@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverStatusQuery reads the server's start time and uptime in seconds, its
+// client connections other than this one, and max_connections.
+const serverStatusQuery = `SELECT date_trunc('second', pg_postmaster_start_time()),
+  extract(epoch FROM now() - pg_postmaster_start_time())::bigint,
+  (SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend' AND pid <> pg_backend_pid()),
+  current_setting('max_connections')`
+
+// printResources prints what status --full adds for a running container: its
+// CPU and memory use, the size of its data volume, the server's uptime and
+// connections, and the size of each database. A part that cannot be read,
+// such as the server of a container still starting, is left out.
+func (o *StatusOrchestrator) printResources(ctx context.Context, name string) {
+	_, _ = fmt.Fprintln(o.output, "\nResources:")
+	field := func(label, value string) {
+		_, _ = fmt.Fprintf(o.output, "  %-12s %s\n", label+":", value)
+	}
+
+	if output, err := o.docker.RunCommandWithOutput(ctx, "stats", "--no-stream", "--format", "{{.CPUPerc}}\t{{.MemUsage}}\t{{.MemPerc}}", name); err == nil {
+		if fields := strings.Split(strings.TrimSpace(output), "\t"); len(fields) == 3 {
+			field("CPU", fields[0])
+			field("Memory", fmt.Sprintf("%s (%s)", fields[1], fields[2]))
+		}
+	}
+	if volume := o.dataVolume(ctx, name); volume != "" {
+		size := NewVolumeOrchestrator(o.docker, o.output, nil).volumeSizes(ctx, []string{volume})[volume]
+		field("Data volume", fmt.Sprintf("%s (%s)", volume, volumeSize(size)))
+	}
+
+	user, database := ResolveCredentials(ctx, o.docker, name, "", "")
+	if rows, err := QueryLines(ctx, o.docker, name, user, database, serverStatusQuery); err == nil && len(rows) > 0 {
+		if fields := strings.Split(rows[0], "\t"); len(fields) == 4 {
+			seconds, _ := strconv.ParseInt(fields[1], 10, 64)
+			field("Uptime", fmt.Sprintf("%s (since %s)", time.Duration(seconds)*time.Second, fields[0]))
+			field("Connections", fmt.Sprintf("%s of %s", fields[2], fields[3]))
+		}
+	}
+	rows, err := QueryLines(ctx, o.docker, name, user, database, databaseSizeQuery)
+	if err != nil || len(rows) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(o.output, "\nDatabase sizes:")
+	for _, row := range rows {
+		if datname, size, ok := strings.Cut(row, "\t"); ok {
+			_, _ = fmt.Fprintf(o.output, "  %-30s %10s\n", datname, formatBytes(parseSize(size)))
+		}
+	}
+}
+
+// dataVolume returns the named volume a container keeps its PostgreSQL data
+// in, or "" when it has none.
+func (o *StatusOrchestrator) dataVolume(ctx context.Context, name string) string {
+	output, err := o.docker.RunCommandWithOutput(ctx, "inspect", "-f",
+		`{{range .Mounts}}{{if eq .Type "volume"}}{{.Name}}{{"\t"}}{{.Destination}}{{"\n"}}{{end}}{{end}}`, name)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if volume, target, ok := strings.Cut(line, "\t"); ok && strings.HasPrefix(target, "/var/lib/postgresql") {
+			return volume
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+)
+
+// TestDBConfig holds configuration for provisioning test databases.
+type TestDBConfig struct {
+	ContainerName string
+	Template      string // Database to clone (default: the container's POSTGRES_DB)
+	Prefix        string // Name prefix; databases are named <prefix>_1 ... <prefix>_N
+	Count         int
+}
+
+// TestDB describes a provisioned test database.
+type TestDB struct {
+	Name string
+	DSN  string
+}
+
+// TestDBOrchestrator creates and drops per-worker test databases inside a
+// single running container.
+type TestDBOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewTestDBOrchestrator creates a new TestDBOrchestrator.
+func NewTestDBOrchestrator(d docker.Docker, w io.Writer) *TestDBOrchestrator {
+	return &TestDBOrchestrator{docker: d, output: w}
+}
+
+// testDBPrefixPattern keeps generated names usable unquoted in SQL and DSNs.
+var testDBPrefixPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// testDBPrefixInvalid matches what the default prefix replaces with "_".
+var testDBPrefixInvalid = regexp.MustCompile(`[^a-z0-9_]`)
+
+// Create clones the template database cfg.Count times, replacing any
+// existing databases with the same names, and prints one DSN per line.
+func (o *TestDBOrchestrator) Create(ctx context.Context, cfg TestDBConfig) ([]TestDB, error) {
+	if cfg.Count < 1 {
+		return nil, fmt.Errorf("--count must be at least 1")
+	}
+
+	name, user, template, prefix, err := o.resolve(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	maintenanceDB := maintenanceDatabase(template)
+
+	password := containerPassword(ctx, o.docker, name)
+	port := "5432"
+	if output, err := o.docker.RunCommandWithOutput(ctx, "port", name, "5432/tcp"); err == nil {
+		if p := parseHostPort(output); p != "" {
+			port = p
+		}
+	}
+	credentials := url.User(user)
+	if password != "" {
+		credentials = url.UserPassword(user, password)
+	}
+
+	dbs := make([]TestDB, 0, cfg.Count)
+	for i := 1; i <= cfg.Count; i++ {
+		dbName := fmt.Sprintf("%s_%d", prefix, i)
+		if _, err := QueryLines(ctx, o.docker, name, user, maintenanceDB,
+			fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", dbName)); err != nil {
+			return nil, fmt.Errorf("failed to drop existing %s: %w", dbName, err)
+		}
+		if _, err := QueryLines(ctx, o.docker, name, user, maintenanceDB,
+			fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", dbName, quoteIdent(template))); err != nil {
+			return nil, fmt.Errorf("failed to create %s from template %s (other sessions must not be connected to the template): %w",
+				dbName, template, err)
+		}
+		dsn := url.URL{Scheme: "postgres", User: credentials, Host: "localhost:" + port, Path: "/" + dbName}
+		dbs = append(dbs, TestDB{Name: dbName, DSN: dsn.String()})
+	}
+
+	for _, db := range dbs {
+		_, _ = fmt.Fprintln(o.output, db.DSN)
+	}
+	return dbs, nil
+}
+
+// Drop removes all databases created by Create with the configured prefix.
+func (o *TestDBOrchestrator) Drop(ctx context.Context, cfg TestDBConfig) error {
+	name, user, template, prefix, err := o.resolve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	maintenanceDB := maintenanceDatabase(template)
+
+	dbNames, err := QueryLines(ctx, o.docker, name, user, maintenanceDB,
+		fmt.Sprintf("SELECT datname FROM pg_database WHERE datname ~ '^%s_[0-9]+$' ORDER BY datname", prefix))
+	if err != nil {
+		return fmt.Errorf("failed to list test databases: %w", err)
+	}
+	if len(dbNames) == 0 {
+		_, _ = fmt.Fprintf(o.output, "No test databases matching %s_N found\n", prefix)
+		return nil
+	}
+
+	for _, dbName := range dbNames {
+		dbName = strings.TrimSpace(dbName)
+		if _, err := QueryLines(ctx, o.docker, name, user, maintenanceDB,
+			fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", dbName)); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", dbName, err)
+		}
+		_, _ = fmt.Fprintf(o.output, "Dropped %s\n", dbName)
+	}
+	return nil
+}
+
+// resolve finds the running container and fills in the template and prefix defaults.
+func (o *TestDBOrchestrator) resolve(ctx context.Context, cfg TestDBConfig) (name, user, template, prefix string, err error) {
+	name, _, err = ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return "", "", "", "", fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return "", "", "", "", fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return "", "", "", "", fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+
+	user, template = ResolveCredentials(ctx, o.docker, name, "", cfg.Template)
+
+	prefix = cfg.Prefix
+	if prefix == "" {
+		prefix = defaultTestDBPrefix(template)
+	}
+	if !testDBPrefixPattern.MatchString(prefix) {
+		return "", "", "", "", fmt.Errorf("invalid prefix %q (use lowercase letters, digits and underscores)", prefix)
+	}
+	return name, user, template, prefix, nil
+}
+
+// defaultTestDBPrefix derives a prefix that passes testDBPrefixPattern from
+// the template's name, e.g. "my_app_test" for "My-App".
+func defaultTestDBPrefix(template string) string {
+	prefix := testDBPrefixInvalid.ReplaceAllString(strings.ToLower(template), "_") + "_test"
+	if prefix[0] >= '0' && prefix[0] <= '9' {
+		prefix = "_" + prefix
+	}
+	return prefix
+}
+
+// maintenanceDatabase returns a database to run CREATE/DROP DATABASE from that
+// is not the template itself, since cloning fails while the template has connections.
+func maintenanceDatabase(template string) string {
+	if template == "postgres" {
+		return "template1"
+	}
+	return "postgres"
+}
+
+// quoteIdent quotes a SQL identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
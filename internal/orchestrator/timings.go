@@ -0,0 +1,153 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/model"
+)
+
+// The steps of creating a container that up times.
+const (
+	stepBuild  = "build"  // docker build of the custom image
+	stepPull   = "pull"   // docker pull of the images not present locally
+	stepCreate = "create" // docker run
+	stepInit   = "init"   // initdb and the init scripts, on a new volume
+	stepStart  = "start"  // until PostgreSQL accepted connections
+)
+
+// stepHints say how to make a step faster, shown when it was the slowest.
+var stepHints = map[string]string{
+	stepBuild: "later ups with the same extensions reuse the image, and overlapping extension sets share its cached layers",
+	stepPull:  "a pulled image stays cached locally, so later containers skip the download",
+	stepInit:  "init only runs on an empty volume; restarting the container skips it",
+}
+
+// timeStep records how long a step of the current up took since start.
+func (o *UpOrchestrator) timeStep(step string, start time.Time, detail string) {
+	o.timings = append(o.timings, config.StepTiming{Step: step, Seconds: roundSeconds(time.Since(start)), Detail: detail})
+}
+
+// saveTimings adds the init and start steps of a ready container to the
+// timings of the current up and keeps them in its state file, replacing
+// those of an earlier container of the same name.
+func (o *UpOrchestrator) saveTimings(containerName string, report StartupReport) error {
+	if report.InitTime > 0 {
+		o.timings = append(o.timings, config.StepTiming{Step: stepInit, Seconds: roundSeconds(report.InitTime), Detail: "initdb and init scripts"})
+	}
+	// The wait for connections starts with the container, so it includes init
+	start := max(report.ReadyAfter-report.InitTime, 0)
+	o.timings = append(o.timings, config.StepTiming{Step: stepStart, Seconds: roundSeconds(start), Detail: "until PostgreSQL accepted connections"})
+
+	state, err := config.LoadContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &config.ContainerState{}
+	}
+	state.Timings = o.timings
+	state.TimedAt = time.Now()
+	_, err = config.SaveContainerState(containerName, *state)
+	return err
+}
+
+// buildDetail summarizes what a custom image build installs, e.g.
+// "2 packages, 1 download".
+func buildDetail(m *model.DockerfileModel) string {
+	var parts []string
+	if n := len(m.AptPackages) + len(m.ApkPackages); n > 0 {
+		parts = append(parts, countOf(n, "package"))
+	}
+	if n := len(m.DebURLs) + len(m.ZipURLs); n > 0 {
+		parts = append(parts, countOf(n, "download"))
+	}
+	if n := len(m.Blocks); n > 0 {
+		parts = append(parts, countOf(n, "custom block"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countOf formats n with noun, pluralized with "s" unless n is 1.
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// roundSeconds converts d to seconds, rounded to milliseconds.
+func roundSeconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// printTimings prints the recorded timings of a container with the slowest
+// step marked, and a hint for speeding that step up when there is one.
+func printTimings(w io.Writer, name string, state *config.ContainerState) {
+	slowest := 0
+	var total time.Duration
+	for i, timing := range state.Timings {
+		if timing.Seconds > state.Timings[slowest].Seconds {
+			slowest = i
+		}
+		total += timing.Duration()
+	}
+
+	_, _ = fmt.Fprintf(w, "Startup timings of %s (pgbox up at %s):\n", name, state.TimedAt.Local().Format("2006-01-02 15:04"))
+	for i, timing := range state.Timings {
+		detail := timing.Detail
+		if i == slowest && len(state.Timings) > 1 {
+			detail = strings.TrimSpace(detail + " <- slowest")
+		}
+		line := fmt.Sprintf("  %-8s %8s  %s", timing.Step, formatDuration(timing.Duration()), detail)
+		_, _ = fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+	_, _ = fmt.Fprintf(w, "  %-8s %8s\n", "total", formatDuration(total))
+	if hint, ok := stepHints[state.Timings[slowest].Step]; ok && len(state.Timings) > 1 {
+		_, _ = fmt.Fprintf(w, "Tip: %s.\n", hint)
+	}
+}
+
+// formatDuration rounds d to a tenth of a second for display.
+func formatDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// TimingsConfig holds configuration for the timings command.
+type TimingsConfig struct {
+	ContainerName string
+}
+
+// TimingsOrchestrator shows how long creating a container took.
+type TimingsOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewTimingsOrchestrator creates a new TimingsOrchestrator.
+func NewTimingsOrchestrator(d docker.Docker, w io.Writer) *TimingsOrchestrator {
+	return &TimingsOrchestrator{docker: d, output: w}
+}
+
+// Run prints the step timings up recorded when it created the container.
+func (o *TimingsOrchestrator) Run(ctx context.Context, cfg TimingsConfig) error {
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return err
+	}
+	state, err := config.LoadContainerState(name)
+	if err != nil {
+		return err
+	}
+	if state == nil || len(state.Timings) == 0 {
+		_, _ = fmt.Fprintf(o.output, "No timings recorded for %s. They are recorded when pgbox up creates a container in the background.\n", name)
+		return nil
+	}
+	printTimings(o.output, name, state)
+	return nil
+}
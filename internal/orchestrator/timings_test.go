@@ -0,0 +1,134 @@
+package orchestrator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const timestampedInitLogs = `2026-10-16T09:30:00.000000000Z The files belonging to this database system will be owned by user "postgres".
+2026-10-16T09:30:01.500000000Z 2026-10-16 09:30:01.500 UTC [48] ERROR:  relation "users" does not exist
+2026-10-16T09:30:02.250000000Z PostgreSQL init process complete; ready for start up.
+2026-10-16T09:30:02.400000000Z 2026-10-16 09:30:02.400 UTC [1] LOG:  database system is ready to accept connections
+`
+
+func TestSplitLogTimestamps(t *testing.T) {
+	logs, initTime := splitLogTimestamps(timestampedInitLogs)
+
+	assert.Equal(t, 2250*time.Millisecond, initTime)
+	assert.NotContains(t, logs, "2026-10-16T09:30")
+	assert.Contains(t, logs, "\nPostgreSQL init process complete; ready for start up.\n")
+	assert.Equal(t, []string{`2026-10-16 09:30:01.500 UTC [48] ERROR:  relation "users" does not exist`}, findLogErrors(logs))
+}
+
+func TestSplitLogTimestamps_ExistingVolume(t *testing.T) {
+	logs, initTime := splitLogTimestamps("2026-10-16T09:30:00Z PostgreSQL Database directory appears to contain a database; Skipping initialization\n")
+
+	assert.Zero(t, initTime)
+	assert.Equal(t, "PostgreSQL Database directory appears to contain a database; Skipping initialization\n", logs)
+
+	logs, initTime = splitLogTimestamps("LOG:  database system is ready to accept connections\n")
+	assert.Zero(t, initTime)
+	assert.Equal(t, "LOG:  database system is ready to accept connections\n", logs)
+}
+
+func TestUpOrchestrator_RecordsTimings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "logs" {
+			return timestampedInitLogs, nil
+		}
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	err := newTestUpOrchestrator(mock, &buf).Run(t.Context(), UpConfig{Version: "17", Detach: true})
+	require.NoError(t, err)
+
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"logs", "-t", "pgbox-pg17"})
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"images", "-q", "postgres:17"})
+	state, err := config.LoadContainerState("pgbox-pg17")
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	steps := make([]string, 0, len(state.Timings))
+	for _, timing := range state.Timings {
+		steps = append(steps, timing.Step)
+	}
+	assert.Equal(t, []string{"pull", "create", "init", "start"}, steps)
+	assert.Equal(t, "postgres:17", state.Timings[0].Detail)
+	assert.Equal(t, 2.25, state.Timings[2].Seconds)
+	assert.False(t, state.TimedAt.IsZero())
+}
+
+func TestUpOrchestrator_RecordsBuildTiming(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	mock := docker.NewMockDocker()
+
+	var buf bytes.Buffer
+	err := newTestUpOrchestrator(mock, &buf).Run(t.Context(), UpConfig{Version: "17", Detach: true, Extensions: []string{"pgvector"}, GenPassword: true})
+	require.NoError(t, err)
+
+	state, err := config.LoadContainerState(mock.Calls.RunPostgres[0].Opts.Name)
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.NotEmpty(t, state.Password, "recording timings keeps the generated password")
+	require.Len(t, state.Timings, 3)
+	assert.Equal(t, "build", state.Timings[0].Step)
+	assert.Equal(t, "1 package", state.Timings[0].Detail)
+	assert.Equal(t, "create", state.Timings[1].Step)
+	assert.Empty(t, state.Timings[1].Detail, "the custom image was just built, so nothing was pulled")
+	assert.Equal(t, "start", state.Timings[2].Step)
+}
+
+func TestPrintTimings_MarksSlowestStep(t *testing.T) {
+	var buf bytes.Buffer
+	printTimings(&buf, "pgbox-pg17-abc123", &config.ContainerState{
+		Timings: []config.StepTiming{
+			{Step: "build", Seconds: 62.34, Detail: "2 packages, 1 download"},
+			{Step: "create", Seconds: 1.2},
+			{Step: "start", Seconds: 0.8, Detail: "until PostgreSQL accepted connections"},
+		},
+		TimedAt: time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local),
+	})
+
+	out := buf.String()
+	assert.Contains(t, out, "Startup timings of pgbox-pg17-abc123 (pgbox up at 2026-10-16 09:30):")
+	assert.Contains(t, out, "  build      1m2.3s  2 packages, 1 download <- slowest\n")
+	assert.Contains(t, out, "  create       1.2s\n")
+	assert.Contains(t, out, "  total      1m4.3s\n")
+	assert.Contains(t, out, "Tip: later ups with the same extensions reuse the image")
+}
+
+func TestTimingsOrchestrator_NoTimings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var buf bytes.Buffer
+
+	err := NewTimingsOrchestrator(docker.NewMockDocker(), &buf).Run(t.Context(), TimingsConfig{ContainerName: "pgbox-pg17"})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "No timings recorded for pgbox-pg17")
+}
+
+func TestTimingsOrchestrator_PrintsRecordedTimings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_, err := config.SaveContainerState("pgbox-pg17", config.ContainerState{
+		Timings: []config.StepTiming{{Step: "start", Seconds: 0.5}},
+		TimedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+
+	err = NewTimingsOrchestrator(docker.NewMockDocker(), &buf).Run(t.Context(), TimingsConfig{ContainerName: "pgbox-pg17"})
+
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "  start       500ms\n")
+	assert.NotContains(t, out, "slowest", "a single step is not marked")
+	assert.NotContains(t, out, "Tip:")
+}
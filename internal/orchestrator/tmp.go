@@ -0,0 +1,204 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// TmpConfig holds configuration for the tmp command.
+type TmpConfig struct {
+	Version     string
+	Database    string
+	User        string
+	Extensions  []string
+	Prefer      []string          // Extensions whose GUC values win conflicts
+	Profile     string            // Tuning profile (see profiles.Catalog)
+	Settings    map[string]string // User GUC overrides
+	FastUnsafe  bool              // Disable durability; the data is thrown away anyway
+	WaitTimeout time.Duration     // How long to wait for connections (default: 60s)
+	Command     []string          // Command to run against the instance, with its arguments
+}
+
+// ChildExitError reports that the command run by tmp exited with a non-zero
+// status, which pgbox passes on as its own.
+type ChildExitError struct {
+	Command string
+	Code    int
+}
+
+func (e *ChildExitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d", e.Command, e.Code)
+}
+
+// TmpOrchestrator runs a command against a throwaway PostgreSQL instance that
+// only lives as long as the command.
+type TmpOrchestrator struct {
+	docker     docker.Docker
+	output     io.Writer
+	newUp      func() *UpOrchestrator
+	freePort   func() (int, error)
+	runCommand func(argv, env []string) (int, error)
+}
+
+// NewTmpOrchestrator creates a new TmpOrchestrator.
+func NewTmpOrchestrator(d docker.Docker, w io.Writer) *TmpOrchestrator {
+	return &TmpOrchestrator{
+		docker:     d,
+		output:     w,
+		newUp:      func() *UpOrchestrator { return NewUpOrchestrator(d, w) },
+		freePort:   util.RandomFreePort,
+		runCommand: runChild,
+	}
+}
+
+// Run starts an instance with a random name, password and port, runs the
+// command with DATABASE_URL and the libpq PG* variables pointing at it, and
+// then removes the container and its volume, also when startup or the
+// command fails. A non-zero exit of the command is returned as a
+// ChildExitError.
+func (o *TmpOrchestrator) Run(ctx context.Context, cfg TmpConfig) error {
+	if len(cfg.Command) == 0 {
+		return fmt.Errorf("no command given")
+	}
+
+	suffix, err := util.GeneratePassword(8)
+	if err != nil {
+		return fmt.Errorf("failed to generate a container name: %w", err)
+	}
+	name := "pgbox-tmp-" + strings.ToLower(suffix)
+	password, err := util.GeneratePassword(24)
+	if err != nil {
+		return fmt.Errorf("failed to generate a password: %w", err)
+	}
+	port, err := o.freePort()
+	if err != nil {
+		return err
+	}
+
+	pgConfig := config.NewPostgresConfig()
+	pgConfig.Password = password
+	if cfg.User != "" {
+		pgConfig.User = cfg.User
+	}
+	if cfg.Database != "" {
+		pgConfig.Database = cfg.Database
+	}
+
+	defer o.teardown(ctx, name)
+	err = o.newUp().Run(ctx, UpConfig{
+		Version:       cfg.Version,
+		Port:          strconv.Itoa(port),
+		ContainerName: name,
+		Password:      pgConfig.Password,
+		Database:      pgConfig.Database,
+		User:          pgConfig.User,
+		Detach:        true,
+		Extensions:    cfg.Extensions,
+		Prefer:        cfg.Prefer,
+		Profile:       cfg.Profile,
+		Settings:      cfg.Settings,
+		FastUnsafe:    cfg.FastUnsafe,
+		WaitTimeout:   cfg.WaitTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	hostPort := strconv.Itoa(port)
+	env := append(os.Environ(),
+		"DATABASE_URL="+connectionString(pgConfig, hostPort),
+		"PGHOST=localhost",
+		"PGPORT="+hostPort,
+		"PGUSER="+pgConfig.User,
+		"PGPASSWORD="+pgConfig.Password,
+		"PGDATABASE="+pgConfig.Database,
+	)
+	logging.Infof(o.output, "\nRunning %s", strings.Join(cfg.Command, " "))
+	code, err := o.runCommand(cfg.Command, env)
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", cfg.Command[0], err)
+	}
+	if code != 0 {
+		return &ChildExitError{Command: cfg.Command[0], Code: code}
+	}
+	return nil
+}
+
+// teardown removes the temporary container, its data volume, its state file
+// and its generated files, also when ctx is done. Failures are reported but do
+// not change the result of the run.
+func (o *TmpOrchestrator) teardown(ctx context.Context, name string) {
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+	logging.Infof(o.output, "\nRemoving %s...", name)
+	if err := o.docker.RemoveContainer(ctx, name); err != nil {
+		logging.Warnf(o.output, "failed to remove container %s: %v", name, err)
+	}
+	if out, err := o.docker.RunCommandWithOutput(ctx, "volume", "rm", name+"-data"); err != nil && !strings.Contains(strings.ToLower(out), "no such volume") {
+		logging.Warnf(o.output, "failed to remove volume %s-data: %v", name, err)
+	}
+	if err := config.RemoveContainerState(name); err != nil {
+		logging.Warnf(o.output, "%v", err)
+	}
+	if err := config.RemoveArtifacts(name); err != nil {
+		logging.Warnf(o.output, "%v", err)
+	}
+}
+
+// runChild runs argv with the terminal's stdin, stdout and stderr and returns
+// its exit code. Ctrl+C reaches the child through the terminal, so pgbox only
+// waits for it to exit; SIGTERM sent to pgbox is passed on to the child.
+func runChild(argv, env []string) (int, error) {
+	child := exec.Command(argv[0], argv[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	if err := child.Start(); err != nil {
+		return 0, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig == syscall.SIGTERM {
+					_ = child.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := child.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Like a shell, report a child killed by a signal as 128+signal
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
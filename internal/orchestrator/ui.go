@@ -0,0 +1,217 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/container"
+	"github.com/ahacop/pgbox/internal/logging"
+	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/util"
+)
+
+// UITool is a database UI that pgbox can run next to PostgreSQL.
+type UITool struct {
+	Image    string
+	Port     string // Port the UI listens on inside its container
+	HostPort string // Default port published on the host
+	// Env returns the environment that points the UI at the database, which
+	// is reachable as host on port 5432.
+	Env func(host string, pg *config.PostgresConfig) map[string]string
+	// Login explains how to sign in, if the UI needs it.
+	Login func(host string, pg *config.PostgresConfig) string
+}
+
+// uiAdminEmail is the pgAdmin login; pgAdmin rejects addresses on reserved domains.
+const uiAdminEmail = "admin@pgbox.dev"
+
+// UITools lists the supported UIs by name.
+var UITools = map[string]UITool{
+	"pgadmin": {
+		Image: "dpage/pgadmin4", Port: "80", HostPort: "5050",
+		Env: func(host string, pg *config.PostgresConfig) map[string]string {
+			return map[string]string{
+				"PGADMIN_DEFAULT_EMAIL":                   uiAdminEmail,
+				"PGADMIN_DEFAULT_PASSWORD":                pg.Password,
+				"PGADMIN_CONFIG_SERVER_MODE":              "False",
+				"PGADMIN_CONFIG_MASTER_PASSWORD_REQUIRED": "False",
+			}
+		},
+		Login: func(host string, pg *config.PostgresConfig) string {
+			return fmt.Sprintf("log in as %s with the database password, then add server %s:5432 (user %s)", uiAdminEmail, host, pg.User)
+		},
+	},
+	"pgweb": {
+		Image: "sosedoff/pgweb", Port: "8081", HostPort: "8081",
+		Env: func(host string, pg *config.PostgresConfig) map[string]string {
+			u := url.URL{
+				Scheme:   "postgres",
+				User:     url.UserPassword(pg.User, pg.Password),
+				Host:     host + ":5432",
+				Path:     pg.Database,
+				RawQuery: "sslmode=disable",
+			}
+			return map[string]string{"PGWEB_DATABASE_URL": u.String()}
+		},
+	},
+	"adminer": {
+		Image: "adminer", Port: "8080", HostPort: "8080",
+		Env: func(host string, pg *config.PostgresConfig) map[string]string {
+			return map[string]string{"ADMINER_DEFAULT_SERVER": host}
+		},
+		Login: func(host string, pg *config.PostgresConfig) string {
+			return fmt.Sprintf("choose PostgreSQL and log in as %s to database %s", pg.User, pg.Database)
+		},
+	},
+}
+
+// UIToolNames returns the supported UI names, sorted.
+func UIToolNames() []string {
+	names := make([]string, 0, len(UITools))
+	for name := range UITools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateUITools checks that every requested UI is supported.
+func validateUITools(names []string) error {
+	for _, name := range names {
+		if _, ok := UITools[name]; !ok {
+			return fmt.Errorf("unknown UI %q (available: %s)", name, strings.Join(UIToolNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// addUISidecars adds compose services for the requested UIs, which start with
+// the database. With profiles, every other UI is added too, behind a compose
+// profile named after it.
+func addUISidecars(m *model.ComposeModel, pg *config.PostgresConfig, tools []string, profiles bool) {
+	for _, name := range UIToolNames() {
+		requested := slices.Contains(tools, name)
+		if !requested && !profiles {
+			continue
+		}
+		tool := UITools[name]
+		sidecar := model.Sidecar{
+			Name:      name,
+			Image:     tool.Image,
+			Env:       tool.Env(m.ServiceName, pg),
+			Ports:     []string{tool.HostPort + ":" + tool.Port},
+			DependsOn: m.ServiceName,
+		}
+		if !requested {
+			sidecar.Profiles = []string{name}
+		}
+		m.AddSidecar(sidecar)
+	}
+}
+
+// uiNetworkName is the network pgbox up shares between a container and its
+// UIs, replica and pgbouncer.
+func uiNetworkName(containerName string) string {
+	return containerName + "-ui"
+}
+
+// uiContainerName is the container pgbox up runs a UI in.
+func uiContainerName(containerName, tool string) string {
+	return containerName + "-" + tool
+}
+
+// startUI runs each requested UI in its own container on a network shared
+// with the database, so it reaches the database by container name. Host
+// ports start at the UI's default and move up when taken.
+func (o *UpOrchestrator) startUI(ctx context.Context, containerName string, pg *config.PostgresConfig, tools []string) error {
+	network, err := o.joinNetwork(ctx, containerName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tools {
+		tool := UITools[name]
+		uiName := uiContainerName(containerName, name)
+		// Replace a UI left over from an earlier pgbox up
+		_, _ = o.docker.RunCommandWithOutput(ctx, "rm", "-f", uiName)
+
+		start, _ := strconv.Atoi(tool.HostPort)
+		hostPort, err := util.FreePort(start, 100)
+		if err != nil {
+			return fmt.Errorf("no free port for %s: %w", name, err)
+		}
+		args := []string{"run", "-d", "--rm", "--name", uiName, "--network", network,
+			"-p", fmt.Sprintf("%d:%s", hostPort, tool.Port)}
+		env := tool.Env(containerName, pg)
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args = append(args, "-e", k+"="+env[k])
+		}
+		args = append(args, tool.Image)
+		if out, err := o.docker.RunCommandWithOutput(ctx, args...); err != nil {
+			return fmt.Errorf("failed to start %s: %s: %w", name, strings.TrimSpace(out), err)
+		}
+
+		_, _ = fmt.Fprintf(o.output, "%s: http://localhost:%d", name, hostPort)
+		if tool.Login != nil {
+			_, _ = fmt.Fprintf(o.output, " (%s)", tool.Login(containerName, pg))
+		}
+		_, _ = fmt.Fprintln(o.output)
+	}
+	return nil
+}
+
+// joinNetwork creates the network containerName shares with its UIs,
+// replica and pgbouncer, if needed, and attaches the container to it.
+// Returns its name.
+func (o *UpOrchestrator) joinNetwork(ctx context.Context, containerName string) (string, error) {
+	network := uiNetworkName(containerName)
+	if _, err := o.docker.RunCommandWithOutput(ctx, "network", "inspect", network); err != nil {
+		if out, err := o.docker.RunCommandWithOutput(ctx, "network", "create", network); err != nil {
+			return "", fmt.Errorf("failed to create network %s: %s: %w", network, strings.TrimSpace(out), err)
+		}
+	}
+	// A restarted container may already be attached
+	if out, err := o.docker.RunCommandWithOutput(ctx, "network", "connect", network, containerName); err != nil && !strings.Contains(out, "already exists") {
+		return "", fmt.Errorf("failed to attach %s to network %s: %s: %w", containerName, network, strings.TrimSpace(out), err)
+	}
+	return network, nil
+}
+
+// stopSidecars stops the UI, pgbouncer and replica containers pgbox up
+// started for containerName and removes their network, once the database has
+// left it. The UIs and pgbouncer run with --rm, so stopping removes them; the
+// replica is kept for the next pgbox up --with-replica.
+func (o *DownOrchestrator) stopSidecars(ctx context.Context, containerName string) {
+	running, _ := o.docker.ListContainers(ctx, containerName+"-")
+	sidecars := []string{container.ReplicaName(containerName), pgbouncerContainerName(containerName)}
+	for _, name := range UIToolNames() {
+		sidecars = append(sidecars, uiContainerName(containerName, name))
+	}
+	stopped := false
+	for _, sidecar := range sidecars {
+		for _, c := range running {
+			if c != sidecar {
+				continue
+			}
+			logging.Infof(o.output, "Stopping %s...", sidecar)
+			if err := o.docker.StopContainer(ctx, sidecar); err != nil {
+				logging.Warnf(o.output, "failed to stop %s: %v", sidecar, err)
+			}
+			stopped = true
+		}
+	}
+	if stopped {
+		_, _ = o.docker.RunCommandWithOutput(ctx, "network", "rm", uiNetworkName(containerName))
+	}
+}
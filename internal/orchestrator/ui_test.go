@@ -0,0 +1,90 @@
+package orchestrator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportOrchestrator_WithUI(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+
+	err := NewExportOrchestrator(&buf).Run(ExportConfig{
+		TargetDir:       dir,
+		Version:         "17",
+		Port:            "5432",
+		User:            "app",
+		Password:        "p@ss",
+		Database:        "shop",
+		UI:              []string{"pgweb"},
+		ComposeProfiles: true,
+	})
+
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	require.NoError(t, err)
+	compose := string(content)
+	assert.Contains(t, compose, "  pgweb:\n    image: sosedoff/pgweb\n    environment:\n")
+	assert.Contains(t, compose, `PGWEB_DATABASE_URL: "postgres://app:p%40ss@db:5432/shop?sslmode=disable"`)
+	assert.Contains(t, compose, "    depends_on:\n      db:\n        condition: service_healthy")
+	assert.Contains(t, compose, "  pgadmin:\n    image: dpage/pgadmin4\n    profiles:\n      - pgadmin\n")
+	assert.Contains(t, compose, `PGADMIN_CONFIG_SERVER_MODE: "False"`)
+	assert.Contains(t, compose, "  adminer:\n    image: adminer\n    profiles:\n      - adminer\n")
+	assert.Contains(t, buf.String(), "docker-compose --profile pgadmin up -d")
+	assert.Contains(t, buf.String(), "pgweb starts with it on http://localhost:8081")
+}
+
+func TestExportOrchestrator_UnknownUI(t *testing.T) {
+	err := NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{TargetDir: t.TempDir(), Version: "17", UI: []string{"phpmyadmin"}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown UI "phpmyadmin" (available: adminer, pgadmin, pgweb)`)
+}
+
+func TestUpOrchestrator_WithUI(t *testing.T) {
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := newTestUpOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), UpConfig{Version: "17", Port: "5432", Detach: true, UI: []string{"adminer"}})
+
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"network", "connect", "pgbox-pg17-ui", "pgbox-pg17"})
+	i := slices.IndexFunc(mock.Calls.RunCommandWithOutput, func(args []string) bool { return args[0] == "run" })
+	require.GreaterOrEqual(t, i, 0)
+	run := mock.Calls.RunCommandWithOutput[i]
+	assert.Equal(t, []string{"run", "-d", "--rm", "--name", "pgbox-pg17-adminer", "--network", "pgbox-pg17-ui"}, run[:7])
+	assert.Equal(t, []string{"-e", "ADMINER_DEFAULT_SERVER=pgbox-pg17", "adminer"}, run[len(run)-3:])
+	assert.Contains(t, buf.String(), "adminer: http://localhost:")
+}
+
+func TestUpOrchestrator_UIRequiresDetach(t *testing.T) {
+	mock := docker.NewMockDocker()
+
+	err := newTestUpOrchestrator(mock, &bytes.Buffer{}).Run(t.Context(), UpConfig{Version: "17", UI: []string{"pgweb"}})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--with-ui needs the database to run in the background")
+	assert.Empty(t, mock.Calls.RunPostgres)
+}
+
+func TestDownOrchestrator_StopsUI(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.ListContainersFunc = func(prefix string) ([]string, error) {
+		return []string{"pgbox-pg17-pgweb", "pgbox-pg17-other"}, nil
+	}
+	var buf bytes.Buffer
+
+	err := NewDownOrchestrator(mock, &buf, nil).Run(t.Context(), DownConfig{ContainerName: "pgbox-pg17"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17", "pgbox-pg17-pgweb"}, mock.Calls.StopContainer)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"network", "rm", "pgbox-pg17-ui"})
+}
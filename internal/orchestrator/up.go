@@ -2,17 +2,28 @@
 package orchestrator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ahacop/pgbox/internal/config"
 	"github.com/ahacop/pgbox/internal/container"
 	"github.com/ahacop/pgbox/internal/docker"
 	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/logging"
 	"github.com/ahacop/pgbox/internal/model"
+	"github.com/ahacop/pgbox/internal/profiles"
 	"github.com/ahacop/pgbox/internal/render"
 	"github.com/ahacop/pgbox/internal/util"
 )
@@ -27,13 +38,64 @@ type UpConfig struct {
 	User          string
 	Detach        bool
 	Extensions    []string
+	Prefer        []string          // Extensions whose GUC values win conflicts
+	Settings      map[string]string // User GUC overrides; win over extension defaults
+	GUCChoices    map[string]string // Values picked for conflicting GUCs (see GUCConflicts); kept in the container's state
+	Profile       string            // Tuning profile (see profiles.Catalog)
+	FastUnsafe    bool              // Disable durability for speed on throwaway databases
+	Hardened      bool              // Restricted container, SCRAM auth, non-default superuser, localhost-only port
+	WaitTimeout   time.Duration     // How long to wait for connections (default: 60s)
+	UI            []string          // Database UIs to run next to PostgreSQL (see UITools)
+	AutoPort      bool              // Move to the next free port when Port is taken
+	GenPassword   bool              // Generate a password and keep it in the container's state file
+	Strict        bool              // Fail on problems that are otherwise only warnings
+	RemoveOnExit  bool              // In the foreground, also remove the container when it stops
+	BuildProgress string            // docker build --progress for custom images (auto, plain, tty, quiet)
+	DryRun        bool              // Print the runtime commands and rendered files instead of running them
+	Replica       bool              // Also run a read-only streaming replica (see startReplica)
+	Pgbouncer     bool              // Also run pgbouncer in transaction pooling mode (see startPgbouncer)
+	Restart       string            // Restart policy of a new container (see RestartPolicies); empty for none
+	Env           map[string]string // Extra environment variables for a new container
+	Recreate      bool              // Replace an existing container, keeping its data volume (see recreateExisting)
+	Locale        string            // initdb --locale of a new data volume
+	Encoding      string            // initdb --encoding of a new data volume
+	Timezone      string            // TZ of a new container
+	InitdbArgs    []string          // Further initdb arguments for a new data volume
+	SSL           bool              // Serve TLS with a generated self-signed certificate (see configureSSL)
+	Pull          string            // When to pull images (see PullPolicies); empty for PullMissing
+}
+
+// RestartPolicies are the restart policies pgbox up --restart accepts.
+var RestartPolicies = []string{"no", "on-failure", "unless-stopped"}
+
+// FastUnsafeSettings are applied by --fast-unsafe. They trade crash safety
+// for write speed. A crash or unclean stop can corrupt the data directory when
+// these are in effect.
+var FastUnsafeSettings = map[string]string{
+	"fsync":              "off",
+	"synchronous_commit": "off",
+	"full_page_writes":   "off",
 }
 
 // UpOrchestrator handles the business logic for starting PostgreSQL containers.
 type UpOrchestrator struct {
-	docker       docker.Docker
-	output       io.Writer
-	containerMgr *container.Manager
+	docker        docker.Docker
+	output        io.Writer
+	containerMgr  *container.Manager
+	readyTimeout  time.Duration
+	pollInterval  time.Duration
+	portInUse     func(port int) bool
+	portProcess   func(port int) string
+	tryLock       func(name string) (*config.ContainerLock, error)
+	lockTimeout   time.Duration
+	signals       func() (<-chan os.Signal, func())
+	strict        bool                // Set from UpConfig.Strict for the current run
+	buildProgress string              // Set from UpConfig.BuildProgress for the current run
+	pull          string              // Set from UpConfig.Pull for the current run
+	timings       []config.StepTiming // Steps timed during the current run
+	dryRun        bool                // Set from UpConfig.DryRun for the current run
+	recreating    bool                // The current run replaces an existing container
+	enable        []string            // Extensions to create after a recreated container starts
 }
 
 // NewUpOrchestrator creates a new UpOrchestrator with the given dependencies.
@@ -42,11 +104,70 @@ func NewUpOrchestrator(d docker.Docker, w io.Writer) *UpOrchestrator {
 		docker:       d,
 		output:       w,
 		containerMgr: container.NewManager(),
+		readyTimeout: defaultReadyTimeout,
+		pollInterval: defaultPollInterval,
+		portInUse:    util.PortInUse,
+		portProcess:  util.PortProcess,
+		tryLock:      config.TryLockContainer,
+		lockTimeout:  defaultLockTimeout,
+		signals:      notifySignals,
 	}
 }
 
 // Run starts a PostgreSQL container with the given configuration.
-func (o *UpOrchestrator) Run(cfg UpConfig) error {
+func (o *UpOrchestrator) Run(ctx context.Context, cfg UpConfig) error {
+	if cfg.WaitTimeout < 0 {
+		return fmt.Errorf("invalid wait timeout %s", cfg.WaitTimeout)
+	}
+	if cfg.WaitTimeout > 0 {
+		o.readyTimeout = cfg.WaitTimeout
+	}
+	o.strict = cfg.Strict
+	o.buildProgress = cfg.BuildProgress
+	o.pull = cfg.Pull
+	if o.pull == "" {
+		o.pull = PullMissing
+	}
+	o.timings = nil
+	o.recreating, o.enable = false, nil
+	o.dryRun = cfg.DryRun
+	if cfg.DryRun {
+		o.docker = docker.NewDryRun(o.docker, o.output)
+		_, _ = fmt.Fprintln(o.output, "Dry run: nothing is built, created or started")
+	}
+	if err := validateUITools(cfg.UI); err != nil {
+		return err
+	}
+	if len(cfg.UI) > 0 && !cfg.Detach {
+		return fmt.Errorf("--with-ui needs the database to run in the background; drop --detach=false")
+	}
+	if cfg.Replica && !cfg.Detach {
+		return fmt.Errorf("--with-replica needs the database to run in the background; drop --detach=false")
+	}
+	if cfg.Pgbouncer && !cfg.Detach {
+		return fmt.Errorf("--with-pgbouncer needs the database to run in the background; drop --detach=false")
+	}
+	if cfg.Restart != "" && !slices.Contains(RestartPolicies, cfg.Restart) {
+		return fmt.Errorf("invalid restart policy %q (must be one of: %s)", cfg.Restart, strings.Join(RestartPolicies, ", "))
+	}
+	if !slices.Contains(PullPolicies, o.pull) {
+		return fmt.Errorf("invalid pull policy %q (must be one of: %s)", o.pull, strings.Join(PullPolicies, ", "))
+	}
+	if err := checkEnv(cfg.Env); err != nil {
+		return err
+	}
+	if cfg.Recreate && !cfg.Detach {
+		return fmt.Errorf("--recreate needs the database to run in the background; drop --detach=false")
+	}
+	if cfg.RemoveOnExit && cfg.Detach {
+		return fmt.Errorf("--rm only applies in the foreground; add --detach=false")
+	}
+	if cfg.Profile != "" {
+		if _, err := profiles.Get(cfg.Profile); err != nil {
+			return err
+		}
+	}
+
 	pgConfig := config.NewPostgresConfig()
 	pgConfig.Version = cfg.Version
 	if cfg.Port != "" {
@@ -61,18 +182,133 @@ func (o *UpOrchestrator) Run(cfg UpConfig) error {
 	if cfg.Password != "" {
 		pgConfig.Password = cfg.Password
 	}
+	pgConfig.Locale, pgConfig.Encoding, pgConfig.Timezone = cfg.Locale, cfg.Encoding, cfg.Timezone
+	pgConfig.InitdbArgs = slices.Clone(cfg.InitdbArgs)
+	pgConfig.SSL = cfg.SSL
+	if err := checkInitdbEnv(cfg.Env, pgConfig); err != nil {
+		return err
+	}
+
+	containerName := o.containerName(cfg)
 
-	containerName := cfg.ContainerName
-	if containerName == "" {
-		containerName = o.containerMgr.Name(pgConfig, cfg.Extensions)
+	// Hold the container's lock until it runs, so a concurrent up of the same
+	// container waits and then finds it instead of racing to create it
+	lock, err := o.lockContainer(ctx, containerName)
+	if err != nil {
+		return err
 	}
+	defer lock.Release()
 
-	if restarted, err := o.tryRestartExisting(containerName); err != nil {
+	if err := o.applyStoredPassword(containerName, pgConfig, cfg); err != nil {
+		return err
+	}
+	choices := o.gucChoices(containerName, cfg)
+	cfg.Prefer, cfg.Settings = applyGUCChoices(cfg.Extensions, choices, cfg.Prefer, cfg.Settings)
+	if len(cfg.GUCChoices) > 0 && !o.dryRun {
+		if err := o.saveGUCChoices(containerName, choices); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Recreate {
+		if err := o.recreateExisting(ctx, containerName, pgConfig, cfg); err != nil {
+			return err
+		}
+	}
+
+	if restarted, err := o.tryRestartExisting(ctx, containerName, cfg); err != nil {
 		return err
 	} else if restarted {
+		if cfg.DryRun {
+			return nil
+		}
+		if !cfg.Detach {
+			lock.Release()
+			sigs, stop := o.signals()
+			defer stop()
+			return o.runForeground(ctx, containerName, cfg.RemoveOnExit, sigs)
+		}
+		if cfg.FastUnsafe {
+			if err := o.warn("--fast-unsafe only applies to new containers; %s keeps its existing settings", containerName); err != nil {
+				return err
+			}
+		}
+		if cfg.Profile != "" {
+			if err := o.warn("--profile only applies to new containers; %s keeps its existing settings", containerName); err != nil {
+				return err
+			}
+		}
+		if cfg.Hardened {
+			if err := o.warn("--hardened only applies to new containers; %s keeps its existing settings", containerName); err != nil {
+				return err
+			}
+		}
+		if cfg.Restart != "" {
+			if err := o.warn("--restart only applies to new containers; %s keeps its existing restart policy", containerName); err != nil {
+				return err
+			}
+		}
+		if len(cfg.Env) > 0 {
+			if err := o.warn("--env only applies to new containers; %s keeps its existing environment", containerName); err != nil {
+				return err
+			}
+		}
+		if len(pgConfig.InitdbEnv()) > 0 {
+			if err := o.warn("--locale, --encoding, --timezone and --initdb-arg only apply to new containers; %s keeps its existing ones", containerName); err != nil {
+				return err
+			}
+		}
+		pgConfig.SSL = containerSSL(ctx, o.docker, containerName)
+		if cfg.SSL && !pgConfig.SSL {
+			if err := o.warn("--ssl only applies to new containers; recreate %s with --recreate --ssl to turn it on", containerName); err != nil {
+				return err
+			}
+		}
+		if !o.waitForReady(ctx, containerName, pgConfig) {
+			return o.notReadyError(ctx, containerName)
+		}
+		_, _ = fmt.Fprintln(o.output, "PostgreSQL is ready")
+		if err := o.pullImages(ctx, sidecarImages(cfg)); err != nil {
+			return err
+		}
+		if cfg.Replica {
+			if err := o.startReplica(ctx, containerName, pgConfig, docker.ContainerOptions{}); err != nil {
+				return err
+			}
+		}
+		if cfg.Pgbouncer {
+			if err := o.startPgbouncer(ctx, containerName, pgConfig, ""); err != nil {
+				return err
+			}
+		}
+		if len(cfg.UI) > 0 {
+			return o.startUI(ctx, containerName, pgConfig, cfg.UI)
+		}
 		return nil
 	}
 
+	// The container being recreated holds the port until it is replaced
+	replaced := ""
+	if o.recreating {
+		replaced = containerName
+	}
+	if err := o.checkPort(ctx, pgConfig, cfg.AutoPort, replaced); err != nil {
+		return err
+	}
+	// A recreated container keeps the credentials its data was created with
+	if cfg.Hardened && cfg.User == "" && !o.recreating {
+		pgConfig.User = HardenedUser
+	}
+	if cfg.Hardened {
+		hardenInitdbArgs(pgConfig)
+	}
+	// Hardened containers never get the default password
+	if !o.recreating && (cfg.GenPassword || (cfg.Hardened && cfg.Password == "")) {
+		if err := o.generatePassword(containerName, pgConfig); err != nil {
+			return err
+		}
+	}
+
 	baseImage := extensions.GetBaseImage(cfg.Extensions, cfg.Version)
 	if baseImage == "" {
 		baseImage = fmt.Sprintf("postgres:%s", cfg.Version)
@@ -82,36 +318,557 @@ func (o *UpOrchestrator) Run(cfg UpConfig) error {
 	initModel := model.NewInitModel()
 
 	if len(cfg.Extensions) > 0 {
-		if err := o.processExtensions(cfg.Version, cfg.Extensions, dockerfileModel, pgConfModel, initModel, pgConfig); err != nil {
+		if err := o.processExtensions(ctx, cfg.Version, cfg.Extensions, cfg.Prefer, cfg.Settings, dockerfileModel, pgConfModel, initModel, pgConfig); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Profile != "" {
+		profile, err := applyProfile(pgConfModel, cfg.Profile)
+		if err != nil {
+			return err
+		}
+		if profile.Unsafe {
+			o.printUnsafeWarning("--profile " + profile.Name)
+		}
+	}
+	if cfg.FastUnsafe {
+		for key, value := range FastUnsafeSettings {
+			pgConfModel.ApplyGUC(key, value, model.GUCSource{Kind: model.SourceProfile, Name: "fast-unsafe"})
+		}
+		o.printUnsafeWarning("--fast-unsafe")
+	}
+	if cfg.Hardened {
+		applyHardenedSettings(pgConfModel)
+	}
+	if cfg.SSL {
+		applySSLSettings(pgConfModel)
+	}
+	for _, warning := range applyUserSettings(pgConfModel, cfg.Settings) {
+		if err := o.warn("%s", warning); err != nil {
+			return err
+		}
+	}
+	if cfg.Replica {
+		if err := checkReplicaSettings(pgConfModel); err != nil {
+			return err
+		}
+	}
+
+	o.printStatus(pgConfig, containerName, cfg.Extensions, pgConfModel, cfg.Detach)
+	opts, err := o.buildContainerOptions(containerName, cfg.Version, cfg.Extensions, pgConfModel, initModel)
+	if err != nil {
+		return err
+	}
+	if cfg.Hardened {
+		hardenContainer(&opts)
+		opts.Labels[container.LabelHardened] = "true"
+	}
+	if len(cfg.Env) > 0 {
+		// Only the names, since the values can be credentials
+		opts.Labels[container.LabelEnv] = strings.Join(slices.Sorted(maps.Keys(cfg.Env)), ",")
+	}
+	if cfg.SSL {
+		if err := o.configureSSL(&opts, containerName); err != nil {
 			return err
 		}
 	}
+	opts.Restart = cfg.Restart
+	// After pgbox's own variables, so --env wins over the hardened defaults
+	opts.ExtraEnv = append(opts.ExtraEnv, sortedEnv(cfg.Env)...)
 
-	o.printStatus(pgConfig, containerName, cfg.Extensions, cfg.Detach)
-	opts := o.buildContainerOptions(containerName, cfg.Detach, cfg.Extensions, pgConfModel, initModel)
+	var sigs <-chan os.Signal
+	if !cfg.Detach {
+		// Catch Ctrl+C from the moment the container exists, so it is never
+		// left running behind pgbox's back
+		var stop func()
+		sigs, stop = o.signals()
+		defer stop()
+	}
+	// Custom images were just built or found locally, so only the stock image
+	// can need a pull
+	images := sidecarImages(cfg)
+	if pgConfig.CustomImage == "" {
+		images = append([]string{pgConfig.Image()}, images...)
+	}
+	if err := o.pullImages(ctx, images); err != nil {
+		return err
+	}
+	// Only now that the new container is ready to create is the old one
+	// removed, so a failure before this point leaves it as it was
+	if o.recreating {
+		if err := o.removeRecreated(ctx, containerName); err != nil {
+			return err
+		}
+	}
+	createStart := time.Now()
+	if err := o.docker.RunPostgres(ctx, pgConfig, opts); err != nil {
+		if o.recreating {
+			return fmt.Errorf("failed to recreate %s (its data is kept in volume %s-data): %w", containerName, containerName, err)
+		}
+		return err
+	}
+	o.timeStep(stepCreate, createStart, "")
+	if cfg.DryRun {
+		if cfg.Replica {
+			if err := o.startReplica(ctx, containerName, pgConfig, opts); err != nil {
+				return err
+			}
+		}
+		if cfg.Pgbouncer {
+			if err := o.startPgbouncer(ctx, containerName, pgConfig, opts.HostIP); err != nil {
+				return err
+			}
+		}
+		if len(cfg.UI) > 0 {
+			return o.startUI(ctx, containerName, pgConfig, cfg.UI)
+		}
+		return nil
+	}
+	if !cfg.Detach {
+		lock.Release()
+		return o.runForeground(ctx, containerName, cfg.RemoveOnExit, sigs)
+	}
+
+	if len(o.enable) > 0 {
+		if err := o.enableExtensions(ctx, containerName, pgConfig); err != nil {
+			return err
+		}
+	}
+
+	report := o.verifyStartup(ctx, containerName, pgConfig, cfg.Extensions)
+	o.printSummary(containerName, pgConfig, cfg.Extensions, report)
+	if report.Ready {
+		if err := o.saveTimings(containerName, report); err != nil {
+			if err := o.warn("failed to record startup timings for %s: %v", containerName, err); err != nil {
+				return err
+			}
+		}
+	}
+	if !report.Ready && len(report.InitErrors) == 0 {
+		return o.notReadyError(ctx, containerName)
+	}
+	if len(report.InitErrors) > 0 {
+		first := report.InitErrors[0]
+		return fmt.Errorf("initialization SQL failed at %s:%s: %s (remove container %s and volume %s-data before retrying, since init scripts only run on an empty volume)",
+			first.File, first.Line, first.Message, containerName, containerName)
+	}
+	if cfg.Strict && (len(report.MissingExtensions) > 0 || len(report.LogErrors) > 0) {
+		return fmt.Errorf("strict mode: %s started with the issues listed above", containerName)
+	}
+	if cfg.Replica {
+		if err := o.startReplica(ctx, containerName, pgConfig, opts); err != nil {
+			return err
+		}
+	}
+	if cfg.Pgbouncer {
+		if err := o.startPgbouncer(ctx, containerName, pgConfig, opts.HostIP); err != nil {
+			return err
+		}
+	}
+	if len(cfg.UI) > 0 {
+		return o.startUI(ctx, containerName, pgConfig, cfg.UI)
+	}
+	return nil
+}
+
+// runForeground streams the container's logs until it exits or pgbox receives
+// SIGINT or SIGTERM, then stops it, and removes it as well when remove is set.
+// The data volume is always kept.
+func (o *UpOrchestrator) runForeground(ctx context.Context, containerName string, remove bool, sigs <-chan os.Signal) error {
+	logsDone := make(chan error, 1)
+	go func() {
+		logsDone <- o.docker.RunCommandWithIO(ctx, nil, o.output, o.output, "logs", "-f", containerName)
+	}()
+
+	select {
+	case sig := <-sigs:
+		logging.Infof(o.output, "\nReceived %s, stopping %s...", sig, containerName)
+	case <-logsDone:
+	}
+
+	// The container is stopped even when an expired --timeout ended the logs
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+	var exitErr error
+	if running, _ := o.docker.IsContainerRunning(ctx, containerName); running {
+		// Ctrl+C also ends docker logs, so a running container here means
+		// the foreground session is over
+		if err := o.docker.StopContainer(ctx, containerName); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", containerName, err)
+		}
+		logging.Infof(o.output, "Stopped %s", containerName)
+	} else {
+		code, _ := o.docker.RunCommandWithOutput(ctx, "inspect", "-f", "{{.State.ExitCode}}", containerName)
+		if code = strings.TrimSpace(code); code != "" && code != "0" {
+			exitErr = fmt.Errorf("%s exited with code %s; see the log above", containerName, code)
+		}
+	}
+
+	if remove {
+		if err := o.docker.RemoveContainer(ctx, containerName); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", containerName, err)
+		}
+		_ = config.RemoveArtifacts(containerName)
+		logging.Infof(o.output, "Removed %s (volume %s-data is kept)", containerName, containerName)
+	}
+	return exitErr
+}
+
+// notifySignals delivers SIGINT and SIGTERM on the returned channel instead
+// of ending pgbox, until the returned function is called.
+func notifySignals() (<-chan os.Signal, func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	return sigs, func() { signal.Stop(sigs) }
+}
+
+// notReadyError reports that the server did not start accepting connections
+// in time, or that ctx ended the wait first.
+func (o *UpOrchestrator) notReadyError(ctx context.Context, containerName string) error {
+	if err := context.Cause(ctx); err != nil {
+		return fmt.Errorf("stopped waiting for PostgreSQL in %s to accept connections: %w", containerName, err)
+	}
+	return fmt.Errorf("PostgreSQL did not accept connections within %s. Check the logs with: pgbox logs -n %s (or raise --wait-timeout)",
+		o.readyTimeout, containerName)
+}
+
+// lockContainer takes the lock for a container, waiting up to lockTimeout,
+// or until ctx is done, for another pgbox up of the same container to finish.
+func (o *UpOrchestrator) lockContainer(ctx context.Context, containerName string) (*config.ContainerLock, error) {
+	deadline := time.Now().Add(o.lockTimeout)
+	waiting := false
+	for {
+		lock, err := o.tryLock(containerName)
+		if !errors.Is(err, config.ErrContainerLocked) {
+			return lock, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("another pgbox up of %s is still running after %s", containerName, o.lockTimeout)
+		}
+		if !waiting {
+			logging.Infof(o.output, "Waiting for another pgbox up of %s to finish...", containerName)
+			waiting = true
+		}
+		if err := sleepContext(ctx, o.pollInterval); err != nil {
+			return nil, fmt.Errorf("stopped waiting for another pgbox up of %s: %w", containerName, err)
+		}
+	}
+}
 
-	return o.docker.RunPostgres(pgConfig, opts)
+// containerName returns the name of the container cfg starts.
+func (o *UpOrchestrator) containerName(cfg UpConfig) string {
+	if cfg.ContainerName != "" {
+		return cfg.ContainerName
+	}
+	return o.containerMgr.Name(&config.PostgresConfig{Version: cfg.Version}, cfg.Extensions)
+}
+
+// GUCConflicts returns the conflicts between the GUC values of cfg's
+// extensions that neither cfg nor the choices stored for its container
+// decide, for the caller to settle with cfg.GUCChoices before Run. Returns
+// nil when there are none, or when an existing container is started as it is.
+func (o *UpOrchestrator) GUCConflicts(ctx context.Context, cfg UpConfig) *extensions.ConflictError {
+	containerName := o.containerName(cfg)
+	if !cfg.Recreate && o.containerExists(ctx, containerName) {
+		return nil
+	}
+	prefer, settings := applyGUCChoices(cfg.Extensions, o.gucChoices(containerName, cfg), cfg.Prefer, cfg.Settings)
+	return gucConflicts(cfg.Extensions, prefer, settings)
+}
+
+// gucChoices returns the values picked for the container's conflicting
+// GUCs: those stored by an earlier up, updated with cfg.GUCChoices.
+func (o *UpOrchestrator) gucChoices(containerName string, cfg UpConfig) map[string]string {
+	choices := make(map[string]string)
+	if state, err := config.LoadContainerState(containerName); err == nil && state != nil {
+		maps.Copy(choices, state.GUCChoices)
+	}
+	maps.Copy(choices, cfg.GUCChoices)
+	return choices
+}
+
+// saveGUCChoices keeps the values picked for the container's conflicting
+// GUCs in its state file, so the next up applies them without asking again.
+func (o *UpOrchestrator) saveGUCChoices(containerName string, choices map[string]string) error {
+	state, err := config.LoadContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &config.ContainerState{}
+	}
+	state.GUCChoices = choices
+	_, err = config.SaveContainerState(containerName, *state)
+	return err
+}
+
+// applyStoredPassword uses the password pgbox generated for the container,
+// since its data volume was initialized with it, unless one was given.
+func (o *UpOrchestrator) applyStoredPassword(containerName string, pgConfig *config.PostgresConfig, cfg UpConfig) error {
+	if cfg.Password != "" {
+		return nil
+	}
+	state, err := config.LoadContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	if state != nil && state.Password != "" {
+		pgConfig.Password = state.Password
+	}
+	return nil
+}
+
+// generatePassword gives a new container a random password and stores it
+// under the user's config directory rather than in the project. A password
+// stored by an earlier up is kept.
+func (o *UpOrchestrator) generatePassword(containerName string, pgConfig *config.PostgresConfig) error {
+	state, err := config.LoadContainerState(containerName)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &config.ContainerState{}
+	} else if state.Password != "" {
+		return nil
+	}
+	password, err := util.GeneratePassword(24)
+	if err != nil {
+		return fmt.Errorf("failed to generate password: %w", err)
+	}
+	state.Password = password
+	pgConfig.Password = password
+	if o.dryRun {
+		path, err := config.ContainerStatePath(containerName)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(o.output, "Would generate a password for %s and store it in %s\n", containerName, path)
+		return nil
+	}
+	path, err := config.SaveContainerState(containerName, *state)
+	if err != nil {
+		return err
+	}
+	logging.Infof(o.output, "Generated a password for %s, stored in %s", containerName, path)
+	return nil
+}
+
+// checkPort makes sure the host port is free before a new container tries to
+// publish it, since docker's own error does not say what holds the port. With
+// autoPort it moves pgConfig to the next free port instead of failing. The
+// port may be held by replaced, a container the new one takes the place of.
+func (o *UpOrchestrator) checkPort(ctx context.Context, pgConfig *config.PostgresConfig, autoPort bool, replaced string) error {
+	// Ports bound to an address (127.0.0.1:5432) are left for docker to check
+	port, err := strconv.Atoi(pgConfig.Port)
+	if err != nil || !o.portInUse(port) {
+		return nil
+	}
+	holder, container := o.portHolder(ctx, port)
+	if replaced != "" && container == replaced {
+		return nil
+	}
+	if !autoPort {
+		hint := "choose another with -p, or pass --auto-port to use the next free one"
+		if container != "" {
+			hint = fmt.Sprintf("stop it with: pgbox down -n %s, %s", container, hint)
+		}
+		return fmt.Errorf("port %d is already in use by %s; %s", port, holder, hint)
+	}
+
+	for next := port + 1; next < port+100; next++ {
+		if o.portInUse(next) {
+			continue
+		}
+		pgConfig.Port = strconv.Itoa(next)
+		logging.Infof(o.output, "Port %d is in use by %s; using port %d instead", port, holder, next)
+		_, _ = fmt.Fprintf(o.output, "Connection: %s\n", connectionString(pgConfig, pgConfig.Port))
+		return nil
+	}
+	return fmt.Errorf("port %d is already in use by %s, and no port between %d and %d is free", port, holder, port+1, port+99)
+}
+
+// portHolder describes what listens on a host port: a container publishing
+// it, else the process lsof reports. container is set in the first case.
+func (o *UpOrchestrator) portHolder(ctx context.Context, port int) (holder, container string) {
+	out, _ := o.docker.RunCommandWithOutput(ctx, "ps", "--filter", fmt.Sprintf("publish=%d", port), "--format", "{{.Names}}")
+	if names := strings.Fields(out); len(names) > 0 {
+		return "container " + names[0], names[0]
+	}
+	if process := o.portProcess(port); process != "" {
+		return process, ""
+	}
+	return "another process", ""
 }
 
 // tryRestartExisting checks if a container exists and restarts it if so.
 // Returns (restarted, error).
-func (o *UpOrchestrator) tryRestartExisting(containerName string) (bool, error) {
-	existingOutput, _ := o.docker.RunCommandWithOutput("ps", "-a", "--filter", fmt.Sprintf("name=^%s$", containerName), "--format", "{{.Names}}")
-	if strings.TrimSpace(existingOutput) == containerName {
-		_, _ = fmt.Fprintf(o.output, "Restarting existing container: %s\n", containerName)
-		if err := o.docker.RunCommand("start", containerName); err != nil {
+func (o *UpOrchestrator) tryRestartExisting(ctx context.Context, containerName string, cfg UpConfig) (bool, error) {
+	if o.recreating {
+		// Replaced before the new container is created
+		return false, nil
+	}
+	if o.containerExists(ctx, containerName) {
+		if err := o.checkDrift(ctx, containerName, cfg); err != nil {
+			return false, err
+		}
+		if running, _ := o.docker.IsContainerRunning(ctx, containerName); running {
+			// Another up, possibly one this run waited for, already started it
+			logging.Infof(o.output, "Container %s is already running", containerName)
+			return true, nil
+		}
+		logging.Infof(o.output, "Restarting existing container: %s", containerName)
+		if err := o.docker.RunCommand(ctx, "start", containerName); err != nil {
 			return false, fmt.Errorf("failed to restart container: %w", err)
 		}
-		_, _ = fmt.Fprintf(o.output, "Container %s restarted successfully\n", containerName)
+		if o.dryRun {
+			return true, nil
+		}
+		logging.Infof(o.output, "Container %s restarted successfully", containerName)
 		return true, nil
 	}
 	return false, nil
 }
 
+// containerExists reports whether a container of that name exists, running
+// or not.
+func (o *UpOrchestrator) containerExists(ctx context.Context, containerName string) bool {
+	out, _ := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", containerName), "--format", "{{.Names}}")
+	return strings.TrimSpace(out) == containerName
+}
+
+// recreateExisting prepares to replace an existing container, so up creates
+// it again with a freshly built image and the requested extensions and
+// settings. The data volume is named after the container, so the new one
+// keeps the data, and the credentials are the old container's, since the data
+// was initialized with them. Requested extensions the old container lacked
+// are created once the new one is up, as the volume's init scripts have run.
+// The old container is only removed by removeRecreated, once everything the
+// new one needs is validated and built.
+func (o *UpOrchestrator) recreateExisting(ctx context.Context, containerName string, pgConfig *config.PostgresConfig, cfg UpConfig) error {
+	if !o.containerExists(ctx, containerName) {
+		return nil
+	}
+	labels := containerLabels(ctx, o.docker, containerName)
+	if version := labels[container.LabelPostgresVersion]; version != "" && version != cfg.Version {
+		return fmt.Errorf("--recreate cannot move %s from PostgreSQL %s to %s, since the data directory is tied to the major version; use pgbox upgrade -n %s",
+			containerName, version, cfg.Version, containerName)
+	}
+	created := container.ParseExtensionsLabel(labels[container.LabelExtensions])
+	for _, ext := range cfg.Extensions {
+		if !slices.Contains(created, ext) {
+			o.enable = append(o.enable, ext)
+		}
+	}
+
+	if cfg.User != "" || cfg.Password != "" || cfg.Database != "" {
+		if err := o.warn("--user, --password and --database do not apply with --recreate; %s keeps the credentials its data was created with", containerName); err != nil {
+			return err
+		}
+	}
+	if cfg.Locale != "" || cfg.Encoding != "" || len(cfg.InitdbArgs) > 0 {
+		if err := o.warn("--locale, --encoding and --initdb-arg do not apply with --recreate; the data in volume %s-data keeps the ones it was created with", containerName); err != nil {
+			return err
+		}
+	}
+	if pgConfig.Timezone == "" {
+		pgConfig.Timezone, _ = o.docker.GetContainerEnv(ctx, containerName, "TZ")
+	}
+	pgConfig.Password = containerPassword(ctx, o.docker, containerName)
+	pgConfig.User, pgConfig.Database = ResolveCredentials(ctx, o.docker, containerName, "", "")
+
+	logging.Infof(o.output, "Recreating %s; the data in volume %s-data is kept", containerName, containerName)
+	o.recreating = true
+	return nil
+}
+
+// removeRecreated stops and removes the container recreateExisting prepared
+// to replace.
+func (o *UpOrchestrator) removeRecreated(ctx context.Context, containerName string) error {
+	if running, _ := o.docker.IsContainerRunning(ctx, containerName); running {
+		if err := o.docker.StopContainer(ctx, containerName); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", containerName, err)
+		}
+	}
+	if err := o.docker.RemoveContainer(ctx, containerName); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", containerName, err)
+	}
+	return nil
+}
+
+// enableExtensions creates the extensions recreateExisting found missing,
+// once the recreated container accepts connections.
+func (o *UpOrchestrator) enableExtensions(ctx context.Context, containerName string, pgConfig *config.PostgresConfig) error {
+	if !o.waitForReady(ctx, containerName, pgConfig) {
+		return o.notReadyError(ctx, containerName)
+	}
+	for _, ext := range o.enable {
+		if _, err := QueryLines(ctx, o.docker, containerName, pgConfig.User, pgConfig.Database, extensions.GetInitSQL(ext)); err != nil {
+			return fmt.Errorf("failed to enable %s in %s: %w", ext, containerName, err)
+		}
+		logging.Infof(o.output, "Enabled %s in %s", ext, containerName)
+		if len(extensions.GetInitdbScripts(ext)) > 0 {
+			logging.Warnf(o.output, "%s's initdb scripts only run on a new data volume and did not run in %s", ext, containerName)
+		}
+	}
+	return nil
+}
+
+// checkDrift compares the extensions requested for a container with the ones
+// its labels say it was created with. Requested extensions that need their own
+// image or server settings are an error, since restarting would silently go
+// without them. Containers created before pgbox labelled them are not checked.
+func (o *UpOrchestrator) checkDrift(ctx context.Context, containerName string, cfg UpConfig) error {
+	labels := containerLabels(ctx, o.docker, containerName)
+	createdWith, ok := labels[container.LabelExtensions]
+	if !ok {
+		return nil
+	}
+	created := container.ParseExtensionsLabel(createdWith)
+
+	var missing, inPlace, extra []string
+	for _, ext := range cfg.Extensions {
+		switch {
+		case slices.Contains(created, ext):
+		case extensions.NeedsRebuild(ext):
+			missing = append(missing, ext)
+		default:
+			inPlace = append(inPlace, ext)
+		}
+	}
+	for _, ext := range created {
+		if !slices.Contains(cfg.Extensions, ext) {
+			extra = append(extra, ext)
+		}
+	}
+	if len(missing) > 0 {
+		list := strings.Join(missing, ",")
+		return fmt.Errorf("container %s was created without %s, which need a rebuilt image; rerun with --recreate to rebuild it and recreate the container (volume %s-data is kept), or add them with: pgbox ext add %s -n %s",
+			containerName, strings.Join(missing, ", "), containerName, list, containerName)
+	}
+
+	if len(inPlace) > 0 {
+		_, _ = fmt.Fprintf(o.output, "Note: %s was created without %s; create them with: pgbox ext add %s -n %s (or rerun with --recreate)\n",
+			containerName, strings.Join(inPlace, ", "), strings.Join(inPlace, ","), containerName)
+	}
+	if len(extra) > 0 && len(cfg.Extensions) > 0 {
+		_, _ = fmt.Fprintf(o.output, "Note: %s was also created with %s, which stay installed\n", containerName, strings.Join(extra, ", "))
+	}
+	if version := labels[container.LabelPostgresVersion]; version != "" && version != cfg.Version {
+		_, _ = fmt.Fprintf(o.output, "Note: %s runs PostgreSQL %s, not the requested %s\n", containerName, version, cfg.Version)
+	}
+	if hash := labels[container.LabelExtensionHash]; hash != o.containerMgr.ExtensionHash(created) {
+		_, _ = fmt.Fprintf(o.output, "Note: the catalog entries for %s's extensions changed since it was created; rerun with --recreate to apply them (volume %s-data is kept)\n", containerName, containerName)
+	}
+	return nil
+}
+
 // processExtensions loads and applies extension configurations using the Go catalog.
-func (o *UpOrchestrator) processExtensions(
+func (o *UpOrchestrator) processExtensions(ctx context.Context,
 	pgVersion string,
 	extNames []string,
+	prefer []string,
+	settings map[string]string,
 	dockerfileModel *model.DockerfileModel,
 	pgConfModel *model.PGConfModel,
 	initModel *model.InitModel,
@@ -120,20 +877,21 @@ func (o *UpOrchestrator) processExtensions(
 	if err := extensions.ValidateExtensions(extNames); err != nil {
 		return err
 	}
-
-	packages := extensions.GetPackages(extNames, pgVersion)
-	if len(packages) > 0 {
-		dockerfileModel.AddPackages(packages, "apt")
+	if err := extensions.ValidateVersion(extNames, pgVersion); err != nil {
+		return err
 	}
-
-	debURLs := extensions.GetDebURLs(extNames, pgVersion, util.GetDebArch())
-	if len(debURLs) > 0 {
-		dockerfileModel.AddDebURLs(debURLs...)
+	if err := validatePrefer(prefer, extNames); err != nil {
+		return err
 	}
 
-	zipURLs := extensions.GetZipURLs(extNames, pgVersion, util.GetDebArch())
-	if len(zipURLs) > 0 {
-		dockerfileModel.AddZipURLs(zipURLs...)
+	install, err := addPackages(dockerfileModel, extNames, pgVersion, util.GetDebArch())
+	if err != nil {
+		return err
+	}
+	if unverified := unverifiedDownloads(dockerfileModel, extNames, pgVersion, util.GetDebArch()); len(unverified) > 0 {
+		if err := o.warn(unverifiedWarning, strings.Join(unverified, ", ")); err != nil {
+			return err
+		}
 	}
 
 	preload := extensions.GetPreloadLibraries(extNames)
@@ -141,12 +899,13 @@ func (o *UpOrchestrator) processExtensions(
 		pgConfModel.AddSharedPreload(preload...)
 	}
 
-	gucs, err := extensions.GetGUCs(extNames)
+	gucs, err := extensions.ResolveGUCs(extNames, prefer, slices.Collect(maps.Keys(settings)))
 	if err != nil {
 		return fmt.Errorf("extension configuration conflict: %w", err)
 	}
 	for key, value := range gucs {
-		pgConfModel.GUCs[key] = value
+		provider := extensions.GetGUCProvider(extNames, key, value)
+		pgConfModel.ApplyGUC(key, value, model.GUCSource{Kind: model.SourceExtension, Name: provider})
 	}
 
 	for _, name := range extNames {
@@ -154,10 +913,13 @@ func (o *UpOrchestrator) processExtensions(
 		if sql != "" {
 			initModel.AddFragment(name+"-init", sql)
 		}
+		for _, script := range extensions.GetInitdbScripts(name) {
+			initModel.AddScript(script.Name, script.Run)
+		}
 	}
 
-	if len(packages) > 0 || len(debURLs) > 0 || len(zipURLs) > 0 {
-		customImage, err := o.buildCustomImage(pgVersion, dockerfileModel, extNames)
+	if install {
+		customImage, err := o.buildCustomImage(ctx, pgVersion, dockerfileModel, extNames)
 		if err != nil {
 			return fmt.Errorf("failed to build custom image: %w", err)
 		}
@@ -168,14 +930,16 @@ func (o *UpOrchestrator) processExtensions(
 }
 
 // buildCustomImage builds a Docker image with the specified extensions.
-func (o *UpOrchestrator) buildCustomImage(pgVersion string, dockerfileModel *model.DockerfileModel, extensions []string) (string, error) {
+func (o *UpOrchestrator) buildCustomImage(ctx context.Context, pgVersion string, dockerfileModel *model.DockerfileModel, extensions []string) (imageName string, err error) {
 	buildDir := filepath.Join(os.TempDir(), fmt.Sprintf("pgbox-build-%d", os.Getpid()))
 	if err := os.MkdirAll(buildDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create build directory: %w", err)
 	}
 	defer func() {
-		if err := os.RemoveAll(buildDir); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove build directory %s: %v\n", buildDir, err)
+		if rmErr := os.RemoveAll(buildDir); rmErr != nil {
+			if warnErr := o.warn("failed to remove build directory %s: %v", buildDir, rmErr); warnErr != nil && err == nil {
+				err = warnErr
+			}
 		}
 	}()
 
@@ -183,106 +947,302 @@ func (o *UpOrchestrator) buildCustomImage(pgVersion string, dockerfileModel *mod
 		return "", fmt.Errorf("failed to render Dockerfile: %w", err)
 	}
 
-	imageName := o.containerMgr.ImageName(pgVersion, extensions)
+	imageName = o.containerMgr.ImageName(pgVersion, extensions)
+	record := config.ImageRecord{
+		Image:     imageName,
+		Version:   pgVersion,
+		BaseImage: dockerfileModel.BaseImage,
+		Packages:  imagePackages(dockerfileModel),
+	}
 
-	existingImages, _ := o.docker.RunCommandWithOutput("images", "-q", imageName)
-	if strings.TrimSpace(existingImages) != "" {
-		_, _ = fmt.Fprintf(o.output, "Using existing custom image: %s\n", imageName)
-		return imageName, nil
+	if o.imageExists(ctx, imageName) {
+		logging.Infof(o.output, "Using existing custom image: %s", imageName)
+		return imageName, o.recordImage(record, false)
+	}
+	if len(dockerfileModel.Blocks) == 0 {
+		if cached := o.findCachedImage(ctx, record); cached != "" {
+			logging.Infof(o.output, "Using custom image %s, which already has the packages these extensions need", cached)
+			return cached, nil
+		}
 	}
 
-	_, _ = fmt.Fprintln(o.output, "Building custom PostgreSQL image with extensions...")
-	buildArgs := []string{"build", "-t", imageName, "--build-arg", fmt.Sprintf("PG_MAJOR=%s", pgVersion), buildDir}
-	if err := o.docker.RunCommand(buildArgs...); err != nil {
+	if o.dryRun {
+		_, _ = fmt.Fprintf(o.output, "Would build %s from:\n", imageName)
+		if err := printRenderedFile(o.output, filepath.Join(buildDir, "Dockerfile")); err != nil {
+			return "", err
+		}
+	} else {
+		logging.Infof(o.output, "Building custom PostgreSQL image with extensions...")
+	}
+	buildArgs := []string{"build", "-t", imageName, "--build-arg", fmt.Sprintf("PG_MAJOR=%s", pgVersion)}
+	if o.buildProgress != "" {
+		buildArgs = append(buildArgs, "--progress", o.buildProgress)
+	}
+	if o.pull == PullAlways {
+		buildArgs = append(buildArgs, "--pull")
+	}
+	buildArgs = append(buildArgs, buildDir)
+	buildStart := time.Now()
+	w := newPrefixWriter(o.output, buildOutputPrefix, nil)
+	err = o.docker.RunCommandWithIO(ctx, nil, w, w, buildArgs...)
+	w.Flush()
+	if err != nil {
 		return "", fmt.Errorf("failed to build Docker image: %w", err)
 	}
+	o.timeStep(stepBuild, buildStart, buildDetail(dockerfileModel))
 
-	return imageName, nil
+	if o.dryRun || len(dockerfileModel.Blocks) > 0 {
+		return imageName, nil
+	}
+	return imageName, o.recordImage(record, true)
+}
+
+// imagePackages lists what a Dockerfile installs: its apt or apk packages and
+// the URLs of its .deb and .zip downloads, sorted.
+func imagePackages(m *model.DockerfileModel) []string {
+	packages := slices.Concat(m.AptPackages, m.ApkPackages, m.DebURLs, m.ZipURLs)
+	sort.Strings(packages)
+	return slices.Compact(packages)
+}
+
+// imageExists reports whether the image is present locally.
+func (o *UpOrchestrator) imageExists(ctx context.Context, image string) bool {
+	out, _ := o.docker.RunCommandWithOutput(ctx, "images", "-q", image)
+	return strings.TrimSpace(out) != ""
+}
+
+// findCachedImage returns a custom image built earlier, for any extensions,
+// from the same base image and with every package the record lists, so
+// overlapping extension sets across projects share one image instead of
+// building their own. The image with the fewest extra packages wins. Records
+// of images that no longer exist are dropped. Returns "" when there is none.
+func (o *UpOrchestrator) findCachedImage(ctx context.Context, want config.ImageRecord) string {
+	records, err := config.LoadImageRecords()
+	if err != nil {
+		return ""
+	}
+	var candidates []config.ImageRecord
+	for _, r := range records {
+		if r.Image != want.Image && r.Version == want.Version && r.BaseImage == want.BaseImage && r.Contains(want.Packages) {
+			candidates = append(candidates, r)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i].Packages) < len(candidates[j].Packages) })
+	for _, r := range candidates {
+		if o.imageExists(ctx, r.Image) {
+			return r.Image
+		}
+		_ = config.RemoveImageRecord(r.Image)
+	}
+	return ""
+}
+
+// recordImage adds a custom image to the image cache so later builds can
+// reuse it. An image already recorded is left alone unless replace is set,
+// as after a rebuild.
+func (o *UpOrchestrator) recordImage(record config.ImageRecord, replace bool) error {
+	if !replace {
+		records, err := config.LoadImageRecords()
+		if err == nil && slices.ContainsFunc(records, func(r config.ImageRecord) bool { return r.Image == record.Image }) {
+			return nil
+		}
+	}
+	record.Built = time.Now().UTC().Truncate(time.Second)
+	if err := config.SaveImageRecord(record); err != nil {
+		return o.warn("failed to record custom image %s: %v", record.Image, err)
+	}
+	return nil
 }
 
 // printStatus prints the startup status to the output writer.
-func (o *UpOrchestrator) printStatus(pgConfig *config.PostgresConfig, containerName string, extensions []string, detach bool) {
-	_, _ = fmt.Fprintf(o.output, "Starting PostgreSQL %s...\n", pgConfig.Version)
-	_, _ = fmt.Fprintf(o.output, "Container: %s\n", containerName)
-	_, _ = fmt.Fprintf(o.output, "Port: %s\n", pgConfig.Port)
-	_, _ = fmt.Fprintf(o.output, "User: %s\n", pgConfig.User)
-	_, _ = fmt.Fprintf(o.output, "Database: %s\n", pgConfig.Database)
+func (o *UpOrchestrator) printStatus(pgConfig *config.PostgresConfig, containerName string, extensions []string, pgConfModel *model.PGConfModel, detach bool) {
+	if o.dryRun {
+		logging.Infof(o.output, "Would start PostgreSQL %s", pgConfig.Version)
+	} else {
+		logging.Infof(o.output, "Starting PostgreSQL %s...", pgConfig.Version)
+	}
+	logging.Infof(o.output, "Container: %s", containerName)
+	logging.Infof(o.output, "Port: %s", pgConfig.Port)
+	logging.Infof(o.output, "User: %s", pgConfig.User)
+	logging.Infof(o.output, "Database: %s", pgConfig.Database)
 	if len(extensions) > 0 {
-		_, _ = fmt.Fprintf(o.output, "Extensions: %s\n", strings.Join(extensions, ", "))
+		logging.Infof(o.output, "Extensions: %s", strings.Join(extensions, ", "))
+	}
+	if len(pgConfModel.GUCs) > 0 {
+		logging.Infof(o.output, "Settings:")
+		for _, key := range pgConfModel.SortedGUCKeys() {
+			logging.Infof(o.output, "  %s = %s (%s)", key, pgConfModel.GUCs[key], pgConfModel.Sources[key])
+		}
 	}
 
-	if !detach {
-		_, _ = fmt.Fprintln(o.output, "\nPress Ctrl+C to stop the container")
-	} else {
-		_, _ = fmt.Fprintf(o.output, "\nRunning in background. Use 'pgbox down -n %s' to stop.\n", containerName)
+	switch {
+	case o.dryRun:
+		// Nothing runs, so there is nothing to follow or stop
+	case !detach:
+		logging.Infof(o.output, "\nFollowing the logs. Press Ctrl+C to stop the container")
+	default:
+		logging.Infof(o.output, "\nRunning in background. Use 'pgbox down -n %s' to stop.", containerName)
 	}
-	_, _ = fmt.Fprintln(o.output, strings.Repeat("-", 40))
+	logging.Infof(o.output, "%s", strings.Repeat("-", 40))
+}
+
+// printUnsafeWarning explains the risk of running with durability disabled
+// by the given flag.
+func (o *UpOrchestrator) printUnsafeWarning(flag string) {
+	bar := strings.Repeat("!", 60)
+	_, _ = fmt.Fprintln(o.output, bar)
+	_, _ = fmt.Fprintf(o.output, "WARNING: %s disables fsync, synchronous_commit and full_page_writes.\n", flag)
+	_, _ = fmt.Fprintln(o.output, "A crash, OOM kill or 'docker kill' can silently corrupt this database.")
+	_, _ = fmt.Fprintln(o.output, "Only use it for throwaway data such as test suites.")
+	_, _ = fmt.Fprintln(o.output, bar)
 }
 
 // buildContainerOptions builds the Docker container options.
 func (o *UpOrchestrator) buildContainerOptions(
 	containerName string,
-	detach bool,
+	version string,
 	extensions []string,
 	pgConfModel *model.PGConfModel,
 	initModel *model.InitModel,
-) docker.ContainerOptions {
+) (docker.ContainerOptions, error) {
+	health := model.PostgresHealthcheck
 	opts := docker.ContainerOptions{
 		Name:      containerName,
 		ExtraArgs: []string{},
+		Labels:    o.containerMgr.Labels(version, extensions),
+		Health:    &health,
 	}
 
-	if detach {
-		opts.ExtraArgs = append(opts.ExtraArgs, "-d")
-	}
+	// Foreground runs follow the logs of a detached container, so pgbox
+	// decides what happens to it on Ctrl+C rather than the docker CLI
+	opts.ExtraArgs = append(opts.ExtraArgs, "-d")
 
 	volumeName := fmt.Sprintf("%s-data", containerName)
 	opts.ExtraArgs = append(opts.ExtraArgs, "-v", fmt.Sprintf("%s:/var/lib/postgresql/data", volumeName))
 
 	if len(extensions) > 0 {
-		o.configureExtensions(&opts, containerName, pgConfModel, initModel)
+		if err := o.configureExtensions(&opts, containerName, pgConfModel, initModel); err != nil {
+			return docker.ContainerOptions{}, err
+		}
 	}
 
-	return opts
+	opts.Command = append(opts.Command, serverArgs(pgConfModel)...)
+
+	return opts, nil
 }
 
-// configureExtensions adds extension-specific configuration to container options.
+// serverArgs returns the -c arguments that pass the model's settings to the
+// server. --set can preload libraries without any extension.
+func serverArgs(pgConfModel *model.PGConfModel) []string {
+	var args []string
+	if len(pgConfModel.SharedPreload) > 0 {
+		args = append(args, "-c", fmt.Sprintf("shared_preload_libraries=%s", pgConfModel.GetSharedPreloadString()))
+	}
+	for _, key := range pgConfModel.SortedGUCKeys() {
+		if key == "shared_preload_libraries" {
+			continue
+		}
+		args = append(args, "-c", fmt.Sprintf("%s=%s", key, pgConfModel.GUCs[key]))
+	}
+	return args
+}
+
+// configureExtensions adds extension-specific configuration to container
+// options. The init SQL and initdb scripts are written to the container's
+// artifacts directory (see config.ArtifactsDir) and mounted, so a failure to
+// write them is an error rather than a container without them.
 func (o *UpOrchestrator) configureExtensions(
 	opts *docker.ContainerOptions,
 	containerName string,
 	pgConfModel *model.PGConfModel,
 	initModel *model.InitModel,
-) {
-	initFile := filepath.Join(os.TempDir(), fmt.Sprintf("pgbox-init-%s.sql", containerName))
-	if err := render.RenderInitSQL(initModel, os.TempDir()); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to render init SQL: %v\n", err)
-		return
+) error {
+	// Render into an empty directory so fragments of extensions no longer
+	// requested are not carried over from an earlier run
+	dir, err := os.MkdirTemp("", "pgbox-init-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	rendered := filepath.Join(dir, initSQLArtifact)
+	if err := render.RenderInitSQLFile(initModel, rendered); err != nil {
+		return fmt.Errorf("failed to render init SQL: %w", err)
 	}
 
-	generatedInitPath := filepath.Join(os.TempDir(), "init.sql")
-	initContent, err := os.ReadFile(generatedInitPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to read generated init.sql: %v\n", err)
-		return
+	var initFile string
+	if o.dryRun {
+		artifacts, err := config.ArtifactsDir(containerName)
+		if err != nil {
+			return err
+		}
+		initFile = filepath.Join(artifacts, initSQLArtifact)
+		_, _ = fmt.Fprintf(o.output, "Would write %s:\n", initFile)
+		if err := printRenderedFile(o.output, rendered); err != nil {
+			return err
+		}
+	} else {
+		content, err := os.ReadFile(rendered)
+		if err != nil {
+			return fmt.Errorf("failed to read rendered init SQL: %w", err)
+		}
+		if initFile, err = config.WriteArtifact(containerName, initSQLArtifact, initSQLMountPath, content); err != nil {
+			return fmt.Errorf("failed to write init SQL: %w", err)
+		}
 	}
-	if err := os.WriteFile(initFile, initContent, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to write init.sql: %v\n", err)
-		return
+	opts.ExtraArgs = append(opts.ExtraArgs, "-v", fmt.Sprintf("%s:%s:ro", initFile, initSQLMountPath))
+
+	scripts, err := render.RenderInitScripts(initModel, dir)
+	if err != nil {
+		return fmt.Errorf("failed to render initdb scripts: %w", err)
 	}
-	if err := os.Remove(generatedInitPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to clean up temp file %s: %v\n", generatedInitPath, err)
+	for _, name := range scripts {
+		mountPath := initDirMountPath + "/" + name
+		var scriptFile string
+		if o.dryRun {
+			artifacts, err := config.ArtifactsDir(containerName)
+			if err != nil {
+				return err
+			}
+			scriptFile = filepath.Join(artifacts, name)
+			_, _ = fmt.Fprintf(o.output, "Would write %s:\n", scriptFile)
+			if err := printRenderedFile(o.output, filepath.Join(dir, name)); err != nil {
+				return err
+			}
+		} else {
+			content, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return fmt.Errorf("failed to read rendered %s: %w", name, err)
+			}
+			if scriptFile, err = config.WriteArtifact(containerName, name, mountPath, content); err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+			if err := os.Chmod(scriptFile, 0755); err != nil {
+				return fmt.Errorf("failed to make %s executable: %w", scriptFile, err)
+			}
+		}
+		opts.ExtraArgs = append(opts.ExtraArgs, "-v", fmt.Sprintf("%s:%s:ro", scriptFile, mountPath))
 	}
-	opts.ExtraArgs = append(opts.ExtraArgs, "-v", fmt.Sprintf("%s:/docker-entrypoint-initdb.d/init.sql:ro", initFile))
 
-	if len(pgConfModel.SharedPreload) > 0 {
-		preloadStr := pgConfModel.GetSharedPreloadString()
-		opts.Command = append(opts.Command, "-c", fmt.Sprintf("shared_preload_libraries=%s", preloadStr))
+	return nil
+}
+
+// warn prints a warning, or returns it as an error in strict mode.
+func (o *UpOrchestrator) warn(format string, args ...any) error {
+	if o.strict {
+		return fmt.Errorf("strict mode: "+format, args...)
 	}
+	logging.Warnf(o.output, format, args...)
+	return nil
+}
 
-	for key, value := range pgConfModel.GUCs {
-		if key == "shared_preload_libraries" {
-			continue
-		}
-		opts.Command = append(opts.Command, "-c", fmt.Sprintf("%s=%s", key, value))
+// printRenderedFile prints a file pgbox rendered, indented, for --dry-run.
+func printRenderedFile(w io.Writer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
 	}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		_, _ = fmt.Fprintf(w, "  %s\n", line)
+	}
+	return nil
 }
@@ -0,0 +1,293 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// UpgradeConfig holds configuration for the upgrade command.
+type UpgradeConfig struct {
+	ContainerName string // Container to upgrade (default: pgbox-pg<from>, then auto-detect)
+	From          string // Expected current major version; read from the container when empty
+	To            string // Major version to upgrade to
+}
+
+// UpgradeOrchestrator moves a container's data to a newer PostgreSQL major
+// version with pg_dumpall, since a data directory cannot be started by a
+// different major version.
+type UpgradeOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+	newUp  func() *UpOrchestrator
+	now    func() time.Time
+}
+
+// NewUpgradeOrchestrator creates a new UpgradeOrchestrator.
+func NewUpgradeOrchestrator(d docker.Docker, w io.Writer) *UpgradeOrchestrator {
+	return &UpgradeOrchestrator{
+		docker: d,
+		output: w,
+		newUp:  func() *UpOrchestrator { return NewUpOrchestrator(d, w) },
+		now:    time.Now,
+	}
+}
+
+// upgradePlan is what an upgrade does with the old container and where the
+// new one goes.
+type upgradePlan struct {
+	name    string // Container being upgraded
+	newName string // Container started on the new version
+	oldName string // Name the old data is kept under when names are swapped
+	swap    bool   // The new container takes over name, so the old data moves to oldName-data
+}
+
+// Run dumps every database of the old container, starts a container on the
+// new version with the same extensions, settings, credentials and port, and
+// restores the dump into its fresh volume.
+//
+// Containers with a default name (pgbox-pg16) are upgraded next to a new
+// default-named container (pgbox-pg17), and the old one is left stopped.
+// Containers with any other name keep it: the old data volume is copied to
+// <name>-pg<from>-data and the new version starts in an empty <name>-data.
+func (o *UpgradeOrchestrator) Run(ctx context.Context, cfg UpgradeConfig) error {
+	if !slices.Contains(config.SupportedVersions, cfg.To) {
+		return fmt.Errorf("invalid PostgreSQL version: %s (must be %s)", cfg.To, strings.Join(config.SupportedVersions, ", "))
+	}
+
+	name := cfg.ContainerName
+	if name == "" && cfg.From != "" {
+		name = "pgbox-pg" + cfg.From
+	}
+	name, _, err := ResolveContainerName(ctx, o.docker, name)
+	if err != nil {
+		return fmt.Errorf("%w. Specify container name with -n flag", err)
+	}
+
+	up := o.newUp()
+	if err := o.ensureRunning(ctx, up, name); err != nil {
+		return err
+	}
+	upCfg, err := inspectUpConfig(ctx, o.docker, name)
+	if err != nil {
+		return err
+	}
+	from := upCfg.Version
+	if cfg.From != "" && cfg.From != from {
+		return fmt.Errorf("%s runs PostgreSQL %s, not %s", name, from, cfg.From)
+	}
+	if !newerVersion(cfg.To, from) {
+		return fmt.Errorf("%s already runs PostgreSQL %s; --to must be a newer major version", name, from)
+	}
+	if err := extensions.ValidateVersion(upCfg.Extensions, cfg.To); err != nil {
+		return err
+	}
+
+	plan, err := o.plan(ctx, up, name, from, cfg.To, upCfg.Extensions)
+	if err != nil {
+		return err
+	}
+
+	logging.Infof(o.output, "Upgrading %s from PostgreSQL %s to %s", name, from, cfg.To)
+	dump, err := o.dumpAll(ctx, name, from, upCfg.User)
+	if err != nil {
+		return err
+	}
+
+	logging.Infof(o.output, "Stopping %s...", name)
+	if err := o.docker.StopContainer(ctx, name); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", name, err)
+	}
+	if plan.swap {
+		if err := o.moveAside(ctx, plan); err != nil {
+			return fmt.Errorf("%w (the dump is kept at %s)", err, dump)
+		}
+	}
+
+	upCfg.Version = cfg.To
+	upCfg.ContainerName = plan.newName
+	if err := up.Run(ctx, upCfg); err != nil {
+		return fmt.Errorf("failed to start PostgreSQL %s (the dump is kept at %s): %w", cfg.To, dump, err)
+	}
+
+	logging.Infof(o.output, "Restoring %s into %s...", filepath.Base(dump), plan.newName)
+	if err := o.restoreAll(ctx, plan.newName, upCfg.User, dump); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Upgraded %s from PostgreSQL %s to %s\n", name, from, cfg.To)
+	if plan.swap {
+		_, _ = fmt.Fprintf(o.output, "The PostgreSQL %s data is kept in volume %s-data. Start it again with: pgbox up -v %s -n %s --port <port>%s\n",
+			from, plan.oldName, from, plan.oldName, extFlag(upCfg.Extensions))
+	} else {
+		_, _ = fmt.Fprintf(o.output, "The PostgreSQL %s container %s is stopped and keeps its data. Remove it with: pgbox down -n %s --volumes\n",
+			from, name, name)
+	}
+	_, _ = fmt.Fprintf(o.output, "Dump kept at %s\n", dump)
+	return nil
+}
+
+// ensureRunning starts a stopped container and waits until it accepts
+// connections, since pg_dumpall needs a running server.
+func (o *UpgradeOrchestrator) ensureRunning(ctx context.Context, up *UpOrchestrator, name string) error {
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if running {
+		return nil
+	}
+	existing, _ := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", name), "--format", "{{.Names}}")
+	if strings.TrimSpace(existing) != name {
+		return fmt.Errorf("container %s does not exist", name)
+	}
+
+	logging.Infof(o.output, "Starting %s to dump it...", name)
+	return up.startStopped(ctx, name)
+}
+
+// plan decides which container the new version runs in, and checks that
+// nothing is in the way before anything is changed.
+func (o *UpgradeOrchestrator) plan(ctx context.Context, up *UpOrchestrator, name, from, to string, exts []string) (upgradePlan, error) {
+	defaultName := "pgbox-pg" + from
+	if name == defaultName || strings.HasPrefix(name, defaultName+"-") {
+		pgConfig := config.NewPostgresConfig()
+		pgConfig.Version = to
+		newName := up.containerMgr.Name(pgConfig, exts)
+		existing, _ := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--filter", fmt.Sprintf("name=^%s$", newName), "--format", "{{.Names}}")
+		if strings.TrimSpace(existing) == newName {
+			return upgradePlan{}, fmt.Errorf("container %s already exists. Remove it first with: pgbox down -n %s --volumes", newName, newName)
+		}
+		if _, err := o.docker.RunCommandWithOutput(ctx, "volume", "inspect", newName+"-data"); err == nil {
+			return upgradePlan{}, fmt.Errorf("volume %s-data already exists. Remove it first with: docker volume rm %s-data", newName, newName)
+		}
+		return upgradePlan{name: name, newName: newName}, nil
+	}
+
+	oldName := fmt.Sprintf("%s-pg%s", name, from)
+	if _, err := o.docker.RunCommandWithOutput(ctx, "volume", "inspect", oldName+"-data"); err == nil {
+		return upgradePlan{}, fmt.Errorf("volume %s-data already exists. Remove it first with: docker volume rm %s-data", oldName, oldName)
+	}
+	return upgradePlan{name: name, newName: name, oldName: oldName, swap: true}, nil
+}
+
+// dumpAll writes pg_dumpall output for the container to a file under the
+// upgrade directory and returns its path. The dump is written to a temporary
+// file first so a failed dump never looks like a complete one.
+func (o *UpgradeOrchestrator) dumpAll(ctx context.Context, name, from, user string) (string, error) {
+	dir, err := config.UpgradeDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-pg%s-%s.sql", name, from, o.now().Format("20060102-150405")))
+
+	tmp, err := os.CreateTemp(dir, ".pgbox-upgrade-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create dump file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	logging.Infof(o.output, "Dumping all databases of %s with pg_dumpall...", name)
+	var stderr strings.Builder
+	args := append([]string{"exec"}, passwordEnv(name)...)
+	args = append(args, name, "pg_dumpall", "-U", user)
+	runErr := o.docker.RunCommandWithIO(ctx, nil, tmp, &stderr, args...)
+	closeErr := tmp.Close()
+	if runErr != nil {
+		return "", fmt.Errorf("pg_dumpall failed: %s: %w", strings.TrimSpace(stderr.String()), runErr)
+	}
+	if closeErr != nil {
+		return "", fmt.Errorf("failed to write dump: %w", closeErr)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// moveAside copies the stopped container's data volume to the old name, then
+// removes the container and its volume so the new version starts empty under
+// the original name.
+func (o *UpgradeOrchestrator) moveAside(ctx context.Context, plan upgradePlan) error {
+	volume := plan.name + "-data"
+	oldVolume := plan.oldName + "-data"
+	logging.Infof(o.output, "Copying volume %s to %s...", volume, oldVolume)
+	if out, err := o.docker.RunCommandWithOutput(ctx, "volume", "create", oldVolume); err != nil {
+		return fmt.Errorf("failed to create volume %s: %s: %w", oldVolume, strings.TrimSpace(out), err)
+	}
+	if out, err := o.docker.RunCommandWithOutput(ctx, "run", "--rm", "-v", volume+":/from:ro", "-v", oldVolume+":/to",
+		volumeHelperImage, "cp", "-a", "/from/.", "/to/"); err != nil {
+		return fmt.Errorf("failed to copy volume %s: %s: %w", volume, strings.TrimSpace(out), err)
+	}
+
+	if err := o.docker.RemoveContainer(ctx, plan.name); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", plan.name, err)
+	}
+	if out, err := o.docker.RunCommandWithOutput(ctx, "volume", "rm", volume); err != nil {
+		return fmt.Errorf("failed to remove volume %s (its copy is in %s): %s: %w", volume, oldVolume, strings.TrimSpace(out), err)
+	}
+	return nil
+}
+
+// restoreAll loads the dump into the new container. Errors about objects the
+// new container already has, such as its superuser role, are expected and
+// ignored; any other error is reported with the first few lines.
+func (o *UpgradeOrchestrator) restoreAll(ctx context.Context, name, user, dump string) error {
+	f, err := os.Open(dump)
+	if err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var stderr strings.Builder
+	args := append([]string{"exec", "-i"}, passwordEnv(name)...)
+	args = append(args, name, "psql", "-X", "-q", "-U", user, "-d", "postgres", "-f", "-")
+	if err := o.docker.RunCommandWithIO(ctx, f, io.Discard, &stderr, args...); err != nil {
+		return fmt.Errorf("restore failed (the dump is kept at %s): %s: %w", dump, strings.TrimSpace(stderr.String()), err)
+	}
+
+	var restoreErrors []string
+	scanner := bufio.NewScanner(strings.NewReader(stderr.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "ERROR:") && !strings.Contains(line, "already exists") {
+			restoreErrors = append(restoreErrors, strings.TrimSpace(line))
+		}
+	}
+	if len(restoreErrors) > 0 {
+		shown := restoreErrors[:min(len(restoreErrors), 5)]
+		return fmt.Errorf("restore finished with %d errors (the dump is kept at %s):\n  %s",
+			len(restoreErrors), dump, strings.Join(shown, "\n  "))
+	}
+	return nil
+}
+
+// newerVersion reports whether major version a is newer than b.
+func newerVersion(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	return errA == nil && errB == nil && x > y
+}
+
+// extFlag returns the --ext flag that recreates a container with exts.
+func extFlag(exts []string) string {
+	if len(exts) == 0 {
+		return ""
+	}
+	return " --ext " + strings.Join(exts, ",")
+}
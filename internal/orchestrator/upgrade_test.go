@@ -0,0 +1,152 @@
+package orchestrator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUpgradeMock returns a mock whose containers run PostgreSQL 16 with
+// hstore, whose pg_dumpall writes a small dump, and where no volume exists
+// besides theirs.
+func newUpgradeMock() (*docker.MockDocker, *bytes.Buffer) {
+	mock := newExtMock()
+	mock.GetContainerEnvFunc = func(containerName, envVar string) (string, error) {
+		switch envVar {
+		case "PG_MAJOR":
+			return "16", nil
+		case "POSTGRES_PASSWORD":
+			return "secret", nil
+		case "POSTGRES_INITDB_ARGS":
+			return "--locale=C --encoding=LATIN1", nil
+		case "TZ":
+			return "Europe/Berlin", nil
+		}
+		return "", nil
+	}
+	inspect := mock.RunCommandWithOutputFunc
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "volume" && args[1] == "inspect" {
+			return "", errors.New("no such volume")
+		}
+		return inspect(args...)
+	}
+	restored := &bytes.Buffer{}
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		if args[0] == "pull" {
+			return nil
+		}
+		if strings.Contains(strings.Join(args, " "), "pg_dumpall") {
+			_, _ = io.WriteString(stdout, "CREATE ROLE postgres;\n")
+			return nil
+		}
+		_, _ = io.Copy(restored, stdin)
+		_, _ = io.WriteString(stderr, `psql:<stdin>:1: ERROR:  role "postgres" already exists`+"\n")
+		return nil
+	}
+	return mock, restored
+}
+
+func newTestUpgradeOrchestrator(t *testing.T, mock *docker.MockDocker, w io.Writer) *UpgradeOrchestrator {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	orch := NewUpgradeOrchestrator(mock, w)
+	orch.newUp = func() *UpOrchestrator {
+		up := newTestUpOrchestrator(mock, w)
+		up.readyTimeout = 0
+		return up
+	}
+	orch.now = func() time.Time { return time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC) }
+	return orch
+}
+
+func TestUpgradeOrchestrator_DefaultName(t *testing.T) {
+	mock, restored := newUpgradeMock()
+	var buf bytes.Buffer
+
+	err := newTestUpgradeOrchestrator(t, mock, &buf).Run(t.Context(), UpgradeConfig{From: "16", To: "17"})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg16"}, mock.Calls.StopContainer)
+	assert.Empty(t, mock.Calls.RemoveContainer, "the old container is kept")
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	run := mock.Calls.RunPostgres[0]
+	assert.Equal(t, "17", run.Config.Version)
+	assert.Equal(t, "5433", run.Config.Port)
+	assert.Equal(t, "secret", run.Config.Password)
+	assert.True(t, strings.HasPrefix(run.Opts.Name, "pgbox-pg17-"), run.Opts.Name)
+	assert.Contains(t, run.Opts.Labels["dev.pgbox.extensions"], "hstore")
+	assert.Contains(t, run.Opts.Command, "work_mem=64MB")
+	assert.Equal(t, map[string]string{"POSTGRES_INITDB_ARGS": "--locale=C --encoding=LATIN1", "TZ": "Europe/Berlin"}, run.Config.InitdbEnv(),
+		"the new volume is initialized like the old one")
+	assert.Equal(t, "CREATE ROLE postgres;\n", restored.String())
+
+	dump := filepath.Join(os.Getenv("XDG_DATA_HOME"), "pgbox", "upgrades", "pgbox-pg16-pg16-20261016-093000.sql")
+	assert.FileExists(t, dump)
+	assert.Contains(t, buf.String(), "Upgraded pgbox-pg16 from PostgreSQL 16 to 17")
+	assert.Contains(t, buf.String(), "pgbox down -n pgbox-pg16 --volumes")
+}
+
+func TestUpgradeOrchestrator_SwapsNames(t *testing.T) {
+	mock, _ := newUpgradeMock()
+	var buf bytes.Buffer
+
+	err := newTestUpgradeOrchestrator(t, mock, &buf).Run(t.Context(), UpgradeConfig{ContainerName: "myapp", To: "18"})
+
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "create", "myapp-pg16-data"})
+	assert.Contains(t, mock.Calls.RunCommandWithOutput,
+		[]string{"run", "--rm", "-v", "myapp-data:/from:ro", "-v", "myapp-pg16-data:/to", volumeHelperImage, "cp", "-a", "/from/.", "/to/"})
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "myapp-data"})
+	assert.Equal(t, []string{"myapp"}, mock.Calls.RemoveContainer)
+	require.Len(t, mock.Calls.RunPostgres, 1)
+	run := mock.Calls.RunPostgres[0]
+	assert.Equal(t, "myapp", run.Opts.Name)
+	assert.Equal(t, "18", run.Config.Version)
+	assert.Contains(t, run.Opts.ExtraArgs, "myapp-data:/var/lib/postgresql/data")
+	assert.Contains(t, buf.String(), "pgbox up -v 16 -n myapp-pg16 --port <port> --ext hstore")
+}
+
+func TestUpgradeOrchestrator_VersionMismatch(t *testing.T) {
+	mock, _ := newUpgradeMock()
+
+	err := newTestUpgradeOrchestrator(t, mock, &bytes.Buffer{}).Run(t.Context(), UpgradeConfig{ContainerName: "myapp", From: "17", To: "18"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "myapp runs PostgreSQL 16, not 17")
+	assert.Empty(t, mock.Calls.StopContainer)
+}
+
+func TestUpgradeOrchestrator_NotNewer(t *testing.T) {
+	mock, _ := newUpgradeMock()
+
+	err := newTestUpgradeOrchestrator(t, mock, &bytes.Buffer{}).Run(t.Context(), UpgradeConfig{ContainerName: "myapp", To: "16"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--to must be a newer major version")
+}
+
+func TestUpgradeOrchestrator_RestoreErrors(t *testing.T) {
+	mock, _ := newUpgradeMock()
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		if args[0] != "pull" && !strings.Contains(strings.Join(args, " "), "pg_dumpall") {
+			_, _ = io.WriteString(stderr, `psql:<stdin>:9: ERROR:  type "widget" does not exist`+"\n")
+		}
+		return nil
+	}
+
+	err := newTestUpgradeOrchestrator(t, mock, &bytes.Buffer{}).Run(t.Context(), UpgradeConfig{ContainerName: "myapp", To: "17"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "restore finished with 1 errors")
+	assert.Contains(t, err.Error(), `type "widget" does not exist`)
+}
@@ -0,0 +1,266 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// VacuumStatusConfig holds configuration for the vacuum-status command.
+type VacuumStatusConfig struct {
+	ContainerName string
+	Database      string
+	User          string
+	Top           int // Number of tables to list, ordered by dead tuples
+}
+
+// VacuumStatusOrchestrator reports autovacuum state for a running container.
+type VacuumStatusOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewVacuumStatusOrchestrator creates a new VacuumStatusOrchestrator.
+func NewVacuumStatusOrchestrator(d docker.Docker, w io.Writer) *VacuumStatusOrchestrator {
+	return &VacuumStatusOrchestrator{docker: d, output: w}
+}
+
+// autovacuumSettings holds the server-wide settings that decide when autovacuum runs.
+type autovacuumSettings struct {
+	Enabled            bool
+	TrackCounts        bool
+	VacuumThreshold    float64
+	VacuumScaleFactor  float64
+	AnalyzeThreshold   float64
+	AnalyzeScaleFactor float64
+}
+
+// tableVacuumStatus is one row of the vacuum-status table report.
+type tableVacuumStatus struct {
+	Name             string
+	Live             int64
+	Dead             int64
+	ModSinceAnalyze  int64
+	VacuumAt         int64 // Dead tuples needed to trigger autovacuum
+	AnalyzeAt        int64 // Modified tuples needed to trigger autoanalyze
+	LastVacuum       string
+	LastAnalyze      string
+	AutovacuumOffFor string // Why autovacuum will never process this table, if it won't
+}
+
+const autovacuumSettingsQuery = `SELECT name, setting FROM pg_settings WHERE name IN (
+  'autovacuum', 'track_counts',
+  'autovacuum_vacuum_threshold', 'autovacuum_vacuum_scale_factor',
+  'autovacuum_analyze_threshold', 'autovacuum_analyze_scale_factor')`
+
+// tableVacuumQuery lists user tables by dead tuples. %d is the row limit.
+const tableVacuumQuery = `SELECT s.schemaname || '.' || s.relname, s.n_live_tup, s.n_dead_tup, s.n_mod_since_analyze,
+  GREATEST(c.reltuples, 0)::bigint,
+  COALESCE(array_to_string(c.reloptions, ','), ''),
+  COALESCE(to_char(GREATEST(s.last_vacuum, s.last_autovacuum), 'YYYY-MM-DD HH24:MI'), ''),
+  COALESCE(to_char(GREATEST(s.last_analyze, s.last_autoanalyze), 'YYYY-MM-DD HH24:MI'), '')
+FROM pg_stat_user_tables s JOIN pg_class c ON c.oid = s.relid
+ORDER BY s.n_dead_tup DESC, 1
+LIMIT %d`
+
+const autovacuumWorkersQuery = `SELECT pid, datname,
+  COALESCE(date_trunc('second', now() - xact_start)::text, ''),
+  regexp_replace(query, '\s+', ' ', 'g')
+FROM pg_stat_activity WHERE backend_type = 'autovacuum worker' ORDER BY xact_start`
+
+// Run prints autovacuum settings, per-table vacuum state and running workers.
+func (o *VacuumStatusOrchestrator) Run(ctx context.Context, cfg VacuumStatusConfig) error {
+	if cfg.Top < 1 {
+		return fmt.Errorf("--top must be at least 1")
+	}
+
+	name, _, err := ResolveContainerName(ctx, o.docker, cfg.ContainerName)
+	if err != nil {
+		return fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	running, err := o.docker.IsContainerRunning(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running. Start it with: pgbox up", name)
+	}
+	user, database := ResolveCredentials(ctx, o.docker, name, cfg.User, cfg.Database)
+
+	rows, err := QueryLines(ctx, o.docker, name, user, database, autovacuumSettingsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to read autovacuum settings: %w", err)
+	}
+	settings := parseAutovacuumSettings(rows)
+
+	rows, err = QueryLines(ctx, o.docker, name, user, database, fmt.Sprintf(tableVacuumQuery, cfg.Top))
+	if err != nil {
+		return fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	var tables []tableVacuumStatus
+	for _, row := range rows {
+		if table, ok := parseTableVacuumStatus(row, settings); ok {
+			tables = append(tables, table)
+		}
+	}
+
+	workers, err := QueryLines(ctx, o.docker, name, user, database, autovacuumWorkersQuery)
+	if err != nil {
+		return fmt.Errorf("failed to list autovacuum workers: %w", err)
+	}
+
+	o.printReport(database, settings, tables, workers)
+	return nil
+}
+
+// printReport prints the vacuum status report.
+func (o *VacuumStatusOrchestrator) printReport(database string, settings autovacuumSettings, tables []tableVacuumStatus, workers []string) {
+	state := "on"
+	if !settings.Enabled {
+		state = "OFF"
+	}
+	_, _ = fmt.Fprintf(o.output, "Autovacuum: %s (vacuum at %g + %g%% of rows, analyze at %g + %g%% of rows)\n",
+		state, settings.VacuumThreshold, settings.VacuumScaleFactor*100, settings.AnalyzeThreshold, settings.AnalyzeScaleFactor*100)
+	if !settings.TrackCounts {
+		logging.Warnf(o.output, "track_counts is off, so autovacuum has no statistics and never runs")
+	}
+
+	_, _ = fmt.Fprintf(o.output, "\nTables in %s by dead tuples:\n", database)
+	if len(tables) == 0 {
+		_, _ = fmt.Fprintln(o.output, "  (none)")
+	} else {
+		_, _ = fmt.Fprintf(o.output, "  %-36s %10s %10s %10s %-16s %-16s %s\n",
+			"TABLE", "LIVE", "DEAD", "VACUUM AT", "LAST VACUUM", "LAST ANALYZE", "NOTE")
+		var never []string
+		for _, t := range tables {
+			note := ""
+			switch {
+			case t.AutovacuumOffFor != "":
+				note = "never autovacuumed: " + t.AutovacuumOffFor
+				never = append(never, t.Name)
+			case t.Dead > t.VacuumAt:
+				note = "vacuum due"
+			case t.ModSinceAnalyze > t.AnalyzeAt:
+				note = "analyze due"
+			}
+			_, _ = fmt.Fprintf(o.output, "  %-36s %10d %10d %10d %-16s %-16s %s\n",
+				t.Name, t.Live, t.Dead, t.VacuumAt, orNever(t.LastVacuum), orNever(t.LastAnalyze), note)
+		}
+		if len(never) > 0 {
+			_, _ = fmt.Fprintf(o.output, "\nWarning: autovacuum will never process %s under the current settings\n", strings.Join(never, ", "))
+		}
+	}
+
+	_, _ = fmt.Fprintln(o.output, "\nRunning autovacuum workers:")
+	if len(workers) == 0 {
+		_, _ = fmt.Fprintln(o.output, "  (none)")
+	}
+	for _, row := range workers {
+		fields := strings.SplitN(row, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		_, _ = fmt.Fprintf(o.output, "  pid %s in %s for %s: %s\n", fields[0], fields[1], fields[2], fields[3])
+	}
+}
+
+// parseAutovacuumSettings reads name/setting rows from pg_settings.
+func parseAutovacuumSettings(rows []string) autovacuumSettings {
+	settings := autovacuumSettings{
+		Enabled:            true,
+		TrackCounts:        true,
+		VacuumThreshold:    50,
+		VacuumScaleFactor:  0.2,
+		AnalyzeThreshold:   50,
+		AnalyzeScaleFactor: 0.1,
+	}
+	for _, row := range rows {
+		fields := strings.Split(row, "\t")
+		if len(fields) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(fields[1])
+		number, _ := strconv.ParseFloat(value, 64)
+		switch fields[0] {
+		case "autovacuum":
+			settings.Enabled = value == "on"
+		case "track_counts":
+			settings.TrackCounts = value == "on"
+		case "autovacuum_vacuum_threshold":
+			settings.VacuumThreshold = number
+		case "autovacuum_vacuum_scale_factor":
+			settings.VacuumScaleFactor = number
+		case "autovacuum_analyze_threshold":
+			settings.AnalyzeThreshold = number
+		case "autovacuum_analyze_scale_factor":
+			settings.AnalyzeScaleFactor = number
+		}
+	}
+	return settings
+}
+
+// parseTableVacuumStatus parses one row of tableVacuumQuery, applying any
+// per-table autovacuum reloptions on top of the server settings.
+func parseTableVacuumStatus(row string, settings autovacuumSettings) (tableVacuumStatus, bool) {
+	fields := strings.Split(row, "\t")
+	if len(fields) != 8 {
+		return tableVacuumStatus{}, false
+	}
+	reltuples := float64(parseSize(fields[4]))
+
+	vacuumThreshold, vacuumScale := settings.VacuumThreshold, settings.VacuumScaleFactor
+	analyzeThreshold, analyzeScale := settings.AnalyzeThreshold, settings.AnalyzeScaleFactor
+	tableEnabled := true
+	for _, option := range strings.Split(fields[5], ",") {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			continue
+		}
+		number, _ := strconv.ParseFloat(value, 64)
+		switch key {
+		case "autovacuum_enabled":
+			tableEnabled = value != "false" && value != "off"
+		case "autovacuum_vacuum_threshold":
+			vacuumThreshold = number
+		case "autovacuum_vacuum_scale_factor":
+			vacuumScale = number
+		case "autovacuum_analyze_threshold":
+			analyzeThreshold = number
+		case "autovacuum_analyze_scale_factor":
+			analyzeScale = number
+		}
+	}
+
+	status := tableVacuumStatus{
+		Name:            fields[0],
+		Live:            parseSize(fields[1]),
+		Dead:            parseSize(fields[2]),
+		ModSinceAnalyze: parseSize(fields[3]),
+		VacuumAt:        int64(vacuumThreshold + vacuumScale*reltuples),
+		AnalyzeAt:       int64(analyzeThreshold + analyzeScale*reltuples),
+		LastVacuum:      fields[6],
+		LastAnalyze:     fields[7],
+	}
+	switch {
+	case !settings.TrackCounts:
+		status.AutovacuumOffFor = "track_counts is off"
+	case !settings.Enabled:
+		status.AutovacuumOffFor = "autovacuum is off"
+	case !tableEnabled:
+		status.AutovacuumOffFor = "autovacuum_enabled=false on table"
+	}
+	return status, true
+}
+
+// orNever returns s, or "never" when s is empty.
+func orNever(s string) string {
+	if s == "" {
+		return "never"
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVacuumStatusOrchestrator_Report(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		query := command[len(command)-1]
+		switch {
+		case strings.Contains(query, "pg_settings"):
+			return "autovacuum\ton\ntrack_counts\ton\nautovacuum_vacuum_threshold\t50\n" +
+				"autovacuum_vacuum_scale_factor\t0.2\nautovacuum_analyze_threshold\t50\nautovacuum_analyze_scale_factor\t0.1\n", nil
+		case strings.Contains(query, "pg_stat_user_tables"):
+			return "public.events\t1000\t400\t10\t1000\t\t2025-01-02 03:04\t\n" +
+				"public.audit\t5000\t20\t0\t5000\tautovacuum_enabled=false\t\t\n" +
+				"public.small\t100\t5\t200\t100\tautovacuum_vacuum_threshold=1000\t\t2025-01-02 03:04\n", nil
+		case strings.Contains(query, "autovacuum worker"):
+			return "4242\tapp\t00:00:05\tautovacuum: VACUUM public.events\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewVacuumStatusOrchestrator(mock, &buf)
+	err := orch.Run(t.Context(), VacuumStatusConfig{ContainerName: "my-postgres", Database: "app", Top: 20})
+
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "Autovacuum: on (vacuum at 50 + 20% of rows, analyze at 50 + 10% of rows)")
+	// 50 + 0.2 * 1000 = 250 dead tuples triggers a vacuum
+	assert.Regexp(t, `public\.events\s+1000\s+400\s+250\s+2025-01-02 03:04\s+never\s+vacuum due`, out)
+	assert.Contains(t, out, "never autovacuumed: autovacuum_enabled=false on table")
+	assert.Regexp(t, `public\.small\s+100\s+5\s+1020 .*analyze due`, out)
+	assert.Contains(t, out, "Warning: autovacuum will never process public.audit")
+	assert.Contains(t, out, "pid 4242 in app for 00:00:05: autovacuum: VACUUM public.events")
+}
+
+func TestVacuumStatusOrchestrator_AutovacuumOff(t *testing.T) {
+	settings := parseAutovacuumSettings([]string{"autovacuum\toff"})
+	table, ok := parseTableVacuumStatus("public.t\t1\t1\t1\t1\t\t\t", settings)
+
+	require.True(t, ok)
+	assert.Equal(t, "autovacuum is off", table.AutovacuumOffFor)
+}
@@ -0,0 +1,345 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/logging"
+)
+
+// volumeHelperImage runs tar next to a volume. It is fully qualified so every
+// runtime resolves it without short-name prompts.
+const volumeHelperImage = "docker.io/library/busybox:1.36"
+
+// volumeProgressStep is how many bytes pass between progress lines.
+const volumeProgressStep = 64 << 20
+
+// VolumeExportConfig holds configuration for exporting a data volume.
+type VolumeExportConfig struct {
+	Target string // Container name or volume name
+	Output string // Archive path on the host (default: <volume>-<timestamp>.tar.gz)
+}
+
+// VolumeImportConfig holds configuration for importing a data volume.
+type VolumeImportConfig struct {
+	Input  string // Archive path on the host
+	Target string // Container name or volume name
+	Force  bool   // Replace the contents of a non-empty volume
+}
+
+// VolumeOrchestrator lists, inspects and removes pgbox data volumes, and
+// copies them to and from the host as tar streams through a helper
+// container, so it works when the daemon runs in a VM (Colima, Docker
+// Desktop, podman machine) and volume paths are not visible on the host.
+type VolumeOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+	input  io.Reader
+}
+
+// NewVolumeOrchestrator creates a new VolumeOrchestrator.
+func NewVolumeOrchestrator(d docker.Docker, w io.Writer, r io.Reader) *VolumeOrchestrator {
+	return &VolumeOrchestrator{docker: d, output: w, input: r}
+}
+
+// Export writes the volume to a gzip-compressed tar archive plus a
+// <archive>.sha256 checksum file, then verifies the archive reads back.
+func (o *VolumeOrchestrator) Export(ctx context.Context, cfg VolumeExportConfig) error {
+	volume, err := o.findVolume(ctx, cfg.Target)
+	if err != nil {
+		return err
+	}
+	if err := o.ensureStopped(ctx, volume); err != nil {
+		return err
+	}
+
+	output := cfg.Output
+	if output == "" {
+		output = fmt.Sprintf("%s-%s.tar.gz", volume, time.Now().Format("20060102-150405"))
+	}
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists; choose another output path or remove it first", output)
+	}
+
+	logging.Infof(o.output, "Exporting volume %s to %s...", volume, output)
+	size, entries, sum, err := o.archiveVolume(ctx, volume, output)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Exported %s (%d files, %s)\n", output, entries, formatBytes(size))
+	_, _ = fmt.Fprintf(o.output, "sha256 %s written to %s.sha256\n", sum, output)
+	return nil
+}
+
+// archiveVolume writes the volume to output as a verified tar.gz with a
+// .sha256 file next to it. It returns the archive size, entry count and checksum.
+func (o *VolumeOrchestrator) archiveVolume(ctx context.Context, volume, output string) (int64, int, string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(output), ".pgbox-volume-*")
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	size, sum, runErr := o.exportVolume(ctx, volume, tmp)
+	closeErr := tmp.Close()
+	if runErr != nil {
+		return 0, 0, "", runErr
+	}
+	if closeErr != nil {
+		return 0, 0, "", fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+
+	entries, err := verifyArchive(tmp.Name())
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("archive verification failed: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return 0, 0, "", fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	if err := writeChecksumFile(output, sum); err != nil {
+		return 0, 0, "", err
+	}
+	return size, entries, sum, nil
+}
+
+// Import restores an archive created by Export into the volume, creating the
+// volume if needed. The archive is checked against its .sha256 file (when
+// present) and read through once before the volume is touched.
+func (o *VolumeOrchestrator) Import(ctx context.Context, cfg VolumeImportConfig) error {
+	if err := verifyChecksumFile(cfg.Input); err != nil {
+		return err
+	}
+	if _, err := verifyArchive(cfg.Input); err != nil {
+		return fmt.Errorf("archive verification failed: %w", err)
+	}
+
+	volume := volumeName(cfg.Target)
+	if err := o.ensureStopped(ctx, volume); err != nil {
+		return err
+	}
+
+	logging.Infof(o.output, "Importing %s into volume %s...", cfg.Input, volume)
+	size, err := o.restoreVolume(ctx, volume, cfg.Input, cfg.Force)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(o.output, "Imported %s into %s\n", formatBytes(size), volume)
+	_, _ = fmt.Fprintf(o.output, "Start it with: pgbox up -n %s\n", strings.TrimSuffix(volume, "-data"))
+	return nil
+}
+
+// restoreVolume extracts a verified archive into the volume, creating the
+// volume if needed. A non-empty volume is cleared first only when force is set.
+func (o *VolumeOrchestrator) restoreVolume(ctx context.Context, volume, input string, force bool) (int64, error) {
+	if _, err := o.docker.RunCommandWithOutput(ctx, "volume", "inspect", volume); err != nil {
+		if out, err := o.docker.RunCommandWithOutput(ctx, "volume", "create", volume); err != nil {
+			return 0, fmt.Errorf("failed to create volume %s: %s: %w", volume, strings.TrimSpace(out), err)
+		}
+	} else {
+		contents, err := o.docker.RunCommandWithOutput(ctx, "run", "--rm", "-v", volume+":/to", volumeHelperImage, "ls", "-A", "/to")
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect volume %s: %s: %w", volume, strings.TrimSpace(contents), err)
+		}
+		if strings.TrimSpace(contents) != "" {
+			if !force {
+				return 0, fmt.Errorf("volume %s is not empty; use --force to replace its contents", volume)
+			}
+			if out, err := o.docker.RunCommandWithOutput(ctx, "run", "--rm", "-v", volume+":/to", volumeHelperImage,
+				"find", "/to", "-mindepth", "1", "-delete"); err != nil {
+				return 0, fmt.Errorf("failed to clear volume %s: %s: %w", volume, strings.TrimSpace(out), err)
+			}
+		}
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return o.importVolume(ctx, volume, f)
+}
+
+// exportVolume streams the volume as a tar.gz to w and returns the number of
+// bytes written and their sha256.
+func (o *VolumeOrchestrator) exportVolume(ctx context.Context, volume string, w io.Writer) (int64, string, error) {
+	hash := sha256.New()
+	progress := &progressWriter{output: o.output}
+	var stderr strings.Builder
+	err := o.docker.RunCommandWithIO(ctx, nil, io.MultiWriter(w, hash, progress), &stderr,
+		"run", "--rm", "-v", volume+":/from:ro", volumeHelperImage, "tar", "czf", "-", "-C", "/from", ".")
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to export volume %s: %s: %w", volume, strings.TrimSpace(stderr.String()), err)
+	}
+	return progress.total, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// importVolume extracts a tar.gz read from r into the volume and returns the
+// number of bytes read.
+func (o *VolumeOrchestrator) importVolume(ctx context.Context, volume string, r io.Reader) (int64, error) {
+	progress := &progressWriter{output: o.output}
+	var stderr strings.Builder
+	err := o.docker.RunCommandWithIO(ctx, io.TeeReader(r, progress), io.Discard, &stderr,
+		"run", "--rm", "-i", "-v", volume+":/to", volumeHelperImage, "tar", "xzf", "-", "-C", "/to")
+	if err != nil {
+		return 0, fmt.Errorf("failed to import into volume %s: %s: %w", volume, strings.TrimSpace(stderr.String()), err)
+	}
+	return progress.total, nil
+}
+
+// findVolume resolves target to an existing volume, accepting either the
+// volume name or the name of the container that owns it.
+func (o *VolumeOrchestrator) findVolume(ctx context.Context, target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("a container or volume name is required")
+	}
+	candidates := []string{target}
+	if !strings.HasSuffix(target, "-data") {
+		candidates = append(candidates, target+"-data")
+	}
+	for _, candidate := range candidates {
+		if _, err := o.docker.RunCommandWithOutput(ctx, "volume", "inspect", candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no volume named %s", strings.Join(candidates, " or "))
+}
+
+// ensureStopped refuses to copy a volume while its pgbox container is running,
+// since a live data directory would not be a consistent copy.
+func (o *VolumeOrchestrator) ensureStopped(ctx context.Context, volume string) error {
+	container := strings.TrimSuffix(volume, "-data")
+	running, err := o.docker.IsContainerRunning(ctx, container)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if running {
+		return fmt.Errorf("container %s is using volume %s. Stop it first with: pgbox down -n %s", container, volume, container)
+	}
+	return nil
+}
+
+// volumeName returns the data volume for target, which may be a container
+// name or a volume name.
+func volumeName(target string) string {
+	if strings.HasSuffix(target, "-data") {
+		return target
+	}
+	return target + "-data"
+}
+
+// verifyArchive reads a tar.gz archive to the end and returns the number of
+// entries, catching truncated or corrupt archives.
+func verifyArchive(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	entries := 0
+	for {
+		_, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return entries, err
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return entries, err
+		}
+		entries++
+	}
+	if entries == 0 {
+		return 0, fmt.Errorf("archive is empty")
+	}
+	return entries, nil
+}
+
+// writeChecksumFile writes path.sha256 in the format read by sha256sum -c.
+func writeChecksumFile(path, sum string) error {
+	content := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write checksum: %w", err)
+	}
+	return nil
+}
+
+// verifyChecksumFile compares path against path.sha256 when that file exists.
+func verifyChecksumFile(path string) error {
+	content, err := os.ReadFile(path + ".sha256")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checksum: %w", err)
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return fmt.Errorf("%s.sha256 is empty", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != fields[0] {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, fields[0], got)
+	}
+	return nil
+}
+
+// progressWriter counts bytes and prints a line every volumeProgressStep.
+type progressWriter struct {
+	output io.Writer
+	total  int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	before := p.total / volumeProgressStep
+	p.total += int64(len(b))
+	if p.total/volumeProgressStep > before {
+		_, _ = fmt.Fprintf(p.output, "  %s transferred\n", formatBytes(p.total))
+	}
+	return len(b), nil
+}
+
+// formatBytes formats a byte count using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,307 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// volumeArchive builds a tar.gz containing the given files.
+func volumeArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestVolumeOrchestrator_Export(t *testing.T) {
+	archive := volumeArchive(t, map[string]string{"./PG_VERSION": "17\n", "./base/1/1259": "data"})
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "volume" && args[2] != "pgbox-pg17-data" {
+			return "Error: no such volume", errors.New("exit status 1")
+		}
+		return "", nil
+	}
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		_, err := stdout.Write(archive)
+		return err
+	}
+	output := filepath.Join(t.TempDir(), "pg17.tar.gz")
+	var buf bytes.Buffer
+
+	orch := NewVolumeOrchestrator(mock, &buf, nil)
+	err := orch.Export(t.Context(), VolumeExportConfig{Target: "pgbox-pg17", Output: output})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pgbox-pg17"}, mock.Calls.IsContainerRunning)
+	assert.Equal(t, []string{"run", "--rm", "-v", "pgbox-pg17-data:/from:ro", volumeHelperImage, "tar", "czf", "-", "-C", "/from", "."},
+		mock.Calls.RunCommandWithIO[0])
+
+	written, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, archive, written)
+	checksum, err := os.ReadFile(output + ".sha256")
+	require.NoError(t, err)
+	assert.Contains(t, string(checksum), "  pg17.tar.gz\n")
+	assert.NoError(t, verifyChecksumFile(output))
+	assert.Contains(t, buf.String(), "(2 files,")
+}
+
+func TestVolumeOrchestrator_ExportRefusesRunningContainer(t *testing.T) {
+	mock := docker.NewMockDocker()
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+	var buf bytes.Buffer
+
+	orch := NewVolumeOrchestrator(mock, &buf, nil)
+	err := orch.Export(t.Context(), VolumeExportConfig{Target: "pgbox-pg17-data", Output: filepath.Join(t.TempDir(), "x.tar.gz")})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Stop it first with: pgbox down -n pgbox-pg17")
+	assert.Empty(t, mock.Calls.RunCommandWithIO)
+}
+
+func TestVolumeOrchestrator_ExportRejectsCorruptStream(t *testing.T) {
+	archive := volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"})
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		_, err := stdout.Write(archive[:len(archive)/2])
+		return err
+	}
+	output := filepath.Join(t.TempDir(), "pg17.tar.gz")
+	var buf bytes.Buffer
+
+	orch := NewVolumeOrchestrator(mock, &buf, nil)
+	err := orch.Export(t.Context(), VolumeExportConfig{Target: "pgbox-pg17-data", Output: output})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "archive verification failed")
+	assert.NoFileExists(t, output)
+}
+
+func TestVolumeOrchestrator_ImportCreatesVolume(t *testing.T) {
+	archive := volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"})
+	input := filepath.Join(t.TempDir(), "pg17.tar.gz")
+	require.NoError(t, os.WriteFile(input, archive, 0644))
+
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "volume" && args[1] == "inspect" {
+			return "Error: no such volume", errors.New("exit status 1")
+		}
+		return "", nil
+	}
+	var received []byte
+	mock.RunCommandWithIOFunc = func(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+		var err error
+		received, err = io.ReadAll(stdin)
+		return err
+	}
+	var buf bytes.Buffer
+
+	orch := NewVolumeOrchestrator(mock, &buf, nil)
+	err := orch.Import(t.Context(), VolumeImportConfig{Input: input, Target: "pgbox-pg17"})
+
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "create", "pgbox-pg17-data"})
+	assert.Equal(t, []string{"run", "--rm", "-i", "-v", "pgbox-pg17-data:/to", volumeHelperImage, "tar", "xzf", "-", "-C", "/to"},
+		mock.Calls.RunCommandWithIO[0])
+	assert.Equal(t, archive, received)
+	assert.Contains(t, buf.String(), "pgbox up -n pgbox-pg17")
+}
+
+func TestVolumeOrchestrator_ImportRefusesNonEmptyVolume(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "pg17.tar.gz")
+	require.NoError(t, os.WriteFile(input, volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"}), 0644))
+
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		if args[0] == "run" {
+			return "PG_VERSION\nbase\n", nil
+		}
+		return "", nil
+	}
+	var buf bytes.Buffer
+
+	orch := NewVolumeOrchestrator(mock, &buf, nil)
+	err := orch.Import(t.Context(), VolumeImportConfig{Input: input, Target: "pgbox-pg17"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "use --force")
+	assert.Empty(t, mock.Calls.RunCommandWithIO)
+
+	err = orch.Import(t.Context(), VolumeImportConfig{Input: input, Target: "pgbox-pg17", Force: true})
+	require.NoError(t, err)
+	assert.Contains(t, mock.Calls.RunCommandWithOutput,
+		[]string{"run", "--rm", "-v", "pgbox-pg17-data:/to", volumeHelperImage, "find", "/to", "-mindepth", "1", "-delete"})
+}
+
+func TestVolumeOrchestrator_ImportChecksumMismatch(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "pg17.tar.gz")
+	require.NoError(t, os.WriteFile(input, volumeArchive(t, map[string]string{"./PG_VERSION": "17\n"}), 0644))
+	require.NoError(t, writeChecksumFile(input, "0000"))
+
+	mock := docker.NewMockDocker()
+	var buf bytes.Buffer
+
+	orch := NewVolumeOrchestrator(mock, &buf, nil)
+	err := orch.Import(t.Context(), VolumeImportConfig{Input: input, Target: "pgbox-pg17"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+	assert.Empty(t, mock.Calls.RunCommandWithOutput)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "64.0 MiB", formatBytes(64<<20))
+}
+
+// volumeListMock returns a mock with a pgbox volume for a stopped pgvector
+// container, one whose container is gone, and a volume of something else.
+func volumeListMock() *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		switch {
+		case args[0] == "volume" && args[1] == "ls":
+			return "pgbox-pg16-0123456789abcdef-data\npgbox-pg17-data\nother-data\n", nil
+		case args[0] == "volume" && args[1] == "inspect" && args[2] == "-f":
+			return "2026-10-01T09:00:00Z\t/var/lib/docker/volumes/pgbox-pg16-0123456789abcdef-data/_data\n", nil
+		case args[0] == "volume" && args[1] == "inspect":
+			if args[2] != "pgbox-pg16-0123456789abcdef-data" && args[2] != "pgbox-pg17-data" {
+				return "", errors.New("no such volume")
+			}
+		case args[0] == "ps":
+			return "pgbox-pg16-0123456789abcdef\texited\nunrelated\trunning\n", nil
+		case args[0] == "inspect" && args[2] == "{{json .Config.Labels}}":
+			return `{"dev.pgbox.postgres-version":"16","dev.pgbox.extensions":"pgvector","dev.pgbox.extension-hash":"0123456789abcdef"}`, nil
+		case args[0] == "inspect" && args[2] == "{{.Config.Image}}":
+			return "pgbox-pg16-custom:0123456789abcdef\n", nil
+		case args[0] == "system":
+			return "Images space usage:\n\nREPOSITORY   TAG   SIZE\npostgres     17    438MB\n\n" +
+				"Local Volumes space usage:\n\nVOLUME NAME                        LINKS     SIZE\n" +
+				"pgbox-pg16-0123456789abcdef-data   1         41.2MB\nother-data   0   0B\n\n" +
+				"Build cache usage: 0B\n", nil
+		case args[0] == "run":
+			return "2048\t/v\n", nil
+		}
+		return "", nil
+	}
+	return mock
+}
+
+func TestVolumeOrchestrator_List(t *testing.T) {
+	mock := volumeListMock()
+	var buf bytes.Buffer
+
+	err := NewVolumeOrchestrator(mock, &buf, nil).List(t.Context(), VolumeListConfig{JSON: true})
+
+	require.NoError(t, err)
+	var volumes []VolumeInfo
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &volumes))
+	assert.Equal(t, []VolumeInfo{
+		{Name: "pgbox-pg16-0123456789abcdef-data", SizeBytes: 41200000, Container: "pgbox-pg16-0123456789abcdef", State: "exited",
+			PostgresVersion: "16", ExtensionHash: "0123456789abcdef", Extensions: []string{"pgvector"}, Image: "pgbox-pg16-custom:0123456789abcdef"},
+		{Name: "pgbox-pg17-data", SizeBytes: 2048 * 1024, Container: "pgbox-pg17", State: "missing", PostgresVersion: "17", Image: "postgres:17"},
+	}, volumes)
+	assert.Equal(t, [][]string{{"run", "--rm", "-v", "pgbox-pg17-data:/v:ro", volumeHelperImage, "du", "-sk", "/v"}},
+		slices.DeleteFunc(slices.Clone(mock.Calls.RunCommandWithOutput), func(call []string) bool { return call[0] != "run" }),
+		"only the volume system df leaves out is measured")
+
+	buf.Reset()
+	require.NoError(t, NewVolumeOrchestrator(mock, &buf, nil).List(t.Context(), VolumeListConfig{}))
+	assert.Contains(t, buf.String(), "VOLUME")
+	assert.Contains(t, buf.String(), "39.3 MiB")
+}
+
+func TestVolumeOrchestrator_ListEmpty(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := NewVolumeOrchestrator(docker.NewMockDocker(), &buf, nil).List(t.Context(), VolumeListConfig{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "No pgbox volumes found.\n", buf.String())
+}
+
+func TestVolumeOrchestrator_Inspect(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := NewVolumeOrchestrator(volumeListMock(), &buf, nil).Inspect(t.Context(), VolumeInspectConfig{Target: "pgbox-pg16-0123456789abcdef"})
+
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "Volume:      pgbox-pg16-0123456789abcdef-data\n")
+	assert.Contains(t, out, "Container:   pgbox-pg16-0123456789abcdef (exited)\n")
+	assert.Contains(t, out, "Extensions:  pgvector\n")
+	assert.Contains(t, out, "Created:     2026-10-01T09:00:00Z\n")
+
+	err = NewVolumeOrchestrator(volumeListMock(), &buf, nil).Inspect(t.Context(), VolumeInspectConfig{Target: "pgbox-pg15"})
+	assert.EqualError(t, err, "no volume named pgbox-pg15 or pgbox-pg15-data")
+}
+
+func TestVolumeOrchestrator_Remove(t *testing.T) {
+	t.Run("removes the stopped container with its volume", func(t *testing.T) {
+		mock := volumeListMock()
+		var buf bytes.Buffer
+
+		err := NewVolumeOrchestrator(mock, &buf, strings.NewReader("y\n")).Remove(t.Context(), VolumeRemoveConfig{
+			Targets: []string{"pgbox-pg16-0123456789abcdef", "pgbox-pg17-data"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pgbox-pg16-0123456789abcdef"}, mock.Calls.RemoveContainer)
+		assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg16-0123456789abcdef-data"})
+		assert.Contains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg17-data"})
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		mock := volumeListMock()
+		var buf bytes.Buffer
+
+		err := NewVolumeOrchestrator(mock, &buf, strings.NewReader("n\n")).Remove(t.Context(), VolumeRemoveConfig{Targets: []string{"pgbox-pg17"}})
+
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Removal cancelled.")
+		assert.NotContains(t, mock.Calls.RunCommandWithOutput, []string{"volume", "rm", "pgbox-pg17-data"})
+	})
+
+	t.Run("refuses a running container's volume", func(t *testing.T) {
+		mock := volumeListMock()
+		mock.IsContainerRunningFunc = func(name string) (bool, error) { return true, nil }
+
+		err := NewVolumeOrchestrator(mock, &bytes.Buffer{}, nil).Remove(t.Context(), VolumeRemoveConfig{Targets: []string{"pgbox-pg17"}, Force: true})
+
+		assert.EqualError(t, err, "container pgbox-pg17 is using volume pgbox-pg17-data. Stop it first with: pgbox down -n pgbox-pg17")
+		assert.Empty(t, mock.Calls.RemoveContainer)
+	})
+}
+
+func TestParseHumanSize(t *testing.T) {
+	for input, want := range map[string]int64{"0B": 0, "512B": 512, "1.5kB": 1500, "41.2MB": 41200000, "2GB": 2000000000} {
+		got, ok := parseHumanSize(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, ok := parseHumanSize("N/A")
+	assert.False(t, ok)
+}
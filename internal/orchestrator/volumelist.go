@@ -0,0 +1,306 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/container"
+)
+
+// VolumeListConfig holds configuration for listing pgbox data volumes.
+type VolumeListConfig struct {
+	JSON bool // Print the volumes as JSON
+}
+
+// VolumeInspectConfig holds configuration for showing one data volume.
+type VolumeInspectConfig struct {
+	Target string // Container name or volume name
+	JSON   bool   // Print the volume as JSON
+}
+
+// VolumeRemoveConfig holds configuration for removing data volumes.
+type VolumeRemoveConfig struct {
+	Targets []string // Container or volume names
+	Force   bool     // Skip the confirmation prompt
+}
+
+// VolumeInfo describes a pgbox data volume and the container it belongs to.
+type VolumeInfo struct {
+	Name            string   `json:"name"`
+	SizeBytes       int64    `json:"size_bytes"` // -1 when it could not be measured
+	Container       string   `json:"container"`
+	State           string   `json:"state"` // The container's state, or "missing" when it was removed
+	PostgresVersion string   `json:"postgres_version,omitempty"`
+	ExtensionHash   string   `json:"extension_hash,omitempty"`
+	Extensions      []string `json:"extensions,omitempty"`
+	Image           string   `json:"image,omitempty"`
+	Replica         bool     `json:"replica"`
+	Created         string   `json:"created,omitempty"`
+	Mountpoint      string   `json:"mountpoint,omitempty"` // Inside the VM when the daemon runs in one
+}
+
+// containerStateMissing is the state of a volume whose container is gone.
+const containerStateMissing = "missing"
+
+// volumeNamePattern matches the data volume of a container pgbox named
+// itself: pgbox-pg<version>, a hash of its extensions, and the replica suffix.
+var volumeNamePattern = regexp.MustCompile(`^pgbox-pg(\d+)(?:-([0-9a-f]{16}))?(-replica)?-data$`)
+
+// List prints the pgbox data volumes with their size and the container,
+// version and image each belongs to.
+func (o *VolumeOrchestrator) List(ctx context.Context, cfg VolumeListConfig) error {
+	output, err := o.docker.RunCommandWithOutput(ctx, "volume", "ls", "--format", "{{.Name}}")
+	if err != nil {
+		return fmt.Errorf("failed to list volumes: %w", err)
+	}
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if strings.HasPrefix(line, "pgbox-") && strings.HasSuffix(line, "-data") {
+			names = append(names, line)
+		}
+	}
+	slices.Sort(names)
+
+	states, err := o.containerStates(ctx)
+	if err != nil {
+		return err
+	}
+	sizes := o.volumeSizes(ctx, names)
+	volumes := []VolumeInfo{}
+	for _, name := range names {
+		info := o.describeVolume(ctx, name, states)
+		info.SizeBytes = sizes[name]
+		volumes = append(volumes, info)
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(o.output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(volumes)
+	}
+	if len(volumes) == 0 {
+		_, _ = fmt.Fprintln(o.output, "No pgbox volumes found.")
+		return nil
+	}
+	_, _ = fmt.Fprintf(o.output, "%-36s %10s  %-30s %-10s %-8s %s\n", "VOLUME", "SIZE", "CONTAINER", "STATE", "VERSION", "IMAGE")
+	for _, v := range volumes {
+		_, _ = fmt.Fprintf(o.output, "%-36s %10s  %-30s %-10s %-8s %s\n",
+			v.Name, volumeSize(v.SizeBytes), v.Container, v.State, v.PostgresVersion, v.Image)
+	}
+	return nil
+}
+
+// Inspect prints what a data volume holds and which container and image it
+// belongs to.
+func (o *VolumeOrchestrator) Inspect(ctx context.Context, cfg VolumeInspectConfig) error {
+	volume, err := o.findVolume(ctx, cfg.Target)
+	if err != nil {
+		return err
+	}
+	states, err := o.containerStates(ctx)
+	if err != nil {
+		return err
+	}
+	info := o.describeVolume(ctx, volume, states)
+	info.SizeBytes = o.volumeSizes(ctx, []string{volume})[volume]
+	if output, err := o.docker.RunCommandWithOutput(ctx, "volume", "inspect", "-f", "{{.CreatedAt}}\t{{.Mountpoint}}", volume); err == nil {
+		info.Created, info.Mountpoint, _ = strings.Cut(strings.TrimSpace(output), "\t")
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(o.output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	field := func(label, value string) {
+		if value != "" {
+			_, _ = fmt.Fprintf(o.output, "%-12s %s\n", label+":", value)
+		}
+	}
+	field("Volume", info.Name)
+	field("Size", volumeSize(info.SizeBytes))
+	field("Container", fmt.Sprintf("%s (%s)", info.Container, info.State))
+	if info.Replica {
+		field("Replica of", strings.TrimSuffix(info.Container, "-replica"))
+	}
+	field("PostgreSQL", info.PostgresVersion)
+	field("Image", info.Image)
+	field("Extensions", strings.Join(info.Extensions, ", "))
+	field("Ext. hash", info.ExtensionHash)
+	field("Created", info.Created)
+	field("Mountpoint", info.Mountpoint)
+	return nil
+}
+
+// Remove deletes data volumes, after asking unless cfg.Force is set. A
+// volume in use by a running container is refused; a stopped container that
+// owns one is removed with it, since it cannot start without its data.
+func (o *VolumeOrchestrator) Remove(ctx context.Context, cfg VolumeRemoveConfig) error {
+	if len(cfg.Targets) == 0 {
+		return fmt.Errorf("a container or volume name is required")
+	}
+	states, err := o.containerStates(ctx)
+	if err != nil {
+		return err
+	}
+	var plan removalPlan
+	for _, target := range cfg.Targets {
+		volume, err := o.findVolume(ctx, target)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(plan.volumes, volume) {
+			continue
+		}
+		if err := o.ensureStopped(ctx, volume); err != nil {
+			return err
+		}
+		if c := strings.TrimSuffix(volume, "-data"); states[c] != "" {
+			plan.containers = append(plan.containers, c)
+		}
+		plan.volumes = append(plan.volumes, volume)
+	}
+
+	plan.print(o.output)
+	if !cfg.Force {
+		ok, err := confirm(o.output, o.input, fmt.Sprintf("\nAre you sure you want to remove these %s? (y/N): ", joinAnd(plan.kinds())))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			_, _ = fmt.Fprintln(o.output, "Removal cancelled.")
+			return nil
+		}
+	}
+	summary := plan.remove(ctx, o.docker, o.output)
+	if len(summary.Failed) > 0 {
+		return fmt.Errorf("failed to remove %d of the resources", len(summary.Failed))
+	}
+	return nil
+}
+
+// describeVolume returns what the volume's name and its container tell about
+// it. The container's labels record its version and extensions; for a volume
+// whose container is gone they come from the name pgbox gave it, when it did.
+func (o *VolumeOrchestrator) describeVolume(ctx context.Context, volume string, states map[string]string) VolumeInfo {
+	name := strings.TrimSuffix(volume, "-data")
+	info := VolumeInfo{Name: volume, SizeBytes: -1, Container: name, State: states[name], Replica: container.IsReplicaName(name)}
+	if info.State == "" {
+		info.State = containerStateMissing
+		if m := volumeNamePattern.FindStringSubmatch(volume); m != nil {
+			info.PostgresVersion, info.ExtensionHash = m[1], m[2]
+			info.Image = "postgres:" + m[1]
+			if m[2] != "" {
+				info.Image = fmt.Sprintf("pgbox-pg%s-custom:%s", m[1], m[2])
+			}
+		}
+		return info
+	}
+
+	labels := containerLabels(ctx, o.docker, name)
+	info.PostgresVersion = labels[container.LabelPostgresVersion]
+	info.ExtensionHash = labels[container.LabelExtensionHash]
+	info.Extensions = container.ParseExtensionsLabel(labels[container.LabelExtensions])
+	if output, err := o.docker.RunCommandWithOutput(ctx, "inspect", "-f", "{{.Config.Image}}", name); err == nil {
+		info.Image = strings.TrimSpace(output)
+	}
+	if info.PostgresVersion == "" {
+		info.PostgresVersion = imageMajor(info.Image)
+	}
+	return info
+}
+
+// containerStates returns the state of every container, running or not, by
+// name.
+func (o *VolumeOrchestrator) containerStates(ctx context.Context) (map[string]string, error) {
+	output, err := o.docker.RunCommandWithOutput(ctx, "ps", "-a", "--format", "{{.Names}}\t{{.State}}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	states := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if name, state, ok := strings.Cut(line, "\t"); ok {
+			states[name] = state
+		}
+	}
+	return states, nil
+}
+
+// volumeSizes returns the size of each volume from the runtime's system df
+// report, measuring those it leaves out with the helper container. A volume
+// that cannot be measured either way is -1.
+func (o *VolumeOrchestrator) volumeSizes(ctx context.Context, volumes []string) map[string]int64 {
+	sizes := make(map[string]int64)
+	if output, err := o.docker.RunCommandWithOutput(ctx, "system", "df", "-v"); err == nil {
+		sizes = parseVolumeUsage(output)
+	}
+	for _, volume := range volumes {
+		if _, ok := sizes[volume]; ok {
+			continue
+		}
+		sizes[volume] = -1
+		output, err := o.docker.RunCommandWithOutput(ctx, "run", "--rm", "-v", volume+":/v:ro", volumeHelperImage, "du", "-sk", "/v")
+		if fields := strings.Fields(output); err == nil && len(fields) > 0 {
+			if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+				sizes[volume] = n * 1024
+			}
+		}
+	}
+	return sizes
+}
+
+// parseVolumeUsage reads the volume sizes from the "Local Volumes space
+// usage" section of system df -v, whose rows end with a size such as 41.2MB.
+func parseVolumeUsage(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	section := false
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Local Volumes space usage"):
+			section = true
+			continue
+		case strings.HasSuffix(strings.TrimSpace(line), "space usage:"):
+			section = false
+		}
+		fields := strings.Fields(line)
+		if !section || len(fields) < 3 || fields[0] == "VOLUME" {
+			continue
+		}
+		if n, ok := parseHumanSize(fields[len(fields)-1]); ok {
+			sizes[fields[0]] = n
+		}
+	}
+	return sizes
+}
+
+// humanSizeUnits are the decimal units the container runtimes print sizes in.
+var humanSizeUnits = map[string]float64{"B": 1, "kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12, "PB": 1e15}
+
+// humanSizePattern matches a size such as 0B, 1.5kB or 41.2MB.
+var humanSizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)([kKMGTP]?B)$`)
+
+// parseHumanSize parses a size printed by the container runtime.
+func parseHumanSize(s string) (int64, bool) {
+	m := humanSizePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(n * humanSizeUnits[m[2]]), true
+}
+
+// volumeSize formats a volume size, which is -1 when unknown.
+func volumeSize(n int64) string {
+	if n < 0 {
+		return "?"
+	}
+	return formatBytes(n)
+}
@@ -0,0 +1,361 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/extensions"
+	"github.com/ahacop/pgbox/internal/model"
+)
+
+// WhyConfig holds configuration for the why command.
+type WhyConfig struct {
+	ContainerName string
+	Dir           string // Explain an exported scaffold instead of a container
+	Item          string // Setting, package, preload library or init.sql fragment
+}
+
+// WhyOrchestrator explains which extension, profile or flag introduced a
+// setting, package or init.sql fragment.
+type WhyOrchestrator struct {
+	docker docker.Docker
+	output io.Writer
+}
+
+// NewWhyOrchestrator creates a new WhyOrchestrator.
+func NewWhyOrchestrator(d docker.Docker, w io.Writer) *WhyOrchestrator {
+	return &WhyOrchestrator{docker: d, output: w}
+}
+
+// provenance is what pgbox put into a container or scaffold.
+type provenance struct {
+	where     string            // Container name or scaffold directory, for messages
+	version   string            // PostgreSQL major version, if known
+	gucs      map[string]string // Settings passed with -c, other than shared_preload_libraries
+	sources   map[string]string // Recorded source of each setting, when the scaffold has one
+	preload   []string
+	packages  []string
+	fragments []string // init.sql fragment names
+}
+
+const (
+	// initDirMountPath is the directory the postgres image runs init files
+	// from.
+	initDirMountPath = "/docker-entrypoint-initdb.d"
+	// initSQLMountPath is where pgbox up mounts the generated init.sql.
+	initSQLMountPath = initDirMountPath + "/init.sql"
+	// initSQLArtifact is the generated init.sql's name in the container's
+	// artifacts directory.
+	initSQLArtifact = "init.sql"
+	// postgresConfPgboxFile records each exported setting with its source.
+	postgresConfPgboxFile = "postgresql.conf.pgbox"
+)
+
+var (
+	imageVersionPattern = regexp.MustCompile(`(?:postgres:|pgbox-pg)(\d+)`)
+	pgMajorPattern      = regexp.MustCompile(`^ARG PG_MAJOR=(\d+)`)
+	aptPackagePattern   = regexp.MustCompile(`\bpostgresql-\d+-[a-z0-9.+-]+`)
+	initFragmentPattern = regexp.MustCompile(`^-- pgbox: begin (\S+)`)
+	confSettingPattern  = regexp.MustCompile(`^(\w[\w.]*) = (.*?)(?:  # (.+))?$`)
+)
+
+// Run prints why cfg.Item is present in the container or scaffold.
+func (o *WhyOrchestrator) Run(ctx context.Context, cfg WhyConfig) error {
+	var p *provenance
+	var err error
+	if cfg.Dir != "" {
+		p, err = scaffoldProvenance(cfg.Dir)
+	} else {
+		p, err = o.containerProvenance(ctx, cfg.ContainerName)
+	}
+	if err != nil {
+		return err
+	}
+
+	lines := p.explain(cfg.Item)
+	if len(lines) == 0 {
+		return fmt.Errorf("%s was not introduced by pgbox in %s", cfg.Item, p.where)
+	}
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(o.output, line)
+	}
+	return nil
+}
+
+// containerProvenance reads the settings a container was started with and,
+// when it is running, its init.sql fragments and installed packages.
+func (o *WhyOrchestrator) containerProvenance(ctx context.Context, containerName string) (*provenance, error) {
+	name, _, err := ResolveContainerName(ctx, o.docker, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Start one with: pgbox up", err)
+	}
+	out, err := o.docker.RunCommandWithOutput(ctx, "inspect", "-f", "{{.Config.Image}}\t{{json .Config.Cmd}}", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %s: %w", name, strings.TrimSpace(out), err)
+	}
+	image, cmdJSON, _ := strings.Cut(strings.TrimSpace(out), "\t")
+	var command []string
+	if err := json.Unmarshal([]byte(cmdJSON), &command); err != nil {
+		return nil, fmt.Errorf("failed to read command of container %s: %w", name, err)
+	}
+	command, _ = stripSSLEntrypoint(command)
+
+	p := &provenance{where: name, gucs: make(map[string]string)}
+	if m := imageVersionPattern.FindStringSubmatch(image); m != nil {
+		p.version = m[1]
+	}
+	for i := 0; i+1 < len(command); i++ {
+		if command[i] == "-c" {
+			p.addSetting(command[i+1])
+			i++
+		}
+	}
+
+	if running, _ := o.docker.IsContainerRunning(ctx, name); running {
+		if initSQL, err := o.docker.ExecCommand(ctx, name, "cat", initSQLMountPath); err == nil {
+			p.addFragments(initSQL)
+		}
+		if list, err := o.docker.ExecCommand(ctx, name, "dpkg-query", "-W", "-f", "${Package}\n"); err == nil {
+			p.packages = aptPackagePattern.FindAllString(list, -1)
+		}
+	}
+	return p, nil
+}
+
+// scaffoldProvenance reads a directory written by pgbox export.
+func scaffoldProvenance(dir string) (*provenance, error) {
+	p := &provenance{where: dir, gucs: make(map[string]string), sources: make(map[string]string)}
+	found := false
+
+	if content, err := os.ReadFile(filepath.Join(dir, "Dockerfile")); err == nil {
+		found = true
+		for _, line := range strings.Split(string(content), "\n") {
+			if m := pgMajorPattern.FindStringSubmatch(line); m != nil {
+				p.version = m[1]
+			}
+		}
+		p.packages = aptPackagePattern.FindAllString(string(content), -1)
+	}
+
+	if content, err := os.ReadFile(filepath.Join(dir, postgresConfPgboxFile)); err == nil {
+		found = true
+		for _, line := range strings.Split(string(content), "\n") {
+			m := confSettingPattern.FindStringSubmatch(strings.TrimSpace(line))
+			if m == nil {
+				continue
+			}
+			p.addSetting(m[1] + "=" + strings.Trim(m[2], "'"))
+			if m[3] != "" {
+				p.sources[m[1]] = m[3]
+			}
+		}
+	} else if content, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml")); err == nil {
+		found = true
+		lines := strings.Split(string(content), "\n")
+		for i := 0; i+1 < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "- -c" {
+				p.addSetting(strings.TrimPrefix(strings.TrimSpace(lines[i+1]), "- "))
+				i++
+			}
+		}
+	}
+
+	initFiles, _ := filepath.Glob(filepath.Join(dir, "*.sql"))
+	nested, _ := filepath.Glob(filepath.Join(dir, initDirName, "*.sql"))
+	for _, path := range append(initFiles, nested...) {
+		if content, err := os.ReadFile(path); err == nil {
+			found = true
+			p.addFragments(string(content))
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no pgbox scaffold found in %s. Create one with: pgbox export %s", dir, dir)
+	}
+	return p, nil
+}
+
+// addSetting records a key=value setting.
+func (p *provenance) addSetting(setting string) {
+	key, value, ok := strings.Cut(setting, "=")
+	if !ok {
+		return
+	}
+	if key == "shared_preload_libraries" {
+		for _, lib := range strings.Split(value, ",") {
+			if lib = strings.TrimSpace(lib); lib != "" {
+				p.preload = append(p.preload, lib)
+			}
+		}
+		return
+	}
+	p.gucs[key] = value
+}
+
+// addFragments records the pgbox-managed fragment names in init SQL.
+func (p *provenance) addFragments(initSQL string) {
+	scanner := bufio.NewScanner(strings.NewReader(initSQL))
+	for scanner.Scan() {
+		if m := initFragmentPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			p.fragments = append(p.fragments, m[1])
+		}
+	}
+}
+
+// installedExtensions returns the catalog extensions that left evidence behind.
+func (p *provenance) installedExtensions() []string {
+	seen := make(map[string]bool)
+	for _, frag := range p.fragments {
+		if name := strings.TrimSuffix(frag, "-init"); isCatalogExtension(name) {
+			seen[name] = true
+		}
+	}
+	for _, lib := range p.preload {
+		for _, name := range preloadExtensions(lib) {
+			seen[name] = true
+		}
+	}
+	for _, pkg := range p.packages {
+		if name := packageExtension(pkg, p.version); name != "" {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// explain returns one line per way item was introduced.
+func (p *provenance) explain(item string) []string {
+	var lines []string
+	key := strings.ToLower(item)
+
+	if key == "shared_preload_libraries" && len(p.preload) > 0 {
+		lines = append(lines, fmt.Sprintf("shared_preload_libraries = %s", strings.Join(p.preload, ",")))
+		for _, lib := range p.preload {
+			lines = append(lines, fmt.Sprintf("  %s: %s", lib, p.preloadReason(lib)))
+		}
+		return lines
+	}
+	if value, ok := p.gucs[key]; ok {
+		lines = append(lines, fmt.Sprintf("%s: set to %s by %s", key, value, p.settingSource(key, value)))
+	}
+	if slices.Contains(p.preload, item) {
+		lines = append(lines, fmt.Sprintf("%s: in shared_preload_libraries, %s", item, p.preloadReason(item)))
+	}
+	if slices.Contains(p.packages, item) {
+		if name := packageExtension(item, p.version); name != "" {
+			lines = append(lines, fmt.Sprintf("%s: installed for extension %s", item, name))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: installed, but no catalog extension uses it", item))
+		}
+	}
+	for _, frag := range p.fragments {
+		if frag != item && frag != item+"-init" {
+			continue
+		}
+		if name := strings.TrimSuffix(frag, "-init"); isCatalogExtension(name) {
+			lines = append(lines, fmt.Sprintf("init.sql fragment %s: added by extension %s", frag, name))
+		} else {
+			lines = append(lines, fmt.Sprintf("init.sql fragment %s: a user block kept by pgbox", frag))
+		}
+	}
+
+	if len(lines) == 0 {
+		if param, ok := extensions.GetParameter(key, p.version); ok {
+			def := param.Default
+			if param.Unit != "" {
+				def += " " + param.Unit
+			}
+			lines = append(lines, fmt.Sprintf("%s: not set by pgbox in %s; PostgreSQL uses its default (%s)", key, p.where, def))
+		}
+	}
+	return lines
+}
+
+// settingSource names what set key to value: the recorded source when the
+// scaffold has one, otherwise the extension or profile that sets that value.
+func (p *provenance) settingSource(key, value string) string {
+	if source, ok := p.sources[key]; ok {
+		switch {
+		case source == model.SourceUser:
+			return "an explicit setting (--set or [settings] in pgbox.toml)"
+		case strings.HasPrefix(source, model.SourceProfile+" "):
+			return source + " (" + profileFlag(strings.TrimPrefix(source, model.SourceProfile+" ")) + ")"
+		}
+		return source
+	}
+
+	installed := p.installedExtensions()
+	var setters []string
+	for name, v := range extensions.GetGUCSetters(key) {
+		if v == value && slices.Contains(installed, name) {
+			setters = append(setters, name)
+		}
+	}
+	sort.Strings(setters)
+	switch {
+	case len(setters) > 0:
+		return "extension " + strings.Join(setters, ", ")
+	case FastUnsafeSettings[key] == value:
+		return "profile fast-unsafe (--fast-unsafe)"
+	}
+	return "an explicit setting (--set or [settings] in pgbox.toml)"
+}
+
+// profileFlag returns the flag that applies the named profile.
+func profileFlag(name string) string {
+	switch name {
+	case "fast-unsafe", "hardened":
+		return "--" + name
+	}
+	return "--profile " + name
+}
+
+// preloadReason explains why lib is preloaded.
+func (p *provenance) preloadReason(lib string) string {
+	if names := preloadExtensions(lib); len(names) > 0 {
+		return "required by extension " + strings.Join(names, ", ")
+	}
+	return "not required by any catalog extension"
+}
+
+func isCatalogExtension(name string) bool {
+	_, ok := extensions.Get(name)
+	return ok
+}
+
+// preloadExtensions returns the catalog extensions that preload lib.
+func preloadExtensions(lib string) []string {
+	var names []string
+	for _, name := range extensions.ListExtensions() {
+		ext, _ := extensions.Get(name)
+		if slices.Contains(ext.Preload, lib) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// packageExtension returns the catalog extension installed by pkg.
+func packageExtension(pkg, version string) string {
+	for _, name := range extensions.ListExtensions() {
+		if version != "" && extensions.GetPackage(name, version) == pkg {
+			return name
+		}
+	}
+	return ""
+}
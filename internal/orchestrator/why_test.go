@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func whyMock(running bool) *docker.MockDocker {
+	mock := docker.NewMockDocker()
+	mock.RunCommandWithOutputFunc = func(args ...string) (string, error) {
+		return "pgbox-pg17-custom:abc123\t" +
+			`["postgres","-c","shared_preload_libraries=pg_cron,wal2json","-c","cron.database_name=postgres","-c","fsync=off","-c","wal_level=logical","-c","work_mem=64MB"]` + "\n", nil
+	}
+	mock.IsContainerRunningFunc = func(name string) (bool, error) { return running, nil }
+	mock.ExecCommandFunc = func(containerName string, command ...string) (string, error) {
+		if command[0] == "cat" {
+			return "-- pgbox: begin pg_cron-init sha256=0123456789abcdef\nCREATE EXTENSION pg_cron;\n-- pgbox: end pg_cron-init\n" +
+				"-- pgbox: begin seed\nINSERT INTO t VALUES (1);\n-- pgbox: end seed\n", nil
+		}
+		return "postgresql-17\npostgresql-17-cron\npostgresql-17-wal2json\n", nil
+	}
+	return mock
+}
+
+func TestWhyOrchestrator_ContainerSettings(t *testing.T) {
+	tests := []struct {
+		item string
+		want string
+	}{
+		{"wal_level", "wal_level: set to logical by extension wal2json"},
+		{"fsync", "fsync: set to off by profile fast-unsafe (--fast-unsafe)"},
+		{"work_mem", "work_mem: set to 64MB by an explicit setting"},
+		{"shared_preload_libraries", "  pg_cron: required by extension pg_cron"},
+		{"postgresql-17-cron", "postgresql-17-cron: installed for extension pg_cron"},
+		{"pg_cron", "init.sql fragment pg_cron-init: added by extension pg_cron"},
+		{"seed", "init.sql fragment seed: a user block kept by pgbox"},
+		{"max_connections", "max_connections: not set by pgbox in my-postgres; PostgreSQL uses its default (100)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.item, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := NewWhyOrchestrator(whyMock(true), &buf).Run(t.Context(), WhyConfig{ContainerName: "my-postgres", Item: tt.item})
+
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestWhyOrchestrator_StoppedContainer(t *testing.T) {
+	mock := whyMock(false)
+	var buf bytes.Buffer
+
+	err := NewWhyOrchestrator(mock, &buf).Run(t.Context(), WhyConfig{ContainerName: "my-postgres", Item: "cron.database_name"})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "cron.database_name: set to postgres by extension pg_cron")
+	assert.Empty(t, mock.Calls.ExecCommand)
+}
+
+func TestWhyOrchestrator_NotFromPgbox(t *testing.T) {
+	err := NewWhyOrchestrator(whyMock(true), &bytes.Buffer{}).Run(t.Context(), WhyConfig{ContainerName: "my-postgres", Item: "postgresql-17-unknown"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "was not introduced by pgbox in my-postgres")
+}
+
+func TestWhyOrchestrator_Scaffold(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, NewExportOrchestrator(&bytes.Buffer{}).Run(ExportConfig{
+		TargetDir:  dir,
+		Version:    "17",
+		Port:       "5432",
+		Extensions: []string{"pg_cron", "wal2json"},
+		Settings:   map[string]string{"wal_level": "logical"},
+	}))
+
+	var buf bytes.Buffer
+	orch := NewWhyOrchestrator(docker.NewMockDocker(), &buf)
+
+	require.NoError(t, orch.Run(t.Context(), WhyConfig{Dir: dir, Item: "wal_level"}))
+	assert.Contains(t, buf.String(), "wal_level: set to logical by an explicit setting")
+
+	buf.Reset()
+	require.NoError(t, orch.Run(t.Context(), WhyConfig{Dir: dir, Item: "cron.max_running_jobs"}))
+	assert.Contains(t, buf.String(), "cron.max_running_jobs: set to 5 by extension pg_cron")
+
+	buf.Reset()
+	require.NoError(t, orch.Run(t.Context(), WhyConfig{Dir: dir, Item: "postgresql-17-wal2json"}))
+	assert.Contains(t, buf.String(), "installed for extension wal2json")
+}
+
+func TestWhyOrchestrator_NoScaffold(t *testing.T) {
+	err := NewWhyOrchestrator(docker.NewMockDocker(), &bytes.Buffer{}).Run(t.Context(), WhyConfig{Dir: t.TempDir(), Item: "wal_level"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no pgbox scaffold found")
+}
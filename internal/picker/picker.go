@@ -0,0 +1,310 @@
+// Package picker is the interactive multi-select list behind pgbox up
+// --interactive and export --interactive: a filter line with fuzzy search
+// over a list of items that are toggled with space and confirmed with enter.
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// ErrCanceled is returned by Run when the user leaves with Esc or Ctrl+C.
+var ErrCanceled = errors.New("canceled")
+
+// Item is one entry of the list.
+type Item struct {
+	Name        string
+	Description string
+	Tags        []string // Short notes shown after the name, such as "preload"
+}
+
+// Picker holds the list, the filter and the selection. It does no terminal
+// I/O itself, so it can be driven by Handle and drawn by Render.
+type Picker struct {
+	title    string
+	items    []Item
+	selected map[string]bool
+	query    string
+	cursor   int // Index into Visible
+	offset   int // First visible row when the list scrolls
+}
+
+// New returns a picker over items with the names in selected already
+// toggled on.
+func New(title string, items []Item, selected []string) *Picker {
+	p := &Picker{title: title, items: items, selected: make(map[string]bool)}
+	for _, name := range selected {
+		p.selected[name] = true
+	}
+	return p
+}
+
+// Visible returns the items that match the filter, best matches first. With
+// an empty filter that is every item in its original order.
+func (p *Picker) Visible() []Item {
+	if p.query == "" {
+		return p.items
+	}
+	type match struct {
+		item  Item
+		score int
+	}
+	var matches []match
+	for _, item := range p.items {
+		if score, ok := Match(p.query, item); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+	visible := make([]Item, len(matches))
+	for i, m := range matches {
+		visible[i] = m.item
+	}
+	return visible
+}
+
+// Selected returns the names of the toggled items in list order.
+func (p *Picker) Selected() []string {
+	var names []string
+	for _, item := range p.items {
+		if p.selected[item.Name] {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// Match reports whether query fuzzy-matches item and how well; lower scores
+// are better. The query's characters must appear in order in the name, and
+// names where they are close together and near the start rank first. A query
+// that is not in the name but is part of the description still matches,
+// after every name match.
+func Match(query string, item Item) (int, bool) {
+	query = strings.ToLower(query)
+	name := strings.ToLower(item.Name)
+	if i := strings.Index(name, query); i >= 0 {
+		return i, true
+	}
+	score, pos, last := 0, 0, -1
+	for _, r := range query {
+		i := strings.IndexRune(name[pos:], r)
+		if i < 0 {
+			if strings.Contains(strings.ToLower(item.Description), query) {
+				return 10000, true
+			}
+			return 0, false
+		}
+		if last >= 0 {
+			score += pos + i - last - 1
+		} else {
+			score += i
+		}
+		last = pos + i
+		pos += i + 1
+	}
+	return 100 + score, true
+}
+
+// KeyType identifies a key press.
+type KeyType int
+
+const (
+	KeyRune      KeyType = iota // A printable character, in Key.Rune
+	KeyUp                       // Up arrow or Ctrl+P
+	KeyDown                     // Down arrow or Ctrl+N
+	KeySpace                    // Toggle the item under the cursor
+	KeyEnter                    // Confirm the selection
+	KeyBackspace                // Delete the last filter character
+	KeyEscape                   // Esc or Ctrl+C
+	KeyClear                    // Ctrl+U: clear the filter
+	KeyOther                    // Anything else, ignored
+)
+
+// Key is one key press.
+type Key struct {
+	Type KeyType
+	Rune rune
+}
+
+// ReadKey reads one key press from a terminal in raw mode.
+func ReadKey(r *bufio.Reader) (Key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	switch c {
+	case '\r', '\n':
+		return Key{Type: KeyEnter}, nil
+	case ' ':
+		return Key{Type: KeySpace}, nil
+	case 0x7f, 0x08:
+		return Key{Type: KeyBackspace}, nil
+	case 0x03:
+		return Key{Type: KeyEscape}, nil
+	case 0x10:
+		return Key{Type: KeyUp}, nil
+	case 0x0e:
+		return Key{Type: KeyDown}, nil
+	case 0x15:
+		return Key{Type: KeyClear}, nil
+	case 0x1b:
+		// A lone Esc, or the start of an arrow key's sequence
+		if r.Buffered() == 0 {
+			return Key{Type: KeyEscape}, nil
+		}
+		next, _ := r.ReadByte()
+		if next != '[' && next != 'O' {
+			return Key{Type: KeyOther}, nil
+		}
+		final, _ := r.ReadByte()
+		switch final {
+		case 'A':
+			return Key{Type: KeyUp}, nil
+		case 'B':
+			return Key{Type: KeyDown}, nil
+		}
+		// Skip the rest of longer sequences such as Delete (ESC [ 3 ~)
+		for final >= '0' && final <= '9' || final == ';' {
+			if final, err = r.ReadByte(); err != nil {
+				break
+			}
+		}
+		return Key{Type: KeyOther}, nil
+	}
+	if c < 0x20 {
+		return Key{Type: KeyOther}, nil
+	}
+	return Key{Type: KeyRune, Rune: c}, nil
+}
+
+// Handle applies a key press. It returns done when the user confirmed the
+// selection with enter, and ErrCanceled when they left with Esc or Ctrl+C.
+func (p *Picker) Handle(k Key) (done bool, err error) {
+	switch k.Type {
+	case KeyEnter:
+		return true, nil
+	case KeyEscape:
+		return false, ErrCanceled
+	case KeyUp:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case KeyDown:
+		if p.cursor < len(p.Visible())-1 {
+			p.cursor++
+		}
+	case KeySpace:
+		if visible := p.Visible(); p.cursor < len(visible) {
+			name := visible[p.cursor].Name
+			p.selected[name] = !p.selected[name]
+		}
+	case KeyBackspace:
+		if p.query != "" {
+			runes := []rune(p.query)
+			p.query = string(runes[:len(runes)-1])
+			p.cursor, p.offset = 0, 0
+		}
+	case KeyClear:
+		p.query = ""
+		p.cursor, p.offset = 0, 0
+	case KeyRune:
+		p.query += string(k.Rune)
+		p.cursor, p.offset = 0, 0
+	}
+	return false, nil
+}
+
+// Render draws the picker in width columns and height rows: the title, the
+// filter, as many items as fit and a line listing the selection. Lines end
+// in "\r\n", as a terminal in raw mode needs.
+func (p *Picker) Render(w io.Writer, width, height int) {
+	visible := p.Visible()
+	rows := max(height-4, 1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+
+	nameWidth, tagWidth := 0, 0
+	for _, item := range p.items {
+		nameWidth = max(nameWidth, len(item.Name))
+		tagWidth = max(tagWidth, len(strings.Join(item.Tags, ",")))
+	}
+
+	line := func(s string) {
+		if runes := []rune(s); width > 0 && len(runes) > width {
+			s = string(runes[:width])
+		}
+		_, _ = fmt.Fprintf(w, "%s\r\n", s)
+	}
+	line(p.title)
+	line("Filter: " + p.query)
+	for i := p.offset; i < len(visible) && i < p.offset+rows; i++ {
+		item := visible[i]
+		cursor, box := " ", "[ ]"
+		if i == p.cursor {
+			cursor = ">"
+		}
+		if p.selected[item.Name] {
+			box = "[x]"
+		}
+		tags := strings.Join(item.Tags, ",")
+		line(fmt.Sprintf("%s %s %-*s %-*s %s", cursor, box, nameWidth, item.Name, tagWidth, tags, item.Description))
+	}
+	if len(visible) == 0 {
+		line("  (no matches)")
+	}
+	selected := p.Selected()
+	if len(selected) == 0 {
+		line("Selected: none")
+	} else {
+		line(fmt.Sprintf("Selected (%d): %s", len(selected), strings.Join(selected, ", ")))
+	}
+}
+
+// Run shows the picker on the terminal in, drawing to out, until the user
+// confirms or cancels, and returns the selected names.
+func Run(in *os.File, out io.Writer, p *Picker) ([]string, error) {
+	fd := in.Fd()
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	// Draw on the alternate screen so the terminal's contents come back
+	_, _ = fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		_, _ = fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+		_ = term.Restore(fd, state)
+	}()
+
+	reader := bufio.NewReader(in)
+	for {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		var frame strings.Builder
+		frame.WriteString("\x1b[H\x1b[2J")
+		p.Render(&frame, width, height)
+		_, _ = fmt.Fprint(out, frame.String())
+
+		key, err := ReadKey(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from the terminal: %w", err)
+		}
+		if done, err := p.Handle(key); err != nil {
+			return nil, err
+		} else if done {
+			return p.Selected(), nil
+		}
+	}
+}
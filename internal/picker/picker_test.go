@@ -0,0 +1,97 @@
+package picker
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testItems = []Item{
+	{Name: "hstore", Description: "Key/value pairs in a single value"},
+	{Name: "pg_cron", Description: "Job scheduler", Tags: []string{"preload"}},
+	{Name: "pg_stat_statements", Description: "Statement statistics", Tags: []string{"preload"}},
+	{Name: "pgvector", Description: "Vector similarity search"},
+}
+
+func names(items []Item) []string {
+	var out []string
+	for _, item := range items {
+		out = append(out, item.Name)
+	}
+	return out
+}
+
+func TestVisible(t *testing.T) {
+	p := New("", testItems, nil)
+	assert.Equal(t, []string{"hstore", "pg_cron", "pg_stat_statements", "pgvector"}, names(p.Visible()))
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"vec", []string{"pgvector"}},
+		{"pgst", []string{"pg_stat_statements"}},
+		{"pgc", []string{"pg_cron", "pgvector"}},
+		{"scheduler", []string{"pg_cron"}},
+		{"xyz", nil},
+	} {
+		p.query = tc.query
+		assert.Equal(t, tc.want, names(p.Visible()), tc.query)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	p := New("", testItems, []string{"hstore"})
+
+	for _, r := range "pg" {
+		_, err := p.Handle(Key{Type: KeyRune, Rune: r})
+		require.NoError(t, err)
+	}
+	_, _ = p.Handle(Key{Type: KeyDown})
+	_, _ = p.Handle(Key{Type: KeySpace})
+	assert.Equal(t, []string{"hstore", "pg_stat_statements"}, p.Selected())
+
+	_, _ = p.Handle(Key{Type: KeyClear})
+	_, _ = p.Handle(Key{Type: KeySpace})
+	done, err := p.Handle(Key{Type: KeyEnter})
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []string{"pg_stat_statements"}, p.Selected())
+
+	_, err = p.Handle(Key{Type: KeyEscape})
+	assert.ErrorIs(t, err, ErrCanceled)
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("a \r\x7f\x1b[A\x1b[B\x1b[3~\x03\x1b"))
+	var got []Key
+	for {
+		key, err := ReadKey(r)
+		if err != nil {
+			break
+		}
+		got = append(got, key)
+	}
+	assert.Equal(t, []Key{
+		{Type: KeyRune, Rune: 'a'}, {Type: KeySpace}, {Type: KeyEnter}, {Type: KeyBackspace},
+		{Type: KeyUp}, {Type: KeyDown}, {Type: KeyOther}, {Type: KeyEscape}, {Type: KeyEscape},
+	}, got)
+}
+
+func TestRender(t *testing.T) {
+	p := New("Extensions", testItems, []string{"pg_cron"})
+	_, _ = p.Handle(Key{Type: KeyDown})
+	var buf bytes.Buffer
+
+	p.Render(&buf, 60, 6)
+
+	assert.Equal(t, "Extensions\r\n"+
+		"Filter: \r\n"+
+		"  [ ] hstore                     Key/value pairs in a single\r\n"+
+		"> [x] pg_cron            preload Job scheduler\r\n"+
+		"Selected (1): pg_cron\r\n", buf.String())
+}
@@ -0,0 +1,110 @@
+// Package profiles provides named PostgreSQL tuning profiles for common
+// scenarios, applied with --profile.
+package profiles
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a named set of server settings. Its values win over those
+// extensions require, and lose to explicit --set values.
+type Profile struct {
+	// Name is the --profile value.
+	Name string
+
+	// Description says what the profile is for, for help and listings.
+	Description string
+
+	// Settings maps GUC names to values.
+	Settings map[string]string
+
+	// Unsafe marks profiles that turn off crash safety, so a crash can
+	// corrupt the data directory.
+	Unsafe bool
+}
+
+// Catalog contains the available profiles, keyed by name.
+var Catalog = map[string]Profile{
+	"dev": {
+		Name:        "dev",
+		Description: "Local development: more memory than the defaults and logging of slow queries and lock waits",
+		Settings: map[string]string{
+			"shared_buffers":             "256MB",
+			"work_mem":                   "16MB",
+			"maintenance_work_mem":       "128MB",
+			"log_min_duration_statement": "500ms",
+			"log_lock_waits":             "on",
+			"track_io_timing":            "on",
+		},
+	},
+	"test": {
+		Name:        "test",
+		Description: "Test suites: durability off for speed and room for parallel test workers",
+		Settings: map[string]string{
+			"fsync":              "off",
+			"synchronous_commit": "off",
+			"full_page_writes":   "off",
+			"max_connections":    "200",
+		},
+		Unsafe: true,
+	},
+	"ci": {
+		Name:        "ci",
+		Description: "CI runners: durability, autovacuum and JIT off, and a small memory footprint",
+		Settings: map[string]string{
+			"fsync":              "off",
+			"synchronous_commit": "off",
+			"full_page_writes":   "off",
+			"autovacuum":         "off",
+			"jit":                "off",
+			"shared_buffers":     "128MB",
+			"max_connections":    "200",
+		},
+		Unsafe: true,
+	},
+	"analytics": {
+		Name:        "analytics",
+		Description: "Analytical queries: large memory settings, parallel query and SSD-friendly planner costs",
+		Settings: map[string]string{
+			"shared_buffers":                  "1GB",
+			"effective_cache_size":            "3GB",
+			"work_mem":                        "64MB",
+			"maintenance_work_mem":            "512MB",
+			"max_parallel_workers_per_gather": "4",
+			"random_page_cost":                "1.1",
+			"default_statistics_target":       "500",
+		},
+	},
+}
+
+// Names returns the profile names, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Catalog))
+	for name := range Catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the profile with the given name, or an error listing the
+// available ones.
+func Get(name string) (Profile, error) {
+	profile, ok := Catalog[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return profile, nil
+}
+
+// SortedKeys returns the profile's setting names in alphabetical order.
+func (p Profile) SortedKeys() []string {
+	keys := make([]string, 0, len(p.Settings))
+	for key := range p.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,30 @@
+package profiles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	profile, err := Get("test")
+	require.NoError(t, err)
+	assert.Equal(t, "off", profile.Settings["fsync"])
+	assert.True(t, profile.Unsafe)
+
+	_, err = Get("turbo")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown profile "turbo" (available: analytics, ci, dev, test)`)
+}
+
+func TestCatalogEntries(t *testing.T) {
+	for _, name := range Names() {
+		profile := Catalog[name]
+		assert.Equal(t, name, profile.Name)
+		assert.NotEmpty(t, profile.Description, name)
+		assert.NotEmpty(t, profile.Settings, name)
+		_, disablesFsync := profile.Settings["fsync"]
+		assert.Equal(t, disablesFsync, profile.Unsafe, "profile %s: Unsafe must match whether it turns off fsync", name)
+	}
+}
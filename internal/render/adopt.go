@@ -0,0 +1,338 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ahacop/pgbox/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeService is the PostgreSQL service of an existing compose file, as
+// export --adopt takes it over.
+type ComposeService struct {
+	Name          string
+	Image         string // Empty when the service is built from a Dockerfile
+	BuildMajor    string // The PG_MAJOR build argument of a built service
+	ContainerName string
+	Ports         []string
+	Volumes       []string
+	Env           map[string]string
+	Command       []string     // Arguments of the service's command
+	Extra         []ServiceKey // Keys pgbox does not generate, kept as written
+	Replaced      []string     // Keys pgbox generates itself, such as healthcheck
+
+	file *ParsedFile // The compose file with the service as its anchored block
+}
+
+// ServiceKey is one key of a compose service with its value, as YAML lines
+// indented for a service.
+type ServiceKey struct {
+	Name  string
+	Lines []string
+}
+
+// postgresImages are fragments of the names of PostgreSQL images.
+var postgresImages = []string{"postgres", "postgis", "pgvector", "timescale"}
+
+// postgresServiceNames are names that mark the database service when no
+// image does, as when it is built from a Dockerfile.
+var postgresServiceNames = []string{"db", "postgres", "postgresql", "database"}
+
+// replacedServiceKeys are the service keys the pgbox block renders from its
+// own model, so an adopted value is dropped.
+var replacedServiceKeys = []string{"build", "healthcheck"}
+
+// AdoptComposeFile reads the compose file at path and finds its PostgreSQL
+// service: the one running a PostgreSQL image, or named like a database when
+// none does. In a file pgbox has not written to, the service is moved into a
+// pgbox block at the top of services; RenderAdoptedComposeFile then writes
+// the file with that block replaced and everything else kept. In a file that
+// already has a pgbox block, the service is the one in it.
+func AdoptComposeFile(path string) (*ComposeService, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	var services *yaml.Node
+	var servicesLine, servicesEnd int
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		root := doc.Content[0].Content
+		for i := 0; i < len(root); i += 2 {
+			if root[i].Value == "services" {
+				services, servicesLine = root[i+1], root[i].Line-1
+				if i+2 < len(root) {
+					servicesEnd = root[i+2].Line - 1
+				}
+			}
+		}
+	}
+	if services == nil || services.Kind != yaml.MappingNode || len(services.Content) == 0 {
+		return nil, fmt.Errorf("%s has no services", filepath.Base(path))
+	}
+	if services.Style&yaml.FlowStyle != 0 {
+		return nil, fmt.Errorf("%s writes services in flow style ({...}), which --adopt cannot rewrite", filepath.Base(path))
+	}
+
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n"), "\n")
+	if servicesEnd == 0 {
+		servicesEnd = len(lines)
+	}
+	parsed, err := ParseFileWithAnchors(path, ComposeAnchors)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := findPostgresService(services, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	key, value := services.Content[index], services.Content[index+1]
+	end := servicesEnd
+	if index+2 < len(services.Content) {
+		end = services.Content[index+2].Line - 1
+	}
+	start, end := key.Line-1, trimBlock(lines, key.Line-1, end)
+	// Take the comments right above the service along, and one of the blank
+	// lines around it
+	for start > servicesLine+1 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "#") {
+		start--
+	}
+	if start > servicesLine+1 && strings.TrimSpace(lines[start-1]) == "" && end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+
+	svc := &ComposeService{Name: key.Value, Env: make(map[string]string)}
+	if err := svc.parse(value, lines, end); err != nil {
+		return nil, fmt.Errorf("service %s in %s: %w", svc.Name, filepath.Base(path), err)
+	}
+
+	if parsed.HasAnchor {
+		// What the pgbox block replaces there was pgbox's own
+		svc.Replaced = nil
+	} else {
+		// The other services follow the block, still under services:
+		parsed = &ParsedFile{
+			PreAnchor:  slices.Clone(lines[:servicesLine]),
+			Anchored:   append([]string{lines[servicesLine]}, lines[start:end]...),
+			PostAnchor: slices.Concat(lines[servicesLine+1:start], lines[end:]),
+			HasAnchor:  true,
+		}
+	}
+	svc.file = parsed
+	return svc, nil
+}
+
+// findPostgresService returns the index of the PostgreSQL service's key in
+// services. With a pgbox block, that is the first service in it.
+func findPostgresService(services *yaml.Node, parsed *ParsedFile) (int, error) {
+	if parsed.HasAnchor {
+		begin := len(parsed.PreAnchor) + 1
+		for i := 0; i < len(services.Content); i += 2 {
+			if line := services.Content[i].Line; line > begin && line <= begin+len(parsed.Anchored) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("the pgbox block holds no service")
+	}
+
+	var byImage, byName []int
+	var names []string
+	for i := 0; i < len(services.Content); i += 2 {
+		name, service := services.Content[i].Value, services.Content[i+1]
+		names = append(names, name)
+		if image := mappingValue(service, "image"); image != nil && isPostgresImage(image.Value) {
+			byImage = append(byImage, i)
+		} else if slices.Contains(postgresServiceNames, name) {
+			byName = append(byName, i)
+		}
+	}
+	candidates := byImage
+	if len(candidates) == 0 {
+		candidates = byName
+	}
+	if len(candidates) > 1 {
+		// Several PostgreSQL images: prefer the one named like a database
+		var named []int
+		for _, i := range candidates {
+			if slices.Contains(postgresServiceNames, services.Content[i].Value) {
+				named = append(named, i)
+			}
+		}
+		if len(named) != 1 {
+			var found []string
+			for _, i := range candidates {
+				found = append(found, services.Content[i].Value)
+			}
+			return 0, fmt.Errorf("found several PostgreSQL services (%s); keep one in the file for --adopt", strings.Join(found, ", "))
+		}
+		candidates = named
+	}
+	if len(candidates) == 0 {
+		return 0, fmt.Errorf("none of the services (%s) runs a PostgreSQL image", strings.Join(names, ", "))
+	}
+	if services.Content[candidates[0]+1].Style&yaml.FlowStyle != 0 {
+		return 0, fmt.Errorf("service %s is written in flow style ({...}), which --adopt cannot rewrite", services.Content[candidates[0]].Value)
+	}
+	return candidates[0], nil
+}
+
+// isPostgresImage reports whether image looks like a PostgreSQL image.
+func isPostgresImage(image string) bool {
+	name, _, _ := strings.Cut(image, ":")
+	name = name[strings.LastIndex(name, "/")+1:]
+	for _, fragment := range postgresImages {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads the service's keys. value is the service's mapping and end the
+// line after the service's last.
+func (s *ComposeService) parse(value *yaml.Node, lines []string, end int) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("not a mapping")
+	}
+	for i := 0; i < len(value.Content); i += 2 {
+		key, node := value.Content[i], value.Content[i+1]
+		var err error
+		switch key.Value {
+		case "image":
+			s.Image = node.Value
+		case "container_name":
+			s.ContainerName = node.Value
+		case "ports":
+			s.Ports, err = scalarList(key.Value, node)
+		case "volumes":
+			s.Volumes, err = scalarList(key.Value, node)
+		case "environment":
+			err = s.parseEnv(node)
+		case "command":
+			if node.Kind == yaml.ScalarNode {
+				s.Command = strings.Fields(node.Value)
+			} else {
+				s.Command, err = scalarList(key.Value, node)
+			}
+		default:
+			if key.Value == "build" {
+				if major := mappingValue(node, "args"); major != nil {
+					if major = mappingValue(major, "PG_MAJOR"); major != nil {
+						s.BuildMajor = major.Value
+					}
+				}
+			}
+			if slices.Contains(replacedServiceKeys, key.Value) {
+				s.Replaced = append(s.Replaced, key.Value)
+				continue
+			}
+			next := end
+			if i+2 < len(value.Content) {
+				next = value.Content[i+2].Line - 1
+			}
+			s.Extra = append(s.Extra, ServiceKey{
+				Name:  key.Value,
+				Lines: reindent(lines[key.Line-1:trimBlock(lines, key.Line-1, next)], key.Column-1, 4),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseEnv reads environment in either of its forms: a mapping, or a list of
+// KEY=VALUE items.
+func (s *ComposeService) parseEnv(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			s.Env[node.Content[i].Value] = node.Content[i+1].Value
+		}
+	case yaml.SequenceNode:
+		items, err := scalarList("environment", node)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			key, value, _ := strings.Cut(item, "=")
+			s.Env[key] = value
+		}
+	default:
+		return fmt.Errorf("environment is neither a mapping nor a list")
+	}
+	return nil
+}
+
+// scalarList reads a list of plain values, such as ports in their short
+// syntax.
+func scalarList(key string, node *yaml.Node) ([]string, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s is not a list", key)
+	}
+	var values []string
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s uses the long syntax, which --adopt does not support; write them as strings", key)
+		}
+		values = append(values, item.Value)
+	}
+	return values, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// trimBlock returns end moved back over the blank and comment lines that
+// close the block from start, which belong to what follows.
+func trimBlock(lines []string, start, end int) int {
+	for end > start+1 {
+		line := strings.TrimSpace(lines[end-1])
+		if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// reindent moves lines indented by from spaces to to spaces.
+func reindent(lines []string, from, to int) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		cut := min(len(line)-len(trimmed), from)
+		if trimmed == "" {
+			out[i] = ""
+			continue
+		}
+		out[i] = strings.Repeat(" ", to) + line[cut:]
+	}
+	return out
+}
+
+// RenderAdoptedComposeFile writes the model to the compose file the service
+// was adopted from, replacing the pgbox block and keeping the rest.
+func RenderAdoptedComposeFile(m *model.ComposeModel, pgConf *model.PGConfModel, svc *ComposeService, composePath string) error {
+	lines := ReplaceAnchored(svc.file, ComposeAnchors, generateComposeService(m, pgConf))
+	return WriteLines(composePath, lines)
+}
@@ -3,6 +3,7 @@ package render
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -11,11 +12,15 @@ import (
 
 // RenderCompose renders a docker-compose.yml from the model
 func RenderCompose(m *model.ComposeModel, pgConf *model.PGConfModel, outputPath string) error {
-	composePath := filepath.Join(outputPath, "docker-compose.yml")
+	return RenderComposeFile(m, pgConf, filepath.Join(outputPath, "docker-compose.yml"))
+}
 
+// RenderComposeFile renders the model to the compose file at composePath,
+// keeping content outside the pgbox anchors
+func RenderComposeFile(m *model.ComposeModel, pgConf *model.PGConfModel, composePath string) error {
 	parsed, err := ParseFileWithAnchors(composePath, ComposeAnchors)
 	if err != nil {
-		return fmt.Errorf("failed to parse existing docker-compose.yml: %w", err)
+		return fmt.Errorf("failed to parse existing %s: %w", filepath.Base(composePath), err)
 	}
 
 	anchoredContent := generateComposeService(m, pgConf)
@@ -63,7 +68,9 @@ func generateComposeService(m *model.ComposeModel, pgConf *model.PGConfModel) []
 	}
 
 	containerName := fmt.Sprintf("pgbox-%s", m.ServiceName)
-	if m.ServiceName == "db" {
+	if m.ContainerName != "" {
+		containerName = m.ContainerName
+	} else if m.ServiceName == "db" {
 		containerName = "pgbox-postgres"
 	}
 	lines = append(lines, fmt.Sprintf("    container_name: %s", containerName))
@@ -76,10 +83,11 @@ func generateComposeService(m *model.ComposeModel, pgConf *model.PGConfModel) []
 		}
 		sort.Strings(keys)
 		for _, k := range keys {
-			lines = append(lines, fmt.Sprintf("      %s: %s", k, m.Env[k]))
+			lines = append(lines, fmt.Sprintf("      %s: %s", k, composeValue(m.Env[k])))
 		}
 	}
 
+	lines = append(lines, composeArgs("entrypoint", m.Entrypoint)...)
 	if pgConf != nil && (len(pgConf.SharedPreload) > 0 || len(pgConf.GUCs) > 0) {
 		lines = append(lines, "    command:")
 		lines = append(lines, "      - postgres")
@@ -118,12 +126,22 @@ func generateComposeService(m *model.ComposeModel, pgConf *model.PGConfModel) []
 		}
 	}
 
+	lines = append(lines, composeList("security_opt", m.SecurityOpt)...)
+	lines = append(lines, composeList("cap_drop", m.CapDrop)...)
+	lines = append(lines, composeList("cap_add", m.CapAdd)...)
+	if m.ReadOnly {
+		lines = append(lines, "    read_only: true")
+	}
+	lines = append(lines, composeList("tmpfs", m.Tmpfs)...)
+	lines = append(lines, m.Extra...)
+
+	health := model.PostgresHealthcheck
 	lines = append(lines,
 		"    healthcheck:",
-		"      test: [\"CMD-SHELL\", \"pg_isready -U ${POSTGRES_USER:-postgres} -d ${POSTGRES_DB:-postgres}\"]",
-		"      interval: 10s",
-		"      timeout: 5s",
-		"      retries: 5",
+		fmt.Sprintf("      test: [\"CMD-SHELL\", %q]", health.Cmd),
+		fmt.Sprintf("      interval: %s", health.Interval),
+		fmt.Sprintf("      timeout: %s", health.Timeout),
+		fmt.Sprintf("      retries: %d", health.Retries),
 	)
 
 	if len(m.Networks) > 0 {
@@ -133,5 +151,124 @@ func generateComposeService(m *model.ComposeModel, pgConf *model.PGConfModel) []
 		}
 	}
 
+	for _, sidecar := range m.Sidecars {
+		lines = append(lines, generateSidecar(sidecar)...)
+	}
+
+	return lines
+}
+
+// composePlainValue matches values that read back as the same string
+// without quotes. ${VAR} references are left plain, as compose expects them.
+var composePlainValue = regexp.MustCompile(`^[A-Za-z0-9_./@%+$=-][A-Za-z0-9_./:@%+,${}=-]*$`)
+
+// composeValue renders a service environment value, quoted when YAML would
+// otherwise read it as something else, such as a boolean or a comment.
+func composeValue(value string) string {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return fmt.Sprintf("%q", value)
+	}
+	if composePlainValue.MatchString(value) {
+		return value
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// composeList renders a service key holding a list, or nothing when the
+// list is empty
+func composeList(key string, values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("    %s:", key)}
+	for _, value := range values {
+		lines = append(lines, fmt.Sprintf("      - %s", value))
+	}
+	return lines
+}
+
+// composeArgs renders a service key holding a command line, one argument per
+// item. Arguments spanning several lines, such as scripts, become literal
+// block scalars.
+func composeArgs(key string, args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("    %s:", key)}
+	for _, arg := range args {
+		if !strings.Contains(arg, "\n") {
+			lines = append(lines, fmt.Sprintf("      - %q", arg))
+			continue
+		}
+		lines = append(lines, "      - |")
+		for _, line := range strings.Split(strings.TrimSuffix(arg, "\n"), "\n") {
+			lines = append(lines, "        "+line)
+		}
+	}
+	return lines
+}
+
+// generateSidecar generates the service configuration for a sidecar
+func generateSidecar(s model.Sidecar) []string {
+	lines := []string{
+		"",
+		fmt.Sprintf("  %s:", s.Name),
+	}
+	if s.Build != "" {
+		lines = append(lines,
+			"    build:",
+			fmt.Sprintf("      context: %s", s.Build),
+			"      dockerfile: Dockerfile",
+		)
+	} else {
+		lines = append(lines, fmt.Sprintf("    image: %s", s.Image))
+	}
+
+	if len(s.Profiles) > 0 {
+		lines = append(lines, "    profiles:")
+		for _, profile := range s.Profiles {
+			lines = append(lines, fmt.Sprintf("      - %s", profile))
+		}
+	}
+
+	if len(s.Env) > 0 {
+		lines = append(lines, "    environment:")
+		var keys []string
+		for k := range s.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			// Quoted so values such as False stay strings
+			lines = append(lines, fmt.Sprintf("      %s: %q", k, s.Env[k]))
+		}
+	}
+
+	lines = append(lines, composeArgs("entrypoint", s.Entrypoint)...)
+	lines = append(lines, composeArgs("command", s.Command)...)
+
+	if len(s.Ports) > 0 {
+		lines = append(lines, "    ports:")
+		for _, port := range s.Ports {
+			lines = append(lines, fmt.Sprintf("      - \"%s\"", port))
+		}
+	}
+
+	if len(s.Volumes) > 0 {
+		lines = append(lines, "    volumes:")
+		for _, volume := range s.Volumes {
+			lines = append(lines, fmt.Sprintf("      - %s", volume))
+		}
+	}
+
+	if s.DependsOn != "" {
+		lines = append(lines,
+			"    depends_on:",
+			fmt.Sprintf("      %s:", s.DependsOn),
+			"        condition: service_healthy",
+		)
+	}
+
 	return lines
 }
@@ -2,7 +2,9 @@ package render
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/ahacop/pgbox/internal/model"
@@ -19,16 +21,30 @@ func RenderDockerfile(m *model.DockerfileModel, outputPath string) error {
 
 	var anchoredContent []string
 
+	if len(m.AptPackages) > 0 || len(m.DebURLs) > 0 || len(m.ZipURLs) > 0 || len(m.ArchDebs) > 0 || len(m.ArchZips) > 0 {
+		anchoredContent = append(anchoredContent, generateAptCacheSetup()...)
+	}
+
 	if len(m.AptPackages) > 0 {
 		anchoredContent = append(anchoredContent, generateAptInstall(m.BaseImage, m.AptPackages)...)
 	}
 
+	if len(m.ApkPackages) > 0 {
+		anchoredContent = append(anchoredContent, generateApkInstall(m.ApkPackages)...)
+	}
+
 	if len(m.DebURLs) > 0 {
-		anchoredContent = append(anchoredContent, generateDebInstall(m.DebURLs)...)
+		anchoredContent = append(anchoredContent, generateDebInstall(m.DebURLs, m.Checksums)...)
 	}
 
 	if len(m.ZipURLs) > 0 {
-		anchoredContent = append(anchoredContent, generateZipInstall(m.ZipURLs)...)
+		anchoredContent = append(anchoredContent, generateZipInstall(m.ZipURLs, m.Checksums)...)
+	}
+
+	if len(m.ArchDebs) > 0 || len(m.ArchZips) > 0 {
+		anchoredContent = append(anchoredContent, "", "# Set by BuildKit to the architecture the image is built for", "ARG TARGETARCH")
+		anchoredContent = append(anchoredContent, generateArchInstall("deb", m.ArchDebs)...)
+		anchoredContent = append(anchoredContent, generateArchInstall("zip", m.ArchZips)...)
 	}
 
 	if !parsed.HasAnchor && len(parsed.PreAnchor) == 0 {
@@ -58,18 +74,33 @@ func generateDefaultDockerfileHeader(baseImage string) []string {
 	}
 }
 
-// generateAptInstall generates apt package installation commands
+// aptCacheMounts keeps apt's package lists and downloaded packages in
+// BuildKit cache mounts, so rebuilding for a changed extension set does not
+// download the unchanged packages again. Nothing under them ends up in the
+// image, so the layers need no apt cleanup.
+const aptCacheMounts = "--mount=type=cache,target=/var/cache/apt,sharing=locked --mount=type=cache,target=/var/lib/apt/lists,sharing=locked"
+
+// generateAptCacheSetup stops Debian images from deleting downloaded packages
+// after each install, so they stay in the apt cache mount. It depends on no
+// extension, so its layer is shared by every extension set.
+func generateAptCacheSetup() []string {
+	return []string{
+		"# Keep downloaded packages in the apt cache mount",
+		"RUN rm -f /etc/apt/apt.conf.d/docker-clean; \\",
+		"    echo 'Binary::apt::APT::Keep-Downloaded-Packages \"true\";' > /etc/apt/apt.conf.d/keep-cache",
+	}
+}
+
+// generateAptInstall generates apt package installation commands: a layer
+// adding the PostgreSQL repository, then one layer per package in sorted
+// order, so adding or removing an extension reuses the layers of the packages
+// before it.
 func generateAptInstall(baseImage string, packages []string) []string {
 	if len(packages) == 0 {
 		return []string{}
 	}
 
-	lines := []string{
-		"# Install PostgreSQL extensions",
-		"RUN set -eux; \\",
-		"    apt-get update; \\",
-	}
-
+	var lines []string
 	hasExtensions := false
 	for _, pkg := range packages {
 		if strings.Contains(pkg, "postgresql-") {
@@ -77,97 +108,182 @@ func generateAptInstall(baseImage string, packages []string) []string {
 			break
 		}
 	}
-
 	if hasExtensions {
 		lines = append(lines,
+			"",
+			"# Add the apt.postgresql.org repository",
+			fmt.Sprintf("RUN %s set -eux; \\", aptCacheMounts),
+			"    apt-get update; \\",
 			"    apt-get install -y --no-install-recommends curl gnupg ca-certificates lsb-release; \\",
 			"    curl -fsSL https://www.postgresql.org/media/keys/ACCC4CF8.asc | gpg --dearmor -o /usr/share/keyrings/postgresql.gpg; \\",
 			"    echo \"deb [signed-by=/usr/share/keyrings/postgresql.gpg] https://apt.postgresql.org/pub/repos/apt $(lsb_release -cs)-pgdg main\" > /etc/apt/sources.list.d/pgdg.list; \\",
-			"    apt-get update; \\",
+			"    apt-get purge -y --auto-remove curl gnupg lsb-release",
 		)
 	}
 
-	lines = append(lines, "    apt-get install -y --no-install-recommends \\")
-	for i, pkg := range packages {
-		if i < len(packages)-1 {
-			lines = append(lines, fmt.Sprintf("        %s \\", pkg))
-		} else {
-			lines = append(lines, fmt.Sprintf("        %s; \\", pkg))
-		}
-	}
-
-	if hasExtensions {
+	for _, pkg := range sortedCopy(packages) {
 		lines = append(lines,
-			"    apt-get purge -y --auto-remove curl gnupg lsb-release; \\",
+			"",
+			fmt.Sprintf("# Install %s", pkg),
+			fmt.Sprintf("RUN %s set -eux; \\", aptCacheMounts),
+			"    apt-get update; \\",
+			fmt.Sprintf("    apt-get install -y --no-install-recommends %s", pkg),
 		)
 	}
-	lines = append(lines,
-		"    rm -rf /var/lib/apt/lists/*",
-	)
 
 	return lines
 }
 
-// generateDebInstall generates commands to download and install .deb packages
-func generateDebInstall(debURLs []string) []string {
-	if len(debURLs) == 0 {
+// generateApkInstall generates apk package installation commands for
+// Alpine-based images, one layer per package in sorted order like
+// generateAptInstall. The packages come from the image's configured
+// repositories.
+func generateApkInstall(packages []string) []string {
+	if len(packages) == 0 {
 		return []string{}
 	}
 
-	lines := []string{
-		"",
-		"# Install extensions from .deb packages",
-		"RUN set -eux; \\",
-		"    apt-get update; \\",
-		"    apt-get install -y --no-install-recommends curl ca-certificates; \\",
+	var lines []string
+	for _, pkg := range sortedCopy(packages) {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("# Install %s", pkg),
+			fmt.Sprintf("RUN apk add --no-cache %s", pkg),
+		)
 	}
 
-	for i, url := range debURLs {
-		filename := fmt.Sprintf("/tmp/ext_%d.deb", i)
-		lines = append(lines, fmt.Sprintf("    curl -fsSL -o %s '%s'; \\", filename, url))
-	}
+	return lines
+}
 
-	var debFiles []string
-	for i := range debURLs {
-		debFiles = append(debFiles, fmt.Sprintf("/tmp/ext_%d.deb", i))
+// generateDebInstall generates commands to download, verify and install
+// .deb packages, one layer per package in sorted URL order
+func generateDebInstall(debURLs []string, checksums map[string]string) []string {
+	if len(debURLs) == 0 {
+		return []string{}
 	}
-	lines = append(lines, fmt.Sprintf("    dpkg -i %s || apt-get install -fy; \\", strings.Join(debFiles, " ")))
 
-	lines = append(lines,
-		"    rm -f /tmp/ext_*.deb; \\",
-		"    apt-get purge -y --auto-remove curl ca-certificates; \\",
-		"    rm -rf /var/lib/apt/lists/*",
-	)
+	var lines []string
+	for _, url := range sortedCopy(debURLs) {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("# Install %s", path.Base(url)),
+			fmt.Sprintf("RUN %s set -eux; \\", aptCacheMounts),
+			"    apt-get update; \\",
+			"    apt-get install -y --no-install-recommends curl ca-certificates; \\",
+			fmt.Sprintf("    curl -fsSL -o /tmp/ext.deb '%s'; \\", url),
+		)
+		lines = append(lines, checksumLines("/tmp/ext.deb", checksums[url])...)
+		lines = append(lines,
+			"    dpkg -i /tmp/ext.deb || apt-get install -fy; \\",
+			"    rm -f /tmp/ext.deb; \\",
+			"    apt-get purge -y --auto-remove curl",
+		)
+	}
 
 	return lines
 }
 
-// generateZipInstall generates commands to download .zip files containing .deb packages and install them
-func generateZipInstall(zipURLs []string) []string {
+// generateZipInstall generates commands to download and verify .zip files
+// containing .deb packages and install them, one layer per file in sorted URL
+// order
+func generateZipInstall(zipURLs []string, checksums map[string]string) []string {
 	if len(zipURLs) == 0 {
 		return []string{}
 	}
 
-	lines := []string{
-		"",
-		"# Install extensions from .zip packages (containing .deb files)",
-		"RUN set -eux; \\",
-		"    apt-get update; \\",
-		"    apt-get install -y --no-install-recommends curl ca-certificates unzip; \\",
+	var lines []string
+	for _, url := range sortedCopy(zipURLs) {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("# Install %s", path.Base(url)),
+			fmt.Sprintf("RUN %s set -eux; \\", aptCacheMounts),
+			"    apt-get update; \\",
+			"    apt-get install -y --no-install-recommends curl ca-certificates unzip; \\",
+			fmt.Sprintf("    curl -fsSL -o /tmp/ext.zip '%s'; \\", url),
+		)
+		lines = append(lines, checksumLines("/tmp/ext.zip", checksums[url])...)
+		lines = append(lines,
+			"    unzip -o /tmp/ext.zip -d /tmp/ext/; \\",
+			"    dpkg -i /tmp/ext/*.deb || apt-get install -fy; \\",
+			"    rm -rf /tmp/ext.zip /tmp/ext/; \\",
+			"    apt-get purge -y --auto-remove curl unzip",
+		)
 	}
 
-	for i, url := range zipURLs {
-		zipFile := fmt.Sprintf("/tmp/ext_%d.zip", i)
-		lines = append(lines, fmt.Sprintf("    curl -fsSL -o %s '%s'; \\", zipFile, url))
-		lines = append(lines, fmt.Sprintf("    unzip -o %s -d /tmp/ext_%d/; \\", zipFile, i))
-		lines = append(lines, fmt.Sprintf("    dpkg -i /tmp/ext_%d/*.deb || apt-get install -fy; \\", i))
+	return lines
+}
+
+// generateArchInstall generates commands to download, verify and install
+// the .deb or .zip (kind) of each extension for the architecture the image is
+// built for, one layer per extension in name order. The build fails on an
+// architecture the extension has no download for.
+func generateArchInstall(kind string, downloads []model.ArchDownload) []string {
+	sorted := append([]model.ArchDownload(nil), downloads...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	tools, file := "curl ca-certificates", "/tmp/ext.deb"
+	install := []string{
+		"    dpkg -i /tmp/ext.deb || apt-get install -fy; \\",
+		"    rm -f /tmp/ext.deb; \\",
+		"    apt-get purge -y --auto-remove curl",
+	}
+	if kind == "zip" {
+		tools, file = "curl ca-certificates unzip", "/tmp/ext.zip"
+		install = []string{
+			"    unzip -o /tmp/ext.zip -d /tmp/ext/; \\",
+			"    dpkg -i /tmp/ext/*.deb || apt-get install -fy; \\",
+			"    rm -rf /tmp/ext.zip /tmp/ext/; \\",
+			"    apt-get purge -y --auto-remove curl unzip",
+		}
 	}
 
-	lines = append(lines,
-		"    rm -rf /tmp/ext_*.zip /tmp/ext_*/; \\",
-		"    apt-get purge -y --auto-remove curl ca-certificates unzip; \\",
-		"    rm -rf /var/lib/apt/lists/*",
-	)
+	var lines []string
+	for _, d := range sorted {
+		arches := make([]string, 0, len(d.URLs))
+		for arch := range d.URLs {
+			arches = append(arches, arch)
+		}
+		sort.Strings(arches)
+		lines = append(lines,
+			"",
+			fmt.Sprintf("# Install %s (.%s for %s)", d.Name, kind, strings.Join(arches, ", ")),
+			fmt.Sprintf("RUN %s set -eux; \\", aptCacheMounts),
+			`    case "$TARGETARCH" in \`,
+		)
+		for _, arch := range arches {
+			if len(d.Checksums) > 0 {
+				lines = append(lines, fmt.Sprintf("        %s) url='%s'; sha256='%s' ;; \\", arch, d.URLs[arch], d.Checksums[arch]))
+			} else {
+				lines = append(lines, fmt.Sprintf("        %s) url='%s' ;; \\", arch, d.URLs[arch]))
+			}
+		}
+		lines = append(lines,
+			fmt.Sprintf(`        *) echo "%s has no .%s for $TARGETARCH" >&2; exit 1 ;; \`, d.Name, kind),
+			"    esac; \\",
+			"    apt-get update; \\",
+			fmt.Sprintf("    apt-get install -y --no-install-recommends %s; \\", tools),
+			fmt.Sprintf(`    curl -fsSL -o %s "$url"; \`, file),
+		)
+		if len(d.Checksums) > 0 {
+			lines = append(lines, fmt.Sprintf(`    if [ -n "$sha256" ]; then echo "$sha256  %s" | sha256sum -c -; fi; \`, file))
+		}
+		lines = append(lines, install...)
+	}
 
 	return lines
 }
+
+// sortedCopy returns the values sorted, leaving the slice alone
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// checksumLines verifies a downloaded file against its expected SHA-256, if known
+func checksumLines(file, sha256 string) []string {
+	if sha256 == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("    echo '%s  %s' | sha256sum -c -; \\", sha256, file)}
+}
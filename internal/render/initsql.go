@@ -2,7 +2,10 @@ package render
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/ahacop/pgbox/internal/model"
@@ -10,8 +13,12 @@ import (
 
 // RenderInitSQL renders init.sql from the model
 func RenderInitSQL(m *model.InitModel, outputPath string) error {
-	initPath := filepath.Join(outputPath, "init.sql")
+	return RenderInitSQLFile(m, filepath.Join(outputPath, "init.sql"))
+}
 
+// RenderInitSQLFile renders the init SQL model to the given file path,
+// preserving user blocks from an existing pgbox-managed file
+func RenderInitSQLFile(m *model.InitModel, initPath string) error {
 	existingBlocks, preContent, err := ParseInitSQLAnchors(initPath)
 	if err != nil {
 		return fmt.Errorf("failed to parse existing init.sql: %w", err)
@@ -66,6 +73,135 @@ func RenderInitSQL(m *model.InitModel, outputPath string) error {
 	return WriteLines(initPath, lines)
 }
 
+// numberedInitPattern matches numbered init files such as
+// "00-pgbox-init-10-pgvector.sql", "00-pgbox-init.sql" and the "10-pgvector.sql"
+// of earlier pgbox versions
+var numberedInitPattern = regexp.MustCompile(`^\d+-.+\.sql$`)
+
+// IsGeneratedInitSQL reports whether init SQL content was generated by pgbox
+func IsGeneratedInitSQL(content string) bool {
+	return strings.Contains(content, "Generated by pgbox") || strings.Contains(content, "-- pgbox: begin ")
+}
+
+// RenderInitSQLFiles renders each fragment to its own numbered file in dir
+// (00-pgbox-init-10-pgvector.sql, 00-pgbox-init-20-pg_cron.sql, ...) in the
+// order fragments were added. The 00-pgbox-init- prefix sorts before user
+// files such as 05-schema.sql, so extensions exist before those run.
+// Numbered files from earlier renders that are no longer needed are removed;
+// files not generated by pgbox are never touched. Returns the written file names.
+func RenderInitSQLFiles(m *model.InitModel, dir string) ([]string, error) {
+	width := 2
+	if len(m.Fragments) >= 10 {
+		width = 3
+	}
+
+	var names []string
+	for i, frag := range m.Fragments {
+		name := fmt.Sprintf("00-pgbox-init-%0*d-%s.sql", width, (i+1)*10, strings.TrimSuffix(frag.Name, "-init"))
+		single := model.NewInitModel()
+		single.AddFragment(frag.Name, frag.Content)
+		if err := RenderInitSQLFile(single, filepath.Join(dir, name)); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		names = append(names, name)
+	}
+
+	if err := RemoveInitSQLFiles(dir, names...); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// RemoveInitSQLFiles removes the numbered init files pgbox generated in dir,
+// other than those named in keep, so init SQL from an earlier render does not
+// run a second time. Files not generated by pgbox are never touched.
+func RemoveInitSQLFiles(dir string, keep ...string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || slices.Contains(keep, entry.Name()) || !numberedInitPattern.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil || !IsGeneratedInitSQL(string(content)) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// initScriptPattern matches the shell files RenderInitScripts writes
+var initScriptPattern = regexp.MustCompile(`^00-pgbox-hook-\d+-.+\.sh$`)
+
+// InitScriptName returns the file RenderInitScripts writes the i-th of n
+// scripts to. The 00-pgbox-hook- prefix sorts before 00-pgbox-init.sql, the
+// 00-pgbox-init-NN- files, init.sql and numbered user files, so the scripts
+// run ahead of the SQL.
+func InitScriptName(i, n int, name string) string {
+	width := 2
+	if n >= 10 {
+		width = 3
+	}
+	return fmt.Sprintf("00-pgbox-hook-%0*d-%s.sh", width, (i+1)*10, name)
+}
+
+// RenderInitScripts renders each script of the model to its own executable
+// numbered file in dir, in the order scripts were added. Scripts from earlier
+// renders that are no longer needed are removed; files not generated by pgbox
+// are never touched. Returns the written file names.
+func RenderInitScripts(m *model.InitModel, dir string) ([]string, error) {
+	written := make(map[string]bool)
+	var names []string
+	for i, script := range m.Scripts {
+		name := InitScriptName(i, len(m.Scripts), script.Name)
+		lines := []string{
+			"#!/bin/sh",
+			fmt.Sprintf("# pgbox: initdb script %s sha256=%s", script.Name, script.SHA256[:16]),
+			"# Generated by pgbox",
+			"set -e",
+			"",
+		}
+		lines = append(lines, strings.Split(strings.TrimSpace(script.Content), "\n")...)
+		path := filepath.Join(dir, name)
+		if err := WriteLines(path, lines); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		// Executable, so the entrypoint runs it in its own shell instead of
+		// sourcing it
+		if err := os.Chmod(path, 0755); err != nil {
+			return nil, fmt.Errorf("failed to make %s executable: %w", name, err)
+		}
+		written[name] = true
+		names = append(names, name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || written[entry.Name()] || !initScriptPattern.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil || !IsGeneratedInitSQL(string(content)) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale %s: %w", entry.Name(), err)
+		}
+	}
+
+	return names, nil
+}
+
 // RenderPostgreSQLConf renders a postgresql.conf snippet or ALTER SYSTEM commands
 func RenderPostgreSQLConf(pgConf *model.PGConfModel, outputPath string) error {
 	if pgConf == nil || (len(pgConf.SharedPreload) == 0 && len(pgConf.GUCs) == 0) {
@@ -87,12 +223,17 @@ func RenderPostgreSQLConf(pgConf *model.PGConfModel, outputPath string) error {
 		lines = append(lines, fmt.Sprintf("shared_preload_libraries = '%s'", preloadStr))
 	}
 
-	for key, value := range pgConf.GUCs {
+	for _, key := range pgConf.SortedGUCKeys() {
+		value := pgConf.GUCs[key]
 		quotedValue := value
 		if strings.ContainsAny(value, " ,='\"") && !strings.HasPrefix(value, "'") {
 			quotedValue = fmt.Sprintf("'%s'", value)
 		}
-		lines = append(lines, fmt.Sprintf("%s = %s", key, quotedValue))
+		line := fmt.Sprintf("%s = %s", key, quotedValue)
+		if source, ok := pgConf.Sources[key]; ok {
+			line += fmt.Sprintf("  # %s", source)
+		}
+		lines = append(lines, line)
 	}
 
 	lines = append(lines, "")
@@ -108,8 +249,8 @@ func RenderPostgreSQLConf(pgConf *model.PGConfModel, outputPath string) error {
 		lines = append(lines, fmt.Sprintf("-- ALTER SYSTEM SET shared_preload_libraries = '%s';", preloadStr))
 	}
 
-	for key, value := range pgConf.GUCs {
-		lines = append(lines, fmt.Sprintf("-- ALTER SYSTEM SET %s = '%s';", key, value))
+	for _, key := range pgConf.SortedGUCKeys() {
+		lines = append(lines, fmt.Sprintf("-- ALTER SYSTEM SET %s = '%s';", key, pgConf.GUCs[key]))
 	}
 
 	if pgConf.RequireRestart {
@@ -43,6 +43,33 @@ func TestRenderDockerfile_BasicAptPackages(t *testing.T) {
 	assert.Contains(t, content, "apt-get install")
 }
 
+func TestRenderDockerfile_PGMajorFromBaseImage(t *testing.T) {
+	for _, tt := range []struct{ image, want string }{
+		{"postgres:16", "ARG PG_MAJOR=16"},
+		{"postgres:17", "ARG PG_MAJOR=17"},
+		{"postgres:18", "ARG PG_MAJOR=18"},
+		{"postgres:18-bookworm", "ARG PG_MAJOR=18"},
+	} {
+		dir := setupTempDir(t)
+		require.NoError(t, RenderDockerfile(model.NewDockerfileModel(tt.image), dir))
+		assert.Contains(t, readFile(t, filepath.Join(dir, "Dockerfile")), tt.want, tt.image)
+	}
+}
+
+func TestRenderDockerfile_ApkPackages(t *testing.T) {
+	dir := setupTempDir(t)
+	m := model.NewDockerfileModel("postgres:17-alpine")
+	m.AddPackages([]string{"postgresql17-pgvector"}, "apk")
+
+	require.NoError(t, RenderDockerfile(m, dir))
+
+	content := readFile(t, filepath.Join(dir, "Dockerfile"))
+	assert.Contains(t, content, "ARG PG_MAJOR=17")
+	assert.Contains(t, content, "FROM postgres:17-alpine")
+	assert.Contains(t, content, "RUN apk add --no-cache postgresql17-pgvector")
+	assert.NotContains(t, content, "apt")
+}
+
 func TestRenderDockerfile_DebURLs(t *testing.T) {
 	dir := setupTempDir(t)
 	m := model.NewDockerfileModel("postgres:17")
@@ -71,6 +98,43 @@ func TestRenderDockerfile_ZipURLs(t *testing.T) {
 	assert.Contains(t, content, "unzip")
 }
 
+func TestRenderDockerfile_LayerPerPackageInSortedOrder(t *testing.T) {
+	dir := setupTempDir(t)
+	m := model.NewDockerfileModel("postgres:17")
+	m.AddPackages([]string{"postgresql-17-pgvector", "postgresql-17-cron"}, "apt")
+	m.AddDebURLs("https://example.com/b.deb", "https://example.com/a.deb")
+
+	require.NoError(t, RenderDockerfile(m, dir))
+
+	content := readFile(t, filepath.Join(dir, "Dockerfile"))
+	// Cache setup, repository, two packages and two .debs
+	assert.Equal(t, 6, strings.Count(content, "\nRUN "))
+	assert.Equal(t, 5, strings.Count(content, "RUN --mount=type=cache,target=/var/cache/apt"))
+	assert.Contains(t, content, "rm -f /etc/apt/apt.conf.d/docker-clean")
+	assert.Less(t, strings.Index(content, "install -y --no-install-recommends postgresql-17-cron"),
+		strings.Index(content, "install -y --no-install-recommends postgresql-17-pgvector"))
+	assert.Less(t, strings.Index(content, "a.deb"), strings.Index(content, "b.deb"))
+	// Package lists live in the cache mount, not the image
+	assert.NotContains(t, content, "rm -rf /var/lib/apt/lists")
+}
+
+func TestRenderDockerfile_StableAcrossExtensionSets(t *testing.T) {
+	renderFor := func(packages ...string) string {
+		dir := setupTempDir(t)
+		m := model.NewDockerfileModel("postgres:17")
+		m.AddPackages(packages, "apt")
+		require.NoError(t, RenderDockerfile(m, dir))
+		return readFile(t, filepath.Join(dir, "Dockerfile"))
+	}
+
+	before := renderFor("postgresql-17-cron")
+	after := renderFor("postgresql-17-pgvector", "postgresql-17-cron")
+
+	// Adding pgvector only appends a layer, so the earlier ones stay cached
+	prefix := strings.TrimSuffix(before, "# pgbox: END\n")
+	assert.True(t, strings.HasPrefix(after, prefix))
+}
+
 func TestRenderDockerfile_NoPackages(t *testing.T) {
 	dir := setupTempDir(t)
 	m := model.NewDockerfileModel("postgres:17")
@@ -210,6 +274,21 @@ func TestRenderPostgreSQLConf_WithSettings(t *testing.T) {
 	assert.Contains(t, content, "ALTER SYSTEM")
 }
 
+func TestRenderPostgreSQLConf_AnnotatesSource(t *testing.T) {
+	dir := setupTempDir(t)
+	pgConf := model.NewPGConfModel()
+	pgConf.ApplyGUC("wal_level", "logical", model.GUCSource{Kind: model.SourceExtension, Name: "wal2json"})
+	pgConf.ApplyGUC("work_mem", "64MB", model.GUCSource{Kind: model.SourceUser})
+
+	err := RenderPostgreSQLConf(pgConf, dir)
+
+	require.NoError(t, err)
+
+	content := readFile(t, filepath.Join(dir, "postgresql.conf.pgbox"))
+	assert.Contains(t, content, "wal_level = logical  # extension wal2json")
+	assert.Contains(t, content, "work_mem = 64MB  # user")
+}
+
 func TestRenderPostgreSQLConf_Empty(t *testing.T) {
 	dir := setupTempDir(t)
 	pgConf := model.NewPGConfModel()
@@ -328,16 +407,25 @@ func TestGenerateAptInstall_WithPackages(t *testing.T) {
 	assert.Contains(t, resultStr, "postgresql-17-pgvector")
 }
 
+func TestGenerateApkInstall_LayerPerPackage(t *testing.T) {
+	assert.Empty(t, generateApkInstall(nil))
+
+	result := strings.Join(generateApkInstall([]string{"postgresql17-pg_cron", "postgresql17-hypopg"}), "\n")
+	hypopg := strings.Index(result, "RUN apk add --no-cache postgresql17-hypopg")
+	cron := strings.Index(result, "RUN apk add --no-cache postgresql17-pg_cron")
+	assert.True(t, hypopg >= 0 && cron > hypopg, result)
+}
+
 // generateDebInstall tests
 
 func TestGenerateDebInstall_Empty(t *testing.T) {
-	result := generateDebInstall([]string{})
+	result := generateDebInstall([]string{}, nil)
 
 	assert.Empty(t, result)
 }
 
 func TestGenerateDebInstall_WithURLs(t *testing.T) {
-	result := generateDebInstall([]string{"https://example.com/ext.deb"})
+	result := generateDebInstall([]string{"https://example.com/ext.deb"}, nil)
 
 	resultStr := strings.Join(result, "\n")
 	assert.Contains(t, resultStr, "curl")
@@ -345,18 +433,201 @@ func TestGenerateDebInstall_WithURLs(t *testing.T) {
 	assert.Contains(t, resultStr, "https://example.com/ext.deb")
 }
 
+func TestGenerateDebInstall_VerifiesChecksum(t *testing.T) {
+	url := "https://example.com/ext.deb"
+	result := generateDebInstall([]string{url, "https://example.com/other.deb"}, map[string]string{url: "abc123"})
+
+	resultStr := strings.Join(result, "\n")
+	assert.Contains(t, resultStr, "echo 'abc123  /tmp/ext.deb' | sha256sum -c -")
+	assert.Equal(t, 1, strings.Count(resultStr, "sha256sum"))
+}
+
 // generateZipInstall tests
 
 func TestGenerateZipInstall_Empty(t *testing.T) {
-	result := generateZipInstall([]string{})
+	result := generateZipInstall([]string{}, nil)
 
 	assert.Empty(t, result)
 }
 
 func TestGenerateZipInstall_WithURLs(t *testing.T) {
-	result := generateZipInstall([]string{"https://example.com/ext.zip"})
+	result := generateZipInstall([]string{"https://example.com/ext.zip"}, nil)
 
 	resultStr := strings.Join(result, "\n")
 	assert.Contains(t, resultStr, "unzip")
 	assert.Contains(t, resultStr, "https://example.com/ext.zip")
 }
+
+// generateArchInstall tests
+
+func TestRenderDockerfile_ArchDownloads(t *testing.T) {
+	dir := setupTempDir(t)
+	m := model.NewDockerfileModel("postgres:17")
+	m.AddArchDownload("deb", model.ArchDownload{
+		Name:      "ext",
+		URLs:      map[string]string{"amd64": "https://example.com/ext_amd64.deb", "arm64": "https://example.com/ext_arm64.deb"},
+		Checksums: map[string]string{"amd64": "abc123"},
+	})
+
+	err := RenderDockerfile(m, dir)
+
+	require.NoError(t, err)
+
+	content := readFile(t, filepath.Join(dir, "Dockerfile"))
+	assert.Contains(t, content, "Keep-Downloaded-Packages")
+	assert.Equal(t, 1, strings.Count(content, "ARG TARGETARCH"))
+	assert.Contains(t, content, "# Install ext (.deb for amd64, arm64)")
+	assert.Contains(t, content, `case "$TARGETARCH" in`)
+	assert.Contains(t, content, "amd64) url='https://example.com/ext_amd64.deb'; sha256='abc123' ;;")
+	assert.Contains(t, content, "arm64) url='https://example.com/ext_arm64.deb'; sha256='' ;;")
+	assert.Contains(t, content, `*) echo "ext has no .deb for $TARGETARCH" >&2; exit 1 ;;`)
+	assert.Contains(t, content, `if [ -n "$sha256" ]; then echo "$sha256  /tmp/ext.deb" | sha256sum -c -; fi`)
+	assert.Contains(t, content, "dpkg -i /tmp/ext.deb")
+}
+
+func TestGenerateArchInstall_ZipWithoutChecksums(t *testing.T) {
+	result := generateArchInstall("zip", []model.ArchDownload{
+		{Name: "zeta", URLs: map[string]string{"amd64": "https://example.com/zeta-amd64.zip"}},
+		{Name: "alpha", URLs: map[string]string{"amd64": "https://example.com/alpha-amd64.zip", "arm64": "https://example.com/alpha-arm64.zip"}},
+	})
+
+	resultStr := strings.Join(result, "\n")
+	assert.Less(t, strings.Index(resultStr, "# Install alpha"), strings.Index(resultStr, "# Install zeta"))
+	assert.Contains(t, resultStr, "amd64) url='https://example.com/zeta-amd64.zip' ;;")
+	assert.Contains(t, resultStr, `curl -fsSL -o /tmp/ext.zip "$url"`)
+	assert.Contains(t, resultStr, "unzip -o /tmp/ext.zip")
+	assert.NotContains(t, resultStr, "sha256sum")
+}
+
+func TestRenderInitSQLFiles_NumberedPerFragment(t *testing.T) {
+	dir := setupTempDir(t)
+	m := model.NewInitModel()
+	m.AddFragment("pgvector-init", "CREATE EXTENSION IF NOT EXISTS vector;")
+	m.AddFragment("pg_cron-init", "CREATE EXTENSION IF NOT EXISTS pg_cron;")
+
+	names, err := RenderInitSQLFiles(m, dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"00-pgbox-init-10-pgvector.sql", "00-pgbox-init-20-pg_cron.sql"}, names)
+	assert.Contains(t, readFile(t, filepath.Join(dir, "00-pgbox-init-10-pgvector.sql")), "CREATE EXTENSION IF NOT EXISTS vector;")
+	assert.NotContains(t, readFile(t, filepath.Join(dir, "00-pgbox-init-10-pgvector.sql")), "pg_cron")
+	assert.Contains(t, readFile(t, filepath.Join(dir, "00-pgbox-init-20-pg_cron.sql")), "CREATE EXTENSION IF NOT EXISTS pg_cron;")
+	assert.Less(t, names[1], "05-schema.sql", "extensions are created before user init files run")
+	assert.Less(t, InitScriptName(0, 1, "dirs"), names[0], "initdb scripts run first")
+}
+
+func TestRenderInitSQLFiles_RemovesStaleGeneratedFiles(t *testing.T) {
+	dir := setupTempDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "20-old.sql"), []byte("-- Generated by pgbox\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00-pgbox-init-20-old.sql"), []byte("-- Generated by pgbox\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "50-user.sql"), []byte("SELECT 1;\n"), 0644))
+
+	m := model.NewInitModel()
+	m.AddFragment("hstore-init", "CREATE EXTENSION IF NOT EXISTS hstore;")
+
+	_, err := RenderInitSQLFiles(m, dir)
+
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "00-pgbox-init-10-hstore.sql"))
+	assert.NoFileExists(t, filepath.Join(dir, "20-old.sql"), "files named by earlier versions are removed too")
+	assert.NoFileExists(t, filepath.Join(dir, "00-pgbox-init-20-old.sql"))
+	assert.FileExists(t, filepath.Join(dir, "50-user.sql"))
+}
+
+func TestRenderInitScripts(t *testing.T) {
+	dir := setupTempDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00-pgbox-hook-20-old-1.sh"), []byte("# Generated by pgbox\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "05-user.sh"), []byte("echo hi\n"), 0755))
+	m := model.NewInitModel()
+	m.AddScript("acme_store-dirs", "mkdir -p \"$PGDATA/acme_store\"\n")
+
+	names, err := RenderInitScripts(m, dir)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"00-pgbox-hook-10-acme_store-dirs.sh"}, names)
+	path := filepath.Join(dir, names[0])
+	content := readFile(t, path)
+	assert.True(t, strings.HasPrefix(content, "#!/bin/sh\n# pgbox: initdb script acme_store-dirs sha256="))
+	assert.True(t, strings.HasSuffix(content, "set -e\n\nmkdir -p \"$PGDATA/acme_store\"\n"))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.NoFileExists(t, filepath.Join(dir, "00-pgbox-hook-20-old-1.sh"))
+	assert.FileExists(t, filepath.Join(dir, "05-user.sh"))
+	assert.Less(t, names[0], "00-pgbox-init.sql", "scripts run before the init SQL")
+}
+
+func TestRenderCompose_WithSidecars(t *testing.T) {
+	dir := setupTempDir(t)
+	m := model.NewComposeModel("db")
+	m.Image = "postgres:17"
+	m.AddSidecar(model.Sidecar{Name: "adminer", Image: "adminer", Ports: []string{"8080:8080"}, DependsOn: "db"})
+	m.AddSidecar(model.Sidecar{Name: "pgweb", Image: "sosedoff/pgweb", Profiles: []string{"pgweb"}, Env: map[string]string{"PGWEB_DATABASE_URL": "postgres://db"}})
+
+	err := RenderCompose(m, model.NewPGConfModel(), dir)
+
+	require.NoError(t, err)
+	content := readFile(t, filepath.Join(dir, "docker-compose.yml"))
+	assert.Contains(t, content, "  adminer:\n    image: adminer\n    ports:\n      - \"8080:8080\"\n    depends_on:\n      db:\n        condition: service_healthy\n")
+	assert.Contains(t, content, "  pgweb:\n    image: sosedoff/pgweb\n    profiles:\n      - pgweb\n    environment:\n      PGWEB_DATABASE_URL: \"postgres://db\"\n")
+
+	// Re-rendering keeps a single copy of each service
+	require.NoError(t, RenderCompose(m, model.NewPGConfModel(), dir))
+	assert.Equal(t, 1, strings.Count(readFile(t, filepath.Join(dir, "docker-compose.yml")), "  adminer:"))
+}
+
+func TestAdoptComposeFile(t *testing.T) {
+	dir := setupTempDir(t)
+	path := filepath.Join(dir, "docker-compose.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`services:
+  app:
+    build: .
+
+  postgres:
+    image: postgres:16
+    restart: unless-stopped
+    environment:
+      - POSTGRES_PASSWORD=secret
+    ports:
+      - "5433:5432"
+    healthcheck:
+      test: ["CMD", "pg_isready"]
+
+  redis:
+    image: redis:7
+`), 0644))
+
+	svc, err := AdoptComposeFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", svc.Name)
+	assert.Equal(t, "postgres:16", svc.Image)
+	assert.Equal(t, map[string]string{"POSTGRES_PASSWORD": "secret"}, svc.Env)
+	assert.Equal(t, []string{"5433:5432"}, svc.Ports)
+	assert.Equal(t, []ServiceKey{{Name: "restart", Lines: []string{"    restart: unless-stopped"}}}, svc.Extra)
+	assert.Equal(t, []string{"healthcheck"}, svc.Replaced)
+
+	m := model.NewComposeModel("postgres")
+	m.Image = "postgres:16"
+	require.NoError(t, RenderAdoptedComposeFile(m, nil, svc, path))
+
+	content := readFile(t, path)
+	assert.True(t, strings.HasPrefix(content, "# pgbox: BEGIN\nservices:\n  postgres:\n    image: postgres:16\n"))
+	assert.Contains(t, content, "# pgbox: END\n  app:\n    build: .\n\n  redis:\n    image: redis:7\n")
+
+	// A second adoption finds the service in the pgbox block
+	svc, err = AdoptComposeFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", svc.Name)
+	assert.Empty(t, svc.Replaced)
+}
+
+func TestAdoptComposeFile_NoPostgresService(t *testing.T) {
+	dir := setupTempDir(t)
+	path := filepath.Join(dir, "docker-compose.yml")
+	require.NoError(t, os.WriteFile(path, []byte("services:\n  web:\n    image: nginx\n"), 0644))
+
+	_, err := AdoptComposeFile(path)
+
+	assert.EqualError(t, err, "docker-compose.yml: none of the services (web) runs a PostgreSQL image")
+}
@@ -3,6 +3,10 @@ package util
 
 import "runtime"
 
+// DebArches are the Debian architectures pgbox resolves .deb and .zip
+// downloads for.
+var DebArches = []string{"amd64", "arm64"}
+
 // GetDebArch returns the Debian architecture string for the current system.
 // This is used when fetching .deb packages from apt repositories.
 func GetDebArch() string {
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateServerCert(t *testing.T) {
+	certPEM, keyPEM, err := GenerateServerCert("pgbox-pg17", []string{"localhost", "127.0.0.1", "pgbox-pg17"})
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "pgbox-pg17", cert.Subject.CommonName)
+	assert.Equal(t, []string{"localhost", "pgbox-pg17"}, cert.DNSNames)
+	require.Len(t, cert.IPAddresses, 1)
+	assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+
+	assert.True(t, ServerCertValid(certPEM, keyPEM, 30*24*time.Hour))
+	assert.False(t, ServerCertValid(certPEM, keyPEM, 20*365*24*time.Hour), "expires before then")
+
+	_, otherKey, err := GenerateServerCert("other", nil)
+	require.NoError(t, err)
+	assert.False(t, ServerCertValid(certPEM, otherKey, 0), "key of another certificate")
+	assert.False(t, ServerCertValid(nil, nil, 0))
+}
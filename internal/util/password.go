@@ -0,0 +1,24 @@
+package util
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// passwordAlphabet avoids characters that need escaping in URLs, DSNs and shells.
+const passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GeneratePassword returns a random password of length characters drawn
+// from a cryptographically secure source.
+func GeneratePassword(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,19 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	a, err := GeneratePassword(24)
+	require.NoError(t, err)
+	b, err := GeneratePassword(24)
+	require.NoError(t, err)
+
+	assert.Len(t, a, 24)
+	assert.NotEqual(t, a, b)
+	assert.Regexp(t, `^[a-zA-Z0-9]+$`, a)
+}
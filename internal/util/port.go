@@ -0,0 +1,70 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FreePort returns the first TCP port in [start, start+count) that can be
+// bound on all interfaces, which is where containers publish their ports.
+func FreePort(start, count int) (int, error) {
+	for port := start; port < start+count; port++ {
+		l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err != nil {
+			continue
+		}
+		_ = l.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port between %d and %d", start, start+count-1)
+}
+
+// RandomFreePort returns a TCP port the operating system considers free on
+// all interfaces, from its ephemeral range.
+func RandomFreePort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// PortInUse reports whether something already listens on the TCP port on any
+// interface, so a container could not publish it.
+func PortInUse(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return true
+	}
+	_ = l.Close()
+	return false
+}
+
+// PortProcess names the process listening on the TCP port, as "command (pid
+// N)". It asks lsof and returns "" when lsof is missing or cannot tell, which
+// is common for processes owned by another user.
+func PortProcess(port int) string {
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return ""
+	}
+	// Output is one field per line: p<pid>, then c<command>. Several processes
+	// may share the socket (e.g. forked servers); the first is enough.
+	var pid, command string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "p") && pid == "":
+			pid = line[1:]
+		case strings.HasPrefix(line, "c") && command == "":
+			command = line[1:]
+		}
+	}
+	if pid == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s (pid %s)", command, pid)
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreePortSkipsPortsInUse(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	port, err := FreePort(busy, 10)
+	require.NoError(t, err)
+	assert.NotEqual(t, busy, port)
+	assert.Greater(t, port, busy)
+
+	_, err = FreePort(busy, 1)
+	assert.Error(t, err)
+}
+
+func TestRandomFreePort(t *testing.T) {
+	port, err := RandomFreePort()
+	require.NoError(t, err)
+	assert.Greater(t, port, 0)
+	assert.False(t, PortInUse(port))
+}
+
+func TestPortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	assert.True(t, PortInUse(busy))
+	require.NoError(t, l.Close())
+	assert.False(t, PortInUse(busy))
+}
@@ -0,0 +1,10 @@
+package util
+
+import "os"
+
+// StdinIsTerminal reports whether stdin is an interactive terminal rather
+// than a pipe or file. A stdin that cannot be inspected counts as not one.
+func StdinIsTerminal() bool {
+	fileInfo, err := os.Stdin.Stat()
+	return err == nil && (fileInfo.Mode()&os.ModeCharDevice) != 0
+}
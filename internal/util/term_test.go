@@ -0,0 +1,23 @@
+package util
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdinIsTerminal(t *testing.T) {
+	saved := os.Stdin
+	t.Cleanup(func() { os.Stdin = saved })
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+
+	os.Stdin = r
+	assert.False(t, StdinIsTerminal(), "a pipe")
+
+	require.NoError(t, r.Close())
+	assert.False(t, StdinIsTerminal(), "a closed stdin cannot be inspected")
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/ahacop/pgbox/cmd"
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
 	"github.com/charmbracelet/fang"
 )
 
@@ -14,7 +17,15 @@ var (
 )
 
 func main() {
-	if err := fang.Execute(context.Background(), cmd.RootCmd(), fang.WithVersion(version)); err != nil {
+	if err := cmd.Execute(context.Background(), fang.WithVersion(version)); err != nil {
+		if docker.IsDaemonError(err) {
+			os.Exit(docker.ExitDaemonUnavailable)
+		}
+		// pgbox tmp exits with the status of the command it ran
+		var childErr *orchestrator.ChildExitError
+		if errors.As(err, &childErr) {
+			os.Exit(childErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,67 @@
+// Package pgboxtest provisions per-worker test databases inside a running
+// pgbox container, for test suites that run in parallel against one instance.
+//
+//	dsns, err := pgboxtest.CreateDatabases(pgboxtest.Options{Count: 8})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer pgboxtest.DropDatabases(pgboxtest.Options{})
+package pgboxtest
+
+import (
+	"context"
+	"io"
+
+	"github.com/ahacop/pgbox/internal/docker"
+	"github.com/ahacop/pgbox/internal/orchestrator"
+)
+
+// Options selects the container and databases to provision.
+type Options struct {
+	Container string // Container name (default: auto-detect a running pgbox container)
+	Template  string // Database to clone (default: the container's POSTGRES_DB)
+	Prefix    string // Name prefix (default: <template>_test)
+	Count     int    // Number of databases to create
+}
+
+// CreateDatabases clones the template Count times and returns one DSN per database.
+// Existing databases with the same names are replaced.
+func CreateDatabases(opts Options) ([]string, error) {
+	return CreateDatabasesContext(context.Background(), opts)
+}
+
+// CreateDatabasesContext is CreateDatabases, stopping the container runtime
+// commands it runs when ctx is done.
+func CreateDatabasesContext(ctx context.Context, opts Options) ([]string, error) {
+	orch := orchestrator.NewTestDBOrchestrator(docker.NewClient(), io.Discard)
+	dbs, err := orch.Create(ctx, toConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	dsns := make([]string, len(dbs))
+	for i, db := range dbs {
+		dsns[i] = db.DSN
+	}
+	return dsns, nil
+}
+
+// DropDatabases drops every database created by CreateDatabases with the same prefix.
+func DropDatabases(opts Options) error {
+	return DropDatabasesContext(context.Background(), opts)
+}
+
+// DropDatabasesContext is DropDatabases, stopping the container runtime
+// commands it runs when ctx is done.
+func DropDatabasesContext(ctx context.Context, opts Options) error {
+	orch := orchestrator.NewTestDBOrchestrator(docker.NewClient(), io.Discard)
+	return orch.Drop(ctx, toConfig(opts))
+}
+
+func toConfig(opts Options) orchestrator.TestDBConfig {
+	return orchestrator.TestDBConfig{
+		ContainerName: opts.Container,
+		Template:      opts.Template,
+		Prefix:        opts.Prefix,
+		Count:         opts.Count,
+	}
+}
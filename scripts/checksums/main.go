@@ -0,0 +1,174 @@
+// Command checksums computes the SHA-256 of the built-in catalog's DebURL
+// and ZipURL downloads for every supported PostgreSQL version and
+// architecture, and records them in internal/extensions/checksums.go.
+//
+// Usage, from the repository root:
+//
+//	go run ./scripts/checksums          # download, hash and record
+//	go run ./scripts/checksums -check   # fail when a download no longer matches its record
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/ahacop/pgbox/internal/config"
+	"github.com/ahacop/pgbox/internal/extensions"
+)
+
+// output is the generated file, relative to the repository root.
+const output = "internal/extensions/checksums.go"
+
+// arches are the architectures pgbox builds images for.
+var arches = []string{"amd64", "arm64"}
+
+func main() {
+	check := flag.Bool("check", false, "Compare downloads with the recorded checksums instead of recording them")
+	flag.Parse()
+
+	checksums, err := compute()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *check {
+		os.Exit(compare(checksums))
+	}
+	if err := write(checksums); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_, _ = fmt.Printf("Recorded checksums for %d extensions in %s\n", len(checksums), output)
+}
+
+// compute downloads and hashes every DebURL and ZipURL download. Downloads
+// that do not exist, e.g. an architecture a project does not publish, are
+// reported and skipped.
+func compute() (map[string]map[string]string, error) {
+	checksums := make(map[string]map[string]string)
+	for _, name := range extensions.ListExtensions() {
+		ext := extensions.Catalog[name]
+		template := ext.DebURL
+		if template == "" {
+			template = ext.ZipURL
+		}
+		if template == "" {
+			continue
+		}
+		versions := ext.Versions
+		if len(versions) == 0 {
+			versions = config.SupportedVersions
+		}
+		for _, version := range versions {
+			for _, arch := range arches {
+				url := extensions.GetDebURL(name, version, arch)
+				if url == "" {
+					url = extensions.GetZipURL(name, version, arch)
+				}
+				sum, found, err := hashURL(url)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				if !found {
+					_, _ = fmt.Fprintf(os.Stderr, "%s: %s not found, skipped\n", name, url)
+					continue
+				}
+				if checksums[name] == nil {
+					checksums[name] = make(map[string]string)
+				}
+				checksums[name][extensions.ChecksumKey(version, arch)] = sum
+				_, _ = fmt.Fprintf(os.Stderr, "%s %s: %s\n", name, extensions.ChecksumKey(version, arch), sum)
+			}
+		}
+	}
+	return checksums, nil
+}
+
+// hashURL returns the hex SHA-256 of the file at url. found is false when
+// the server answers 404.
+func hashURL(url string) (sum string, found bool, err error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", false, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// compare reports downloads whose checksum differs from the recorded one or
+// was never recorded, and returns the exit code.
+func compare(checksums map[string]map[string]string) int {
+	code := 0
+	for _, name := range sortedKeys(checksums) {
+		recorded := extensions.Catalog[name].SHA256
+		for _, key := range sortedKeys(checksums[name]) {
+			switch want := recorded[key]; want {
+			case checksums[name][key]:
+			case "":
+				_, _ = fmt.Printf("%s %s: not recorded\n", name, key)
+				code = 1
+			default:
+				_, _ = fmt.Printf("%s %s: recorded %s, downloaded %s\n", name, key, want, checksums[name][key])
+				code = 1
+			}
+		}
+	}
+	if code == 0 {
+		_, _ = fmt.Println("All recorded checksums match")
+	}
+	return code
+}
+
+// write replaces the generated file with checksums.
+func write(checksums map[string]map[string]string) error {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by scripts/checksums; DO NOT EDIT.\n\npackage extensions\n\n")
+	b.WriteString("// recordedChecksums holds the SHA-256 of the built-in catalog's DebURL and\n")
+	b.WriteString("// ZipURL downloads, by catalog name and ChecksumKey.\n")
+	b.WriteString("var recordedChecksums = map[string]map[string]string{\n")
+	for _, name := range sortedKeys(checksums) {
+		_, _ = fmt.Fprintf(&b, "%q: {\n", name)
+		for _, key := range sortedKeys(checksums[name]) {
+			_, _ = fmt.Fprintf(&b, "%q: %q,\n", key, checksums[name][key])
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", output, err)
+	}
+	if err := os.WriteFile(output, src, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}